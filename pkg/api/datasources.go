@@ -18,6 +18,7 @@ import (
 	"github.com/grafana/grafana/pkg/plugins/adapters"
 	"github.com/grafana/grafana/pkg/services/datasources"
 	"github.com/grafana/grafana/pkg/services/datasources/permissions"
+	alertingProvisioning "github.com/grafana/grafana/pkg/services/ngalert/provisioning"
 	"github.com/grafana/grafana/pkg/util"
 	"github.com/grafana/grafana/pkg/util/proxyutil"
 	"github.com/grafana/grafana/pkg/web"
@@ -128,6 +129,10 @@ func (hs *HTTPServer) DeleteDataSourceById(c *models.ReqContext) response.Respon
 		return response.Error(403, "Cannot delete read-only data source", nil)
 	}
 
+	if resp := hs.checkDataSourceDelete(c, ds.Uid); resp != nil {
+		return resp
+	}
+
 	cmd := &models.DeleteDataSourceCommand{ID: id, OrgID: c.OrgId, Name: ds.Name}
 
 	err = hs.DataSourcesService.DeleteDataSource(c.Req.Context(), cmd)
@@ -184,6 +189,10 @@ func (hs *HTTPServer) DeleteDataSourceByUID(c *models.ReqContext) response.Respo
 		return response.Error(403, "Cannot delete read-only data source", nil)
 	}
 
+	if resp := hs.checkDataSourceDelete(c, ds.Uid); resp != nil {
+		return resp
+	}
+
 	cmd := &models.DeleteDataSourceCommand{UID: uid, OrgID: c.OrgId, Name: ds.Name}
 
 	err = hs.DataSourcesService.DeleteDataSource(c.Req.Context(), cmd)
@@ -219,6 +228,10 @@ func (hs *HTTPServer) DeleteDataSourceByName(c *models.ReqContext) response.Resp
 		return response.Error(403, "Cannot delete read-only data source", nil)
 	}
 
+	if resp := hs.checkDataSourceDelete(c, getCmd.Result.Uid); resp != nil {
+		return resp
+	}
+
 	cmd := &models.DeleteDataSourceCommand{Name: name, OrgID: c.OrgId}
 	err := hs.DataSourcesService.DeleteDataSource(c.Req.Context(), cmd)
 	if err != nil {
@@ -233,6 +246,21 @@ func (hs *HTTPServer) DeleteDataSourceByName(c *models.ReqContext) response.Resp
 	})
 }
 
+// checkDataSourceDelete returns an error response if alert rules that query the data source prevent its deletion.
+func (hs *HTTPServer) checkDataSourceDelete(c *models.ReqContext, uid string) response.Response {
+	if hs.AlertNG == nil || hs.AlertNG.IsDisabled() || hs.AlertNG.AlertRuleService() == nil {
+		return nil
+	}
+	err := hs.AlertNG.AlertRuleService().CheckDataSourceDelete(c.Req.Context(), c.OrgId, uid)
+	if errors.Is(err, alertingProvisioning.ErrDataSourceInUse) {
+		return response.Error(http.StatusConflict, err.Error(), err)
+	}
+	if err != nil {
+		return response.Error(500, "Failed to delete datasource", err)
+	}
+	return nil
+}
+
 func validateURL(cmdType string, url string) response.Response {
 	if _, err := datasource.ValidateURL(cmdType, url); err != nil {
 		return response.Error(400, fmt.Sprintf("Validation error, invalid URL: %q", url), err)
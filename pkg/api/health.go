@@ -19,3 +19,17 @@ func (hs *HTTPServer) databaseHealthy(ctx context.Context) bool {
 	hs.CacheService.Set(cacheKey, healthy, time.Second*5)
 	return healthy
 }
+
+// alertingHealthy reports whether the store of unified alerting is reachable.
+func (hs *HTTPServer) alertingHealthy(ctx context.Context) bool {
+	const cacheKey = "alerting-healthy"
+
+	if cached, found := hs.CacheService.Get(cacheKey); found {
+		return cached.(bool)
+	}
+
+	healthy := hs.AlertNG.HealthCheck(ctx) == nil
+
+	hs.CacheService.Set(cacheKey, healthy, time.Second*5)
+	return healthy
+}
@@ -592,6 +592,14 @@ func (hs *HTTPServer) apiHealthHandler(ctx *web.Context) {
 		data.Set("commit", hs.Cfg.BuildCommit)
 	}
 
+	if hs.AlertNG != nil && !hs.AlertNG.IsDisabled() {
+		if hs.alertingHealthy(ctx.Req.Context()) {
+			data.Set("alerting", "ok")
+		} else {
+			data.Set("alerting", "degraded")
+		}
+	}
+
 	if !hs.databaseHealthy(ctx.Req.Context()) {
 		data.Set("database", "failing")
 		ctx.Resp.Header().Set("Content-Type", "application/json; charset=UTF-8")
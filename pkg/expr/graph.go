@@ -3,6 +3,7 @@ package expr
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/grafana/grafana/pkg/expr/mathexp"
@@ -11,6 +12,10 @@ import (
 	"gonum.org/v1/gonum/graph/topo"
 )
 
+// ErrSelfReference is returned for an expression that uses its own result as input. It is the
+// shortest cycle possible, but reported separately from other cycles for a clearer message.
+var ErrSelfReference = errors.New("expression references itself")
+
 // NodeType is the type of a DPNode. Currently either a expression command or datasource query.
 type NodeType int
 
@@ -189,7 +194,7 @@ func buildGraphEdges(dp *simple.DirectedGraph, registry map[string]Node) error {
 			}
 
 			if neededNode.ID() == cmdNode.ID() {
-				return fmt.Errorf("can not add self referencing node for var '%v' ", neededVar)
+				return fmt.Errorf("%w: can not add self referencing node for var '%v', expression %v cannot use its own result as input", ErrSelfReference, neededVar, cmdNode.RefID())
 			}
 
 			if cmdNode.CMDType == TypeClassicConditions {
@@ -2,6 +2,7 @@ package expr
 
 import (
 	"encoding/json"
+	"errors"
 	"testing"
 
 	"github.com/grafana/grafana/pkg/models"
@@ -248,3 +249,20 @@ func getRefIDOrder(nodes []Node) []string {
 	}
 	return ids
 }
+
+func TestServicebuildPipeLineSelfReference(t *testing.T) {
+	for _, model := range []string{
+		`{"expression": "$A", "type": "math"}`,
+		`{"expression": "A", "reducer": "mean", "type": "reduce"}`,
+	} {
+		s := Service{}
+		_, err := s.buildPipeline(&Request{
+			Queries: []Query{
+				{RefID: "A", DataSource: DataSourceModel(), JSON: json.RawMessage(model)},
+			},
+		})
+		require.Error(t, err)
+		require.True(t, errors.Is(err, ErrSelfReference), "expected a self reference error for %s, got %v", model, err)
+		require.Contains(t, err.Error(), "expression A cannot use its own result as input")
+	}
+}
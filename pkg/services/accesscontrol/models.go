@@ -365,6 +365,15 @@ const (
 	ActionAlertingRuleUpdate = "alert.rules:write"
 	ActionAlertingRuleDelete = "alert.rules:delete"
 
+	// ActionAlertingSystemRuleRead allows to read the alert rules of all organizations at once.
+	ActionAlertingSystemRuleRead = "system:alert-rules:read"
+
+	// ActionAlertingRuleUnmanage allows to remove the provenance of provisioned alert rules.
+	ActionAlertingRuleUnmanage = "alert.rules:unmanage"
+
+	// ActionAlertingRuleProvisionNamespace restricts the provisioning of alert rules to the folders in its scopes.
+	ActionAlertingRuleProvisionNamespace = "alert.rules.provisioning:namespace"
+
 	// Alerting instances (+silences) actions
 	ActionAlertingInstanceCreate = "alert.instances:create"
 	ActionAlertingInstanceUpdate = "alert.instances:write"
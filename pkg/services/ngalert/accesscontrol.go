@@ -138,6 +138,38 @@ var (
 		},
 	}
 
+	systemRulesReaderRole = accesscontrol.RoleRegistration{
+		Role: accesscontrol.RoleDTO{
+			Name:        accesscontrol.FixedRolePrefix + "alerting.system.rules:reader",
+			DisplayName: "System Rules Reader",
+			Description: "Can read the alert rules of all organizations at once",
+			Group:       AlertRolesGroup,
+			Version:     1,
+			Permissions: []accesscontrol.Permission{
+				{
+					Action: accesscontrol.ActionAlertingSystemRuleRead,
+				},
+			},
+		},
+		Grants: []string{accesscontrol.RoleGrafanaAdmin},
+	}
+
+	rulesUnmanagerRole = accesscontrol.RoleRegistration{
+		Role: accesscontrol.RoleDTO{
+			Name:        accesscontrol.FixedRolePrefix + "alerting.rules:unmanager",
+			DisplayName: "Rules Unmanager",
+			Description: "Can remove the provenance of provisioned alert rules so that they can be edited",
+			Group:       AlertRolesGroup,
+			Version:     1,
+			Permissions: []accesscontrol.Permission{
+				{
+					Action: accesscontrol.ActionAlertingRuleUnmanage,
+				},
+			},
+		},
+		Grants: []string{accesscontrol.RoleGrafanaAdmin},
+	}
+
 	alertingReaderRole = accesscontrol.RoleRegistration{
 		Role: accesscontrol.RoleDTO{
 			Name:        accesscontrol.FixedRolePrefix + "alerting:reader",
@@ -169,5 +201,6 @@ func DeclareFixedRoles(ac accesscontrol.AccessControl) error {
 		instancesReaderRole, instancesEditorRole,
 		notificationsReaderRole, notificationsEditorRole,
 		alertingReaderRole, alertingWriterRole,
+		systemRulesReaderRole, rulesUnmanagerRole,
 	)
 }
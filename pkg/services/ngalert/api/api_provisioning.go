@@ -3,7 +3,12 @@ package api
 import (
 	"context"
 	"errors"
+	"math"
+	"mime"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/grafana/grafana/pkg/api/response"
 	"github.com/grafana/grafana/pkg/infra/log"
@@ -34,6 +39,7 @@ type ProvisioningSrv struct {
 
 type ContactPointService interface {
 	GetContactPoints(ctx context.Context, orgID int64) ([]apimodels.EmbeddedContactPoint, error)
+	ListContactPoints(ctx context.Context, orgID int64, filter provisioning.ContactPointFilter) ([]apimodels.EmbeddedContactPoint, error)
 	CreateContactPoint(ctx context.Context, orgID int64, contactPoint apimodels.EmbeddedContactPoint, p alerting_models.Provenance) (apimodels.EmbeddedContactPoint, error)
 	UpdateContactPoint(ctx context.Context, orgID int64, contactPoint apimodels.EmbeddedContactPoint, p alerting_models.Provenance) error
 	DeleteContactPoint(ctx context.Context, orgID int64, uid string) error
@@ -59,10 +65,17 @@ type MuteTimingService interface {
 
 type AlertRuleService interface {
 	GetAlertRule(ctx context.Context, orgID int64, ruleUID string) (alerting_models.AlertRule, alerting_models.Provenance, error)
-	CreateAlertRule(ctx context.Context, rule alerting_models.AlertRule, provenance alerting_models.Provenance) (alerting_models.AlertRule, error)
-	UpdateAlertRule(ctx context.Context, rule alerting_models.AlertRule, provenance alerting_models.Provenance) (alerting_models.AlertRule, error)
+	GetAlertRuleWithStateSummary(ctx context.Context, orgID int64, ruleUID string) (alerting_models.AlertRule, alerting_models.Provenance, *alerting_models.AlertRuleStateSummary, error)
+	CreateAlertRuleWithIssues(ctx context.Context, rule alerting_models.AlertRule, provenance alerting_models.Provenance) (alerting_models.AlertRule, []alerting_models.ValidationIssue, error)
+	UpdateAlertRuleWithIssues(ctx context.Context, rule alerting_models.AlertRule, provenance alerting_models.Provenance) (alerting_models.AlertRule, []alerting_models.ValidationIssue, error)
 	DeleteAlertRule(ctx context.Context, orgID int64, ruleUID string, provenance alerting_models.Provenance) error
-	UpdateAlertGroup(ctx context.Context, orgID int64, folderUID, rulegroup string, interval int64) error
+	UpdateAlertGroup(ctx context.Context, orgID int64, folderUID, rulegroup string, interval int64, provenance alerting_models.Provenance) error
+	UpdateRuleGroupEvalStrategy(ctx context.Context, orgID int64, folderUID, group, strategy string, provenance alerting_models.Provenance) error
+	UpdateRuleGroupLabels(ctx context.Context, orgID int64, folderUID, group string, labels map[string]string, provenance alerting_models.Provenance) error
+	GetAlertRuleGroup(ctx context.Context, orgID int64, namespaceUID, group string) ([]alerting_models.AlertRule, error)
+	UpdateRuleGroupFull(ctx context.Context, orgID int64, namespaceUID, group string, interval int64, rules []alerting_models.AlertRule, provenance alerting_models.Provenance) error
+	GetProvisioningSource(ctx context.Context, orgID int64, resourceType, uid string) (*alerting_models.ProvisioningSource, error)
+	TestAlertRuleNotification(ctx context.Context, orgID int64, ruleUID string) error
 }
 
 func (srv *ProvisioningSrv) RouteGetPolicyTree(c *models.ReqContext) response.Response {
@@ -85,6 +98,12 @@ func (srv *ProvisioningSrv) RoutePutPolicyTree(c *models.ReqContext, tree apimod
 	if errors.Is(err, provisioning.ErrValidation) {
 		return ErrResp(http.StatusBadRequest, err, "")
 	}
+	if errors.Is(err, provisioning.ErrRateLimited) {
+		return rateLimitedResp(err)
+	}
+	if errors.Is(err, provisioning.ErrProvisioningDisabled) {
+		return ErrResp(http.StatusForbidden, err, "")
+	}
 	if err != nil {
 		return ErrResp(http.StatusInternalServerError, err, "")
 	}
@@ -93,7 +112,13 @@ func (srv *ProvisioningSrv) RoutePutPolicyTree(c *models.ReqContext, tree apimod
 }
 
 func (srv *ProvisioningSrv) RouteGetContactPoints(c *models.ReqContext) response.Response {
-	cps, err := srv.contactPointService.GetContactPoints(c.Req.Context(), c.OrgId)
+	filter := provisioning.ContactPointFilter{
+		Name:            c.Query("name"),
+		NameContains:    c.Query("search"),
+		Types:           c.QueryStrings("type"),
+		ProvisionedOnly: c.QueryBool("provisioned"),
+	}
+	cps, err := srv.contactPointService.ListContactPoints(c.Req.Context(), c.OrgId, filter)
 	if err != nil {
 		return ErrResp(http.StatusInternalServerError, err, "")
 	}
@@ -103,6 +128,12 @@ func (srv *ProvisioningSrv) RouteGetContactPoints(c *models.ReqContext) response
 func (srv *ProvisioningSrv) RoutePostContactPoint(c *models.ReqContext, cp apimodels.EmbeddedContactPoint) response.Response {
 	// TODO: provenance is hardcoded for now, change it later to make it more flexible
 	contactPoint, err := srv.contactPointService.CreateContactPoint(c.Req.Context(), c.OrgId, cp, alerting_models.ProvenanceAPI)
+	if errors.Is(err, provisioning.ErrRateLimited) {
+		return rateLimitedResp(err)
+	}
+	if errors.Is(err, provisioning.ErrProvisioningDisabled) {
+		return ErrResp(http.StatusForbidden, err, "")
+	}
 	if err != nil {
 		return ErrResp(http.StatusInternalServerError, err, "")
 	}
@@ -112,6 +143,12 @@ func (srv *ProvisioningSrv) RoutePostContactPoint(c *models.ReqContext, cp apimo
 func (srv *ProvisioningSrv) RoutePutContactPoint(c *models.ReqContext, cp apimodels.EmbeddedContactPoint) response.Response {
 	cp.UID = pathParam(c, uidPathParam)
 	err := srv.contactPointService.UpdateContactPoint(c.Req.Context(), c.OrgId, cp, alerting_models.ProvenanceAPI)
+	if errors.Is(err, provisioning.ErrRateLimited) {
+		return rateLimitedResp(err)
+	}
+	if errors.Is(err, provisioning.ErrProvisioningDisabled) {
+		return ErrResp(http.StatusForbidden, err, "")
+	}
 	if err != nil {
 		return ErrResp(http.StatusInternalServerError, err, "")
 	}
@@ -121,6 +158,12 @@ func (srv *ProvisioningSrv) RoutePutContactPoint(c *models.ReqContext, cp apimod
 func (srv *ProvisioningSrv) RouteDeleteContactPoint(c *models.ReqContext) response.Response {
 	UID := pathParam(c, uidPathParam)
 	err := srv.contactPointService.DeleteContactPoint(c.Req.Context(), c.OrgId, UID)
+	if errors.Is(err, provisioning.ErrRateLimited) {
+		return rateLimitedResp(err)
+	}
+	if errors.Is(err, provisioning.ErrProvisioningDisabled) {
+		return ErrResp(http.StatusForbidden, err, "")
+	}
 	if err != nil {
 		return ErrResp(http.StatusInternalServerError, err, "")
 	}
@@ -163,6 +206,12 @@ func (srv *ProvisioningSrv) RoutePutTemplate(c *models.ReqContext, body apimodel
 		if errors.Is(err, provisioning.ErrValidation) {
 			return ErrResp(http.StatusBadRequest, err, "")
 		}
+		if errors.Is(err, provisioning.ErrRateLimited) {
+			return rateLimitedResp(err)
+		}
+		if errors.Is(err, provisioning.ErrProvisioningDisabled) {
+			return ErrResp(http.StatusForbidden, err, "")
+		}
 		return ErrResp(http.StatusInternalServerError, err, "")
 	}
 	return response.JSON(http.StatusAccepted, modified)
@@ -171,6 +220,12 @@ func (srv *ProvisioningSrv) RoutePutTemplate(c *models.ReqContext, body apimodel
 func (srv *ProvisioningSrv) RouteDeleteTemplate(c *models.ReqContext) response.Response {
 	name := pathParam(c, namePathParam)
 	err := srv.templates.DeleteTemplate(c.Req.Context(), c.OrgId, name)
+	if errors.Is(err, provisioning.ErrRateLimited) {
+		return rateLimitedResp(err)
+	}
+	if errors.Is(err, provisioning.ErrProvisioningDisabled) {
+		return ErrResp(http.StatusForbidden, err, "")
+	}
 	if err != nil {
 		return ErrResp(http.StatusInternalServerError, err, "")
 	}
@@ -205,6 +260,12 @@ func (srv *ProvisioningSrv) RoutePostMuteTiming(c *models.ReqContext, mt apimode
 		if errors.Is(err, provisioning.ErrValidation) {
 			return ErrResp(http.StatusBadRequest, err, "")
 		}
+		if errors.Is(err, provisioning.ErrRateLimited) {
+			return rateLimitedResp(err)
+		}
+		if errors.Is(err, provisioning.ErrProvisioningDisabled) {
+			return ErrResp(http.StatusForbidden, err, "")
+		}
 		return ErrResp(http.StatusInternalServerError, err, "")
 	}
 	return response.JSON(http.StatusCreated, created)
@@ -218,6 +279,12 @@ func (srv *ProvisioningSrv) RoutePutMuteTiming(c *models.ReqContext, mt apimodel
 		if errors.Is(err, provisioning.ErrValidation) {
 			return ErrResp(http.StatusBadRequest, err, "")
 		}
+		if errors.Is(err, provisioning.ErrRateLimited) {
+			return rateLimitedResp(err)
+		}
+		if errors.Is(err, provisioning.ErrProvisioningDisabled) {
+			return ErrResp(http.StatusForbidden, err, "")
+		}
 		return ErrResp(http.StatusInternalServerError, err, "")
 	}
 	if updated == nil {
@@ -229,6 +296,12 @@ func (srv *ProvisioningSrv) RoutePutMuteTiming(c *models.ReqContext, mt apimodel
 func (srv *ProvisioningSrv) RouteDeleteMuteTiming(c *models.ReqContext) response.Response {
 	name := pathParam(c, namePathParam)
 	err := srv.muteTimings.DeleteMuteTiming(c.Req.Context(), name, c.OrgId)
+	if errors.Is(err, provisioning.ErrRateLimited) {
+		return rateLimitedResp(err)
+	}
+	if errors.Is(err, provisioning.ErrProvisioningDisabled) {
+		return ErrResp(http.StatusForbidden, err, "")
+	}
 	if err != nil {
 		return ErrResp(http.StatusInternalServerError, err, "")
 	}
@@ -237,36 +310,164 @@ func (srv *ProvisioningSrv) RouteDeleteMuteTiming(c *models.ReqContext) response
 
 func (srv *ProvisioningSrv) RouteRouteGetAlertRule(c *models.ReqContext) response.Response {
 	uid := pathParam(c, uidPathParam)
-	rule, provenace, err := srv.alertRules.GetAlertRule(c.Req.Context(), c.OrgId, uid)
+	format, err := durationFormat(c)
+	if err != nil {
+		return ErrResp(http.StatusBadRequest, err, "")
+	}
+	if c.QueryBool("includeState") {
+		rule, provenance, summary, err := srv.alertRules.GetAlertRuleWithStateSummary(callerContext(c), c.OrgId, uid)
+		if errors.Is(err, alerting_models.ErrAlertRuleNotFound) {
+			return response.Empty(http.StatusNotFound)
+		}
+		if errors.Is(err, provisioning.ErrAccessDenied) {
+			return ErrResp(http.StatusForbidden, err, "")
+		}
+		if err != nil {
+			return ErrResp(http.StatusInternalServerError, err, "")
+		}
+		result := apimodels.NewAlertRule(rule, provenance)
+		result.State = summary
+		result.DurationFormat = format
+		return srv.alertRuleResponse(c, result)
+	}
+	rule, provenace, err := srv.alertRules.GetAlertRule(callerContext(c), c.OrgId, uid)
+	if errors.Is(err, alerting_models.ErrAlertRuleNotFound) {
+		return response.Empty(http.StatusNotFound)
+	}
+	if errors.Is(err, provisioning.ErrAccessDenied) {
+		return ErrResp(http.StatusForbidden, err, "")
+	}
 	if err != nil {
 		return ErrResp(http.StatusInternalServerError, err, "")
 	}
-	return response.JSON(http.StatusOK, apimodels.NewAlertRule(rule, provenace))
+	result := apimodels.NewAlertRule(rule, provenace)
+	result.DurationFormat = format
+	return srv.alertRuleResponse(c, result)
+}
+
+// alertRuleResponse writes the alert rule, with the file it is provisioned from if the request asks
+// for it with includeSource.
+func (srv *ProvisioningSrv) alertRuleResponse(c *models.ReqContext, result apimodels.AlertRule) response.Response {
+	if c.QueryBool("includeSource") {
+		source, err := srv.alertRules.GetProvisioningSource(callerContext(c), c.OrgId, (&alerting_models.AlertRule{}).ResourceType(), result.UID)
+		if err != nil {
+			return ErrResp(http.StatusInternalServerError, err, "")
+		}
+		result.ProvisioningSource = source
+	}
+	return response.JSON(http.StatusOK, result)
+}
+
+// callerContext returns the context of the request with its user as the caller of the provisioning
+// service, which restricts the namespaces that the user can provision alert rules in.
+func callerContext(c *models.ReqContext) context.Context {
+	return provisioning.WithCaller(c.Req.Context(), c.SignedInUser)
+}
+
+// durationFormat returns the format of durations in responses, which is requested by the durations
+// parameter of the Accept header, e.g. "Accept: application/json; durations=human".
+func durationFormat(c *models.ReqContext) (apimodels.DurationFormat, error) {
+	for _, accepted := range strings.Split(c.Req.Header.Get("Accept"), ",") {
+		_, params, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil {
+			continue
+		}
+		if format, ok := params["durations"]; ok {
+			return apimodels.ParseDurationFormat(format)
+		}
+	}
+	return apimodels.DurationFormatSeconds, nil
 }
 
 func (srv *ProvisioningSrv) RoutePostAlertRule(c *models.ReqContext, ar apimodels.AlertRule) response.Response {
-	createdAlertRule, err := srv.alertRules.CreateAlertRule(c.Req.Context(), ar.UpstreamModel(), alerting_models.ProvenanceAPI)
+	metadataWarnings, err := ar.ValidateMetadata()
+	if err != nil {
+		return ErrResp(http.StatusBadRequest, err, "")
+	}
+	if ar.DurationFormat, err = durationFormat(c); err != nil {
+		return ErrResp(http.StatusBadRequest, err, "")
+	}
+	createdAlertRule, warnings, err := srv.alertRules.CreateAlertRuleWithIssues(callerContext(c), ar.UpstreamModel(), alerting_models.ProvenanceAPI)
+	if errors.Is(err, provisioning.ErrContactPointNotFound) || errors.Is(err, provisioning.ErrValidation) {
+		return ErrResp(http.StatusBadRequest, err, "")
+	}
+	if errors.Is(err, alerting_models.ErrAlertRuleDuplicateTitle) {
+		return ErrResp(http.StatusConflict, err, "")
+	}
+	if errors.Is(err, provisioning.ErrRateLimited) {
+		return rateLimitedResp(err)
+	}
+	if errors.Is(err, provisioning.ErrProvisioningDisabled) || errors.Is(err, provisioning.ErrQuotaReached) || errors.Is(err, provisioning.ErrAccessDenied) {
+		return ErrResp(http.StatusForbidden, err, "")
+	}
 	if err != nil {
 		return ErrResp(http.StatusInternalServerError, err, "")
 	}
 	ar.ID = createdAlertRule.ID
 	ar.UID = createdAlertRule.UID
 	ar.Updated = createdAlertRule.Updated
+	ar.Annotations = createdAlertRule.Annotations
+	ar.Warnings = append(metadataWarnings, warnings...)
 	return response.JSON(http.StatusCreated, ar)
 }
 
 func (srv *ProvisioningSrv) RoutePutAlertRule(c *models.ReqContext, ar apimodels.AlertRule) response.Response {
-	updatedAlertRule, err := srv.alertRules.UpdateAlertRule(c.Req.Context(), ar.UpstreamModel(), alerting_models.ProvenanceAPI)
+	metadataWarnings, err := ar.ValidateMetadata()
+	if err != nil {
+		return ErrResp(http.StatusBadRequest, err, "")
+	}
+	if ar.DurationFormat, err = durationFormat(c); err != nil {
+		return ErrResp(http.StatusBadRequest, err, "")
+	}
+	updatedAlertRule, warnings, err := srv.alertRules.UpdateAlertRuleWithIssues(callerContext(c), ar.UpstreamModel(), alerting_models.ProvenanceAPI)
+	if errors.Is(err, provisioning.ErrContactPointNotFound) || errors.Is(err, provisioning.ErrValidation) {
+		return ErrResp(http.StatusBadRequest, err, "")
+	}
+	if errors.Is(err, alerting_models.ErrAlertRuleDuplicateTitle) {
+		return ErrResp(http.StatusConflict, err, "")
+	}
+	if errors.Is(err, provisioning.ErrRateLimited) {
+		return rateLimitedResp(err)
+	}
+	if errors.Is(err, provisioning.ErrProvisioningDisabled) || errors.Is(err, provisioning.ErrAccessDenied) {
+		return ErrResp(http.StatusForbidden, err, "")
+	}
 	if err != nil {
 		return ErrResp(http.StatusInternalServerError, err, "")
 	}
+	ar.ID = updatedAlertRule.ID
 	ar.Updated = updatedAlertRule.Updated
+	ar.Annotations = updatedAlertRule.Annotations
+	ar.DashboardUID = updatedAlertRule.DashboardUID
+	ar.PanelID = updatedAlertRule.PanelID
+	ar.Warnings = append(metadataWarnings, warnings...)
 	return response.JSON(http.StatusOK, ar)
 }
 
+// RoutePostAlertRuleTestNotification sends a test alert of the rule through the notification policies.
+func (srv *ProvisioningSrv) RoutePostAlertRuleTestNotification(c *models.ReqContext) response.Response {
+	err := srv.alertRules.TestAlertRuleNotification(callerContext(c), c.OrgId, pathParam(c, uidPathParam))
+	if errors.Is(err, alerting_models.ErrAlertRuleNotFound) {
+		return response.Empty(http.StatusNotFound)
+	}
+	if errors.Is(err, provisioning.ErrAccessDenied) {
+		return ErrResp(http.StatusForbidden, err, "")
+	}
+	if err != nil {
+		return ErrResp(http.StatusInternalServerError, err, "")
+	}
+	return response.JSON(http.StatusAccepted, util.DynMap{"message": "test notification sent"})
+}
+
 func (srv *ProvisioningSrv) RouteDeleteAlertRule(c *models.ReqContext) response.Response {
 	uid := pathParam(c, uidPathParam)
-	err := srv.alertRules.DeleteAlertRule(c.Req.Context(), c.OrgId, uid, alerting_models.ProvenanceAPI)
+	err := srv.alertRules.DeleteAlertRule(callerContext(c), c.OrgId, uid, alerting_models.ProvenanceAPI)
+	if errors.Is(err, provisioning.ErrRateLimited) {
+		return rateLimitedResp(err)
+	}
+	if errors.Is(err, provisioning.ErrProvisioningDisabled) || errors.Is(err, provisioning.ErrAccessDenied) {
+		return ErrResp(http.StatusForbidden, err, "")
+	}
 	if err != nil {
 		return ErrResp(http.StatusInternalServerError, err, "")
 	}
@@ -276,13 +477,201 @@ func (srv *ProvisioningSrv) RouteDeleteAlertRule(c *models.ReqContext) response.
 func (srv *ProvisioningSrv) RoutePutAlertRuleGroup(c *models.ReqContext, ag apimodels.AlertRuleGroup) response.Response {
 	rulegroup := pathParam(c, groupPathParam)
 	folderUID := pathParam(c, folderUIDPathParam)
-	err := srv.alertRules.UpdateAlertGroup(c.Req.Context(), c.OrgId, folderUID, rulegroup, ag.Interval)
+	if ag.EvalStrategy != "" {
+		err := srv.alertRules.UpdateRuleGroupEvalStrategy(callerContext(c), c.OrgId, folderUID, rulegroup, ag.EvalStrategy, alerting_models.ProvenanceAPI)
+		if errors.Is(err, provisioning.ErrValidation) {
+			return ErrResp(http.StatusBadRequest, err, "")
+		}
+		if errors.Is(err, store.ErrAlertRuleGroupNotFound) {
+			return ErrResp(http.StatusNotFound, err, "")
+		}
+		if errors.Is(err, provisioning.ErrProvenanceMismatch) {
+			return ErrResp(http.StatusConflict, err, "")
+		}
+		if errors.Is(err, provisioning.ErrRateLimited) {
+			return rateLimitedResp(err)
+		}
+		if errors.Is(err, provisioning.ErrProvisioningDisabled) || errors.Is(err, provisioning.ErrAccessDenied) {
+			return ErrResp(http.StatusForbidden, err, "")
+		}
+		if err != nil {
+			return ErrResp(http.StatusInternalServerError, err, "")
+		}
+	}
+	if ag.Labels != nil {
+		err := srv.alertRules.UpdateRuleGroupLabels(callerContext(c), c.OrgId, folderUID, rulegroup, ag.Labels, alerting_models.ProvenanceAPI)
+		if errors.Is(err, provisioning.ErrValidation) {
+			return ErrResp(http.StatusBadRequest, err, "")
+		}
+		if errors.Is(err, store.ErrAlertRuleGroupNotFound) {
+			return ErrResp(http.StatusNotFound, err, "")
+		}
+		if errors.Is(err, provisioning.ErrProvenanceMismatch) {
+			return ErrResp(http.StatusConflict, err, "")
+		}
+		if errors.Is(err, provisioning.ErrRateLimited) {
+			return rateLimitedResp(err)
+		}
+		if errors.Is(err, provisioning.ErrProvisioningDisabled) || errors.Is(err, provisioning.ErrAccessDenied) {
+			return ErrResp(http.StatusForbidden, err, "")
+		}
+		if err != nil {
+			return ErrResp(http.StatusInternalServerError, err, "")
+		}
+	}
+	err := srv.alertRules.UpdateAlertGroup(callerContext(c), c.OrgId, folderUID, rulegroup, ag.Interval, alerting_models.ProvenanceAPI)
+	if errors.Is(err, provisioning.ErrValidation) || errors.Is(err, alerting_models.ErrAlertRuleFailedValidation) {
+		return ErrResp(http.StatusBadRequest, err, "")
+	}
+	if errors.Is(err, store.ErrAlertRuleGroupNotFound) {
+		return ErrResp(http.StatusNotFound, err, "")
+	}
+	if errors.Is(err, provisioning.ErrProvenanceMismatch) {
+		return ErrResp(http.StatusConflict, err, "")
+	}
+	if errors.Is(err, provisioning.ErrRateLimited) {
+		return rateLimitedResp(err)
+	}
+	if errors.Is(err, provisioning.ErrProvisioningDisabled) || errors.Is(err, provisioning.ErrAccessDenied) {
+		return ErrResp(http.StatusForbidden, err, "")
+	}
 	if err != nil {
 		return ErrResp(http.StatusInternalServerError, err, "")
 	}
 	return response.JSON(http.StatusOK, ag)
 }
 
+// rateLimitedResp tells the client when it can retry a change that was rejected by the rate limit.
+func rateLimitedResp(err error) response.Response {
+	resp := ErrResp(http.StatusTooManyRequests, err, "")
+	var limitErr *provisioning.RateLimitError
+	if errors.As(err, &limitErr) {
+		resp.SetHeader("Retry-After", strconv.Itoa(int(math.Ceil(limitErr.RetryAfter.Seconds()))))
+	}
+	return resp
+}
+
+// RouteGetAlertRuleGroupExport returns a rule group in the file provisioning format, as YAML if the
+// request accepts application/yaml and as JSON otherwise.
+func (srv *ProvisioningSrv) RouteGetAlertRuleGroupExport(c *models.ReqContext) response.Response {
+	rulegroup := pathParam(c, groupPathParam)
+	folderUID := pathParam(c, folderUIDPathParam)
+	rules, err := srv.alertRules.GetAlertRuleGroup(callerContext(c), c.OrgId, folderUID, rulegroup)
+	if errors.Is(err, store.ErrAlertRuleGroupNotFound) {
+		return ErrResp(http.StatusNotFound, err, "")
+	}
+	if errors.Is(err, provisioning.ErrAccessDenied) {
+		return ErrResp(http.StatusForbidden, err, "")
+	}
+	if err != nil {
+		return ErrResp(http.StatusInternalServerError, err, "")
+	}
+	file, err := provisioning.NewAlertingFileExport(c.OrgId, ruleRefs(rules))
+	if err != nil {
+		return ErrResp(http.StatusInternalServerError, err, "")
+	}
+	if c.QueryBool("includeSource") {
+		for _, group := range file.Groups {
+			for i := range group.Rules {
+				source, err := srv.alertRules.GetProvisioningSource(callerContext(c), c.OrgId, (&alerting_models.AlertRule{}).ResourceType(), group.Rules[i].UID)
+				if err != nil {
+					return ErrResp(http.StatusInternalServerError, err, "")
+				}
+				group.Rules[i].ProvisioningSource = source
+			}
+		}
+	}
+	return exportResponse(c, http.StatusOK, file)
+}
+
+// RoutePostRuleGroups creates or replaces the rule groups of the body, which is in the file
+// provisioning format as JSON or YAML. Every group is replaced in its own transaction, in the order
+// of the body, so the groups before a group that fails are replaced. The response contains the
+// replaced groups in the format of the Accept header.
+func (srv *ProvisioningSrv) RoutePostRuleGroups(c *models.ReqContext) response.Response {
+	groups, err := decodeRuleGroupFiles(c.Req)
+	if err != nil {
+		return ErrResp(http.StatusBadRequest, err, "")
+	}
+	if len(groups) == 0 {
+		return ErrResp(http.StatusBadRequest, errors.New("the body has no rule groups"), "")
+	}
+	type ruleGroup struct {
+		folderUID string
+		name      string
+		interval  int64
+		rules     []alerting_models.AlertRule
+	}
+	replaces := make([]ruleGroup, 0, len(groups))
+	for _, group := range groups {
+		if group.Name == "" || group.Folder == "" {
+			return ErrResp(http.StatusBadRequest, errors.New("rule groups must have a name and a folder"), "")
+		}
+		// the rule groups are replaced in the organization of the request, whatever the file says.
+		group.OrgID = c.OrgId
+		replace := ruleGroup{
+			folderUID: group.Folder,
+			name:      group.Name,
+			interval:  int64(time.Duration(group.Interval).Seconds()),
+			rules:     make([]alerting_models.AlertRule, 0, len(group.Rules)),
+		}
+		for _, export := range group.Rules {
+			rule, err := export.UpstreamModel(group)
+			if err != nil {
+				return ErrResp(http.StatusBadRequest, err, "invalid rule '%s' of rule group '%s'", export.Title, group.Name)
+			}
+			replace.rules = append(replace.rules, rule)
+		}
+		replaces = append(replaces, replace)
+	}
+
+	ctx := callerContext(c)
+	var replaced []*alerting_models.AlertRule
+	for _, replace := range replaces {
+		err := srv.alertRules.UpdateRuleGroupFull(ctx, c.OrgId, replace.folderUID, replace.name, replace.interval, replace.rules, alerting_models.ProvenanceAPI)
+		if err != nil {
+			return ruleGroupReplaceErrResp(err, replace.name)
+		}
+		rules, err := srv.alertRules.GetAlertRuleGroup(ctx, c.OrgId, replace.folderUID, replace.name)
+		if errors.Is(err, store.ErrAlertRuleGroupNotFound) {
+			// a group without rules is deleted.
+			continue
+		}
+		if err != nil {
+			return ErrResp(http.StatusInternalServerError, err, "")
+		}
+		replaced = append(replaced, ruleRefs(rules)...)
+	}
+	file, err := provisioning.NewAlertingFileExport(c.OrgId, replaced)
+	if err != nil {
+		return ErrResp(http.StatusInternalServerError, err, "")
+	}
+	return exportResponse(c, http.StatusOK, file)
+}
+
+func ruleGroupReplaceErrResp(err error, group string) response.Response {
+	switch {
+	case errors.Is(err, provisioning.ErrContactPointNotFound), errors.Is(err, provisioning.ErrValidation):
+		return ErrResp(http.StatusBadRequest, err, "failed to replace rule group '%s'", group)
+	case errors.Is(err, alerting_models.ErrAlertRuleDuplicateTitle), errors.Is(err, provisioning.ErrProvenanceMismatch):
+		return ErrResp(http.StatusConflict, err, "failed to replace rule group '%s'", group)
+	case errors.Is(err, provisioning.ErrRateLimited):
+		return rateLimitedResp(err)
+	case errors.Is(err, provisioning.ErrProvisioningDisabled), errors.Is(err, provisioning.ErrQuotaReached), errors.Is(err, provisioning.ErrAccessDenied):
+		return ErrResp(http.StatusForbidden, err, "failed to replace rule group '%s'", group)
+	default:
+		return ErrResp(http.StatusInternalServerError, err, "failed to replace rule group '%s'", group)
+	}
+}
+
+func ruleRefs(rules []alerting_models.AlertRule) []*alerting_models.AlertRule {
+	result := make([]*alerting_models.AlertRule, 0, len(rules))
+	for i := range rules {
+		result = append(result, &rules[i])
+	}
+	return result
+}
+
 func pathParam(c *models.ReqContext, param string) string {
 	return web.Params(c.Req)[param]
 }
@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"net/http"
 	"testing"
+	"time"
 
+	"github.com/grafana/grafana/pkg/api/response"
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/models"
 	apimodels "github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
@@ -51,6 +53,19 @@ func TestProvisioningApi(t *testing.T) {
 		})
 	})
 
+	t.Run("when the org changes resources too fast", func(t *testing.T) {
+		t.Run("PUT policies returns 429 with Retry-After", func(t *testing.T) {
+			sut := createProvisioningSrvSut()
+			sut.policies = &fakeThrottledNotificationPolicyService{}
+			rc := createTestRequestCtx()
+
+			resp := sut.RoutePutPolicyTree(&rc, apimodels.Route{})
+
+			require.Equal(t, 429, resp.Status())
+			require.Equal(t, "2", resp.(*response.NormalResponse).Header().Get("Retry-After"))
+		})
+	})
+
 	t.Run("when org has no AM config", func(t *testing.T) {
 		t.Run("GET policies returns 404", func(t *testing.T) {
 			sut := createProvisioningSrvSut()
@@ -170,3 +185,13 @@ func (f *fakeRejectingNotificationPolicyService) GetPolicyTree(ctx context.Conte
 func (f *fakeRejectingNotificationPolicyService) UpdatePolicyTree(ctx context.Context, orgID int64, tree apimodels.Route, p domain.Provenance) error {
 	return fmt.Errorf("%w: invalid policy tree", provisioning.ErrValidation)
 }
+
+type fakeThrottledNotificationPolicyService struct{}
+
+func (f *fakeThrottledNotificationPolicyService) GetPolicyTree(ctx context.Context, orgID int64) (apimodels.Route, error) {
+	return apimodels.Route{}, nil
+}
+
+func (f *fakeThrottledNotificationPolicyService) UpdatePolicyTree(ctx context.Context, orgID int64, tree apimodels.Route, p domain.Provenance) error {
+	return &provisioning.RateLimitError{OrgID: orgID, RetryAfter: 1500 * time.Millisecond}
+}
@@ -9,6 +9,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/prometheus/common/model"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -23,6 +24,7 @@ import (
 	"github.com/grafana/grafana/pkg/services/ngalert/provisioning"
 	"github.com/grafana/grafana/pkg/services/ngalert/schedule"
 	"github.com/grafana/grafana/pkg/services/ngalert/store"
+	"github.com/grafana/grafana/pkg/setting"
 	"github.com/grafana/grafana/pkg/util"
 	"github.com/grafana/grafana/pkg/web"
 )
@@ -541,6 +543,91 @@ func TestRouteDeleteAlertRules(t *testing.T) {
 	})
 }
 
+func TestRoutePostNameRulesConfig(t *testing.T) {
+	// the ruler API only sets some of the fields of the rules, the others must be kept when a group is saved.
+	setup := func(t *testing.T) (*RulerSrv, *store.FakeRuleStore, *models.AlertRule, *models2.ReqContext) {
+		t.Helper()
+		orgID := rand.Int63()
+		folder := randFolder()
+		ruleStore := store.NewFakeRuleStore(t)
+		ruleStore.Folders[orgID] = append(ruleStore.Folders[orgID], folder)
+		existing := models.AlertRuleGen(withOrgID(orgID), withNamespace(folder), func(rule *models.AlertRule) {
+			rule.IntervalSeconds = 60
+			rule.DashboardUID = nil
+			rule.PanelID = nil
+			rule.NotificationSettings = []models.NotificationSettings{{ReceiverName: "team-a", GroupBy: []string{"alertname"}}}
+			rule.TitleTemplate = "{{ .Labels.instance }} is down"
+			rule.ActiveWindow = &models.ActiveWindow{Location: "Europe/Berlin"}
+			expiresAt := time.Now().Add(time.Hour)
+			rule.ExpiresAt = &expiresAt
+			rule.EvalOffsetSeconds = 30
+			rule.GroupLabels = map[string]string{"team": "a"}
+			rule.ManagedBy = "operator"
+			rule.WarmUpEvals = 3
+		})()
+		ruleStore.PutRule(context.Background(), existing)
+
+		scheduler := &schedule.FakeScheduleService{}
+		scheduler.On("UpdateAlertRule", mock.Anything)
+		svc := createService(acMock.New().WithDisabled(), ruleStore, scheduler)
+		svc.cfg = &setting.UnifiedAlertingSettings{BaseInterval: 10 * time.Second, DefaultRuleEvaluationInterval: time.Minute}
+		request := createRequestContext(orgID, models2.ROLE_EDITOR, map[string]string{
+			":Namespace": folder.Title,
+		})
+		return svc, ruleStore, existing, request
+	}
+	submit := func(existing *models.AlertRule, title string) apimodels.PostableRuleGroupConfig {
+		return apimodels.PostableRuleGroupConfig{
+			Name:     existing.RuleGroup,
+			Interval: model.Duration(time.Duration(existing.IntervalSeconds) * time.Second),
+			Rules: []apimodels.PostableExtendedRuleNode{{
+				ApiRuleNode: &apimodels.ApiRuleNode{
+					Labels:      existing.Labels,
+					Annotations: existing.Annotations,
+				},
+				GrafanaManagedAlert: &apimodels.PostableGrafanaRule{UID: existing.UID, Title: title},
+			}},
+		}
+	}
+	recordedUpdates := func(ruleStore *store.FakeRuleStore) []store.UpdateRule {
+		var updates []store.UpdateRule
+		for _, op := range ruleStore.RecordedOps {
+			if u, ok := op.([]store.UpdateRule); ok {
+				updates = append(updates, u...)
+			}
+		}
+		return updates
+	}
+
+	t.Run("should keep the fields that the ruler API does not set", func(t *testing.T) {
+		svc, ruleStore, existing, request := setup(t)
+
+		response := svc.RoutePostNameRulesConfig(request, submit(existing, "updated title"))
+		require.Equalf(t, http.StatusAccepted, response.Status(), "unexpected response: %s", string(response.Body()))
+
+		updates := recordedUpdates(ruleStore)
+		require.Len(t, updates, 1)
+		updated := updates[0].New
+		require.Equal(t, "updated title", updated.Title)
+		require.Equal(t, existing.NotificationSettings, updated.NotificationSettings)
+		require.Equal(t, existing.TitleTemplate, updated.TitleTemplate)
+		require.Equal(t, existing.ActiveWindow, updated.ActiveWindow)
+		require.Equal(t, existing.ExpiresAt, updated.ExpiresAt)
+		require.Equal(t, existing.EvalOffsetSeconds, updated.EvalOffsetSeconds)
+		require.Equal(t, existing.GroupLabels, updated.GroupLabels)
+		require.Equal(t, existing.ManagedBy, updated.ManagedBy)
+		require.Equal(t, existing.WarmUpEvals, updated.WarmUpEvals)
+	})
+
+	t.Run("should not update rules that are submitted unchanged", func(t *testing.T) {
+		svc, ruleStore, existing, request := setup(t)
+
+		response := svc.RoutePostNameRulesConfig(request, submit(existing, existing.Title))
+		require.Equalf(t, http.StatusAccepted, response.Status(), "unexpected response: %s", string(response.Body()))
+		require.Empty(t, recordedUpdates(ruleStore))
+	})
+}
+
 func TestRouteGetNamespaceRulesConfig(t *testing.T) {
 	t.Run("fine-grained access is enabled", func(t *testing.T) {
 		t.Run("should return rules for which user has access to data source", func(t *testing.T) {
@@ -87,6 +87,10 @@ func (f *ForkedProvisioningApi) forkRoutePostAlertRule(ctx *models.ReqContext, a
 	return f.svc.RoutePostAlertRule(ctx, ar)
 }
 
+func (f *ForkedProvisioningApi) forkRoutePostAlertRuleTestNotification(ctx *models.ReqContext) response.Response {
+	return f.svc.RoutePostAlertRuleTestNotification(ctx)
+}
+
 func (f *ForkedProvisioningApi) forkRoutePutAlertRule(ctx *models.ReqContext, ar apimodels.AlertRule) response.Response {
 	return f.svc.RoutePutAlertRule(ctx, ar)
 }
@@ -98,3 +102,11 @@ func (f *ForkedProvisioningApi) forkRouteDeleteAlertRule(ctx *models.ReqContext)
 func (f *ForkedProvisioningApi) forkRoutePutAlertRuleGroup(ctx *models.ReqContext, ag apimodels.AlertRuleGroup) response.Response {
 	return f.svc.RoutePutAlertRuleGroup(ctx, ag)
 }
+
+func (f *ForkedProvisioningApi) forkRouteGetAlertRuleGroupExport(ctx *models.ReqContext) response.Response {
+	return f.svc.RouteGetAlertRuleGroupExport(ctx)
+}
+
+func (f *ForkedProvisioningApi) forkRoutePostRuleGroups(ctx *models.ReqContext) response.Response {
+	return f.svc.RoutePostRuleGroups(ctx)
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/models"
+	apimodels "github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+)
+
+// yamlMediaType is the media type of YAML responses of the provisioning API.
+const yamlMediaType = "application/yaml"
+
+// yamlMediaTypes are the media types that identify YAML request bodies and Accept headers.
+var yamlMediaTypes = map[string]struct{}{
+	yamlMediaType:        {},
+	"application/x-yaml": {},
+	"text/yaml":          {},
+	"text/x-yaml":        {},
+}
+
+func isYAMLMediaType(value string) bool {
+	mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(value))
+	if err != nil {
+		return false
+	}
+	_, ok := yamlMediaTypes[mediaType]
+	return ok
+}
+
+// acceptsYAML returns true if the Accept header of the request lists a YAML media type before
+// application/json. Responses are JSON otherwise.
+func acceptsYAML(c *models.ReqContext) bool {
+	for _, accepted := range strings.Split(c.Req.Header.Get("Accept"), ",") {
+		if isYAMLMediaType(accepted) {
+			return true
+		}
+		if mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accepted)); err == nil && mediaType == "application/json" {
+			return false
+		}
+	}
+	return false
+}
+
+// exportResponse writes the rule groups in the file provisioning format, as YAML if the request
+// accepts it and as JSON otherwise.
+func exportResponse(c *models.ReqContext, status int, file apimodels.AlertingFileExport) response.Response {
+	if !acceptsYAML(c) {
+		return response.JSON(status, file)
+	}
+	body, err := yaml.Marshal(file)
+	if err != nil {
+		return ErrResp(http.StatusInternalServerError, err, "failed to write rule groups as YAML")
+	}
+	return response.Respond(status, body).SetHeader("Content-Type", yamlMediaType)
+}
+
+// decodeRuleGroupFiles reads the rule groups of a request body in the file provisioning format. The
+// body is YAML if its Content-Type is a YAML media type and JSON otherwise. A YAML body can contain
+// several files as separate documents, and the groups of all documents are returned in their order.
+// Errors report the line of the body that could not be decoded.
+func decodeRuleGroupFiles(r *http.Request) ([]apimodels.AlertRuleGroupExport, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the body: %w", err)
+	}
+	if isYAMLMediaType(r.Header.Get("Content-Type")) {
+		return decodeYAMLRuleGroupFiles(body)
+	}
+	return decodeJSONRuleGroupFile(body)
+}
+
+func decodeYAMLRuleGroupFiles(body []byte) ([]apimodels.AlertRuleGroupExport, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(body))
+	var groups []apimodels.AlertRuleGroupExport
+	for {
+		var document yaml.Node
+		err := decoder.Decode(&document)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid YAML: %w", err)
+		}
+		if len(document.Content) == 0 {
+			continue
+		}
+		var file apimodels.AlertingFileExport
+		if err := document.Decode(&file); err != nil {
+			return nil, fmt.Errorf("invalid YAML: %w", err)
+		}
+		if file.APIVersion != 1 {
+			return nil, fmt.Errorf("invalid YAML: line %d: unsupported apiVersion %d, must be 1", document.Content[0].Line, file.APIVersion)
+		}
+		groups = append(groups, file.Groups...)
+	}
+	return groups, nil
+}
+
+func decodeJSONRuleGroupFile(body []byte) ([]apimodels.AlertRuleGroupExport, error) {
+	var file apimodels.AlertingFileExport
+	if err := json.Unmarshal(body, &file); err != nil {
+		var syntaxErr *json.SyntaxError
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case errors.As(err, &syntaxErr):
+			return nil, fmt.Errorf("invalid JSON: line %d: %w", lineOfOffset(body, syntaxErr.Offset), err)
+		case errors.As(err, &typeErr):
+			return nil, fmt.Errorf("invalid JSON: line %d: %w", lineOfOffset(body, typeErr.Offset), err)
+		default:
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+	}
+	if file.APIVersion != 1 {
+		return nil, fmt.Errorf("invalid JSON: unsupported apiVersion %d, must be 1", file.APIVersion)
+	}
+	return file.Groups, nil
+}
+
+// lineOfOffset returns the 1-based line of the byte offset in the body.
+func lineOfOffset(body []byte, offset int64) int {
+	if offset > int64(len(body)) {
+		offset = int64(len(body))
+	}
+	return bytes.Count(body[:offset], []byte("\n")) + 1
+}
@@ -0,0 +1,217 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	apimodels "github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	domain "github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/store"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+const ruleGroupFilesYAML = `apiVersion: 1
+groups:
+  - orgId: 1
+    name: latency
+    folder: team-a
+    interval: 1m
+    rules:
+      - uid: latency-p99
+        title: Latency p99
+        condition: B
+        data: &queries
+          - refId: A
+            datasourceUid: prometheus
+            relativeTimeRange:
+              from: 10m
+              to: 0
+            model:
+              expr: histogram_quantile(0.99, rate(request_duration_seconds_bucket[5m]))
+          - refId: B
+            datasourceUid: __expr__
+            model:
+              type: threshold
+              expression: A
+        noDataState: NoData
+        execErrState: Alerting
+        for: 5m
+        labels: &labels
+          team: a
+          severity: page
+        annotations:
+          summary: Latency is high
+          description: |
+            The p99 latency is above the threshold.
+            Check the dashboards of the service:
+              - requests
+              - saturation
+      - uid: latency-p90
+        title: Latency p90
+        condition: B
+        data: *queries
+        noDataState: NoData
+        execErrState: Alerting
+        for: 300
+        labels:
+          <<: *labels
+          severity: ticket
+        annotations:
+          summary: >-
+            Latency is
+            elevated
+---
+apiVersion: 1
+groups:
+  - name: errors
+    folder: team-b
+    interval: 90s
+    rules: []
+`
+
+func TestDecodeRuleGroupFiles(t *testing.T) {
+	newRequest := func(contentType, body string) *http.Request {
+		r, err := http.NewRequest(http.MethodPost, "/api/v1/provisioning/rule-groups", strings.NewReader(body))
+		require.NoError(t, err)
+		r.Header.Set("Content-Type", contentType)
+		return r
+	}
+
+	t.Run("should decode YAML with anchors, merge keys and multiline strings", func(t *testing.T) {
+		groups, err := decodeRuleGroupFiles(newRequest("application/yaml", ruleGroupFilesYAML))
+		require.NoError(t, err)
+		require.Len(t, groups, 2, "every document should add its groups")
+
+		latency := groups[0]
+		require.Equal(t, "latency", latency.Name)
+		require.Equal(t, time.Minute, time.Duration(latency.Interval))
+		require.Len(t, latency.Rules, 2)
+
+		p99, p90 := latency.Rules[0], latency.Rules[1]
+		require.Equal(t, 5*time.Minute, time.Duration(p99.For))
+		require.Equal(t, 5*time.Minute, time.Duration(p90.For), "durations should also be read from numbers of seconds")
+		require.Equal(t, 10*time.Minute, time.Duration(p99.Data[0].RelativeTimeRange.From))
+		require.Equal(t, p99.Data, p90.Data, "aliases should be resolved")
+		require.Equal(t, map[string]string{"team": "a", "severity": "ticket"}, p90.Labels, "merge keys should be resolved")
+		require.Equal(t, "The p99 latency is above the threshold.\nCheck the dashboards of the service:\n  - requests\n  - saturation\n", p99.Annotations["description"])
+		require.Equal(t, "Latency is elevated", p90.Annotations["summary"])
+
+		rule, err := p90.UpstreamModel(latency)
+		require.NoError(t, err)
+		require.Equal(t, int64(60), rule.IntervalSeconds)
+		require.Equal(t, "team-a", rule.NamespaceUID)
+		require.JSONEq(t, `{"expr":"histogram_quantile(0.99, rate(request_duration_seconds_bucket[5m]))"}`, string(rule.Data[0].Model))
+
+		require.Equal(t, "errors", groups[1].Name)
+		require.Equal(t, 90*time.Second, time.Duration(groups[1].Interval))
+	})
+	t.Run("should decode JSON into the same groups", func(t *testing.T) {
+		groups, err := decodeRuleGroupFiles(newRequest("application/json", `{
+			"apiVersion": 1,
+			"groups": [{"name": "errors", "folder": "team-b", "interval": "90s", "rules": []}]
+		}`))
+		require.NoError(t, err)
+		require.Len(t, groups, 1)
+		require.Equal(t, 90*time.Second, time.Duration(groups[0].Interval))
+	})
+	t.Run("YAML errors should report their line", func(t *testing.T) {
+		invalidDuration := strings.Replace(ruleGroupFilesYAML, "for: 5m", "for: five minutes", 1)
+		_, err := decodeRuleGroupFiles(newRequest("application/yaml", invalidDuration))
+		require.ErrorContains(t, err, "line 26")
+
+		invalidType := strings.Replace(ruleGroupFilesYAML, "orgId: 1", "orgId: [1]", 1)
+		_, err = decodeRuleGroupFiles(newRequest("application/x-yaml", invalidType))
+		require.ErrorContains(t, err, "line 3")
+
+		invalidSyntax := strings.Replace(ruleGroupFilesYAML, "name: errors", "name: errors: again", 1)
+		_, err = decodeRuleGroupFiles(newRequest("text/yaml; charset=utf-8", invalidSyntax))
+		require.ErrorContains(t, err, "line 54")
+
+		invalidVersion := strings.Replace(ruleGroupFilesYAML, "---\napiVersion: 1", "---\napiVersion: 2", 1)
+		_, err = decodeRuleGroupFiles(newRequest("application/yaml", invalidVersion))
+		require.ErrorContains(t, err, "line 52: unsupported apiVersion 2")
+	})
+	t.Run("JSON errors should report their line", func(t *testing.T) {
+		_, err := decodeRuleGroupFiles(newRequest("application/json", "{\n\"apiVersion\": 1,\n\"groups\": [{\"name\": 1}]\n}"))
+		require.ErrorContains(t, err, "line 3")
+	})
+}
+
+type fakeRuleGroupService struct {
+	AlertRuleService
+	groups map[string][]domain.AlertRule
+}
+
+func (f *fakeRuleGroupService) GetAlertRuleGroup(_ context.Context, _ int64, namespaceUID, group string) ([]domain.AlertRule, error) {
+	rules, ok := f.groups[namespaceUID+"/"+group]
+	if !ok || len(rules) == 0 {
+		return nil, store.ErrAlertRuleGroupNotFound
+	}
+	return rules, nil
+}
+
+func (f *fakeRuleGroupService) UpdateRuleGroupFull(_ context.Context, _ int64, namespaceUID, group string, interval int64, rules []domain.AlertRule, _ domain.Provenance) error {
+	for i := range rules {
+		rules[i].IntervalSeconds = interval
+	}
+	f.groups[namespaceUID+"/"+group] = rules
+	return nil
+}
+
+func TestProvisioningApiRuleGroupFiles(t *testing.T) {
+	service := &fakeRuleGroupService{groups: map[string][]domain.AlertRule{}}
+	srv := ProvisioningSrv{log: log.NewNopLogger(), alertRules: service}
+	newContext := func(method, body string, header http.Header, params map[string]string) *models.ReqContext {
+		r, err := http.NewRequest(method, "/", strings.NewReader(body))
+		require.NoError(t, err)
+		r.Header = header
+		return &models.ReqContext{
+			Context:      &web.Context{Req: web.SetURLParams(r, params)},
+			SignedInUser: &models.SignedInUser{OrgId: 1},
+		}
+	}
+
+	c := newContext(http.MethodPost, ruleGroupFilesYAML, http.Header{
+		"Content-Type": []string{"application/yaml"},
+		"Accept":       []string{"application/yaml"},
+	}, nil)
+	resp := srv.RoutePostRuleGroups(c)
+	require.Equal(t, http.StatusOK, resp.Status(), string(resp.Body()))
+	require.Len(t, service.groups["team-a/latency"], 2)
+	require.Equal(t, int64(60), service.groups["team-a/latency"][0].IntervalSeconds)
+
+	var file apimodels.AlertingFileExport
+	require.NoError(t, yaml.Unmarshal(resp.Body(), &file))
+	require.Len(t, file.Groups, 1, "groups without rules are deleted")
+	require.Equal(t, "latency", file.Groups[0].Name)
+
+	t.Run("should export rule groups as YAML or JSON", func(t *testing.T) {
+		params := map[string]string{folderUIDPathParam: "team-a", groupPathParam: "latency"}
+		yamlResp := srv.RouteGetAlertRuleGroupExport(newContext(http.MethodGet, "", http.Header{"Accept": []string{"application/yaml"}}, params))
+		require.Equal(t, http.StatusOK, yamlResp.Status())
+		require.Contains(t, string(yamlResp.Body()), "interval: 1m\n")
+		require.Contains(t, string(yamlResp.Body()), "description: |\n")
+
+		jsonResp := srv.RouteGetAlertRuleGroupExport(newContext(http.MethodGet, "", http.Header{"Accept": []string{"application/json"}}, params))
+		require.Equal(t, http.StatusOK, jsonResp.Status())
+		require.Contains(t, string(jsonResp.Body()), `"interval":"1m"`)
+
+		params[groupPathParam] = "errors"
+		resp := srv.RouteGetAlertRuleGroupExport(newContext(http.MethodGet, "", http.Header{}, params))
+		require.Equal(t, http.StatusNotFound, resp.Status())
+	})
+	t.Run("should reject bodies that cannot be decoded with their line", func(t *testing.T) {
+		c := newContext(http.MethodPost, "apiVersion: 1\ngroups:\n  - name: [\n", http.Header{"Content-Type": []string{"application/yaml"}}, nil)
+		resp := srv.RoutePostRuleGroups(c)
+		require.Equal(t, http.StatusBadRequest, resp.Status())
+		require.Contains(t, string(resp.Body()), "line 3")
+	})
+}
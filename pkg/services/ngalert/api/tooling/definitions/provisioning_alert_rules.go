@@ -1,8 +1,13 @@
 package definitions
 
 import (
+	"fmt"
+	"net/url"
 	"time"
 
+	"github.com/prometheus/alertmanager/pkg/labels"
+	"github.com/prometheus/common/model"
+
 	"github.com/grafana/grafana/pkg/services/ngalert/models"
 )
 
@@ -41,12 +46,32 @@ import (
 //       204: description: The alert rule was deleted successfully.
 //       400: ValidationError
 
-// swagger:parameters RouteGetAlertRule RoutePutAlertRule RouteDeleteAlertRule
+// swagger:route POST /api/v1/provisioning/alert-rules/{UID}/test-notification provisioning stable RoutePostAlertRuleTestNotification
+//
+// Send a firing test alert with the labels and annotations of the alert rule through the notification policies.
+//
+//     Responses:
+//       202: Ack
+//       404: description: Not found.
+
+// swagger:parameters RouteGetAlertRule RoutePutAlertRule RouteDeleteAlertRule RoutePostAlertRuleTestNotification
 type AlertRuleUIDReference struct {
 	// in:path
 	UID string
 }
 
+// swagger:parameters RouteGetAlertRule
+type AlertRuleGetParams struct {
+	// Include a summary of the current state of the alert instances of the rule.
+	// in:query
+	// required:false
+	IncludeState bool `json:"includeState"`
+	// Include the file that the rule is provisioned from, if it is provisioned from a file.
+	// in:query
+	// required:false
+	IncludeSource bool `json:"includeSource"`
+}
+
 // swagger:parameters RoutePostAlertRule RoutePutAlertRule
 type AlertRulePayload struct {
 	// in:body
@@ -68,10 +93,55 @@ type AlertRule struct {
 	For          time.Duration              `json:"for"`
 	Annotations  map[string]string          `json:"annotations,omitempty"`
 	Labels       map[string]string          `json:"labels,omitempty"`
-	Provenance   models.Provenance          `json:"provenance,omitempty"`
+	// EvalOffsetSeconds shifts the evaluations of the rule within the interval of its group. It must be
+	// shorter than the interval and divided exactly by the scheduler interval.
+	EvalOffsetSeconds int64 `json:"evalOffsetSeconds,omitempty"`
+	// EvalEveryN evaluates the rule only on every Nth tick of the interval of its group. If it is omitted,
+	// the rule is evaluated on every tick.
+	EvalEveryN int `json:"evalEveryN,omitempty"`
+	// WarmUpEvals suppresses the notifications of the first evaluations of a new rule.
+	WarmUpEvals int `json:"warmUpEvals,omitempty"`
+	// ManagedBy identifies the external controller that owns the rule. It is free-form and optional.
+	ManagedBy string `json:"managedBy,omitempty"`
+	// DashboardUID and PanelID link the rule to a panel. Both or none of them must be set. Updates
+	// that set none of them keep the link, and an empty DashboardUID without PanelID removes it.
+	DashboardUID *string `json:"dashboardUID,omitempty"`
+	PanelID      *int64  `json:"panelID,omitempty"`
+	// Description is stored as the description annotation of the rule.
+	Description string `json:"description,omitempty"`
+	// RunbookURL is stored as the runbook_url annotation of the rule. It must be an http(s) URL.
+	RunbookURL string `json:"runbookURL,omitempty"`
+	// TitleTemplate is rendered with the labels of each alert instance, available as {{ .Labels }},
+	// and sent with its notifications. The title of the rule is not changed.
+	TitleTemplate string `json:"titleTemplate,omitempty"`
+	// NotificationSettings overrides the notification policy tree for the alerts of the rule.
+	NotificationSettings *models.NotificationSettings `json:"notificationSettings,omitempty"`
+	// ActiveWindow restricts the evaluation of the rule to some times of the week. Outside of the
+	// window, the rule is not evaluated and its alerts are Normal.
+	ActiveWindow *models.ActiveWindow `json:"activeWindow,omitempty"`
+	Provenance   models.Provenance    `json:"provenance,omitempty"`
+	// Warnings lists issues with the rule that did not prevent it from being saved.
+	// It is only set in responses.
+	Warnings []models.ValidationIssue `json:"warnings,omitempty"`
+	// State summarizes the current state of the alert instances of the rule.
+	// It is only set in responses if requested and available.
+	State *models.AlertRuleStateSummary `json:"state,omitempty"`
+	// Status is the outcome of the latest evaluation of the rule: ok, degraded or error.
+	// It is only set in responses for rules that were evaluated.
+	Status models.AlertRuleStatus `json:"status,omitempty"`
+	// ProvisioningSource is the file that the rule is provisioned from.
+	// It is only set in responses if requested and the rule is provisioned from a file.
+	ProvisioningSource *models.ProvisioningSource `json:"provisioningSource,omitempty"`
+	// DurationFormat is the format of the relative time ranges of the queries in responses.
+	// Requests may use numbers of seconds and duration strings.
+	DurationFormat DurationFormat `json:"-"`
 }
 
 func (a *AlertRule) UpstreamModel() models.AlertRule {
+	var notificationSettings []models.NotificationSettings
+	if a.NotificationSettings != nil {
+		notificationSettings = []models.NotificationSettings{*a.NotificationSettings}
+	}
 	return models.AlertRule{
 		ID:           a.ID,
 		UID:          a.UID,
@@ -85,9 +155,69 @@ func (a *AlertRule) UpstreamModel() models.AlertRule {
 		NoDataState:  a.NoDataState,
 		ExecErrState: a.ExecErrState,
 		For:          a.For,
-		Annotations:  a.Annotations,
+		Annotations:  a.annotations(),
 		Labels:       a.Labels,
+		DashboardUID: a.DashboardUID,
+		PanelID:      a.PanelID,
+
+		EvalOffsetSeconds:    a.EvalOffsetSeconds,
+		EvalEveryN:           a.EvalEveryN,
+		WarmUpEvals:          a.WarmUpEvals,
+		ManagedBy:            a.ManagedBy,
+		TitleTemplate:        a.TitleTemplate,
+		NotificationSettings: notificationSettings,
+		ActiveWindow:         a.ActiveWindow,
+	}
+}
+
+// annotations returns the annotations of the rule merged with the values of
+// the dedicated metadata fields, which take precedence.
+func (a *AlertRule) annotations() map[string]string {
+	if a.Description == "" && a.RunbookURL == "" {
+		return a.Annotations
+	}
+	result := make(map[string]string, len(a.Annotations)+2)
+	for k, v := range a.Annotations {
+		result[k] = v
 	}
+	if a.Description != "" {
+		result[models.DescriptionAnnotation] = a.Description
+	}
+	if a.RunbookURL != "" {
+		result[models.RunbookURLAnnotation] = a.RunbookURL
+	}
+	return result
+}
+
+// ValidateMetadata checks the dedicated metadata fields of the rule. It returns
+// an error if a field is invalid, and a warning for every annotation that is
+// overridden by a field with a different value.
+func (a *AlertRule) ValidateMetadata() ([]models.ValidationIssue, error) {
+	if a.RunbookURL != "" {
+		u, err := url.Parse(a.RunbookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("runbook URL '%s' must be an absolute http or https URL", a.RunbookURL)
+		}
+	}
+	var issues []models.ValidationIssue
+	fields := []struct {
+		field      string
+		annotation string
+		value      string
+	}{
+		{field: "description", annotation: models.DescriptionAnnotation, value: a.Description},
+		{field: "runbookURL", annotation: models.RunbookURLAnnotation, value: a.RunbookURL},
+	}
+	for _, f := range fields {
+		if existing, ok := a.Annotations[f.annotation]; ok && f.value != "" && existing != f.value {
+			issues = append(issues, models.ValidationIssue{
+				Code:    models.IssueAnnotationOverridden,
+				Field:   f.field,
+				Message: fmt.Sprintf("%s overrides the value of the annotation '%s'", f.field, f.annotation),
+			})
+		}
+	}
+	return issues, nil
 }
 
 func NewAlertRule(rule models.AlertRule, provenance models.Provenance) AlertRule {
@@ -106,7 +236,20 @@ func NewAlertRule(rule models.AlertRule, provenance models.Provenance) AlertRule
 		ExecErrState: rule.ExecErrState,
 		Annotations:  rule.Annotations,
 		Labels:       rule.Labels,
+		DashboardUID: rule.DashboardUID,
+		PanelID:      rule.PanelID,
+		Description:  rule.Annotations[models.DescriptionAnnotation],
+		RunbookURL:   rule.Annotations[models.RunbookURLAnnotation],
 		Provenance:   provenance,
+		Status:       rule.Status,
+
+		EvalOffsetSeconds:    rule.EvalOffsetSeconds,
+		EvalEveryN:           rule.EvalEveryN,
+		WarmUpEvals:          rule.WarmUpEvals,
+		ManagedBy:            rule.ManagedBy,
+		TitleTemplate:        rule.TitleTemplate,
+		NotificationSettings: rule.GetNotificationSettings(),
+		ActiveWindow:         rule.ActiveWindow,
 	}
 }
 
@@ -121,18 +264,55 @@ func NewAlertRule(rule models.AlertRule, provenance models.Provenance) AlertRule
 //       200: AlertRuleGroup
 //       400: ValidationError
 
-// swagger:parameters RoutePutAlertRuleGroup
+// swagger:route GET /api/v1/provisioning/folder/{FolderUID}/rule-groups/{Group}/export provisioning stable RouteGetAlertRuleGroupExport
+//
+// Export a rule group in the file provisioning format.
+//
+//     Produces:
+//     - application/json
+//     - application/yaml
+//
+//     Responses:
+//       200: AlertingFileExport
+//       404: description: Not found.
+
+// swagger:route POST /api/v1/provisioning/rule-groups provisioning stable RoutePostRuleGroups
+//
+// Create or replace rule groups from files in the file provisioning format. YAML bodies can
+// contain several files as separate documents.
+//
+//     Consumes:
+//     - application/json
+//     - application/yaml
+//
+//     Produces:
+//     - application/json
+//     - application/yaml
+//
+//     Responses:
+//       200: AlertingFileExport
+//       400: ValidationError
+
+// swagger:parameters RoutePutAlertRuleGroup RouteGetAlertRuleGroupExport
 type FolderUIDPathParam struct {
 	// in:path
 	FolderUID string `json:"FolderUID"`
 }
 
-// swagger:parameters RoutePutAlertRuleGroup
+// swagger:parameters RoutePutAlertRuleGroup RouteGetAlertRuleGroupExport
 type RuleGroupPathParam struct {
 	// in:path
 	Group string `json:"Group"`
 }
 
+// swagger:parameters RouteGetAlertRuleGroupExport
+type AlertRuleGroupExportParams struct {
+	// Include the files that the rules are provisioned from.
+	// in:query
+	// required:false
+	IncludeSource bool `json:"includeSource"`
+}
+
 // swagger:parameters RoutePutAlertRuleGroup
 type AlertRuleGroupPayload struct {
 	// in:body
@@ -141,4 +321,58 @@ type AlertRuleGroupPayload struct {
 
 type AlertRuleGroup struct {
 	Interval int64 `json:"interval"`
+	// EvalStrategy decides how the rules of the group are evaluated when some of them fail:
+	// independent, all_success or any_success. The strategy is not changed if it is empty.
+	EvalStrategy string `json:"evalStrategy,omitempty"`
+	// Labels are added to the labels of all rules of the group, whose own labels win on conflicts.
+	// The labels are not changed if they are missing, and removed if they are empty.
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// NotificationSettingsRoute returns the route for the alerts of the rule with the given UID that
+// overrides the notification policy tree with the notification settings of the rule. The route
+// matches the alerts by the rule UID label and inherits the timings that the settings do not
+// override from its parent.
+func NotificationSettingsRoute(ruleUID string, settings models.NotificationSettings) (*Route, error) {
+	return SimplifiedPolicyRoute(ruleUID, models.SimplifiedPolicy{
+		ReceiverName:   settings.ReceiverName,
+		GroupBy:        settings.GroupBy,
+		GroupWait:      (*time.Duration)(settings.GroupWait),
+		GroupInterval:  (*time.Duration)(settings.GroupInterval),
+		RepeatInterval: (*time.Duration)(settings.RepeatInterval),
+	})
+}
+
+// SimplifiedPolicyRoute returns the route for the alerts of the rule with the given UID that
+// implements the policy. The route matches the alerts by the rule UID label and inherits the
+// timings that the policy does not override from its parent.
+func SimplifiedPolicyRoute(ruleUID string, policy models.SimplifiedPolicy) (*Route, error) {
+	matcher, err := labels.NewMatcher(labels.MatchEqual, models.RuleUIDLabel, ruleUID)
+	if err != nil {
+		return nil, err
+	}
+	route := &Route{
+		Receiver:       policy.ReceiverName,
+		ObjectMatchers: ObjectMatchers{matcher},
+	}
+	for _, label := range policy.GroupBy {
+		route.GroupByStr = append(route.GroupByStr, label)
+		if label == models.GroupByAll {
+			route.GroupByAll = true
+			continue
+		}
+		route.GroupBy = append(route.GroupBy, model.LabelName(label))
+	}
+	route.GroupWait = optionalDuration(policy.GroupWait)
+	route.GroupInterval = optionalDuration(policy.GroupInterval)
+	route.RepeatInterval = optionalDuration(policy.RepeatInterval)
+	return route, nil
+}
+
+func optionalDuration(d *time.Duration) *model.Duration {
+	if d == nil {
+		return nil
+	}
+	result := model.Duration(*d)
+	return &result
 }
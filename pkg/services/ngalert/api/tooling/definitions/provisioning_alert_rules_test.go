@@ -0,0 +1,189 @@
+package definitions
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestAlertRuleMetadata(t *testing.T) {
+	t.Run("metadata fields should be stored as annotations", func(t *testing.T) {
+		rule := AlertRule{
+			Annotations: map[string]string{"foo": "bar", models.DescriptionAnnotation: "old"},
+			Description: "new",
+			RunbookURL:  "https://example.com/runbook",
+		}
+
+		upstream := rule.UpstreamModel()
+
+		require.Equal(t, map[string]string{
+			"foo":                        "bar",
+			models.DescriptionAnnotation: "new",
+			models.RunbookURLAnnotation:  "https://example.com/runbook",
+		}, upstream.Annotations)
+		require.Equal(t, "old", rule.Annotations[models.DescriptionAnnotation], "the annotations of the request should not be modified")
+
+		converted := NewAlertRule(upstream, models.ProvenanceNone)
+		require.Equal(t, "new", converted.Description)
+		require.Equal(t, "https://example.com/runbook", converted.RunbookURL)
+	})
+
+	for _, tc := range []struct {
+		desc     string
+		input    AlertRule
+		err      bool
+		warnings int
+	}{
+		{
+			desc:  "no metadata",
+			input: AlertRule{Annotations: map[string]string{models.RunbookURLAnnotation: "not a url"}},
+		},
+		{
+			desc:  "valid runbook URL",
+			input: AlertRule{RunbookURL: "http://example.com/runbook"},
+		},
+		{
+			desc:  "runbook URL with unsupported scheme",
+			input: AlertRule{RunbookURL: "ftp://example.com/runbook"},
+			err:   true,
+		},
+		{
+			desc:  "relative runbook URL",
+			input: AlertRule{RunbookURL: "/runbook"},
+			err:   true,
+		},
+		{
+			desc: "same value as annotation",
+			input: AlertRule{
+				Annotations: map[string]string{models.DescriptionAnnotation: "description"},
+				Description: "description",
+			},
+		},
+		{
+			desc: "different values than annotations",
+			input: AlertRule{
+				Annotations: map[string]string{
+					models.DescriptionAnnotation: "description",
+					models.RunbookURLAnnotation:  "https://example.com/old",
+				},
+				Description: "other description",
+				RunbookURL:  "https://example.com/new",
+			},
+			warnings: 2,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			issues, err := tc.input.ValidateMetadata()
+			if tc.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, issues, tc.warnings)
+			for _, issue := range issues {
+				require.Equal(t, models.IssueAnnotationOverridden, issue.Code)
+			}
+		})
+	}
+}
+
+func TestNotificationSettingsRoute(t *testing.T) {
+	route, err := NotificationSettingsRoute("rule-uid", models.NotificationSettings{ReceiverName: "receiver", GroupBy: []string{"alertname", "team"}})
+	require.NoError(t, err)
+	require.Equal(t, "receiver", route.Receiver)
+	require.Equal(t, []string{"alertname", "team"}, route.GroupByStr)
+	require.Len(t, route.GroupBy, 2)
+	require.False(t, route.GroupByAll)
+	require.Len(t, route.ObjectMatchers, 1)
+	require.True(t, route.ObjectMatchers[0].Matches("rule-uid"))
+	require.Equal(t, models.RuleUIDLabel, route.ObjectMatchers[0].Name)
+
+	require.Nil(t, route.GroupWait)
+	require.Nil(t, route.GroupInterval)
+	require.Nil(t, route.RepeatInterval)
+
+	groupWait, repeatInterval := model.Duration(time.Minute), model.Duration(4*time.Hour)
+	route, err = NotificationSettingsRoute("rule-uid", models.NotificationSettings{ReceiverName: "receiver", GroupBy: []string{models.GroupByAll}, GroupWait: &groupWait, RepeatInterval: &repeatInterval})
+	require.NoError(t, err)
+	require.True(t, route.GroupByAll)
+	require.Empty(t, route.GroupBy)
+	require.Equal(t, &groupWait, route.GroupWait)
+	require.Nil(t, route.GroupInterval)
+	require.Equal(t, &repeatInterval, route.RepeatInterval)
+}
+
+func TestAlertRuleRelativeTimeRange(t *testing.T) {
+	parse := func(t *testing.T, from string) (AlertRule, error) {
+		t.Helper()
+		var rule AlertRule
+		err := json.Unmarshal([]byte(`{"title":"rule","data":[{"refId":"A","relativeTimeRange":{"from":`+from+`,"to":0},"model":{"expr":"up"}}]}`), &rule)
+		return rule, err
+	}
+
+	for _, tc := range []struct {
+		from     string
+		expected time.Duration
+		human    string
+	}{
+		{from: `"90s"`, expected: 90 * time.Second, human: `"1m30s"`},
+		{from: `"1h30m"`, expected: 90 * time.Minute, human: `"1h30m"`},
+		{from: `600`, expected: 10 * time.Minute, human: `"10m"`},
+	} {
+		t.Run(tc.from, func(t *testing.T) {
+			rule, err := parse(t, tc.from)
+			require.NoError(t, err)
+			require.Equal(t, "rule", rule.Title)
+			require.Len(t, rule.Data, 1)
+			require.Equal(t, models.Duration(tc.expected), rule.Data[0].RelativeTimeRange.From)
+			require.JSONEq(t, `{"expr":"up"}`, string(rule.Data[0].Model))
+
+			out, err := json.Marshal(rule)
+			require.NoError(t, err)
+			require.Contains(t, string(out), fmt.Sprintf(`"relativeTimeRange":{"from":%d,"to":0}`, int64(tc.expected.Seconds())))
+
+			rule.DurationFormat = DurationFormatHuman
+			out, err = json.Marshal(rule)
+			require.NoError(t, err)
+			require.Contains(t, string(out), `"relativeTimeRange":{"from":`+tc.human+`,"to":"0s"}`)
+
+			roundTripped, err := parse(t, tc.human)
+			require.NoError(t, err)
+			require.Equal(t, rule.Data[0].RelativeTimeRange, roundTripped.Data[0].RelativeTimeRange)
+		})
+	}
+
+	t.Run("invalid duration strings are rejected", func(t *testing.T) {
+		for _, from := range []string{`"ten minutes"`, `"10"`, `"-5m"`, `true`} {
+			_, err := parse(t, from)
+			require.Error(t, err, from)
+		}
+	})
+
+	t.Run("exported durations are read from seconds and written as duration strings", func(t *testing.T) {
+		var timeRange RelativeTimeRangeExport
+		require.NoError(t, yaml.Unmarshal([]byte("from: 5400\nto: 90s\n"), &timeRange))
+		require.Equal(t, RelativeTimeRangeExport{From: ExportDuration(90 * time.Minute), To: ExportDuration(90 * time.Second)}, timeRange)
+
+		out, err := yaml.Marshal(timeRange)
+		require.NoError(t, err)
+		require.Equal(t, "from: 1h30m\nto: 1m30s\n", string(out))
+
+		require.Error(t, yaml.Unmarshal([]byte("from: ten minutes\n"), &timeRange))
+	})
+	t.Run("duration format is parsed", func(t *testing.T) {
+		format, err := ParseDurationFormat("")
+		require.NoError(t, err)
+		require.Equal(t, DurationFormatSeconds, format)
+		format, err = ParseDurationFormat("human")
+		require.NoError(t, err)
+		require.Equal(t, DurationFormatHuman, format)
+		_, err = ParseDurationFormat("minutes")
+		require.Error(t, err)
+	})
+}
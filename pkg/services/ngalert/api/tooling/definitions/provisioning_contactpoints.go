@@ -48,6 +48,26 @@ import (
 //       202: Ack
 //       400: ValidationError
 
+// swagger:parameters RouteGetContactpoints
+type ContactPointParams struct {
+	// Filter by the exact name of the contact point.
+	// in:query
+	// required:false
+	Name string `json:"name"`
+	// Filter by a part of the name of the contact point, ignoring case.
+	// in:query
+	// required:false
+	Search string `json:"search"`
+	// Filter by the integration type of the contact point, e.g. slack or email. Can be repeated.
+	// in:query
+	// required:false
+	Type []string `json:"type"`
+	// Only return provisioned contact points.
+	// in:query
+	// required:false
+	Provisioned bool `json:"provisioned"`
+}
+
 // swagger:parameters RoutePutContactpoint RouteDeleteContactpoints
 type ContactPointUIDReference struct {
 	// ContactPointUID should be the contact point UID identifier
@@ -0,0 +1,177 @@
+package definitions
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"gopkg.in/yaml.v3"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+// DurationFormat is the format of the relative time ranges of queries in responses of the provisioning API.
+type DurationFormat string
+
+const (
+	// DurationFormatSeconds writes durations as numbers of seconds, e.g. 5400.
+	DurationFormatSeconds DurationFormat = "seconds"
+	// DurationFormatHuman writes durations as duration strings, e.g. "1h30m".
+	DurationFormatHuman DurationFormat = "human"
+)
+
+// ParseDurationFormat parses a duration format. The empty format is DurationFormatSeconds.
+func ParseDurationFormat(s string) (DurationFormat, error) {
+	switch DurationFormat(s) {
+	case "", DurationFormatSeconds:
+		return DurationFormatSeconds, nil
+	case DurationFormatHuman:
+		return DurationFormatHuman, nil
+	default:
+		return "", fmt.Errorf("unknown duration format '%s', must be '%s' or '%s'", s, DurationFormatSeconds, DurationFormatHuman)
+	}
+}
+
+// parseDuration parses a duration of the provisioning API, which is either a number of seconds or a
+// duration string like "1h30m".
+func parseDuration(v interface{}) (time.Duration, error) {
+	switch value := v.(type) {
+	case float64:
+		return time.Duration(value) * time.Second, nil
+	case int:
+		return time.Duration(value) * time.Second, nil
+	case string:
+		d, err := model.ParseDuration(value)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration '%s': %w", value, err)
+		}
+		return time.Duration(d), nil
+	default:
+		return 0, fmt.Errorf("invalid duration %v", v)
+	}
+}
+
+// ExportDuration is a duration in exported files and provisioning files. It is read from a number
+// of seconds or a duration string like "1h30m", and always written as a duration string.
+type ExportDuration time.Duration
+
+func (d ExportDuration) String() string {
+	return model.Duration(d).String()
+}
+
+func (d ExportDuration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *ExportDuration) UnmarshalJSON(b []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	value, err := parseDuration(v)
+	*d = ExportDuration(value)
+	return err
+}
+
+func (d ExportDuration) MarshalYAML() (interface{}, error) {
+	return d.String(), nil
+}
+
+// UnmarshalYAML reads the duration from a YAML node, and reports the line of the node if it is not
+// a valid duration.
+func (d *ExportDuration) UnmarshalYAML(node *yaml.Node) error {
+	var v interface{}
+	if err := node.Decode(&v); err != nil {
+		return err
+	}
+	value, err := parseDuration(v)
+	if err != nil {
+		return fmt.Errorf("line %d: %w", node.Line, err)
+	}
+	*d = ExportDuration(value)
+	return nil
+}
+
+// provisionedDuration is a duration of the provisioning API. It is read like an ExportDuration and
+// written in its format.
+type provisionedDuration struct {
+	value  models.Duration
+	format DurationFormat
+}
+
+func (d provisionedDuration) MarshalJSON() ([]byte, error) {
+	if d.format == DurationFormatHuman {
+		return json.Marshal(ExportDuration(d.value).String())
+	}
+	return json.Marshal(d.value)
+}
+
+func (d *provisionedDuration) UnmarshalJSON(b []byte) error {
+	var value ExportDuration
+	if err := value.UnmarshalJSON(b); err != nil {
+		return err
+	}
+	d.value = models.Duration(value)
+	return nil
+}
+
+type provisionedRelativeTimeRange struct {
+	From provisionedDuration `json:"from"`
+	To   provisionedDuration `json:"to"`
+}
+
+// provisionedAlertQuery is an alert query whose relative time range is a pair of provisionedDurations.
+type provisionedAlertQuery struct {
+	models.AlertQuery
+	RelativeTimeRange provisionedRelativeTimeRange `json:"relativeTimeRange"`
+}
+
+// alertRule has the fields of AlertRule without its methods.
+type alertRule AlertRule
+
+// alertRuleJSON is the JSON representation of an AlertRule in the provisioning API.
+type alertRuleJSON struct {
+	alertRule
+	Data []provisionedAlertQuery `json:"data"`
+}
+
+// MarshalJSON writes the relative time ranges of the queries of the rule in its DurationFormat.
+func (a AlertRule) MarshalJSON() ([]byte, error) {
+	result := alertRuleJSON{alertRule: alertRule(a)}
+	if a.Data != nil {
+		result.Data = make([]provisionedAlertQuery, 0, len(a.Data))
+	}
+	for _, query := range a.Data {
+		result.Data = append(result.Data, provisionedAlertQuery{
+			AlertQuery: query,
+			RelativeTimeRange: provisionedRelativeTimeRange{
+				From: provisionedDuration{value: query.RelativeTimeRange.From, format: a.DurationFormat},
+				To:   provisionedDuration{value: query.RelativeTimeRange.To, format: a.DurationFormat},
+			},
+		})
+	}
+	return json.Marshal(result)
+}
+
+// UnmarshalJSON reads the relative time ranges of the queries of the rule as numbers of seconds
+// or as duration strings.
+func (a *AlertRule) UnmarshalJSON(b []byte) error {
+	var result alertRuleJSON
+	if err := json.Unmarshal(b, &result); err != nil {
+		return err
+	}
+	*a = AlertRule(result.alertRule)
+	a.Data = nil
+	if result.Data != nil {
+		a.Data = make([]models.AlertQuery, 0, len(result.Data))
+	}
+	for _, query := range result.Data {
+		query.AlertQuery.RelativeTimeRange = models.RelativeTimeRange{
+			From: query.RelativeTimeRange.From.value,
+			To:   query.RelativeTimeRange.To.value,
+		}
+		a.Data = append(a.Data, query.AlertQuery)
+	}
+	return nil
+}
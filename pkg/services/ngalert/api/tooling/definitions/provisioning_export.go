@@ -0,0 +1,134 @@
+package definitions
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+// AlertRuleGroupExport is the representation of a rule group in exported files.
+// AlertingFileExport is the file provisioning format of exported rule groups.
+type AlertingFileExport struct {
+	APIVersion int64                  `json:"apiVersion" yaml:"apiVersion"`
+	Groups     []AlertRuleGroupExport `json:"groups" yaml:"groups"`
+}
+
+type AlertRuleGroupExport struct {
+	OrgID    int64             `json:"orgId" yaml:"orgId"`
+	Name     string            `json:"name" yaml:"name"`
+	Folder   string            `json:"folder" yaml:"folder"`
+	Interval ExportDuration    `json:"interval" yaml:"interval"`
+	Labels   map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	Rules    []AlertRuleExport `json:"rules" yaml:"rules"`
+}
+
+// AlertRuleExport is the representation of an alert rule in exported files.
+type AlertRuleExport struct {
+	UID                  string                       `json:"uid" yaml:"uid"`
+	Title                string                       `json:"title" yaml:"title"`
+	Condition            string                       `json:"condition" yaml:"condition"`
+	Data                 []AlertQueryExport           `json:"data" yaml:"data"`
+	DashboardUID         *string                      `json:"dashboardUid,omitempty" yaml:"dashboardUid,omitempty"`
+	PanelID              *int64                       `json:"panelId,omitempty" yaml:"panelId,omitempty"`
+	NoDataState          models.NoDataState           `json:"noDataState" yaml:"noDataState"`
+	ExecErrState         models.ExecutionErrorState   `json:"execErrState" yaml:"execErrState"`
+	For                  ExportDuration               `json:"for" yaml:"for"`
+	Annotations          map[string]string            `json:"annotations,omitempty" yaml:"annotations,omitempty"`
+	Labels               map[string]string            `json:"labels,omitempty" yaml:"labels,omitempty"`
+	NotificationSettings *AlertRuleNotificationExport `json:"notificationSettings,omitempty" yaml:"notificationSettings,omitempty"`
+	ActiveWindow         *models.ActiveWindow         `json:"activeWindow,omitempty" yaml:"activeWindow,omitempty"`
+	// ProvisioningSource is the file that the rule is provisioned from. It is only exported if
+	// requested and is ignored when the rule is imported.
+	ProvisioningSource *models.ProvisioningSource `json:"provisioningSource,omitempty" yaml:"provisioningSource,omitempty"`
+}
+
+// UpstreamModel returns the alert rule of the rule group that the export describes.
+func (r AlertRuleExport) UpstreamModel(group AlertRuleGroupExport) (models.AlertRule, error) {
+	data := make([]models.AlertQuery, 0, len(r.Data))
+	for _, query := range r.Data {
+		queryModel, err := json.Marshal(query.Model)
+		if err != nil {
+			return models.AlertRule{}, fmt.Errorf("invalid model of query %s: %w", query.RefID, err)
+		}
+		data = append(data, models.AlertQuery{
+			RefID:     query.RefID,
+			QueryType: query.QueryType,
+			RelativeTimeRange: models.RelativeTimeRange{
+				From: models.Duration(query.RelativeTimeRange.From),
+				To:   models.Duration(query.RelativeTimeRange.To),
+			},
+			DatasourceUID: query.DatasourceUID,
+			Model:         queryModel,
+		})
+	}
+	result := models.AlertRule{
+		OrgID:           group.OrgID,
+		UID:             r.UID,
+		Title:           r.Title,
+		Condition:       r.Condition,
+		Data:            data,
+		IntervalSeconds: int64(time.Duration(group.Interval).Seconds()),
+		NamespaceUID:    group.Folder,
+		RuleGroup:       group.Name,
+		DashboardUID:    r.DashboardUID,
+		PanelID:         r.PanelID,
+		NoDataState:     r.NoDataState,
+		ExecErrState:    r.ExecErrState,
+		For:             time.Duration(r.For),
+		Annotations:     r.Annotations,
+		Labels:          r.Labels,
+		GroupLabels:     group.Labels,
+		ActiveWindow:    r.ActiveWindow,
+	}
+	if r.NotificationSettings != nil {
+		result.NotificationSettings = []models.NotificationSettings{{
+			ReceiverName:   r.NotificationSettings.Receiver,
+			GroupBy:        r.NotificationSettings.GroupBy,
+			GroupWait:      r.NotificationSettings.GroupWait,
+			GroupInterval:  r.NotificationSettings.GroupInterval,
+			RepeatInterval: r.NotificationSettings.RepeatInterval,
+		}}
+	}
+	return result, nil
+}
+
+// AlertQueryExport is the representation of a query of an alert rule in exported files.
+type AlertQueryExport struct {
+	RefID             string                  `json:"refId" yaml:"refId"`
+	QueryType         string                  `json:"queryType,omitempty" yaml:"queryType,omitempty"`
+	RelativeTimeRange RelativeTimeRangeExport `json:"relativeTimeRange,omitempty" yaml:"relativeTimeRange,omitempty"`
+	DatasourceUID     string                  `json:"datasourceUid" yaml:"datasourceUid"`
+	Model             map[string]interface{}  `json:"model" yaml:"model"`
+}
+
+// RelativeTimeRangeExport is the representation of the relative time range of a query in exported files.
+type RelativeTimeRangeExport struct {
+	From ExportDuration `json:"from" yaml:"from"`
+	To   ExportDuration `json:"to" yaml:"to"`
+}
+
+// AlertRuleNotificationExport is the representation of the notification settings of an alert rule in exported files.
+type AlertRuleNotificationExport struct {
+	Receiver       string          `json:"receiver" yaml:"receiver"`
+	GroupBy        []string        `json:"group_by,omitempty" yaml:"group_by,omitempty"`
+	GroupWait      *model.Duration `json:"group_wait,omitempty" yaml:"group_wait,omitempty"`
+	GroupInterval  *model.Duration `json:"group_interval,omitempty" yaml:"group_interval,omitempty"`
+	RepeatInterval *model.Duration `json:"repeat_interval,omitempty" yaml:"repeat_interval,omitempty"`
+}
+
+// ExportIndex lists the content of an export archive.
+type ExportIndex struct {
+	Groups []ExportIndexEntry `json:"groups" yaml:"groups"`
+}
+
+// ExportIndexEntry describes a single rule group file of an export archive.
+type ExportIndexEntry struct {
+	Folder string `json:"folder" yaml:"folder"`
+	Group  string `json:"group" yaml:"group"`
+	Path   string `json:"path" yaml:"path"`
+	Rules  int    `json:"rules" yaml:"rules"`
+}
@@ -43,6 +43,7 @@ type NGAlert struct {
 	stateMetrics                *State
 	multiOrgAlertmanagerMetrics *MultiOrgAlertmanager
 	apiMetrics                  *API
+	provisioningMetrics         *Provisioning
 }
 
 type Scheduler struct {
@@ -70,6 +71,11 @@ type API struct {
 	RequestDuration *prometheus.HistogramVec
 }
 
+type Provisioning struct {
+	ThrottledChanges *prometheus.CounterVec
+	BrokenResources  *prometheus.GaugeVec
+}
+
 type Alertmanager struct {
 	Registerer prometheus.Registerer
 	*metrics.Alerts
@@ -96,6 +102,10 @@ func (ng *NGAlert) GetMultiOrgAlertmanagerMetrics() *MultiOrgAlertmanager {
 	return ng.multiOrgAlertmanagerMetrics
 }
 
+func (ng *NGAlert) GetProvisioningMetrics() *Provisioning {
+	return ng.provisioningMetrics
+}
+
 // NewNGAlert manages the metrics of all the alerting components.
 func NewNGAlert(r prometheus.Registerer) *NGAlert {
 	return &NGAlert{
@@ -104,6 +114,7 @@ func NewNGAlert(r prometheus.Registerer) *NGAlert {
 		stateMetrics:                newStateMetrics(r),
 		multiOrgAlertmanagerMetrics: newMultiOrgAlertmanagerMetrics(r),
 		apiMetrics:                  newAPIMetrics(r),
+		provisioningMetrics:         newProvisioningMetrics(r),
 	}
 }
 
@@ -268,6 +279,29 @@ func newAPIMetrics(r prometheus.Registerer) *API {
 	}
 }
 
+func newProvisioningMetrics(r prometheus.Registerer) *Provisioning {
+	return &Provisioning{
+		ThrottledChanges: promauto.With(r).NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: Namespace,
+				Subsystem: Subsystem,
+				Name:      "provisioning_throttled_changes_total",
+				Help:      "The number of changes made through provisioning that were rejected by the rate limit.",
+			},
+			[]string{"org"},
+		),
+		BrokenResources: promauto.With(r).NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: Namespace,
+				Subsystem: Subsystem,
+				Name:      "provisioning_broken_resources",
+				Help:      "The number of provisioned resources that reference data sources, templates or receivers that do not exist.",
+			},
+			[]string{"org"},
+		),
+	}
+}
+
 // OrgRegistries represents a map of registries per org.
 type OrgRegistries struct {
 	regsMu sync.Mutex
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/alertmanager/timeinterval"
+)
+
+// ErrInvalidActiveWindow is returned when the active window of a rule is invalid.
+var ErrInvalidActiveWindow = fmt.Errorf("%w: invalid active window", ErrAlertRuleFailedValidation)
+
+// StateReasonInactiveWindow is the state reason of the alert instances of rules that are not
+// evaluated because they are outside of their active window.
+const StateReasonInactiveWindow = "InactiveWindow"
+
+// ActiveWindow restricts the evaluation of an alert rule to weekdays and times of the day in a time
+// zone. Outside of its active window, a rule is not evaluated and its alert instances are Normal.
+type ActiveWindow struct {
+	// Times are the ranges of the time of day in which the rule is active, such as 09:00 to 17:00.
+	// The rule is active all day if there are none.
+	Times []timeinterval.TimeRange `json:"times,omitempty" yaml:"times,omitempty"`
+	// Weekdays are the ranges of days on which the rule is active, such as monday:friday.
+	// The rule is active on every day if there are none.
+	Weekdays []timeinterval.WeekdayRange `json:"weekdays,omitempty" yaml:"weekdays,flow,omitempty"`
+	// Location is the IANA name of the time zone of the times and weekdays. It is UTC if empty.
+	Location string `json:"location,omitempty" yaml:"location,omitempty"`
+}
+
+// locations caches the loaded time zones by their names, since loading them reads the time zone database.
+var locations sync.Map
+
+func loadLocation(name string) (*time.Location, error) {
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	locations.Store(name, loc)
+	return loc, nil
+}
+
+// Validate returns ErrInvalidActiveWindow if the window has an unknown time zone or invalid ranges.
+func (w *ActiveWindow) Validate() error {
+	if w == nil {
+		return nil
+	}
+	if _, err := loadLocation(w.Location); err != nil {
+		return fmt.Errorf("%w: unknown time zone '%s', it must be an IANA time zone name such as 'Europe/Berlin'", ErrInvalidActiveWindow, w.Location)
+	}
+	for _, r := range w.Times {
+		if r.StartMinute < 0 || r.EndMinute > 24*60 || r.StartMinute >= r.EndMinute {
+			return fmt.Errorf("%w: time range from minute %d to %d of the day is not valid", ErrInvalidActiveWindow, r.StartMinute, r.EndMinute)
+		}
+	}
+	for _, r := range w.Weekdays {
+		if r.Begin < 0 || r.End > 6 || r.Begin > r.End {
+			return fmt.Errorf("%w: weekday range from %d to %d is not valid", ErrInvalidActiveWindow, r.Begin, r.End)
+		}
+	}
+	return nil
+}
+
+// Contains returns true if the rule is active at the time. Rules without an active window are always active.
+func (w *ActiveWindow) Contains(t time.Time) bool {
+	if w == nil {
+		return true
+	}
+	loc, err := loadLocation(w.Location)
+	if err != nil {
+		// windows are validated when they are stored, so this is only a safeguard against
+		// time zones that were removed from the database since.
+		return true
+	}
+	return timeinterval.TimeInterval{Times: w.Times, Weekdays: w.Weekdays}.ContainsTime(t.In(loc))
+}
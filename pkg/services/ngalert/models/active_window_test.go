@@ -0,0 +1,50 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestActiveWindow(t *testing.T) {
+	var window ActiveWindow
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"times": [{"start_time": "09:00", "end_time": "17:00"}],
+		"weekdays": ["monday:friday"],
+		"location": "America/New_York"
+	}`), &window))
+	require.NoError(t, window.Validate())
+
+	t.Run("should contain the times in its time zone", func(t *testing.T) {
+		// Wednesday, 2022-06-01, is in daylight saving time, which is 4 hours behind UTC in New York.
+		require.True(t, window.Contains(time.Date(2022, 6, 1, 13, 0, 0, 0, time.UTC)))
+		require.True(t, window.Contains(time.Date(2022, 6, 1, 20, 59, 0, 0, time.UTC)))
+		require.False(t, window.Contains(time.Date(2022, 6, 1, 12, 59, 0, 0, time.UTC)))
+		require.False(t, window.Contains(time.Date(2022, 6, 1, 21, 0, 0, 0, time.UTC)))
+		// Saturday
+		require.False(t, window.Contains(time.Date(2022, 6, 4, 15, 0, 0, 0, time.UTC)))
+	})
+	t.Run("rules without window should always be active", func(t *testing.T) {
+		var none *ActiveWindow
+		require.NoError(t, none.Validate())
+		require.True(t, none.Contains(time.Date(2022, 6, 4, 3, 0, 0, 0, time.UTC)))
+	})
+	t.Run("should round-trip through YAML", func(t *testing.T) {
+		out, err := yaml.Marshal(window)
+		require.NoError(t, err)
+		var parsed ActiveWindow
+		require.NoError(t, yaml.Unmarshal(out, &parsed))
+		require.Equal(t, window, parsed)
+	})
+	t.Run("should reject unknown time zones with their name", func(t *testing.T) {
+		invalid := window
+		invalid.Location = "Europe/Atlantis"
+		err := invalid.Validate()
+		require.ErrorIs(t, err, ErrInvalidActiveWindow)
+		require.ErrorIs(t, err, ErrAlertRuleFailedValidation)
+		require.Contains(t, err.Error(), "'Europe/Atlantis'")
+	})
+}
@@ -77,9 +77,20 @@ type AlertQuery struct {
 	// JSON is the raw JSON query and includes the above properties as well as custom properties.
 	Model json.RawMessage `json:"model"`
 
+	// LibraryQuery optionally references a library query that provides the data source and model of the query.
+	LibraryQuery *LibraryQueryReference `json:"libraryQuery,omitempty"`
+
 	modelProps map[string]interface{}
 }
 
+// LibraryQueryReference references a library query from a query of an alert rule.
+type LibraryQueryReference struct {
+	UID string `json:"uid"`
+	// Live keeps the reference after the library query is copied into the rule,
+	// so that later changes of the library query can be synced into the rule.
+	Live bool `json:"live,omitempty"`
+}
+
 func (aq *AlertQuery) setModelProps() error {
 	aq.modelProps = make(map[string]interface{})
 	err := json.Unmarshal(aq.Model, &aq.modelProps)
@@ -4,10 +4,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/prometheus/common/model"
 
 	"github.com/grafana/grafana/pkg/util/cmputil"
 )
@@ -22,8 +26,13 @@ var (
 	ErrRuleGroupNamespaceNotFound         = errors.New("rule group not found under this namespace")
 	ErrAlertRuleFailedValidation          = errors.New("invalid alert rule")
 	ErrAlertRuleUniqueConstraintViolation = errors.New("a conflicting alert rule is found: rule title under the same organisation and folder should be unique")
+	ErrAlertRuleDuplicateTitle            = errors.New("an alert rule with the same title already exists in the folder")
+	ErrInvalidSortField                   = errors.New("invalid sort field")
 )
 
+// DefaultEvalEveryN is the evaluation sampling of rules that do not set one. They are evaluated on every tick.
+const DefaultEvalEveryN = 1
+
 type NoDataState string
 
 func (noDataState NoDataState) String() string {
@@ -74,24 +83,74 @@ const (
 	OkErrState       ExecutionErrorState = "OK"
 )
 
+// The evaluation strategies of rule groups decide how the scheduler handles rules of a group
+// that fail to evaluate.
+const (
+	// EvalStrategyIndependent evaluates each rule of the group on its own.
+	EvalStrategyIndependent = "independent"
+	// EvalStrategyAllSuccess holds the pending alerts of all rules of the group while any rule of the group fails.
+	EvalStrategyAllSuccess = "all_success"
+	// EvalStrategyAnySuccess ignores the failures of rules of the group as long as at least one rule
+	// of the group succeeds, so that the alerts of the group fire if at least one rule fires.
+	EvalStrategyAnySuccess = "any_success"
+)
+
+// ValidateEvalStrategy returns an error if the strategy is not a known evaluation strategy of rule groups.
+func ValidateEvalStrategy(strategy string) error {
+	switch strategy {
+	case EvalStrategyIndependent, EvalStrategyAllSuccess, EvalStrategyAnySuccess:
+		return nil
+	default:
+		return fmt.Errorf("unknown evaluation strategy %s", strategy)
+	}
+}
+
 const (
 	RuleUIDLabel      = "__alert_rule_uid__"
 	NamespaceUIDLabel = "__alert_rule_namespace_uid__"
 
+	// The meta-labels are added to every alert instance to give the context of its rule.
+	OrgIDMetaLabel        = "__org_id__"
+	RuleUIDMetaLabel      = "__rule_uid__"
+	RuleGroupMetaLabel    = "__rule_group__"
+	NamespaceUIDMetaLabel = "__namespace_uid__"
+	// ExportedLabelPrefix is prepended to the name of labels of alert instances that have the name of a
+	// meta-label, so that they are kept next to the meta-label.
+	ExportedLabelPrefix = "exported_"
+
 	// Annotations are actually a set of labels, so technically this is the label name of an annotation.
 	DashboardUIDAnnotation = "__dashboardUid__"
 	PanelIDAnnotation      = "__panelId__"
 
+	// DescriptionAnnotation and RunbookURLAnnotation are the well-known annotations
+	// that hold the description and the runbook URL of a rule.
+	DescriptionAnnotation = "description"
+	RunbookURLAnnotation  = "runbook_url"
+
 	// This isn't a hard-coded secret token, hence the nolint.
 	//nolint:gosec
 	ScreenshotTokenAnnotation = "__alertScreenshotToken__"
+
+	// TitleAnnotation holds the title template of a rule rendered with the labels of the alert instance.
+	TitleAnnotation = "__alertTitle__"
 )
 
 var (
 	// InternalLabelNameSet are labels that grafana automatically include as part of the labelset.
 	InternalLabelNameSet = map[string]struct{}{
-		RuleUIDLabel:      {},
-		NamespaceUIDLabel: {},
+		RuleUIDLabel:          {},
+		NamespaceUIDLabel:     {},
+		OrgIDMetaLabel:        {},
+		RuleUIDMetaLabel:      {},
+		RuleGroupMetaLabel:    {},
+		NamespaceUIDMetaLabel: {},
+	}
+	// metaLabelNameSet are the meta-labels that grafana automatically include as part of the labelset.
+	metaLabelNameSet = map[string]struct{}{
+		OrgIDMetaLabel:        {},
+		RuleUIDMetaLabel:      {},
+		RuleGroupMetaLabel:    {},
+		NamespaceUIDMetaLabel: {},
 	}
 	InternalAnnotationNameSet = map[string]struct{}{
 		DashboardUIDAnnotation:    {},
@@ -102,9 +161,11 @@ var (
 
 // AlertRule is the model for alert rules in unified alerting.
 type AlertRule struct {
-	ID              int64 `xorm:"pk autoincr 'id'"`
-	OrgID           int64 `xorm:"org_id"`
-	Title           string
+	ID    int64 `xorm:"pk autoincr 'id'"`
+	OrgID int64 `xorm:"org_id"`
+	Title string
+	// TitleLower is the lowercased title that title searches use. The store maintains it on write.
+	TitleLower      string `xorm:"title_lower"`
 	Condition       string
 	Data            []AlertQuery
 	Updated         time.Time
@@ -115,21 +176,197 @@ type AlertRule struct {
 	DashboardUID    *string `xorm:"dashboard_uid"`
 	PanelID         *int64  `xorm:"panel_id"`
 	RuleGroup       string
-	NoDataState     NoDataState
-	ExecErrState    ExecutionErrorState
+	// RuleGroupIndex is the 1-based position of the rule within its rule group.
+	RuleGroupIndex int `xorm:"rule_group_idx"`
+	// EvalStrategy is the evaluation strategy of the rule group. Like the interval, it is the same for all rules of the group.
+	EvalStrategy string `xorm:"eval_strategy"`
+	// EvalOrder is the 1-based priority of the evaluation of the rule within its rule group. Rules with a lower
+	// order are evaluated first, and rules without an order are evaluated after the ordered ones.
+	EvalOrder    int `xorm:"eval_order"`
+	NoDataState  NoDataState
+	ExecErrState ExecutionErrorState
 	// ideally this field should have been apimodels.ApiDuration
 	// but this is currently not possible because of circular dependencies
 	For         time.Duration
 	Annotations map[string]string
 	Labels      map[string]string
+	// TitleTemplate is an optional text/template that is rendered with the labels of each alert
+	// instance when the rule is evaluated. The rendered title is sent with the notifications.
+	TitleTemplate string `xorm:"title_template"`
+	// NotificationSettings is either empty or contains exactly one element that
+	// overrides the notification policy tree for the alerts of this rule.
+	NotificationSettings []NotificationSettings `xorm:"notification_settings"`
+	// ActiveWindow optionally restricts the evaluation of the rule to some times of the week.
+	ActiveWindow *ActiveWindow `xorm:"active_window json"`
+	// ExpiresAt is optional and the time after which the rule is deleted. Rules without it never expire.
+	ExpiresAt *time.Time `xorm:"expires_at"`
+	// EvalOffsetSeconds shifts the evaluations of the rule within its interval. The rule is evaluated
+	// when the seconds since the Unix epoch minus the offset are a multiple of the interval.
+	EvalOffsetSeconds int64 `xorm:"eval_offset"`
+	// EvalEveryN makes the scheduler evaluate the rule only on every Nth tick of its interval, which reduces
+	// the load of rules that rarely need to be evaluated. It is at least 1, which evaluates the rule on every
+	// tick and is the default of new rules that do not set it.
+	EvalEveryN int `xorm:"eval_every_n"`
+	// WarmUpEvals is the number of the first evaluations of a new rule whose notifications are suppressed.
+	// The states of the alerts of these evaluations are recorded as usual.
+	WarmUpEvals int `xorm:"warm_up_evals"`
+	// GroupLabels are the labels of the rule group. They are the same for all rules of the group and are
+	// added to the labels of the rule, which win on conflicts. See EffectiveLabels.
+	GroupLabels map[string]string `xorm:"group_labels"`
+	// ManagedBy is optional and identifies the external controller that owns the rule. Unlike the
+	// provenance, it tells apart the controllers of rules that are provisioned the same way.
+	ManagedBy string `xorm:"managed_by"`
+	// Status is the outcome of the latest evaluation of the rule. It is stored in its own table and is only
+	// set when the rule is fetched by its UID.
+	Status AlertRuleStatus `xorm:"-"`
+}
+
+// NotificationSettings defines how the notifications of an alert rule are routed.
+type NotificationSettings struct {
+	ReceiverName string `json:"receiver"`
+	// GroupBy overrides the labels the notifications of the rule are grouped by.
+	// The special label '...' groups by all labels.
+	GroupBy []string `json:"group_by,omitempty"`
+	// GroupWait, GroupInterval and RepeatInterval override the timings of the notifications of the rule.
+	// Timings that are not set are inherited from the notification policy tree.
+	GroupWait      *model.Duration `json:"group_wait,omitempty"`
+	GroupInterval  *model.Duration `json:"group_interval,omitempty"`
+	RepeatInterval *model.Duration `json:"repeat_interval,omitempty"`
+}
+
+// GroupByAll is the group by label that groups notifications by all labels.
+const GroupByAll = "..."
+
+// Validate checks that the group by labels are valid label names that are not reserved for routing,
+// that the timings are positive, and that both are only used together with a receiver.
+func (s NotificationSettings) Validate() error {
+	if s.ReceiverName == "" {
+		if len(s.GroupBy) > 0 {
+			return errors.New("group_by requires a receiver")
+		}
+		if s.GroupWait != nil || s.GroupInterval != nil || s.RepeatInterval != nil {
+			return errors.New("notification timings require a receiver")
+		}
+		return nil
+	}
+	if s.GroupWait != nil && *s.GroupWait <= 0 {
+		return errors.New("group_wait must be positive")
+	}
+	if s.GroupInterval != nil && *s.GroupInterval <= 0 {
+		return errors.New("group_interval must be positive")
+	}
+	if s.RepeatInterval != nil && *s.RepeatInterval <= 0 {
+		return errors.New("repeat_interval must be positive")
+	}
+	seen := make(map[string]struct{}, len(s.GroupBy))
+	for _, label := range s.GroupBy {
+		if label == GroupByAll {
+			if len(s.GroupBy) > 1 {
+				return fmt.Errorf("group_by '%s' cannot be combined with other labels", GroupByAll)
+			}
+			continue
+		}
+		if !model.LabelName(label).IsValid() {
+			return fmt.Errorf("group_by label '%s' is not a valid label name", label)
+		}
+		// the alerts of a rule are routed by its internal labels, so they cannot be used for grouping.
+		if strings.HasPrefix(label, model.ReservedLabelPrefix) {
+			return fmt.Errorf("group_by label '%s' is reserved", label)
+		}
+		if _, ok := seen[label]; ok {
+			return fmt.Errorf("group_by label '%s' is used more than once", label)
+		}
+		seen[label] = struct{}{}
+	}
+	return nil
+}
+
+// SimplifiedPolicy is the notification policy of a single alert rule. It routes the alerts of the
+// rule to a receiver with optional grouping and timing overrides. Timings that are not set are
+// inherited from the parent policy.
+type SimplifiedPolicy struct {
+	ReceiverName   string
+	GroupBy        []string
+	GroupWait      *time.Duration
+	GroupInterval  *time.Duration
+	RepeatInterval *time.Duration
+}
+
+// Validate checks that the policy has a receiver, valid group by labels and positive timings.
+func (p SimplifiedPolicy) Validate() error {
+	if p.ReceiverName == "" {
+		return errors.New("receiver must be specified")
+	}
+	if err := (NotificationSettings{ReceiverName: p.ReceiverName, GroupBy: p.GroupBy}).Validate(); err != nil {
+		return err
+	}
+	if p.GroupWait != nil && *p.GroupWait <= 0 {
+		return errors.New("group_wait must be positive")
+	}
+	if p.GroupInterval != nil && *p.GroupInterval <= 0 {
+		return errors.New("group_interval must be positive")
+	}
+	if p.RepeatInterval != nil && *p.RepeatInterval <= 0 {
+		return errors.New("repeat_interval must be positive")
+	}
+	return nil
 }
 
 type SchedulableAlertRule struct {
 	Title           string
 	UID             string `xorm:"uid"`
 	OrgID           int64  `xorm:"org_id"`
+	NamespaceUID    string `xorm:"namespace_uid"`
+	RuleGroup       string
 	IntervalSeconds int64
-	Version         int64
+	EvalStrategy    string `xorm:"eval_strategy"`
+	EvalOrder       int    `xorm:"eval_order"`
+	// EvalOffsetSeconds shifts the evaluations of the rule within its interval.
+	EvalOffsetSeconds int64 `xorm:"eval_offset"`
+	// EvalEveryN makes the scheduler evaluate the rule only on every Nth tick of its interval.
+	EvalEveryN int `xorm:"eval_every_n"`
+	Version    int64
+}
+
+// GetGroupKey returns the identifier of the rule group of the rule.
+func (alertRule *SchedulableAlertRule) GetGroupKey() AlertRuleGroupKey {
+	return AlertRuleGroupKey{OrgID: alertRule.OrgID, NamespaceUID: alertRule.NamespaceUID, RuleGroup: alertRule.RuleGroup}
+}
+
+// MetaLabels returns the meta-labels of the alert instances of the rule.
+func (alertRule *AlertRule) MetaLabels() map[string]string {
+	return map[string]string{
+		OrgIDMetaLabel:        strconv.FormatInt(alertRule.OrgID, 10),
+		RuleUIDMetaLabel:      alertRule.UID,
+		RuleGroupMetaLabel:    alertRule.RuleGroup,
+		NamespaceUIDMetaLabel: alertRule.NamespaceUID,
+	}
+}
+
+// NextEvaluation returns the first time at or after now that the scheduler evaluates the rule. The
+// scheduler evaluates rules at whole seconds, when the seconds since the Unix epoch minus the offset
+// of the rule are a multiple of its interval times EvalEveryN. It returns the zero time for rules
+// without interval.
+func (alertRule *AlertRule) NextEvaluation(now time.Time) time.Time {
+	interval := alertRule.IntervalSeconds
+	if interval <= 0 {
+		return time.Time{}
+	}
+	if alertRule.EvalEveryN > 1 {
+		interval *= int64(alertRule.EvalEveryN)
+	}
+	seconds := now.Unix()
+	if now.Nanosecond() > 0 {
+		seconds++
+	}
+	rest := (seconds - alertRule.EvalOffsetSeconds) % interval
+	if rest < 0 {
+		rest += interval
+	}
+	if rest > 0 {
+		seconds += interval - rest
+	}
+	return time.Unix(seconds, 0).In(now.Location())
 }
 
 type LabelOption func(map[string]string)
@@ -144,9 +381,25 @@ func WithoutInternalLabels() LabelOption {
 	}
 }
 
-// GetLabels returns the labels specified as part of the alert rule.
+// EffectiveLabels returns the labels of the rule group merged with the labels of the rule. The labels
+// of the rule win on conflicts. The labels of the rule are returned as they are if the group has no labels.
+func (alertRule *AlertRule) EffectiveLabels() map[string]string {
+	if len(alertRule.GroupLabels) == 0 {
+		return alertRule.Labels
+	}
+	labels := make(map[string]string, len(alertRule.GroupLabels)+len(alertRule.Labels))
+	for k, v := range alertRule.GroupLabels {
+		labels[k] = v
+	}
+	for k, v := range alertRule.Labels {
+		labels[k] = v
+	}
+	return labels
+}
+
+// GetLabels returns the labels specified as part of the alert rule, including the labels of its rule group.
 func (alertRule *AlertRule) GetLabels(opts ...LabelOption) map[string]string {
-	labels := alertRule.Labels
+	labels := alertRule.EffectiveLabels()
 
 	for _, opt := range opts {
 		opt(labels)
@@ -194,6 +447,14 @@ func (k AlertRuleKey) String() string {
 	return fmt.Sprintf("{orgID: %d, UID: %s}", k.OrgID, k.UID)
 }
 
+// GetNotificationSettings returns the notification settings of the rule or nil if the rule does not override them.
+func (alertRule *AlertRule) GetNotificationSettings() *NotificationSettings {
+	if len(alertRule.NotificationSettings) == 0 {
+		return nil
+	}
+	return &alertRule.NotificationSettings[0]
+}
+
 // GetKey returns the alert definitions identifier
 func (alertRule *AlertRule) GetKey() AlertRuleKey {
 	return AlertRuleKey{OrgID: alertRule.OrgID, UID: alertRule.UID}
@@ -204,6 +465,25 @@ func (alertRule *AlertRule) GetGroupKey() AlertRuleGroupKey {
 	return AlertRuleGroupKey{OrgID: alertRule.OrgID, NamespaceUID: alertRule.NamespaceUID, RuleGroup: alertRule.RuleGroup}
 }
 
+// SortAlertRulesByGroupIndex sorts the rules by their position within their rule group. Rules without
+// a position are placed after the positioned ones. Rules are then sorted by title and UID, so that the
+// order does not depend on the order in which the rules were inserted or read from the database.
+func SortAlertRulesByGroupIndex(rules []*AlertRule) {
+	sort.Slice(rules, func(i, j int) bool {
+		a, b := rules[i], rules[j]
+		if a.RuleGroupIndex != b.RuleGroupIndex {
+			if a.RuleGroupIndex <= 0 || b.RuleGroupIndex <= 0 {
+				return b.RuleGroupIndex <= 0
+			}
+			return a.RuleGroupIndex < b.RuleGroupIndex
+		}
+		if a.Title != b.Title {
+			return a.Title < b.Title
+		}
+		return a.UID < b.UID
+	})
+}
+
 // GetKey returns the alert definitions identifier
 func (alertRule *SchedulableAlertRule) GetKey() AlertRuleKey {
 	return AlertRuleKey{OrgID: alertRule.OrgID, UID: alertRule.UID}
@@ -241,12 +521,17 @@ type AlertRuleVersion struct {
 	RuleUID          string `xorm:"rule_uid"`
 	RuleNamespaceUID string `xorm:"rule_namespace_uid"`
 	RuleGroup        string
+	RuleGroupIndex   int    `xorm:"rule_group_idx"`
+	EvalStrategy     string `xorm:"eval_strategy"`
+	EvalOrder        int    `xorm:"eval_order"`
+	EvalOffset       int64  `xorm:"eval_offset"`
 	ParentVersion    int64
 	RestoredFrom     int64
 	Version          int64
 
 	Created         time.Time
 	Title           string
+	TitleTemplate   string `xorm:"title_template"`
 	Condition       string
 	Data            []AlertQuery
 	IntervalSeconds int64
@@ -254,9 +539,16 @@ type AlertRuleVersion struct {
 	ExecErrState    ExecutionErrorState
 	// ideally this field should have been apimodels.ApiDuration
 	// but this is currently not possible because of circular dependencies
-	For         time.Duration
-	Annotations map[string]string
-	Labels      map[string]string
+	For                  time.Duration
+	Annotations          map[string]string
+	Labels               map[string]string
+	NotificationSettings []NotificationSettings `xorm:"notification_settings"`
+	ActiveWindow         *ActiveWindow          `xorm:"active_window json"`
+	ExpiresAt            *time.Time             `xorm:"expires_at"`
+	EvalEveryN           int                    `xorm:"eval_every_n"`
+	WarmUpEvals          int                    `xorm:"warm_up_evals"`
+	GroupLabels          map[string]string      `xorm:"group_labels"`
+	ManagedBy            string                 `xorm:"managed_by"`
 }
 
 // GetAlertRuleByUIDQuery is the query for retrieving/deleting an alert rule by UID and organisation ID.
@@ -282,14 +574,46 @@ type ListAlertRulesQuery struct {
 	ExcludeOrgs   []int64
 	RuleGroup     string
 
+	// RuleUIDs is optional and restricts the rules to the ones with these UIDs.
+	RuleUIDs []string
+
 	// DashboardUID and PanelID are optional and allow filtering rules
 	// to return just those for a dashboard and panel.
 	DashboardUID string
 	PanelID      int64
 
+	// SortBy is optional and lists the fields to sort the rules by, in order of precedence.
+	// Rules are sorted by ID after that. SortDesc reverses the order of all fields.
+	SortBy   []SortField
+	SortDesc bool
+
+	// TitleSearch is optional and matches rules whose title contains it, ignoring case.
+	// TitlePrefix restricts the matches to titles that start with it, which can use an index.
+	// Matching rules are sorted by title unless SortBy is set.
+	TitleSearch string
+	TitlePrefix bool
+
+	// ExpiredAt is optional and matches the rules that expire at or before it.
+	ExpiredAt *time.Time
+
+	// Limit and Offset are optional and page through the rules. A zero Limit returns all rules.
+	Limit  int
+	Offset int
+
 	Result []*AlertRule
 }
 
+// SortField is a field that alert rules can be sorted by.
+type SortField string
+
+const (
+	SortByTitle     SortField = "title"
+	SortByInterval  SortField = "interval"
+	SortByUpdatedAt SortField = "updated"
+	SortByGroup     SortField = "group"
+	SortByNamespace SortField = "namespace"
+)
+
 type GetAlertRulesForSchedulingQuery struct {
 	ExcludeOrgIDs []int64
 
@@ -311,6 +635,18 @@ type ListRuleGroupsQuery struct {
 	Result []string
 }
 
+// AmbiguousRuleGroup lists rule groups of a namespace whose names only differ by case or
+// surrounding whitespace. Databases with case-insensitive collations can confuse them.
+type AmbiguousRuleGroup struct {
+	NamespaceUID string
+	RuleGroups   []string
+}
+
+// NormalizeRuleGroupName returns the name that rule groups are compared by to detect ambiguous names.
+func NormalizeRuleGroupName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
 // ListOrgRuleGroupsQuery is the query for listing unique rule groups
 // for an organization
 type ListOrgRuleGroupsQuery struct {
@@ -366,6 +702,9 @@ func PatchPartialAlertRule(existingRule *AlertRule, ruleToPatch *AlertRule) {
 	if ruleToPatch.RuleGroup == "" {
 		ruleToPatch.RuleGroup = existingRule.RuleGroup
 	}
+	if ruleToPatch.RuleGroupIndex <= 0 {
+		ruleToPatch.RuleGroupIndex = existingRule.RuleGroupIndex
+	}
 	if ruleToPatch.ExecErrState == "" {
 		ruleToPatch.ExecErrState = existingRule.ExecErrState
 	}
@@ -375,4 +714,37 @@ func PatchPartialAlertRule(existingRule *AlertRule, ruleToPatch *AlertRule) {
 	if ruleToPatch.For == 0 {
 		ruleToPatch.For = existingRule.For
 	}
+	if ruleToPatch.EvalStrategy == "" {
+		ruleToPatch.EvalStrategy = existingRule.EvalStrategy
+	}
+	if ruleToPatch.EvalOrder <= 0 {
+		ruleToPatch.EvalOrder = existingRule.EvalOrder
+	}
+	if ruleToPatch.EvalEveryN <= 0 {
+		ruleToPatch.EvalEveryN = existingRule.EvalEveryN
+	}
+	if len(ruleToPatch.NotificationSettings) == 0 {
+		ruleToPatch.NotificationSettings = existingRule.NotificationSettings
+	}
+	if ruleToPatch.TitleTemplate == "" {
+		ruleToPatch.TitleTemplate = existingRule.TitleTemplate
+	}
+	if ruleToPatch.ActiveWindow == nil {
+		ruleToPatch.ActiveWindow = existingRule.ActiveWindow
+	}
+	if ruleToPatch.ExpiresAt == nil {
+		ruleToPatch.ExpiresAt = existingRule.ExpiresAt
+	}
+	if ruleToPatch.EvalOffsetSeconds == 0 {
+		ruleToPatch.EvalOffsetSeconds = existingRule.EvalOffsetSeconds
+	}
+	if len(ruleToPatch.GroupLabels) == 0 {
+		ruleToPatch.GroupLabels = existingRule.GroupLabels
+	}
+	if ruleToPatch.ManagedBy == "" {
+		ruleToPatch.ManagedBy = existingRule.ManagedBy
+	}
+	if ruleToPatch.WarmUpEvals == 0 {
+		ruleToPatch.WarmUpEvals = existingRule.WarmUpEvals
+	}
 }
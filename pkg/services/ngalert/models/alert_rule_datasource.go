@@ -0,0 +1,35 @@
+package models
+
+import (
+	"github.com/grafana/grafana/pkg/expr"
+)
+
+// AlertRuleDatasource links an alert rule to a data source that is queried by the rule.
+type AlertRuleDatasource struct {
+	ID            int64  `xorm:"pk autoincr 'id'"`
+	OrgID         int64  `xorm:"org_id"`
+	RuleUID       string `xorm:"rule_uid"`
+	DatasourceUID string `xorm:"datasource_uid"`
+}
+
+// A XORM interface that defines the used table for this struct.
+func (d *AlertRuleDatasource) TableName() string {
+	return "alert_rule_datasources"
+}
+
+// QueriedDatasourceUIDs returns the unique UIDs of the data sources that the queries use, without expressions.
+func QueriedDatasourceUIDs(data []AlertQuery) []string {
+	uids := make([]string, 0, len(data))
+	seen := make(map[string]struct{}, len(data))
+	for _, query := range data {
+		if query.DatasourceUID == "" || expr.IsDataSource(query.DatasourceUID) {
+			continue
+		}
+		if _, ok := seen[query.DatasourceUID]; ok {
+			continue
+		}
+		seen[query.DatasourceUID] = struct{}{}
+		uids = append(uids, query.DatasourceUID)
+	}
+	return uids
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// AlertRuleStatus is the outcome of the latest evaluation of an alert rule.
+type AlertRuleStatus string
+
+const (
+	// AlertRuleStatusOK means that all queries of the rule were evaluated successfully.
+	AlertRuleStatusOK AlertRuleStatus = "ok"
+	// AlertRuleStatusDegraded means that the rule was evaluated but some of its series had no data or failed.
+	AlertRuleStatusDegraded AlertRuleStatus = "degraded"
+	// AlertRuleStatusError means that the rule could not be evaluated.
+	AlertRuleStatusError AlertRuleStatus = "error"
+)
+
+// AlertRuleStatusEntry is the status of an alert rule that the scheduler stores after every evaluation.
+type AlertRuleStatusEntry struct {
+	ID             int64           `xorm:"pk autoincr 'id'"`
+	OrgID          int64           `xorm:"org_id"`
+	RuleUID        string          `xorm:"rule_uid"`
+	Status         AlertRuleStatus `xorm:"status"`
+	Error          string          `xorm:"error"`
+	LastEvaluation time.Time       `xorm:"last_evaluation"`
+}
+
+// A XORM interface that defines the used table for this struct.
+func (s *AlertRuleStatusEntry) TableName() string {
+	return "alert_rule_status"
+}
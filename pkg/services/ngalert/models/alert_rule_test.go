@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/common/model"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -80,6 +81,41 @@ func TestErrStateFromString(t *testing.T) {
 	})
 }
 
+func TestNotificationSettingsValidate(t *testing.T) {
+	for _, tc := range []struct {
+		desc     string
+		settings NotificationSettings
+		err      bool
+	}{
+		{desc: "receiver only", settings: NotificationSettings{ReceiverName: "receiver"}},
+		{desc: "group by labels", settings: NotificationSettings{ReceiverName: "receiver", GroupBy: []string{"alertname", "team"}}},
+		{desc: "group by all", settings: NotificationSettings{ReceiverName: "receiver", GroupBy: []string{GroupByAll}}},
+		{desc: "group by without receiver", settings: NotificationSettings{GroupBy: []string{"alertname"}}, err: true},
+		{desc: "invalid label name", settings: NotificationSettings{ReceiverName: "receiver", GroupBy: []string{"not-a-label"}}, err: true},
+		{desc: "duplicate label", settings: NotificationSettings{ReceiverName: "receiver", GroupBy: []string{"team", "team"}}, err: true},
+		{desc: "group by all with other labels", settings: NotificationSettings{ReceiverName: "receiver", GroupBy: []string{GroupByAll, "team"}}, err: true},
+		{desc: "reserved label", settings: NotificationSettings{ReceiverName: "receiver", GroupBy: []string{"alertname", RuleUIDLabel}}, err: true},
+		{desc: "timings", settings: NotificationSettings{ReceiverName: "receiver", GroupWait: durationPtr(time.Minute), GroupInterval: durationPtr(time.Hour), RepeatInterval: durationPtr(4 * time.Hour)}},
+		{desc: "timings without receiver", settings: NotificationSettings{GroupWait: durationPtr(time.Minute)}, err: true},
+		{desc: "zero group wait", settings: NotificationSettings{ReceiverName: "receiver", GroupWait: durationPtr(0)}, err: true},
+		{desc: "negative repeat interval", settings: NotificationSettings{ReceiverName: "receiver", RepeatInterval: durationPtr(-time.Hour)}, err: true},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := tc.settings.Validate()
+			if tc.err {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func durationPtr(d time.Duration) *model.Duration {
+	result := model.Duration(d)
+	return &result
+}
+
 func TestPatchPartialAlertRule(t *testing.T) {
 	t.Run("patches", func(t *testing.T) {
 		testCases := []struct {
@@ -105,6 +141,18 @@ func TestPatchPartialAlertRule(t *testing.T) {
 					r.ExecErrState = ""
 				},
 			},
+			{
+				name: "EvalEveryN is 0",
+				mutator: func(r *AlertRule) {
+					r.EvalEveryN = 0
+				},
+			},
+			{
+				name: "RuleGroupIndex is 0",
+				mutator: func(r *AlertRule) {
+					r.RuleGroupIndex = 0
+				},
+			},
 			{
 				name: "NoDataState is empty",
 				mutator: func(r *AlertRule) {
@@ -117,6 +165,60 @@ func TestPatchPartialAlertRule(t *testing.T) {
 					r.For = 0
 				},
 			},
+			{
+				name: "EvalStrategy is empty",
+				mutator: func(r *AlertRule) {
+					r.EvalStrategy = ""
+				},
+			},
+			{
+				name: "NotificationSettings are empty",
+				mutator: func(r *AlertRule) {
+					r.NotificationSettings = nil
+				},
+			},
+			{
+				name: "TitleTemplate is empty",
+				mutator: func(r *AlertRule) {
+					r.TitleTemplate = ""
+				},
+			},
+			{
+				name: "ActiveWindow is nil",
+				mutator: func(r *AlertRule) {
+					r.ActiveWindow = nil
+				},
+			},
+			{
+				name: "ExpiresAt is nil",
+				mutator: func(r *AlertRule) {
+					r.ExpiresAt = nil
+				},
+			},
+			{
+				name: "EvalOffsetSeconds is 0",
+				mutator: func(r *AlertRule) {
+					r.EvalOffsetSeconds = 0
+				},
+			},
+			{
+				name: "GroupLabels are empty",
+				mutator: func(r *AlertRule) {
+					r.GroupLabels = nil
+				},
+			},
+			{
+				name: "ManagedBy is empty",
+				mutator: func(r *AlertRule) {
+					r.ManagedBy = ""
+				},
+			},
+			{
+				name: "WarmUpEvals is 0",
+				mutator: func(r *AlertRule) {
+					r.WarmUpEvals = 0
+				},
+			},
 		}
 
 		for _, testCase := range testCases {
@@ -125,6 +227,15 @@ func TestPatchPartialAlertRule(t *testing.T) {
 				for {
 					existing = AlertRuleGen(func(rule *AlertRule) {
 						rule.For = time.Duration(rand.Int63n(1000) + 1)
+						rule.NotificationSettings = []NotificationSettings{{ReceiverName: util.GenerateShortUID()}}
+						rule.TitleTemplate = "{{ .Labels.instance }} " + util.GenerateShortUID()
+						rule.ActiveWindow = &ActiveWindow{Location: "Europe/Berlin"}
+						expiresAt := time.Now().Add(time.Hour)
+						rule.ExpiresAt = &expiresAt
+						rule.EvalOffsetSeconds = rand.Int63n(100) + 1
+						rule.GroupLabels = map[string]string{"team": util.GenerateShortUID()}
+						rule.ManagedBy = util.GenerateShortUID()
+						rule.WarmUpEvals = rand.Intn(10) + 1
 					})()
 					cloned := *existing
 					testCase.mutator(&cloned)
@@ -228,6 +339,82 @@ func TestPatchPartialAlertRule(t *testing.T) {
 	})
 }
 
+func TestAlertRuleNextEvaluation(t *testing.T) {
+	base := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	rule := AlertRule{IntervalSeconds: 60, EvalOffsetSeconds: 15}
+	testCases := []struct {
+		desc     string
+		now      time.Time
+		expected time.Time
+	}{
+		{desc: "before the offset", now: base, expected: base.Add(15 * time.Second)},
+		{desc: "at the tick", now: base.Add(15 * time.Second), expected: base.Add(15 * time.Second)},
+		{desc: "right after the tick", now: base.Add(15*time.Second + time.Millisecond), expected: base.Add(75 * time.Second)},
+		{desc: "after the offset", now: base.Add(40 * time.Second), expected: base.Add(75 * time.Second)},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			require.Equal(t, tc.expected, rule.NextEvaluation(tc.now))
+		})
+	}
+	t.Run("without offset ticks are aligned to the interval", func(t *testing.T) {
+		rule := AlertRule{IntervalSeconds: 60}
+		require.Equal(t, base.Add(time.Minute), rule.NextEvaluation(base.Add(time.Second)))
+	})
+	t.Run("sampled rules skip ticks", func(t *testing.T) {
+		rule := AlertRule{IntervalSeconds: 60, EvalEveryN: 3}
+		require.Equal(t, base.Add(3*time.Minute), rule.NextEvaluation(base.Add(time.Second)))
+		rule.EvalEveryN = 1
+		require.Equal(t, base.Add(time.Minute), rule.NextEvaluation(base.Add(time.Second)))
+	})
+	t.Run("keeps the location of now", func(t *testing.T) {
+		location := time.FixedZone("UTC+2", 2*60*60)
+		next := rule.NextEvaluation(base.In(location))
+		require.Equal(t, location, next.Location())
+		require.True(t, base.Add(15*time.Second).Equal(next))
+	})
+	t.Run("rules without interval have no evaluation", func(t *testing.T) {
+		require.True(t, (&AlertRule{}).NextEvaluation(base).IsZero())
+	})
+}
+
+func TestAlertRuleEffectiveLabels(t *testing.T) {
+	t.Run("rule labels win over the labels of the group", func(t *testing.T) {
+		rule := AlertRule{
+			Labels:      map[string]string{"severity": "page", "team": "a"},
+			GroupLabels: map[string]string{"severity": "info", "env": "prod"},
+		}
+		require.Equal(t, map[string]string{"severity": "page", "team": "a", "env": "prod"}, rule.EffectiveLabels())
+		require.Equal(t, map[string]string{"severity": "page", "team": "a"}, rule.Labels)
+	})
+	t.Run("without group labels the labels of the rule are returned", func(t *testing.T) {
+		rule := AlertRule{Labels: map[string]string{"team": "a"}, GroupLabels: map[string]string{}}
+		require.Equal(t, map[string]string{"team": "a"}, rule.EffectiveLabels())
+	})
+}
+
+func TestSortAlertRulesByGroupIndex(t *testing.T) {
+	rule := func(index int, title, uid string) *AlertRule {
+		return &AlertRule{RuleGroupIndex: index, Title: title, UID: uid}
+	}
+	expected := []*AlertRule{
+		rule(1, "b", "uid-1"),
+		rule(2, "a", "uid-2"),
+		rule(2, "b", "uid-3"),
+		rule(2, "b", "uid-4"),
+		rule(3, "a", "uid-5"),
+		rule(0, "a", "uid-6"),
+		rule(0, "c", "uid-7"),
+	}
+	for i := 0; i < 10; i++ {
+		rules := make([]*AlertRule, len(expected))
+		copy(rules, expected)
+		rand.Shuffle(len(rules), func(i, j int) { rules[i], rules[j] = rules[j], rules[i] })
+		SortAlertRulesByGroupIndex(rules)
+		require.Equal(t, expected, rules)
+	}
+}
+
 func TestDiff(t *testing.T) {
 	t.Run("should return nil if there is no diff", func(t *testing.T) {
 		rule1 := AlertRuleGen()()
@@ -333,6 +520,13 @@ func TestDiff(t *testing.T) {
 			assert.Equal(t, rule2.RuleGroup, diff[0].Right.String())
 			difCnt++
 		}
+		if rule1.RuleGroupIndex != rule2.RuleGroupIndex {
+			diff := diffs.GetDiffsForField("RuleGroupIndex")
+			assert.Len(t, diff, 1)
+			assert.Equal(t, rule1.RuleGroupIndex, diff[0].Left.Interface())
+			assert.Equal(t, rule2.RuleGroupIndex, diff[0].Right.Interface())
+			difCnt++
+		}
 		if rule1.NoDataState != rule2.NoDataState {
 			diff := diffs.GetDiffsForField("NoDataState")
 			assert.Len(t, diff, 1)
@@ -517,6 +711,7 @@ func TestDiff(t *testing.T) {
 			query2.RefID = "test"
 			query2.DatasourceUID = "test"
 			query2.Model = json.RawMessage(`{ "test": "da2ta"}`)
+			query2.LibraryQuery = &LibraryQueryReference{UID: "test"}
 
 			rule2.Data = []AlertQuery{query2}
 
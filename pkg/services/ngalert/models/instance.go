@@ -16,6 +16,9 @@ type AlertInstance struct {
 	CurrentStateSince time.Time
 	CurrentStateEnd   time.Time
 	LastEvalTime      time.Time
+	// MissingSeriesLabels are the label sets returned by the last evaluation of the rule that
+	// returned data, if the instance fires because no data was returned.
+	MissingSeriesLabels []map[string]string `xorm:"missing_series_labels"`
 }
 
 // InstanceStateType is an enum for instance states.
@@ -44,6 +47,37 @@ func (i InstanceStateType) IsValid() bool {
 		i == InstanceStateError
 }
 
+// AlertRuleStateSummary summarizes the persisted alert instances of an alert rule.
+type AlertRuleStateSummary struct {
+	// Instances is the number of alert instances of the rule per state.
+	Instances map[InstanceStateType]int64 `json:"instances"`
+	// LastEvaluation is the time of the latest evaluation of any instance of the rule.
+	LastEvaluation time.Time `json:"lastEvaluation"`
+	// LastError is the time of the latest evaluation of an instance that failed, either because the
+	// instance is in the Error state or because the error was mapped to another state. It is zero if
+	// none of the instances failed. The error messages themselves are not persisted.
+	LastError time.Time `json:"lastError,omitempty"`
+}
+
+const (
+	// SeverityLabel is the label that firing alerts are summarized by.
+	SeverityLabel = "severity"
+	// SeverityUnset is the severity that alerts without a severity label are summarized as.
+	SeverityUnset = "unset"
+)
+
+// AlertRuleStateChangedEvent is published on the event bus when an alert instance of a rule
+// changes its state.
+type AlertRuleStateChangedEvent struct {
+	OrgID         int64
+	RuleUID       string
+	PreviousState InstanceStateType
+	NewState      InstanceStateType
+	Labels        map[string]string
+	// FiredAt is the time of the evaluation that changed the state.
+	FiredAt time.Time
+}
+
 // SaveAlertInstanceCommand is the query for saving a new alert instance.
 type SaveAlertInstanceCommand struct {
 	RuleOrgID         int64
@@ -54,6 +88,8 @@ type SaveAlertInstanceCommand struct {
 	LastEvalTime      time.Time
 	CurrentStateSince time.Time
 	CurrentStateEnd   time.Time
+	// MissingSeriesLabels are the label sets that the evaluation did not return data for.
+	MissingSeriesLabels []map[string]string
 }
 
 // GetAlertInstanceQuery is the query for retrieving/deleting an alert definition by ID.
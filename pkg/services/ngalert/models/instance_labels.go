@@ -38,8 +38,10 @@ func (il *InstanceLabels) ToDB() ([]byte, error) {
 	return []byte{}, fmt.Errorf("database serialization of alerting ng Instance labels is not implemented")
 }
 
+// StringKey returns the key of the alert instance with the labels. The meta-labels are left out of the
+// key, see keyLabels.
 func (il *InstanceLabels) StringKey() (string, error) {
-	tl := labelsToTupleLabels(*il)
+	tl := labelsToTupleLabels(il.keyLabels())
 	b, err := json.Marshal(tl)
 	if err != nil {
 		return "", fmt.Errorf("can not gereate key due to failure to encode labels: %w", err)
@@ -48,7 +50,7 @@ func (il *InstanceLabels) StringKey() (string, error) {
 }
 
 // StringAndHash returns a the json representation of the labels as tuples
-// sorted by key. It also returns the a hash of that representation.
+// sorted by key. It also returns the a hash of the key of the labels, see StringKey.
 func (il *InstanceLabels) StringAndHash() (string, string, error) {
 	tl := labelsToTupleLabels(*il)
 
@@ -57,14 +59,45 @@ func (il *InstanceLabels) StringAndHash() (string, string, error) {
 		return "", "", fmt.Errorf("can not gereate key for alert instance due to failure to encode labels: %w", err)
 	}
 
+	key, err := il.StringKey()
+	if err != nil {
+		return "", "", err
+	}
+
 	h := sha1.New()
-	if _, err := h.Write(b); err != nil {
+	if _, err := h.Write([]byte(key)); err != nil {
 		return "", "", err
 	}
 
 	return string(b), fmt.Sprintf("%x", h.Sum(nil)), nil
 }
 
+// keyLabels returns the labels that identify the alert instance. The meta-labels are derived from the rule,
+// so they are left out and the labels that were kept with a prefix next to them get their names back. That
+// way alert instances keep the keys they had before the meta-labels were added to them. Labels that do not
+// have all meta-labels were created before them and are returned as they are.
+func (il InstanceLabels) keyLabels() InstanceLabels {
+	for name := range metaLabelNameSet {
+		if _, ok := il[name]; !ok {
+			return il
+		}
+	}
+	key := make(InstanceLabels, len(il))
+	for name, value := range il {
+		if _, ok := metaLabelNameSet[name]; ok {
+			continue
+		}
+		key[name] = value
+	}
+	for name := range metaLabelNameSet {
+		if value, ok := il[ExportedLabelPrefix+name]; ok {
+			delete(key, ExportedLabelPrefix+name)
+			key[name] = value
+		}
+	}
+	return key
+}
+
 // The following is based on SDK code, copied for now
 
 // tupleLables is an alternative representation of Labels (map[string]string) that can be sorted
@@ -0,0 +1,49 @@
+package models
+
+import (
+	// nolint:gosec
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstanceLabelsKey(t *testing.T) {
+	// the key of the labels before the meta-labels were added to them.
+	withoutMetaLabels := InstanceLabels{
+		RuleUIDLabel:   "rule",
+		"instance":     "a",
+		OrgIDMetaLabel: "instance",
+	}
+	b, err := json.Marshal(labelsToTupleLabels(withoutMetaLabels))
+	require.NoError(t, err)
+	expectedKey := string(b)
+	expectedHash := fmt.Sprintf("%x", sha1.Sum(b))
+
+	withMetaLabels := InstanceLabels{
+		RuleUIDLabel:                         "rule",
+		"instance":                           "a",
+		OrgIDMetaLabel:                       "1",
+		ExportedLabelPrefix + OrgIDMetaLabel: "instance",
+		RuleUIDMetaLabel:                     "rule",
+		RuleGroupMetaLabel:                   "group",
+		NamespaceUIDMetaLabel:                "folder",
+	}
+
+	for name, labels := range map[string]InstanceLabels{"without meta-labels": withoutMetaLabels, "with meta-labels": withMetaLabels} {
+		t.Run(name, func(t *testing.T) {
+			key, err := labels.StringKey()
+			require.NoError(t, err)
+			require.Equal(t, expectedKey, key)
+
+			s, hash, err := labels.StringAndHash()
+			require.NoError(t, err)
+			require.Equal(t, expectedHash, hash)
+			stored := InstanceLabels{}
+			require.NoError(t, stored.FromDB([]byte(s)))
+			require.Equal(t, labels, stored, "the stored labels should keep the meta-labels")
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// RuleGroupIntervalLimits are the bounds of the evaluation interval of the rule groups of an organization.
+// The limits with OrgID 0 apply to all organizations that do not have limits of their own.
+// A bound of 0 means that the interval is not limited in that direction.
+type RuleGroupIntervalLimits struct {
+	ID                 int64 `xorm:"pk autoincr 'id'"`
+	OrgID              int64 `xorm:"org_id"`
+	MinIntervalSeconds int64 `xorm:"min_interval_seconds"`
+	MaxIntervalSeconds int64 `xorm:"max_interval_seconds"`
+}
+
+// A XORM interface that defines the used table for this struct.
+func (l *RuleGroupIntervalLimits) TableName() string {
+	return "alert_rule_group_interval_limits"
+}
+
+// Validate returns an error if the interval is outside of the limits.
+func (l *RuleGroupIntervalLimits) Validate(intervalSeconds int64) error {
+	source := fmt.Sprintf("the interval limits of organization %d", l.OrgID)
+	if l.OrgID == 0 {
+		source = "the default interval limits"
+	}
+	interval := time.Duration(intervalSeconds) * time.Second
+	if l.MinIntervalSeconds > 0 && intervalSeconds < l.MinIntervalSeconds {
+		return fmt.Errorf("interval %s is less than the minimum interval %s set by %s", interval, time.Duration(l.MinIntervalSeconds)*time.Second, source)
+	}
+	if l.MaxIntervalSeconds > 0 && intervalSeconds > l.MaxIntervalSeconds {
+		return fmt.Errorf("interval %s is greater than the maximum interval %s set by %s", interval, time.Duration(l.MaxIntervalSeconds)*time.Second, source)
+	}
+	return nil
+}
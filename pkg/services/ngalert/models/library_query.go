@@ -0,0 +1,51 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var (
+	ErrLibraryQueryNotFound = errors.New("library query not found")
+	ErrLibraryQueryInUse    = errors.New("library query is referenced by alert rules")
+)
+
+// LibraryQuery is a query that can be shared by the queries of many alert rules.
+type LibraryQuery struct {
+	ID            int64     `xorm:"pk autoincr 'id'"`
+	OrgID         int64     `xorm:"org_id"`
+	UID           string    `xorm:"uid"`
+	Title         string    `xorm:"title"`
+	DatasourceUID string    `xorm:"datasource_uid"`
+	QueryType     string    `xorm:"query_type"`
+	Model         string    `xorm:"model"`
+	Updated       time.Time `xorm:"updated"`
+}
+
+// A XORM interface that defines the used table for this struct.
+func (q *LibraryQuery) TableName() string {
+	return "alert_library_query"
+}
+
+// Materialize copies the data source and model of the library query into the query.
+// The reference is kept only if it is live.
+func (q *LibraryQuery) Materialize(query *AlertQuery) {
+	query.DatasourceUID = q.DatasourceUID
+	query.QueryType = q.QueryType
+	query.Model = json.RawMessage(q.Model)
+	query.modelProps = nil
+	if query.LibraryQuery != nil && !query.LibraryQuery.Live {
+		query.LibraryQuery = nil
+	}
+}
+
+// HasLiveLibraryQueryReference returns true if any query of the rule keeps a live reference to the library query.
+func (alertRule *AlertRule) HasLiveLibraryQueryReference(libraryQueryUID string) bool {
+	for _, query := range alertRule.Data {
+		if query.LibraryQuery != nil && query.LibraryQuery.Live && query.LibraryQuery.UID == libraryQueryUID {
+			return true
+		}
+	}
+	return false
+}
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type Provenance string
 
 const (
@@ -15,3 +17,13 @@ type Provisionable interface {
 	ResourceType() string
 	ResourceID() string
 }
+
+// ProvisioningSource is the file that a file-provisioned resource was last applied from.
+type ProvisioningSource struct {
+	// Path is the path of the provisioning file.
+	Path string `json:"path" yaml:"path"`
+	// Hash is the hex-encoded SHA-256 of the content of the file when it was applied.
+	Hash string `json:"hash" yaml:"hash"`
+	// AppliedAt is the time the resource was last applied from the file.
+	AppliedAt time.Time `json:"appliedAt" yaml:"appliedAt"`
+}
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ProvisioningJobType is the kind of work of a provisioning job.
+type ProvisioningJobType string
+
+const (
+	ProvisioningJobTypeImport ProvisioningJobType = "import"
+	ProvisioningJobTypeExport ProvisioningJobType = "export"
+)
+
+// ProvisioningJobStatus is the state of a provisioning job.
+type ProvisioningJobStatus string
+
+const (
+	// ProvisioningJobPending jobs wait for a free worker.
+	ProvisioningJobPending ProvisioningJobStatus = "pending"
+	// ProvisioningJobRunning jobs are run by a worker. Running jobs whose worker stopped are resumed.
+	ProvisioningJobRunning ProvisioningJobStatus = "running"
+	// ProvisioningJobCompleted jobs processed all of their items. Some items may have failed.
+	ProvisioningJobCompleted ProvisioningJobStatus = "completed"
+	// ProvisioningJobFailed jobs stopped because of an error that is not specific to an item.
+	ProvisioningJobFailed ProvisioningJobStatus = "failed"
+	// ProvisioningJobCanceled jobs were canceled before they were finished.
+	ProvisioningJobCanceled ProvisioningJobStatus = "canceled"
+)
+
+// Finished returns true if the job does not run anymore.
+func (s ProvisioningJobStatus) Finished() bool {
+	return s == ProvisioningJobCompleted || s == ProvisioningJobFailed || s == ProvisioningJobCanceled
+}
+
+// ProvisioningJobItemError is the failure of a single item of a provisioning job, such as a rule group.
+type ProvisioningJobItemError struct {
+	Item  string `json:"item"`
+	Error string `json:"error"`
+}
+
+// ProvisioningJob is an import or an export of alert rules that runs in the background. Its progress
+// is persisted, so that it can be reported and resumed by any instance.
+type ProvisioningJob struct {
+	ID     int64                 `xorm:"pk autoincr 'id'"`
+	UID    string                `xorm:"uid"`
+	OrgID  int64                 `xorm:"org_id"`
+	Type   ProvisioningJobType   `xorm:"type"`
+	Status ProvisioningJobStatus `xorm:"status"`
+	// Input is the serialized input of the job, e.g. the rule groups of an import.
+	Input string `xorm:"input"`
+	// Result is the output of finished exports.
+	Result []byte `xorm:"result"`
+	// Errors is the serialized list of the errors of the items of the job, see GetErrors.
+	Errors string `xorm:"errors"`
+	// Message describes why a job failed.
+	Message   string `xorm:"message"`
+	Processed int    `xorm:"processed"`
+	Total     int    `xorm:"total"`
+	// Version is incremented by every update, so that concurrent updates of a job are detected.
+	Version int64
+	Created time.Time `xorm:"created"`
+	Updated time.Time `xorm:"updated"`
+}
+
+// A XORM interface that defines the used table for this struct.
+func (j *ProvisioningJob) TableName() string {
+	return "alert_provisioning_job"
+}
+
+// GetErrors returns the errors of the items of the job.
+func (j *ProvisioningJob) GetErrors() ([]ProvisioningJobItemError, error) {
+	if j.Errors == "" {
+		return nil, nil
+	}
+	var result []ProvisioningJobItemError
+	if err := json.Unmarshal([]byte(j.Errors), &result); err != nil {
+		return nil, fmt.Errorf("failed to deserialize the errors of the provisioning job: %w", err)
+	}
+	return result, nil
+}
+
+// AddError adds the error of an item to the errors of the job.
+func (j *ProvisioningJob) AddError(item string, itemErr error) error {
+	errs, err := j.GetErrors()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(append(errs, ProvisioningJobItemError{Item: item, Error: itemErr.Error()}))
+	if err != nil {
+		return fmt.Errorf("failed to serialize the errors of the provisioning job: %w", err)
+	}
+	j.Errors = string(data)
+	return nil
+}
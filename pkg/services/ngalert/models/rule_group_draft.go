@@ -0,0 +1,47 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// RuleGroupDraft is a copy of a rule group that can be changed without affecting the live group,
+// and that replaces the live group once it is promoted. Drafts are not evaluated.
+type RuleGroupDraft struct {
+	ID           int64  `xorm:"pk autoincr 'id'"`
+	OrgID        int64  `xorm:"org_id"`
+	NamespaceUID string `xorm:"namespace_uid"`
+	RuleGroup    string `xorm:"rule_group"`
+	// IntervalSeconds is the evaluation interval the group gets when the draft is promoted.
+	IntervalSeconds int64  `xorm:"interval_seconds"`
+	Rules           string `xorm:"rules"`
+	// Owner is the login of the user who created the draft. It is empty for drafts created by the system.
+	Owner   string    `xorm:"owner"`
+	Created time.Time `xorm:"created"`
+	Updated time.Time `xorm:"updated"`
+}
+
+// A XORM interface that defines the used table for this struct.
+func (d *RuleGroupDraft) TableName() string {
+	return "alert_rule_group_draft"
+}
+
+// SetRules stores the rules in the draft.
+func (d *RuleGroupDraft) SetRules(rules []AlertRule) error {
+	data, err := json.Marshal(rules)
+	if err != nil {
+		return fmt.Errorf("failed to serialize the rules of the rule group draft: %w", err)
+	}
+	d.Rules = string(data)
+	return nil
+}
+
+// GetRules returns the rules stored in the draft.
+func (d *RuleGroupDraft) GetRules() ([]AlertRule, error) {
+	var rules []AlertRule
+	if err := json.Unmarshal([]byte(d.Rules), &rules); err != nil {
+		return nil, fmt.Errorf("failed to read the rules of the rule group draft: %w", err)
+	}
+	return rules, nil
+}
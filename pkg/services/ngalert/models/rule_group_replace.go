@@ -0,0 +1,64 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// RuleGroupReplaceOperationKind is the kind of change a RuleGroupReplaceOperation makes.
+type RuleGroupReplaceOperationKind string
+
+const (
+	RuleGroupReplaceDelete RuleGroupReplaceOperationKind = "delete"
+	RuleGroupReplaceUpdate RuleGroupReplaceOperationKind = "update"
+	RuleGroupReplaceCreate RuleGroupReplaceOperationKind = "create"
+)
+
+// RuleGroupReplaceOperation is a single change of a rule group replace. Rule is not set for deletes.
+type RuleGroupReplaceOperation struct {
+	Kind RuleGroupReplaceOperationKind `json:"kind"`
+	UID  string                        `json:"uid"`
+	Rule *AlertRule                    `json:"rule,omitempty"`
+}
+
+// RuleGroupReplaceJournal records the progress of a rule group replace that is applied in batches.
+// A journal is removed once all of its operations are applied, so a journal that is still stored
+// belongs to a replace that was interrupted after applying the first Applied operations.
+type RuleGroupReplaceJournal struct {
+	ID           int64     `xorm:"pk autoincr 'id'"`
+	OrgID        int64     `xorm:"org_id"`
+	NamespaceUID string    `xorm:"namespace_uid"`
+	RuleGroup    string    `xorm:"rule_group"`
+	Provenance   string    `xorm:"provenance"`
+	Operations   string    `xorm:"operations"`
+	Total        int       `xorm:"total"`
+	Applied      int       `xorm:"applied"`
+	Created      time.Time `xorm:"created"`
+	Updated      time.Time `xorm:"updated"`
+}
+
+// A XORM interface that defines the used table for this struct.
+func (j *RuleGroupReplaceJournal) TableName() string {
+	return "alert_rule_group_replace_journal"
+}
+
+// SetOperations stores the operations in the journal.
+func (j *RuleGroupReplaceJournal) SetOperations(ops []RuleGroupReplaceOperation) error {
+	data, err := json.Marshal(ops)
+	if err != nil {
+		return fmt.Errorf("failed to serialize the operations of the rule group replace: %w", err)
+	}
+	j.Operations = string(data)
+	j.Total = len(ops)
+	return nil
+}
+
+// GetOperations returns the operations stored in the journal.
+func (j *RuleGroupReplaceJournal) GetOperations() ([]RuleGroupReplaceOperation, error) {
+	var ops []RuleGroupReplaceOperation
+	if err := json.Unmarshal([]byte(j.Operations), &ops); err != nil {
+		return nil, fmt.Errorf("failed to read the operations of the rule group replace: %w", err)
+	}
+	return ops, nil
+}
@@ -74,6 +74,9 @@ func AlertRuleGen(mutators ...func(*AlertRule)) func() *AlertRule {
 			DashboardUID:    dashUID,
 			PanelID:         panelID,
 			RuleGroup:       "TEST-GROUP-" + util.GenerateShortUID(),
+			EvalStrategy:    EvalStrategyIndependent,
+			RuleGroupIndex:  rand.Intn(100) + 1,
+			EvalEveryN:      DefaultEvalEveryN,
 			NoDataState:     randNoDataState(),
 			ExecErrState:    randErrState(),
 			For:             forInterval,
@@ -145,19 +148,26 @@ func GenerateGroupKey(orgID int64) AlertRuleGroupKey {
 // CopyRule creates a deep copy of AlertRule
 func CopyRule(r *AlertRule) *AlertRule {
 	result := AlertRule{
-		ID:              r.ID,
-		OrgID:           r.OrgID,
-		Title:           r.Title,
-		Condition:       r.Condition,
-		Updated:         r.Updated,
-		IntervalSeconds: r.IntervalSeconds,
-		Version:         r.Version,
-		UID:             r.UID,
-		NamespaceUID:    r.NamespaceUID,
-		RuleGroup:       r.RuleGroup,
-		NoDataState:     r.NoDataState,
-		ExecErrState:    r.ExecErrState,
-		For:             r.For,
+		ID:                r.ID,
+		OrgID:             r.OrgID,
+		Title:             r.Title,
+		TitleTemplate:     r.TitleTemplate,
+		Condition:         r.Condition,
+		Updated:           r.Updated,
+		IntervalSeconds:   r.IntervalSeconds,
+		Version:           r.Version,
+		UID:               r.UID,
+		NamespaceUID:      r.NamespaceUID,
+		RuleGroup:         r.RuleGroup,
+		EvalStrategy:      r.EvalStrategy,
+		RuleGroupIndex:    r.RuleGroupIndex,
+		EvalOrder:         r.EvalOrder,
+		EvalOffsetSeconds: r.EvalOffsetSeconds,
+		EvalEveryN:        r.EvalEveryN,
+		WarmUpEvals:       r.WarmUpEvals,
+		NoDataState:       r.NoDataState,
+		ExecErrState:      r.ExecErrState,
+		For:               r.For,
 	}
 
 	if r.DashboardUID != nil {
@@ -168,6 +178,16 @@ func CopyRule(r *AlertRule) *AlertRule {
 		p := *r.PanelID
 		result.PanelID = &p
 	}
+	if r.ActiveWindow != nil {
+		window := *r.ActiveWindow
+		window.Times = append(window.Times[:0:0], window.Times...)
+		window.Weekdays = append(window.Weekdays[:0:0], window.Weekdays...)
+		result.ActiveWindow = &window
+	}
+	if r.ExpiresAt != nil {
+		expiresAt := *r.ExpiresAt
+		result.ExpiresAt = &expiresAt
+	}
 
 	for _, d := range r.Data {
 		q := AlertQuery{
@@ -195,5 +215,12 @@ func CopyRule(r *AlertRule) *AlertRule {
 		}
 	}
 
+	if r.GroupLabels != nil {
+		result.GroupLabels = make(map[string]string, len(r.GroupLabels))
+		for s, s2 := range r.GroupLabels {
+			result.GroupLabels[s] = s2
+		}
+	}
+
 	return &result
 }
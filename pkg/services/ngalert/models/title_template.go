@@ -0,0 +1,41 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// ErrInvalidTitleTemplate is returned when the title template of a rule cannot be parsed.
+var ErrInvalidTitleTemplate = fmt.Errorf("%w: invalid title template", ErrAlertRuleFailedValidation)
+
+// titleTemplateData is the data the title template of a rule is rendered with.
+type titleTemplateData struct {
+	Labels map[string]string
+}
+
+func parseTitleTemplate(text string) (*template.Template, error) {
+	// Missing labels are rendered as empty strings instead of "<no value>".
+	return template.New("title").Option("missingkey=zero").Parse(text)
+}
+
+// ValidateTitleTemplate returns ErrInvalidTitleTemplate if the title template cannot be parsed.
+func ValidateTitleTemplate(text string) error {
+	if _, err := parseTitleTemplate(text); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidTitleTemplate, err)
+	}
+	return nil
+}
+
+// RenderTitleTemplate renders the title template of a rule with the labels of an alert instance.
+func RenderTitleTemplate(text string, labels map[string]string) (string, error) {
+	tmpl, err := parseTitleTemplate(text)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrInvalidTitleTemplate, err)
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, titleTemplateData{Labels: labels}); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
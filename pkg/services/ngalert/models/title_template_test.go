@@ -0,0 +1,64 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderTitleTemplate(t *testing.T) {
+	labels := map[string]string{"instance": "host-1", "job": "node"}
+	testCases := []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{
+			name:     "static title",
+			template: "High CPU",
+			expected: "High CPU",
+		},
+		{
+			name:     "labels",
+			template: "High CPU on {{ .Labels.instance }} ({{ .Labels.job }})",
+			expected: "High CPU on host-1 (node)",
+		},
+		{
+			name:     "index function",
+			template: `{{ index .Labels "instance" }}`,
+			expected: "host-1",
+		},
+		{
+			name:     "missing label",
+			template: "High CPU on {{ .Labels.pod }}",
+			expected: "High CPU on ",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.NoError(t, ValidateTitleTemplate(tc.template))
+			title, err := RenderTitleTemplate(tc.template, labels)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, title)
+		})
+	}
+
+	t.Run("missing labels", func(t *testing.T) {
+		title, err := RenderTitleTemplate("High CPU on {{ .Labels.instance }}", nil)
+		require.NoError(t, err)
+		require.Equal(t, "High CPU on ", title)
+	})
+}
+
+func TestValidateTitleTemplate(t *testing.T) {
+	for _, text := range []string{"{{ .Labels.instance", "{{ end }}", "{{ unknown .Labels }}"} {
+		t.Run(text, func(t *testing.T) {
+			err := ValidateTitleTemplate(text)
+			require.ErrorIs(t, err, ErrInvalidTitleTemplate)
+			require.ErrorIs(t, err, ErrAlertRuleFailedValidation)
+
+			_, err = RenderTitleTemplate(text, nil)
+			require.ErrorIs(t, err, ErrInvalidTitleTemplate)
+		})
+	}
+}
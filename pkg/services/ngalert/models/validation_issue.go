@@ -0,0 +1,27 @@
+package models
+
+// ValidationIssueCode identifies the kind of a validation issue. Codes are part of the API
+// and must not change, so that clients can rely on them, e.g. to suppress specific warnings.
+type ValidationIssueCode string
+
+const (
+	// IssueShortTimeRange is reported when a query covers less time than the evaluation interval of the rule.
+	IssueShortTimeRange ValidationIssueCode = "short_time_range"
+	// IssueForShorterThanInterval is reported when the pending period of a rule is shorter than its evaluation interval.
+	IssueForShorterThanInterval ValidationIssueCode = "for_shorter_than_interval"
+	// IssueAnnotationOverridden is reported when a dedicated field of a rule overrides an annotation with a different value.
+	IssueAnnotationOverridden ValidationIssueCode = "annotation_overridden"
+	// IssueInvalidTemplate is reported when an annotation cannot be parsed as a template, e.g. because it uses an unknown variable.
+	IssueInvalidTemplate ValidationIssueCode = "invalid_template"
+	// IssueUnknownTemplateValue is reported when an annotation template reads a value of a RefID that the rule does not have.
+	IssueUnknownTemplateValue ValidationIssueCode = "unknown_template_value"
+	// IssueGroupEvaluationCost is reported when the rules of a rule group are estimated to take most of the group interval to evaluate.
+	IssueGroupEvaluationCost ValidationIssueCode = "group_evaluation_cost"
+)
+
+// ValidationIssue describes a problem with an object that does not prevent it from being saved.
+type ValidationIssue struct {
+	Code    ValidationIssueCode `json:"code"`
+	Field   string              `json:"field,omitempty"`
+	Message string              `json:"message"`
+}
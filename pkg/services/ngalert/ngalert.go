@@ -2,12 +2,16 @@ package ngalert
 
 import (
 	"context"
+	"fmt"
+	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/benbjohnson/clock"
 	"golang.org/x/sync/errgroup"
 
 	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/bus"
 	"github.com/grafana/grafana/pkg/expr"
 	"github.com/grafana/grafana/pkg/infra/kvstore"
 	"github.com/grafana/grafana/pkg/infra/log"
@@ -32,10 +36,18 @@ import (
 	"github.com/grafana/grafana/pkg/setting"
 )
 
+// schedulerShutdownTimeout is how long the running evaluations may take to complete when Grafana shuts down.
+const schedulerShutdownTimeout = 30 * time.Second
+
+// resourceHealthCheckInterval is how often the provisioned resources of all organizations are checked
+// for references to missing resources.
+const resourceHealthCheckInterval = 10 * time.Minute
+
 func ProvideService(cfg *setting.Cfg, dataSourceCache datasources.CacheService, routeRegister routing.RouteRegister,
 	sqlStore *sqlstore.SQLStore, kvStore kvstore.KVStore, expressionService *expr.Service, dataProxy *datasourceproxy.DataSourceProxyService,
 	quotaService *quota.QuotaService, secretsService secrets.Service, notificationService notifications.Service, m *metrics.NGAlert,
-	folderService dashboards.FolderService, ac accesscontrol.AccessControl, dashboardService dashboards.DashboardService, renderService rendering.Service) (*AlertNG, error) {
+	folderService dashboards.FolderService, ac accesscontrol.AccessControl, dashboardService dashboards.DashboardService, renderService rendering.Service,
+	bus bus.Bus) (*AlertNG, error) {
 	ng := &AlertNG{
 		Cfg:                 cfg,
 		DataSourceCache:     dataSourceCache,
@@ -53,6 +65,7 @@ func ProvideService(cfg *setting.Cfg, dataSourceCache datasources.CacheService,
 		accesscontrol:       ac,
 		dashboardService:    dashboardService,
 		renderService:       renderService,
+		bus:                 bus,
 	}
 
 	if ng.IsDisabled() {
@@ -86,10 +99,16 @@ type AlertNG struct {
 	stateManager        *state.Manager
 	folderService       dashboards.FolderService
 	dashboardService    dashboards.DashboardService
+	bus                 bus.Bus
 
 	// Alerting notification services
 	MultiOrgAlertmanager *notifier.MultiOrgAlertmanager
 	accesscontrol        accesscontrol.AccessControl
+	alertRuleService     *provisioning.AlertRuleService
+	// resourceHealthService checks that provisioned resources do not reference missing resources.
+	resourceHealthService *provisioning.ResourceHealthService
+	// provisioningJobService runs the imports and exports of alert rules in the background.
+	provisioningJobService *provisioning.ProvisioningJobService
 }
 
 func (ng *AlertNG) init() error {
@@ -130,6 +149,7 @@ func (ng *AlertNG) init() error {
 		MaxAttempts:             ng.Cfg.UnifiedAlerting.MaxAttempts,
 		Evaluator:               eval.NewEvaluator(ng.Cfg, ng.Log, ng.DataSourceCache, ng.SecretsService),
 		InstanceStore:           store,
+		RuleStatusStore:         store,
 		RuleStore:               store,
 		AdminConfigStore:        store,
 		OrgStore:                store,
@@ -138,6 +158,11 @@ func (ng *AlertNG) init() error {
 		AdminConfigPollInterval: ng.Cfg.UnifiedAlerting.AdminConfigPollInterval,
 		DisabledOrgs:            ng.Cfg.UnifiedAlerting.DisabledOrgs,
 		MinRuleInterval:         ng.Cfg.UnifiedAlerting.MinInterval,
+		EventBus:                ng.bus,
+		DryRun:                  ng.Cfg.UnifiedAlerting.DryRun,
+		RecoverFromEvalPanic:    ng.Cfg.UnifiedAlerting.RecoverFromEvalPanic,
+		ConcurrentGroupEval:     ng.Cfg.UnifiedAlerting.ConcurrentGroupEval,
+		MaxConcurrentGroupEval:  ng.Cfg.UnifiedAlerting.MaxConcurrentGroupEval,
 	}
 
 	appUrl, err := url.Parse(ng.Cfg.AppURL)
@@ -153,11 +178,67 @@ func (ng *AlertNG) init() error {
 	ng.schedule = scheduler
 
 	// Provisioning
-	policyService := provisioning.NewNotificationPolicyService(store, store, store, ng.Log)
-	contactPointService := provisioning.NewContactPointService(store, ng.SecretsService, store, store, ng.Log)
-	templateService := provisioning.NewTemplateService(store, store, store, ng.Log)
-	muteTimingService := provisioning.NewMuteTimingService(store, store, store, ng.Log)
-	alertRuleService := provisioning.NewAlertRuleService(store, store, store, int64(ng.Cfg.UnifiedAlerting.DefaultRuleEvaluationInterval.Seconds()), ng.Log)
+	readOnlyResources := make([]provisioning.ResourceType, 0, len(ng.Cfg.UnifiedAlerting.ProvisioningReadOnlyResources))
+	for _, name := range ng.Cfg.UnifiedAlerting.ProvisioningReadOnlyResources {
+		resource, err := provisioning.ParseResourceType(name)
+		if err != nil {
+			return fmt.Errorf("invalid setting 'provisioning_read_only_resources': %w", err)
+		}
+		readOnlyResources = append(readOnlyResources, resource)
+	}
+	resourcePolicy := provisioning.NewResourcePolicy(readOnlyResources...)
+	if limit := ng.Cfg.UnifiedAlerting.ProvisioningRateLimit; limit > 0 {
+		resourcePolicy.RateLimiter = provisioning.NewRateLimiter(limit, ng.Cfg.UnifiedAlerting.ProvisioningRateLimitBurst, ng.Metrics.GetProvisioningMetrics().ThrottledChanges)
+	}
+	policyService := provisioning.NewNotificationPolicyService(store, store, store, resourcePolicy, ng.Log)
+	contactPointService := provisioning.NewContactPointService(store, ng.SecretsService, store, store, store, resourcePolicy, ng.Log)
+	templateService := provisioning.NewTemplateService(store, store, store, resourcePolicy, ng.Log)
+	muteTimingService := provisioning.NewMuteTimingService(store, store, store, resourcePolicy, ng.Log)
+	var groupNotifier provisioning.RuleGroupChangeNotifier
+	if webhookURL := ng.Cfg.UnifiedAlerting.ProvisioningWebhookURL; webhookURL != "" {
+		groupNotifier = provisioning.NewWebhookRuleGroupNotifier(webhookURL, &http.Client{Timeout: 10 * time.Second})
+	}
+	ruleServiceCfg := provisioning.AlertRuleServiceConfig{
+		RequireProvenanceOrgs:  ng.Cfg.UnifiedAlerting.ProvisioningRequireProvenanceOrgs,
+		ReplaceBatchSize:       ng.Cfg.UnifiedAlerting.ProvisioningReplaceBatchSize,
+		BlockDSDeleteIfUsed:    ng.Cfg.UnifiedAlerting.BlockDSDeleteIfUsed,
+		DefaultFor:             ng.Cfg.UnifiedAlerting.ProvisioningDefaultFor,
+		CollapseNameWhitespace: ng.Cfg.UnifiedAlerting.ProvisioningCollapseNameWhitespace,
+		DraftTTL:               ng.Cfg.UnifiedAlerting.ProvisioningDraftTTL,
+		BaseInterval:           ng.Cfg.UnifiedAlerting.BaseInterval,
+		ProvisioningWebhook: provisioning.ProvisioningWebhookConfig{
+			URL:     ng.Cfg.UnifiedAlerting.ProvisioningRuleEventsWebhookURL,
+			Secret:  ng.Cfg.UnifiedAlerting.ProvisioningRuleEventsWebhookSecret,
+			Timeout: ng.Cfg.UnifiedAlerting.ProvisioningRuleEventsWebhookTimeout,
+		},
+	}
+	ruleServiceOpts := provisioning.AlertRuleServiceOptions{
+		Sources:             store,
+		Dashboards:          ng.dashboardService,
+		Datasources:         ng.SQLStore,
+		IntervalLimits:      store,
+		LibraryQueries:      store,
+		ReplaceJournals:     store,
+		Drafts:              store,
+		StateSummaries:      store,
+		EvaluationDurations: stateManager,
+		GroupNotifier:       groupNotifier,
+		Events:              ng.bus,
+		RuleRoutes:          policyService,
+		Alerts:              ng.MultiOrgAlertmanager,
+		AccessControl:       ng.accesscontrol,
+	}
+	if ng.QuotaService != nil {
+		ruleServiceOpts.Quotas = ng.QuotaService
+	}
+	ng.alertRuleService = provisioning.NewAlertRuleService(store, store, contactPointService, provisioning.NoopQuotaChecker{}, store, int64(ng.Cfg.UnifiedAlerting.DefaultRuleEvaluationInterval.Seconds()), ruleServiceCfg, resourcePolicy, ng.Log, ruleServiceOpts)
+
+	jobCfg := provisioning.DefaultProvisioningJobConfig()
+	jobCfg.MaxConcurrentJobs = ng.Cfg.UnifiedAlerting.ProvisioningJobConcurrency
+	jobCfg.Retention = ng.Cfg.UnifiedAlerting.ProvisioningJobRetention
+	ng.provisioningJobService = provisioning.NewProvisioningJobService(ng.alertRuleService, store, jobCfg, ng.Log)
+
+	ng.resourceHealthService = provisioning.NewResourceHealthService(store, store, store, ng.SQLStore, store, ng.Metrics.GetProvisioningMetrics().BrokenResources, ng.Log)
 
 	api := api.API{
 		Cfg:                  ng.Cfg,
@@ -181,7 +262,7 @@ func (ng *AlertNG) init() error {
 		ContactPointService:  contactPointService,
 		Templates:            templateService,
 		MuteTimings:          muteTimingService,
-		AlertRules:           alertRuleService,
+		AlertRules:           ng.alertRuleService,
 	}
 	api.RegisterAPIEndpoints(ng.Metrics.GetAPIMetrics())
 
@@ -197,15 +278,59 @@ func (ng *AlertNG) Run(ctx context.Context) error {
 
 	if ng.Cfg.UnifiedAlerting.ExecuteAlerts {
 		children.Go(func() error {
-			return ng.schedule.Run(subCtx)
+			// The scheduler is only stopped after its running evaluations completed, so that they
+			// are not interrupted halfway through saving the state of their alert instances.
+			schedulerCtx, stopScheduler := context.WithCancel(context.Background())
+			defer stopScheduler()
+			go func() {
+				<-subCtx.Done()
+				shutdownCtx, cancel := context.WithTimeout(context.Background(), schedulerShutdownTimeout)
+				defer cancel()
+				if err := ng.schedule.Shutdown(shutdownCtx); err != nil {
+					ng.Log.Warn("scheduler did not shut down gracefully", "err", err)
+				}
+				stopScheduler()
+			}()
+			return ng.schedule.Run(schedulerCtx)
 		})
 	}
 	children.Go(func() error {
 		return ng.MultiOrgAlertmanager.Run(subCtx)
 	})
+	if ng.alertRuleService != nil && ng.Cfg.UnifiedAlerting.ProvisioningDraftTTL > 0 {
+		children.Go(func() error {
+			return ng.alertRuleService.RunRuleGroupDraftCleanup(subCtx, time.Hour)
+		})
+	}
+	if ng.alertRuleService != nil {
+		children.Go(func() error {
+			return ng.alertRuleService.RunAlertRuleExpiry(subCtx, time.Minute)
+		})
+	}
+	if ng.provisioningJobService != nil {
+		children.Go(func() error {
+			return ng.provisioningJobService.Run(subCtx, time.Hour)
+		})
+	}
+	if ng.resourceHealthService != nil {
+		children.Go(func() error {
+			return ng.resourceHealthService.RunResourceHealthCheck(subCtx, resourceHealthCheckInterval)
+		})
+	}
 	return children.Wait()
 }
 
+// AlertRuleService returns the service that manages provisioned alert rules, or nil if alerting is disabled.
+func (ng *AlertNG) AlertRuleService() *provisioning.AlertRuleService {
+	return ng.alertRuleService
+}
+
+// ProvisioningJobService returns the service that imports and exports alert rules in the background,
+// or nil if alerting is disabled.
+func (ng *AlertNG) ProvisioningJobService() *provisioning.ProvisioningJobService {
+	return ng.provisioningJobService
+}
+
 // IsDisabled returns true if the alerting service is disable for this instance.
 func (ng *AlertNG) IsDisabled() bool {
 	if ng.Cfg == nil {
@@ -213,3 +338,11 @@ func (ng *AlertNG) IsDisabled() bool {
 	}
 	return !ng.Cfg.UnifiedAlerting.IsEnabled()
 }
+
+// HealthCheck returns an error if the store of the alerting subsystem is unavailable.
+func (ng *AlertNG) HealthCheck(ctx context.Context) error {
+	if ng.alertRuleService == nil {
+		return nil
+	}
+	return ng.alertRuleService.HealthCheck(ctx)
+}
@@ -10,6 +10,7 @@ import (
 	"net/url"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"sync"
 	"time"
@@ -89,6 +90,9 @@ type ClusterPeer interface {
 type AlertingStore interface {
 	store.AlertingStore
 	store.ImageStore
+	// ListNotificationSettings returns the notification settings of the alert rules of the organization
+	// that override the notification policy tree, by rule UID.
+	ListNotificationSettings(ctx context.Context, orgID int64) (map[string]ngmodels.NotificationSettings, error)
 }
 
 type Alertmanager struct {
@@ -261,9 +265,13 @@ func (am *Alertmanager) SaveAndApplyDefaultConfig(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	ruleRoutes, err := am.ruleRoutes(ctx)
+	if err != nil {
+		return err
+	}
 
 	err = am.Store.SaveAlertmanagerConfigurationWithCallback(ctx, cmd, func() error {
-		if err := am.applyConfig(cfg, []byte(am.Settings.UnifiedAlerting.DefaultConfiguration)); err != nil {
+		if err := am.applyConfig(cfg, []byte(am.Settings.UnifiedAlerting.DefaultConfiguration), ruleRoutes); err != nil {
 			return err
 		}
 		return nil
@@ -291,9 +299,13 @@ func (am *Alertmanager) SaveAndApplyConfig(ctx context.Context, cfg *apimodels.P
 		ConfigurationVersion:      fmt.Sprintf("v%d", ngmodels.AlertConfigurationVersion),
 		OrgID:                     am.orgID,
 	}
+	ruleRoutes, err := am.ruleRoutes(ctx)
+	if err != nil {
+		return err
+	}
 
 	err = am.Store.SaveAlertmanagerConfigurationWithCallback(ctx, cmd, func() error {
-		if err := am.applyConfig(cfg, rawConfig); err != nil {
+		if err := am.applyConfig(cfg, rawConfig, ruleRoutes); err != nil {
 			return err
 		}
 		return nil
@@ -305,18 +317,23 @@ func (am *Alertmanager) SaveAndApplyConfig(ctx context.Context, cfg *apimodels.P
 	return nil
 }
 
-// ApplyConfig applies the configuration to the Alertmanager.
-func (am *Alertmanager) ApplyConfig(dbCfg *ngmodels.AlertConfiguration) error {
+// ApplyConfig applies the configuration to the Alertmanager, together with the routes of the
+// notification settings of the alert rules, which can change without the configuration.
+func (am *Alertmanager) ApplyConfig(ctx context.Context, dbCfg *ngmodels.AlertConfiguration) error {
 	var err error
 	cfg, err := Load([]byte(dbCfg.AlertmanagerConfiguration))
 	if err != nil {
 		return fmt.Errorf("failed to parse Alertmanager config: %w", err)
 	}
+	ruleRoutes, err := am.ruleRoutes(ctx)
+	if err != nil {
+		return err
+	}
 
 	am.reloadConfigMtx.Lock()
 	defer am.reloadConfigMtx.Unlock()
 
-	if err = am.applyConfig(cfg, nil); err != nil {
+	if err = am.applyConfig(cfg, nil, ruleRoutes); err != nil {
 		return fmt.Errorf("unable to apply configuration: %w", err)
 	}
 	return nil
@@ -356,9 +373,34 @@ func (am *Alertmanager) buildMuteTimesMap(muteTimeIntervals []config.MuteTimeInt
 	return muteTimes
 }
 
+// ruleRoutes returns the routes of the alert rules whose notification settings override the notification
+// policy tree, ordered by rule UID. They are generated from the rules whenever the configuration is
+// applied, and are not part of the stored configuration.
+func (am *Alertmanager) ruleRoutes(ctx context.Context) ([]*apimodels.Route, error) {
+	settings, err := am.Store.ListNotificationSettings(ctx, am.orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the notification settings of alert rules: %w", err)
+	}
+	uids := make([]string, 0, len(settings))
+	for uid := range settings {
+		uids = append(uids, uid)
+	}
+	sort.Strings(uids)
+	routes := make([]*apimodels.Route, 0, len(uids))
+	for _, uid := range uids {
+		route, err := apimodels.NotificationSettingsRoute(uid, settings[uid])
+		if err != nil {
+			return nil, fmt.Errorf("failed to build the route of alert rule %s: %w", uid, err)
+		}
+		routes = append(routes, route)
+	}
+	return routes, nil
+}
+
 // applyConfig applies a new configuration by re-initializing all components using the configuration provided.
-// It is not safe to call concurrently.
-func (am *Alertmanager) applyConfig(cfg *apimodels.PostableUserConfig, rawConfig []byte) (err error) {
+// The routes of the alert rules are added to the top of the root route, so that they take precedence over
+// the notification policy tree. It is not safe to call concurrently.
+func (am *Alertmanager) applyConfig(cfg *apimodels.PostableUserConfig, rawConfig []byte, ruleRoutes []*apimodels.Route) (err error) {
 	// First, let's make sure this config is not already loaded
 	var configChanged bool
 	if rawConfig == nil {
@@ -369,8 +411,13 @@ func (am *Alertmanager) applyConfig(cfg *apimodels.PostableUserConfig, rawConfig
 		}
 		rawConfig = enc
 	}
+	rawRuleRoutes, err := json.Marshal(ruleRoutes)
+	if err != nil {
+		return err
+	}
+	configHash := md5.Sum(append(append([]byte{}, rawConfig...), rawRuleRoutes...))
 
-	if am.configHash != md5.Sum(rawConfig) {
+	if am.configHash != configHash {
 		configChanged = true
 	}
 
@@ -426,7 +473,17 @@ func (am *Alertmanager) applyConfig(cfg *apimodels.PostableUserConfig, rawConfig
 		routingStage[name] = notify.MultiStage{meshStage, silencingStage, timeMuteStage, inhibitionStage, stage}
 	}
 
-	am.route = dispatch.NewRoute(cfg.AlertmanagerConfig.Route.AsAMRoute(), nil)
+	root := *cfg.AlertmanagerConfig.Route
+	root.Routes = make([]*apimodels.Route, 0, len(ruleRoutes)+len(cfg.AlertmanagerConfig.Route.Routes))
+	for _, route := range ruleRoutes {
+		if _, ok := integrationsMap[route.Receiver]; !ok {
+			am.logger.Warn("skipping the route of an alert rule with an unknown receiver", "receiver", route.Receiver, "matchers", route.ObjectMatchers)
+			continue
+		}
+		root.Routes = append(root.Routes, route)
+	}
+	root.Routes = append(root.Routes, cfg.AlertmanagerConfig.Route.Routes...)
+	am.route = dispatch.NewRoute(root.AsAMRoute(), nil)
 	am.dispatcher = dispatch.NewDispatcher(am.alerts, am.route, routingStage, am.marker, am.timeoutFunc, &nilLimits{}, am.logger, am.dispatcherMetrics)
 
 	am.wg.Add(1)
@@ -442,7 +499,7 @@ func (am *Alertmanager) applyConfig(cfg *apimodels.PostableUserConfig, rawConfig
 	}()
 
 	am.config = cfg
-	am.configHash = md5.Sum(rawConfig)
+	am.configHash = configHash
 
 	return nil
 }
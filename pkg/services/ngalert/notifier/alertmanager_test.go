@@ -2,6 +2,7 @@ package notifier
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"sort"
 	"testing"
@@ -12,6 +13,7 @@ import (
 
 	"github.com/go-openapi/strfmt"
 	"github.com/prometheus/alertmanager/api/v2/models"
+	"github.com/prometheus/alertmanager/dispatch"
 	"github.com/prometheus/alertmanager/provider/mem"
 	"github.com/prometheus/alertmanager/types"
 	"github.com/prometheus/client_golang/prometheus"
@@ -21,6 +23,7 @@ import (
 	"github.com/grafana/grafana/pkg/infra/log"
 	apimodels "github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
 	"github.com/grafana/grafana/pkg/services/ngalert/metrics"
+	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
 	"github.com/grafana/grafana/pkg/services/ngalert/store"
 	secretsManager "github.com/grafana/grafana/pkg/services/secrets/manager"
 	"github.com/grafana/grafana/pkg/services/sqlstore"
@@ -414,3 +417,97 @@ func TestSilenceCleanup(t *testing.T) {
 		return len(found) == 2
 	}, 6*time.Second, 150*time.Millisecond)
 }
+
+func TestApplyConfigRoutesRuleNotificationSettings(t *testing.T) {
+	am := setupAMTest(t)
+	dbstore := am.Store.(*store.DBstore)
+	ctx := context.Background()
+
+	groupWait := model.Duration(time.Minute)
+	repeatInterval := model.Duration(3 * time.Hour)
+	rule := func(title string, settings ...ngmodels.NotificationSettings) ngmodels.AlertRule {
+		return ngmodels.AlertRule{
+			OrgID:                1,
+			Title:                title,
+			Condition:            "A",
+			IntervalSeconds:      10,
+			NamespaceUID:         "folder",
+			RuleGroup:            "group",
+			NoDataState:          ngmodels.NoData,
+			ExecErrState:         ngmodels.AlertingErrState,
+			NotificationSettings: settings,
+			Data: []ngmodels.AlertQuery{{
+				RefID:             "A",
+				DatasourceUID:     "-100",
+				Model:             json.RawMessage(`{"type": "math", "expression": "2 + 3 > 1"}`),
+				RelativeTimeRange: ngmodels.RelativeTimeRange{From: ngmodels.Duration(5 * time.Hour)},
+			}},
+		}
+	}
+	_, err := dbstore.InsertAlertRules(ctx, []ngmodels.AlertRule{
+		rule("with settings", ngmodels.NotificationSettings{ReceiverName: "team-a", GroupBy: []string{"team"}, GroupWait: &groupWait, RepeatInterval: &repeatInterval}),
+		rule("with unknown receiver", ngmodels.NotificationSettings{ReceiverName: "unknown"}),
+		rule("without settings"),
+	})
+	require.NoError(t, err)
+	query := &ngmodels.ListAlertRulesQuery{OrgID: 1}
+	require.NoError(t, dbstore.ListAlertRules(ctx, query))
+	uids := make(map[string]string, len(query.Result))
+	for _, r := range query.Result {
+		uids[r.Title] = r.UID
+	}
+
+	cfg, err := Load([]byte(`{
+		"alertmanager_config": {
+			"route": {"receiver": "default", "group_by": ["alertname"]},
+			"receivers": [{
+				"name": "default",
+				"grafana_managed_receiver_configs": [{"uid": "", "name": "default", "type": "email", "settings": {"addresses": "<default@example.com>"}}]
+			}, {
+				"name": "team-a",
+				"grafana_managed_receiver_configs": [{"uid": "", "name": "team-a", "type": "email", "settings": {"addresses": "<team-a@example.com>"}}]
+			}]
+		}
+	}`))
+	require.NoError(t, err)
+	require.NoError(t, am.SaveAndApplyConfig(ctx, cfg))
+
+	match := func(t *testing.T, title string) *dispatch.RouteOpts {
+		t.Helper()
+		routes := am.route.Match(model.LabelSet{ngmodels.RuleUIDLabel: model.LabelValue(uids[title]), "alertname": model.LabelValue(title)})
+		require.Len(t, routes, 1)
+		return &routes[0].RouteOpts
+	}
+
+	t.Run("should route the alerts of rules with notification settings according to the settings", func(t *testing.T) {
+		opts := match(t, "with settings")
+		require.Equal(t, "team-a", opts.Receiver)
+		require.Equal(t, map[model.LabelName]struct{}{"team": {}}, opts.GroupBy)
+		require.Equal(t, time.Minute, opts.GroupWait)
+		require.Equal(t, 3*time.Hour, opts.RepeatInterval)
+		require.Equal(t, dispatch.DefaultRouteOpts.GroupInterval, opts.GroupInterval)
+	})
+	t.Run("should route the alerts of other rules by the notification policy tree", func(t *testing.T) {
+		require.Equal(t, "default", match(t, "without settings").Receiver)
+		require.Equal(t, "default", match(t, "with unknown receiver").Receiver)
+	})
+	t.Run("should not change the stored configuration", func(t *testing.T) {
+		require.Empty(t, am.config.AlertmanagerConfig.Route.Routes)
+	})
+	t.Run("should apply changes of the notification settings with the stored configuration", func(t *testing.T) {
+		existing := query.Result[0]
+		for _, r := range query.Result {
+			if r.UID == uids["with settings"] {
+				existing = r
+			}
+		}
+		updated := *existing
+		updated.NotificationSettings = nil
+		require.NoError(t, dbstore.UpdateAlertRules(ctx, []store.UpdateRule{{Existing: existing, New: updated}}))
+
+		dbCfg := &ngmodels.GetLatestAlertmanagerConfigurationQuery{OrgID: 1}
+		require.NoError(t, dbstore.GetLatestAlertmanagerConfiguration(ctx, dbCfg))
+		require.NoError(t, am.ApplyConfig(ctx, dbCfg.Result))
+		require.Equal(t, "default", match(t, "with settings").Receiver)
+	})
+}
@@ -18,6 +18,11 @@ type FakeConfigStore struct {
 	configs map[int64]*models.AlertConfiguration
 }
 
+// ListNotificationSettings returns no notification settings, as the store has no alert rules.
+func (f *FakeConfigStore) ListNotificationSettings(_ context.Context, _ int64) (map[string]models.NotificationSettings, error) {
+	return nil, nil
+}
+
 // Saves the image or returns an error.
 func (f *FakeConfigStore) SaveImage(ctx context.Context, img *models.Image) error {
 	return models.ErrImageNotFound
@@ -0,0 +1,106 @@
+package provisioning
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+// BatchMode defines how UpdateAlertRules handles updates that fail.
+type BatchMode string
+
+const (
+	// BatchModeAtomic applies all updates in one transaction, and none of them if one fails.
+	BatchModeAtomic BatchMode = "atomic"
+	// BatchModeBestEffort applies every update in a transaction of its own, so that failed
+	// updates do not prevent the others.
+	BatchModeBestEffort BatchMode = "best-effort"
+)
+
+// RuleUpdate is an update of an alert rule in a batch.
+type RuleUpdate struct {
+	Rule models.AlertRule
+	// Version is the version of the stored rule that the update is based on. The update fails
+	// with ErrVersionConflict if the rule was changed since. Zero updates any version.
+	Version int64
+}
+
+// RuleUpdateResult is the result of a RuleUpdate. Err is nil if Rule was updated.
+type RuleUpdateResult struct {
+	Rule models.AlertRule
+	Err  error
+}
+
+// UpdateAlertRules updates the alert rules and returns a result for every update, in the order
+// of the updates. The provenance and the version of every rule are checked before it is updated.
+// In BatchModeAtomic, the results of the updates that were not applied because another one
+// failed have an ErrBatchAborted error. The returned error is only set if the batch itself
+// failed, such as for an unknown mode.
+func (service *AlertRuleService) UpdateAlertRules(ctx context.Context, updates []RuleUpdate, provenance models.Provenance, mode BatchMode) (_ []RuleUpdateResult, err error) {
+	defer wrapServiceError(&err)
+	results := make([]RuleUpdateResult, len(updates))
+	switch mode {
+	case BatchModeBestEffort:
+		for i, update := range updates {
+			results[i].Rule, results[i].Err = service.updateAlertRuleOfBatch(ctx, update, provenance)
+		}
+		return results, nil
+	case BatchModeAtomic:
+		failed := -1
+		err = service.xact.InTransaction(ctx, func(ctx context.Context) error {
+			for i, update := range updates {
+				rule, err := service.updateAlertRuleOfBatch(ctx, update, provenance)
+				if err != nil {
+					failed = i
+					return err
+				}
+				results[i].Rule = rule
+			}
+			return nil
+		})
+		if err == nil {
+			return results, nil
+		}
+		if failed < 0 {
+			return nil, err
+		}
+		for i := range results {
+			if i == failed {
+				results[i] = RuleUpdateResult{Err: err}
+				continue
+			}
+			aborted := fmt.Errorf("%w: the update of rule '%s' failed", ErrBatchAborted, updates[failed].Rule.UID)
+			wrapServiceError(&aborted)
+			results[i] = RuleUpdateResult{Err: aborted}
+		}
+		return results, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown batch mode '%s'", ErrValidation, mode)
+	}
+}
+
+func (service *AlertRuleService) updateAlertRuleOfBatch(ctx context.Context, update RuleUpdate, provenance models.Provenance) (rule models.AlertRule, err error) {
+	defer wrapServiceError(&err)
+	if err := service.policy.checkMutation(update.Rule.OrgID, ResourceTypeAlertRules, provenance); err != nil {
+		return models.AlertRule{}, err
+	}
+	err = service.xact.InTransaction(ctx, func(ctx context.Context) error {
+		if update.Version != 0 {
+			storedRule, _, err := service.GetAlertRule(ctx, update.Rule.OrgID, update.Rule.UID)
+			if err != nil {
+				return err
+			}
+			if storedRule.Version != update.Version {
+				return fmt.Errorf("%w: rule '%s' has version %d, not %d", ErrVersionConflict, update.Rule.UID, storedRule.Version, update.Version)
+			}
+		}
+		var err error
+		rule, _, err = service.updateAlertRule(ctx, update.Rule, provenance, 0)
+		return err
+	})
+	if err != nil {
+		return models.AlertRule{}, err
+	}
+	return rule, nil
+}
@@ -0,0 +1,91 @@
+package provisioning
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+func TestAlertRuleServiceUpdateAlertRules(t *testing.T) {
+	ruleService := createAlertRuleService(t)
+	var orgID int64 = 51
+	ctx := context.Background()
+	create := func(title string, provenance models.Provenance) models.AlertRule {
+		rule := dummyRule(title, orgID)
+		// stored rules keep the relative time ranges of queries in seconds.
+		rule.Data[0].RelativeTimeRange.From = models.Duration(time.Minute)
+		rule, err := ruleService.CreateAlertRule(ctx, rule, provenance)
+		require.NoError(t, err)
+		rule, _, err = ruleService.GetAlertRule(ctx, orgID, rule.UID)
+		require.NoError(t, err)
+		return rule
+	}
+	withTitle := func(rule models.AlertRule, title string) models.AlertRule {
+		rule.Title = title
+		return rule
+	}
+	requireTitle := func(t *testing.T, uid, title string) {
+		t.Helper()
+		rule, _, err := ruleService.GetAlertRule(ctx, orgID, uid)
+		require.NoError(t, err)
+		require.Equal(t, title, rule.Title)
+	}
+	apiRule := create("test#batch-1", models.ProvenanceAPI)
+	staleRule := create("test#batch-2", models.ProvenanceAPI)
+	fileRule := create("test#batch-3", models.ProvenanceFile)
+
+	t.Run("atomic batches should not apply any update if one fails", func(t *testing.T) {
+		results, err := ruleService.UpdateAlertRules(ctx, []RuleUpdate{
+			{Rule: withTitle(apiRule, "test#batch-1-atomic"), Version: apiRule.Version},
+			{Rule: withTitle(fileRule, "test#batch-3-atomic")},
+		}, models.ProvenanceAPI, BatchModeAtomic)
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+		require.ErrorIs(t, results[0].Err, ErrBatchAborted)
+		require.ErrorIs(t, results[1].Err, ErrProvenanceMismatch)
+		require.Equal(t, ErrCodeProvenanceMismatch, ErrorCodeOf(results[1].Err))
+
+		requireTitle(t, apiRule.UID, "test#batch-1")
+		requireTitle(t, fileRule.UID, "test#batch-3")
+	})
+
+	t.Run("best-effort batches should apply the updates that succeed", func(t *testing.T) {
+		_, err := ruleService.UpdateAlertRule(ctx, withTitle(staleRule, "test#batch-2-concurrent"), models.ProvenanceAPI)
+		require.NoError(t, err)
+
+		results, err := ruleService.UpdateAlertRules(ctx, []RuleUpdate{
+			{Rule: withTitle(apiRule, "test#batch-1-best-effort"), Version: apiRule.Version},
+			{Rule: withTitle(staleRule, "test#batch-2-best-effort"), Version: staleRule.Version},
+			{Rule: withTitle(fileRule, "test#batch-3-best-effort")},
+		}, models.ProvenanceAPI, BatchModeBestEffort)
+		require.NoError(t, err)
+		require.Len(t, results, 3)
+		require.NoError(t, results[0].Err)
+		require.Equal(t, "test#batch-1-best-effort", results[0].Rule.Title)
+		require.ErrorIs(t, results[1].Err, ErrVersionConflict)
+		require.Equal(t, ErrCodeOptimisticLock, ErrorCodeOf(results[1].Err))
+		require.ErrorIs(t, results[2].Err, ErrProvenanceMismatch)
+
+		requireTitle(t, apiRule.UID, "test#batch-1-best-effort")
+		requireTitle(t, staleRule.UID, "test#batch-2-concurrent")
+		requireTitle(t, fileRule.UID, "test#batch-3")
+	})
+
+	t.Run("should check the versions of atomic batches", func(t *testing.T) {
+		results, err := ruleService.UpdateAlertRules(ctx, []RuleUpdate{
+			{Rule: withTitle(apiRule, "test#batch-1-outdated"), Version: apiRule.Version},
+		}, models.ProvenanceAPI, BatchModeAtomic)
+		require.NoError(t, err)
+		require.ErrorIs(t, results[0].Err, ErrVersionConflict)
+		requireTitle(t, apiRule.UID, "test#batch-1-best-effort")
+	})
+
+	t.Run("should reject unknown modes", func(t *testing.T) {
+		_, err := ruleService.UpdateAlertRules(ctx, nil, models.ProvenanceAPI, "sometimes")
+		require.ErrorIs(t, err, ErrValidation)
+	})
+}
@@ -2,47 +2,247 @@ package provisioning
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/benbjohnson/clock"
+
+	"github.com/grafana/grafana/pkg/bus"
 	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
 	"github.com/grafana/grafana/pkg/services/ngalert/models"
 	"github.com/grafana/grafana/pkg/services/ngalert/store"
 	"github.com/grafana/grafana/pkg/util"
 )
 
+// healthCheckTimeout is the time after which the store is considered unavailable by HealthCheck.
+const healthCheckTimeout = 2 * time.Second
+
+// StorageUnavailableError is returned by HealthCheck if the store cannot be reached.
+type StorageUnavailableError struct {
+	Err error
+}
+
+func (e StorageUnavailableError) Error() string {
+	return fmt.Sprintf("alert rule storage is unavailable: %s", e.Err)
+}
+
+func (e StorageUnavailableError) Unwrap() error {
+	return e.Err
+}
+
+// ContactPointValidator checks that contact points referenced by alert rules exist.
+type ContactPointValidator interface {
+	Exists(ctx context.Context, orgID int64, name string) (bool, error)
+}
+
+// AlertRuleServiceConfig holds the optional behavior of the AlertRuleService.
+type AlertRuleServiceConfig struct {
+	// RequireProvenanceOrgs are the organizations in which rules cannot be created with ProvenanceNone.
+	RequireProvenanceOrgs map[int64]struct{}
+	// ReplaceBatchSize is the number of changes a rule group replace applies per transaction.
+	// Replaces with more changes are applied in batches. 0 applies every replace in a single transaction.
+	ReplaceBatchSize int
+	// BlockDSDeleteIfUsed makes CheckDataSourceDelete reject the deletion of data sources that alert rules query.
+	BlockDSDeleteIfUsed bool
+	// OperationTimeouts limit how long the operations of the service may take.
+	OperationTimeouts OperationTimeouts
+	// AllowedLabelKeys are the only label keys rules may use. Empty allows all keys.
+	AllowedLabelKeys []string
+	// RequiredLabelKeys are the label keys every rule must use.
+	RequiredLabelKeys []string
+	// AllowedAnnotationKeys are the only annotation keys rules may use. Empty allows all keys.
+	AllowedAnnotationKeys []string
+	// RequiredAnnotationKeys are the annotation keys every rule must use.
+	RequiredAnnotationKeys []string
+	// StrictTemplateValidation rejects rules whose annotation templates have issues instead of
+	// only reporting them as warnings.
+	StrictTemplateValidation bool
+	// DefaultFor is the pending period of rules that are created without one. It must be a
+	// multiple of the interval of the rule group. 0 keeps the rules without a pending period.
+	DefaultFor time.Duration
+	// CollapseNameWhitespace collapses inner whitespace of titles and rule group names to single
+	// spaces when rules are written. Surrounding whitespace is always removed.
+	CollapseNameWhitespace bool
+	// DraftTTL is the time after its last update after which a rule group draft is deleted.
+	// 0 keeps drafts until they are promoted or deleted.
+	DraftTTL time.Duration
+	// BaseInterval is the interval of the scheduler. Rule group intervals are multiples of it.
+	BaseInterval time.Duration
+	// ProvisioningWebhook is informed about every alert rule that is created, updated or deleted.
+	ProvisioningWebhook ProvisioningWebhookConfig
+}
+
+// CreateAlertRuleOptions change how CreateAlertRuleWithOptions creates an alert rule.
+type CreateAlertRuleOptions struct {
+	// DefaultFor overrides the DefaultFor of the service configuration if it is set.
+	DefaultFor *time.Duration
+}
+
+// OperationTimeouts are the timeouts of the operations of the AlertRuleService by operation type.
+// A zero timeout keeps the deadline of the incoming context as is.
+type OperationTimeouts struct {
+	Create time.Duration
+	Update time.Duration
+	Delete time.Duration
+	List   time.Duration
+	Get    time.Duration
+}
+
+// withTimeout returns a context that is canceled after the timeout, unless the timeout is zero.
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
 type AlertRuleService struct {
-	defaultInterval int64
-	ruleStore       store.RuleStore
-	provenanceStore ProvisioningStore
-	xact            TransactionManager
-	log             log.Logger
+	cfg                   AlertRuleServiceConfig
+	defaultInterval       int64
+	ruleStore             store.RuleStore
+	provenanceStore       ProvisioningStore
+	contactPointValidator ContactPointValidator
+	// sources is optional and required to record the files that rules are provisioned from.
+	sources ProvisioningSourceStore
+	// dashboards is optional and used to check that linked dashboards exist.
+	dashboards DashboardProvider
+	// datasources is optional and used to resolve the data sources of imported rules.
+	datasources    DataSourceLookup
+	intervalLimits IntervalLimitStore
+	libraryQueries LibraryQueryStore
+	// replaceJournals is optional and required to apply rule group replaces in batches.
+	replaceJournals RuleGroupReplaceJournalStore
+	// drafts is optional and required for drafts of rule groups.
+	drafts RuleGroupDraftStore
+	// stateSummaries is optional. Rules have no state summary without it.
+	stateSummaries StateSummaryStore
+	// groupNotifier is optional and informed about committed changes of rule groups.
+	groupNotifier RuleGroupChangeNotifier
+	xact          TransactionManager
+	log           log.Logger
+	// policy decides whether alert rules can be changed.
+	policy ResourcePolicy
+	// createLocks serializes concurrent creation of the same logical rule.
+	createLocks *keyedMutex
+	// events is optional and carries the state changes of alert instances to subscribers.
+	events bus.Bus
+	// evaluationDurations is optional and used to estimate the evaluation cost of rule groups.
+	evaluationDurations EvaluationDurationProvider
+	// quota is checked before alert rules are created.
+	quota QuotaChecker
+	// quotas is optional. Without it, the alert rule quotas of organizations are not enforced.
+	quotas QuotaService
+	// ruleRoutes is optional and required to set the notification policies of alert rules.
+	ruleRoutes RuleRouteSetter
+	// alerts is optional and required to send test notifications of alert rules.
+	alerts AlertSender
+	// ac authorizes the operations that are not scoped to an organization.
+	ac accesscontrol.AccessControl
+	// clock is the time source of the expiry of alert rules.
+	clock clock.Clock
+}
+
+// AlertRuleServiceOptions are the optional collaborators of the AlertRuleService. The features
+// that depend on a collaborator that is not set are not available.
+type AlertRuleServiceOptions struct {
+	// Sources records the files that rules are provisioned from.
+	Sources ProvisioningSourceStore
+	// Dashboards checks that linked dashboards exist.
+	Dashboards DashboardProvider
+	// Datasources resolves the data sources of imported rules.
+	Datasources DataSourceLookup
+	// IntervalLimits limits the intervals of rule groups per organization.
+	IntervalLimits IntervalLimitStore
+	// LibraryQueries stores the queries that rules share.
+	LibraryQueries LibraryQueryStore
+	// ReplaceJournals applies rule group replaces in batches.
+	ReplaceJournals RuleGroupReplaceJournalStore
+	// Drafts stores the drafts of rule groups.
+	Drafts RuleGroupDraftStore
+	// StateSummaries summarizes the alert instances of rules.
+	StateSummaries StateSummaryStore
+	// EvaluationDurations estimates the evaluation cost of rule groups.
+	EvaluationDurations EvaluationDurationProvider
+	// GroupNotifier is informed about committed changes of rule groups.
+	GroupNotifier RuleGroupChangeNotifier
+	// Events carries the state changes of alert instances to subscribers.
+	Events bus.Bus
+	// RuleRoutes sets the notification policies of alert rules.
+	RuleRoutes RuleRouteSetter
+	// Alerts sends test notifications of alert rules.
+	Alerts AlertSender
+	// AccessControl authorizes the operations that are not scoped to an organization.
+	AccessControl accesscontrol.AccessControl
+	// Quotas enforces the alert rule quotas of organizations that the ruler API enforces.
+	Quotas QuotaService
 }
 
 func NewAlertRuleService(ruleStore store.RuleStore,
 	provenanceStore ProvisioningStore,
+	contactPointValidator ContactPointValidator,
+	quota QuotaChecker,
 	xact TransactionManager,
 	defaultInterval int64,
-	log log.Logger) *AlertRuleService {
+	cfg AlertRuleServiceConfig,
+	policy ResourcePolicy,
+	log log.Logger,
+	opts AlertRuleServiceOptions) *AlertRuleService {
 	return &AlertRuleService{
-		defaultInterval: defaultInterval,
-		ruleStore:       ruleStore,
-		provenanceStore: provenanceStore,
-		xact:            xact,
-		log:             log,
+		cfg:                   cfg,
+		defaultInterval:       defaultInterval,
+		ruleStore:             ruleStore,
+		provenanceStore:       provenanceStore,
+		sources:               opts.Sources,
+		contactPointValidator: contactPointValidator,
+		dashboards:            opts.Dashboards,
+		datasources:           opts.Datasources,
+		intervalLimits:        opts.IntervalLimits,
+		libraryQueries:        opts.LibraryQueries,
+		replaceJournals:       opts.ReplaceJournals,
+		drafts:                opts.Drafts,
+		stateSummaries:        opts.StateSummaries,
+		evaluationDurations:   opts.EvaluationDurations,
+		groupNotifier:         opts.GroupNotifier,
+		events:                opts.Events,
+		quota:                 quota,
+		quotas:                opts.Quotas,
+		ruleRoutes:            opts.RuleRoutes,
+		alerts:                opts.Alerts,
+		xact:                  xact,
+		log:                   log,
+		policy:                policy,
+		ac:                    opts.AccessControl,
+		createLocks:           newKeyedMutex(),
+		clock:                 clock.New(),
 	}
 }
 
-func (service *AlertRuleService) GetAlertRule(ctx context.Context, orgID int64, ruleUID string) (models.AlertRule, models.Provenance, error) {
+// GetAlertRule returns the alert rule of the organization and its provenance. It returns
+// models.ErrAlertRuleNotFound if the organization has no rule with the UID, whether or not another
+// organization has one, so that the rules of other organizations cannot be discovered.
+func (service *AlertRuleService) GetAlertRule(ctx context.Context, orgID int64, ruleUID string) (_ models.AlertRule, _ models.Provenance, err error) {
+	defer wrapServiceError(&err)
+	ctx, cancel := withTimeout(ctx, service.cfg.OperationTimeouts.Get)
+	defer cancel()
 	query := &models.GetAlertRuleByUIDQuery{
 		OrgID: orgID,
 		UID:   ruleUID,
 	}
-	err := service.ruleStore.GetAlertRuleByUID(ctx, query)
+	err = service.ruleStore.GetAlertRuleByUID(ctx, query)
 	if err != nil {
 		return models.AlertRule{}, models.ProvenanceNone, err
 	}
+	if query.Result == nil {
+		return models.AlertRule{}, models.ProvenanceNone, models.ErrAlertRuleNotFound
+	}
+	if err := service.checkNamespaces(ctx, query.Result.NamespaceUID); err != nil {
+		return models.AlertRule{}, models.ProvenanceNone, err
+	}
 	provenance, err := service.provenanceStore.GetProvenance(ctx, query.Result, orgID)
 	if err != nil {
 		return models.AlertRule{}, models.ProvenanceNone, err
@@ -50,7 +250,381 @@ func (service *AlertRuleService) GetAlertRule(ctx context.Context, orgID int64,
 	return *query.Result, provenance, nil
 }
 
-func (service *AlertRuleService) CreateAlertRule(ctx context.Context, rule models.AlertRule, provenance models.Provenance) (models.AlertRule, error) {
+// GetAlertRuleWithStateSummary returns the alert rule like GetAlertRule together with a summary
+// of its alert instances. The summary is nil if state summaries are not available.
+func (service *AlertRuleService) GetAlertRuleWithStateSummary(ctx context.Context, orgID int64, ruleUID string) (_ models.AlertRule, _ models.Provenance, _ *models.AlertRuleStateSummary, err error) {
+	defer wrapServiceError(&err)
+	rule, provenance, err := service.GetAlertRule(ctx, orgID, ruleUID)
+	if err != nil {
+		return models.AlertRule{}, models.ProvenanceNone, nil, err
+	}
+	summaries := service.getStateSummaries(ctx, orgID, ruleUID)
+	return rule, provenance, summaries[ruleUID], nil
+}
+
+// GetNextEvaluation returns the first time at or after now that the alert rule is evaluated, from its
+// interval and evaluation offset.
+func (service *AlertRuleService) GetNextEvaluation(ctx context.Context, orgID int64, ruleUID string, now time.Time) (_ time.Time, err error) {
+	defer wrapServiceError(&err)
+	rule, _, err := service.GetAlertRule(ctx, orgID, ruleUID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return rule.NextEvaluation(now), nil
+}
+
+// GetAlertRulesForDashboard returns all alert rules of an organization that
+// are linked to the dashboard with the given UID.
+func (service *AlertRuleService) GetAlertRulesForDashboard(ctx context.Context, orgID int64, dashboardUID string) (_ []models.AlertRule, err error) {
+	defer wrapServiceError(&err)
+	ctx, cancel := withTimeout(ctx, service.cfg.OperationTimeouts.List)
+	defer cancel()
+	if dashboardUID == "" {
+		return []models.AlertRule{}, nil
+	}
+	query := &models.ListAlertRulesQuery{
+		OrgID:        orgID,
+		DashboardUID: dashboardUID,
+	}
+	scope, err := service.callerNamespaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !scope.restrict(query) {
+		return []models.AlertRule{}, nil
+	}
+	err = service.ruleStore.ListAlertRules(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	rules := make([]models.AlertRule, 0, len(query.Result))
+	for _, rule := range query.Result {
+		rules = append(rules, *rule)
+	}
+	return rules, nil
+}
+
+// GetAlertRulesByDataSource returns all alert rules of an organization that query the data source with the given UID.
+func (service *AlertRuleService) GetAlertRulesByDataSource(ctx context.Context, orgID int64, datasourceUID string) (_ []models.AlertRule, err error) {
+	defer wrapServiceError(&err)
+	ctx, cancel := withTimeout(ctx, service.cfg.OperationTimeouts.List)
+	defer cancel()
+	result, err := service.ruleStore.GetAlertRulesByDataSource(ctx, orgID, datasourceUID)
+	if err != nil {
+		return nil, err
+	}
+	rules := make([]models.AlertRule, 0, len(result))
+	for _, rule := range result {
+		rules = append(rules, *rule)
+	}
+	return service.filterNamespaces(ctx, rules)
+}
+
+// GetAlertRulesByOwner returns all alert rules of an organization that are managed by the owner.
+func (service *AlertRuleService) GetAlertRulesByOwner(ctx context.Context, orgID int64, owner string) (_ []models.AlertRule, err error) {
+	defer wrapServiceError(&err)
+	if owner == "" {
+		return nil, fmt.Errorf("%w: owner must not be empty", ErrValidation)
+	}
+	ctx, cancel := withTimeout(ctx, service.cfg.OperationTimeouts.List)
+	defer cancel()
+	result, err := service.ruleStore.GetAlertRulesByOwner(ctx, orgID, owner)
+	if err != nil {
+		return nil, err
+	}
+	rules := make([]models.AlertRule, 0, len(result))
+	for _, rule := range result {
+		rules = append(rules, *rule)
+	}
+	return service.filterNamespaces(ctx, rules)
+}
+
+// CheckDataSourceDelete is called before a data source is deleted. If BlockDSDeleteIfUsed is set,
+// it returns ErrDataSourceInUse with the UIDs of the alert rules that query the data source.
+func (service *AlertRuleService) CheckDataSourceDelete(ctx context.Context, orgID int64, datasourceUID string) (err error) {
+	defer wrapServiceError(&err)
+	if !service.cfg.BlockDSDeleteIfUsed {
+		return nil
+	}
+	ctx, cancel := withTimeout(ctx, service.cfg.OperationTimeouts.List)
+	defer cancel()
+	// the rules of all namespaces use the data source, whichever namespaces the caller is restricted to.
+	rules, err := service.ruleStore.GetAlertRulesByDataSource(ctx, orgID, datasourceUID)
+	if err != nil {
+		return err
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+	uids := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		uids = append(uids, rule.UID)
+	}
+	return fmt.Errorf("%w: %s", ErrDataSourceInUse, strings.Join(uids, ", "))
+}
+
+// ListAlertRulesOptions controls the order and the pages of the rules returned by ListAlertRules.
+type ListAlertRulesOptions struct {
+	// SortBy lists the fields to sort by, in order of precedence. Rules with equal fields are
+	// sorted by ID, so that pages are stable for every order.
+	SortBy []models.SortField
+	// Desc sorts all fields in descending order.
+	Desc bool
+	// Limit is the maximum number of rules to return. 0 returns all rules.
+	Limit int
+	// Offset is the number of rules to skip. It is ignored without Limit.
+	Offset int
+}
+
+// ListAlertRules returns all alert rules of an organization. It returns models.ErrInvalidSortField
+// if the options contain a field that rules cannot be sorted by.
+func (service *AlertRuleService) ListAlertRules(ctx context.Context, orgID int64, opts ListAlertRulesOptions) (_ []models.AlertRule, err error) {
+	defer wrapServiceError(&err)
+	ctx, cancel := withTimeout(ctx, service.cfg.OperationTimeouts.List)
+	defer cancel()
+	query := &models.ListAlertRulesQuery{
+		OrgID:    orgID,
+		SortBy:   opts.SortBy,
+		SortDesc: opts.Desc,
+		Limit:    opts.Limit,
+		Offset:   opts.Offset,
+	}
+	scope, err := service.callerNamespaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !scope.restrict(query) {
+		return []models.AlertRule{}, nil
+	}
+	if err := service.ruleStore.ListAlertRules(ctx, query); err != nil {
+		return nil, err
+	}
+	rules := make([]models.AlertRule, 0, len(query.Result))
+	for _, rule := range query.Result {
+		rules = append(rules, *rule)
+	}
+	return rules, nil
+}
+
+// SearchAlertRulesOptions filters and pages the rules returned by SearchAlertRules.
+type SearchAlertRulesOptions struct {
+	// Title matches rules whose title contains it, ignoring case.
+	Title string
+	// TitlePrefix only matches rules whose title starts with Title. These searches can use an index,
+	// while searches anywhere in the title have to look at every rule of the organization.
+	TitlePrefix bool
+	// Limit is the maximum number of rules to return. 0 returns all matching rules.
+	Limit int
+	// Offset is the number of matching rules to skip. It is ignored without Limit.
+	Offset int
+}
+
+// SearchAlertRules returns the alert rules of an organization whose title matches the search, sorted by title.
+func (service *AlertRuleService) SearchAlertRules(ctx context.Context, orgID int64, opts SearchAlertRulesOptions) (_ []models.AlertRule, err error) {
+	defer wrapServiceError(&err)
+	ctx, cancel := withTimeout(ctx, service.cfg.OperationTimeouts.List)
+	defer cancel()
+	if opts.Limit < 0 || opts.Offset < 0 {
+		return nil, fmt.Errorf("%w: limit and offset must not be negative", ErrValidation)
+	}
+	query := &models.ListAlertRulesQuery{
+		OrgID:       orgID,
+		TitleSearch: opts.Title,
+		TitlePrefix: opts.TitlePrefix,
+		Limit:       opts.Limit,
+		Offset:      opts.Offset,
+	}
+	if opts.Title == "" {
+		query.SortBy = []models.SortField{models.SortByTitle}
+	}
+	scope, err := service.callerNamespaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !scope.restrict(query) {
+		return []models.AlertRule{}, nil
+	}
+	if err := service.ruleStore.ListAlertRules(ctx, query); err != nil {
+		return nil, err
+	}
+	rules := make([]models.AlertRule, 0, len(query.Result))
+	for _, rule := range query.Result {
+		rules = append(rules, *rule)
+	}
+	return rules, nil
+}
+
+// AlertRulesByProvenanceOptions page through the rules returned by GetAlertRulesByProvenance.
+type AlertRulesByProvenanceOptions struct {
+	// Limit is the maximum number of rules of a page. 0 returns all remaining rules.
+	Limit int
+	// PageToken is the token returned with the previous page. It is empty for the first page.
+	PageToken string
+}
+
+// GetAlertRulesByProvenance returns the alert rules of the organization with the given provenance,
+// sorted by UID. If there are more rules than the limit, it also returns the token of the next page.
+// The token continues after the UID of the last returned rule, so that paging is stable while rules
+// are created or deleted. Rules without provenance have no provenance record and cannot be listed.
+func (service *AlertRuleService) GetAlertRulesByProvenance(ctx context.Context, orgID int64, provenance models.Provenance, opts AlertRulesByProvenanceOptions) (_ []models.AlertRule, nextPageToken string, err error) {
+	defer wrapServiceError(&err)
+	ctx, cancel := withTimeout(ctx, service.cfg.OperationTimeouts.List)
+	defer cancel()
+	if provenance == models.ProvenanceNone {
+		return nil, "", fmt.Errorf("%w: rules cannot be listed without a provenance", ErrValidation)
+	}
+	if opts.Limit < 0 {
+		return nil, "", fmt.Errorf("%w: limit must not be negative", ErrValidation)
+	}
+	after, err := decodePageToken(opts.PageToken)
+	if err != nil {
+		return nil, "", err
+	}
+	provenances, err := service.provenanceStore.GetProvenances(ctx, orgID, (&models.AlertRule{}).ResourceType())
+	if err != nil {
+		return nil, "", err
+	}
+	uids := make([]string, 0)
+	for uid, p := range provenances {
+		if p == provenance && uid > after {
+			uids = append(uids, uid)
+		}
+	}
+	sort.Strings(uids)
+	scope, err := service.callerNamespaces(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	// the rules of the UIDs are loaded a page at a time. Provenance records of deleted rules can be left
+	// behind and rules can be outside of the namespaces of the caller, so the next UIDs are loaded until
+	// the page is full. One more rule than the limit is collected to know whether there is a next page.
+	want := len(uids)
+	if opts.Limit > 0 && opts.Limit < want {
+		want = opts.Limit + 1
+	}
+	rules := make([]models.AlertRule, 0, want)
+	for len(uids) > 0 && len(rules) < want {
+		batch := uids
+		if len(batch) > want-len(rules) {
+			batch = batch[:want-len(rules)]
+		}
+		uids = uids[len(batch):]
+		query := &models.ListAlertRulesQuery{OrgID: orgID, RuleUIDs: batch}
+		if err := service.ruleStore.ListAlertRules(ctx, query); err != nil {
+			return nil, "", err
+		}
+		byUID := make(map[string]*models.AlertRule, len(query.Result))
+		for _, rule := range query.Result {
+			byUID[rule.UID] = rule
+		}
+		for _, uid := range batch {
+			rule, ok := byUID[uid]
+			if !ok || !scope.allows(rule.NamespaceUID) {
+				continue
+			}
+			rules = append(rules, *rule)
+		}
+	}
+	if opts.Limit > 0 && len(rules) > opts.Limit {
+		rules = rules[:opts.Limit]
+		nextPageToken = encodePageToken(rules[len(rules)-1].UID)
+	}
+	return rules, nextPageToken, nil
+}
+
+func encodePageToken(uid string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(uid))
+}
+
+func decodePageToken(token string) (string, error) {
+	uid, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid page token", ErrValidation)
+	}
+	return string(uid), nil
+}
+
+// ListAlertRulesWithStateSummaries returns the alert rules like ListAlertRules together with
+// summaries of their alert instances by rule UID. Rules without summary are not part of the map.
+func (service *AlertRuleService) ListAlertRulesWithStateSummaries(ctx context.Context, orgID int64, opts ListAlertRulesOptions) (_ []models.AlertRule, _ map[string]*models.AlertRuleStateSummary, err error) {
+	defer wrapServiceError(&err)
+	rules, err := service.ListAlertRules(ctx, orgID, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return rules, service.getStateSummaries(ctx, orgID), nil
+}
+
+// getStateSummaries summarizes the alert instances of the rules with the given UIDs, or of all rules
+// of the organization if there are none. State summaries are an addition to the rules, so they are
+// left out if they are not available or cannot be read.
+func (service *AlertRuleService) getStateSummaries(ctx context.Context, orgID int64, ruleUIDs ...string) map[string]*models.AlertRuleStateSummary {
+	if service.stateSummaries == nil {
+		return nil
+	}
+	summaries, err := service.stateSummaries.GetAlertRuleStateSummaries(ctx, orgID, ruleUIDs...)
+	if err != nil {
+		service.log.Warn("failed to summarize the state of alert rules", "org", orgID, "err", err)
+		return nil
+	}
+	return summaries
+}
+
+func (service *AlertRuleService) CreateAlertRule(ctx context.Context, rule models.AlertRule, provenance models.Provenance) (_ models.AlertRule, err error) {
+	defer wrapServiceError(&err)
+	rule, _, err = service.CreateAlertRuleWithIssues(ctx, rule, provenance)
+	return rule, err
+}
+
+// CreateAlertRuleWithIssues creates the alert rule and returns the issues found
+// with it that were not severe enough to reject it.
+func (service *AlertRuleService) CreateAlertRuleWithIssues(ctx context.Context, rule models.AlertRule, provenance models.Provenance) (_ models.AlertRule, _ []models.ValidationIssue, err error) {
+	defer wrapServiceError(&err)
+	return service.createAlertRule(ctx, rule, provenance, CreateAlertRuleOptions{})
+}
+
+// CreateAlertRuleWithOptions is like CreateAlertRuleWithIssues but creates the alert rule
+// according to the options.
+func (service *AlertRuleService) CreateAlertRuleWithOptions(ctx context.Context, rule models.AlertRule, provenance models.Provenance, opts CreateAlertRuleOptions) (_ models.AlertRule, _ []models.ValidationIssue, err error) {
+	defer wrapServiceError(&err)
+	return service.createAlertRule(ctx, rule, provenance, opts)
+}
+
+func (service *AlertRuleService) createAlertRule(ctx context.Context, rule models.AlertRule, provenance models.Provenance, opts CreateAlertRuleOptions) (models.AlertRule, []models.ValidationIssue, error) {
+	if err := service.policy.checkMutation(rule.OrgID, ResourceTypeAlertRules, provenance); err != nil {
+		return models.AlertRule{}, nil, err
+	}
+	if err := service.checkNamespaces(ctx, rule.NamespaceUID); err != nil {
+		return models.AlertRule{}, nil, err
+	}
+	ctx, cancel := withTimeout(ctx, service.cfg.OperationTimeouts.Create)
+	defer cancel()
+	if err := service.checkRuleCap(ctx, rule.OrgID, 1); err != nil {
+		return models.AlertRule{}, nil, err
+	}
+	if _, ok := service.cfg.RequireProvenanceOrgs[rule.OrgID]; ok && provenance == models.ProvenanceNone {
+		return models.AlertRule{}, nil, fmt.Errorf("%w: alert rules of organization %d must be created with a provenance", ErrValidation, rule.OrgID)
+	}
+	if err := service.materializeLibraryQueries(ctx, &rule); err != nil {
+		return models.AlertRule{}, nil, err
+	}
+	title, err := service.normalizeName("title", rule.Title)
+	if err != nil {
+		return models.AlertRule{}, nil, err
+	}
+	group, err := service.normalizeName("rule group", rule.RuleGroup)
+	if err != nil {
+		return models.AlertRule{}, nil, err
+	}
+	rule.Title, rule.RuleGroup = title, group
+	setRuleDefaults(&rule)
+	if err := service.validateAlertRule(ctx, rule); err != nil {
+		return models.AlertRule{}, nil, err
+	}
+	rule.Annotations = withDashboardAnnotations(rule)
+	if err := service.validateRuleGroupName(ctx, rule); err != nil {
+		return models.AlertRule{}, nil, err
+	}
 	if rule.UID == "" {
 		rule.UID = util.GenerateShortUID()
 	}
@@ -59,10 +633,40 @@ func (service *AlertRuleService) CreateAlertRule(ctx context.Context, rule model
 	if err != nil && errors.Is(err, store.ErrAlertRuleGroupNotFound) {
 		interval = service.defaultInterval
 	} else if err != nil {
-		return models.AlertRule{}, err
+		return models.AlertRule{}, nil, err
+	}
+	if err := service.validateGroupInterval(ctx, rule.OrgID, interval); err != nil {
+		return models.AlertRule{}, nil, err
 	}
 	rule.IntervalSeconds = interval
+	if err := validateEvalOffset(rule); err != nil {
+		return models.AlertRule{}, nil, err
+	}
+	if rule.For == 0 {
+		defaultFor := service.cfg.DefaultFor
+		if opts.DefaultFor != nil {
+			defaultFor = *opts.DefaultFor
+		}
+		if err := validateDefaultFor(defaultFor, interval); err != nil {
+			return models.AlertRule{}, nil, err
+		}
+		rule.For = defaultFor
+	}
 	rule.Updated = time.Now()
+	if rule.RuleGroupIndex <= 0 {
+		if rule.RuleGroupIndex, err = service.nextRuleGroupIndex(ctx, rule.OrgID, rule.NamespaceUID, rule.RuleGroup); err != nil {
+			return models.AlertRule{}, nil, err
+		}
+	}
+	issues := append(alertRuleWarnings(rule), service.groupEvaluationCostIssues(ctx, rule)...)
+	// Two identical rules created at the same time would both pass validation before either is
+	// committed. Serializing them makes sure that the second one sees the first one and fails
+	// with ErrAlertRuleDuplicateTitle instead of a unique constraint violation of the database.
+	unlock := service.createLocks.Lock(createLockKey(rule))
+	defer unlock()
+	if err := service.checkTitleUniqueness(ctx, rule); err != nil {
+		return models.AlertRule{}, nil, err
+	}
 	err = service.xact.InTransaction(ctx, func(ctx context.Context) error {
 		ids, err := service.ruleStore.InsertAlertRules(ctx, []models.AlertRule{
 			rule,
@@ -82,31 +686,133 @@ func (service *AlertRuleService) CreateAlertRule(ctx context.Context, rule model
 		return service.provenanceStore.SetProvenance(ctx, &rule, rule.OrgID, provenance)
 	})
 	if err != nil {
+		return models.AlertRule{}, nil, err
+	}
+	service.publishRuleEvent(newRuleEvent(RuleEventCreated, rule, provenance))
+	return rule, issues, nil
+}
+
+// UpdateAlertRule replaces the alert rule with the given one. The content of the rule, such as its
+// labels, annotations and notification settings, is taken as is, so omitted content is removed.
+// The fields that the server manages are kept, see keepServerManagedFields.
+func (service *AlertRuleService) UpdateAlertRule(ctx context.Context, rule models.AlertRule, provenance models.Provenance) (_ models.AlertRule, err error) {
+	defer wrapServiceError(&err)
+	rule, _, err = service.UpdateAlertRuleWithIssues(ctx, rule, provenance)
+	return rule, err
+}
+
+// UpdateAlertRuleWithIssues updates the alert rule and returns the issues found
+// with it that were not severe enough to reject it.
+func (service *AlertRuleService) UpdateAlertRuleWithIssues(ctx context.Context, rule models.AlertRule, provenance models.Provenance) (_ models.AlertRule, _ []models.ValidationIssue, err error) {
+	defer wrapServiceError(&err)
+	if err := service.policy.checkMutation(rule.OrgID, ResourceTypeAlertRules, provenance); err != nil {
+		return models.AlertRule{}, nil, err
+	}
+	return service.updateAlertRule(ctx, rule, provenance, 0)
+}
+
+// RestoreAlertRule updates the alert rule to the content it had in the given version.
+// The restored rule gets a new version, like every other update. The interval, evaluation strategy
+// and labels of the rule group are not restored, because they are shared by all rules of the group.
+func (service *AlertRuleService) RestoreAlertRule(ctx context.Context, orgID int64, ruleUID string, version int64, provenance models.Provenance) (_ models.AlertRule, err error) {
+	defer wrapServiceError(&err)
+	if err := service.policy.checkMutation(orgID, ResourceTypeAlertRules, provenance); err != nil {
 		return models.AlertRule{}, err
 	}
-	return rule, nil
+	storedRule, _, err := service.GetAlertRule(ctx, orgID, ruleUID)
+	if err != nil {
+		return models.AlertRule{}, err
+	}
+	ruleVersion, err := service.ruleStore.GetAlertRuleVersion(ctx, orgID, ruleUID, version)
+	if err != nil {
+		return models.AlertRule{}, err
+	}
+	rule := storedRule
+	rule.NamespaceUID = ruleVersion.RuleNamespaceUID
+	rule.RuleGroup = ruleVersion.RuleGroup
+	rule.Title = ruleVersion.Title
+	rule.Condition = ruleVersion.Condition
+	rule.Data = ruleVersion.Data
+	rule.NoDataState = ruleVersion.NoDataState
+	rule.ExecErrState = ruleVersion.ExecErrState
+	rule.For = ruleVersion.For
+	rule.Annotations = ruleVersion.Annotations
+	rule.Labels = ruleVersion.Labels
+	rule.NotificationSettings = ruleVersion.NotificationSettings
+	rule.TitleTemplate = ruleVersion.TitleTemplate
+	rule.ActiveWindow = ruleVersion.ActiveWindow
+	rule.EvalOrder = ruleVersion.EvalOrder
+	rule.EvalOffsetSeconds = ruleVersion.EvalOffset
+	rule.ExpiresAt = ruleVersion.ExpiresAt
+	rule.EvalEveryN = ruleVersion.EvalEveryN
+	rule.WarmUpEvals = ruleVersion.WarmUpEvals
+	rule.ManagedBy = ruleVersion.ManagedBy
+	rule, _, err = service.updateAlertRule(ctx, rule, provenance, version)
+	return rule, err
 }
 
-func (service *AlertRuleService) UpdateAlertRule(ctx context.Context, rule models.AlertRule, provenance models.Provenance) (models.AlertRule, error) {
+func (service *AlertRuleService) updateAlertRule(ctx context.Context, rule models.AlertRule, provenance models.Provenance, restoredFrom int64) (models.AlertRule, []models.ValidationIssue, error) {
+	ctx, cancel := withTimeout(ctx, service.cfg.OperationTimeouts.Update)
+	defer cancel()
+	if err := service.materializeLibraryQueries(ctx, &rule); err != nil {
+		return models.AlertRule{}, nil, err
+	}
+	title, err := service.normalizeName("title", rule.Title)
+	if err != nil {
+		return models.AlertRule{}, nil, err
+	}
+	rule.Title = title
 	storedRule, storedProvenance, err := service.GetAlertRule(ctx, rule.OrgID, rule.UID)
 	if err != nil {
-		return models.AlertRule{}, err
+		return models.AlertRule{}, nil, err
+	}
+	// GetAlertRule checked the namespace the rule is in, this checks the one it moves to.
+	if err := service.checkNamespaces(ctx, rule.NamespaceUID); err != nil {
+		return models.AlertRule{}, nil, err
+	}
+	// A single rule cannot rename its rule group, so rules that stay in their group keep its
+	// stored name even if it is not normalized.
+	if rule.RuleGroup != storedRule.RuleGroup {
+		if rule.RuleGroup, err = service.normalizeName("rule group", rule.RuleGroup); err != nil {
+			return models.AlertRule{}, nil, err
+		}
+	}
+	keepServerManagedFields(&rule, storedRule)
+	setRuleDefaults(&rule)
+	if err := service.validateAlertRule(ctx, rule); err != nil {
+		return models.AlertRule{}, nil, err
 	}
+	rule.Annotations = withDashboardAnnotations(rule)
 	if storedProvenance != provenance && storedProvenance != models.ProvenanceNone {
-		return models.AlertRule{}, fmt.Errorf("cannot changed provenance from '%s' to '%s'", storedProvenance, provenance)
+		return models.AlertRule{}, nil, fmt.Errorf("%w: cannot change provenance from '%s' to '%s'", ErrProvenanceMismatch, storedProvenance, provenance)
+	}
+	if rule.RuleGroup != storedRule.RuleGroup || rule.NamespaceUID != storedRule.NamespaceUID {
+		if err := service.validateRuleGroupName(ctx, rule); err != nil {
+			return models.AlertRule{}, nil, err
+		}
 	}
 	rule.Updated = time.Now()
-	rule.ID = storedRule.ID
 	rule.IntervalSeconds, err = service.ruleStore.GetRuleGroupInterval(ctx, rule.OrgID, rule.NamespaceUID, rule.RuleGroup)
 	if err != nil {
-		return models.AlertRule{}, err
+		return models.AlertRule{}, nil, err
+	}
+	if err := service.validateGroupInterval(ctx, rule.OrgID, rule.IntervalSeconds); err != nil {
+		return models.AlertRule{}, nil, err
 	}
+	if err := validateEvalOffset(rule); err != nil {
+		return models.AlertRule{}, nil, err
+	}
+	if err := service.checkTitleUniqueness(ctx, rule); err != nil {
+		return models.AlertRule{}, nil, err
+	}
+	issues := append(alertRuleWarnings(rule), service.groupEvaluationCostIssues(ctx, rule)...)
 	service.log.Info("update rule", "ID", storedRule.ID, "labels", fmt.Sprintf("%+v", rule.Labels))
 	err = service.xact.InTransaction(ctx, func(ctx context.Context) error {
 		err := service.ruleStore.UpdateAlertRules(ctx, []store.UpdateRule{
 			{
-				Existing: &storedRule,
-				New:      rule,
+				Existing:     &storedRule,
+				New:          rule,
+				RestoredFrom: restoredFrom,
 			},
 		})
 		if err != nil {
@@ -119,12 +825,57 @@ func (service *AlertRuleService) UpdateAlertRule(ctx context.Context, rule model
 		return service.provenanceStore.SetProvenance(ctx, &rule, rule.OrgID, provenance)
 	})
 	if err != nil {
-		return models.AlertRule{}, err
+		return models.AlertRule{}, nil, err
+	}
+	service.publishRuleEvent(newRuleEvent(RuleEventUpdated, rule, provenance))
+	return rule, issues, nil
+}
+
+// keepServerManagedFields copies the fields of the stored rule that callers of an update do not
+// manage to the updated rule, so that they are not reset when a caller omits them:
+//   - the ID, and the version that the update gives the rule.
+//   - the position of the rule in its group, if the caller does not move it.
+//   - the expiry, which is set with SetAlertRuleTTL.
+//   - the link to a dashboard panel. A caller changes the link by sending another one, and removes it
+//     by sending an empty dashboard UID without panel ID.
+func keepServerManagedFields(rule *models.AlertRule, stored models.AlertRule) {
+	rule.ID = stored.ID
+	rule.Version = stored.Version + 1
+	if rule.RuleGroupIndex <= 0 {
+		rule.RuleGroupIndex = stored.RuleGroupIndex
+	}
+	// the evaluation order is only meaningful within the group it was set in.
+	if rule.EvalOrder <= 0 && rule.GetGroupKey() == stored.GetGroupKey() {
+		rule.EvalOrder = stored.EvalOrder
+	}
+	// the labels of the group are changed with the group, a rule that moves takes the labels of its new group.
+	if rule.GetGroupKey() == stored.GetGroupKey() {
+		rule.GroupLabels = stored.GroupLabels
+	} else {
+		rule.GroupLabels = nil
+	}
+	if rule.ExpiresAt == nil {
+		rule.ExpiresAt = stored.ExpiresAt
+	}
+	switch {
+	case rule.DashboardUID == nil && rule.PanelID == nil:
+		rule.DashboardUID, rule.PanelID = stored.DashboardUID, stored.PanelID
+	case rule.DashboardUID != nil && *rule.DashboardUID == "" && rule.PanelID == nil:
+		rule.DashboardUID = nil
+		rule.Annotations = withoutDashboardAnnotations(rule.Annotations)
 	}
-	return rule, err
 }
 
-func (service *AlertRuleService) DeleteAlertRule(ctx context.Context, orgID int64, ruleUID string, provenance models.Provenance) error {
+func (service *AlertRuleService) DeleteAlertRule(ctx context.Context, orgID int64, ruleUID string, provenance models.Provenance) (err error) {
+	defer wrapServiceError(&err)
+	if err := service.policy.checkMutation(orgID, ResourceTypeAlertRules, provenance); err != nil {
+		return err
+	}
+	ctx, cancel := withTimeout(ctx, service.cfg.OperationTimeouts.Delete)
+	defer cancel()
+	if err := service.checkRuleNamespace(ctx, orgID, ruleUID); err != nil {
+		return err
+	}
 	rule := &models.AlertRule{
 		OrgID: orgID,
 		UID:   ruleUID,
@@ -135,17 +886,816 @@ func (service *AlertRuleService) DeleteAlertRule(ctx context.Context, orgID int6
 		return err
 	}
 	if storedProvenance != provenance && storedProvenance != models.ProvenanceNone {
-		return fmt.Errorf("cannot delete with provided provenance '%s', needs '%s'", provenance, storedProvenance)
+		return fmt.Errorf("%w: cannot delete with provided provenance '%s', needs '%s'", ErrProvenanceMismatch, provenance, storedProvenance)
 	}
-	return service.xact.InTransaction(ctx, func(ctx context.Context) error {
+	err = service.xact.InTransaction(ctx, func(ctx context.Context) error {
 		err := service.ruleStore.DeleteAlertRulesByUID(ctx, orgID, ruleUID)
 		if err != nil {
 			return err
 		}
+		if service.sources != nil {
+			if err := service.sources.DeleteProvisioningSource(ctx, rule, rule.OrgID); err != nil {
+				return err
+			}
+		}
 		return service.provenanceStore.DeleteProvenance(ctx, rule, rule.OrgID)
 	})
+	if err != nil {
+		return err
+	}
+	service.publishRuleEvent(newRuleEvent(RuleEventDeleted, *rule, provenance))
+	return nil
+}
+
+// AdoptAlertRules sets the provenance of rules that are not provisioned yet without changing the
+// rules themselves, so that their version stays the same. It fails without adopting any rule if one
+// of the rules does not exist or is already provisioned.
+func (service *AlertRuleService) AdoptAlertRules(ctx context.Context, orgID int64, uids []string, provenance models.Provenance) (err error) {
+	defer wrapServiceError(&err)
+	if err := service.policy.checkMutation(orgID, ResourceTypeAlertRules, provenance); err != nil {
+		return err
+	}
+	ctx, cancel := withTimeout(ctx, service.cfg.OperationTimeouts.Update)
+	defer cancel()
+	if provenance == models.ProvenanceNone {
+		return fmt.Errorf("%w: rules cannot be adopted without a provenance", ErrValidation)
+	}
+	return service.xact.InTransaction(ctx, func(ctx context.Context) error {
+		for _, uid := range uids {
+			query := &models.GetAlertRuleByUIDQuery{OrgID: orgID, UID: uid}
+			if err := service.ruleStore.GetAlertRuleByUID(ctx, query); err != nil {
+				return err
+			}
+			if err := service.checkNamespaces(ctx, query.Result.NamespaceUID); err != nil {
+				return err
+			}
+			storedProvenance, err := service.provenanceStore.GetProvenance(ctx, query.Result, orgID)
+			if err != nil {
+				return err
+			}
+			if storedProvenance != models.ProvenanceNone {
+				return fmt.Errorf("%w: cannot adopt rule '%s' with provenance '%s'", ErrProvenanceMismatch, uid, storedProvenance)
+			}
+			if err := service.provenanceStore.SetProvenance(ctx, query.Result, orgID, provenance); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// UnmanageAlertRule removes the provenance of the alert rule, so that it can be changed like rules
+// that were not provisioned. Since this bypasses the provisioning of the rule, it is rejected like
+// other changes of the provenance unless force is set, and the caller of the context must be
+// allowed to unmanage alert rules.
+func (service *AlertRuleService) UnmanageAlertRule(ctx context.Context, orgID int64, uid string, force bool) (err error) {
+	defer wrapServiceError(&err)
+	if err := service.policy.checkMutation(orgID, ResourceTypeAlertRules, models.ProvenanceNone); err != nil {
+		return err
+	}
+	ctx, cancel := withTimeout(ctx, service.cfg.OperationTimeouts.Update)
+	defer cancel()
+	rule, storedProvenance, err := service.GetAlertRule(ctx, orgID, uid)
+	if err != nil {
+		return err
+	}
+	if storedProvenance == models.ProvenanceNone {
+		return nil
+	}
+	if !force {
+		return fmt.Errorf("%w: cannot change provenance from '%s' to '%s'", ErrProvenanceMismatch, storedProvenance, models.ProvenanceNone)
+	}
+	if err := service.authorizeSystemAccess(ctx, accesscontrol.ActionAlertingRuleUnmanage); err != nil {
+		return err
+	}
+	if err := service.provenanceStore.DeleteProvenance(ctx, &rule, orgID); err != nil {
+		return err
+	}
+	service.log.Warn("removed the provenance of a provisioned alert rule, it is no longer managed by its provisioning",
+		"org", orgID, "uid", uid, "provenance", storedProvenance, "user", callerFromContext(ctx).Login)
+	return nil
+}
+
+// GetRuleGroupInterval returns the interval of a rule group in seconds. It returns
+// store.ErrAlertRuleGroupNotFound if the group has no rules.
+func (service *AlertRuleService) GetRuleGroupInterval(ctx context.Context, orgID int64, namespaceUID, group string) (_ int64, err error) {
+	defer wrapServiceError(&err)
+	if err := service.checkNamespaces(ctx, namespaceUID); err != nil {
+		return 0, err
+	}
+	ctx, cancel := withTimeout(ctx, service.cfg.OperationTimeouts.Get)
+	defer cancel()
+	return service.ruleStore.GetRuleGroupInterval(ctx, orgID, namespaceUID, group)
+}
+
+func (service *AlertRuleService) UpdateAlertGroup(ctx context.Context, orgID int64, folderUID, roulegroup string, interval int64, provenance models.Provenance) (err error) {
+	defer wrapServiceError(&err)
+	if err := service.policy.checkMutation(orgID, ResourceTypeAlertRules, provenance); err != nil {
+		return err
+	}
+	if err := service.checkNamespaces(ctx, folderUID); err != nil {
+		return err
+	}
+	ctx, cancel := withTimeout(ctx, service.cfg.OperationTimeouts.Update)
+	defer cancel()
+	if err := service.validateGroupInterval(ctx, orgID, interval); err != nil {
+		return err
+	}
+	err = service.xact.InTransaction(ctx, func(ctx context.Context) error {
+		if err := service.checkRuleGroupProvenance(ctx, orgID, folderUID, roulegroup, provenance, "interval"); err != nil {
+			return err
+		}
+		return service.ruleStore.SetRuleGroupInterval(ctx, orgID, folderUID, roulegroup, interval)
+	})
+	if err != nil {
+		return err
+	}
+	service.notifyGroupUpdated(ctx, orgID, folderUID, roulegroup)
+	return nil
+}
+
+// UpdateRuleGroupEvalStrategy changes the evaluation strategy of all rules of the rule group.
+func (service *AlertRuleService) UpdateRuleGroupEvalStrategy(ctx context.Context, orgID int64, folderUID, group, strategy string, provenance models.Provenance) (err error) {
+	defer wrapServiceError(&err)
+	if err := service.policy.checkMutation(orgID, ResourceTypeAlertRules, provenance); err != nil {
+		return err
+	}
+	if err := service.checkNamespaces(ctx, folderUID); err != nil {
+		return err
+	}
+	ctx, cancel := withTimeout(ctx, service.cfg.OperationTimeouts.Update)
+	defer cancel()
+	if err := models.ValidateEvalStrategy(strategy); err != nil {
+		return fmt.Errorf("%w: %s", ErrValidation, err.Error())
+	}
+	err = service.xact.InTransaction(ctx, func(ctx context.Context) error {
+		if err := service.checkRuleGroupProvenance(ctx, orgID, folderUID, group, provenance, "evaluation strategy"); err != nil {
+			return err
+		}
+		return service.ruleStore.UpdateRuleGroupEvalStrategy(ctx, orgID, folderUID, group, strategy)
+	})
+	if err != nil {
+		return err
+	}
+	service.notifyGroupUpdated(ctx, orgID, folderUID, group)
+	return nil
+}
+
+// UpdateRuleGroupLabels replaces the labels of the rule group. They are added to the labels of all rules
+// of the group, whose own labels win on conflicts. Empty labels remove the labels of the group.
+func (service *AlertRuleService) UpdateRuleGroupLabels(ctx context.Context, orgID int64, folderUID, group string, labels map[string]string, provenance models.Provenance) (err error) {
+	defer wrapServiceError(&err)
+	if err := service.policy.checkMutation(orgID, ResourceTypeAlertRules, provenance); err != nil {
+		return err
+	}
+	if err := service.checkNamespaces(ctx, folderUID); err != nil {
+		return err
+	}
+	ctx, cancel := withTimeout(ctx, service.cfg.OperationTimeouts.Update)
+	defer cancel()
+	if err := validateKeys("group labels", labels, service.cfg.AllowedLabelKeys, nil); err != nil {
+		return err
+	}
+	err = service.xact.InTransaction(ctx, func(ctx context.Context) error {
+		query := &models.ListAlertRulesQuery{
+			OrgID:         orgID,
+			NamespaceUIDs: []string{folderUID},
+			RuleGroup:     group,
+		}
+		if err := service.ruleStore.ListAlertRules(ctx, query); err != nil {
+			return err
+		}
+		if len(query.Result) == 0 {
+			return store.ErrAlertRuleGroupNotFound
+		}
+		provenances, err := service.provenanceStore.GetProvenances(ctx, orgID, (&models.AlertRule{}).ResourceType())
+		if err != nil {
+			return err
+		}
+		for _, rule := range query.Result {
+			if storedProvenance, ok := provenances[rule.UID]; ok && storedProvenance != provenance && storedProvenance != models.ProvenanceNone {
+				return fmt.Errorf("%w: cannot change the labels of the group of rule '%s' with provenance '%s', needs '%s'", ErrProvenanceMismatch, rule.UID, provenance, storedProvenance)
+			}
+			updated := *rule
+			updated.GroupLabels = labels
+			if err := validateKeys("labels", updated.EffectiveLabels(), nil, service.cfg.RequiredLabelKeys); err != nil {
+				return err
+			}
+		}
+		return service.ruleStore.UpdateRuleGroupLabels(ctx, orgID, folderUID, group, labels)
+	})
+	if err != nil {
+		return err
+	}
+	service.notifyGroupUpdated(ctx, orgID, folderUID, group)
+	return nil
+}
+
+// checkRuleGroupProvenance returns ErrProvenanceMismatch if a rule of the rule group has a stored provenance
+// that does not allow the change of the group with the provenance. It returns store.ErrAlertRuleGroupNotFound
+// if the group has no rules.
+func (service *AlertRuleService) checkRuleGroupProvenance(ctx context.Context, orgID int64, folderUID, group string, provenance models.Provenance, change string) error {
+	query := &models.ListAlertRulesQuery{
+		OrgID:         orgID,
+		NamespaceUIDs: []string{folderUID},
+		RuleGroup:     group,
+	}
+	if err := service.ruleStore.ListAlertRules(ctx, query); err != nil {
+		return err
+	}
+	if len(query.Result) == 0 {
+		return store.ErrAlertRuleGroupNotFound
+	}
+	provenances, err := service.provenanceStore.GetProvenances(ctx, orgID, (&models.AlertRule{}).ResourceType())
+	if err != nil {
+		return err
+	}
+	for _, rule := range query.Result {
+		if storedProvenance, ok := provenances[rule.UID]; ok && storedProvenance != provenance && storedProvenance != models.ProvenanceNone {
+			return fmt.Errorf("%w: cannot change the %s of the group of rule '%s' with provenance '%s', needs '%s'", ErrProvenanceMismatch, change, rule.UID, provenance, storedProvenance)
+		}
+	}
+	return nil
+}
+
+// notifyGroupUpdated notifies the group change notifier that all rules of the rule group were updated.
+func (service *AlertRuleService) notifyGroupUpdated(ctx context.Context, orgID int64, folderUID, group string) {
+	if service.groupNotifier == nil {
+		return
+	}
+	query := &models.ListAlertRulesQuery{
+		OrgID:         orgID,
+		NamespaceUIDs: []string{folderUID},
+		RuleGroup:     group,
+	}
+	if err := service.ruleStore.ListAlertRules(ctx, query); err != nil {
+		service.log.Warn("failed to list the rules of the updated rule group", "folder", folderUID, "group", group, "err", err)
+		return
+	}
+	updated := make([]string, 0, len(query.Result))
+	for _, rule := range query.Result {
+		updated = append(updated, rule.UID)
+	}
+	service.notifyGroupChange(ctx, RuleGroupChange{
+		OrgID:        orgID,
+		NamespaceUID: folderUID,
+		RuleGroup:    group,
+		Created:      []string{},
+		Updated:      updated,
+		Deleted:      []string{},
+	})
+}
+
+// GetAlertRuleGroup returns the rules of a rule group in their order within the group. It returns
+// store.ErrAlertRuleGroupNotFound if the group has no rules.
+func (service *AlertRuleService) GetAlertRuleGroup(ctx context.Context, orgID int64, namespaceUID, group string) (_ []models.AlertRule, err error) {
+	defer wrapServiceError(&err)
+	if err := service.checkNamespaces(ctx, namespaceUID); err != nil {
+		return nil, err
+	}
+	ctx, cancel := withTimeout(ctx, service.cfg.OperationTimeouts.Get)
+	defer cancel()
+	query := &models.ListAlertRulesQuery{
+		OrgID:         orgID,
+		NamespaceUIDs: []string{namespaceUID},
+		RuleGroup:     group,
+	}
+	if err := service.ruleStore.ListAlertRules(ctx, query); err != nil {
+		return nil, err
+	}
+	if len(query.Result) == 0 {
+		return nil, store.ErrAlertRuleGroupNotFound
+	}
+	models.SortAlertRulesByGroupIndex(query.Result)
+	rules := make([]models.AlertRule, 0, len(query.Result))
+	for _, rule := range query.Result {
+		rules = append(rules, *rule)
+	}
+	return rules, nil
+}
+
+// ReorderRuleGroup moves the rules of a rule group into the given order. The UIDs must be exactly
+// the UIDs of the rules of the group. All rules are updated in a single transaction.
+func (service *AlertRuleService) ReorderRuleGroup(ctx context.Context, orgID int64, namespaceUID, group string, orderedUIDs []string, provenance models.Provenance) (err error) {
+	defer wrapServiceError(&err)
+	if err := service.policy.checkMutation(orgID, ResourceTypeAlertRules, provenance); err != nil {
+		return err
+	}
+	return service.reorderRules(ctx, orgID, namespaceUID, group, orderedUIDs, provenance, func(rule *models.AlertRule, position int) bool {
+		if rule.RuleGroupIndex == position {
+			return false
+		}
+		rule.RuleGroupIndex = position
+		return true
+	})
 }
 
-func (service *AlertRuleService) UpdateAlertGroup(ctx context.Context, orgID int64, folderUID, roulegroup string, interval int64) error {
-	return service.ruleStore.UpdateRuleGroup(ctx, orgID, folderUID, roulegroup, interval)
+// ReorderRulesInGroup sets the order in which the scheduler evaluates the rules of a rule group. The UIDs
+// must be exactly the UIDs of the rules of the group, and the rules are evaluated in the order of the
+// UIDs. Unlike ReorderRuleGroup, it does not change the position of the rules in the group.
+func (service *AlertRuleService) ReorderRulesInGroup(ctx context.Context, orgID int64, namespaceUID, ruleGroup string, orderedUIDs []string, provenance models.Provenance) (err error) {
+	defer wrapServiceError(&err)
+	if err := service.policy.checkMutation(orgID, ResourceTypeAlertRules, provenance); err != nil {
+		return err
+	}
+	return service.reorderRules(ctx, orgID, namespaceUID, ruleGroup, orderedUIDs, provenance, func(rule *models.AlertRule, order int) bool {
+		if rule.EvalOrder == order {
+			return false
+		}
+		rule.EvalOrder = order
+		return true
+	})
+}
+
+// reorderRules checks that the UIDs are exactly the UIDs of the rules of the rule group, and calls set
+// with every rule and its 1-based position in the UIDs. set changes the rule and returns whether it
+// changed it, and the rules that are changed are stored in one transaction. The rules must have the
+// provenance or none.
+func (service *AlertRuleService) reorderRules(ctx context.Context, orgID int64, namespaceUID, group string, orderedUIDs []string, provenance models.Provenance, set func(rule *models.AlertRule, position int) bool) error {
+	if err := service.checkNamespaces(ctx, namespaceUID); err != nil {
+		return err
+	}
+	ctx, cancel := withTimeout(ctx, service.cfg.OperationTimeouts.Update)
+	defer cancel()
+	positions := make(map[string]int, len(orderedUIDs))
+	for i, uid := range orderedUIDs {
+		if _, ok := positions[uid]; ok {
+			return fmt.Errorf("%w: rule '%s' is listed more than once", ErrValidation, uid)
+		}
+		positions[uid] = i + 1
+	}
+	var updated []string
+	err := service.xact.InTransaction(ctx, func(ctx context.Context) error {
+		query := &models.ListAlertRulesQuery{
+			OrgID:         orgID,
+			NamespaceUIDs: []string{namespaceUID},
+			RuleGroup:     group,
+		}
+		if err := service.ruleStore.ListAlertRules(ctx, query); err != nil {
+			return err
+		}
+		if len(query.Result) == 0 {
+			return store.ErrAlertRuleGroupNotFound
+		}
+		if len(query.Result) != len(orderedUIDs) {
+			return fmt.Errorf("%w: the order lists %d rules but the group has %d", ErrValidation, len(orderedUIDs), len(query.Result))
+		}
+		provenances, err := service.provenanceStore.GetProvenances(ctx, orgID, (&models.AlertRule{}).ResourceType())
+		if err != nil {
+			return err
+		}
+		updates := make([]store.UpdateRule, 0, len(query.Result))
+		for _, rule := range query.Result {
+			position, ok := positions[rule.UID]
+			if !ok {
+				return fmt.Errorf("%w: rule '%s' of the group is missing from the order", ErrValidation, rule.UID)
+			}
+			if storedProvenance, ok := provenances[rule.UID]; ok && storedProvenance != provenance && storedProvenance != models.ProvenanceNone {
+				return fmt.Errorf("%w: cannot reorder rule '%s' with provenance '%s', needs '%s'", ErrProvenanceMismatch, rule.UID, provenance, storedProvenance)
+			}
+			reordered := *rule
+			if !set(&reordered, position) {
+				continue
+			}
+			updates = append(updates, store.UpdateRule{Existing: rule, New: reordered})
+			updated = append(updated, rule.UID)
+		}
+		if len(updates) == 0 {
+			return nil
+		}
+		return service.ruleStore.UpdateAlertRules(ctx, updates)
+	})
+	if err != nil {
+		return err
+	}
+	if len(updated) > 0 {
+		service.notifyGroupChange(ctx, RuleGroupChange{
+			OrgID:        orgID,
+			NamespaceUID: namespaceUID,
+			RuleGroup:    group,
+			Created:      []string{},
+			Updated:      updated,
+			Deleted:      []string{},
+		})
+	}
+	return nil
+}
+
+// NormalizeGroupInterval sets the interval of all rules of the rule group to the smallest interval
+// among them, rounded up to a multiple of the BaseInterval, and returns that interval in seconds.
+// It repairs groups whose rules were stored with different intervals.
+func (service *AlertRuleService) NormalizeGroupInterval(ctx context.Context, orgID int64, namespaceUID, group string, provenance models.Provenance) (_ int64, err error) {
+	defer wrapServiceError(&err)
+	if err := service.policy.checkMutation(orgID, ResourceTypeAlertRules, provenance); err != nil {
+		return 0, err
+	}
+	if err := service.checkNamespaces(ctx, namespaceUID); err != nil {
+		return 0, err
+	}
+	ctx, cancel := withTimeout(ctx, service.cfg.OperationTimeouts.Update)
+	defer cancel()
+	var interval int64
+	var updated []string
+	err = service.xact.InTransaction(ctx, func(ctx context.Context) error {
+		query := &models.ListAlertRulesQuery{
+			OrgID:         orgID,
+			NamespaceUIDs: []string{namespaceUID},
+			RuleGroup:     group,
+		}
+		if err := service.ruleStore.ListAlertRules(ctx, query); err != nil {
+			return err
+		}
+		if len(query.Result) == 0 {
+			return store.ErrAlertRuleGroupNotFound
+		}
+		interval = query.Result[0].IntervalSeconds
+		for _, rule := range query.Result {
+			if rule.IntervalSeconds < interval {
+				interval = rule.IntervalSeconds
+			}
+		}
+		if base := int64(service.cfg.BaseInterval.Seconds()); base > 0 && interval%base != 0 {
+			interval = (interval/base + 1) * base
+		}
+		if err := service.validateGroupInterval(ctx, orgID, interval); err != nil {
+			return err
+		}
+		provenances, err := service.provenanceStore.GetProvenances(ctx, orgID, (&models.AlertRule{}).ResourceType())
+		if err != nil {
+			return err
+		}
+		updates := make([]store.UpdateRule, 0, len(query.Result))
+		for _, rule := range query.Result {
+			if rule.IntervalSeconds == interval {
+				continue
+			}
+			if storedProvenance, ok := provenances[rule.UID]; ok && storedProvenance != provenance && storedProvenance != models.ProvenanceNone {
+				return fmt.Errorf("%w: cannot change the interval of rule '%s' with provenance '%s', needs '%s'", ErrProvenanceMismatch, rule.UID, provenance, storedProvenance)
+			}
+			normalized := *rule
+			normalized.IntervalSeconds = interval
+			updates = append(updates, store.UpdateRule{Existing: rule, New: normalized})
+			updated = append(updated, rule.UID)
+		}
+		if len(updates) == 0 {
+			return nil
+		}
+		return service.ruleStore.UpdateAlertRules(ctx, updates)
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(updated) > 0 {
+		service.notifyGroupChange(ctx, RuleGroupChange{
+			OrgID:        orgID,
+			NamespaceUID: namespaceUID,
+			RuleGroup:    group,
+			Created:      []string{},
+			Updated:      updated,
+			Deleted:      []string{},
+		})
+	}
+	return interval, nil
+}
+
+// nextRuleGroupIndex returns the position after the last rule of the rule group.
+func (service *AlertRuleService) nextRuleGroupIndex(ctx context.Context, orgID int64, namespaceUID, group string) (int, error) {
+	query := &models.ListAlertRulesQuery{
+		OrgID:         orgID,
+		NamespaceUIDs: []string{namespaceUID},
+		RuleGroup:     group,
+	}
+	if err := service.ruleStore.ListAlertRules(ctx, query); err != nil {
+		return 0, err
+	}
+	next := 1
+	for _, rule := range query.Result {
+		if rule.RuleGroupIndex >= next {
+			next = rule.RuleGroupIndex + 1
+		}
+	}
+	return next, nil
+}
+
+// notifyGroupChange informs the group notifier about a committed change. The change
+// is already persisted at this point, so failures are only logged.
+func (service *AlertRuleService) notifyGroupChange(ctx context.Context, change RuleGroupChange) {
+	if service.groupNotifier == nil {
+		return
+	}
+	if err := service.groupNotifier.Notify(ctx, change); err != nil {
+		service.log.Warn("failed to notify about rule group change", "folder", change.NamespaceUID, "group", change.RuleGroup, "err", err)
+	}
+}
+
+// HealthCheck checks that the store of the service is reachable. It returns a
+// StorageUnavailableError if it is not, or if it does not answer in time.
+func (service *AlertRuleService) HealthCheck(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+	result := make(chan error, 1)
+	go func() {
+		result <- service.xact.InTransaction(ctx, func(ctx context.Context) error {
+			return service.ruleStore.Ping(ctx)
+		})
+	}()
+	select {
+	case err := <-result:
+		if err != nil {
+			return StorageUnavailableError{Err: err}
+		}
+		return nil
+	case <-ctx.Done():
+		return StorageUnavailableError{Err: ctx.Err()}
+	}
+}
+
+// RuleAuditResult describes a stored alert rule that does not pass the current validation.
+type RuleAuditResult struct {
+	UID          string
+	Title        string
+	NamespaceUID string
+	RuleGroup    string
+	Err          error
+}
+
+// AuditAlertRules validates all alert rules of the organization and returns the ones
+// that would be rejected if they were created now. It does not modify any rule.
+func (service *AlertRuleService) AuditAlertRules(ctx context.Context, orgID int64) (_ []RuleAuditResult, err error) {
+	defer wrapServiceError(&err)
+	query := &models.ListAlertRulesQuery{
+		OrgID: orgID,
+	}
+	scope, err := service.callerNamespaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !scope.restrict(query) {
+		return []RuleAuditResult{}, nil
+	}
+	if err := service.ruleStore.ListAlertRules(ctx, query); err != nil {
+		return nil, err
+	}
+	results := make([]RuleAuditResult, 0)
+	for _, rule := range query.Result {
+		if err := service.validateAlertRule(ctx, *rule); err != nil {
+			results = append(results, RuleAuditResult{
+				UID:          rule.UID,
+				Title:        rule.Title,
+				NamespaceUID: rule.NamespaceUID,
+				RuleGroup:    rule.RuleGroup,
+				Err:          err,
+			})
+		}
+	}
+	return results, nil
+}
+
+// ValidateAlertRule checks the rule like CreateAlertRule would, without storing it.
+// The interval of the rule is checked against the limits of its organization as is.
+func (service *AlertRuleService) ValidateAlertRule(ctx context.Context, rule models.AlertRule) (err error) {
+	defer wrapServiceError(&err)
+	if rule.Title == "" {
+		return fmt.Errorf("%w: title is empty", ErrValidation)
+	}
+	if len(rule.Data) == 0 {
+		return fmt.Errorf("%w: no queries or expressions are found", ErrValidation)
+	}
+	if err := service.materializeLibraryQueries(ctx, &rule); err != nil {
+		return err
+	}
+	for _, query := range rule.Data {
+		if err := query.PreSave(); err != nil {
+			return fmt.Errorf("%w: invalid alert query %s: %s", ErrValidation, query.RefID, err.Error())
+		}
+	}
+	setRuleDefaults(&rule)
+	if err := service.validateAlertRule(ctx, rule); err != nil {
+		return err
+	}
+	return service.validateGroupInterval(ctx, rule.OrgID, rule.IntervalSeconds)
+}
+
+// validateAlertRule runs the validations of the service that are not already part of the store.
+func (service *AlertRuleService) validateAlertRule(ctx context.Context, rule models.AlertRule) error {
+	if err := validateRuleLocation(rule); err != nil {
+		return err
+	}
+	if err := validateAnnotations(rule); err != nil {
+		return err
+	}
+	if rule.EvalEveryN < 1 {
+		return fmt.Errorf("%w: evaluation sampling %d must be at least 1", ErrValidation, rule.EvalEveryN)
+	}
+	if rule.WarmUpEvals < 0 {
+		return fmt.Errorf("%w: warm-up evaluations %d must not be negative", ErrValidation, rule.WarmUpEvals)
+	}
+	if len(rule.ManagedBy) > store.AlertRuleMaxManagedByLength {
+		return fmt.Errorf("%w: owner is longer than %d characters", ErrValidation, store.AlertRuleMaxManagedByLength)
+	}
+	if err := validateKeys("labels", rule.EffectiveLabels(), service.cfg.AllowedLabelKeys, service.cfg.RequiredLabelKeys); err != nil {
+		return err
+	}
+	if err := validateKeys("annotations", withoutReservedAnnotations(rule.Annotations), service.cfg.AllowedAnnotationKeys, service.cfg.RequiredAnnotationKeys); err != nil {
+		return err
+	}
+	if err := rule.ActiveWindow.Validate(); err != nil {
+		return err
+	}
+	if service.cfg.StrictTemplateValidation {
+		if issues := annotationTemplateIssues(rule); len(issues) > 0 {
+			return fmt.Errorf("%w: %s", ErrValidation, issues[0].Message)
+		}
+	}
+	if err := service.validateDashboardLink(ctx, rule); err != nil {
+		return err
+	}
+	return service.validateNotificationSettings(ctx, rule)
+}
+
+// validateEvalOffset checks that the evaluation offset of the rule is within its interval.
+func validateEvalOffset(rule models.AlertRule) error {
+	if rule.EvalOffsetSeconds < 0 || rule.EvalOffsetSeconds >= rule.IntervalSeconds {
+		return fmt.Errorf("%w: evaluation offset %ds must not be negative and must be shorter than the interval of %ds", ErrValidation, rule.EvalOffsetSeconds, rule.IntervalSeconds)
+	}
+	return nil
+}
+
+// setRuleDefaults sets the optional fields of the rule that are not set to their defaults.
+func setRuleDefaults(rule *models.AlertRule) {
+	if rule.EvalEveryN == 0 {
+		rule.EvalEveryN = models.DefaultEvalEveryN
+	}
+}
+
+// validateGroupInterval makes sure that the interval of a rule group is within the limits of the
+// organization. Rules that are already outside of new limits keep being evaluated, but they cannot
+// be changed without bringing their group back into the limits.
+func (service *AlertRuleService) validateGroupInterval(ctx context.Context, orgID int64, interval int64) error {
+	if service.intervalLimits == nil {
+		return nil
+	}
+	limits, err := service.intervalLimits.GetRuleGroupIntervalLimits(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	if limits == nil {
+		return nil
+	}
+	if err := limits.Validate(interval); err != nil {
+		return fmt.Errorf("%w: %s", ErrValidation, err.Error())
+	}
+	return nil
+}
+
+// validateDefaultFor makes sure that the default pending period of a rule is a multiple of the
+// interval of its rule group, so that it spans a whole number of evaluations.
+func validateDefaultFor(defaultFor time.Duration, intervalSeconds int64) error {
+	if defaultFor < 0 {
+		return fmt.Errorf("%w: the default pending period %s is negative", ErrValidation, defaultFor)
+	}
+	if intervalSeconds > 0 && defaultFor%(time.Duration(intervalSeconds)*time.Second) != 0 {
+		return fmt.Errorf("%w: the default pending period %s is not a multiple of the interval %ds", ErrValidation, defaultFor, intervalSeconds)
+	}
+	return nil
+}
+
+// validateNotificationSettings makes sure that the notification settings are valid and that the contact point
+// the rule sends its notifications to exists.
+func (service *AlertRuleService) validateNotificationSettings(ctx context.Context, rule models.AlertRule) error {
+	settings := rule.GetNotificationSettings()
+	if settings == nil {
+		return nil
+	}
+	if err := settings.Validate(); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidGroupBy, err.Error())
+	}
+	if service.contactPointValidator == nil {
+		return nil
+	}
+	exists, err := service.contactPointValidator.Exists(ctx, rule.OrgID, settings.ReceiverName)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("%w: '%s'", ErrContactPointNotFound, settings.ReceiverName)
+	}
+	return nil
+}
+
+func createLockKey(rule models.AlertRule) string {
+	return fmt.Sprintf("%d/%s/%s", rule.OrgID, rule.NamespaceUID, rule.Title)
+}
+
+// checkTitleUniqueness returns ErrAlertRuleDuplicateTitle if another rule of the same folder has
+// the same title.
+func (service *AlertRuleService) checkTitleUniqueness(ctx context.Context, rule models.AlertRule) error {
+	existing, err := service.ruleStore.GetAlertRuleByTitle(ctx, rule.OrgID, rule.NamespaceUID, rule.Title)
+	if errors.Is(err, models.ErrAlertRuleNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if existing.UID == rule.UID {
+		return nil
+	}
+	return fmt.Errorf("%w: '%s' conflicts with rule '%s'", models.ErrAlertRuleDuplicateTitle, rule.Title, existing.UID)
+}
+
+// validateRuleGroupName rejects a new rule group whose name only differs by case or surrounding
+// whitespace from an existing group of the same namespace. Adding rules to existing groups is
+// always allowed, even if their names are already ambiguous.
+func (service *AlertRuleService) validateRuleGroupName(ctx context.Context, rule models.AlertRule) error {
+	query := &models.ListOrgRuleGroupsQuery{
+		OrgID:         rule.OrgID,
+		NamespaceUIDs: []string{rule.NamespaceUID},
+	}
+	if err := service.ruleStore.ListOrgRuleGroups(ctx, query); err != nil {
+		return err
+	}
+	name := models.NormalizeRuleGroupName(rule.RuleGroup)
+	var conflict string
+	for _, group := range query.Result {
+		if group[0] == rule.RuleGroup {
+			return nil
+		}
+		if models.NormalizeRuleGroupName(group[0]) == name {
+			conflict = group[0]
+		}
+	}
+	if conflict != "" {
+		return fmt.Errorf("%w: rule group '%s' only differs by case or whitespace from the existing group '%s'", ErrValidation, rule.RuleGroup, conflict)
+	}
+	return nil
+}
+
+// groupEvaluationCostWarningRatio is the share of the group interval that the estimated evaluation
+// cost of a rule group may take before a warning is reported.
+const groupEvaluationCostWarningRatio = 0.8
+
+// groupEvaluationCostIssues estimates how long an evaluation of the rule group of the rule takes and
+// reports an issue if it takes most of the group interval, because the evaluations will then start
+// to be late. The estimate is the number of rules of the group times the average duration of the last
+// evaluations of its rules. It is only an estimate, so it never rejects the rule.
+func (service *AlertRuleService) groupEvaluationCostIssues(ctx context.Context, rule models.AlertRule) []models.ValidationIssue {
+	if service.evaluationDurations == nil || rule.IntervalSeconds <= 0 {
+		return nil
+	}
+	query := &models.ListAlertRulesQuery{
+		OrgID:         rule.OrgID,
+		NamespaceUIDs: []string{rule.NamespaceUID},
+		RuleGroup:     rule.RuleGroup,
+	}
+	if err := service.ruleStore.ListAlertRules(ctx, query); err != nil {
+		service.log.Warn("failed to estimate the evaluation cost of the rule group", "org", rule.OrgID, "namespace", rule.NamespaceUID, "group", rule.RuleGroup, "err", err)
+		return nil
+	}
+	uids := []string{rule.UID}
+	for _, r := range query.Result {
+		if r.UID != rule.UID {
+			uids = append(uids, r.UID)
+		}
+	}
+	durations := service.evaluationDurations.GetEvaluationDurations(rule.OrgID, uids...)
+	if len(durations) == 0 {
+		return nil
+	}
+	var total time.Duration
+	for _, d := range durations {
+		total += d
+	}
+	cost := total / time.Duration(len(durations)) * time.Duration(len(uids))
+	interval := time.Duration(rule.IntervalSeconds) * time.Second
+	if float64(cost) <= float64(interval)*groupEvaluationCostWarningRatio {
+		return nil
+	}
+	return []models.ValidationIssue{{
+		Code:    models.IssueGroupEvaluationCost,
+		Field:   "ruleGroup",
+		Message: fmt.Sprintf("evaluating the %d rules of rule group %s is estimated to take %s which is close to or more than its interval of %s", len(uids), rule.RuleGroup, cost, interval),
+	}}
+}
+
+// ListAmbiguousGroups returns the rule groups of the organization whose names only differ
+// by case or surrounding whitespace, so that they can be renamed.
+func (service *AlertRuleService) ListAmbiguousGroups(ctx context.Context, orgID int64) (_ []models.AmbiguousRuleGroup, err error) {
+	defer wrapServiceError(&err)
+	ctx, cancel := withTimeout(ctx, service.cfg.OperationTimeouts.List)
+	defer cancel()
+	scope, err := service.callerNamespaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := service.ruleStore.ListAmbiguousGroups(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]models.AmbiguousRuleGroup, 0, len(groups))
+	for _, group := range groups {
+		if scope.allows(group.NamespaceUID) {
+			result = append(result, group)
+		}
+	}
+	return result, nil
 }
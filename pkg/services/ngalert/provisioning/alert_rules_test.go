@@ -3,10 +3,21 @@ package provisioning
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/benbjohnson/clock"
 	"github.com/grafana/grafana/pkg/infra/log"
+	models2 "github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	acmock "github.com/grafana/grafana/pkg/services/accesscontrol/mock"
 	"github.com/grafana/grafana/pkg/services/ngalert/models"
 	"github.com/grafana/grafana/pkg/services/ngalert/store"
 	"github.com/grafana/grafana/pkg/services/sqlstore"
@@ -39,13 +50,51 @@ func TestAlertRuleService(t *testing.T) {
 		require.Equal(t, int64(60), rule.IntervalSeconds)
 
 		var interval int64 = 120
-		err = ruleService.UpdateAlertGroup(context.Background(), orgID, rule.NamespaceUID, rule.RuleGroup, 120)
+		err = ruleService.UpdateAlertGroup(context.Background(), orgID, rule.NamespaceUID, rule.RuleGroup, 120, models.ProvenanceNone)
 		require.NoError(t, err)
 
 		rule, _, err = ruleService.GetAlertRule(context.Background(), orgID, rule.UID)
 		require.NoError(t, err)
 		require.Equal(t, interval, rule.IntervalSeconds)
 	})
+	t.Run("alert rule group interval update should increment the versions and keep the provenance", func(t *testing.T) {
+		var orgID int64 = 1
+		rule := dummyRule("test#3-1", orgID)
+		rule.RuleGroup = "a-1"
+		rule, err := ruleService.CreateAlertRule(context.Background(), rule, models.ProvenanceAPI)
+		require.NoError(t, err)
+
+		err = ruleService.UpdateAlertGroup(context.Background(), orgID, rule.NamespaceUID, rule.RuleGroup, 180, models.ProvenanceAPI)
+		require.NoError(t, err)
+
+		updated, provenance, err := ruleService.GetAlertRule(context.Background(), orgID, rule.UID)
+		require.NoError(t, err)
+		require.Equal(t, int64(180), updated.IntervalSeconds)
+		require.Equal(t, rule.Version+1, updated.Version)
+		require.Equal(t, models.ProvenanceAPI, provenance)
+
+		err = ruleService.UpdateAlertGroup(context.Background(), orgID, rule.NamespaceUID, "missing", 180, models.ProvenanceAPI)
+		require.ErrorIs(t, err, store.ErrAlertRuleGroupNotFound)
+	})
+	t.Run("alert rule group interval update should fail if the provenance of a rule is different", func(t *testing.T) {
+		var orgID int64 = 1
+		rule := dummyRule("test#3-2", orgID)
+		rule.RuleGroup = "a-2"
+		rule, err := ruleService.CreateAlertRule(context.Background(), rule, models.ProvenanceFile)
+		require.NoError(t, err)
+
+		err = ruleService.UpdateAlertGroup(context.Background(), orgID, rule.NamespaceUID, rule.RuleGroup, 180, models.ProvenanceAPI)
+		require.ErrorIs(t, err, ErrProvenanceMismatch)
+		err = ruleService.UpdateAlertGroup(context.Background(), orgID, rule.NamespaceUID, rule.RuleGroup, 180, models.ProvenanceNone)
+		require.ErrorIs(t, err, ErrProvenanceMismatch)
+
+		updated, _, err := ruleService.GetAlertRule(context.Background(), orgID, rule.UID)
+		require.NoError(t, err)
+		require.Equal(t, rule.IntervalSeconds, updated.IntervalSeconds)
+
+		err = ruleService.UpdateAlertGroup(context.Background(), orgID, rule.NamespaceUID, rule.RuleGroup, 180, models.ProvenanceFile)
+		require.NoError(t, err)
+	})
 	t.Run("alert rule should get interval from existing rule group", func(t *testing.T) {
 		var orgID int64 = 1
 		rule := dummyRule("test#4", orgID)
@@ -54,7 +103,7 @@ func TestAlertRuleService(t *testing.T) {
 		require.NoError(t, err)
 
 		var interval int64 = 120
-		err = ruleService.UpdateAlertGroup(context.Background(), orgID, rule.NamespaceUID, rule.RuleGroup, 120)
+		err = ruleService.UpdateAlertGroup(context.Background(), orgID, rule.NamespaceUID, rule.RuleGroup, 120, models.ProvenanceNone)
 		require.NoError(t, err)
 
 		rule = dummyRule("test#4-1", orgID)
@@ -63,6 +112,341 @@ func TestAlertRuleService(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, interval, rule.IntervalSeconds)
 	})
+	t.Run("alert rules linked to a dashboard should be returned for that dashboard", func(t *testing.T) {
+		var orgID int64 = 1
+		dashboardUID := "dashboard-uid"
+		var panelID int64 = 7
+		linked := dummyRule("test#5", orgID)
+		linked.DashboardUID = &dashboardUID
+		linked.PanelID = &panelID
+		linked, err := ruleService.CreateAlertRule(context.Background(), linked, models.ProvenanceNone)
+		require.NoError(t, err)
+		unlinked, err := ruleService.CreateAlertRule(context.Background(), dummyRule("test#5-1", orgID), models.ProvenanceNone)
+		require.NoError(t, err)
+
+		rules, err := ruleService.GetAlertRulesForDashboard(context.Background(), orgID, dashboardUID)
+		require.NoError(t, err)
+		require.Len(t, rules, 1)
+		require.Equal(t, linked.UID, rules[0].UID)
+		require.NotEqual(t, unlinked.UID, rules[0].UID)
+		require.NotNil(t, rules[0].PanelID)
+		require.Equal(t, panelID, *rules[0].PanelID)
+	})
+	t.Run("concurrent creation of the same alert rule should let exactly one win", func(t *testing.T) {
+		var orgID int64 = 1
+		rule := dummyRule("test#6", orgID)
+		// the first insert waits for the second one, so without the lock both creations would pass
+		// the title check before either rule is stored.
+		racingService := ruleService
+		racingService.ruleStore = &insertBarrierStore{RuleStore: ruleService.ruleStore, second: make(chan struct{})}
+
+		var wg sync.WaitGroup
+		errs := make([]error, 2)
+		for i := range errs {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				_, errs[i] = racingService.CreateAlertRule(context.Background(), rule, models.ProvenanceNone)
+			}(i)
+		}
+		wg.Wait()
+
+		succeeded := 0
+		for _, err := range errs {
+			if err == nil {
+				succeeded++
+				continue
+			}
+			require.ErrorIs(t, err, models.ErrAlertRuleDuplicateTitle)
+			require.NotErrorIs(t, err, models.ErrAlertRuleUniqueConstraintViolation)
+		}
+		require.Equal(t, 1, succeeded)
+	})
+	t.Run("alert rule creation and update should return warnings", func(t *testing.T) {
+		var orgID int64 = 1
+		rule := dummyRule("test#7", orgID)
+		rule.For = 10 * time.Second
+		rule, issues, err := ruleService.CreateAlertRuleWithIssues(context.Background(), rule, models.ProvenanceNone)
+		require.NoError(t, err)
+		require.Len(t, issues, 2)
+		require.Equal(t, models.IssueShortTimeRange, issues[0].Code)
+		require.Equal(t, models.IssueForShorterThanInterval, issues[1].Code)
+
+		rule.For = 5 * time.Minute
+		rule.Data[0].RelativeTimeRange.From = models.Duration(10 * time.Minute)
+		_, issues, err = ruleService.UpdateAlertRuleWithIssues(context.Background(), rule, models.ProvenanceNone)
+		require.NoError(t, err)
+		require.Empty(t, issues)
+	})
+	t.Run("alert rule should be rejected if its contact point does not exist", func(t *testing.T) {
+		var orgID int64 = 1
+		service := createAlertRuleService(t)
+		service.contactPointValidator = newFakeContactPointValidator("known-receiver")
+
+		rule := dummyRule("test#8", orgID)
+		rule.NotificationSettings = []models.NotificationSettings{{ReceiverName: "unknown-receiver"}}
+		_, err := service.CreateAlertRule(context.Background(), rule, models.ProvenanceNone)
+		require.ErrorIs(t, err, ErrContactPointNotFound)
+
+		rule.NotificationSettings = []models.NotificationSettings{{ReceiverName: "known-receiver"}}
+		rule, err = service.CreateAlertRule(context.Background(), rule, models.ProvenanceNone)
+		require.NoError(t, err)
+
+		rule.NotificationSettings = []models.NotificationSettings{{ReceiverName: "unknown-receiver"}}
+		_, err = service.UpdateAlertRule(context.Background(), rule, models.ProvenanceNone)
+		require.ErrorIs(t, err, ErrContactPointNotFound)
+
+		stored, _, err := service.GetAlertRule(context.Background(), orgID, rule.UID)
+		require.NoError(t, err)
+		require.Equal(t, "known-receiver", stored.GetNotificationSettings().ReceiverName)
+	})
+	t.Run("alert rule should store valid group by overrides of its notification settings", func(t *testing.T) {
+		var orgID int64 = 1
+		service := createAlertRuleService(t)
+
+		rule := dummyRule("test#8-1", orgID)
+		rule.NotificationSettings = []models.NotificationSettings{{ReceiverName: "receiver", GroupBy: []string{"not-a-label"}}}
+		_, err := service.CreateAlertRule(context.Background(), rule, models.ProvenanceNone)
+		require.ErrorIs(t, err, ErrValidation)
+		rule.NotificationSettings = []models.NotificationSettings{{GroupBy: []string{"alertname"}}}
+		_, err = service.CreateAlertRule(context.Background(), rule, models.ProvenanceNone)
+		require.ErrorIs(t, err, ErrValidation)
+
+		rule.NotificationSettings = []models.NotificationSettings{{ReceiverName: "receiver", GroupBy: []string{"alertname", "team"}}}
+		rule, err = service.CreateAlertRule(context.Background(), rule, models.ProvenanceNone)
+		require.NoError(t, err)
+		stored, _, err := service.GetAlertRule(context.Background(), orgID, rule.UID)
+		require.NoError(t, err)
+		require.Equal(t, []string{"alertname", "team"}, stored.GetNotificationSettings().GroupBy)
+	})
+	t.Run("alert rule should report unknown receivers and invalid group by labels with specific errors", func(t *testing.T) {
+		var orgID int64 = 1
+		service := createAlertRuleService(t)
+		service.contactPointValidator = newFakeContactPointValidator("known-receiver")
+
+		rule := dummyRule("test#8-2", orgID)
+		rule.NotificationSettings = []models.NotificationSettings{{ReceiverName: "known-receiver", GroupBy: []string{"alertname"}}}
+		rule, err := service.CreateAlertRule(context.Background(), rule, models.ProvenanceNone)
+		require.NoError(t, err)
+
+		rule.NotificationSettings = []models.NotificationSettings{{ReceiverName: "unknown-receiver", GroupBy: []string{"alertname"}}}
+		_, err = service.UpdateAlertRule(context.Background(), rule, models.ProvenanceNone)
+		require.ErrorIs(t, err, ErrContactPointNotFound)
+		require.NotErrorIs(t, err, ErrInvalidGroupBy)
+
+		rule.NotificationSettings = []models.NotificationSettings{{ReceiverName: "known-receiver", GroupBy: []string{models.RuleUIDLabel}}}
+		_, err = service.UpdateAlertRule(context.Background(), rule, models.ProvenanceNone)
+		require.ErrorIs(t, err, ErrInvalidGroupBy)
+		require.NotErrorIs(t, err, ErrContactPointNotFound)
+		require.Equal(t, ErrCodeValidation, ErrorCodeOf(err))
+		require.Contains(t, err.Error(), models.RuleUIDLabel)
+	})
+	t.Run("alert rule should fail with the error of the contact point lookup", func(t *testing.T) {
+		var orgID int64 = 1
+		service := createAlertRuleService(t)
+		validator := newFakeContactPointValidator("known-receiver")
+		validator.err = errors.New("alertmanager configuration is unavailable")
+		service.contactPointValidator = validator
+
+		rule := dummyRule("test#8-3", orgID)
+		rule.NotificationSettings = []models.NotificationSettings{{ReceiverName: "known-receiver"}}
+		_, err := service.CreateAlertRule(context.Background(), rule, models.ProvenanceNone)
+		require.ErrorIs(t, err, validator.err)
+		require.NotErrorIs(t, err, ErrContactPointNotFound)
+	})
+	t.Run("audit should report stored alert rules that no longer pass validation", func(t *testing.T) {
+		var orgID int64 = 1
+		service := createAlertRuleService(t)
+		validator := newFakeContactPointValidator("receiver")
+		service.contactPointValidator = validator
+
+		valid, err := service.CreateAlertRule(context.Background(), dummyRule("test#9", orgID), models.ProvenanceNone)
+		require.NoError(t, err)
+		rule := dummyRule("test#9-1", orgID)
+		rule.NotificationSettings = []models.NotificationSettings{{ReceiverName: "receiver"}}
+		invalid, err := service.CreateAlertRule(context.Background(), rule, models.ProvenanceNone)
+		require.NoError(t, err)
+
+		results, err := service.AuditAlertRules(context.Background(), orgID)
+		require.NoError(t, err)
+		require.Empty(t, results)
+
+		validator.remove("receiver")
+		results, err = service.AuditAlertRules(context.Background(), orgID)
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		require.Equal(t, invalid.UID, results[0].UID)
+		require.NotEqual(t, valid.UID, results[0].UID)
+		require.ErrorIs(t, results[0].Err, ErrContactPointNotFound)
+	})
+	t.Run("rule group changes should be sent to the webhook after they are committed", func(t *testing.T) {
+		var orgID int64 = 1
+		var received RuleGroupChange
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+		service := createAlertRuleService(t)
+		notifier := NewWebhookRuleGroupNotifier(server.URL, server.Client())
+		notifier.backoff = 0
+		service.groupNotifier = notifier
+
+		rule := dummyRule("test#10", orgID)
+		rule.RuleGroup = "webhook"
+		rule, err := service.CreateAlertRule(context.Background(), rule, models.ProvenanceNone)
+		require.NoError(t, err)
+
+		err = service.UpdateAlertGroup(context.Background(), orgID, rule.NamespaceUID, rule.RuleGroup, 120, models.ProvenanceNone)
+		require.NoError(t, err, "a failed notification should not fail the committed change")
+		require.Equal(t, RuleGroupChange{
+			OrgID:        orgID,
+			NamespaceUID: rule.NamespaceUID,
+			RuleGroup:    "webhook",
+			Created:      []string{},
+			Updated:      []string{rule.UID},
+			Deleted:      []string{},
+		}, received)
+
+		interval, err := service.ruleStore.GetRuleGroupInterval(context.Background(), orgID, rule.NamespaceUID, rule.RuleGroup)
+		require.NoError(t, err)
+		require.Equal(t, int64(120), interval)
+	})
+	t.Run("alert rule should be restored to a previous version", func(t *testing.T) {
+		var orgID int64 = 1
+		rule := dummyRule("test#11", orgID)
+		rule.Data[0].RelativeTimeRange.From = models.Duration(10 * time.Minute)
+		rule.Labels = map[string]string{"team": "a"}
+		rule, err := ruleService.CreateAlertRule(context.Background(), rule, models.ProvenanceAPI)
+		require.NoError(t, err)
+
+		rule.Title = "test#11-updated"
+		rule.Labels = map[string]string{"team": "b"}
+		_, err = ruleService.UpdateAlertRule(context.Background(), rule, models.ProvenanceAPI)
+		require.NoError(t, err)
+
+		_, err = ruleService.RestoreAlertRule(context.Background(), orgID, rule.UID, 1, models.ProvenanceNone)
+		require.Error(t, err, "provenance should be checked")
+
+		_, err = ruleService.RestoreAlertRule(context.Background(), orgID, rule.UID, 1, models.ProvenanceAPI)
+		require.NoError(t, err)
+
+		restored, _, err := ruleService.GetAlertRule(context.Background(), orgID, rule.UID)
+		require.NoError(t, err)
+		require.Equal(t, "test#11", restored.Title)
+		require.Equal(t, map[string]string{"team": "a"}, restored.Labels)
+		require.Equal(t, int64(3), restored.Version)
+
+		_, err = ruleService.RestoreAlertRule(context.Background(), orgID, rule.UID, 42, models.ProvenanceAPI)
+		require.ErrorIs(t, err, store.ErrVersionNotFound)
+	})
+	t.Run("alert rule should be restored with every field of the version", func(t *testing.T) {
+		var orgID int64 = 1
+		expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+		rule := dummyRule("test#11-1", orgID)
+		rule.Data[0].RelativeTimeRange.From = models.Duration(10 * time.Minute)
+		rule.TitleTemplate = "first {{ .Labels.instance }}"
+		rule.ActiveWindow = &models.ActiveWindow{Location: "UTC"}
+		rule.EvalOrder = 1
+		rule.EvalOffsetSeconds = 10
+		rule.ExpiresAt = &expiresAt
+		rule.EvalEveryN = 2
+		rule.WarmUpEvals = 1
+		rule.ManagedBy = "first-operator"
+		rule, err := ruleService.CreateAlertRule(context.Background(), rule, models.ProvenanceAPI)
+		require.NoError(t, err)
+		original, _, err := ruleService.GetAlertRule(context.Background(), orgID, rule.UID)
+		require.NoError(t, err)
+
+		changedExpiry := expiresAt.Add(time.Hour)
+		rule.TitleTemplate = "second {{ .Labels.instance }}"
+		rule.ActiveWindow = &models.ActiveWindow{Location: "Europe/Berlin"}
+		rule.EvalOrder = 2
+		rule.EvalOffsetSeconds = 20
+		rule.ExpiresAt = &changedExpiry
+		rule.EvalEveryN = 3
+		rule.WarmUpEvals = 2
+		rule.ManagedBy = "second-operator"
+		_, err = ruleService.UpdateAlertRule(context.Background(), rule, models.ProvenanceAPI)
+		require.NoError(t, err)
+
+		_, err = ruleService.RestoreAlertRule(context.Background(), orgID, rule.UID, original.Version, models.ProvenanceAPI)
+		require.NoError(t, err)
+
+		restored, _, err := ruleService.GetAlertRule(context.Background(), orgID, rule.UID)
+		require.NoError(t, err)
+		require.Equal(t, original.TitleTemplate, restored.TitleTemplate)
+		require.Equal(t, original.ActiveWindow, restored.ActiveWindow)
+		require.Equal(t, original.EvalOrder, restored.EvalOrder)
+		require.Equal(t, original.EvalOffsetSeconds, restored.EvalOffsetSeconds)
+		require.NotNil(t, restored.ExpiresAt)
+		require.Equal(t, expiresAt, restored.ExpiresAt.UTC())
+		require.Equal(t, original.EvalEveryN, restored.EvalEveryN)
+		require.Equal(t, original.WarmUpEvals, restored.WarmUpEvals)
+		require.Equal(t, original.ManagedBy, restored.ManagedBy)
+	})
+	t.Run("rule group intervals should be within the limits of the org", func(t *testing.T) {
+		service := createAlertRuleService(t)
+		dbStore := service.ruleStore.(store.DBstore)
+		service.intervalLimits = dbStore
+
+		existing, err := service.CreateAlertRule(context.Background(), dummyRule("test#12", 1), models.ProvenanceNone)
+		require.NoError(t, err)
+
+		require.NoError(t, dbStore.SetRuleGroupIntervalLimits(context.Background(), models.RuleGroupIntervalLimits{OrgID: 0, MinIntervalSeconds: 30, MaxIntervalSeconds: 600}))
+		require.NoError(t, dbStore.SetRuleGroupIntervalLimits(context.Background(), models.RuleGroupIntervalLimits{OrgID: 1, MinIntervalSeconds: 120}))
+
+		rule := dummyRule("test#12-1", 1)
+		rule.RuleGroup = "limited"
+		_, err = service.CreateAlertRule(context.Background(), rule, models.ProvenanceNone)
+		require.ErrorIs(t, err, ErrValidation)
+		require.Contains(t, err.Error(), "organization 1")
+
+		rule = dummyRule("test#12-2", 2)
+		rule.RuleGroup = "limited"
+		_, err = service.CreateAlertRule(context.Background(), rule, models.ProvenanceNone)
+		require.NoError(t, err, "the default limits should apply to org 2")
+		err = service.UpdateAlertGroup(context.Background(), 2, rule.NamespaceUID, rule.RuleGroup, 900, models.ProvenanceNone)
+		require.ErrorIs(t, err, ErrValidation)
+		require.Contains(t, err.Error(), "default interval limits")
+
+		existing.Title = "test#12-updated"
+		_, err = service.UpdateAlertRule(context.Background(), existing, models.ProvenanceNone)
+		require.ErrorIs(t, err, ErrValidation, "rules outside of the limits should not be changed")
+
+		err = service.UpdateAlertGroup(context.Background(), 1, existing.NamespaceUID, existing.RuleGroup, 120, models.ProvenanceNone)
+		require.NoError(t, err)
+		_, err = service.UpdateAlertRule(context.Background(), existing, models.ProvenanceNone)
+		require.NoError(t, err)
+	})
+	t.Run("alert rule with reserved annotation keys should be rejected", func(t *testing.T) {
+		var orgID int64 = 1
+		rule := dummyRule("test#13", orgID)
+		rule.Annotations = map[string]string{
+			"summary":                     "fine",
+			"__custom__":                  "value",
+			models.DashboardUIDAnnotation: "dashboard",
+		}
+		_, err := ruleService.CreateAlertRule(context.Background(), rule, models.ProvenanceNone)
+		require.ErrorIs(t, err, ErrValidation)
+		require.Contains(t, err.Error(), "'__custom__', '__dashboardUid__'")
+	})
+	t.Run("alert rule with normal annotations should be accepted", func(t *testing.T) {
+		var orgID int64 = 1
+		dashboardUID := "dashboard"
+		var panelID int64 = 3
+		rule := dummyRule("test#13-1", orgID)
+		rule.DashboardUID = &dashboardUID
+		rule.PanelID = &panelID
+		rule.Annotations = map[string]string{
+			"summary":                     "fine",
+			"_not_reserved":               "value",
+			models.DashboardUIDAnnotation: dashboardUID,
+			models.PanelIDAnnotation:      "3",
+		}
+		_, err := ruleService.CreateAlertRule(context.Background(), rule, models.ProvenanceNone)
+		require.NoError(t, err)
+	})
 	t.Run("alert rule provenace should be correctly checked", func(t *testing.T) {
 		tests := []struct {
 			name   string
@@ -125,42 +509,1713 @@ func TestAlertRuleService(t *testing.T) {
 	})
 }
 
-func createAlertRuleService(t *testing.T) AlertRuleService {
-	t.Helper()
-	sqlStore := sqlstore.InitTestDB(t)
-	store := store.DBstore{
-		SQLStore:     sqlStore,
-		BaseInterval: time.Second * 10,
+func TestAlertRuleServiceTitleUniqueness(t *testing.T) {
+	ruleService := createAlertRuleService(t)
+
+	t.Run("should reject the same title in another group of the namespace", func(t *testing.T) {
+		rule := dummyRule("unique title #1", 1)
+		_, err := ruleService.CreateAlertRule(context.Background(), rule, models.ProvenanceNone)
+		require.NoError(t, err)
+
+		duplicate := dummyRule("unique title #1", 1)
+		duplicate.RuleGroup = "other-group"
+		_, err = ruleService.CreateAlertRule(context.Background(), duplicate, models.ProvenanceNone)
+		require.ErrorIs(t, err, models.ErrAlertRuleDuplicateTitle)
+	})
+	t.Run("should accept the same title in a different namespace", func(t *testing.T) {
+		rule := dummyRule("unique title #2", 1)
+		_, err := ruleService.CreateAlertRule(context.Background(), rule, models.ProvenanceNone)
+		require.NoError(t, err)
+
+		rule = dummyRule("unique title #2", 1)
+		rule.NamespaceUID = "other-namespace"
+		_, err = ruleService.CreateAlertRule(context.Background(), rule, models.ProvenanceNone)
+		require.NoError(t, err)
+	})
+	t.Run("should accept the same title in a different org", func(t *testing.T) {
+		rule := dummyRule("unique title #3", 1)
+		_, err := ruleService.CreateAlertRule(context.Background(), rule, models.ProvenanceNone)
+		require.NoError(t, err)
+
+		_, err = ruleService.CreateAlertRule(context.Background(), dummyRule("unique title #3", 2), models.ProvenanceNone)
+		require.NoError(t, err)
+	})
+	t.Run("should reject an update to the title of another rule", func(t *testing.T) {
+		_, err := ruleService.CreateAlertRule(context.Background(), dummyRule("unique title #4", 1), models.ProvenanceNone)
+		require.NoError(t, err)
+		rule, err := ruleService.CreateAlertRule(context.Background(), dummyRule("unique title #5", 1), models.ProvenanceNone)
+		require.NoError(t, err)
+
+		rule.Title = "unique title #4"
+		_, err = ruleService.UpdateAlertRule(context.Background(), rule, models.ProvenanceNone)
+		require.ErrorIs(t, err, models.ErrAlertRuleDuplicateTitle)
+
+		rule.Title = "unique title #5"
+		_, err = ruleService.UpdateAlertRule(context.Background(), rule, models.ProvenanceNone)
+		require.NoError(t, err)
+	})
+	t.Run("should accept titles that only differ in case or whitespace", func(t *testing.T) {
+		_, err := ruleService.CreateAlertRule(context.Background(), dummyRule("unique title #6", 1), models.ProvenanceNone)
+		require.NoError(t, err)
+		_, err = ruleService.CreateAlertRule(context.Background(), dummyRule("Unique Title #6", 1), models.ProvenanceNone)
+		require.NoError(t, err)
+		_, err = ruleService.CreateAlertRule(context.Background(), dummyRule(" unique  title #6", 1), models.ProvenanceNone)
+		require.NoError(t, err)
+	})
+}
+
+func TestAlertRuleServiceDashboardLink(t *testing.T) {
+	ruleService := createAlertRuleService(t)
+	ruleService.dashboards = newFakeDashboardProvider("dashboard-1")
+
+	t.Run("should set the reserved annotations of a valid link", func(t *testing.T) {
+		rule := dummyRule("test#dashboard-1", 1)
+		rule.Data[0].RelativeTimeRange.From = models.Duration(10 * time.Minute)
+		dashboardUID, panelID := "dashboard-1", int64(3)
+		rule.DashboardUID = &dashboardUID
+		rule.PanelID = &panelID
+		rule.Annotations = map[string]string{"summary": "test"}
+		rule, err := ruleService.CreateAlertRule(context.Background(), rule, models.ProvenanceNone)
+		require.NoError(t, err)
+
+		rule, _, err = ruleService.GetAlertRule(context.Background(), 1, rule.UID)
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{
+			"summary":                     "test",
+			models.DashboardUIDAnnotation: "dashboard-1",
+			models.PanelIDAnnotation:      "3",
+		}, rule.Annotations)
+
+		newPanelID := int64(4)
+		rule.PanelID = &newPanelID
+		rule, err = ruleService.UpdateAlertRule(context.Background(), rule, models.ProvenanceNone)
+		require.NoError(t, err)
+		require.Equal(t, "4", rule.Annotations[models.PanelIDAnnotation])
+	})
+	t.Run("should reject a missing dashboard", func(t *testing.T) {
+		rule := dummyRule("test#dashboard-2", 1)
+		dashboardUID, panelID := "missing", int64(3)
+		rule.DashboardUID = &dashboardUID
+		rule.PanelID = &panelID
+		_, err := ruleService.CreateAlertRule(context.Background(), rule, models.ProvenanceNone)
+		require.ErrorIs(t, err, ErrValidation)
+	})
+	t.Run("should reject a panel ID without dashboard", func(t *testing.T) {
+		rule := dummyRule("test#dashboard-3", 1)
+		panelID := int64(3)
+		rule.PanelID = &panelID
+		_, err := ruleService.CreateAlertRule(context.Background(), rule, models.ProvenanceNone)
+		require.ErrorIs(t, err, ErrValidation)
+	})
+	t.Run("should reject a panel ID that is not positive", func(t *testing.T) {
+		rule := dummyRule("test#dashboard-4", 1)
+		dashboardUID, panelID := "dashboard-1", int64(0)
+		rule.DashboardUID = &dashboardUID
+		rule.PanelID = &panelID
+		_, err := ruleService.CreateAlertRule(context.Background(), rule, models.ProvenanceNone)
+		require.ErrorIs(t, err, ErrValidation)
+	})
+}
+
+func TestAlertRuleServiceUpdateKeepsServerManagedFields(t *testing.T) {
+	ruleService := createAlertRuleService(t)
+	ruleService.dashboards = newFakeDashboardProvider("dashboard-1")
+	create := func(t *testing.T, title string) models.AlertRule {
+		t.Helper()
+		rule := dummyRule(title, 1)
+		rule.Data[0].RelativeTimeRange.From = models.Duration(10 * time.Minute)
+		dashboardUID, panelID := "dashboard-1", int64(3)
+		rule.DashboardUID = &dashboardUID
+		rule.PanelID = &panelID
+		rule.Labels = map[string]string{"team": "a"}
+		rule.Annotations = map[string]string{"summary": "test"}
+		rule, err := ruleService.CreateAlertRule(context.Background(), rule, models.ProvenanceNone)
+		require.NoError(t, err)
+		rule, _, err = ruleService.GetAlertRule(context.Background(), 1, rule.UID)
+		require.NoError(t, err)
+		return rule
 	}
-	return AlertRuleService{
-		ruleStore:       store,
-		provenanceStore: store,
-		xact:            sqlStore,
-		log:             log.New("testing"),
-		defaultInterval: 60,
+
+	t.Run("a round trip that changes the title should keep every other field", func(t *testing.T) {
+		stored := create(t, "test#update-1")
+		rule := stored
+		rule.Title = "test#update-1 renamed"
+		updated, err := ruleService.UpdateAlertRule(context.Background(), rule, models.ProvenanceNone)
+		require.NoError(t, err)
+		require.Equal(t, stored.Version+1, updated.Version)
+
+		actual, _, err := ruleService.GetAlertRule(context.Background(), 1, rule.UID)
+		require.NoError(t, err)
+		require.True(t, actual.Updated.After(stored.Updated) || actual.Updated.Equal(stored.Updated))
+		expected := stored
+		expected.Title = "test#update-1 renamed"
+		expected.TitleLower = "test#update-1 renamed"
+		expected.Version = stored.Version + 1
+		expected.Updated = actual.Updated
+		require.Equal(t, expected, actual)
+	})
+	t.Run("omitted server-managed fields should be kept", func(t *testing.T) {
+		stored := create(t, "test#update-2")
+		rule := stored
+		rule.ID, rule.Version, rule.Updated = 0, 0, time.Time{}
+		rule.DashboardUID, rule.PanelID = nil, nil
+		rule.Annotations = map[string]string{"summary": "test"}
+		updated, err := ruleService.UpdateAlertRule(context.Background(), rule, models.ProvenanceNone)
+		require.NoError(t, err)
+		require.Equal(t, stored.ID, updated.ID)
+		require.Equal(t, stored.Version+1, updated.Version)
+		require.False(t, updated.Updated.IsZero())
+
+		actual, _, err := ruleService.GetAlertRule(context.Background(), 1, rule.UID)
+		require.NoError(t, err)
+		require.Equal(t, stored.DashboardUID, actual.DashboardUID)
+		require.Equal(t, stored.PanelID, actual.PanelID)
+		require.Equal(t, stored.Annotations, actual.Annotations, "the annotations that link the rule should be kept")
+	})
+	t.Run("omitted labels and annotations should be removed", func(t *testing.T) {
+		stored := create(t, "test#update-3")
+		rule := stored
+		rule.Labels = nil
+		rule.Annotations = nil
+		_, err := ruleService.UpdateAlertRule(context.Background(), rule, models.ProvenanceNone)
+		require.NoError(t, err)
+
+		actual, _, err := ruleService.GetAlertRule(context.Background(), 1, rule.UID)
+		require.NoError(t, err)
+		require.Empty(t, actual.Labels)
+		require.Equal(t, map[string]string{
+			models.DashboardUIDAnnotation: "dashboard-1",
+			models.PanelIDAnnotation:      "3",
+		}, actual.Annotations)
+	})
+	t.Run("an empty dashboard UID should remove the link", func(t *testing.T) {
+		stored := create(t, "test#update-4")
+		rule := stored
+		unlinked := ""
+		rule.DashboardUID, rule.PanelID = &unlinked, nil
+		_, err := ruleService.UpdateAlertRule(context.Background(), rule, models.ProvenanceNone)
+		require.NoError(t, err)
+
+		actual, _, err := ruleService.GetAlertRule(context.Background(), 1, rule.UID)
+		require.NoError(t, err)
+		require.Nil(t, actual.DashboardUID)
+		require.Nil(t, actual.PanelID)
+		require.Equal(t, map[string]string{"summary": "test"}, actual.Annotations)
+	})
+}
+
+func TestAlertRuleServiceGetNextEvaluation(t *testing.T) {
+	ruleService := createAlertRuleService(t)
+	rule := dummyRule("test#next-evaluation", 1)
+	rule.EvalOffsetSeconds = 30
+	rule, err := ruleService.CreateAlertRule(context.Background(), rule, models.ProvenanceNone)
+	require.NoError(t, err)
+	require.Equal(t, int64(60), rule.IntervalSeconds)
+
+	now := time.Date(2022, 6, 1, 12, 0, 40, 0, time.UTC)
+	next, err := ruleService.GetNextEvaluation(context.Background(), 1, rule.UID, now)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2022, 6, 1, 12, 1, 30, 0, time.UTC), next)
+
+	_, err = ruleService.GetNextEvaluation(context.Background(), 1, "missing", now)
+	require.ErrorIs(t, err, models.ErrAlertRuleNotFound)
+
+	t.Run("should reject offsets that are not within the interval", func(t *testing.T) {
+		for _, offset := range []int64{-10, 60, 90} {
+			rule := dummyRule(fmt.Sprintf("test#next-evaluation-%d", offset), 1)
+			rule.EvalOffsetSeconds = offset
+			_, err := ruleService.CreateAlertRule(context.Background(), rule, models.ProvenanceNone)
+			require.ErrorIs(t, err, ErrValidation)
+		}
+	})
+}
+
+func TestAlertRuleServiceValidateAlertRule(t *testing.T) {
+	ruleService := createAlertRuleService(t)
+
+	t.Run("should accept a valid rule without storing it", func(t *testing.T) {
+		rule := dummyRule("test#validate-1", 1)
+		rule.UID = "validate-1"
+		require.NoError(t, ruleService.ValidateAlertRule(context.Background(), rule))
+		_, _, err := ruleService.GetAlertRule(context.Background(), 1, rule.UID)
+		require.ErrorIs(t, err, models.ErrAlertRuleNotFound)
+	})
+	t.Run("should reject a rule without title", func(t *testing.T) {
+		err := ruleService.ValidateAlertRule(context.Background(), dummyRule("", 1))
+		require.ErrorIs(t, err, ErrValidation)
+	})
+	t.Run("should reject a rule with reserved annotations", func(t *testing.T) {
+		rule := dummyRule("test#validate-2", 1)
+		rule.Annotations = map[string]string{"__custom__": "value"}
+		err := ruleService.ValidateAlertRule(context.Background(), rule)
+		require.ErrorIs(t, err, ErrValidation)
+	})
+	t.Run("should reject a rule with negative evaluation sampling", func(t *testing.T) {
+		rule := dummyRule("test#validate-3", 1)
+		rule.EvalEveryN = -1
+		err := ruleService.ValidateAlertRule(context.Background(), rule)
+		require.ErrorIs(t, err, ErrValidation)
+		rule.EvalEveryN = 3
+		require.NoError(t, ruleService.ValidateAlertRule(context.Background(), rule))
+	})
+	t.Run("should evaluate rules without evaluation sampling on every tick", func(t *testing.T) {
+		rule := dummyRule("test#validate-4", 1)
+		rule.EvalEveryN = 0
+		require.NoError(t, ruleService.ValidateAlertRule(context.Background(), rule))
+		created, err := ruleService.CreateAlertRule(context.Background(), rule, models.ProvenanceNone)
+		require.NoError(t, err)
+		require.Equal(t, models.DefaultEvalEveryN, created.EvalEveryN)
+		stored, _, err := ruleService.GetAlertRule(context.Background(), 1, created.UID)
+		require.NoError(t, err)
+		require.Equal(t, models.DefaultEvalEveryN, stored.EvalEveryN)
+	})
+}
+
+func TestAlertRuleServiceLabelKeys(t *testing.T) {
+	ruleService := createAlertRuleService(t)
+	withLabels := func(labels map[string]string) models.AlertRule {
+		rule := dummyRule("test#labels", 1)
+		rule.Labels = labels
+		return rule
 	}
+
+	t.Run("should accept rules that only use allowed labels", func(t *testing.T) {
+		ruleService.cfg.AllowedLabelKeys = []string{"team", "severity"}
+		ruleService.cfg.RequiredLabelKeys = nil
+		err := ruleService.ValidateAlertRule(context.Background(), withLabels(map[string]string{"team": "a", "severity": "critical"}))
+		require.NoError(t, err)
+	})
+	t.Run("should reject rules with labels that are not allowed", func(t *testing.T) {
+		ruleService.cfg.AllowedLabelKeys = []string{"team", "severity"}
+		ruleService.cfg.RequiredLabelKeys = nil
+		err := ruleService.ValidateAlertRule(context.Background(), withLabels(map[string]string{"team": "a", "owner": "b"}))
+		require.ErrorIs(t, err, ErrValidation)
+		require.Contains(t, err.Error(), "'owner'")
+	})
+	t.Run("should reject rules without required labels", func(t *testing.T) {
+		ruleService.cfg.AllowedLabelKeys = nil
+		ruleService.cfg.RequiredLabelKeys = []string{"team"}
+		err := ruleService.ValidateAlertRule(context.Background(), withLabels(map[string]string{"severity": "critical"}))
+		require.ErrorIs(t, err, ErrValidation)
+		require.Contains(t, err.Error(), "'team'")
+	})
+	t.Run("should accept all labels if the allowlist is empty", func(t *testing.T) {
+		ruleService.cfg.AllowedLabelKeys = nil
+		ruleService.cfg.RequiredLabelKeys = nil
+		err := ruleService.ValidateAlertRule(context.Background(), withLabels(map[string]string{"anything": "goes"}))
+		require.NoError(t, err)
+	})
 }
 
-func dummyRule(title string, orgID int64) models.AlertRule {
-	return models.AlertRule{
-		OrgID:           orgID,
-		Title:           title,
-		Condition:       "A",
-		Version:         1,
-		IntervalSeconds: 60,
-		Data: []models.AlertQuery{
-			{
-				RefID: "A",
-				Model: json.RawMessage("{}"),
-				RelativeTimeRange: models.RelativeTimeRange{
-					From: models.Duration(60),
-					To:   models.Duration(0),
-				},
-			},
-		},
-		RuleGroup:    "my-cool-group",
-		For:          time.Second * 60,
-		NoDataState:  models.OK,
-		ExecErrState: models.OkErrState,
+func TestAlertRuleServiceAnnotationKeys(t *testing.T) {
+	ruleService := createAlertRuleService(t)
+	withAnnotations := func(annotations map[string]string) models.AlertRule {
+		rule := dummyRule("test#annotations", 1)
+		rule.Annotations = annotations
+		return rule
+	}
+
+	t.Run("should accept rules that only use allowed annotations", func(t *testing.T) {
+		ruleService.cfg = AlertRuleServiceConfig{AllowedAnnotationKeys: []string{"summary", "runbook_url"}}
+		err := ruleService.ValidateAlertRule(context.Background(), withAnnotations(map[string]string{"summary": "a", "runbook_url": "https://example.com"}))
+		require.NoError(t, err)
+	})
+	t.Run("should reject rules with annotations that are not allowed", func(t *testing.T) {
+		ruleService.cfg = AlertRuleServiceConfig{AllowedAnnotationKeys: []string{"summary"}}
+		err := ruleService.ValidateAlertRule(context.Background(), withAnnotations(map[string]string{"summary": "a", "notes": "b"}))
+		require.ErrorIs(t, err, ErrValidation)
+		require.Contains(t, err.Error(), "'notes'")
+	})
+	t.Run("should reject rules without required annotations", func(t *testing.T) {
+		ruleService.cfg = AlertRuleServiceConfig{RequiredAnnotationKeys: []string{"summary"}}
+		err := ruleService.ValidateAlertRule(context.Background(), withAnnotations(map[string]string{"notes": "b"}))
+		require.ErrorIs(t, err, ErrValidation)
+		require.Contains(t, err.Error(), "'summary'")
+	})
+	t.Run("should accept all annotations if the allowlist is empty", func(t *testing.T) {
+		ruleService.cfg = AlertRuleServiceConfig{}
+		err := ruleService.ValidateAlertRule(context.Background(), withAnnotations(map[string]string{"anything": "goes"}))
+		require.NoError(t, err)
+	})
+	t.Run("should accept the annotations that link rules to panels", func(t *testing.T) {
+		ruleService.cfg = AlertRuleServiceConfig{AllowedAnnotationKeys: []string{"summary"}}
+		rule := withAnnotations(map[string]string{"summary": "a"})
+		dashboardUID, panelID := "dashboard", int64(1)
+		rule.DashboardUID, rule.PanelID = &dashboardUID, &panelID
+		rule.Annotations = withDashboardAnnotations(rule)
+		require.NoError(t, validateKeys("annotations", withoutReservedAnnotations(rule.Annotations), ruleService.cfg.AllowedAnnotationKeys, nil))
+	})
+	t.Run("should apply the label and annotation allowlists independently", func(t *testing.T) {
+		ruleService.cfg = AlertRuleServiceConfig{AllowedLabelKeys: []string{"team"}, AllowedAnnotationKeys: []string{"summary"}}
+		rule := withAnnotations(map[string]string{"summary": "a"})
+		rule.Labels = map[string]string{"team": "a"}
+		require.NoError(t, ruleService.ValidateAlertRule(context.Background(), rule))
+
+		rule.Labels = map[string]string{"summary": "a"}
+		err := ruleService.ValidateAlertRule(context.Background(), rule)
+		require.ErrorIs(t, err, ErrValidation)
+		require.Contains(t, err.Error(), "labels")
+
+		rule.Labels = map[string]string{"team": "a"}
+		rule.Annotations = map[string]string{"team": "a"}
+		err = ruleService.ValidateAlertRule(context.Background(), rule)
+		require.ErrorIs(t, err, ErrValidation)
+		require.Contains(t, err.Error(), "annotations")
+	})
+}
+
+func TestAlertRuleServiceAnnotationTemplates(t *testing.T) {
+	ruleService := createAlertRuleService(t)
+	withAnnotations := func(annotations map[string]string) models.AlertRule {
+		rule := dummyRule("test#templates", 1)
+		rule.Data[0].RelativeTimeRange.From = models.Duration(10 * time.Minute)
+		rule.Annotations = annotations
+		return rule
+	}
+
+	t.Run("should not report valid templates", func(t *testing.T) {
+		rule := withAnnotations(map[string]string{"summary": "{{ $labels.instance }} is at {{ $values.A }}"})
+		require.Empty(t, alertRuleWarnings(rule))
+	})
+	t.Run("should report templates that cannot be parsed", func(t *testing.T) {
+		rule := withAnnotations(map[string]string{"summary": "{{ $lables.instance }} is down"})
+		issues := alertRuleWarnings(rule)
+		require.Len(t, issues, 1)
+		require.Equal(t, models.IssueInvalidTemplate, issues[0].Code)
+		require.Equal(t, "annotations.summary", issues[0].Field)
+	})
+	t.Run("should report values of unknown RefIDs", func(t *testing.T) {
+		rule := withAnnotations(map[string]string{"description": "value is {{ $values.B }}"})
+		issues := alertRuleWarnings(rule)
+		require.Len(t, issues, 1)
+		require.Equal(t, models.IssueUnknownTemplateValue, issues[0].Code)
+		require.Contains(t, issues[0].Message, "B")
+	})
+	t.Run("should save rules with template issues as warnings", func(t *testing.T) {
+		_, issues, err := ruleService.CreateAlertRuleWithIssues(context.Background(), withAnnotations(map[string]string{"summary": "{{ $values.B }}"}), models.ProvenanceNone)
+		require.NoError(t, err)
+		require.Len(t, issues, 1)
+		require.Equal(t, models.IssueUnknownTemplateValue, issues[0].Code)
+	})
+	t.Run("should reject rules with template issues with strict template validation", func(t *testing.T) {
+		ruleService.cfg.StrictTemplateValidation = true
+		defer func() { ruleService.cfg.StrictTemplateValidation = false }()
+		err := ruleService.ValidateAlertRule(context.Background(), withAnnotations(map[string]string{"summary": "{{ $values.B }}"}))
+		require.ErrorIs(t, err, ErrValidation)
+		require.NoError(t, ruleService.ValidateAlertRule(context.Background(), withAnnotations(map[string]string{"summary": "{{ $values.A }}"})))
+	})
+}
+
+func TestAlertRuleServiceRequireProvenance(t *testing.T) {
+	ruleService := createAlertRuleService(t)
+	ruleService.cfg.RequireProvenanceOrgs = map[int64]struct{}{1: {}}
+
+	t.Run("should reject creating a rule without provenance", func(t *testing.T) {
+		_, err := ruleService.CreateAlertRule(context.Background(), dummyRule("test#provenance-1", 1), models.ProvenanceNone)
+		require.ErrorIs(t, err, ErrValidation)
+	})
+	t.Run("should create a rule with provenance", func(t *testing.T) {
+		_, err := ruleService.CreateAlertRule(context.Background(), dummyRule("test#provenance-2", 1), models.ProvenanceAPI)
+		require.NoError(t, err)
+	})
+	t.Run("should create a rule without provenance in other orgs", func(t *testing.T) {
+		_, err := ruleService.CreateAlertRule(context.Background(), dummyRule("test#provenance-3", 2), models.ProvenanceNone)
+		require.NoError(t, err)
+	})
+}
+
+func TestAlertRuleServiceDefaultFor(t *testing.T) {
+	ruleService := createAlertRuleService(t)
+	ruleService.cfg.DefaultFor = 5 * time.Minute
+	withFor := func(title string, f time.Duration) models.AlertRule {
+		rule := dummyRule(title, 1)
+		rule.RuleGroup = "default-for"
+		rule.For = f
+		return rule
+	}
+
+	t.Run("rule without pending period gets the default", func(t *testing.T) {
+		rule, err := ruleService.CreateAlertRule(context.Background(), withFor("test#default-for-1", 0), models.ProvenanceAPI)
+		require.NoError(t, err)
+		require.Equal(t, 5*time.Minute, rule.For)
+
+		stored, _, err := ruleService.GetAlertRule(context.Background(), 1, rule.UID)
+		require.NoError(t, err)
+		require.Equal(t, 5*time.Minute, stored.For)
+	})
+	t.Run("rule with pending period keeps it", func(t *testing.T) {
+		rule, err := ruleService.CreateAlertRule(context.Background(), withFor("test#default-for-2", 2*time.Minute), models.ProvenanceAPI)
+		require.NoError(t, err)
+		require.Equal(t, 2*time.Minute, rule.For)
+	})
+	t.Run("default can be overridden per call", func(t *testing.T) {
+		override := 3 * time.Minute
+		rule, _, err := ruleService.CreateAlertRuleWithOptions(context.Background(), withFor("test#default-for-3", 0), models.ProvenanceAPI, CreateAlertRuleOptions{DefaultFor: &override})
+		require.NoError(t, err)
+		require.Equal(t, override, rule.For)
+	})
+	t.Run("default that is not a multiple of the interval is rejected", func(t *testing.T) {
+		override := 90 * time.Second
+		_, _, err := ruleService.CreateAlertRuleWithOptions(context.Background(), withFor("test#default-for-4", 0), models.ProvenanceAPI, CreateAlertRuleOptions{DefaultFor: &override})
+		require.ErrorIs(t, err, ErrValidation)
+	})
+}
+
+func TestAlertRuleServiceGroupEvaluationCost(t *testing.T) {
+	ruleService := createAlertRuleService(t)
+	durations := fakeEvaluationDurations{}
+	ruleService.evaluationDurations = durations
+	inGroup := func(title string) models.AlertRule {
+		rule := dummyRule(title, 1)
+		rule.RuleGroup = "evaluation-cost"
+		return rule
+	}
+	costIssues := func(issues []models.ValidationIssue) []models.ValidationIssue {
+		var result []models.ValidationIssue
+		for _, issue := range issues {
+			if issue.Code == models.IssueGroupEvaluationCost {
+				result = append(result, issue)
+			}
+		}
+		return result
+	}
+
+	first, issues, err := ruleService.CreateAlertRuleWithIssues(context.Background(), inGroup("test#cost-1"), models.ProvenanceAPI)
+	require.NoError(t, err)
+	require.Empty(t, costIssues(issues), "rules that were never evaluated have no cost")
+
+	t.Run("cheap group has no warning", func(t *testing.T) {
+		durations[first.UID] = 10 * time.Second
+		_, issues, err := ruleService.CreateAlertRuleWithIssues(context.Background(), inGroup("test#cost-2"), models.ProvenanceAPI)
+		require.NoError(t, err)
+		require.Empty(t, costIssues(issues))
+	})
+	t.Run("expensive group is saved with a warning", func(t *testing.T) {
+		durations[first.UID] = 20 * time.Second
+		rule, issues, err := ruleService.CreateAlertRuleWithIssues(context.Background(), inGroup("test#cost-3"), models.ProvenanceAPI)
+		require.NoError(t, err)
+		require.Len(t, costIssues(issues), 1)
+
+		rule.Data[0].RelativeTimeRange.From = models.Duration(10 * time.Minute)
+		_, issues, err = ruleService.UpdateAlertRuleWithIssues(context.Background(), rule, models.ProvenanceAPI)
+		require.NoError(t, err)
+		require.Len(t, costIssues(issues), 1)
+	})
+}
+
+func TestAlertRuleServiceQuota(t *testing.T) {
+	ruleStore := store.NewFakeRuleStore(t)
+	provenanceStore := NewFakeProvisioningStore()
+	service := AlertRuleService{
+		ruleStore:       ruleStore,
+		provenanceStore: provenanceStore,
+		xact:            newNopTransactionManager(),
+		log:             log.NewNopLogger(),
+		defaultInterval: 60,
+		createLocks:     newKeyedMutex(),
+		quota:           fakeQuotaChecker{exceededOrgs: map[int64]struct{}{99: {}}},
+	}
+
+	_, err := service.CreateAlertRule(context.Background(), dummyRule("test#quota", 99), models.ProvenanceAPI)
+	require.ErrorIs(t, err, ErrQuotaReached)
+	require.Equal(t, ErrCodeQuotaExceeded, ErrorCodeOf(err))
+	require.Empty(t, ruleStore.RecordedOps)
+	require.Empty(t, provenanceStore.records)
+
+	_, err = service.CreateRuleGroupIfAbsent(context.Background(), 99, "folder", "group", []models.AlertRule{dummyRule("test#quota", 99)}, 60, models.ProvenanceAPI)
+	require.ErrorIs(t, err, ErrQuotaReached, "batches should be held to the same limit")
+	require.Empty(t, ruleStore.Rules[99])
+}
+
+func TestAlertRuleServiceNameNormalization(t *testing.T) {
+	ruleService := createAlertRuleService(t)
+	var orgID int64 = 8
+
+	t.Run("should trim titles and group names", func(t *testing.T) {
+		rule := dummyRule(" CPU  high ", orgID)
+		rule.RuleGroup = " cpu "
+		rule, err := ruleService.CreateAlertRule(context.Background(), rule, models.ProvenanceAPI)
+		require.NoError(t, err)
+		require.Equal(t, "CPU  high", rule.Title)
+		require.Equal(t, "cpu", rule.RuleGroup)
+
+		stored, _, err := ruleService.GetAlertRule(context.Background(), orgID, rule.UID)
+		require.NoError(t, err)
+		require.Equal(t, "CPU  high", stored.Title)
+		require.Equal(t, "cpu", stored.RuleGroup)
+	})
+	t.Run("should collapse inner whitespace if configured", func(t *testing.T) {
+		service := createAlertRuleService(t)
+		service.cfg.CollapseNameWhitespace = true
+		rule := dummyRule(" memory    high ", orgID)
+		rule.RuleGroup = "memory  usage"
+		rule, err := service.CreateAlertRule(context.Background(), rule, models.ProvenanceAPI)
+		require.NoError(t, err)
+		require.Equal(t, "memory high", rule.Title)
+		require.Equal(t, "memory usage", rule.RuleGroup)
+	})
+	t.Run("should reject invisible characters and show them escaped", func(t *testing.T) {
+		rule := dummyRule("disk\u200bfull", orgID)
+		_, err := ruleService.CreateAlertRule(context.Background(), rule, models.ProvenanceAPI)
+		require.ErrorIs(t, err, ErrValidation)
+		require.Contains(t, err.Error(), `"disk\u200bfull"`)
+
+		rule = dummyRule("disk full", orgID)
+		rule.RuleGroup = "disk\tusage"
+		_, err = ruleService.CreateAlertRule(context.Background(), rule, models.ProvenanceAPI)
+		require.ErrorIs(t, err, ErrValidation)
+		require.Contains(t, err.Error(), `"disk\tusage"`)
+
+		err = ruleService.ReplaceRuleGroup(context.Background(), orgID, "folder", "disk\x00usage", 60, []models.AlertRule{dummyRule("disk full", orgID)}, models.ProvenanceAPI)
+		require.ErrorIs(t, err, ErrValidation)
+	})
+	t.Run("should keep stored names until the rule is updated", func(t *testing.T) {
+		rule := dummyRule(" legacy ", orgID)
+		rule.UID = "legacy"
+		rule.RuleGroup = " legacy group "
+		rule.Data[0].RelativeTimeRange.From = models.Duration(10 * time.Minute)
+		_, err := ruleService.ruleStore.InsertAlertRules(context.Background(), []models.AlertRule{rule})
+		require.NoError(t, err)
+
+		stored, _, err := ruleService.GetAlertRule(context.Background(), orgID, rule.UID)
+		require.NoError(t, err)
+		require.Equal(t, " legacy ", stored.Title)
+
+		updated, err := ruleService.UpdateAlertRule(context.Background(), stored, models.ProvenanceNone)
+		require.NoError(t, err)
+		require.Equal(t, "legacy", updated.Title)
+		require.Equal(t, " legacy group ", updated.RuleGroup)
+	})
+	t.Run("should normalize replaced rule groups", func(t *testing.T) {
+		rule := dummyRule(" replaced ", orgID)
+		rule.Data[0].RelativeTimeRange.From = models.Duration(10 * time.Minute)
+		err := ruleService.ReplaceRuleGroup(context.Background(), orgID, "folder", " replaced group ", 60, []models.AlertRule{rule}, models.ProvenanceAPI)
+		require.NoError(t, err)
+
+		rules, err := ruleService.GetAlertRuleGroup(context.Background(), orgID, "folder", "replaced group")
+		require.NoError(t, err)
+		require.Len(t, rules, 1)
+		require.Equal(t, "replaced", rules[0].Title)
+	})
+}
+
+func TestAlertRuleServiceRuleLocationValidation(t *testing.T) {
+	ruleService := createAlertRuleService(t)
+	var orgID int64 = 9
+
+	t.Run("should reject rules without a rule group on create", func(t *testing.T) {
+		for _, group := range []string{"", "   "} {
+			rule := dummyRule("test#location", orgID)
+			rule.RuleGroup = group
+			_, err := ruleService.CreateAlertRule(context.Background(), rule, models.ProvenanceAPI)
+			require.ErrorIs(t, err, ErrValidation)
+			require.Contains(t, err.Error(), "ruleGroup must not be empty")
+		}
+	})
+	t.Run("should reject rules without a folder on create", func(t *testing.T) {
+		for _, folder := range []string{"", "  "} {
+			rule := dummyRule("test#location", orgID)
+			rule.NamespaceUID = folder
+			_, err := ruleService.CreateAlertRule(context.Background(), rule, models.ProvenanceAPI)
+			require.ErrorIs(t, err, ErrValidation)
+			require.Contains(t, err.Error(), "folderUID must not be empty")
+		}
+	})
+	t.Run("should reject empty rule groups and folders on update", func(t *testing.T) {
+		rule, err := ruleService.CreateAlertRule(context.Background(), dummyRule("test#location", orgID), models.ProvenanceAPI)
+		require.NoError(t, err)
+
+		withoutGroup := rule
+		withoutGroup.RuleGroup = " "
+		_, err = ruleService.UpdateAlertRule(context.Background(), withoutGroup, models.ProvenanceAPI)
+		require.ErrorIs(t, err, ErrValidation)
+		require.Contains(t, err.Error(), "ruleGroup must not be empty")
+
+		withoutFolder := rule
+		withoutFolder.NamespaceUID = ""
+		_, err = ruleService.UpdateAlertRule(context.Background(), withoutFolder, models.ProvenanceAPI)
+		require.ErrorIs(t, err, ErrValidation)
+		require.Contains(t, err.Error(), "folderUID must not be empty")
+	})
+}
+
+func TestAlertRuleServiceGetAlertRulesByProvenance(t *testing.T) {
+	ruleService := createAlertRuleService(t)
+	var orgID int64 = 9
+	fileRules := make(map[string]struct{})
+	for i := 0; i < 7; i++ {
+		rule, err := ruleService.CreateAlertRule(context.Background(), dummyRule(fmt.Sprintf("test#file-%d", i), orgID), models.ProvenanceFile)
+		require.NoError(t, err)
+		fileRules[rule.UID] = struct{}{}
+	}
+	for i := 0; i < 2; i++ {
+		_, err := ruleService.CreateAlertRule(context.Background(), dummyRule(fmt.Sprintf("test#api-%d", i), orgID), models.ProvenanceAPI)
+		require.NoError(t, err)
+	}
+
+	pageThroughFileRules := func(t *testing.T) {
+		t.Helper()
+		var uids []string
+		var pageSizes []int
+		opts := AlertRulesByProvenanceOptions{Limit: 3}
+		for {
+			rules, next, err := ruleService.GetAlertRulesByProvenance(context.Background(), orgID, models.ProvenanceFile, opts)
+			require.NoError(t, err)
+			pageSizes = append(pageSizes, len(rules))
+			for _, rule := range rules {
+				uids = append(uids, rule.UID)
+			}
+			if next == "" {
+				break
+			}
+			opts.PageToken = next
+		}
+		require.Equal(t, []int{3, 3, 1}, pageSizes)
+		require.Len(t, uids, len(fileRules))
+		require.True(t, sort.StringsAreSorted(uids))
+		for _, uid := range uids {
+			require.Contains(t, fileRules, uid)
+		}
+	}
+
+	t.Run("should page through the rules with the provenance", func(t *testing.T) {
+		pageThroughFileRules(t)
+	})
+	t.Run("should fill the pages if provenance records of deleted rules are left behind", func(t *testing.T) {
+		for i := 0; i < 4; i++ {
+			deleted := &models.AlertRule{UID: fmt.Sprintf("deleted-%d", i)}
+			require.NoError(t, ruleService.provenanceStore.SetProvenance(context.Background(), deleted, orgID, models.ProvenanceFile))
+		}
+		pageThroughFileRules(t)
+	})
+	t.Run("should return all rules without a limit", func(t *testing.T) {
+		rules, next, err := ruleService.GetAlertRulesByProvenance(context.Background(), orgID, models.ProvenanceAPI, AlertRulesByProvenanceOptions{})
+		require.NoError(t, err)
+		require.Len(t, rules, 2)
+		require.Empty(t, next)
+	})
+	t.Run("should reject invalid options", func(t *testing.T) {
+		_, _, err := ruleService.GetAlertRulesByProvenance(context.Background(), orgID, models.ProvenanceFile, AlertRulesByProvenanceOptions{PageToken: "not a token"})
+		require.ErrorIs(t, err, ErrValidation)
+		_, _, err = ruleService.GetAlertRulesByProvenance(context.Background(), orgID, models.ProvenanceNone, AlertRulesByProvenanceOptions{})
+		require.ErrorIs(t, err, ErrValidation)
+	})
+}
+
+func TestAlertRuleServiceRuleGroupNames(t *testing.T) {
+	ruleService := createAlertRuleService(t)
+	var orgID int64 = 4
+
+	rule := dummyRule("test#group-1", orgID)
+	rule.RuleGroup = "Prod"
+	_, err := ruleService.CreateAlertRule(context.Background(), rule, models.ProvenanceNone)
+	require.NoError(t, err)
+
+	t.Run("should reject a new group that only differs by case", func(t *testing.T) {
+		rule := dummyRule("test#group-2", orgID)
+		rule.RuleGroup = "prod"
+		_, err := ruleService.CreateAlertRule(context.Background(), rule, models.ProvenanceNone)
+		require.ErrorIs(t, err, ErrValidation)
+	})
+	t.Run("should trim surrounding whitespace of new groups", func(t *testing.T) {
+		rule := dummyRule("test#group-3", orgID)
+		rule.RuleGroup = " Prod "
+		rule, err := ruleService.CreateAlertRule(context.Background(), rule, models.ProvenanceNone)
+		require.NoError(t, err)
+		require.Equal(t, "Prod", rule.RuleGroup)
+	})
+	t.Run("should reject moving a rule to a group that only differs by case", func(t *testing.T) {
+		rule := dummyRule("test#group-4", orgID)
+		rule.RuleGroup = "staging"
+		rule, err := ruleService.CreateAlertRule(context.Background(), rule, models.ProvenanceNone)
+		require.NoError(t, err)
+
+		rule.RuleGroup = "PROD"
+		_, err = ruleService.UpdateAlertRule(context.Background(), rule, models.ProvenanceNone)
+		require.ErrorIs(t, err, ErrValidation)
+	})
+	t.Run("should list existing groups that only differ by case", func(t *testing.T) {
+		rule := dummyRule("test#group-5", orgID)
+		rule.UID = "group-5"
+		rule.RuleGroup = "PROD"
+		_, err := ruleService.ruleStore.InsertAlertRules(context.Background(), []models.AlertRule{rule})
+		require.NoError(t, err)
+
+		groups, err := ruleService.ListAmbiguousGroups(context.Background(), orgID)
+		require.NoError(t, err)
+		require.Equal(t, []models.AmbiguousRuleGroup{
+			{NamespaceUID: "my-cool-folder", RuleGroups: []string{"PROD", "Prod"}},
+		}, groups)
+
+		rules, err := ruleService.ListAlertRules(context.Background(), orgID, ListAlertRulesOptions{})
+		require.NoError(t, err)
+		require.Len(t, rules, 4)
+		interval, err := ruleService.ruleStore.GetRuleGroupInterval(context.Background(), orgID, "my-cool-folder", "PROD")
+		require.NoError(t, err)
+		require.Equal(t, int64(60), interval)
+	})
+}
+
+func TestAlertRuleServiceGetRuleGroupInterval(t *testing.T) {
+	ruleService := createAlertRuleService(t)
+	var orgID int64 = 5
+
+	t.Run("should return the interval set by UpdateAlertGroup", func(t *testing.T) {
+		rule := dummyRule("test#interval-1", orgID)
+		_, err := ruleService.CreateAlertRule(context.Background(), rule, models.ProvenanceNone)
+		require.NoError(t, err)
+		require.NoError(t, ruleService.UpdateAlertGroup(context.Background(), orgID, rule.NamespaceUID, rule.RuleGroup, 180, models.ProvenanceNone))
+
+		interval, err := ruleService.GetRuleGroupInterval(context.Background(), orgID, rule.NamespaceUID, rule.RuleGroup)
+		require.NoError(t, err)
+		require.Equal(t, int64(180), interval)
+	})
+	t.Run("should return not found for a group without rules", func(t *testing.T) {
+		_, err := ruleService.GetRuleGroupInterval(context.Background(), orgID, "my-cool-folder", "empty-group")
+		require.ErrorIs(t, err, store.ErrAlertRuleGroupNotFound)
+	})
+}
+
+func TestAlertRuleServiceGetAlertRuleAcrossOrgs(t *testing.T) {
+	ruleService := createAlertRuleService(t)
+	var orgA, orgB, orgC int64 = 41, 42, 43
+	for _, orgID := range []int64{orgA, orgB} {
+		rule := dummyRule(fmt.Sprintf("test#shared-%d", orgID), orgID)
+		rule.UID = "shared-uid"
+		_, err := ruleService.CreateAlertRule(context.Background(), rule, models.ProvenanceNone)
+		require.NoError(t, err)
+	}
+
+	t.Run("should return the rule of the organization", func(t *testing.T) {
+		for _, orgID := range []int64{orgA, orgB} {
+			rule, _, err := ruleService.GetAlertRule(context.Background(), orgID, "shared-uid")
+			require.NoError(t, err)
+			require.Equal(t, orgID, rule.OrgID)
+			require.Equal(t, fmt.Sprintf("test#shared-%d", orgID), rule.Title)
+		}
+	})
+	t.Run("should not reveal rules of other organizations", func(t *testing.T) {
+		_, _, missingErr := ruleService.GetAlertRule(context.Background(), orgA, "missing-uid")
+		require.ErrorIs(t, missingErr, models.ErrAlertRuleNotFound)
+		require.Equal(t, ErrCodeRuleNotFound, ErrorCodeOf(missingErr))
+
+		for _, orgID := range []int64{orgC, 0} {
+			_, _, err := ruleService.GetAlertRule(context.Background(), orgID, "shared-uid")
+			require.ErrorIs(t, err, models.ErrAlertRuleNotFound)
+			require.Equal(t, ErrCodeRuleNotFound, ErrorCodeOf(err))
+			require.Equal(t, missingErr.Error(), err.Error())
+		}
+	})
+	t.Run("should return store failures as internal errors", func(t *testing.T) {
+		failing := store.NewFakeRuleStore(t)
+		failing.Hook = func(interface{}) error {
+			return errors.New("database is locked")
+		}
+		service := ruleService
+		service.ruleStore = failing
+		_, _, err := service.GetAlertRule(context.Background(), orgA, "shared-uid")
+		require.Error(t, err)
+		require.NotErrorIs(t, err, models.ErrAlertRuleNotFound)
+		require.Equal(t, ErrCodeInternal, ErrorCodeOf(err))
+	})
+}
+
+func TestAlertRuleServiceNormalizeGroupInterval(t *testing.T) {
+	ruleService := createAlertRuleService(t)
+	ruleService.cfg.BaseInterval = 20 * time.Second
+	var orgID int64 = 6
+	// insertDrifted stores rules of a group with the given intervals, bypassing the service like older data did.
+	insertDrifted := func(t *testing.T, group string, intervals ...int64) {
+		t.Helper()
+		rules := make([]models.AlertRule, 0, len(intervals))
+		for i, interval := range intervals {
+			rule := dummyRule(fmt.Sprintf("test#%s-%d", group, i), orgID)
+			rule.RuleGroup = group
+			rule.RuleGroupIndex = i + 1
+			rule.IntervalSeconds = interval
+			rule.Data[0].RelativeTimeRange.From = models.Duration(time.Minute)
+			rules = append(rules, rule)
+		}
+		_, err := ruleService.ruleStore.InsertAlertRules(context.Background(), rules)
+		require.NoError(t, err)
+	}
+	groupIntervals := func(t *testing.T, group string) []int64 {
+		t.Helper()
+		rules, err := ruleService.GetAlertRuleGroup(context.Background(), orgID, "my-cool-folder", group)
+		require.NoError(t, err)
+		intervals := make([]int64, 0, len(rules))
+		for _, rule := range rules {
+			intervals = append(intervals, rule.IntervalSeconds)
+		}
+		return intervals
+	}
+
+	t.Run("should set all rules to the minimum interval", func(t *testing.T) {
+		insertDrifted(t, "drifted", 60, 120)
+		interval, err := ruleService.NormalizeGroupInterval(context.Background(), orgID, "my-cool-folder", "drifted", models.ProvenanceNone)
+		require.NoError(t, err)
+		require.Equal(t, int64(60), interval)
+		require.Equal(t, []int64{60, 60}, groupIntervals(t, "drifted"))
+	})
+	t.Run("should round up to a multiple of the base interval", func(t *testing.T) {
+		insertDrifted(t, "unaligned", 50, 90)
+		interval, err := ruleService.NormalizeGroupInterval(context.Background(), orgID, "my-cool-folder", "unaligned", models.ProvenanceNone)
+		require.NoError(t, err)
+		require.Equal(t, int64(60), interval)
+		require.Equal(t, []int64{60, 60}, groupIntervals(t, "unaligned"))
+	})
+	t.Run("should return not found for a group without rules", func(t *testing.T) {
+		_, err := ruleService.NormalizeGroupInterval(context.Background(), orgID, "my-cool-folder", "empty-group", models.ProvenanceNone)
+		require.ErrorIs(t, err, store.ErrAlertRuleGroupNotFound)
+	})
+}
+
+func TestAlertRuleServiceListAlertRules(t *testing.T) {
+	ruleService := createAlertRuleService(t)
+	var orgID int64 = 3
+	for _, r := range []struct {
+		title    string
+		group    string
+		interval int64
+	}{
+		{title: "B", group: "group-1", interval: 60},
+		{title: "A", group: "group-2", interval: 120},
+		{title: "C", group: "group-2", interval: 120},
+		{title: "D", group: "group-1", interval: 60},
+	} {
+		rule := dummyRule(r.title, orgID)
+		rule.RuleGroup = r.group
+		_, err := ruleService.CreateAlertRule(context.Background(), rule, models.ProvenanceNone)
+		require.NoError(t, err)
+		require.NoError(t, ruleService.UpdateAlertGroup(context.Background(), orgID, rule.NamespaceUID, r.group, r.interval, models.ProvenanceNone))
+	}
+	titles := func(rules []models.AlertRule) []string {
+		result := make([]string, 0, len(rules))
+		for _, rule := range rules {
+			result = append(result, rule.Title)
+		}
+		return result
+	}
+
+	t.Run("should sort by title ascending", func(t *testing.T) {
+		rules, err := ruleService.ListAlertRules(context.Background(), orgID, ListAlertRulesOptions{
+			SortBy: []models.SortField{models.SortByTitle},
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"A", "B", "C", "D"}, titles(rules))
+	})
+	t.Run("should sort by interval descending", func(t *testing.T) {
+		rules, err := ruleService.ListAlertRules(context.Background(), orgID, ListAlertRulesOptions{
+			SortBy: []models.SortField{models.SortByInterval},
+			Desc:   true,
+		})
+		require.NoError(t, err)
+		require.Len(t, rules, 4)
+		require.Equal(t, int64(120), rules[0].IntervalSeconds)
+		require.Equal(t, int64(120), rules[1].IntervalSeconds)
+		require.Equal(t, int64(60), rules[3].IntervalSeconds)
+	})
+	t.Run("should break ties with the following fields", func(t *testing.T) {
+		rules, err := ruleService.ListAlertRules(context.Background(), orgID, ListAlertRulesOptions{
+			SortBy: []models.SortField{models.SortByInterval, models.SortByTitle},
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"B", "D", "A", "C"}, titles(rules))
+	})
+	t.Run("should sort by group and title", func(t *testing.T) {
+		rules, err := ruleService.ListAlertRules(context.Background(), orgID, ListAlertRulesOptions{
+			SortBy: []models.SortField{models.SortByGroup, models.SortByTitle},
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"B", "D", "A", "C"}, titles(rules))
+	})
+	t.Run("should sort by updated descending", func(t *testing.T) {
+		var orgID int64 = 4
+		t.Cleanup(func() { store.TimeNow = time.Now })
+		updated := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+		for _, r := range []struct {
+			title string
+			age   time.Duration
+		}{
+			{title: "old", age: 2 * time.Hour},
+			{title: "newest", age: 0},
+			{title: "older", age: 3 * time.Hour},
+			{title: "new", age: time.Hour},
+		} {
+			store.TimeNow = func() time.Time { return updated.Add(-r.age) }
+			rule := dummyRule(r.title, orgID)
+			rule.Data[0].RelativeTimeRange.From = models.Duration(time.Minute)
+			_, err := ruleService.ruleStore.InsertAlertRules(context.Background(), []models.AlertRule{rule})
+			require.NoError(t, err)
+		}
+
+		sorted, err := ruleService.ListAlertRules(context.Background(), orgID, ListAlertRulesOptions{
+			SortBy: []models.SortField{models.SortByUpdatedAt},
+			Desc:   true,
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"newest", "new", "old", "older"}, titles(sorted))
+
+		var paged []string
+		for offset := 0; offset < len(sorted); offset += 3 {
+			page, err := ruleService.ListAlertRules(context.Background(), orgID, ListAlertRulesOptions{
+				SortBy: []models.SortField{models.SortByUpdatedAt},
+				Desc:   true,
+				Limit:  3,
+				Offset: offset,
+			})
+			require.NoError(t, err)
+			paged = append(paged, titles(page)...)
+		}
+		require.Equal(t, titles(sorted), paged)
+	})
+	t.Run("should page through rules with equal sort fields", func(t *testing.T) {
+		var paged []string
+		for offset := 0; offset < 4; offset++ {
+			page, err := ruleService.ListAlertRules(context.Background(), orgID, ListAlertRulesOptions{
+				SortBy: []models.SortField{models.SortByNamespace},
+				Limit:  1,
+				Offset: offset,
+			})
+			require.NoError(t, err)
+			paged = append(paged, titles(page)...)
+		}
+		require.Equal(t, []string{"B", "A", "C", "D"}, paged)
+	})
+	t.Run("should reject an unknown sort field", func(t *testing.T) {
+		_, err := ruleService.ListAlertRules(context.Background(), orgID, ListAlertRulesOptions{
+			SortBy: []models.SortField{"severity"},
+		})
+		require.ErrorIs(t, err, models.ErrInvalidSortField)
+	})
+}
+
+func TestAlertRuleServiceSearchAlertRules(t *testing.T) {
+	var orgID int64 = 1
+	service := createAlertRuleService(t)
+	for _, title := range []string{"web latency", "Web errors", "database errors"} {
+		_, err := service.CreateAlertRule(context.Background(), dummyRule(title, orgID), models.ProvenanceNone)
+		require.NoError(t, err)
+	}
+	titles := func(rules []models.AlertRule) []string {
+		result := make([]string, 0, len(rules))
+		for _, rule := range rules {
+			result = append(result, rule.Title)
+		}
+		return result
+	}
+
+	t.Run("should return the matching rules sorted by title", func(t *testing.T) {
+		rules, err := service.SearchAlertRules(context.Background(), orgID, SearchAlertRulesOptions{Title: "WEB", TitlePrefix: true})
+		require.NoError(t, err)
+		require.Equal(t, []string{"Web errors", "web latency"}, titles(rules))
+	})
+	t.Run("should page through the matching rules", func(t *testing.T) {
+		rules, err := service.SearchAlertRules(context.Background(), orgID, SearchAlertRulesOptions{Title: "errors", Limit: 1, Offset: 1})
+		require.NoError(t, err)
+		require.Equal(t, []string{"Web errors"}, titles(rules))
+	})
+	t.Run("should reject negative pages", func(t *testing.T) {
+		_, err := service.SearchAlertRules(context.Background(), orgID, SearchAlertRulesOptions{Limit: -1})
+		require.ErrorIs(t, err, ErrValidation)
+	})
+}
+
+func TestAlertRuleServiceHealthCheck(t *testing.T) {
+	t.Run("should succeed if the store is reachable", func(t *testing.T) {
+		service := createAlertRuleService(t)
+		require.NoError(t, service.HealthCheck(context.Background()))
+	})
+	t.Run("should return StorageUnavailableError if the store fails", func(t *testing.T) {
+		ruleStore := store.NewFakeRuleStore(t)
+		ruleStore.Hook = func(interface{}) error {
+			return errors.New("database is down")
+		}
+		service := AlertRuleService{
+			ruleStore: ruleStore,
+			xact:      newNopTransactionManager(),
+			log:       log.NewNopLogger(),
+		}
+
+		err := service.HealthCheck(context.Background())
+		var storageErr StorageUnavailableError
+		require.ErrorAs(t, err, &storageErr)
+		require.EqualError(t, storageErr.Err, "database is down")
+	})
+	t.Run("should return StorageUnavailableError if the transaction cannot be started", func(t *testing.T) {
+		service := AlertRuleService{
+			ruleStore: store.NewFakeRuleStore(t),
+			xact:      &failingTransactionManager{err: errors.New("connection refused")},
+			log:       log.NewNopLogger(),
+		}
+
+		err := service.HealthCheck(context.Background())
+		require.ErrorAs(t, err, &StorageUnavailableError{})
+	})
+}
+
+func TestAlertRuleServiceGetAlertRulesByDataSource(t *testing.T) {
+	ruleService := createAlertRuleService(t)
+	var orgID int64 = 6
+	ruleWithDataSource := func(title string, datasourceUIDs ...string) models.AlertRule {
+		rule := dummyRule(title, orgID)
+		rule.Data = nil
+		for i, uid := range datasourceUIDs {
+			rule.Data = append(rule.Data, models.AlertQuery{
+				RefID:             fmt.Sprintf("Q%d", i),
+				DatasourceUID:     uid,
+				Model:             json.RawMessage("{}"),
+				RelativeTimeRange: models.RelativeTimeRange{From: models.Duration(10 * time.Minute)},
+			})
+		}
+		rule.Condition = rule.Data[0].RefID
+		return rule
+	}
+	uids := func(rules []models.AlertRule) []string {
+		result := make([]string, 0, len(rules))
+		for _, rule := range rules {
+			result = append(result, rule.UID)
+		}
+		return result
+	}
+	matching, err := ruleService.CreateAlertRule(context.Background(), ruleWithDataSource("test#ds-1", "ds-1", "__expr__"), models.ProvenanceNone)
+	require.NoError(t, err)
+	both, err := ruleService.CreateAlertRule(context.Background(), ruleWithDataSource("test#ds-2", "ds-2", "ds-1"), models.ProvenanceNone)
+	require.NoError(t, err)
+	other, err := ruleService.CreateAlertRule(context.Background(), ruleWithDataSource("test#ds-3", "ds-2"), models.ProvenanceNone)
+	require.NoError(t, err)
+
+	t.Run("should return the rules that query the data source", func(t *testing.T) {
+		rules, err := ruleService.GetAlertRulesByDataSource(context.Background(), orgID, "ds-1")
+		require.NoError(t, err)
+		require.Equal(t, []string{matching.UID, both.UID}, uids(rules))
+	})
+	t.Run("should follow updates and deletes of rules", func(t *testing.T) {
+		updated := ruleWithDataSource(other.Title, "ds-1")
+		updated.UID = other.UID
+		_, err := ruleService.UpdateAlertRule(context.Background(), updated, models.ProvenanceNone)
+		require.NoError(t, err)
+		require.NoError(t, ruleService.DeleteAlertRule(context.Background(), orgID, matching.UID, models.ProvenanceNone))
+
+		rules, err := ruleService.GetAlertRulesByDataSource(context.Background(), orgID, "ds-1")
+		require.NoError(t, err)
+		require.Equal(t, []string{both.UID, other.UID}, uids(rules))
+		rules, err = ruleService.GetAlertRulesByDataSource(context.Background(), orgID, "ds-2")
+		require.NoError(t, err)
+		require.Equal(t, []string{both.UID}, uids(rules))
+	})
+	t.Run("should not return rules of other organizations or expressions", func(t *testing.T) {
+		rules, err := ruleService.GetAlertRulesByDataSource(context.Background(), orgID+1, "ds-1")
+		require.NoError(t, err)
+		require.Empty(t, rules)
+		rules, err = ruleService.GetAlertRulesByDataSource(context.Background(), orgID, "__expr__")
+		require.NoError(t, err)
+		require.Empty(t, rules)
+	})
+	t.Run("CheckDataSourceDelete should block the deletion of used data sources if configured", func(t *testing.T) {
+		require.NoError(t, ruleService.CheckDataSourceDelete(context.Background(), orgID, "ds-1"))
+
+		ruleService.cfg.BlockDSDeleteIfUsed = true
+		defer func() { ruleService.cfg.BlockDSDeleteIfUsed = false }()
+		err := ruleService.CheckDataSourceDelete(context.Background(), orgID, "ds-1")
+		require.ErrorIs(t, err, ErrDataSourceInUse)
+		require.Contains(t, err.Error(), both.UID)
+		require.Contains(t, err.Error(), other.UID)
+		require.NoError(t, ruleService.CheckDataSourceDelete(context.Background(), orgID, "ds-unused"))
+	})
+}
+
+func TestAlertRuleServiceGetAlertRulesByOwner(t *testing.T) {
+	ruleService := createAlertRuleService(t)
+	var orgID int64 = 7
+	createRule := func(title string, owner string) models.AlertRule {
+		rule := dummyRule(title, orgID)
+		rule.ManagedBy = owner
+		rule, err := ruleService.CreateAlertRule(context.Background(), rule, models.ProvenanceAPI)
+		require.NoError(t, err)
+		return rule
+	}
+	first := createRule("test#owner-a-1", "operator-a")
+	createRule("test#owner-b", "operator-b")
+	second := createRule("test#owner-a-2", "operator-a")
+	createRule("test#no-owner", "")
+
+	t.Run("should return the rules of the owner", func(t *testing.T) {
+		rules, err := ruleService.GetAlertRulesByOwner(context.Background(), orgID, "operator-a")
+		require.NoError(t, err)
+		require.Len(t, rules, 2)
+		require.Equal(t, first.UID, rules[0].UID)
+		require.Equal(t, second.UID, rules[1].UID)
+		require.Equal(t, "operator-a", rules[0].ManagedBy)
+	})
+	t.Run("should not return rules of other organizations", func(t *testing.T) {
+		rules, err := ruleService.GetAlertRulesByOwner(context.Background(), orgID+1, "operator-a")
+		require.NoError(t, err)
+		require.Empty(t, rules)
+	})
+	t.Run("should reject an empty owner", func(t *testing.T) {
+		_, err := ruleService.GetAlertRulesByOwner(context.Background(), orgID, "")
+		require.ErrorIs(t, err, ErrValidation)
+	})
+	t.Run("should reject owners that are too long", func(t *testing.T) {
+		rule := dummyRule("test#long-owner", orgID)
+		rule.ManagedBy = strings.Repeat("a", store.AlertRuleMaxManagedByLength+1)
+		_, err := ruleService.CreateAlertRule(context.Background(), rule, models.ProvenanceAPI)
+		require.ErrorIs(t, err, ErrValidation)
+	})
+}
+
+func TestAlertRuleServiceOperationTimeouts(t *testing.T) {
+	service := createAlertRuleService(t)
+	service.ruleStore = &slowRuleStore{RuleStore: service.ruleStore}
+
+	t.Run("should cancel operations after their timeout", func(t *testing.T) {
+		service.cfg.OperationTimeouts = OperationTimeouts{Create: time.Millisecond}
+		_, err := service.CreateAlertRule(context.Background(), dummyRule("test#timeout", 1), models.ProvenanceNone)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+	t.Run("should keep the deadline of the context if the timeout is zero", func(t *testing.T) {
+		service.cfg.OperationTimeouts = OperationTimeouts{Get: time.Millisecond}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := service.CreateAlertRule(ctx, dummyRule("test#timeout", 1), models.ProvenanceNone)
+		require.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestAlertRuleServiceReorderRuleGroup(t *testing.T) {
+	var orgID int64 = 1
+	service := createAlertRuleService(t)
+	var uids []string
+	for _, title := range []string{"a", "b", "c"} {
+		rule := dummyRule(title, orgID)
+		rule.Data[0].RelativeTimeRange.From = models.Duration(10 * time.Minute)
+		rule.NamespaceUID = "folder"
+		rule.RuleGroup = "group"
+		created, err := service.CreateAlertRule(context.Background(), rule, models.ProvenanceAPI)
+		require.NoError(t, err)
+		uids = append(uids, created.UID)
+	}
+	groupTitles := func(t *testing.T) []string {
+		t.Helper()
+		rules, err := service.GetAlertRuleGroup(context.Background(), orgID, "folder", "group")
+		require.NoError(t, err)
+		titles := make([]string, 0, len(rules))
+		for _, rule := range rules {
+			titles = append(titles, rule.Title)
+		}
+		return titles
+	}
+	require.Equal(t, []string{"a", "b", "c"}, groupTitles(t))
+
+	t.Run("should move the rules into the given order", func(t *testing.T) {
+		err := service.ReorderRuleGroup(context.Background(), orgID, "folder", "group", []string{uids[2], uids[0], uids[1]}, models.ProvenanceAPI)
+		require.NoError(t, err)
+		require.Equal(t, []string{"c", "a", "b"}, groupTitles(t))
+	})
+	t.Run("should reject orders with missing rules", func(t *testing.T) {
+		err := service.ReorderRuleGroup(context.Background(), orgID, "folder", "group", []string{uids[1], uids[0]}, models.ProvenanceAPI)
+		require.ErrorIs(t, err, ErrValidation)
+		require.Equal(t, []string{"c", "a", "b"}, groupTitles(t))
+	})
+	t.Run("should reject orders with extra rules", func(t *testing.T) {
+		err := service.ReorderRuleGroup(context.Background(), orgID, "folder", "group", []string{uids[0], uids[1], uids[2], "unknown"}, models.ProvenanceAPI)
+		require.ErrorIs(t, err, ErrValidation)
+		require.Equal(t, []string{"c", "a", "b"}, groupTitles(t))
+	})
+	t.Run("should reject orders with another provenance", func(t *testing.T) {
+		err := service.ReorderRuleGroup(context.Background(), orgID, "folder", "group", uids, models.ProvenanceFile)
+		require.ErrorIs(t, err, ErrProvenanceMismatch)
+	})
+	t.Run("should append created rules to the group", func(t *testing.T) {
+		rule := dummyRule("d", orgID)
+		rule.NamespaceUID = "folder"
+		rule.RuleGroup = "group"
+		_, err := service.CreateAlertRule(context.Background(), rule, models.ProvenanceAPI)
+		require.NoError(t, err)
+		require.Equal(t, []string{"c", "a", "b", "d"}, groupTitles(t))
+	})
+}
+
+func TestAlertRuleServiceReorderRulesInGroup(t *testing.T) {
+	var orgID int64 = 1
+	service := createAlertRuleService(t)
+	var uids []string
+	for _, title := range []string{"a", "b", "c"} {
+		rule := dummyRule(title, orgID)
+		rule.Data[0].RelativeTimeRange.From = models.Duration(10 * time.Minute)
+		rule.NamespaceUID = "folder"
+		rule.RuleGroup = "eval-group"
+		created, err := service.CreateAlertRule(context.Background(), rule, models.ProvenanceFile)
+		require.NoError(t, err)
+		uids = append(uids, created.UID)
+	}
+	evalOrders := func(t *testing.T) map[string]int {
+		t.Helper()
+		rules, err := service.GetAlertRuleGroup(context.Background(), orgID, "folder", "eval-group")
+		require.NoError(t, err)
+		orders := make(map[string]int, len(rules))
+		for _, rule := range rules {
+			orders[rule.Title] = rule.EvalOrder
+		}
+		return orders
+	}
+
+	t.Run("should store the evaluation order", func(t *testing.T) {
+		err := service.ReorderRulesInGroup(context.Background(), orgID, "folder", "eval-group", []string{uids[2], uids[0], uids[1]}, models.ProvenanceFile)
+		require.NoError(t, err)
+		require.Equal(t, map[string]int{"c": 1, "a": 2, "b": 3}, evalOrders(t))
+
+		rules, err := service.GetAlertRuleGroup(context.Background(), orgID, "folder", "eval-group")
+		require.NoError(t, err)
+		require.Equal(t, "a", rules[0].Title, "the position of the rules in the group should not change")
+	})
+	t.Run("should reject orders that are not the rules of the group", func(t *testing.T) {
+		err := service.ReorderRulesInGroup(context.Background(), orgID, "folder", "eval-group", []string{uids[1], uids[0]}, models.ProvenanceFile)
+		require.ErrorIs(t, err, ErrValidation)
+		err = service.ReorderRulesInGroup(context.Background(), orgID, "folder", "eval-group", []string{uids[1], uids[0], "unknown"}, models.ProvenanceFile)
+		require.ErrorIs(t, err, ErrValidation)
+		err = service.ReorderRulesInGroup(context.Background(), orgID, "folder", "eval-group", []string{uids[1], uids[0], uids[1]}, models.ProvenanceFile)
+		require.ErrorIs(t, err, ErrValidation)
+		require.Equal(t, map[string]int{"c": 1, "a": 2, "b": 3}, evalOrders(t))
+	})
+	t.Run("should reject orders with another provenance", func(t *testing.T) {
+		err := service.ReorderRulesInGroup(context.Background(), orgID, "folder", "eval-group", []string{uids[0], uids[1], uids[2]}, models.ProvenanceAPI)
+		require.ErrorIs(t, err, ErrProvenanceMismatch)
+		err = service.ReorderRulesInGroup(context.Background(), orgID, "folder", "eval-group", []string{uids[0], uids[1], uids[2]}, models.ProvenanceNone)
+		require.ErrorIs(t, err, ErrProvenanceMismatch)
+		require.Equal(t, map[string]int{"c": 1, "a": 2, "b": 3}, evalOrders(t))
+	})
+	t.Run("should keep the evaluation order on updates", func(t *testing.T) {
+		rule, _, err := service.GetAlertRule(context.Background(), orgID, uids[0])
+		require.NoError(t, err)
+		rule.Title = "a-updated"
+		rule.EvalOrder = 0
+		_, err = service.UpdateAlertRule(context.Background(), rule, models.ProvenanceFile)
+		require.NoError(t, err)
+		require.Equal(t, map[string]int{"c": 1, "a-updated": 2, "b": 3}, evalOrders(t))
+	})
+}
+
+func TestAlertRuleServiceAdoptAlertRules(t *testing.T) {
+	var orgID int64 = 1
+	service := createAlertRuleService(t)
+	createRule := func(t *testing.T, title string, provenance models.Provenance) models.AlertRule {
+		t.Helper()
+		rule, err := service.CreateAlertRule(context.Background(), dummyRule(title, orgID), provenance)
+		require.NoError(t, err)
+		return rule
+	}
+	a := createRule(t, "adopt#a", models.ProvenanceNone)
+	b := createRule(t, "adopt#b", models.ProvenanceNone)
+	provisioned := createRule(t, "adopt#provisioned", models.ProvenanceAPI)
+	unmanaged := createRule(t, "adopt#unmanaged", models.ProvenanceNone)
+
+	t.Run("should set the provenance of the rules without changing them", func(t *testing.T) {
+		err := service.AdoptAlertRules(context.Background(), orgID, []string{a.UID, b.UID}, models.ProvenanceFile)
+		require.NoError(t, err)
+		for _, created := range []models.AlertRule{a, b} {
+			stored, provenance, err := service.GetAlertRule(context.Background(), orgID, created.UID)
+			require.NoError(t, err)
+			require.Equal(t, models.ProvenanceFile, provenance)
+			require.Equal(t, created.Version, stored.Version)
+			require.Equal(t, created.Title, stored.Title)
+		}
+	})
+	t.Run("should not adopt any rule if one of them is provisioned", func(t *testing.T) {
+		err := service.AdoptAlertRules(context.Background(), orgID, []string{unmanaged.UID, provisioned.UID}, models.ProvenanceFile)
+		require.ErrorIs(t, err, ErrProvenanceMismatch)
+		_, provenance, err := service.GetAlertRule(context.Background(), orgID, unmanaged.UID)
+		require.NoError(t, err)
+		require.Equal(t, models.ProvenanceNone, provenance)
+	})
+	t.Run("should not adopt rules that do not exist", func(t *testing.T) {
+		err := service.AdoptAlertRules(context.Background(), orgID, []string{unmanaged.UID, "unknown"}, models.ProvenanceFile)
+		require.ErrorIs(t, err, models.ErrAlertRuleNotFound)
+		_, provenance, err := service.GetAlertRule(context.Background(), orgID, unmanaged.UID)
+		require.NoError(t, err)
+		require.Equal(t, models.ProvenanceNone, provenance)
+	})
+	t.Run("should reject adopting without a provenance", func(t *testing.T) {
+		err := service.AdoptAlertRules(context.Background(), orgID, []string{unmanaged.UID}, models.ProvenanceNone)
+		require.ErrorIs(t, err, ErrValidation)
+	})
+}
+
+func TestAlertRuleServiceTitleTemplate(t *testing.T) {
+	var orgID int64 = 1
+	service := createAlertRuleService(t)
+
+	t.Run("should store the title template next to the title", func(t *testing.T) {
+		rule := dummyRule("test#title-template", orgID)
+		rule.TitleTemplate = "High CPU on {{ .Labels.instance }}"
+		created, err := service.CreateAlertRule(context.Background(), rule, models.ProvenanceNone)
+		require.NoError(t, err)
+
+		stored, _, err := service.GetAlertRule(context.Background(), orgID, created.UID)
+		require.NoError(t, err)
+		require.Equal(t, "test#title-template", stored.Title)
+		require.Equal(t, "High CPU on {{ .Labels.instance }}", stored.TitleTemplate)
+	})
+	t.Run("should reject title templates that cannot be parsed", func(t *testing.T) {
+		rule := dummyRule("test#invalid-title-template", orgID)
+		rule.TitleTemplate = "High CPU on {{ .Labels.instance"
+		_, err := service.CreateAlertRule(context.Background(), rule, models.ProvenanceNone)
+		require.ErrorIs(t, err, models.ErrInvalidTitleTemplate)
+		require.Equal(t, ErrCodeValidation, ErrorCodeOf(err))
+	})
+	t.Run("should reject active windows with unknown time zones", func(t *testing.T) {
+		rule := dummyRule("test#invalid-active-window", orgID)
+		rule.ActiveWindow = &models.ActiveWindow{Location: "Mars/Olympus_Mons"}
+		_, err := service.CreateAlertRule(context.Background(), rule, models.ProvenanceNone)
+		require.ErrorIs(t, err, models.ErrInvalidActiveWindow)
+		require.Equal(t, ErrCodeValidation, ErrorCodeOf(err))
+		require.Contains(t, err.Error(), "'Mars/Olympus_Mons'")
+	})
+}
+
+func TestAlertRuleServiceGetAlertRuleGroupOrder(t *testing.T) {
+	var orgID int64 = 1
+	service := createAlertRuleService(t)
+	dbStore := service.ruleStore.(store.DBstore)
+	rules := []models.AlertRule{}
+	for _, r := range []struct {
+		title string
+		index int
+	}{{"d", 2}, {"c", 0}, {"b", 2}, {"a", 3}, {"e", 1}} {
+		rule := dummyRule(r.title, orgID)
+		rule.NamespaceUID = "folder"
+		rule.RuleGroup = "group"
+		rule.RuleGroupIndex = r.index
+		rules = append(rules, rule)
+	}
+	_, err := dbStore.InsertAlertRules(context.Background(), rules)
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		group, err := service.GetAlertRuleGroup(context.Background(), orgID, "folder", "group")
+		require.NoError(t, err)
+		titles := make([]string, 0, len(group))
+		for _, rule := range group {
+			titles = append(titles, rule.Title)
+		}
+		require.Equal(t, []string{"e", "b", "d", "a", "c"}, titles)
+	}
+}
+
+func TestAlertRuleServiceUpdateRuleGroupEvalStrategy(t *testing.T) {
+	var orgID int64 = 1
+	service := createAlertRuleService(t)
+	newRule := func(title string) models.AlertRule {
+		rule := dummyRule(title, orgID)
+		rule.Data[0].RelativeTimeRange.From = models.Duration(10 * time.Minute)
+		rule.NamespaceUID = "folder"
+		rule.RuleGroup = "group"
+		return rule
+	}
+	groupStrategies := func(t *testing.T) []string {
+		t.Helper()
+		rules, err := service.GetAlertRuleGroup(context.Background(), orgID, "folder", "group")
+		require.NoError(t, err)
+		strategies := make([]string, 0, len(rules))
+		for _, rule := range rules {
+			strategies = append(strategies, rule.EvalStrategy)
+		}
+		return strategies
+	}
+	first, err := service.CreateAlertRule(context.Background(), newRule("a"), models.ProvenanceAPI)
+	require.NoError(t, err)
+	_, err = service.CreateAlertRule(context.Background(), newRule("b"), models.ProvenanceAPI)
+	require.NoError(t, err)
+	require.Equal(t, []string{models.EvalStrategyIndependent, models.EvalStrategyIndependent}, groupStrategies(t))
+
+	t.Run("should update the strategy of all rules of the group", func(t *testing.T) {
+		err := service.UpdateRuleGroupEvalStrategy(context.Background(), orgID, "folder", "group", models.EvalStrategyAllSuccess, models.ProvenanceAPI)
+		require.NoError(t, err)
+		require.Equal(t, []string{models.EvalStrategyAllSuccess, models.EvalStrategyAllSuccess}, groupStrategies(t))
+
+		stored, _, err := service.GetAlertRule(context.Background(), orgID, first.UID)
+		require.NoError(t, err)
+		require.Equal(t, first.Version+1, stored.Version)
+		version, err := service.ruleStore.GetAlertRuleVersion(context.Background(), orgID, first.UID, stored.Version)
+		require.NoError(t, err)
+		require.Equal(t, models.EvalStrategyAllSuccess, version.EvalStrategy)
+		require.Equal(t, first.Version, version.ParentVersion)
+	})
+	t.Run("should reject unknown strategies", func(t *testing.T) {
+		err := service.UpdateRuleGroupEvalStrategy(context.Background(), orgID, "folder", "group", "some_success", models.ProvenanceAPI)
+		require.ErrorIs(t, err, ErrValidation)
+		require.Equal(t, []string{models.EvalStrategyAllSuccess, models.EvalStrategyAllSuccess}, groupStrategies(t))
+	})
+	t.Run("should fail if the provenance of a rule is different", func(t *testing.T) {
+		err := service.UpdateRuleGroupEvalStrategy(context.Background(), orgID, "folder", "group", models.EvalStrategyAnySuccess, models.ProvenanceFile)
+		require.ErrorIs(t, err, ErrProvenanceMismatch)
+		require.Equal(t, []string{models.EvalStrategyAllSuccess, models.EvalStrategyAllSuccess}, groupStrategies(t))
+	})
+	t.Run("should fail if the group has no rules", func(t *testing.T) {
+		err := service.UpdateRuleGroupEvalStrategy(context.Background(), orgID, "folder", "missing", models.EvalStrategyAllSuccess, models.ProvenanceAPI)
+		require.ErrorIs(t, err, store.ErrAlertRuleGroupNotFound)
+		err = service.ruleStore.UpdateRuleGroupEvalStrategy(context.Background(), orgID, "folder", "missing", models.EvalStrategyAllSuccess)
+		require.ErrorIs(t, err, store.ErrAlertRuleGroupNotFound)
+	})
+	t.Run("should keep the strategy of the group for created and updated rules", func(t *testing.T) {
+		_, err := service.CreateAlertRule(context.Background(), newRule("c"), models.ProvenanceAPI)
+		require.NoError(t, err)
+		updated := newRule("a-renamed")
+		updated.UID = first.UID
+		_, err = service.UpdateAlertRule(context.Background(), updated, models.ProvenanceAPI)
+		require.NoError(t, err)
+		require.Equal(t, []string{models.EvalStrategyAllSuccess, models.EvalStrategyAllSuccess, models.EvalStrategyAllSuccess}, groupStrategies(t))
+	})
+}
+
+func TestAlertRuleServiceUpdateRuleGroupLabels(t *testing.T) {
+	var orgID int64 = 1
+	service := createAlertRuleService(t)
+	newRule := func(title string) models.AlertRule {
+		rule := dummyRule(title, orgID)
+		rule.Data[0].RelativeTimeRange.From = models.Duration(10 * time.Minute)
+		rule.NamespaceUID = "folder"
+		rule.RuleGroup = "group"
+		return rule
+	}
+	groupLabels := func(t *testing.T) []map[string]string {
+		t.Helper()
+		rules, err := service.GetAlertRuleGroup(context.Background(), orgID, "folder", "group")
+		require.NoError(t, err)
+		labels := make([]map[string]string, 0, len(rules))
+		for _, rule := range rules {
+			labels = append(labels, rule.GroupLabels)
+		}
+		return labels
+	}
+	rule := newRule("a")
+	rule.Labels = map[string]string{"severity": "page"}
+	first, err := service.CreateAlertRule(context.Background(), rule, models.ProvenanceAPI)
+	require.NoError(t, err)
+	_, err = service.CreateAlertRule(context.Background(), newRule("b"), models.ProvenanceAPI)
+	require.NoError(t, err)
+	labels := map[string]string{"severity": "info", "team": "a"}
+
+	t.Run("should update the labels of all rules of the group", func(t *testing.T) {
+		err := service.UpdateRuleGroupLabels(context.Background(), orgID, "folder", "group", labels, models.ProvenanceAPI)
+		require.NoError(t, err)
+		require.Equal(t, []map[string]string{labels, labels}, groupLabels(t))
+
+		stored, _, err := service.GetAlertRule(context.Background(), orgID, first.UID)
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{"severity": "page"}, stored.Labels)
+		require.Equal(t, map[string]string{"severity": "page", "team": "a"}, stored.EffectiveLabels())
+		require.Equal(t, first.Version+1, stored.Version)
+
+		version, err := service.ruleStore.GetAlertRuleVersion(context.Background(), orgID, first.UID, stored.Version)
+		require.NoError(t, err)
+		require.Equal(t, labels, version.GroupLabels)
+		require.Equal(t, map[string]string{"severity": "page"}, version.Labels)
+		require.Equal(t, first.Version, version.ParentVersion)
+	})
+	t.Run("should keep the labels of the group for created and updated rules", func(t *testing.T) {
+		_, err := service.CreateAlertRule(context.Background(), newRule("c"), models.ProvenanceAPI)
+		require.NoError(t, err)
+		updated := newRule("a-renamed")
+		updated.UID = first.UID
+		_, err = service.UpdateAlertRule(context.Background(), updated, models.ProvenanceAPI)
+		require.NoError(t, err)
+		require.Equal(t, []map[string]string{labels, labels, labels}, groupLabels(t))
+	})
+	t.Run("should export the labels at the level of the group", func(t *testing.T) {
+		rules, err := service.GetAlertRuleGroup(context.Background(), orgID, "folder", "group")
+		require.NoError(t, err)
+		refs := make([]*models.AlertRule, 0, len(rules))
+		for i := range rules {
+			refs = append(refs, &rules[i])
+		}
+		export, err := newAlertRuleGroupExport(orgID, "folder", "group", refs)
+		require.NoError(t, err)
+		require.Equal(t, labels, export.Labels)
+		for _, rule := range export.Rules {
+			require.NotContains(t, rule.Labels, "team")
+		}
+		upstream, err := export.Rules[0].UpstreamModel(export)
+		require.NoError(t, err)
+		require.Equal(t, labels, upstream.GroupLabels)
+	})
+	t.Run("should reject rules with another provenance", func(t *testing.T) {
+		other := newRule("d")
+		_, err := service.CreateAlertRule(context.Background(), other, models.ProvenanceFile)
+		require.NoError(t, err)
+		err = service.UpdateRuleGroupLabels(context.Background(), orgID, "folder", "group", map[string]string{"team": "b"}, models.ProvenanceAPI)
+		require.ErrorIs(t, err, ErrProvenanceMismatch)
+		require.Equal(t, []map[string]string{labels, labels, labels, labels}, groupLabels(t))
+	})
+	t.Run("should fail for missing groups", func(t *testing.T) {
+		err := service.UpdateRuleGroupLabels(context.Background(), orgID, "folder", "missing", labels, models.ProvenanceAPI)
+		require.ErrorIs(t, err, store.ErrAlertRuleGroupNotFound)
+	})
+}
+
+func TestAlertRuleServiceStateSummaries(t *testing.T) {
+	var orgID int64 = 1
+	service := createAlertRuleService(t)
+	dbStore := service.ruleStore.(store.DBstore)
+	rule, err := service.CreateAlertRule(context.Background(), dummyRule("test#state", orgID), models.ProvenanceNone)
+	require.NoError(t, err)
+	other, err := service.CreateAlertRule(context.Background(), dummyRule("test#no-state", orgID), models.ProvenanceNone)
+	require.NoError(t, err)
+
+	lastEval := time.Unix(1700000000, 0)
+	instances := []models.SaveAlertInstanceCommand{
+		{State: models.InstanceStateFiring, LastEvalTime: lastEval.Add(-time.Minute)},
+		{State: models.InstanceStateFiring, LastEvalTime: lastEval},
+		{State: models.InstanceStateNormal, StateReason: string(models.InstanceStateError), LastEvalTime: lastEval.Add(-2 * time.Minute)},
+	}
+	for i, cmd := range instances {
+		cmd := cmd
+		cmd.RuleOrgID = orgID
+		cmd.RuleUID = rule.UID
+		cmd.Labels = models.InstanceLabels{"instance": fmt.Sprint(i)}
+		require.NoError(t, dbStore.SaveAlertInstance(context.Background(), &cmd))
+	}
+
+	t.Run("should not include a summary if state summaries are not available", func(t *testing.T) {
+		_, _, summary, err := service.GetAlertRuleWithStateSummary(context.Background(), orgID, rule.UID)
+		require.NoError(t, err)
+		require.Nil(t, summary)
+	})
+
+	service.stateSummaries = dbStore
+	t.Run("should summarize the alert instances of a rule", func(t *testing.T) {
+		_, _, summary, err := service.GetAlertRuleWithStateSummary(context.Background(), orgID, rule.UID)
+		require.NoError(t, err)
+		require.NotNil(t, summary)
+		require.Equal(t, map[models.InstanceStateType]int64{models.InstanceStateFiring: 2, models.InstanceStateNormal: 1}, summary.Instances)
+		require.True(t, lastEval.Equal(summary.LastEvaluation))
+		require.True(t, lastEval.Add(-2*time.Minute).Equal(summary.LastError))
+	})
+	t.Run("should summarize the alert instances of all listed rules", func(t *testing.T) {
+		rules, summaries, err := service.ListAlertRulesWithStateSummaries(context.Background(), orgID, ListAlertRulesOptions{})
+		require.NoError(t, err)
+		require.Len(t, rules, 2)
+		require.Len(t, summaries, 1)
+		require.Equal(t, int64(2), summaries[rule.UID].Instances[models.InstanceStateFiring])
+		require.Nil(t, summaries[other.UID])
+	})
+}
+
+// slowRuleStore blocks reading rule group intervals until the context is done.
+type slowRuleStore struct {
+	store.RuleStore
+}
+
+func (s *slowRuleStore) GetRuleGroupInterval(ctx context.Context, _ int64, _ string, _ string) (int64, error) {
+	<-ctx.Done()
+	return 0, ctx.Err()
+}
+
+func createAlertRuleService(t *testing.T) AlertRuleService {
+	t.Helper()
+	sqlStore := sqlstore.InitTestDB(t)
+	store := store.DBstore{
+		SQLStore:     sqlStore,
+		BaseInterval: time.Second * 10,
+		Logger:       log.NewNopLogger(),
+	}
+	return AlertRuleService{
+		ruleStore:       store,
+		provenanceStore: store,
+		sources:         store,
+		xact:            sqlStore,
+		log:             log.New("testing"),
+		defaultInterval: 60,
+		createLocks:     newKeyedMutex(),
+		quota:           NoopQuotaChecker{},
+		clock:           clock.New(),
+	}
+}
+
+func dummyRule(title string, orgID int64) models.AlertRule {
+	return models.AlertRule{
+		OrgID:           orgID,
+		Title:           title,
+		Condition:       "A",
+		Version:         1,
+		IntervalSeconds: 60,
+		Data: []models.AlertQuery{
+			{
+				RefID: "A",
+				Model: json.RawMessage("{}"),
+				RelativeTimeRange: models.RelativeTimeRange{
+					From: models.Duration(60),
+					To:   models.Duration(0),
+				},
+			},
+		},
+		NamespaceUID: "my-cool-folder",
+		RuleGroup:    "my-cool-group",
+		For:          time.Second * 60,
+		NoDataState:  models.OK,
+		ExecErrState: models.OkErrState,
+	}
+}
+
+func TestAlertRuleServiceUnmanageAlertRule(t *testing.T) {
+	ruleService := createAlertRuleService(t)
+	var orgID int64 = 7
+	admin := WithCaller(context.Background(), &models2.SignedInUser{OrgId: orgID, Login: "admin"})
+	rule, err := ruleService.CreateAlertRule(context.Background(), dummyRule("test#unmanage", orgID), models.ProvenanceFile)
+	require.NoError(t, err)
+	requireProvenance := func(t *testing.T, expected models.Provenance) {
+		t.Helper()
+		_, provenance, err := ruleService.GetAlertRule(context.Background(), orgID, rule.UID)
+		require.NoError(t, err)
+		require.Equal(t, expected, provenance)
+	}
+
+	t.Run("should reject the downgrade without force", func(t *testing.T) {
+		ruleService.ac = acmock.New().WithPermissions([]*accesscontrol.Permission{{Action: accesscontrol.ActionAlertingRuleUnmanage}})
+		err := ruleService.UnmanageAlertRule(admin, orgID, rule.UID, false)
+		require.ErrorIs(t, err, ErrProvenanceMismatch)
+		requireProvenance(t, models.ProvenanceFile)
+	})
+	t.Run("should reject callers that are not allowed to unmanage rules", func(t *testing.T) {
+		ruleService.ac = acmock.New()
+		err := ruleService.UnmanageAlertRule(admin, orgID, rule.UID, true)
+		require.ErrorIs(t, err, ErrAccessDenied)
+		requireProvenance(t, models.ProvenanceFile)
+	})
+	t.Run("should remove the provenance with force", func(t *testing.T) {
+		ruleService.ac = acmock.New().WithPermissions([]*accesscontrol.Permission{{Action: accesscontrol.ActionAlertingRuleUnmanage}})
+		require.NoError(t, ruleService.UnmanageAlertRule(admin, orgID, rule.UID, true))
+		requireProvenance(t, models.ProvenanceNone)
+
+		rule.Title = "test#unmanaged"
+		_, err := ruleService.UpdateAlertRule(context.Background(), rule, models.ProvenanceNone)
+		require.NoError(t, err)
+	})
+}
+
+// insertBarrierStore holds back the first insert of alert rules until a second insert starts or
+// a timeout expires, to make concurrent creations overlap.
+type insertBarrierStore struct {
+	store.RuleStore
+	mtx     sync.Mutex
+	inserts int
+	second  chan struct{}
+}
+
+func (s *insertBarrierStore) InsertAlertRules(ctx context.Context, rules []models.AlertRule) (map[string]int64, error) {
+	s.mtx.Lock()
+	s.inserts++
+	first := s.inserts == 1
+	if s.inserts == 2 {
+		close(s.second)
+	}
+	s.mtx.Unlock()
+	if first {
+		select {
+		case <-s.second:
+		case <-time.After(200 * time.Millisecond):
+		}
 	}
+	return s.RuleStore.InsertAlertRules(ctx, rules)
 }
@@ -0,0 +1,165 @@
+package provisioning
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	apimodels "github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/store"
+)
+
+// RenameContactPoint renames the contact point and updates all routes of the policy tree and all
+// notification settings of alert rules that reference it, so that none of them break. The contact
+// point, the policy tree and the rules must all be changeable with the provenance. The configuration
+// and the rules are updated in a single transaction. It returns the number of updated references.
+func (ecp *ContactPointService) RenameContactPoint(ctx context.Context, orgID int64, oldName, newName string, provenance models.Provenance) (int, error) {
+	if err := ecp.policy.checkMutation(orgID, ResourceTypeContactPoints, provenance); err != nil {
+		return 0, err
+	}
+	newName = strings.TrimSpace(newName)
+	if newName == "" {
+		return 0, fmt.Errorf("%w: the new name of the contact point is empty", ErrValidation)
+	}
+	if newName == oldName {
+		return 0, fmt.Errorf("%w: the contact point is already named '%s'", ErrValidation, newName)
+	}
+	revision, err := getLastConfiguration(ctx, orgID, ecp.amStore)
+	if err != nil {
+		return 0, err
+	}
+	var renamed *apimodels.PostableApiReceiver
+	for _, receiver := range revision.cfg.AlertmanagerConfig.Receivers {
+		switch receiver.Name {
+		case oldName:
+			renamed = receiver
+		case newName:
+			return 0, fmt.Errorf("%w: a contact point named '%s' already exists", ErrValidation, newName)
+		}
+	}
+	if renamed == nil {
+		return 0, fmt.Errorf("%w: '%s'", ErrContactPointNotFound, oldName)
+	}
+
+	for _, integration := range renamed.GrafanaManagedReceivers {
+		if err := ecp.checkProvenance(ctx, &apimodels.EmbeddedContactPoint{UID: integration.UID}, orgID, provenance); err != nil {
+			return 0, fmt.Errorf("contact point '%s': %w", oldName, err)
+		}
+	}
+	tree := revision.cfg.AlertmanagerConfig.Route
+	if tree != nil && isContactPointInUse(oldName, []*apimodels.Route{tree}) {
+		if err := ecp.policy.checkWritable(ResourceTypeNotificationPolicies, provenance); err != nil {
+			return 0, err
+		}
+		if err := ecp.checkProvenance(ctx, tree, orgID, provenance); err != nil {
+			return 0, fmt.Errorf("notification policy tree: %w", err)
+		}
+	}
+	ruleUpdates, err := ecp.renameRuleReceivers(ctx, orgID, oldName, newName, provenance)
+	if err != nil {
+		return 0, err
+	}
+
+	renamed.Name = newName
+	for _, integration := range renamed.GrafanaManagedReceivers {
+		integration.Name = newName
+	}
+	updated := len(ruleUpdates)
+	if tree != nil {
+		updated += renameRouteReceivers(tree, oldName, newName)
+	}
+
+	data, err := json.Marshal(revision.cfg)
+	if err != nil {
+		return 0, err
+	}
+	err = ecp.xact.InTransaction(ctx, func(ctx context.Context) error {
+		err := ecp.amStore.UpdateAlertmanagerConfiguration(ctx, &models.SaveAlertmanagerConfigurationCmd{
+			AlertmanagerConfiguration: string(data),
+			FetchedConfigurationHash:  revision.concurrencyToken,
+			ConfigurationVersion:      revision.version,
+			Default:                   false,
+			OrgID:                     orgID,
+		})
+		if err != nil {
+			return err
+		}
+		if len(ruleUpdates) == 0 {
+			return nil
+		}
+		return ecp.ruleStore.UpdateAlertRules(ctx, ruleUpdates)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return updated, nil
+}
+
+// renameRuleReceivers returns the updates of the alert rules whose notification settings reference
+// the contact point. It fails if any of the rules cannot be changed with the provenance.
+func (ecp *ContactPointService) renameRuleReceivers(ctx context.Context, orgID int64, oldName, newName string, provenance models.Provenance) ([]store.UpdateRule, error) {
+	query := &models.ListAlertRulesQuery{OrgID: orgID}
+	if err := ecp.ruleStore.ListAlertRules(ctx, query); err != nil {
+		return nil, err
+	}
+	var updates []store.UpdateRule
+	for _, rule := range query.Result {
+		if !referencesReceiver(rule.NotificationSettings, oldName) {
+			continue
+		}
+		if err := ecp.policy.checkWritable(ResourceTypeAlertRules, provenance); err != nil {
+			return nil, err
+		}
+		if err := ecp.checkProvenance(ctx, rule, orgID, provenance); err != nil {
+			return nil, fmt.Errorf("alert rule '%s': %w", rule.UID, err)
+		}
+		newRule := *rule
+		newRule.NotificationSettings = make([]models.NotificationSettings, 0, len(rule.NotificationSettings))
+		for _, settings := range rule.NotificationSettings {
+			if settings.ReceiverName == oldName {
+				settings.ReceiverName = newName
+			}
+			newRule.NotificationSettings = append(newRule.NotificationSettings, settings)
+		}
+		updates = append(updates, store.UpdateRule{Existing: rule, New: newRule})
+	}
+	return updates, nil
+}
+
+// checkProvenance returns ErrProvenanceMismatch if the stored provenance of the object does not
+// allow changing it with the provenance.
+func (ecp *ContactPointService) checkProvenance(ctx context.Context, o models.Provisionable, orgID int64, provenance models.Provenance) error {
+	storedProvenance, err := ecp.provenanceStore.GetProvenance(ctx, o, orgID)
+	if err != nil {
+		return err
+	}
+	if storedProvenance != provenance && storedProvenance != models.ProvenanceNone {
+		return fmt.Errorf("%w: cannot change with provenance '%s', needs '%s'", ErrProvenanceMismatch, provenance, storedProvenance)
+	}
+	return nil
+}
+
+func referencesReceiver(settings []models.NotificationSettings, name string) bool {
+	for _, s := range settings {
+		if s.ReceiverName == name {
+			return true
+		}
+	}
+	return false
+}
+
+// renameRouteReceivers replaces the receiver of the route and all its child routes and returns the
+// number of replaced references.
+func renameRouteReceivers(route *apimodels.Route, oldName, newName string) int {
+	updated := 0
+	if route.Receiver == oldName {
+		route.Receiver = newName
+		updated++
+	}
+	for _, child := range route.Routes {
+		updated += renameRouteReceivers(child, oldName, newName)
+	}
+	return updated
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"sort"
+	"strings"
 
 	"github.com/grafana/grafana/pkg/infra/log"
 	apimodels "github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
@@ -21,21 +22,66 @@ type ContactPointService struct {
 	encryptionService secrets.Service
 	provenanceStore   ProvisioningStore
 	xact              TransactionManager
+	ruleStore         store.RuleStore
+	policy            ResourcePolicy
 	log               log.Logger
 }
 
 func NewContactPointService(store store.AlertingStore, encryptionService secrets.Service,
-	provenanceStore ProvisioningStore, xact TransactionManager, log log.Logger) *ContactPointService {
+	provenanceStore ProvisioningStore, xact TransactionManager, ruleStore store.RuleStore, policy ResourcePolicy, log log.Logger) *ContactPointService {
 	return &ContactPointService{
 		amStore:           store,
 		encryptionService: encryptionService,
 		provenanceStore:   provenanceStore,
 		xact:              xact,
+		ruleStore:         ruleStore,
+		policy:            policy,
 		log:               log,
 	}
 }
 
+// ContactPointFilter selects the contact points returned by ListContactPoints.
+// Empty fields match all contact points.
+type ContactPointFilter struct {
+	// Name matches contact points with exactly this name.
+	Name string
+	// NameContains matches contact points whose name contains it, ignoring case.
+	NameContains string
+	// Types matches contact points that use one of these integrations, e.g. slack or email.
+	Types []string
+	// ProvisionedOnly matches contact points with a provenance other than none.
+	ProvisionedOnly bool
+}
+
+func (f ContactPointFilter) matches(contactPoint *apimodels.PostableGrafanaReceiver, provenance models.Provenance) bool {
+	if f.Name != "" && contactPoint.Name != f.Name {
+		return false
+	}
+	if f.NameContains != "" && !strings.Contains(strings.ToLower(contactPoint.Name), strings.ToLower(f.NameContains)) {
+		return false
+	}
+	if len(f.Types) > 0 {
+		found := false
+		for _, t := range f.Types {
+			if contactPoint.Type == t {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return !f.ProvisionedOnly || provenance != models.ProvenanceNone
+}
+
 func (ecp *ContactPointService) GetContactPoints(ctx context.Context, orgID int64) ([]apimodels.EmbeddedContactPoint, error) {
+	return ecp.ListContactPoints(ctx, orgID, ContactPointFilter{})
+}
+
+// ListContactPoints returns the contact points of the organization that match the filter, sorted by name.
+// Contact points are filtered before their secure settings are decrypted.
+func (ecp *ContactPointService) ListContactPoints(ctx context.Context, orgID int64, filter ContactPointFilter) ([]apimodels.EmbeddedContactPoint, error) {
 	revision, err := getLastConfiguration(ctx, orgID, ecp.amStore)
 	if err != nil {
 		return nil, err
@@ -46,6 +92,9 @@ func (ecp *ContactPointService) GetContactPoints(ctx context.Context, orgID int6
 	}
 	contactPoints := []apimodels.EmbeddedContactPoint{}
 	for _, contactPoint := range revision.cfg.GetGrafanaReceiverMap() {
+		if !filter.matches(contactPoint, provenances[contactPoint.UID]) {
+			continue
+		}
 		embeddedContactPoint := apimodels.EmbeddedContactPoint{
 			UID:                   contactPoint.UID,
 			Type:                  contactPoint.Type,
@@ -75,6 +124,20 @@ func (ecp *ContactPointService) GetContactPoints(ctx context.Context, orgID int6
 	return contactPoints, nil
 }
 
+// Exists returns true if the organization has a contact point with the given name.
+func (ecp *ContactPointService) Exists(ctx context.Context, orgID int64, name string) (bool, error) {
+	revision, err := getLastConfiguration(ctx, orgID, ecp.amStore)
+	if err != nil {
+		return false, err
+	}
+	for _, receiver := range revision.cfg.AlertmanagerConfig.Receivers {
+		if receiver.Name == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 // internal only
 func (ecp *ContactPointService) getContactPointDecrypted(ctx context.Context, orgID int64, uid string) (apimodels.EmbeddedContactPoint, error) {
 	revision, err := getLastConfiguration(ctx, orgID, ecp.amStore)
@@ -110,6 +173,9 @@ func (ecp *ContactPointService) getContactPointDecrypted(ctx context.Context, or
 
 func (ecp *ContactPointService) CreateContactPoint(ctx context.Context, orgID int64,
 	contactPoint apimodels.EmbeddedContactPoint, provenance models.Provenance) (apimodels.EmbeddedContactPoint, error) {
+	if err := ecp.policy.checkMutation(orgID, ResourceTypeContactPoints, provenance); err != nil {
+		return apimodels.EmbeddedContactPoint{}, err
+	}
 	if err := contactPoint.Valid(ecp.encryptionService.GetDecryptedValue); err != nil {
 		return apimodels.EmbeddedContactPoint{}, fmt.Errorf("contact point is not valid: %w", err)
 	}
@@ -196,6 +262,9 @@ func (ecp *ContactPointService) CreateContactPoint(ctx context.Context, orgID in
 }
 
 func (ecp *ContactPointService) UpdateContactPoint(ctx context.Context, orgID int64, contactPoint apimodels.EmbeddedContactPoint, provenance models.Provenance) error {
+	if err := ecp.policy.checkMutation(orgID, ResourceTypeContactPoints, provenance); err != nil {
+		return err
+	}
 	// set all redacted values with the latest known value from the store
 	rawContactPoint, err := ecp.getContactPointDecrypted(ctx, orgID, contactPoint.UID)
 	if err != nil {
@@ -288,6 +357,9 @@ func (ecp *ContactPointService) UpdateContactPoint(ctx context.Context, orgID in
 }
 
 func (ecp *ContactPointService) DeleteContactPoint(ctx context.Context, orgID int64, uid string) error {
+	if err := ecp.policy.checkMutation(orgID, ResourceTypeContactPoints, models.ProvenanceNone); err != nil {
+		return err
+	}
 	revision, err := getLastConfiguration(ctx, orgID, ecp.amStore)
 	if err != nil {
 		return err
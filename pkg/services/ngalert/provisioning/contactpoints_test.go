@@ -3,6 +3,7 @@ package provisioning
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/grafana/grafana/pkg/components/simplejson"
 	"github.com/grafana/grafana/pkg/infra/log"
@@ -157,6 +158,81 @@ func TestContactPointService(t *testing.T) {
 		intercepted := fake.lastSaveCommand
 		require.Equal(t, expectedConcurrencyToken, intercepted.FetchedConfigurationHash)
 	})
+
+	t.Run("service reports whether a contact point exists", func(t *testing.T) {
+		sut := createContactPointServiceSut(secretsService)
+
+		exists, err := sut.Exists(context.Background(), 1, "grafana-default-email")
+		require.NoError(t, err)
+		require.True(t, exists)
+		exists, err = sut.Exists(context.Background(), 1, "unknown receiver")
+		require.NoError(t, err)
+		require.False(t, exists)
+
+		sut.amStore.(*fakeAMConfigStore).config.AlertmanagerConfiguration = "{"
+		_, err = sut.Exists(context.Background(), 1, "grafana-default-email")
+		require.Error(t, err)
+	})
+}
+
+func TestContactPointServiceListContactPoints(t *testing.T) {
+	sqlStore := sqlstore.InitTestDB(t)
+	secretsService := &countingSecretsService{Service: manager.SetupTestService(t, database.ProvideSecretsStore(sqlStore))}
+	sut := createContactPointServiceSut(secretsService)
+	slack := createTestContactPoint()
+	slack.Name = "Team Payments"
+	_, err := sut.CreateContactPoint(context.Background(), 1, slack, models.ProvenanceAPI)
+	require.NoError(t, err)
+	webhook := createTestContactPoint()
+	webhook.Name = "payments webhook"
+	webhook.Type = "webhook"
+	webhook.Settings = simplejson.NewFromAny(map[string]interface{}{"url": "http://localhost"})
+	_, err = sut.CreateContactPoint(context.Background(), 1, webhook, models.ProvenanceNone)
+	require.NoError(t, err)
+
+	names := func(t *testing.T, filter ContactPointFilter) []string {
+		t.Helper()
+		cps, err := sut.ListContactPoints(context.Background(), 1, filter)
+		require.NoError(t, err)
+		result := make([]string, 0, len(cps))
+		for _, cp := range cps {
+			result = append(result, cp.Name)
+		}
+		return result
+	}
+
+	t.Run("should return all contact points without filter", func(t *testing.T) {
+		require.Equal(t, []string{"Team Payments", "email receiver", "payments webhook"}, names(t, ContactPointFilter{}))
+	})
+	t.Run("should filter by exact name", func(t *testing.T) {
+		require.Equal(t, []string{"Team Payments"}, names(t, ContactPointFilter{Name: "Team Payments"}))
+		require.Empty(t, names(t, ContactPointFilter{Name: "team payments"}))
+	})
+	t.Run("should filter by a part of the name ignoring case", func(t *testing.T) {
+		require.Equal(t, []string{"Team Payments", "payments webhook"}, names(t, ContactPointFilter{NameContains: "PAYMENTS"}))
+	})
+	t.Run("should filter by integration type", func(t *testing.T) {
+		require.Equal(t, []string{"Team Payments", "email receiver"}, names(t, ContactPointFilter{Types: []string{"slack", "email"}}))
+	})
+	t.Run("should filter provisioned contact points", func(t *testing.T) {
+		require.Equal(t, []string{"Team Payments"}, names(t, ContactPointFilter{ProvisionedOnly: true}))
+	})
+	t.Run("should not decrypt the secure settings of filtered contact points", func(t *testing.T) {
+		secretsService.decrypted = 0
+		names(t, ContactPointFilter{Types: []string{"email"}})
+		require.Zero(t, secretsService.decrypted)
+	})
+}
+
+// countingSecretsService counts the values it decrypts.
+type countingSecretsService struct {
+	secrets.Service
+	decrypted int
+}
+
+func (s *countingSecretsService) Decrypt(ctx context.Context, payload []byte) ([]byte, error) {
+	s.decrypted++
+	return s.Service.Decrypt(ctx, payload)
 }
 
 func TestContactPointInUse(t *testing.T) {
@@ -190,6 +266,92 @@ func TestContactPointInUse(t *testing.T) {
 	require.False(t, result)
 }
 
+func TestContactPointServiceRenameContactPoint(t *testing.T) {
+	sqlStore := sqlstore.InitTestDB(t)
+	secretsService := manager.SetupTestService(t, database.ProvideSecretsStore(sqlStore))
+	// The default configuration routes all alerts to grafana-default-email with the root route and its child route.
+	setup := func(t *testing.T) (*ContactPointService, AlertRuleService) {
+		ruleService := createAlertRuleService(t)
+		sut := createContactPointServiceSut(secretsService)
+		sut.ruleStore = ruleService.ruleStore
+		sut.provenanceStore = ruleService.provenanceStore
+		return sut, ruleService
+	}
+	createRule := func(t *testing.T, ruleService AlertRuleService, title, receiver string, provenance models.Provenance) models.AlertRule {
+		t.Helper()
+		rule := dummyRule(title, 1)
+		rule.Data[0].RelativeTimeRange.From = models.Duration(10 * time.Minute)
+		rule.NotificationSettings = []models.NotificationSettings{{ReceiverName: receiver}}
+		rule, err := ruleService.CreateAlertRule(context.Background(), rule, provenance)
+		require.NoError(t, err)
+		return rule
+	}
+	ruleReceiver := func(t *testing.T, ruleService AlertRuleService, uid string) string {
+		t.Helper()
+		rule, _, err := ruleService.GetAlertRule(context.Background(), 1, uid)
+		require.NoError(t, err)
+		return rule.NotificationSettings[0].ReceiverName
+	}
+
+	t.Run("should rename the contact point and all its references", func(t *testing.T) {
+		sut, ruleService := setup(t)
+		referencing := createRule(t, ruleService, "referencing", "grafana-default-email", models.ProvenanceNone)
+		other := createRule(t, ruleService, "other", "a new receiver", models.ProvenanceNone)
+
+		updated, err := sut.RenameContactPoint(context.Background(), 1, "grafana-default-email", "renamed", models.ProvenanceAPI)
+		require.NoError(t, err)
+		require.Equal(t, 3, updated)
+
+		revision, err := getLastConfiguration(context.Background(), 1, sut.amStore)
+		require.NoError(t, err)
+		names := make([]string, 0, len(revision.cfg.AlertmanagerConfig.Receivers))
+		for _, receiver := range revision.cfg.AlertmanagerConfig.Receivers {
+			names = append(names, receiver.Name)
+		}
+		require.Equal(t, []string{"renamed", "a new receiver"}, names)
+		for _, integration := range revision.cfg.AlertmanagerConfig.Receivers[0].GrafanaManagedReceivers {
+			require.Equal(t, "renamed", integration.Name)
+		}
+		require.Equal(t, "renamed", revision.cfg.AlertmanagerConfig.Route.Receiver)
+		require.Equal(t, "renamed", revision.cfg.AlertmanagerConfig.Route.Routes[0].Receiver)
+		require.Equal(t, "renamed", ruleReceiver(t, ruleService, referencing.UID))
+		require.Equal(t, "a new receiver", ruleReceiver(t, ruleService, other.UID))
+	})
+
+	t.Run("should refuse to rename to an existing name", func(t *testing.T) {
+		sut, _ := setup(t)
+		_, err := sut.RenameContactPoint(context.Background(), 1, "grafana-default-email", "a new receiver", models.ProvenanceAPI)
+		require.ErrorIs(t, err, ErrValidation)
+	})
+
+	t.Run("should fail if the contact point does not exist", func(t *testing.T) {
+		sut, _ := setup(t)
+		_, err := sut.RenameContactPoint(context.Background(), 1, "unknown", "renamed", models.ProvenanceAPI)
+		require.ErrorIs(t, err, ErrContactPointNotFound)
+	})
+
+	t.Run("should not rename anything if a referencing rule has another provenance", func(t *testing.T) {
+		sut, ruleService := setup(t)
+		rule := createRule(t, ruleService, "provisioned", "grafana-default-email", models.ProvenanceFile)
+
+		_, err := sut.RenameContactPoint(context.Background(), 1, "grafana-default-email", "renamed", models.ProvenanceAPI)
+		require.ErrorIs(t, err, ErrProvenanceMismatch)
+
+		revision, err := getLastConfiguration(context.Background(), 1, sut.amStore)
+		require.NoError(t, err)
+		require.Equal(t, "grafana-default-email", revision.cfg.AlertmanagerConfig.Receivers[0].Name)
+		require.Equal(t, "grafana-default-email", ruleReceiver(t, ruleService, rule.UID))
+	})
+
+	t.Run("should not rename anything if the referencing policy tree has another provenance", func(t *testing.T) {
+		sut, _ := setup(t)
+		require.NoError(t, sut.provenanceStore.SetProvenance(context.Background(), &definitions.Route{}, 1, models.ProvenanceFile))
+
+		_, err := sut.RenameContactPoint(context.Background(), 1, "grafana-default-email", "renamed", models.ProvenanceAPI)
+		require.ErrorIs(t, err, ErrProvenanceMismatch)
+	})
+}
+
 func createContactPointServiceSut(secretService secrets.Service) *ContactPointService {
 	return &ContactPointService{
 		amStore:           newFakeAMConfigStore(),
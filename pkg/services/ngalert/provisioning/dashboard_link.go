@@ -0,0 +1,76 @@
+package provisioning
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	models2 "github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+// DashboardProvider looks up the dashboards that alert rules are linked to.
+type DashboardProvider interface {
+	GetDashboard(ctx context.Context, query *models2.GetDashboardQuery) error
+}
+
+// validateDashboardLink checks that a rule is either linked to a panel of an existing
+// dashboard or not linked at all.
+func (service *AlertRuleService) validateDashboardLink(ctx context.Context, rule models.AlertRule) error {
+	if rule.DashboardUID == nil && rule.PanelID == nil {
+		return nil
+	}
+	if rule.DashboardUID == nil || rule.PanelID == nil {
+		return fmt.Errorf("%w: dashboardUID and panelID must be set together", ErrValidation)
+	}
+	if *rule.PanelID <= 0 {
+		return fmt.Errorf("%w: panelID must be positive, got %d", ErrValidation, *rule.PanelID)
+	}
+	if service.dashboards == nil {
+		return nil
+	}
+	query := &models2.GetDashboardQuery{
+		OrgId: rule.OrgID,
+		Uid:   *rule.DashboardUID,
+	}
+	if err := service.dashboards.GetDashboard(ctx, query); err != nil {
+		if errors.Is(err, models2.ErrDashboardNotFound) {
+			return fmt.Errorf("%w: dashboard '%s' not found", ErrValidation, *rule.DashboardUID)
+		}
+		return err
+	}
+	return nil
+}
+
+// withDashboardAnnotations returns the annotations of the rule with the reserved annotations
+// that link it to a panel set from its dashboard UID and panel ID. The map of the rule is not modified.
+func withDashboardAnnotations(rule models.AlertRule) map[string]string {
+	if rule.DashboardUID == nil || rule.PanelID == nil {
+		return rule.Annotations
+	}
+	result := make(map[string]string, len(rule.Annotations)+2)
+	for k, v := range rule.Annotations {
+		result[k] = v
+	}
+	result[models.DashboardUIDAnnotation] = *rule.DashboardUID
+	result[models.PanelIDAnnotation] = strconv.FormatInt(*rule.PanelID, 10)
+	return result
+}
+
+// withoutDashboardAnnotations returns the annotations without the reserved annotations that link a
+// rule to a panel. The map is not modified.
+func withoutDashboardAnnotations(annotations map[string]string) map[string]string {
+	_, hasDashboard := annotations[models.DashboardUIDAnnotation]
+	_, hasPanel := annotations[models.PanelIDAnnotation]
+	if !hasDashboard && !hasPanel {
+		return annotations
+	}
+	result := make(map[string]string, len(annotations))
+	for k, v := range annotations {
+		result[k] = v
+	}
+	delete(result, models.DashboardUIDAnnotation)
+	delete(result, models.PanelIDAnnotation)
+	return result
+}
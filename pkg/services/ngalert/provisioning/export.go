@@ -0,0 +1,410 @@
+package provisioning
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/alertmanager/pkg/labels"
+	"github.com/prometheus/common/model"
+	"gopkg.in/yaml.v3"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/store"
+)
+
+const exportIndexName = "index"
+
+var unsafeFileNameChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// ExportFormat is the file format of exported alert rules. Both formats hold the same file
+// provisioning format, so that the rules of a JSON export re-import like the rules of a YAML export.
+type ExportFormat string
+
+const (
+	ExportFormatYAML ExportFormat = "yaml"
+	ExportFormatJSON ExportFormat = "json"
+)
+
+// ParseExportFormat returns the export format of the name, e.g. "json". An empty name is YAML.
+func ParseExportFormat(name string) (ExportFormat, error) {
+	format := ExportFormat(strings.ToLower(strings.TrimSpace(name)))
+	if err := format.validate(); err != nil {
+		return "", err
+	}
+	return format.orDefault(), nil
+}
+
+// orDefault returns YAML for the zero value, so that exports are YAML unless JSON is asked for.
+func (f ExportFormat) orDefault() ExportFormat {
+	if f == "" {
+		return ExportFormatYAML
+	}
+	return f
+}
+
+func (f ExportFormat) validate() error {
+	switch f.orDefault() {
+	case ExportFormatYAML, ExportFormatJSON:
+		return nil
+	default:
+		return fmt.Errorf("%w: unsupported export format '%s', must be one of: %s, %s", ErrValidation, f, ExportFormatYAML, ExportFormatJSON)
+	}
+}
+
+// fileExtension returns the extension of the exported files, including the dot.
+func (f ExportFormat) fileExtension() string {
+	return "." + string(f.orDefault())
+}
+
+// encode writes the content to w in the format.
+func (f ExportFormat) encode(w io.Writer, content interface{}) error {
+	switch f.orDefault() {
+	case ExportFormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(content)
+	case ExportFormatYAML:
+		enc := yaml.NewEncoder(w)
+		if err := enc.Encode(content); err != nil {
+			return err
+		}
+		return enc.Close()
+	default:
+		return f.validate()
+	}
+}
+
+// marshal returns the content in the format.
+func (f ExportFormat) marshal(content interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := f.encode(&buf, content); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ExportOptions controls which rules are exported.
+type ExportOptions struct {
+	// FolderUIDs limits the export to the given folders. All folders are exported if it is empty.
+	FolderUIDs []string
+	// Format is the format of the files of the export. The zero value is YAML.
+	Format ExportFormat
+}
+
+// ExportAllRuleGroups writes a zip archive with all rule groups of the organization to w.
+// Every folder is a directory that contains one file per rule group, and the index file, e.g.
+// index.yaml, lists all groups of the archive. Rule groups are loaded and written one
+// at a time, so the archive is never held in memory as a whole.
+func (service *AlertRuleService) ExportAllRuleGroups(ctx context.Context, orgID int64, opts ExportOptions, w io.Writer) (err error) {
+	defer wrapServiceError(&err)
+	return service.exportAllRuleGroups(ctx, orgID, opts, w, nil)
+}
+
+// exportAllRuleGroups writes the archive of ExportAllRuleGroups. If progress is not nil, it is
+// called with the number of written and of all rule groups after every group, and the export
+// stops with its error.
+func (service *AlertRuleService) exportAllRuleGroups(ctx context.Context, orgID int64, opts ExportOptions, w io.Writer, progress func(processed, total int) error) error {
+	if err := opts.Format.validate(); err != nil {
+		return err
+	}
+	query := &models.ListOrgRuleGroupsQuery{
+		OrgID:         orgID,
+		NamespaceUIDs: opts.FolderUIDs,
+	}
+	if err := service.ruleStore.ListOrgRuleGroups(ctx, query); err != nil {
+		return err
+	}
+	scope, err := service.callerNamespaces(ctx)
+	if err != nil {
+		return err
+	}
+	groups := make([][]string, 0, len(query.Result))
+	for _, group := range query.Result {
+		if scope.allows(group[1]) {
+			groups = append(groups, group)
+		}
+	}
+	query.Result = groups
+	fileNames := ruleGroupFileNames(query.Result, opts.Format.fileExtension())
+
+	archive := zip.NewWriter(w)
+	index := definitions.ExportIndex{Groups: make([]definitions.ExportIndexEntry, 0, len(query.Result))}
+	for _, group := range query.Result {
+		ruleGroup, folderUID := group[0], group[1]
+		rulesQuery := &models.ListAlertRulesQuery{
+			OrgID:         orgID,
+			NamespaceUIDs: []string{folderUID},
+			RuleGroup:     ruleGroup,
+		}
+		if err := service.ruleStore.ListAlertRules(ctx, rulesQuery); err != nil {
+			return err
+		}
+		export, err := newAlertRuleGroupExport(orgID, folderUID, ruleGroup, rulesQuery.Result)
+		if err != nil {
+			return err
+		}
+		filePath := path.Join(sanitizeFileName(folderUID), fileNames[folderUID][ruleGroup])
+		if err := writeExportFile(archive, filePath, opts.Format, export); err != nil {
+			return err
+		}
+		index.Groups = append(index.Groups, definitions.ExportIndexEntry{
+			Folder: folderUID,
+			Group:  ruleGroup,
+			Path:   filePath,
+			Rules:  len(export.Rules),
+		})
+		if progress != nil {
+			if err := progress(len(index.Groups), len(query.Result)); err != nil {
+				return err
+			}
+		}
+	}
+	if err := writeExportFile(archive, exportIndexName+opts.Format.fileExtension(), opts.Format, index); err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+// LabelSelector selects the alert rules whose labels match all of its matchers, e.g. team="payments".
+type LabelSelector labels.Matchers
+
+// ParseLabelSelector parses a selector in the matcher syntax of the Alertmanager, e.g. {team="payments",env=~"prod|staging"}.
+func ParseLabelSelector(s string) (LabelSelector, error) {
+	matchers, err := labels.ParseMatchers(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid label selector: %s", ErrValidation, err)
+	}
+	return matchers, nil
+}
+
+// Matches returns true if the labels match all matchers of the selector. An empty selector matches all labels.
+func (s LabelSelector) Matches(ruleLabels map[string]string) bool {
+	set := make(model.LabelSet, len(ruleLabels))
+	for name, value := range ruleLabels {
+		set[model.LabelName(name)] = model.LabelValue(value)
+	}
+	return labels.Matchers(s).Matches(set)
+}
+
+// ExportRulesByLabel returns the rules of the organization whose labels match the selector in the
+// file provisioning format. The rules are grouped by folder and rule group, and groups without
+// matching rules are left out.
+func (service *AlertRuleService) ExportRulesByLabel(ctx context.Context, orgID int64, selector LabelSelector, format ExportFormat) (_ []byte, err error) {
+	defer wrapServiceError(&err)
+	file, err := service.exportRules(ctx, &models.ListAlertRulesQuery{OrgID: orgID}, selector, format)
+	if err != nil {
+		return nil, err
+	}
+	return format.marshal(file)
+}
+
+// ExportOrgRules returns all rules of the organization in the file provisioning format.
+func (service *AlertRuleService) ExportOrgRules(ctx context.Context, orgID int64, format ExportFormat) (_ []byte, err error) {
+	defer wrapServiceError(&err)
+	file, err := service.exportRules(ctx, &models.ListAlertRulesQuery{OrgID: orgID}, nil, format)
+	if err != nil {
+		return nil, err
+	}
+	return format.marshal(file)
+}
+
+// ExportRuleGroup returns the rules of the rule group in the file provisioning format. It returns
+// store.ErrAlertRuleGroupNotFound if the group has no rules.
+func (service *AlertRuleService) ExportRuleGroup(ctx context.Context, orgID int64, namespaceUID, group string, format ExportFormat) (_ []byte, err error) {
+	defer wrapServiceError(&err)
+	if err := service.checkNamespaces(ctx, namespaceUID); err != nil {
+		return nil, err
+	}
+	file, err := service.exportRules(ctx, &models.ListAlertRulesQuery{
+		OrgID:         orgID,
+		NamespaceUIDs: []string{namespaceUID},
+		RuleGroup:     group,
+	}, nil, format)
+	if err != nil {
+		return nil, err
+	}
+	if len(file.Groups) == 0 {
+		return nil, store.ErrAlertRuleGroupNotFound
+	}
+	return format.marshal(file)
+}
+
+// exportRules returns the rules of the query that the caller can read and whose labels match the
+// selector. It is the representation that all formats of the export are written from.
+func (service *AlertRuleService) exportRules(ctx context.Context, query *models.ListAlertRulesQuery, selector LabelSelector, format ExportFormat) (definitions.AlertingFileExport, error) {
+	if err := format.validate(); err != nil {
+		return definitions.AlertingFileExport{}, err
+	}
+	ctx, cancel := withTimeout(ctx, service.cfg.OperationTimeouts.List)
+	defer cancel()
+	scope, err := service.callerNamespaces(ctx)
+	if err != nil {
+		return definitions.AlertingFileExport{}, err
+	}
+	if scope.restrict(query) {
+		if err := service.ruleStore.ListAlertRules(ctx, query); err != nil {
+			return definitions.AlertingFileExport{}, err
+		}
+	}
+	matching := make([]*models.AlertRule, 0, len(query.Result))
+	for _, rule := range query.Result {
+		if selector.Matches(rule.Labels) {
+			matching = append(matching, rule)
+		}
+	}
+	return NewAlertingFileExport(query.OrgID, matching)
+}
+
+// NewAlertingFileExport returns the rules in the file provisioning format, grouped by folder and
+// rule group and sorted by folder, rule group and the index of the rules within their group.
+func NewAlertingFileExport(orgID int64, rules []*models.AlertRule) (definitions.AlertingFileExport, error) {
+	type groupKey struct {
+		folderUID string
+		ruleGroup string
+	}
+	groups := make(map[groupKey][]*models.AlertRule)
+	var keys []groupKey
+	for _, rule := range rules {
+		key := groupKey{folderUID: rule.NamespaceUID, ruleGroup: rule.RuleGroup}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], rule)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].folderUID != keys[j].folderUID {
+			return keys[i].folderUID < keys[j].folderUID
+		}
+		return keys[i].ruleGroup < keys[j].ruleGroup
+	})
+	file := definitions.AlertingFileExport{APIVersion: 1, Groups: make([]definitions.AlertRuleGroupExport, 0, len(keys))}
+	for _, key := range keys {
+		rules := groups[key]
+		models.SortAlertRulesByGroupIndex(rules)
+		export, err := newAlertRuleGroupExport(orgID, key.folderUID, key.ruleGroup, rules)
+		if err != nil {
+			return definitions.AlertingFileExport{}, err
+		}
+		file.Groups = append(file.Groups, export)
+	}
+	return file, nil
+}
+
+func writeExportFile(archive *zip.Writer, name string, format ExportFormat, content interface{}) error {
+	f, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := format.encode(f, content); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+// ruleGroupFileNames returns the file name of every rule group by folder UID and group name, with
+// the extension of the format of the export. Groups of the same folder whose names sanitize to the same file name get a hash of their
+// name appended, so that no file is overwritten.
+func ruleGroupFileNames(groups [][]string, extension string) map[string]map[string]string {
+	counts := make(map[string]int, len(groups))
+	for _, group := range groups {
+		counts[group[1]+"/"+sanitizeFileName(group[0])]++
+	}
+	result := make(map[string]map[string]string)
+	for _, group := range groups {
+		ruleGroup, folderUID := group[0], group[1]
+		name := sanitizeFileName(ruleGroup)
+		if counts[folderUID+"/"+name] > 1 {
+			sum := sha256.Sum256([]byte(ruleGroup))
+			name = name + "-" + hex.EncodeToString(sum[:])[:8]
+		}
+		if _, ok := result[folderUID]; !ok {
+			result[folderUID] = make(map[string]string)
+		}
+		result[folderUID][ruleGroup] = name + extension
+	}
+	return result
+}
+
+// sanitizeFileName replaces all characters that are not safe in file names.
+func sanitizeFileName(name string) string {
+	name = strings.Trim(unsafeFileNameChars.ReplaceAllString(name, "_"), "._")
+	if name == "" {
+		return "_"
+	}
+	return name
+}
+
+func newAlertRuleGroupExport(orgID int64, folderUID, ruleGroup string, rules []*models.AlertRule) (definitions.AlertRuleGroupExport, error) {
+	export := definitions.AlertRuleGroupExport{
+		OrgID:  orgID,
+		Name:   ruleGroup,
+		Folder: folderUID,
+		Rules:  make([]definitions.AlertRuleExport, 0, len(rules)),
+	}
+	for _, rule := range rules {
+		export.Interval = definitions.ExportDuration(time.Duration(rule.IntervalSeconds) * time.Second)
+		export.Labels = rule.GroupLabels
+		ruleExport, err := newAlertRuleExport(*rule)
+		if err != nil {
+			return definitions.AlertRuleGroupExport{}, err
+		}
+		export.Rules = append(export.Rules, ruleExport)
+	}
+	return export, nil
+}
+
+func newAlertRuleExport(rule models.AlertRule) (definitions.AlertRuleExport, error) {
+	data := make([]definitions.AlertQueryExport, 0, len(rule.Data))
+	for _, query := range rule.Data {
+		var queryModel map[string]interface{}
+		if err := json.Unmarshal(query.Model, &queryModel); err != nil {
+			return definitions.AlertRuleExport{}, fmt.Errorf("failed to export query %s of alert rule %s: %w", query.RefID, rule.UID, err)
+		}
+		data = append(data, definitions.AlertQueryExport{
+			RefID:     query.RefID,
+			QueryType: query.QueryType,
+			RelativeTimeRange: definitions.RelativeTimeRangeExport{
+				From: definitions.ExportDuration(query.RelativeTimeRange.From),
+				To:   definitions.ExportDuration(query.RelativeTimeRange.To),
+			},
+			DatasourceUID: query.DatasourceUID,
+			Model:         queryModel,
+		})
+	}
+	export := definitions.AlertRuleExport{
+		UID:          rule.UID,
+		Title:        rule.Title,
+		Condition:    rule.Condition,
+		Data:         data,
+		DashboardUID: rule.DashboardUID,
+		PanelID:      rule.PanelID,
+		NoDataState:  rule.NoDataState,
+		ExecErrState: rule.ExecErrState,
+		For:          definitions.ExportDuration(rule.For),
+		Annotations:  rule.Annotations,
+		Labels:       rule.Labels,
+		ActiveWindow: rule.ActiveWindow,
+	}
+	if settings := rule.GetNotificationSettings(); settings != nil {
+		export.NotificationSettings = &definitions.AlertRuleNotificationExport{
+			Receiver:       settings.ReceiverName,
+			GroupBy:        settings.GroupBy,
+			GroupWait:      settings.GroupWait,
+			GroupInterval:  settings.GroupInterval,
+			RepeatInterval: settings.RepeatInterval,
+		}
+	}
+	return export, nil
+}
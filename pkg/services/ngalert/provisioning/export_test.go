@@ -0,0 +1,328 @@
+package provisioning
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/prometheus/alertmanager/timeinterval"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/store"
+)
+
+func TestExportAllRuleGroups(t *testing.T) {
+	var orgID int64 = 1
+	service := createAlertRuleService(t)
+	groupWait := model.Duration(time.Minute)
+	for _, r := range []struct {
+		title  string
+		folder string
+		group  string
+	}{
+		{title: "rule-1", folder: "folder-a", group: "group"},
+		{title: "rule-2", folder: "folder-a", group: "group"},
+		{title: "rule-3", folder: "folder-a", group: "my group"},
+		{title: "rule-4", folder: "folder-a", group: "my/group"},
+		{title: "rule-5", folder: "folder-b", group: "group"},
+	} {
+		rule := dummyRule(r.title, orgID)
+		rule.NamespaceUID = r.folder
+		rule.NotificationSettings = []models.NotificationSettings{{ReceiverName: "receiver", GroupBy: []string{"team"}, GroupWait: &groupWait}}
+		rule.RuleGroup = r.group
+		_, err := service.CreateAlertRule(context.Background(), rule, models.ProvenanceNone)
+		require.NoError(t, err)
+	}
+	otherOrgRule := dummyRule("rule-6", 2)
+	otherOrgRule.NamespaceUID = "folder-c"
+	_, err := service.CreateAlertRule(context.Background(), otherOrgRule, models.ProvenanceNone)
+	require.NoError(t, err)
+
+	t.Run("should write a file per group and an index", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := service.ExportAllRuleGroups(context.Background(), orgID, ExportOptions{}, &buf)
+		require.NoError(t, err)
+
+		files := readZip(t, buf.Bytes())
+		var index definitions.ExportIndex
+		require.NoError(t, yaml.Unmarshal(files["index.yaml"], &index))
+		require.Len(t, index.Groups, 4)
+		require.Len(t, files, 5)
+
+		paths := make(map[string]definitions.ExportIndexEntry)
+		for _, entry := range index.Groups {
+			require.Contains(t, files, entry.Path)
+			paths[entry.Path] = entry
+		}
+		require.Equal(t, 2, paths["folder-a/group.yaml"].Rules)
+		require.Equal(t, 1, paths["folder-b/group.yaml"].Rules)
+		require.NotContains(t, files, "folder-a/my_group.yaml", "colliding file names should be made unique")
+
+		var group definitions.AlertRuleGroupExport
+		require.NoError(t, yaml.Unmarshal(files["folder-a/group.yaml"], &group))
+		require.Equal(t, "group", group.Name)
+		require.Equal(t, "folder-a", group.Folder)
+		require.Len(t, group.Rules, 2)
+		require.Equal(t, "rule-1", group.Rules[0].Title)
+		require.Equal(t, "A", group.Rules[0].Data[0].RefID)
+		require.Equal(t, &definitions.AlertRuleNotificationExport{Receiver: "receiver", GroupBy: []string{"team"}, GroupWait: &groupWait}, group.Rules[0].NotificationSettings)
+	})
+
+	t.Run("should only export the requested folders", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := service.ExportAllRuleGroups(context.Background(), orgID, ExportOptions{FolderUIDs: []string{"folder-b"}}, &buf)
+		require.NoError(t, err)
+
+		files := readZip(t, buf.Bytes())
+		require.Len(t, files, 2)
+		require.Contains(t, files, "folder-b/group.yaml")
+	})
+}
+
+func TestRuleGroupFileNames(t *testing.T) {
+	names := ruleGroupFileNames([][]string{
+		{"my group", "folder"},
+		{"my/group", "folder"},
+		{"my group", "other-folder"},
+		{"..", "folder"},
+	}, ".yaml")
+
+	require.NotEqual(t, names["folder"]["my group"], names["folder"]["my/group"])
+	require.Regexp(t, `^my_group-[0-9a-f]{8}\.yaml$`, names["folder"]["my group"])
+	require.Equal(t, "my_group.yaml", names["other-folder"]["my group"])
+	require.Equal(t, "_.yaml", names["folder"][".."])
+}
+
+func readZip(t *testing.T, data []byte) map[string][]byte {
+	t.Helper()
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	files := make(map[string][]byte, len(reader.File))
+	for _, f := range reader.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		require.NoError(t, rc.Close())
+		files[f.Name] = content
+	}
+	return files
+}
+
+func TestExportRulesByLabel(t *testing.T) {
+	var orgID int64 = 1
+	service := createAlertRuleService(t)
+	for _, r := range []struct {
+		title  string
+		folder string
+		group  string
+		team   string
+	}{
+		{title: "rule-1", folder: "folder-a", group: "group-1", team: "payments"},
+		{title: "rule-2", folder: "folder-a", group: "group-1", team: "search"},
+		{title: "rule-3", folder: "folder-a", group: "group-2", team: "search"},
+		{title: "rule-4", folder: "folder-b", group: "group-1", team: "payments"},
+	} {
+		rule := dummyRule(r.title, orgID)
+		rule.NamespaceUID = r.folder
+		rule.RuleGroup = r.group
+		rule.Labels = map[string]string{"team": r.team}
+		_, err := service.CreateAlertRule(context.Background(), rule, models.ProvenanceNone)
+		require.NoError(t, err)
+	}
+
+	selector, err := ParseLabelSelector(`{team="payments"}`)
+	require.NoError(t, err)
+	out, err := service.ExportRulesByLabel(context.Background(), orgID, selector, ExportFormatYAML)
+	require.NoError(t, err)
+
+	var file definitions.AlertingFileExport
+	require.NoError(t, yaml.Unmarshal(out, &file))
+	require.Equal(t, int64(1), file.APIVersion)
+	require.Len(t, file.Groups, 2, "groups without matching rules should be omitted")
+	require.Equal(t, "folder-a", file.Groups[0].Folder)
+	require.Equal(t, "group-1", file.Groups[0].Name)
+	require.Len(t, file.Groups[0].Rules, 1)
+	require.Equal(t, "rule-1", file.Groups[0].Rules[0].Title)
+	require.Equal(t, "folder-b", file.Groups[1].Folder)
+	require.Len(t, file.Groups[1].Rules, 1)
+	require.Equal(t, "rule-4", file.Groups[1].Rules[0].Title)
+
+	_, err = ParseLabelSelector(`{team}`)
+	require.ErrorIs(t, err, ErrValidation)
+}
+
+func TestVerifyExportRoundTrip(t *testing.T) {
+	var orgID int64 = 1
+	service := createAlertRuleService(t)
+	dashboardUID := "dashboard"
+	panelID := int64(3)
+	for _, r := range []struct {
+		title  string
+		folder string
+		group  string
+	}{
+		{title: "rule-1", folder: "folder-a", group: "group-1"},
+		{title: "rule-2", folder: "folder-a", group: "group-1"},
+		{title: "rule-3", folder: "folder-b", group: "group-2"},
+	} {
+		rule := dummyRule(r.title, orgID)
+		rule.NamespaceUID = r.folder
+		rule.RuleGroup = r.group
+		rule.Data[0].RelativeTimeRange.From = models.Duration(10 * time.Minute)
+		rule.Data[0].Model = json.RawMessage(`{"expr":"up","intervalMs":1000,"nested":{"values":[1,2.5,"a"]}}`)
+		rule.Labels = map[string]string{"team": "payments"}
+		rule.Annotations = map[string]string{"summary": "{{ $values.A }}"}
+		rule.For = 5 * time.Minute
+		rule.DashboardUID = &dashboardUID
+		rule.PanelID = &panelID
+		rule.NotificationSettings = []models.NotificationSettings{{ReceiverName: "team", GroupBy: []string{"alertname"}}}
+		rule.ActiveWindow = &models.ActiveWindow{
+			Times:    []timeinterval.TimeRange{{StartMinute: 9 * 60, EndMinute: 17 * 60}},
+			Weekdays: []timeinterval.WeekdayRange{{InclusiveRange: timeinterval.InclusiveRange{Begin: 1, End: 5}}},
+			Location: "Europe/Berlin",
+		}
+		_, err := service.CreateAlertRule(context.Background(), rule, models.ProvenanceNone)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, service.VerifyExportRoundTrip(context.Background(), orgID))
+
+	out, err := service.ExportRulesByLabel(context.Background(), orgID, nil, "")
+	require.NoError(t, err)
+	require.Contains(t, string(out), "from: 10m\n", "relative time ranges should be exported as duration strings")
+	require.Contains(t, string(out), "location: Europe/Berlin\n")
+
+	t.Run("should report content that is lost in the export", func(t *testing.T) {
+		rule := dummyRule("rule-4", orgID)
+		rule.Data[0].RelativeTimeRange.From = models.Duration(10*time.Minute + 500*time.Microsecond)
+		group, err := newAlertRuleGroupExport(orgID, rule.NamespaceUID, rule.RuleGroup, []*models.AlertRule{&rule})
+		require.NoError(t, err)
+		out, err := yaml.Marshal(group)
+		require.NoError(t, err)
+		require.NoError(t, yaml.Unmarshal(out, &group))
+		parsed, err := parseAlertRuleExport(group, group.Rules[0])
+		require.NoError(t, err)
+
+		diffs := roundTripDiffs(rule, parsed)
+		require.Len(t, diffs, 1)
+		require.Contains(t, diffs[0], "data[0].relativeTimeRange")
+	})
+}
+
+func TestExportFormats(t *testing.T) {
+	var orgID int64 = 1
+	service := createAlertRuleService(t)
+	dashboardUID := "dashboard"
+	panelID := int64(3)
+	for _, r := range []struct {
+		title  string
+		folder string
+		group  string
+	}{
+		{title: "rule-1", folder: "folder-a", group: "group-1"},
+		{title: "rule-2", folder: "folder-a", group: "group-1"},
+		{title: "rule-3", folder: "folder-b", group: "group 2"},
+	} {
+		rule := dummyRule(r.title, orgID)
+		rule.NamespaceUID = r.folder
+		rule.RuleGroup = r.group
+		rule.Data[0].RelativeTimeRange.From = models.Duration(10 * time.Minute)
+		rule.Data[0].Model = json.RawMessage(`{"expr":"up","intervalMs":1000,"nested":{"values":[1,2.5,"a"]}}`)
+		rule.Labels = map[string]string{"team": "payments"}
+		rule.Annotations = map[string]string{"description": "line 1\nline 2: {{ $values.A }}"}
+		rule.For = 5 * time.Minute
+		rule.DashboardUID = &dashboardUID
+		rule.PanelID = &panelID
+		rule.NotificationSettings = []models.NotificationSettings{{ReceiverName: "team", GroupBy: []string{"alertname"}}}
+		rule.ActiveWindow = &models.ActiveWindow{
+			Times:    []timeinterval.TimeRange{{StartMinute: 9 * 60, EndMinute: 17 * 60}},
+			Weekdays: []timeinterval.WeekdayRange{{InclusiveRange: timeinterval.InclusiveRange{Begin: 1, End: 5}}},
+			Location: "Europe/Berlin",
+		}
+		_, err := service.CreateAlertRule(context.Background(), rule, models.ProvenanceNone)
+		require.NoError(t, err)
+	}
+	reimport := func(t *testing.T, file definitions.AlertingFileExport) []models.AlertRule {
+		t.Helper()
+		var rules []models.AlertRule
+		for _, group := range file.Groups {
+			for _, ruleExport := range group.Rules {
+				rule, err := ruleExport.UpstreamModel(group)
+				require.NoError(t, err)
+				rules = append(rules, rule)
+			}
+		}
+		return rules
+	}
+
+	t.Run("YAML and JSON exports should re-import to equal rules", func(t *testing.T) {
+		yamlOut, err := service.ExportOrgRules(context.Background(), orgID, ExportFormatYAML)
+		require.NoError(t, err)
+		jsonOut, err := service.ExportOrgRules(context.Background(), orgID, ExportFormatJSON)
+		require.NoError(t, err)
+		require.True(t, json.Valid(jsonOut))
+
+		var yamlFile, jsonFile definitions.AlertingFileExport
+		require.NoError(t, yaml.Unmarshal(yamlOut, &yamlFile))
+		require.NoError(t, json.Unmarshal(jsonOut, &jsonFile))
+		yamlRules, jsonRules := reimport(t, yamlFile), reimport(t, jsonFile)
+		require.Len(t, yamlRules, 3)
+		require.Equal(t, yamlRules, jsonRules)
+		require.Equal(t, "line 1\nline 2: {{ $values.A }}", jsonRules[0].Annotations["description"])
+		require.Equal(t, "Europe/Berlin", jsonRules[0].ActiveWindow.Location)
+	})
+	t.Run("should export a single rule group in both formats", func(t *testing.T) {
+		yamlOut, err := service.ExportRuleGroup(context.Background(), orgID, "folder-a", "group-1", ExportFormatYAML)
+		require.NoError(t, err)
+		jsonOut, err := service.ExportRuleGroup(context.Background(), orgID, "folder-a", "group-1", ExportFormatJSON)
+		require.NoError(t, err)
+
+		var yamlFile, jsonFile definitions.AlertingFileExport
+		require.NoError(t, yaml.Unmarshal(yamlOut, &yamlFile))
+		require.NoError(t, json.Unmarshal(jsonOut, &jsonFile))
+		require.Len(t, yamlFile.Groups, 1)
+		require.Len(t, yamlFile.Groups[0].Rules, 2)
+		require.Equal(t, reimport(t, yamlFile), reimport(t, jsonFile))
+
+		_, err = service.ExportRuleGroup(context.Background(), orgID, "folder-a", "group 2", ExportFormatJSON)
+		require.ErrorIs(t, err, store.ErrAlertRuleGroupNotFound)
+	})
+	t.Run("should write the files of archives in the format", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := service.ExportAllRuleGroups(context.Background(), orgID, ExportOptions{Format: ExportFormatJSON}, &buf)
+		require.NoError(t, err)
+
+		files := readZip(t, buf.Bytes())
+		require.Len(t, files, 3)
+		var index definitions.ExportIndex
+		require.NoError(t, json.Unmarshal(files["index.json"], &index))
+		require.Len(t, index.Groups, 2)
+		var group definitions.AlertRuleGroupExport
+		require.NoError(t, json.Unmarshal(files["folder-b/group_2.json"], &group))
+		require.Equal(t, "group 2", group.Name)
+	})
+	t.Run("should reject unknown formats", func(t *testing.T) {
+		format, err := ParseExportFormat(" JSON")
+		require.NoError(t, err)
+		require.Equal(t, ExportFormatJSON, format)
+		format, err = ParseExportFormat("")
+		require.NoError(t, err)
+		require.Equal(t, ExportFormatYAML, format)
+
+		_, err = ParseExportFormat("toml")
+		require.ErrorIs(t, err, ErrValidation)
+		_, err = service.ExportOrgRules(context.Background(), orgID, "toml")
+		require.ErrorIs(t, err, ErrValidation)
+		err = service.ExportAllRuleGroups(context.Background(), orgID, ExportOptions{Format: "toml"}, io.Discard)
+		require.ErrorIs(t, err, ErrValidation)
+	})
+}
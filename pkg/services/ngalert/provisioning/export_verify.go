@@ -0,0 +1,185 @@
+package provisioning
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+// VerifyExportRoundTrip exports all alert rules of the organization, parses the export again and
+// compares the parsed rules with the stored rules. It returns an error that wraps ErrExportRoundTrip
+// and lists every difference if a rule would not survive an export and a re-import unchanged.
+// It is meant to be run by CI and operators to detect lossy exports.
+func (service *AlertRuleService) VerifyExportRoundTrip(ctx context.Context, orgID int64) (err error) {
+	defer wrapServiceError(&err)
+	ctx, cancel := withTimeout(ctx, service.cfg.OperationTimeouts.List)
+	defer cancel()
+	query := &models.ListAlertRulesQuery{OrgID: orgID}
+	scope, err := service.callerNamespaces(ctx)
+	if err != nil {
+		return err
+	}
+	if !scope.restrict(query) {
+		return nil
+	}
+	if err := service.ruleStore.ListAlertRules(ctx, query); err != nil {
+		return err
+	}
+	file, err := NewAlertingFileExport(orgID, query.Result)
+	if err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(file)
+	if err != nil {
+		return err
+	}
+	var parsed definitions.AlertingFileExport
+	if err := yaml.Unmarshal(data, &parsed); err != nil {
+		return fmt.Errorf("%w: failed to parse the export: %s", ErrExportRoundTrip, err)
+	}
+
+	stored := make(map[string]*models.AlertRule, len(query.Result))
+	for _, rule := range query.Result {
+		stored[rule.UID] = rule
+	}
+	var diffs []string
+	seen := make(map[string]struct{}, len(query.Result))
+	for _, group := range parsed.Groups {
+		for _, ruleExport := range group.Rules {
+			rule, ok := stored[ruleExport.UID]
+			if !ok {
+				diffs = append(diffs, fmt.Sprintf("rule %s: exported but not stored", ruleExport.UID))
+				continue
+			}
+			seen[rule.UID] = struct{}{}
+			parsedRule, err := parseAlertRuleExport(group, ruleExport)
+			if err != nil {
+				diffs = append(diffs, fmt.Sprintf("rule %s: %s", rule.UID, err))
+				continue
+			}
+			for _, diff := range roundTripDiffs(*rule, parsedRule) {
+				diffs = append(diffs, fmt.Sprintf("rule %s: %s", rule.UID, diff))
+			}
+		}
+	}
+	for _, rule := range query.Result {
+		if _, ok := seen[rule.UID]; !ok {
+			diffs = append(diffs, fmt.Sprintf("rule %s: stored but not exported", rule.UID))
+		}
+	}
+	if len(diffs) > 0 {
+		return fmt.Errorf("%w: %d differences:\n%s", ErrExportRoundTrip, len(diffs), strings.Join(diffs, "\n"))
+	}
+	return nil
+}
+
+// parseAlertRuleExport returns the alert rule that a rule of an exported rule group describes.
+func parseAlertRuleExport(group definitions.AlertRuleGroupExport, export definitions.AlertRuleExport) (models.AlertRule, error) {
+	rule := models.AlertRule{
+		OrgID:           group.OrgID,
+		NamespaceUID:    group.Folder,
+		RuleGroup:       group.Name,
+		IntervalSeconds: int64(time.Duration(group.Interval).Seconds()),
+		UID:             export.UID,
+		Title:           export.Title,
+		Condition:       export.Condition,
+		DashboardUID:    export.DashboardUID,
+		PanelID:         export.PanelID,
+		NoDataState:     export.NoDataState,
+		ExecErrState:    export.ExecErrState,
+		For:             time.Duration(export.For),
+		Annotations:     export.Annotations,
+		Labels:          export.Labels,
+		ActiveWindow:    export.ActiveWindow,
+	}
+	for _, query := range export.Data {
+		queryModel, err := json.Marshal(query.Model)
+		if err != nil {
+			return models.AlertRule{}, fmt.Errorf("failed to parse the model of query %s: %w", query.RefID, err)
+		}
+		rule.Data = append(rule.Data, models.AlertQuery{
+			RefID:     query.RefID,
+			QueryType: query.QueryType,
+			RelativeTimeRange: models.RelativeTimeRange{
+				From: models.Duration(query.RelativeTimeRange.From),
+				To:   models.Duration(query.RelativeTimeRange.To),
+			},
+			DatasourceUID: query.DatasourceUID,
+			Model:         queryModel,
+		})
+	}
+	if export.NotificationSettings != nil {
+		rule.NotificationSettings = []models.NotificationSettings{{
+			ReceiverName:   export.NotificationSettings.Receiver,
+			GroupBy:        export.NotificationSettings.GroupBy,
+			GroupWait:      export.NotificationSettings.GroupWait,
+			GroupInterval:  export.NotificationSettings.GroupInterval,
+			RepeatInterval: export.NotificationSettings.RepeatInterval,
+		}}
+	}
+	return rule, nil
+}
+
+// roundTripDiffs describes the differences of the semantic content of a stored rule and the rule
+// parsed from its export. Empty and missing maps are considered equal.
+func roundTripDiffs(stored, parsed models.AlertRule) []string {
+	var diffs []string
+	check := func(field string, storedValue, parsedValue interface{}) {
+		if !reflect.DeepEqual(storedValue, parsedValue) {
+			diffs = append(diffs, fmt.Sprintf("%s is %v but %v after the round trip", field, storedValue, parsedValue))
+		}
+	}
+	check("orgId", stored.OrgID, parsed.OrgID)
+	check("folder", stored.NamespaceUID, parsed.NamespaceUID)
+	check("rule group", stored.RuleGroup, parsed.RuleGroup)
+	check("interval", stored.IntervalSeconds, parsed.IntervalSeconds)
+	check("title", stored.Title, parsed.Title)
+	check("condition", stored.Condition, parsed.Condition)
+	check("dashboard UID", stored.DashboardUID, parsed.DashboardUID)
+	check("panel ID", stored.PanelID, parsed.PanelID)
+	check("no data state", stored.NoDataState, parsed.NoDataState)
+	check("error state", stored.ExecErrState, parsed.ExecErrState)
+	check("for", stored.For, parsed.For)
+	check("annotations", nilIfEmpty(stored.Annotations), nilIfEmpty(parsed.Annotations))
+	check("labels", nilIfEmpty(stored.Labels), nilIfEmpty(parsed.Labels))
+	check("notification settings", stored.GetNotificationSettings(), parsed.GetNotificationSettings())
+	check("active window", stored.ActiveWindow, parsed.ActiveWindow)
+	if len(stored.Data) != len(parsed.Data) {
+		diffs = append(diffs, fmt.Sprintf("has %d queries but %d after the round trip", len(stored.Data), len(parsed.Data)))
+		return diffs
+	}
+	for i := range stored.Data {
+		storedQuery, parsedQuery := stored.Data[i], parsed.Data[i]
+		field := fmt.Sprintf("data[%d]", i)
+		check(field+".refId", storedQuery.RefID, parsedQuery.RefID)
+		check(field+".queryType", storedQuery.QueryType, parsedQuery.QueryType)
+		check(field+".relativeTimeRange", storedQuery.RelativeTimeRange, parsedQuery.RelativeTimeRange)
+		check(field+".datasourceUid", storedQuery.DatasourceUID, parsedQuery.DatasourceUID)
+		var storedModel, parsedModel interface{}
+		if err := json.Unmarshal(storedQuery.Model, &storedModel); err != nil {
+			diffs = append(diffs, fmt.Sprintf("%s.model cannot be parsed: %s", field, err))
+			continue
+		}
+		if err := json.Unmarshal(parsedQuery.Model, &parsedModel); err != nil {
+			diffs = append(diffs, fmt.Sprintf("%s.model cannot be parsed after the round trip: %s", field, err))
+			continue
+		}
+		check(field+".model", storedModel, parsedModel)
+	}
+	return diffs
+}
+
+func nilIfEmpty(m map[string]string) map[string]string {
+	if len(m) == 0 {
+		return nil
+	}
+	return m
+}
@@ -0,0 +1,114 @@
+package provisioning
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/grafana/grafana/pkg/expr"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+// fingerprintedQuery is the part of a query of an alert rule that is compared by Fingerprint.
+type fingerprintedQuery struct {
+	RefID     string `json:"refId"`
+	QueryType string `json:"queryType"`
+	// From and To are in seconds, the precision in which they are stored.
+	From int64 `json:"from"`
+	To   int64 `json:"to"`
+	// Expression is set instead of the data source for server side expressions.
+	Expression bool        `json:"expression"`
+	Model      interface{} `json:"model"`
+}
+
+// fingerprintedRule is the part of an alert rule that is compared by Fingerprint.
+type fingerprintedRule struct {
+	Condition       string               `json:"condition"`
+	Queries         []fingerprintedQuery `json:"queries"`
+	IntervalSeconds int64                `json:"intervalSeconds"`
+	For             int64                `json:"for"`
+	NoDataState     string               `json:"noDataState"`
+	ExecErrState    string               `json:"execErrState"`
+}
+
+// Fingerprint returns a hash of what the alert rule evaluates: its queries, condition and thresholds,
+// its interval, and how its states are computed. Fields that belong to the organization of the rule,
+// such as its UID, folder, title and the data sources of its queries, are not part of the hash, so
+// that identical rules in different organizations have the same fingerprint.
+func Fingerprint(rule models.AlertRule) string {
+	fingerprinted := fingerprintedRule{
+		Condition:       rule.Condition,
+		Queries:         make([]fingerprintedQuery, 0, len(rule.Data)),
+		IntervalSeconds: rule.IntervalSeconds,
+		For:             int64(rule.For / time.Second),
+		NoDataState:     string(rule.NoDataState),
+		ExecErrState:    string(rule.ExecErrState),
+	}
+	for _, query := range rule.Data {
+		fingerprinted.Queries = append(fingerprinted.Queries, fingerprintedQuery{
+			RefID:      query.RefID,
+			QueryType:  query.QueryType,
+			From:       int64(time.Duration(query.RelativeTimeRange.From) / time.Second),
+			To:         int64(time.Duration(query.RelativeTimeRange.To) / time.Second),
+			Expression: expr.IsDataSource(query.DatasourceUID),
+			Model:      fingerprintedModel(query.Model),
+		})
+	}
+	sort.Slice(fingerprinted.Queries, func(i, j int) bool {
+		return fingerprinted.Queries[i].RefID < fingerprinted.Queries[j].RefID
+	})
+	// Maps are encoded with sorted keys, so equal models are encoded equally.
+	b, _ := json.Marshal(fingerprinted)
+	return fmt.Sprintf("%x", sha256.Sum256(b))
+}
+
+// fingerprintedModel returns the model of a query without the data source that it references.
+// Models that are not JSON objects are returned as they are.
+func fingerprintedModel(model json.RawMessage) interface{} {
+	var props map[string]interface{}
+	if err := json.Unmarshal(model, &props); err != nil {
+		return string(model)
+	}
+	delete(props, "datasource")
+	return props
+}
+
+// FindDuplicateUIDs returns the UIDs of alert rules that more than one organization uses, with the IDs
+// of these organizations. Tooling that identifies rules by UID alone cannot tell such rules apart. The
+// caller of the context must be allowed to read the alert rules of all organizations.
+func (service *AlertRuleService) FindDuplicateUIDs(ctx context.Context) (_ map[string][]int64, err error) {
+	defer wrapServiceError(&err)
+	if err := service.authorizeSystemAccess(ctx, accesscontrol.ActionAlertingSystemRuleRead); err != nil {
+		return nil, err
+	}
+	ctx, cancel := withTimeout(ctx, service.cfg.OperationTimeouts.List)
+	defer cancel()
+	return service.ruleStore.ListDuplicateAlertRuleUIDs(ctx)
+}
+
+// FindDuplicateAlertRules returns the alert rules of all organizations that have the fingerprint.
+// The caller of the context must be allowed to read the alert rules of all organizations.
+func (service *AlertRuleService) FindDuplicateAlertRules(ctx context.Context, fingerprint string) (_ []models.AlertRule, err error) {
+	defer wrapServiceError(&err)
+	if err := service.authorizeSystemAccess(ctx, accesscontrol.ActionAlertingSystemRuleRead); err != nil {
+		return nil, err
+	}
+	ctx, cancel := withTimeout(ctx, service.cfg.OperationTimeouts.List)
+	defer cancel()
+	// A negative organization lists the rules of all organizations.
+	query := &models.ListAlertRulesQuery{OrgID: -1}
+	if err := service.ruleStore.ListAlertRules(ctx, query); err != nil {
+		return nil, err
+	}
+	result := make([]models.AlertRule, 0)
+	for _, rule := range query.Result {
+		if Fingerprint(*rule) == fingerprint {
+			result = append(result, *rule)
+		}
+	}
+	return result, nil
+}
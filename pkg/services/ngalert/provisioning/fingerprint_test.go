@@ -0,0 +1,98 @@
+package provisioning
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	models2 "github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	acmock "github.com/grafana/grafana/pkg/services/accesscontrol/mock"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+func TestFingerprint(t *testing.T) {
+	withThreshold := func(orgID int64, datasourceUID, threshold string) models.AlertRule {
+		rule := dummyRule("test#fingerprint", orgID)
+		rule.Data[0].DatasourceUID = datasourceUID
+		rule.Data[0].Model = json.RawMessage(`{"datasource":{"uid":"` + datasourceUID + `"},"expr":"up > ` + threshold + `"}`)
+		return rule
+	}
+
+	t.Run("rules with the same queries should have the same fingerprint", func(t *testing.T) {
+		rule := withThreshold(1, "datasource-1", "1")
+		other := withThreshold(2, "datasource-2", "1")
+		other.UID = "other"
+		other.Title = "test#fingerprint-copy"
+		other.NamespaceUID = "other-folder"
+		require.Equal(t, Fingerprint(rule), Fingerprint(other))
+	})
+	t.Run("rules with different thresholds should have different fingerprints", func(t *testing.T) {
+		require.NotEqual(t, Fingerprint(withThreshold(1, "datasource", "1")), Fingerprint(withThreshold(1, "datasource", "2")))
+	})
+	t.Run("rules with different intervals should have different fingerprints", func(t *testing.T) {
+		rule := withThreshold(1, "datasource", "1")
+		other := withThreshold(1, "datasource", "1")
+		other.IntervalSeconds = 2 * rule.IntervalSeconds
+		require.NotEqual(t, Fingerprint(rule), Fingerprint(other))
+	})
+}
+
+func TestAlertRuleServiceFindDuplicateAlertRules(t *testing.T) {
+	ruleService := createAlertRuleService(t)
+	ruleService.ac = acmock.New().WithPermissions([]*accesscontrol.Permission{
+		{Action: accesscontrol.ActionAlertingSystemRuleRead},
+	})
+	system := WithCaller(context.Background(), &models2.SignedInUser{OrgId: 1, IsGrafanaAdmin: true})
+	create := func(orgID int64) models.AlertRule {
+		rule := dummyRule("test#duplicate", orgID)
+		rule.Data[0].RelativeTimeRange.From = models.Duration(time.Minute)
+		rule.Data[0].Model = json.RawMessage(`{"expr":"duplicated_metric > 1"}`)
+		created, err := ruleService.CreateAlertRule(context.Background(), rule, models.ProvenanceAPI)
+		require.NoError(t, err)
+		return created
+	}
+	first := create(61)
+	second := create(62)
+	other := create(63)
+	require.NoError(t, ruleService.UpdateAlertGroup(context.Background(), other.OrgID, other.NamespaceUID, other.RuleGroup, 2*other.IntervalSeconds, models.ProvenanceAPI))
+
+	duplicates, err := ruleService.FindDuplicateAlertRules(system, Fingerprint(first))
+	require.NoError(t, err)
+	uids := make([]string, 0, len(duplicates))
+	for _, rule := range duplicates {
+		uids = append(uids, rule.UID)
+	}
+	require.ElementsMatch(t, []string{first.UID, second.UID}, uids)
+
+	_, err = ruleService.FindDuplicateAlertRules(context.Background(), Fingerprint(first))
+	require.ErrorIs(t, err, ErrAccessDenied)
+}
+
+func TestAlertRuleServiceFindDuplicateUIDs(t *testing.T) {
+	ruleService := createAlertRuleService(t)
+	ruleService.ac = acmock.New().WithPermissions([]*accesscontrol.Permission{
+		{Action: accesscontrol.ActionAlertingSystemRuleRead},
+	})
+	system := WithCaller(context.Background(), &models2.SignedInUser{OrgId: 1, IsGrafanaAdmin: true})
+	create := func(orgID int64, uid string) {
+		rule := dummyRule("test#"+uid, orgID)
+		rule.UID = uid
+		rule.Data[0].RelativeTimeRange.From = models.Duration(time.Minute)
+		_, err := ruleService.CreateAlertRule(context.Background(), rule, models.ProvenanceAPI)
+		require.NoError(t, err)
+	}
+	create(72, "shared-uid")
+	create(71, "shared-uid")
+	create(71, "unique-uid")
+
+	duplicates, err := ruleService.FindDuplicateUIDs(system)
+	require.NoError(t, err)
+	require.Equal(t, map[string][]int64{"shared-uid": {71, 72}}, duplicates)
+
+	_, err = ruleService.FindDuplicateUIDs(context.Background())
+	require.ErrorIs(t, err, ErrAccessDenied)
+}
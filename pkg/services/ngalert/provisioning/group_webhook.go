@@ -0,0 +1,98 @@
+package provisioning
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	webhookDefaultMaxAttempts = 3
+	webhookDefaultBackoff     = 100 * time.Millisecond
+)
+
+// RuleGroupChange describes a committed change of the rules of a rule group.
+type RuleGroupChange struct {
+	OrgID        int64    `json:"orgId"`
+	NamespaceUID string   `json:"namespaceUid"`
+	RuleGroup    string   `json:"ruleGroup"`
+	Created      []string `json:"created"`
+	Updated      []string `json:"updated"`
+	Deleted      []string `json:"deleted"`
+}
+
+// RuleGroupChangeNotifier is informed about every successful change of a rule group.
+type RuleGroupChangeNotifier interface {
+	Notify(ctx context.Context, change RuleGroupChange) error
+}
+
+// WebhookClient sends the requests of a WebhookRuleGroupNotifier.
+type WebhookClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// WebhookRuleGroupNotifier posts rule group changes as JSON to a webhook.
+// Requests that fail with a server error are retried with an exponential backoff.
+type WebhookRuleGroupNotifier struct {
+	url         string
+	client      WebhookClient
+	maxAttempts int
+	backoff     time.Duration
+}
+
+func NewWebhookRuleGroupNotifier(url string, client WebhookClient) *WebhookRuleGroupNotifier {
+	return &WebhookRuleGroupNotifier{
+		url:         url,
+		client:      client,
+		maxAttempts: webhookDefaultMaxAttempts,
+		backoff:     webhookDefaultBackoff,
+	}
+}
+
+func (n *WebhookRuleGroupNotifier) Notify(ctx context.Context, change RuleGroupChange) error {
+	body, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+	backoff := n.backoff
+	for attempt := 1; ; attempt++ {
+		retry, err := n.send(ctx, body)
+		if err == nil || !retry || attempt >= n.maxAttempts {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// send posts the body to the webhook once. It reports whether a failed request should be retried.
+func (n *WebhookRuleGroupNotifier) send(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer func() {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return true, fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return false, fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return false, nil
+}
@@ -0,0 +1,83 @@
+package provisioning
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookRuleGroupNotifier(t *testing.T) {
+	change := RuleGroupChange{
+		OrgID:        1,
+		NamespaceUID: "folder",
+		RuleGroup:    "group",
+		Created:      []string{"a"},
+		Updated:      []string{"b"},
+		Deleted:      []string{},
+	}
+
+	t.Run("should post the change as JSON", func(t *testing.T) {
+		var received RuleGroupChange
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, http.MethodPost, r.Method)
+			require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		}))
+		defer server.Close()
+
+		err := NewWebhookRuleGroupNotifier(server.URL, server.Client()).Notify(context.Background(), change)
+		require.NoError(t, err)
+		require.Equal(t, change, received)
+	})
+
+	t.Run("should retry on server errors", func(t *testing.T) {
+		calls := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			if calls < 3 {
+				w.WriteHeader(http.StatusBadGateway)
+			}
+		}))
+		defer server.Close()
+		notifier := NewWebhookRuleGroupNotifier(server.URL, server.Client())
+		notifier.backoff = 0
+
+		err := notifier.Notify(context.Background(), change)
+		require.NoError(t, err)
+		require.Equal(t, 3, calls)
+	})
+
+	t.Run("should give up after the maximum number of attempts", func(t *testing.T) {
+		calls := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+		notifier := NewWebhookRuleGroupNotifier(server.URL, server.Client())
+		notifier.backoff = 0
+
+		err := notifier.Notify(context.Background(), change)
+		require.Error(t, err)
+		require.Equal(t, webhookDefaultMaxAttempts, calls)
+	})
+
+	t.Run("should not retry on client errors", func(t *testing.T) {
+		calls := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer server.Close()
+		notifier := NewWebhookRuleGroupNotifier(server.URL, server.Client())
+		notifier.backoff = 0
+
+		err := notifier.Notify(context.Background(), change)
+		require.Error(t, err)
+		require.Equal(t, 1, calls)
+	})
+}
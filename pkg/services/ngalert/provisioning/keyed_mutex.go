@@ -0,0 +1,44 @@
+package provisioning
+
+import "sync"
+
+// keyedMutex provides mutual exclusion per key. Locks for keys that are not
+// held by anyone are released so that the set of keys does not grow unbounded.
+type keyedMutex struct {
+	mtx   sync.Mutex
+	locks map[string]*keyedLock
+}
+
+type keyedLock struct {
+	mtx  sync.Mutex
+	refs int
+}
+
+func newKeyedMutex() *keyedMutex {
+	return &keyedMutex{
+		locks: map[string]*keyedLock{},
+	}
+}
+
+// Lock acquires the lock for the given key and returns the function that releases it.
+func (m *keyedMutex) Lock(key string) func() {
+	m.mtx.Lock()
+	l, ok := m.locks[key]
+	if !ok {
+		l = &keyedLock{}
+		m.locks[key] = l
+	}
+	l.refs++
+	m.mtx.Unlock()
+
+	l.mtx.Lock()
+	return func() {
+		l.mtx.Unlock()
+		m.mtx.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(m.locks, key)
+		}
+		m.mtx.Unlock()
+	}
+}
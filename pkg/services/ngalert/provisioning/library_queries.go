@@ -0,0 +1,140 @@
+package provisioning
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/store"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+// SaveLibraryQuery creates or updates a library query. Rules that keep a live reference to
+// the query are not changed until ResyncLibraryQuery is called.
+func (service *AlertRuleService) SaveLibraryQuery(ctx context.Context, query models.LibraryQuery) (_ models.LibraryQuery, err error) {
+	defer wrapServiceError(&err)
+	if service.libraryQueries == nil {
+		return models.LibraryQuery{}, errors.New("library queries are not supported")
+	}
+	if !json.Valid([]byte(query.Model)) {
+		return models.LibraryQuery{}, fmt.Errorf("%w: model of library query is not valid JSON", ErrValidation)
+	}
+	if query.UID == "" {
+		query.UID = util.GenerateShortUID()
+	}
+	if err := service.libraryQueries.SaveLibraryQuery(ctx, &query); err != nil {
+		return models.LibraryQuery{}, err
+	}
+	return query, nil
+}
+
+// DeleteLibraryQuery deletes a library query. It fails with ErrLibraryQueryInUse if any alert
+// rule keeps a live reference to it.
+func (service *AlertRuleService) DeleteLibraryQuery(ctx context.Context, orgID int64, uid string) (err error) {
+	defer wrapServiceError(&err)
+	if service.libraryQueries == nil {
+		return errors.New("library queries are not supported")
+	}
+	return service.xact.InTransaction(ctx, func(ctx context.Context) error {
+		rules, err := service.rulesReferencingLibraryQuery(ctx, orgID, uid)
+		if err != nil {
+			return err
+		}
+		if len(rules) > 0 {
+			uids := make([]string, 0, len(rules))
+			for _, rule := range rules {
+				uids = append(uids, rule.UID)
+			}
+			return fmt.Errorf("%w: %s", models.ErrLibraryQueryInUse, strings.Join(uids, ", "))
+		}
+		return service.libraryQueries.DeleteLibraryQuery(ctx, orgID, uid)
+	})
+}
+
+// ResyncLibraryQuery copies the current content of the library query into all alert rules
+// of the organization that keep a live reference to it.
+func (service *AlertRuleService) ResyncLibraryQuery(ctx context.Context, orgID int64, libraryUID string) (err error) {
+	defer wrapServiceError(&err)
+	if err := service.policy.checkMutation(orgID, ResourceTypeAlertRules, models.ProvenanceNone); err != nil {
+		return err
+	}
+	if service.libraryQueries == nil {
+		return errors.New("library queries are not supported")
+	}
+	libraryQuery, err := service.libraryQueries.GetLibraryQuery(ctx, orgID, libraryUID)
+	if err != nil {
+		return err
+	}
+	return service.xact.InTransaction(ctx, func(ctx context.Context) error {
+		rules, err := service.rulesReferencingLibraryQuery(ctx, orgID, libraryUID)
+		if err != nil {
+			return err
+		}
+		updates := make([]store.UpdateRule, 0, len(rules))
+		for _, rule := range rules {
+			updated := *rule
+			updated.Data = make([]models.AlertQuery, len(rule.Data))
+			copy(updated.Data, rule.Data)
+			for i := range updated.Data {
+				if ref := updated.Data[i].LibraryQuery; ref != nil && ref.UID == libraryUID {
+					libraryQuery.Materialize(&updated.Data[i])
+				}
+			}
+			updated.Updated = time.Now()
+			updates = append(updates, store.UpdateRule{
+				Existing: rule,
+				New:      updated,
+			})
+		}
+		if len(updates) == 0 {
+			return nil
+		}
+		return service.ruleStore.UpdateAlertRules(ctx, updates)
+	})
+}
+
+func (service *AlertRuleService) rulesReferencingLibraryQuery(ctx context.Context, orgID int64, libraryUID string) ([]*models.AlertRule, error) {
+	query := &models.ListAlertRulesQuery{
+		OrgID: orgID,
+	}
+	if err := service.ruleStore.ListAlertRules(ctx, query); err != nil {
+		return nil, err
+	}
+	var result []*models.AlertRule
+	for _, rule := range query.Result {
+		if rule.HasLiveLibraryQueryReference(libraryUID) {
+			result = append(result, rule)
+		}
+	}
+	return result, nil
+}
+
+// materializeLibraryQueries replaces the queries of the rule that reference a library query
+// with a copy of the library query.
+func (service *AlertRuleService) materializeLibraryQueries(ctx context.Context, rule *models.AlertRule) error {
+	data := make([]models.AlertQuery, len(rule.Data))
+	copy(data, rule.Data)
+	for i := range data {
+		ref := data[i].LibraryQuery
+		if ref == nil {
+			continue
+		}
+		if service.libraryQueries == nil {
+			return fmt.Errorf("%w: query %s references a library query but library queries are not supported", ErrValidation, data[i].RefID)
+		}
+		libraryQuery, err := service.libraryQueries.GetLibraryQuery(ctx, rule.OrgID, ref.UID)
+		if errors.Is(err, models.ErrLibraryQueryNotFound) {
+			return fmt.Errorf("%w: query %s references unknown library query '%s'", ErrValidation, data[i].RefID, ref.UID)
+		}
+		if err != nil {
+			return err
+		}
+		libraryQuery.Materialize(&data[i])
+	}
+	rule.Data = data
+	return nil
+}
@@ -0,0 +1,88 @@
+package provisioning
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/store"
+)
+
+func TestLibraryQueries(t *testing.T) {
+	var orgID int64 = 1
+	service := createAlertRuleService(t)
+	service.libraryQueries = service.ruleStore.(store.DBstore)
+
+	libraryQuery, err := service.SaveLibraryQuery(context.Background(), models.LibraryQuery{
+		OrgID:         orgID,
+		Title:         "cpu usage",
+		DatasourceUID: "prometheus",
+		Model:         `{"expr":"cpu > 80"}`,
+	})
+	require.NoError(t, err)
+
+	libraryRule := func(title string, live bool) models.AlertRule {
+		rule := dummyRule(title, orgID)
+		rule.Data[0].RelativeTimeRange.From = models.Duration(10 * time.Minute)
+		rule.Data[0].LibraryQuery = &models.LibraryQueryReference{UID: libraryQuery.UID, Live: live}
+		return rule
+	}
+
+	copied, err := service.CreateAlertRule(context.Background(), libraryRule("library#1", false), models.ProvenanceNone)
+	require.NoError(t, err)
+	live, err := service.CreateAlertRule(context.Background(), libraryRule("library#2", true), models.ProvenanceNone)
+	require.NoError(t, err)
+
+	t.Run("library query should be copied into the rule", func(t *testing.T) {
+		stored, _, err := service.GetAlertRule(context.Background(), orgID, copied.UID)
+		require.NoError(t, err)
+		require.Equal(t, "prometheus", stored.Data[0].DatasourceUID)
+		require.Equal(t, "cpu > 80", queryExpr(t, stored.Data[0]))
+		require.Nil(t, stored.Data[0].LibraryQuery)
+	})
+
+	t.Run("unknown library query should be rejected", func(t *testing.T) {
+		rule := libraryRule("library#3", false)
+		rule.Data[0].LibraryQuery.UID = "unknown"
+		_, err := service.CreateAlertRule(context.Background(), rule, models.ProvenanceNone)
+		require.ErrorIs(t, err, ErrValidation)
+	})
+
+	t.Run("resync should update live references only", func(t *testing.T) {
+		libraryQuery.Model = `{"expr":"cpu > 90"}`
+		_, err := service.SaveLibraryQuery(context.Background(), libraryQuery)
+		require.NoError(t, err)
+
+		require.NoError(t, service.ResyncLibraryQuery(context.Background(), orgID, libraryQuery.UID))
+
+		stored, _, err := service.GetAlertRule(context.Background(), orgID, live.UID)
+		require.NoError(t, err)
+		require.Equal(t, "cpu > 90", queryExpr(t, stored.Data[0]))
+		require.Equal(t, libraryQuery.UID, stored.Data[0].LibraryQuery.UID)
+
+		stored, _, err = service.GetAlertRule(context.Background(), orgID, copied.UID)
+		require.NoError(t, err)
+		require.Equal(t, "cpu > 80", queryExpr(t, stored.Data[0]))
+	})
+
+	t.Run("library query with live references should not be deleted", func(t *testing.T) {
+		err := service.DeleteLibraryQuery(context.Background(), orgID, libraryQuery.UID)
+		require.ErrorIs(t, err, models.ErrLibraryQueryInUse)
+
+		require.NoError(t, service.DeleteAlertRule(context.Background(), orgID, live.UID, models.ProvenanceNone))
+		require.NoError(t, service.DeleteLibraryQuery(context.Background(), orgID, libraryQuery.UID))
+		_, err = service.ruleStore.(store.DBstore).GetLibraryQuery(context.Background(), orgID, libraryQuery.UID)
+		require.ErrorIs(t, err, models.ErrLibraryQueryNotFound)
+	})
+}
+
+func queryExpr(t *testing.T, query models.AlertQuery) interface{} {
+	t.Helper()
+	var model map[string]interface{}
+	require.NoError(t, json.Unmarshal(query.Model, &model))
+	return model["expr"]
+}
@@ -14,14 +14,16 @@ type MuteTimingService struct {
 	config AMConfigStore
 	prov   ProvisioningStore
 	xact   TransactionManager
+	policy ResourcePolicy
 	log    log.Logger
 }
 
-func NewMuteTimingService(config AMConfigStore, prov ProvisioningStore, xact TransactionManager, log log.Logger) *MuteTimingService {
+func NewMuteTimingService(config AMConfigStore, prov ProvisioningStore, xact TransactionManager, policy ResourcePolicy, log log.Logger) *MuteTimingService {
 	return &MuteTimingService{
 		config: config,
 		prov:   prov,
 		xact:   xact,
+		policy: policy,
 		log:    log,
 	}
 }
@@ -46,6 +48,9 @@ func (svc *MuteTimingService) GetMuteTimings(ctx context.Context, orgID int64) (
 
 // CreateMuteTiming adds a new mute timing within the specified org. The created mute timing is returned.
 func (svc *MuteTimingService) CreateMuteTiming(ctx context.Context, mt definitions.MuteTimeInterval, orgID int64) (*definitions.MuteTimeInterval, error) {
+	if err := svc.policy.checkMutation(orgID, ResourceTypeMuteTimings, mt.Provenance); err != nil {
+		return nil, err
+	}
 	if err := mt.Validate(); err != nil {
 		return nil, fmt.Errorf("%w: %s", ErrValidation, err.Error())
 	}
@@ -96,6 +101,9 @@ func (svc *MuteTimingService) CreateMuteTiming(ctx context.Context, mt definitio
 
 // UpdateMuteTiming replaces an existing mute timing within the specified org. The replaced mute timing is returned. If the mute timing does not exist, nil is returned and no action is taken.
 func (svc *MuteTimingService) UpdateMuteTiming(ctx context.Context, mt definitions.MuteTimeInterval, orgID int64) (*definitions.MuteTimeInterval, error) {
+	if err := svc.policy.checkMutation(orgID, ResourceTypeMuteTimings, mt.Provenance); err != nil {
+		return nil, err
+	}
 	if err := mt.Validate(); err != nil {
 		return nil, fmt.Errorf("%w: %s", ErrValidation, err.Error())
 	}
@@ -151,6 +159,9 @@ func (svc *MuteTimingService) UpdateMuteTiming(ctx context.Context, mt definitio
 
 // DeleteMuteTiming deletes the mute timing with the given name in the given org. If the mute timing does not exist, no error is returned.
 func (svc *MuteTimingService) DeleteMuteTiming(ctx context.Context, name string, orgID int64) error {
+	if err := svc.policy.checkMutation(orgID, ResourceTypeMuteTimings, models.ProvenanceNone); err != nil {
+		return err
+	}
 	revision, err := getLastConfiguration(ctx, orgID, svc.config)
 	if err != nil {
 		return err
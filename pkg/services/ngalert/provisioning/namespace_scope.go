@@ -0,0 +1,142 @@
+package provisioning
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+// namespaceScope is the set of namespaces that the caller of an operation can read and write alert
+// rules in. The zero value does not restrict the caller.
+type namespaceScope struct {
+	restricted bool
+	allowed    map[string]struct{}
+}
+
+// allows returns true if the caller can read and write the alert rules of the namespace.
+func (s namespaceScope) allows(namespaceUID string) bool {
+	if !s.restricted {
+		return true
+	}
+	_, ok := s.allowed[namespaceUID]
+	return ok
+}
+
+// check returns ErrPermissionDenied if the caller cannot read or write the alert rules of one of the namespaces.
+func (s namespaceScope) check(namespaceUIDs ...string) error {
+	for _, namespaceUID := range namespaceUIDs {
+		if !s.allows(namespaceUID) {
+			return fmt.Errorf("%w: '%s'", ErrPermissionDenied, namespaceUID)
+		}
+	}
+	return nil
+}
+
+// restrict limits the query to the allowed namespaces. It returns false if the query cannot match
+// any rule of an allowed namespace, since a query without namespaces matches all of them.
+func (s namespaceScope) restrict(query *models.ListAlertRulesQuery) bool {
+	if !s.restricted {
+		return true
+	}
+	if len(query.NamespaceUIDs) == 0 {
+		query.NamespaceUIDs = make([]string, 0, len(s.allowed))
+		for namespaceUID := range s.allowed {
+			query.NamespaceUIDs = append(query.NamespaceUIDs, namespaceUID)
+		}
+		sort.Strings(query.NamespaceUIDs)
+		return len(query.NamespaceUIDs) > 0
+	}
+	namespaceUIDs := make([]string, 0, len(query.NamespaceUIDs))
+	for _, namespaceUID := range query.NamespaceUIDs {
+		if s.allows(namespaceUID) {
+			namespaceUIDs = append(namespaceUIDs, namespaceUID)
+		}
+	}
+	query.NamespaceUIDs = namespaceUIDs
+	return len(namespaceUIDs) > 0
+}
+
+// filter returns the rules of the allowed namespaces.
+func (s namespaceScope) filter(rules []models.AlertRule) []models.AlertRule {
+	if !s.restricted {
+		return rules
+	}
+	result := make([]models.AlertRule, 0, len(rules))
+	for _, rule := range rules {
+		if s.allows(rule.NamespaceUID) {
+			result = append(result, rule)
+		}
+	}
+	return result
+}
+
+// callerNamespaces returns the namespaces that the caller of the context is restricted to. Callers
+// are restricted by granting them ActionAlertingRuleProvisionNamespace with the scopes of the folders
+// they can provision alert rules in, such as the service accounts of the CI pipelines of a team.
+// Callers without the permission, and operations without caller, are not restricted.
+func (service *AlertRuleService) callerNamespaces(ctx context.Context) (namespaceScope, error) {
+	user := callerFromContext(ctx)
+	if user == nil || service.ac == nil || service.ac.IsDisabled() {
+		return namespaceScope{}, nil
+	}
+	permissions, err := service.ac.GetUserPermissions(ctx, user, accesscontrol.Options{})
+	if err != nil {
+		return namespaceScope{}, err
+	}
+	scopes, ok := accesscontrol.GroupScopesByAction(permissions)[accesscontrol.ActionAlertingRuleProvisionNamespace]
+	if !ok {
+		return namespaceScope{}, nil
+	}
+	allowed := make(map[string]struct{}, len(scopes))
+	for _, scope := range scopes {
+		switch {
+		case scope == "*" || scope == dashboards.ScopeFoldersAll || scope == dashboards.ScopeFoldersPrefix+"*":
+			return namespaceScope{}, nil
+		case strings.HasPrefix(scope, dashboards.ScopeFoldersPrefix):
+			allowed[strings.TrimPrefix(scope, dashboards.ScopeFoldersPrefix)] = struct{}{}
+		}
+	}
+	return namespaceScope{restricted: true, allowed: allowed}, nil
+}
+
+// checkNamespaces returns ErrPermissionDenied if the caller of the context cannot read or write the
+// alert rules of one of the namespaces.
+func (service *AlertRuleService) checkNamespaces(ctx context.Context, namespaceUIDs ...string) error {
+	scope, err := service.callerNamespaces(ctx)
+	if err != nil {
+		return err
+	}
+	return scope.check(namespaceUIDs...)
+}
+
+// checkRuleNamespace returns ErrPermissionDenied if the caller of the context cannot write the alert
+// rule because of its namespace. Rules that do not exist are left to the operation.
+func (service *AlertRuleService) checkRuleNamespace(ctx context.Context, orgID int64, ruleUID string) error {
+	scope, err := service.callerNamespaces(ctx)
+	if err != nil || !scope.restricted {
+		return err
+	}
+	query := &models.GetAlertRuleByUIDQuery{OrgID: orgID, UID: ruleUID}
+	if err := service.ruleStore.GetAlertRuleByUID(ctx, query); err != nil {
+		if errors.Is(err, models.ErrAlertRuleNotFound) {
+			return nil
+		}
+		return err
+	}
+	return scope.check(query.Result.NamespaceUID)
+}
+
+// filterNamespaces returns the rules that the caller of the context can read.
+func (service *AlertRuleService) filterNamespaces(ctx context.Context, rules []models.AlertRule) ([]models.AlertRule, error) {
+	scope, err := service.callerNamespaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return scope.filter(rules), nil
+}
@@ -0,0 +1,65 @@
+package provisioning
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	models2 "github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	acmock "github.com/grafana/grafana/pkg/services/accesscontrol/mock"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+func TestAlertRuleServiceNamespaceScope(t *testing.T) {
+	const orgID = int64(71)
+	ruleService := createAlertRuleService(t)
+	ruleService.ac = acmock.New().WithPermissions([]*accesscontrol.Permission{
+		{Action: accesscontrol.ActionAlertingRuleProvisionNamespace, Scope: "folders:uid:team-a"},
+	})
+	teamA := WithCaller(context.Background(), &models2.SignedInUser{OrgId: orgID})
+	inNamespace := func(title, namespaceUID string) models.AlertRule {
+		rule := dummyRule(title, orgID)
+		rule.NamespaceUID = namespaceUID
+		rule.Data[0].RelativeTimeRange.From = models.Duration(time.Minute)
+		return rule
+	}
+	// rules created without caller are not restricted.
+	other, err := ruleService.CreateAlertRule(context.Background(), inNamespace("test#other-team", "team-b"), models.ProvenanceAPI)
+	require.NoError(t, err)
+
+	t.Run("should create rules in allowed namespaces", func(t *testing.T) {
+		_, err := ruleService.CreateAlertRule(teamA, inNamespace("test#own-team", "team-a"), models.ProvenanceAPI)
+		require.NoError(t, err)
+	})
+	t.Run("should reject rules of other namespaces with the namespace", func(t *testing.T) {
+		_, err := ruleService.CreateAlertRule(teamA, inNamespace("test#wrong-team", "team-b"), models.ProvenanceAPI)
+		require.ErrorIs(t, err, ErrPermissionDenied)
+		require.ErrorIs(t, err, ErrAccessDenied)
+		require.Contains(t, err.Error(), "'team-b'")
+
+		_, _, err = ruleService.GetAlertRule(teamA, orgID, other.UID)
+		require.ErrorIs(t, err, ErrPermissionDenied)
+
+		err = ruleService.UpdateAlertGroup(teamA, orgID, "team-b", other.RuleGroup, 2*other.IntervalSeconds, models.ProvenanceNone)
+		require.ErrorIs(t, err, ErrPermissionDenied)
+
+		err = ruleService.DeleteAlertRule(teamA, orgID, other.UID, models.ProvenanceAPI)
+		require.ErrorIs(t, err, ErrPermissionDenied)
+	})
+	t.Run("should filter listings to allowed namespaces", func(t *testing.T) {
+		rules, err := ruleService.ListAlertRules(teamA, orgID, ListAlertRulesOptions{})
+		require.NoError(t, err)
+		require.NotEmpty(t, rules)
+		for _, rule := range rules {
+			require.Equal(t, "team-a", rule.NamespaceUID)
+		}
+	})
+	t.Run("callers without the permission should not be restricted", func(t *testing.T) {
+		ruleService.ac = acmock.New()
+		_, _, err := ruleService.GetAlertRule(teamA, orgID, other.UID)
+		require.NoError(t, err)
+	})
+}
@@ -0,0 +1,32 @@
+package provisioning
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// zeroWidthRunes are invisible code points that make names look identical to names without them.
+var zeroWidthRunes = map[rune]struct{}{
+	'\u200b': {}, // zero width space
+	'\u200c': {}, // zero width non-joiner
+	'\u200d': {}, // zero width joiner
+	'\u2060': {}, // word joiner
+	'\ufeff': {}, // zero width no-break space
+}
+
+// normalizeName returns the name with surrounding whitespace removed and, if the service is configured
+// to, inner whitespace collapsed to single spaces. Names with control characters or zero-width code
+// points are rejected, and the error shows the name with these characters escaped.
+func (service *AlertRuleService) normalizeName(field, name string) (string, error) {
+	for _, r := range name {
+		_, zeroWidth := zeroWidthRunes[r]
+		if zeroWidth || unicode.IsControl(r) {
+			return "", fmt.Errorf("%w: %s %q contains the invalid character %U", ErrValidation, field, name, r)
+		}
+	}
+	if service.cfg.CollapseNameWhitespace {
+		return strings.Join(strings.Fields(name), " "), nil
+	}
+	return strings.TrimSpace(name), nil
+}
@@ -2,25 +2,34 @@ package provisioning
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/alertmanager/pkg/labels"
 
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
 	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/store"
 )
 
 type NotificationPolicyService struct {
 	amStore         AMConfigStore
 	provenanceStore ProvisioningStore
 	xact            TransactionManager
+	policy          ResourcePolicy
 	log             log.Logger
 }
 
-func NewNotificationPolicyService(am AMConfigStore, prov ProvisioningStore, xact TransactionManager, log log.Logger) *NotificationPolicyService {
+func NewNotificationPolicyService(am AMConfigStore, prov ProvisioningStore, xact TransactionManager, policy ResourcePolicy, log log.Logger) *NotificationPolicyService {
 	return &NotificationPolicyService{
 		amStore:         am,
 		provenanceStore: prov,
 		xact:            xact,
+		policy:          policy,
 		log:             log,
 	}
 }
@@ -59,6 +68,9 @@ func (nps *NotificationPolicyService) GetPolicyTree(ctx context.Context, orgID i
 }
 
 func (nps *NotificationPolicyService) UpdatePolicyTree(ctx context.Context, orgID int64, tree definitions.Route, p models.Provenance) error {
+	if err := nps.policy.checkMutation(orgID, ResourceTypeNotificationPolicies, p); err != nil {
+		return err
+	}
 	err := tree.Validate()
 	if err != nil {
 		return fmt.Errorf("%w: %s", ErrValidation, err.Error())
@@ -68,6 +80,125 @@ func (nps *NotificationPolicyService) UpdatePolicyTree(ctx context.Context, orgI
 		return err
 	}
 
+	return nps.savePolicyTree(ctx, orgID, revision, tree, p)
+}
+
+// RoutePath locates a route in the policy tree. Every element of the path selects a child of the
+// route that the previous elements locate, starting at the root route. An element is either the
+// index of the child or matchers like {team="a"} that are equal to the matchers of exactly one child.
+// The empty path locates the root route.
+type RoutePath []string
+
+func (path RoutePath) String() string {
+	return "/" + strings.Join(path, "/")
+}
+
+// AddRoute appends the route to the child routes of the route at the parent path.
+func (nps *NotificationPolicyService) AddRoute(ctx context.Context, orgID int64, parentPath RoutePath, route definitions.Route, p models.Provenance) error {
+	return nps.modifyPolicyTree(ctx, orgID, p, func(tree *definitions.Route) error {
+		parent, err := findRoute(tree, parentPath)
+		if err != nil {
+			return err
+		}
+		parent.Routes = append(parent.Routes, &route)
+		return nil
+	})
+}
+
+// UpdateRoute replaces the route at the path. The child routes of the replaced route are kept
+// if the given route has none.
+func (nps *NotificationPolicyService) UpdateRoute(ctx context.Context, orgID int64, path RoutePath, route definitions.Route, p models.Provenance) error {
+	return nps.modifyPolicyTree(ctx, orgID, p, func(tree *definitions.Route) error {
+		existing, err := findRoute(tree, path)
+		if err != nil {
+			return err
+		}
+		if route.Routes == nil {
+			route.Routes = existing.Routes
+		}
+		route.Provenance = existing.Provenance
+		*existing = route
+		return nil
+	})
+}
+
+// DeleteRoute removes the route at the path from the policy tree. Routes with child routes are
+// only removed together with their children if cascade is set. The root route cannot be removed.
+func (nps *NotificationPolicyService) DeleteRoute(ctx context.Context, orgID int64, path RoutePath, cascade bool, p models.Provenance) error {
+	if len(path) == 0 {
+		return fmt.Errorf("%w: the root route cannot be deleted", ErrValidation)
+	}
+	return nps.modifyPolicyTree(ctx, orgID, p, func(tree *definitions.Route) error {
+		parent, err := findRoute(tree, path[:len(path)-1])
+		if err != nil {
+			return err
+		}
+		idx, err := findChildRoute(parent, path[len(path)-1])
+		if err != nil {
+			return fmt.Errorf("route %s: %w", path, err)
+		}
+		if len(parent.Routes[idx].Routes) > 0 && !cascade {
+			return fmt.Errorf("%w: route %s has %d child routes, delete them too to delete the route", ErrValidation, path, len(parent.Routes[idx].Routes))
+		}
+		parent.Routes = append(parent.Routes[:idx], parent.Routes[idx+1:]...)
+		return nil
+	})
+}
+
+// SetRuleRoute sets the route for the alerts of the rule with the given UID. The route replaces the
+// child route of the root route that matches exactly the rule UID label of the rule. Otherwise it is
+// added as the first child route so that it takes precedence over the other routes. The provenance
+// of the policy tree is kept.
+func (nps *NotificationPolicyService) SetRuleRoute(ctx context.Context, orgID int64, ruleUID string, route definitions.Route) error {
+	if err := nps.policy.checkWritable(ResourceTypeNotificationPolicies, models.ProvenanceNone); err != nil {
+		return err
+	}
+	provenance, err := nps.provenanceStore.GetProvenance(ctx, &definitions.Route{}, orgID)
+	if err != nil {
+		return err
+	}
+	matchers := []string{(&labels.Matcher{Type: labels.MatchEqual, Name: models.RuleUIDLabel, Value: ruleUID}).String()}
+	return nps.modifyPolicyTree(ctx, orgID, provenance, func(tree *definitions.Route) error {
+		for i, child := range tree.Routes {
+			if equalMatcherStrings(matchers, routeMatcherStrings(child)) {
+				tree.Routes[i] = &route
+				return nil
+			}
+		}
+		tree.Routes = append([]*definitions.Route{&route}, tree.Routes...)
+		return nil
+	})
+}
+
+// modifyPolicyTree applies the change to the stored policy tree and stores the result if it is valid.
+// The tree is only stored if it was not changed since it was read, so that concurrent changes fail
+// with ErrPolicyTreeConflict instead of overwriting each other.
+func (nps *NotificationPolicyService) modifyPolicyTree(ctx context.Context, orgID int64, p models.Provenance, change func(tree *definitions.Route) error) error {
+	if err := nps.policy.checkMutation(orgID, ResourceTypeNotificationPolicies, p); err != nil {
+		return err
+	}
+	revision, err := getLastConfiguration(ctx, orgID, nps.amStore)
+	if err != nil {
+		return err
+	}
+	tree := revision.cfg.AlertmanagerConfig.Config.Route
+	if tree == nil {
+		return fmt.Errorf("no route present in current alertmanager config")
+	}
+	if err := change(tree); err != nil {
+		return err
+	}
+	if err := tree.Validate(); err != nil {
+		return fmt.Errorf("%w: %s", ErrValidation, err.Error())
+	}
+	err = nps.savePolicyTree(ctx, orgID, revision, *tree, p)
+	if errors.Is(err, store.ErrVersionLockedObjectNotFound) {
+		return fmt.Errorf("%w: %s", ErrPolicyTreeConflict, err)
+	}
+	return err
+}
+
+func (nps *NotificationPolicyService) savePolicyTree(ctx context.Context, orgID int64, revision *cfgRevision, tree definitions.Route, p models.Provenance) error {
 	revision.cfg.AlertmanagerConfig.Config.Route = &tree
 
 	serialized, err := serializeAlertmanagerConfig(*revision.cfg)
@@ -81,20 +212,89 @@ func (nps *NotificationPolicyService) UpdatePolicyTree(ctx context.Context, orgI
 		Default:                   false,
 		OrgID:                     orgID,
 	}
-	err = nps.xact.InTransaction(ctx, func(ctx context.Context) error {
+	return nps.xact.InTransaction(ctx, func(ctx context.Context) error {
 		err = nps.amStore.UpdateAlertmanagerConfiguration(ctx, &cmd)
 		if err != nil {
 			return err
 		}
-		err = nps.provenanceStore.SetProvenance(ctx, &tree, orgID, p)
+		return nps.provenanceStore.SetProvenance(ctx, &tree, orgID, p)
+	})
+}
+
+// findRoute returns the route of the tree at the path.
+func findRoute(tree *definitions.Route, path RoutePath) (*definitions.Route, error) {
+	route := tree
+	for i, element := range path {
+		idx, err := findChildRoute(route, element)
 		if err != nil {
-			return err
+			return nil, fmt.Errorf("route %s: %w", path[:i+1], err)
 		}
-		return nil
-	})
+		route = route.Routes[idx]
+	}
+	return route, nil
+}
+
+// findChildRoute returns the index of the child route of the route that the path element selects.
+// It returns ErrRouteNotFound if the element does not select exactly one child route.
+func findChildRoute(route *definitions.Route, element string) (int, error) {
+	if idx, err := strconv.Atoi(element); err == nil {
+		if idx < 0 || idx >= len(route.Routes) {
+			return 0, fmt.Errorf("%w: the parent route has %d child routes", ErrRouteNotFound, len(route.Routes))
+		}
+		return idx, nil
+	}
+	matchers, err := labels.ParseMatchers(element)
 	if err != nil {
-		return err
+		return 0, fmt.Errorf("%w: invalid matchers: %s", ErrValidation, err)
 	}
+	expected := matcherStrings(matchers)
+	found := -1
+	for i, child := range route.Routes {
+		if !equalMatcherStrings(expected, routeMatcherStrings(child)) {
+			continue
+		}
+		if found >= 0 {
+			return 0, fmt.Errorf("%w: more than one child route has the matchers", ErrRouteNotFound)
+		}
+		found = i
+	}
+	if found < 0 {
+		return 0, fmt.Errorf("%w: no child route has the matchers", ErrRouteNotFound)
+	}
+	return found, nil
+}
 
-	return nil
+// routeMatcherStrings returns the sorted matchers of the route in all their notations.
+func routeMatcherStrings(route *definitions.Route) []string {
+	result := matcherStrings(labels.Matchers(route.ObjectMatchers))
+	result = append(result, matcherStrings(labels.Matchers(route.Matchers))...)
+	for name, value := range route.Match {
+		result = append(result, (&labels.Matcher{Type: labels.MatchEqual, Name: name, Value: value}).String())
+	}
+	for name, value := range route.MatchRE {
+		result = append(result, (&labels.Matcher{Type: labels.MatchRegexp, Name: name, Value: value.String()}).String())
+	}
+	sort.Strings(result)
+	return result
+}
+
+func matcherStrings(matchers labels.Matchers) []string {
+	result := make([]string, 0, len(matchers))
+	for _, matcher := range matchers {
+		result = append(result, matcher.String())
+	}
+	sort.Strings(result)
+	return result
+}
+
+func equalMatcherStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
 }
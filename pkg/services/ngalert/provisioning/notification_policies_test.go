@@ -7,6 +7,7 @@ import (
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
 	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/prometheus/alertmanager/pkg/labels"
 	"github.com/prometheus/common/model"
 	"github.com/stretchr/testify/require"
 )
@@ -99,3 +100,113 @@ func createTestRoutingTree() definitions.Route {
 		Receiver: "a new receiver",
 	}
 }
+
+func TestNotificationPolicyServiceRoutes(t *testing.T) {
+	route := func(t *testing.T, receiver string, matchers ...string) definitions.Route {
+		t.Helper()
+		result := definitions.Route{Receiver: receiver}
+		for _, m := range matchers {
+			matcher, err := labels.ParseMatcher(m)
+			require.NoError(t, err)
+			result.ObjectMatchers = append(result.ObjectMatchers, matcher)
+		}
+		return result
+	}
+	// The default tree has a single child route with the matcher a="b".
+	receivers := func(t *testing.T, sut *NotificationPolicyService, path RoutePath) []string {
+		t.Helper()
+		tree, err := sut.GetPolicyTree(context.Background(), 1)
+		require.NoError(t, err)
+		parent, err := findRoute(&tree, path)
+		require.NoError(t, err)
+		result := make([]string, 0, len(parent.Routes))
+		for _, child := range parent.Routes {
+			result = append(result, child.Receiver)
+		}
+		return result
+	}
+
+	t.Run("should add routes to the located parent route", func(t *testing.T) {
+		sut := createNotificationPolicyServiceSut()
+		err := sut.AddRoute(context.Background(), 1, nil, route(t, "a new receiver", `team="a"`), models.ProvenanceAPI)
+		require.NoError(t, err)
+		err = sut.AddRoute(context.Background(), 1, RoutePath{`{team="a"}`}, route(t, "grafana-default-email", `severity="critical"`), models.ProvenanceAPI)
+		require.NoError(t, err)
+
+		require.Equal(t, []string{"grafana-default-email", "a new receiver"}, receivers(t, sut, nil))
+		require.Equal(t, []string{"grafana-default-email"}, receivers(t, sut, RoutePath{"1"}))
+	})
+
+	t.Run("should update the located route and keep its child routes", func(t *testing.T) {
+		sut := createNotificationPolicyServiceSut()
+		require.NoError(t, sut.AddRoute(context.Background(), 1, RoutePath{"0"}, route(t, "grafana-default-email", `c="d"`), models.ProvenanceAPI))
+
+		err := sut.UpdateRoute(context.Background(), 1, RoutePath{`{a="b"}`}, route(t, "a new receiver", `a="b"`), models.ProvenanceAPI)
+		require.NoError(t, err)
+
+		require.Equal(t, []string{"a new receiver"}, receivers(t, sut, nil))
+		require.Equal(t, []string{"grafana-default-email"}, receivers(t, sut, RoutePath{"0"}))
+	})
+
+	t.Run("should only delete routes with child routes if cascading", func(t *testing.T) {
+		sut := createNotificationPolicyServiceSut()
+		require.NoError(t, sut.AddRoute(context.Background(), 1, RoutePath{"0"}, route(t, "grafana-default-email", `c="d"`), models.ProvenanceAPI))
+
+		err := sut.DeleteRoute(context.Background(), 1, RoutePath{`{a="b"}`}, false, models.ProvenanceAPI)
+		require.ErrorIs(t, err, ErrValidation)
+		require.Len(t, receivers(t, sut, nil), 1)
+
+		err = sut.DeleteRoute(context.Background(), 1, RoutePath{`{a="b"}`}, true, models.ProvenanceAPI)
+		require.NoError(t, err)
+		require.Empty(t, receivers(t, sut, nil))
+	})
+
+	t.Run("should not delete the root route", func(t *testing.T) {
+		sut := createNotificationPolicyServiceSut()
+		err := sut.DeleteRoute(context.Background(), 1, nil, true, models.ProvenanceAPI)
+		require.ErrorIs(t, err, ErrValidation)
+	})
+
+	t.Run("should fail for paths that do not locate a route", func(t *testing.T) {
+		sut := createNotificationPolicyServiceSut()
+		for _, path := range []RoutePath{{"1"}, {"-1"}, {`{team="a"}`}, {"0", "0"}} {
+			err := sut.UpdateRoute(context.Background(), 1, path, route(t, "a new receiver"), models.ProvenanceAPI)
+			require.ErrorIs(t, err, ErrRouteNotFound, path.String())
+		}
+	})
+
+	t.Run("should reject changes that make the tree invalid", func(t *testing.T) {
+		sut := createNotificationPolicyServiceSut()
+		err := sut.UpdateRoute(context.Background(), 1, nil, route(t, ""), models.ProvenanceAPI)
+		require.ErrorIs(t, err, ErrValidation)
+	})
+
+	t.Run("should reject paths with invalid matchers", func(t *testing.T) {
+		sut := createNotificationPolicyServiceSut()
+		err := sut.DeleteRoute(context.Background(), 1, RoutePath{`{a}`}, false, models.ProvenanceAPI)
+		require.ErrorIs(t, err, ErrValidation)
+	})
+
+	t.Run("should fail if the tree was changed concurrently", func(t *testing.T) {
+		sut := createNotificationPolicyServiceSut()
+		sut.amStore = &concurrentlyChangedAMConfigStore{fakeAMConfigStore: sut.amStore.(*fakeAMConfigStore)}
+
+		err := sut.AddRoute(context.Background(), 1, nil, route(t, "a new receiver", `team="a"`), models.ProvenanceAPI)
+		require.ErrorIs(t, err, ErrPolicyTreeConflict)
+		require.Equal(t, []string{"grafana-default-email"}, receivers(t, sut, nil))
+	})
+}
+
+// concurrentlyChangedAMConfigStore changes the stored configuration whenever it is read,
+// as if another writer saved it right after.
+type concurrentlyChangedAMConfigStore struct {
+	*fakeAMConfigStore
+}
+
+func (f *concurrentlyChangedAMConfigStore) GetLatestAlertmanagerConfiguration(ctx context.Context, query *models.GetLatestAlertmanagerConfigurationQuery) error {
+	if err := f.fakeAMConfigStore.GetLatestAlertmanagerConfiguration(ctx, query); err != nil {
+		return err
+	}
+	f.config.AlertmanagerConfiguration += " "
+	return nil
+}
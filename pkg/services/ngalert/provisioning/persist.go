@@ -2,8 +2,10 @@ package provisioning
 
 import (
 	"context"
+	"time"
 
 	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/quota"
 )
 
 // AMStore is a store of Alertmanager configurations.
@@ -26,3 +28,63 @@ type ProvisioningStore interface {
 type TransactionManager interface {
 	InTransaction(ctx context.Context, work func(ctx context.Context) error) error
 }
+
+// IntervalLimitStore is a store of the limits of rule group intervals.
+type IntervalLimitStore interface {
+	GetRuleGroupIntervalLimits(ctx context.Context, orgID int64) (*models.RuleGroupIntervalLimits, error)
+}
+
+// LibraryQueryStore is a store of library queries.
+type LibraryQueryStore interface {
+	GetLibraryQuery(ctx context.Context, orgID int64, uid string) (*models.LibraryQuery, error)
+	SaveLibraryQuery(ctx context.Context, query *models.LibraryQuery) error
+	DeleteLibraryQuery(ctx context.Context, orgID int64, uid string) error
+}
+
+// RuleGroupReplaceJournalStore is a store of the progress of rule group replaces that are applied in batches.
+type RuleGroupReplaceJournalStore interface {
+	GetRuleGroupReplaceJournal(ctx context.Context, orgID int64, namespaceUID, group string) (*models.RuleGroupReplaceJournal, error)
+	SaveRuleGroupReplaceJournal(ctx context.Context, journal *models.RuleGroupReplaceJournal) error
+	DeleteRuleGroupReplaceJournal(ctx context.Context, id int64) error
+}
+
+// RuleGroupDraftStore is a store of drafts of rule groups.
+type RuleGroupDraftStore interface {
+	GetRuleGroupDraft(ctx context.Context, orgID int64, namespaceUID, group string) (*models.RuleGroupDraft, error)
+	ListRuleGroupDrafts(ctx context.Context, orgID int64) ([]*models.RuleGroupDraft, error)
+	SaveRuleGroupDraft(ctx context.Context, draft *models.RuleGroupDraft) error
+	DeleteRuleGroupDraft(ctx context.Context, id int64) error
+	DeleteRuleGroupDraftsUpdatedBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// ProvisioningJobStore is a store of the jobs that import and export alert rules in the background.
+type ProvisioningJobStore interface {
+	GetProvisioningJob(ctx context.Context, orgID int64, uid string) (*models.ProvisioningJob, error)
+	ListProvisioningJobs(ctx context.Context, statuses ...models.ProvisioningJobStatus) ([]*models.ProvisioningJob, error)
+	InsertProvisioningJob(ctx context.Context, job *models.ProvisioningJob) error
+	// UpdateProvisioningJob returns false if the job was updated since it was read.
+	UpdateProvisioningJob(ctx context.Context, job *models.ProvisioningJob) (bool, error)
+	DeleteProvisioningJobsUpdatedBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// StateSummaryStore summarizes the persisted alert instances of alert rules.
+type StateSummaryStore interface {
+	GetAlertRuleStateSummaries(ctx context.Context, orgID int64, ruleUIDs ...string) (map[string]*models.AlertRuleStateSummary, error)
+}
+
+// QuotaChecker checks the limits of the plan of an organization.
+type QuotaChecker interface {
+	// CheckAlertRuleQuota returns an error that wraps ErrQuotaReached if the organization cannot have more alert rules.
+	CheckAlertRuleQuota(ctx context.Context, orgID int64) error
+}
+
+// QuotaService checks the quotas that limit the number of resources, such as alert rules.
+type QuotaService interface {
+	// CheckQuotaReachedFor returns true if the quota of the target does not allow count more of it.
+	CheckQuotaReachedFor(ctx context.Context, target string, scopeParams *quota.ScopeParameters, count int64) (bool, error)
+}
+
+// EvaluationDurationProvider reports how long the last evaluations of alert rules took.
+type EvaluationDurationProvider interface {
+	GetEvaluationDurations(orgID int64, ruleUIDs ...string) map[string]time.Duration
+}
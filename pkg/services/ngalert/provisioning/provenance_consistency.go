@@ -0,0 +1,85 @@
+package provisioning
+
+import (
+	"context"
+	"sort"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+// ProvenanceDiscrepancyKind categorizes the discrepancies between the provenance store and the rule store.
+type ProvenanceDiscrepancyKind string
+
+const (
+	// ProvenanceDiscrepancyOrphan is a provenance record of an alert rule that does not exist.
+	ProvenanceDiscrepancyOrphan ProvenanceDiscrepancyKind = "orphan"
+	// ProvenanceDiscrepancyMissing is an alert rule without a provenance record. Such rules are
+	// treated as not provisioned.
+	ProvenanceDiscrepancyMissing ProvenanceDiscrepancyKind = "missing"
+)
+
+// ProvenanceDiscrepancy is a single inconsistency between the provenance store and the rule store.
+type ProvenanceDiscrepancy struct {
+	Kind    ProvenanceDiscrepancyKind
+	RuleUID string
+	// Provenance is the stored provenance of orphans and ProvenanceNone for rules without a record.
+	Provenance models.Provenance
+}
+
+// ConsistencyReport lists the discrepancies between the provenance store and the rule store of an
+// organization, ordered by kind and rule UID.
+type ConsistencyReport struct {
+	OrgID         int64
+	Discrepancies []ProvenanceDiscrepancy
+}
+
+// Consistent returns true if the report has no discrepancies.
+func (r ConsistencyReport) Consistent() bool {
+	return len(r.Discrepancies) == 0
+}
+
+// VerifyProvenanceConsistency compares the provenance records of the alert rules of the organization
+// with the stored alert rules. It reports provenance records without a matching rule and rules
+// without a provenance record. The check is read-only, it does not repair any discrepancy.
+func (service *AlertRuleService) VerifyProvenanceConsistency(ctx context.Context, orgID int64) (_ ConsistencyReport, err error) {
+	defer wrapServiceError(&err)
+	ctx, cancel := withTimeout(ctx, service.cfg.OperationTimeouts.List)
+	defer cancel()
+	report := ConsistencyReport{OrgID: orgID}
+	provenances, err := service.provenanceStore.GetProvenances(ctx, orgID, (&models.AlertRule{}).ResourceType())
+	if err != nil {
+		return report, err
+	}
+	query := &models.ListAlertRulesQuery{OrgID: orgID}
+	if err := service.ruleStore.ListAlertRules(ctx, query); err != nil {
+		return report, err
+	}
+	rules := make(map[string]struct{}, len(query.Result))
+	for _, rule := range query.Result {
+		rules[rule.UID] = struct{}{}
+		if _, ok := provenances[rule.UID]; !ok {
+			report.Discrepancies = append(report.Discrepancies, ProvenanceDiscrepancy{
+				Kind:       ProvenanceDiscrepancyMissing,
+				RuleUID:    rule.UID,
+				Provenance: models.ProvenanceNone,
+			})
+		}
+	}
+	for uid, provenance := range provenances {
+		if _, ok := rules[uid]; !ok {
+			report.Discrepancies = append(report.Discrepancies, ProvenanceDiscrepancy{
+				Kind:       ProvenanceDiscrepancyOrphan,
+				RuleUID:    uid,
+				Provenance: provenance,
+			})
+		}
+	}
+	sort.Slice(report.Discrepancies, func(i, j int) bool {
+		a, b := report.Discrepancies[i], report.Discrepancies[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.RuleUID < b.RuleUID
+	})
+	return report, nil
+}
@@ -0,0 +1,56 @@
+package provisioning
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+func TestAlertRuleServiceVerifyProvenanceConsistency(t *testing.T) {
+	ruleService := createAlertRuleService(t)
+	var orgID int64 = 1
+
+	consistent, err := ruleService.CreateAlertRule(context.Background(), dummyRule("test#consistent", orgID), models.ProvenanceAPI)
+	require.NoError(t, err)
+
+	t.Run("should not report anything if the stores agree", func(t *testing.T) {
+		report, err := ruleService.VerifyProvenanceConsistency(context.Background(), orgID)
+		require.NoError(t, err)
+		require.True(t, report.Consistent())
+	})
+
+	missing, err := ruleService.CreateAlertRule(context.Background(), dummyRule("test#missing", orgID), models.ProvenanceFile)
+	require.NoError(t, err)
+	require.NoError(t, ruleService.provenanceStore.DeleteProvenance(context.Background(), &missing, orgID))
+	orphan := &models.AlertRule{UID: "deleted-rule", OrgID: orgID}
+	require.NoError(t, ruleService.provenanceStore.SetProvenance(context.Background(), orphan, orgID, models.ProvenanceFile))
+
+	t.Run("should report orphans and rules without provenance", func(t *testing.T) {
+		report, err := ruleService.VerifyProvenanceConsistency(context.Background(), orgID)
+		require.NoError(t, err)
+		require.False(t, report.Consistent())
+		require.Equal(t, ConsistencyReport{
+			OrgID: orgID,
+			Discrepancies: []ProvenanceDiscrepancy{
+				{Kind: ProvenanceDiscrepancyMissing, RuleUID: missing.UID, Provenance: models.ProvenanceNone},
+				{Kind: ProvenanceDiscrepancyOrphan, RuleUID: orphan.UID, Provenance: models.ProvenanceFile},
+			},
+		}, report)
+	})
+	t.Run("should not change the stores", func(t *testing.T) {
+		_, provenance, err := ruleService.GetAlertRule(context.Background(), orgID, consistent.UID)
+		require.NoError(t, err)
+		require.Equal(t, models.ProvenanceAPI, provenance)
+		report, err := ruleService.VerifyProvenanceConsistency(context.Background(), orgID)
+		require.NoError(t, err)
+		require.Len(t, report.Discrepancies, 2)
+	})
+	t.Run("should only check the organization", func(t *testing.T) {
+		report, err := ruleService.VerifyProvenanceConsistency(context.Background(), orgID+1)
+		require.NoError(t, err)
+		require.True(t, report.Consistent())
+	})
+}
@@ -0,0 +1,470 @@
+package provisioning
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+// errJobTakenOver stops a job whose update failed because the job was canceled or claimed by another worker.
+var errJobTakenOver = errors.New("provisioning job was updated by another worker")
+
+// ProvisioningJobConfig configures how the jobs of an instance are run.
+type ProvisioningJobConfig struct {
+	// MaxConcurrentJobs is the number of jobs the instance runs at once. Further jobs stay pending.
+	MaxConcurrentJobs int
+	// Retention is how long finished jobs and the archives of exports are kept.
+	Retention time.Duration
+	// PollInterval is how often pending jobs are looked for, e.g. the jobs started by other instances.
+	PollInterval time.Duration
+	// StaleAfter is the time after its last update after which a running job is considered abandoned,
+	// e.g. because its instance stopped, and is resumed by any instance.
+	StaleAfter time.Duration
+}
+
+// DefaultProvisioningJobConfig returns the configuration of the jobs without settings.
+func DefaultProvisioningJobConfig() ProvisioningJobConfig {
+	return ProvisioningJobConfig{
+		MaxConcurrentJobs: 2,
+		Retention:         24 * time.Hour,
+		PollInterval:      5 * time.Second,
+		StaleAfter:        5 * time.Minute,
+	}
+}
+
+// importJobInput is the persisted input of an import job.
+type importJobInput struct {
+	Groups  []definitions.AlertRuleGroupExport `json:"groups"`
+	Options ImportRuleGroupsOptions            `json:"options"`
+}
+
+// ProvisioningJobReport describes the state and the progress of a job.
+type ProvisioningJobReport struct {
+	UID    string
+	Type   models.ProvisioningJobType
+	Status models.ProvisioningJobStatus
+	// Processed is the number of items of the job that were processed, e.g. the rule groups of an
+	// import. Failed items are counted as well.
+	Processed int
+	Total     int
+	Errors    []models.ProvisioningJobItemError
+	// Message describes why the job failed.
+	Message string
+	// Import is the report of the groups that an import job processed so far.
+	Import  *ImportReport
+	Created time.Time
+	Updated time.Time
+}
+
+// ProvisioningJobService imports and exports the alert rules of organizations in the background. The
+// progress of every job is persisted, so that it can be reported by all instances and a job that was
+// interrupted, e.g. by a restart, is resumed by any instance. Imports resume with the first rule group
+// that was not processed, exports start over.
+type ProvisioningJobService struct {
+	rules *AlertRuleService
+	store ProvisioningJobStore
+	cfg   ProvisioningJobConfig
+	log   log.Logger
+
+	// slots limits the number of jobs that run at once.
+	slots chan struct{}
+	// wake makes the worker look for pending jobs before the next poll.
+	wake chan struct{}
+	wg   sync.WaitGroup
+
+	mtx sync.Mutex
+	// running cancels the jobs of the instance by ID.
+	running map[int64]context.CancelFunc
+}
+
+func NewProvisioningJobService(rules *AlertRuleService, store ProvisioningJobStore, cfg ProvisioningJobConfig, log log.Logger) *ProvisioningJobService {
+	defaults := DefaultProvisioningJobConfig()
+	if cfg.MaxConcurrentJobs <= 0 {
+		cfg.MaxConcurrentJobs = defaults.MaxConcurrentJobs
+	}
+	if cfg.Retention <= 0 {
+		cfg.Retention = defaults.Retention
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = defaults.PollInterval
+	}
+	if cfg.StaleAfter <= 0 {
+		cfg.StaleAfter = defaults.StaleAfter
+	}
+	return &ProvisioningJobService{
+		rules:   rules,
+		store:   store,
+		cfg:     cfg,
+		log:     log,
+		slots:   make(chan struct{}, cfg.MaxConcurrentJobs),
+		wake:    make(chan struct{}, 1),
+		running: make(map[int64]context.CancelFunc),
+	}
+}
+
+// StartImportJob starts a job that imports the rule groups into the organization like
+// ImportRuleGroups, one group at a time, and returns the UID of the job. A group that fails is
+// recorded as an error of the job and does not stop the import of the other groups.
+func (service *ProvisioningJobService) StartImportJob(ctx context.Context, orgID int64, groups []definitions.AlertRuleGroupExport, opts ImportRuleGroupsOptions) (_ string, err error) {
+	defer wrapServiceError(&err)
+	if err := service.rules.policy.checkWritable(ResourceTypeAlertRules, opts.Provenance); err != nil {
+		return "", err
+	}
+	// the job runs without the caller, so the folders of the caller are checked before it is started.
+	folderUIDs := make([]string, 0, len(groups))
+	for _, group := range groups {
+		folderUIDs = append(folderUIDs, group.Folder)
+	}
+	if err := service.rules.checkNamespaces(ctx, folderUIDs...); err != nil {
+		return "", err
+	}
+	input, err := json.Marshal(importJobInput{Groups: groups, Options: opts})
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize the rule groups of the import: %w", err)
+	}
+	return service.start(ctx, &models.ProvisioningJob{
+		OrgID: orgID,
+		Type:  models.ProvisioningJobTypeImport,
+		Input: string(input),
+		Total: len(groups),
+	})
+}
+
+// StartExportJob starts a job that writes the archive of ExportAllRuleGroups, and returns the UID of
+// the job. The archive is fetched with GetExportJobResult once the job is completed.
+func (service *ProvisioningJobService) StartExportJob(ctx context.Context, orgID int64, opts ExportOptions) (_ string, err error) {
+	defer wrapServiceError(&err)
+	if err := opts.Format.validate(); err != nil {
+		return "", err
+	}
+	// the job runs without the caller, so the export is limited to the folders of the caller.
+	scope, err := service.rules.callerNamespaces(ctx)
+	if err != nil {
+		return "", err
+	}
+	query := &models.ListAlertRulesQuery{NamespaceUIDs: opts.FolderUIDs}
+	if !scope.restrict(query) {
+		return "", fmt.Errorf("%w: no folder can be exported", ErrPermissionDenied)
+	}
+	opts.FolderUIDs = query.NamespaceUIDs
+	input, err := json.Marshal(opts)
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize the options of the export: %w", err)
+	}
+	return service.start(ctx, &models.ProvisioningJob{
+		OrgID: orgID,
+		Type:  models.ProvisioningJobTypeExport,
+		Input: string(input),
+	})
+}
+
+func (service *ProvisioningJobService) start(ctx context.Context, job *models.ProvisioningJob) (string, error) {
+	job.UID = util.GenerateShortUID()
+	job.Status = models.ProvisioningJobPending
+	if err := service.store.InsertProvisioningJob(ctx, job); err != nil {
+		return "", err
+	}
+	select {
+	case service.wake <- struct{}{}:
+	default:
+	}
+	return job.UID, nil
+}
+
+// GetJobStatus returns the state and the progress of the job of the organization.
+func (service *ProvisioningJobService) GetJobStatus(ctx context.Context, orgID int64, uid string) (_ ProvisioningJobReport, err error) {
+	defer wrapServiceError(&err)
+	job, err := service.getJob(ctx, orgID, uid)
+	if err != nil {
+		return ProvisioningJobReport{}, err
+	}
+	errs, err := job.GetErrors()
+	if err != nil {
+		return ProvisioningJobReport{}, err
+	}
+	report := ProvisioningJobReport{
+		UID:       job.UID,
+		Type:      job.Type,
+		Status:    job.Status,
+		Processed: job.Processed,
+		Total:     job.Total,
+		Errors:    errs,
+		Message:   job.Message,
+		Created:   job.Created,
+		Updated:   job.Updated,
+	}
+	if job.Type == models.ProvisioningJobTypeImport {
+		if report.Import, err = importJobReport(job); err != nil {
+			return ProvisioningJobReport{}, err
+		}
+	}
+	return report, nil
+}
+
+// GetExportJobResult returns the archive of the completed export job of the organization.
+func (service *ProvisioningJobService) GetExportJobResult(ctx context.Context, orgID int64, uid string) (_ []byte, err error) {
+	defer wrapServiceError(&err)
+	job, err := service.getJob(ctx, orgID, uid)
+	if err != nil {
+		return nil, err
+	}
+	if job.Type != models.ProvisioningJobTypeExport {
+		return nil, fmt.Errorf("%w: job '%s' is not an export", ErrValidation, uid)
+	}
+	if job.Status != models.ProvisioningJobCompleted {
+		return nil, fmt.Errorf("%w: job '%s' is %s", ErrJobNotCompleted, uid, job.Status)
+	}
+	return job.Result, nil
+}
+
+// CancelJob cancels the pending or running job of the organization. The groups that an import
+// job already imported are kept.
+func (service *ProvisioningJobService) CancelJob(ctx context.Context, orgID int64, uid string) (err error) {
+	defer wrapServiceError(&err)
+	for {
+		job, err := service.getJob(ctx, orgID, uid)
+		if err != nil {
+			return err
+		}
+		if job.Status.Finished() {
+			return fmt.Errorf("%w: job '%s' is %s", ErrJobFinished, uid, job.Status)
+		}
+		job.Status = models.ProvisioningJobCanceled
+		updated, err := service.store.UpdateProvisioningJob(ctx, job)
+		if err != nil {
+			return err
+		}
+		if !updated {
+			// the worker recorded progress in the meantime.
+			continue
+		}
+		// a job of another instance stops when it fails to record its next progress.
+		service.mtx.Lock()
+		if cancel, ok := service.running[job.ID]; ok {
+			cancel()
+		}
+		service.mtx.Unlock()
+		return nil
+	}
+}
+
+func (service *ProvisioningJobService) getJob(ctx context.Context, orgID int64, uid string) (*models.ProvisioningJob, error) {
+	job, err := service.store.GetProvisioningJob(ctx, orgID, uid)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil {
+		return nil, fmt.Errorf("%w: '%s'", ErrJobNotFound, uid)
+	}
+	return job, nil
+}
+
+// DeleteExpiredJobs deletes the jobs that finished longer ago than the configured retention, and
+// returns how many were deleted.
+func (service *ProvisioningJobService) DeleteExpiredJobs(ctx context.Context) (int64, error) {
+	return service.store.DeleteProvisioningJobsUpdatedBefore(ctx, time.Now().Add(-service.cfg.Retention))
+}
+
+// Run runs pending and abandoned jobs, and deletes expired jobs every cleanupInterval, until the
+// context is done. Jobs that are running when the context is done are left as they are and resumed later.
+func (service *ProvisioningJobService) Run(ctx context.Context, cleanupInterval time.Duration) error {
+	poll := time.NewTicker(service.cfg.PollInterval)
+	defer poll.Stop()
+	cleanup := time.NewTicker(cleanupInterval)
+	defer cleanup.Stop()
+	defer service.wg.Wait()
+	for {
+		service.runPendingJobs(ctx)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-poll.C:
+		case <-service.wake:
+		case <-cleanup.C:
+			deleted, err := service.DeleteExpiredJobs(ctx)
+			if err != nil {
+				service.log.Error("failed to delete expired provisioning jobs", "err", err)
+				continue
+			}
+			if deleted > 0 {
+				service.log.Info("deleted expired provisioning jobs", "count", deleted)
+			}
+		}
+	}
+}
+
+// runPendingJobs claims pending and abandoned jobs until all slots of the instance are taken.
+func (service *ProvisioningJobService) runPendingJobs(ctx context.Context) {
+	jobs, err := service.store.ListProvisioningJobs(ctx, models.ProvisioningJobPending, models.ProvisioningJobRunning)
+	if err != nil {
+		service.log.Error("failed to list provisioning jobs", "err", err)
+		return
+	}
+	staleBefore := time.Now().Add(-service.cfg.StaleAfter)
+	for _, job := range jobs {
+		if job.Status == models.ProvisioningJobRunning && job.Updated.After(staleBefore) {
+			continue
+		}
+		service.mtx.Lock()
+		_, ok := service.running[job.ID]
+		service.mtx.Unlock()
+		if ok {
+			continue
+		}
+		select {
+		case service.slots <- struct{}{}:
+		default:
+			return
+		}
+		// the update fails if another instance claimed the job first.
+		resumed := job.Status == models.ProvisioningJobRunning
+		job.Status = models.ProvisioningJobRunning
+		claimed, err := service.store.UpdateProvisioningJob(ctx, job)
+		if err != nil || !claimed {
+			<-service.slots
+			if err != nil {
+				service.log.Error("failed to claim provisioning job", "job", job.UID, "err", err)
+			}
+			continue
+		}
+		jobCtx, cancel := context.WithCancel(ctx)
+		service.mtx.Lock()
+		service.running[job.ID] = cancel
+		service.mtx.Unlock()
+		service.wg.Add(1)
+		go func(job *models.ProvisioningJob) {
+			defer func() {
+				service.mtx.Lock()
+				delete(service.running, job.ID)
+				service.mtx.Unlock()
+				cancel()
+				<-service.slots
+				service.wg.Done()
+			}()
+			service.runJob(jobCtx, job, resumed)
+		}(job)
+	}
+}
+
+func (service *ProvisioningJobService) runJob(ctx context.Context, job *models.ProvisioningJob, resumed bool) {
+	logger := service.log.New("job", job.UID, "org", job.OrgID, "type", job.Type)
+	logger.Info("running provisioning job", "resumed", resumed, "processed", job.Processed)
+	var err error
+	switch job.Type {
+	case models.ProvisioningJobTypeImport:
+		err = service.runImport(ctx, job)
+	case models.ProvisioningJobTypeExport:
+		err = service.runExport(ctx, job)
+	default:
+		err = fmt.Errorf("unknown type of provisioning job '%s'", job.Type)
+	}
+	switch {
+	case errors.Is(err, errJobTakenOver) || ctx.Err() != nil:
+		// the job was canceled, or is resumed after the restart of the instance.
+		logger.Info("provisioning job stopped", "processed", job.Processed)
+		return
+	case err != nil:
+		logger.Error("provisioning job failed", "err", err)
+		job.Status = models.ProvisioningJobFailed
+		job.Message = err.Error()
+	default:
+		logger.Info("provisioning job completed", "processed", job.Processed)
+		job.Status = models.ProvisioningJobCompleted
+	}
+	if _, err := service.store.UpdateProvisioningJob(ctx, job); err != nil {
+		logger.Error("failed to save provisioning job", "err", err)
+	}
+}
+
+// runImport imports the groups that were not processed yet one at a time, and records the progress
+// after every group.
+func (service *ProvisioningJobService) runImport(ctx context.Context, job *models.ProvisioningJob) error {
+	var input importJobInput
+	if err := json.Unmarshal([]byte(job.Input), &input); err != nil {
+		return fmt.Errorf("failed to deserialize the input of the job: %w", err)
+	}
+	report, err := importJobReport(job)
+	if err != nil {
+		return err
+	}
+	for i := job.Processed; i < len(input.Groups); i++ {
+		group := input.Groups[i]
+		groupReport, err := service.rules.ImportRuleGroups(ctx, job.OrgID, input.Groups[i:i+1], input.Options)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			if err := job.AddError(group.Folder+"/"+group.Name, err); err != nil {
+				return err
+			}
+		}
+		report.Groups += groupReport.Groups
+		report.Rules += groupReport.Rules
+		for uid, rules := range groupReport.UnmappedDataSources {
+			if report.UnmappedDataSources == nil {
+				report.UnmappedDataSources = make(map[string][]string)
+			}
+			report.UnmappedDataSources[uid] = append(report.UnmappedDataSources[uid], rules...)
+		}
+		if job.Result, err = json.Marshal(report); err != nil {
+			return err
+		}
+		job.Processed = i + 1
+		if err := service.saveProgress(ctx, job); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runExport writes the archive of the export, and records the progress after every rule group.
+func (service *ProvisioningJobService) runExport(ctx context.Context, job *models.ProvisioningJob) error {
+	var opts ExportOptions
+	if err := json.Unmarshal([]byte(job.Input), &opts); err != nil {
+		return fmt.Errorf("failed to deserialize the input of the job: %w", err)
+	}
+	var archive bytes.Buffer
+	err := service.rules.exportAllRuleGroups(ctx, job.OrgID, opts, &archive, func(processed, total int) error {
+		job.Processed, job.Total = processed, total
+		return service.saveProgress(ctx, job)
+	})
+	if err != nil {
+		return err
+	}
+	job.Result = archive.Bytes()
+	return nil
+}
+
+// saveProgress records the progress of the job. It returns errJobTakenOver if the job was updated
+// by someone else since, e.g. because it was canceled.
+func (service *ProvisioningJobService) saveProgress(ctx context.Context, job *models.ProvisioningJob) error {
+	updated, err := service.store.UpdateProvisioningJob(ctx, job)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return errJobTakenOver
+	}
+	return nil
+}
+
+// importJobReport returns the report of the groups that the import job processed so far.
+func importJobReport(job *models.ProvisioningJob) (*ImportReport, error) {
+	report := &ImportReport{}
+	if len(job.Result) == 0 {
+		return report, nil
+	}
+	if err := json.Unmarshal(job.Result, report); err != nil {
+		return nil, fmt.Errorf("failed to deserialize the report of the import: %w", err)
+	}
+	return report, nil
+}
@@ -0,0 +1,145 @@
+package provisioning
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/store"
+)
+
+func TestProvisioningJobService(t *testing.T) {
+	ruleService := createAlertRuleService(t)
+	jobStore := ruleService.ruleStore.(store.DBstore)
+	service := NewProvisioningJobService(&ruleService, jobStore, ProvisioningJobConfig{StaleAfter: time.Millisecond}, log.NewNopLogger())
+
+	for _, group := range []string{"group-a", "group-b"} {
+		rule := dummyRule("test#"+group, 1)
+		rule.RuleGroup = group
+		rule.Data[0].RelativeTimeRange = models.RelativeTimeRange{From: models.Duration(10 * time.Minute)}
+		_, err := ruleService.CreateAlertRule(context.Background(), rule, models.ProvenanceNone)
+		require.NoError(t, err)
+	}
+	query := &models.ListAlertRulesQuery{OrgID: 1}
+	require.NoError(t, ruleService.ruleStore.ListAlertRules(context.Background(), query))
+	file, err := NewAlertingFileExport(1, query.Result)
+	require.NoError(t, err)
+	require.Len(t, file.Groups, 2)
+
+	// runJobs runs the pending jobs and waits until they are finished.
+	runJobs := func() {
+		service.runPendingJobs(context.Background())
+		service.wg.Wait()
+	}
+
+	t.Run("should import the groups and record the errors of failed groups", func(t *testing.T) {
+		invalid := file.Groups[1]
+		invalid.Name = "invalid"
+		invalid.Rules = []definitions.AlertRuleExport{invalid.Rules[0]}
+		invalid.Rules[0].Title = ""
+		groups := []definitions.AlertRuleGroupExport{file.Groups[0], invalid, file.Groups[1]}
+
+		uid, err := service.StartImportJob(context.Background(), 2, groups, ImportRuleGroupsOptions{})
+		require.NoError(t, err)
+		status, err := service.GetJobStatus(context.Background(), 2, uid)
+		require.NoError(t, err)
+		require.Equal(t, models.ProvisioningJobPending, status.Status)
+		require.Equal(t, 3, status.Total)
+
+		runJobs()
+
+		status, err = service.GetJobStatus(context.Background(), 2, uid)
+		require.NoError(t, err)
+		require.Equal(t, models.ProvisioningJobCompleted, status.Status)
+		require.Equal(t, 3, status.Processed)
+		require.Len(t, status.Errors, 1)
+		require.Equal(t, "my-cool-folder/invalid", status.Errors[0].Item)
+		require.Equal(t, 2, status.Import.Groups)
+		for _, group := range []string{"group-a", "group-b"} {
+			rules, err := ruleService.GetAlertRuleGroup(context.Background(), 2, "my-cool-folder", group)
+			require.NoError(t, err)
+			require.Len(t, rules, 1)
+		}
+	})
+	t.Run("should resume abandoned imports with the first group that was not processed", func(t *testing.T) {
+		uid, err := service.StartImportJob(context.Background(), 3, file.Groups, ImportRuleGroupsOptions{})
+		require.NoError(t, err)
+		job, err := jobStore.GetProvisioningJob(context.Background(), 3, uid)
+		require.NoError(t, err)
+		// the instance that ran the job stopped after the first group.
+		job.Status = models.ProvisioningJobRunning
+		job.Processed = 1
+		updated, err := jobStore.UpdateProvisioningJob(context.Background(), job)
+		require.NoError(t, err)
+		require.True(t, updated)
+		time.Sleep(10 * time.Millisecond)
+
+		runJobs()
+
+		status, err := service.GetJobStatus(context.Background(), 3, uid)
+		require.NoError(t, err)
+		require.Equal(t, models.ProvisioningJobCompleted, status.Status)
+		require.Equal(t, 2, status.Processed)
+		_, err = ruleService.GetAlertRuleGroup(context.Background(), 3, "my-cool-folder", "group-a")
+		require.ErrorIs(t, err, store.ErrAlertRuleGroupNotFound)
+		rules, err := ruleService.GetAlertRuleGroup(context.Background(), 3, "my-cool-folder", "group-b")
+		require.NoError(t, err)
+		require.Len(t, rules, 1)
+	})
+	t.Run("should export the groups as an archive", func(t *testing.T) {
+		uid, err := service.StartExportJob(context.Background(), 1, ExportOptions{})
+		require.NoError(t, err)
+		_, err = service.GetExportJobResult(context.Background(), 1, uid)
+		require.ErrorIs(t, err, ErrJobNotCompleted)
+
+		runJobs()
+
+		status, err := service.GetJobStatus(context.Background(), 1, uid)
+		require.NoError(t, err)
+		require.Equal(t, models.ProvisioningJobCompleted, status.Status)
+		require.Equal(t, 2, status.Processed)
+		require.Equal(t, 2, status.Total)
+		archive, err := service.GetExportJobResult(context.Background(), 1, uid)
+		require.NoError(t, err)
+		files := readZip(t, archive)
+		require.Contains(t, files, "index.yaml")
+		require.Contains(t, files, "my-cool-folder/group-a.yaml")
+		require.Contains(t, files, "my-cool-folder/group-b.yaml")
+	})
+	t.Run("should cancel pending jobs", func(t *testing.T) {
+		uid, err := service.StartImportJob(context.Background(), 4, file.Groups, ImportRuleGroupsOptions{})
+		require.NoError(t, err)
+		require.NoError(t, service.CancelJob(context.Background(), 4, uid))
+
+		runJobs()
+
+		status, err := service.GetJobStatus(context.Background(), 4, uid)
+		require.NoError(t, err)
+		require.Equal(t, models.ProvisioningJobCanceled, status.Status)
+		require.Zero(t, status.Processed)
+		require.ErrorIs(t, service.CancelJob(context.Background(), 4, uid), ErrJobFinished)
+	})
+	t.Run("should only find the jobs of the organization", func(t *testing.T) {
+		uid, err := service.StartExportJob(context.Background(), 1, ExportOptions{})
+		require.NoError(t, err)
+		_, err = service.GetJobStatus(context.Background(), 2, uid)
+		require.ErrorIs(t, err, ErrJobNotFound)
+		require.ErrorIs(t, service.CancelJob(context.Background(), 2, uid), ErrJobNotFound)
+		require.NoError(t, service.CancelJob(context.Background(), 1, uid))
+	})
+	t.Run("should delete finished jobs after the retention", func(t *testing.T) {
+		service.cfg.Retention = time.Millisecond
+		time.Sleep(10 * time.Millisecond)
+		deleted, err := service.DeleteExpiredJobs(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, int64(5), deleted)
+		jobs, err := jobStore.ListProvisioningJobs(context.Background(), models.ProvisioningJobPending, models.ProvisioningJobRunning)
+		require.NoError(t, err)
+		require.Empty(t, jobs)
+	})
+}
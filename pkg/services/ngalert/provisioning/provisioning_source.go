@@ -0,0 +1,67 @@
+package provisioning
+
+import (
+	"context"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+// ProvisioningSourceStore stores the files that file-provisioned resources were last applied from.
+type ProvisioningSourceStore interface {
+	GetProvisioningSource(ctx context.Context, o models.Provisionable, org int64) (*models.ProvisioningSource, error)
+	SetProvisioningSource(ctx context.Context, o models.Provisionable, org int64, source models.ProvisioningSource) error
+	DeleteProvisioningSource(ctx context.Context, o models.Provisionable, org int64) error
+}
+
+// provisionableResource identifies a provisioned resource by its type and ID.
+type provisionableResource struct {
+	resourceType string
+	id           string
+}
+
+func (r provisionableResource) ResourceType() string {
+	return r.resourceType
+}
+
+func (r provisionableResource) ResourceID() string {
+	return r.id
+}
+
+// GetProvisioningSource returns the file that the resource of the type, e.g. "alertRule", was last
+// applied from, so that the definition of a file-provisioned resource can be found. It returns nil if
+// the resource is not provisioned from a file.
+func (service *AlertRuleService) GetProvisioningSource(ctx context.Context, orgID int64, resourceType, uid string) (_ *models.ProvisioningSource, err error) {
+	defer wrapServiceError(&err)
+	if service.sources == nil {
+		return nil, nil
+	}
+	ctx, cancel := withTimeout(ctx, service.cfg.OperationTimeouts.Get)
+	defer cancel()
+	if resourceType == (&models.AlertRule{}).ResourceType() {
+		if err := service.checkRuleNamespace(ctx, orgID, uid); err != nil {
+			return nil, err
+		}
+	}
+	resource := provisionableResource{resourceType: resourceType, id: uid}
+	provenance, err := service.provenanceStore.GetProvenance(ctx, resource, orgID)
+	if err != nil {
+		return nil, err
+	}
+	// the source of a resource that is no longer provisioned from a file is outdated.
+	if provenance != models.ProvenanceFile {
+		return nil, nil
+	}
+	return service.sources.GetProvisioningSource(ctx, resource, orgID)
+}
+
+// SetProvisioningSource records the file that the resource was applied from. It is called by the
+// file provisioning after the resource was written.
+func (service *AlertRuleService) SetProvisioningSource(ctx context.Context, orgID int64, resourceType, uid string, source models.ProvisioningSource) (err error) {
+	defer wrapServiceError(&err)
+	if service.sources == nil {
+		return nil
+	}
+	ctx, cancel := withTimeout(ctx, service.cfg.OperationTimeouts.Update)
+	defer cancel()
+	return service.sources.SetProvisioningSource(ctx, provisionableResource{resourceType: resourceType, id: uid}, orgID, source)
+}
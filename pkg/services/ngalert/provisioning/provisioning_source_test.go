@@ -0,0 +1,53 @@
+package provisioning
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+func TestAlertRuleServiceProvisioningSource(t *testing.T) {
+	ruleService := createAlertRuleService(t)
+	const orgID = int64(1)
+	rule := dummyRule("test#source", orgID)
+	rule, err := ruleService.CreateAlertRule(context.Background(), rule, models.ProvenanceFile)
+	require.NoError(t, err)
+	source := models.ProvisioningSource{
+		Path:      "/etc/grafana/provisioning/alerting/rules.yaml",
+		Hash:      "8f434346648f6b96df89dda901c5176b10a6d83961dd3c1ac88b59b2dc327aa4",
+		AppliedAt: time.Date(2022, 6, 1, 10, 0, 0, 0, time.UTC),
+	}
+	require.NoError(t, ruleService.SetProvisioningSource(context.Background(), orgID, rule.ResourceType(), rule.UID, source))
+
+	t.Run("should return the file of file-provisioned rules", func(t *testing.T) {
+		stored, err := ruleService.GetProvisioningSource(context.Background(), orgID, rule.ResourceType(), rule.UID)
+		require.NoError(t, err)
+		require.NotNil(t, stored)
+		require.Equal(t, source.Path, stored.Path)
+		require.Equal(t, source.Hash, stored.Hash)
+		require.True(t, source.AppliedAt.Equal(stored.AppliedAt))
+
+		stored, err = ruleService.GetProvisioningSource(context.Background(), orgID+1, rule.ResourceType(), rule.UID)
+		require.NoError(t, err)
+		require.Nil(t, stored)
+	})
+	t.Run("should not return the source of rules that are not provisioned from a file", func(t *testing.T) {
+		other, err := ruleService.CreateAlertRule(context.Background(), dummyRule("test#api", orgID), models.ProvenanceAPI)
+		require.NoError(t, err)
+		require.NoError(t, ruleService.SetProvisioningSource(context.Background(), orgID, other.ResourceType(), other.UID, source))
+
+		stored, err := ruleService.GetProvisioningSource(context.Background(), orgID, other.ResourceType(), other.UID)
+		require.NoError(t, err)
+		require.Nil(t, stored, "the source is outdated once the rule is provisioned differently")
+	})
+	t.Run("should delete the source with the rule", func(t *testing.T) {
+		require.NoError(t, ruleService.DeleteAlertRule(context.Background(), orgID, rule.UID, models.ProvenanceFile))
+		stored, err := ruleService.sources.GetProvisioningSource(context.Background(), &rule, orgID)
+		require.NoError(t, err)
+		require.Nil(t, stored)
+	})
+}
@@ -0,0 +1,42 @@
+package provisioning
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/grafana/grafana/pkg/services/quota"
+)
+
+// NoopQuotaChecker is the QuotaChecker of OSS, which has no plans that limit the number of alert rules.
+type NoopQuotaChecker struct{}
+
+func (NoopQuotaChecker) CheckAlertRuleQuota(ctx context.Context, orgID int64) error {
+	return nil
+}
+
+// checkRuleCap returns an error that wraps ErrQuotaReached if the plan of the organization, its alert rule
+// quota or the global one does not allow the given number of rules more. The quotas are the ones that the
+// ruler API enforces. It is the only quota check of the service, so that a single rule and a batch of rules
+// are held to the same limit. Batches are checked before their transaction is opened, so that a batch that
+// obviously exceeds the quota is not partially written and rolled back.
+func (service *AlertRuleService) checkRuleCap(ctx context.Context, orgID int64, added int) error {
+	if added <= 0 {
+		return nil
+	}
+	if service.quota != nil {
+		if err := service.quota.CheckAlertRuleQuota(ctx, orgID); err != nil {
+			return err
+		}
+	}
+	if service.quotas == nil {
+		return nil
+	}
+	reached, err := service.quotas.CheckQuotaReachedFor(ctx, "alert_rule", &quota.ScopeParameters{OrgId: orgID}, int64(added)) // alert rule is table name
+	if err != nil {
+		return fmt.Errorf("failed to get alert rules quota: %w", err)
+	}
+	if reached {
+		return fmt.Errorf("%w: organization %d cannot have %d more alert rules", ErrQuotaReached, orgID, added)
+	}
+	return nil
+}
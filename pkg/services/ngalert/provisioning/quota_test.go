@@ -0,0 +1,90 @@
+package provisioning
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	gfmodels "github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/store"
+	"github.com/grafana/grafana/pkg/services/quota"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestAlertRuleServiceRuleCap(t *testing.T) {
+	var orgID int64 = 1
+	service := createAlertRuleService(t)
+	cfg := setting.NewCfg()
+	cfg.Quota = setting.QuotaSettings{
+		Enabled: true,
+		Org:     &setting.OrgQuota{AlertRule: 3},
+		Global:  &setting.GlobalQuota{AlertRule: 7},
+	}
+	sqlStore := service.ruleStore.(store.DBstore).SQLStore
+	service.quotas = &quota.QuotaService{Cfg: cfg, SQLStore: sqlStore, Logger: log.NewNopLogger()}
+	rules := func(titles ...string) []models.AlertRule {
+		result := make([]models.AlertRule, 0, len(titles))
+		for _, title := range titles {
+			result = append(result, dummyRule(title, orgID))
+		}
+		return result
+	}
+	count := func(t *testing.T) int64 {
+		t.Helper()
+		query := &models.ListAlertRulesQuery{OrgID: orgID}
+		require.NoError(t, service.ruleStore.ListAlertRules(context.Background(), query))
+		return int64(len(query.Result))
+	}
+
+	created, err := service.CreateRuleGroupIfAbsent(context.Background(), orgID, "folder", "group-1", rules("a", "b"), 60, models.ProvenanceAPI)
+	require.NoError(t, err)
+	require.True(t, created)
+
+	t.Run("should reject batches that exceed the cap before opening a transaction", func(t *testing.T) {
+		xact := service.xact
+		service.xact = &failingTransactionManager{err: errors.New("the transaction should not be opened")}
+		defer func() { service.xact = xact }()
+
+		_, err := service.CreateRuleGroupIfAbsent(context.Background(), orgID, "folder", "group-2", rules("c", "d"), 60, models.ProvenanceAPI)
+		require.ErrorIs(t, err, ErrQuotaReached)
+		require.Contains(t, err.Error(), "organization 1 cannot have 2 more alert rules")
+		require.Equal(t, int64(2), count(t))
+	})
+	t.Run("should reject replaces that exceed the cap", func(t *testing.T) {
+		stored, err := service.GetAlertRuleGroup(context.Background(), orgID, "folder", "group-1")
+		require.NoError(t, err)
+		err = service.ReplaceRuleGroup(context.Background(), orgID, "folder", "group-1", 60, append(stored, rules("c", "d")...), models.ProvenanceAPI)
+		require.ErrorIs(t, err, ErrQuotaReached)
+		require.Equal(t, int64(2), count(t))
+	})
+	t.Run("should allow changes up to the cap", func(t *testing.T) {
+		_, err := service.CreateAlertRule(context.Background(), dummyRule("c", orgID), models.ProvenanceAPI)
+		require.NoError(t, err)
+		require.Equal(t, int64(3), count(t))
+
+		_, err = service.CreateAlertRule(context.Background(), dummyRule("d", orgID), models.ProvenanceAPI)
+		require.ErrorIs(t, err, ErrQuotaReached)
+
+		err = service.ReplaceRuleGroup(context.Background(), orgID, "folder", "group-1", 60, rules("e", "f"), models.ProvenanceAPI)
+		require.NoError(t, err, "replacing rules should not count the rules that are deleted")
+		require.Equal(t, int64(3), count(t))
+	})
+	t.Run("should count the rules of every organization separately", func(t *testing.T) {
+		_, err := service.CreateRuleGroupIfAbsent(context.Background(), orgID+1, "folder", "group-1", []models.AlertRule{dummyRule("a", orgID+1), dummyRule("b", orgID+1)}, 60, models.ProvenanceAPI)
+		require.NoError(t, err)
+	})
+	t.Run("should use the quota of the organization instead of the default one", func(t *testing.T) {
+		require.NoError(t, sqlStore.UpdateOrgQuota(context.Background(), &gfmodels.UpdateOrgQuotaCmd{OrgId: orgID + 2, Target: "alert_rule", Limit: 1}))
+		_, err := service.CreateRuleGroupIfAbsent(context.Background(), orgID+2, "folder", "group-1", []models.AlertRule{dummyRule("a", orgID+2), dummyRule("b", orgID+2)}, 60, models.ProvenanceAPI)
+		require.ErrorIs(t, err, ErrQuotaReached)
+	})
+	t.Run("should enforce the global quota", func(t *testing.T) {
+		_, err := service.CreateRuleGroupIfAbsent(context.Background(), orgID+3, "folder", "group-1", []models.AlertRule{dummyRule("a", orgID+3), dummyRule("b", orgID+3), dummyRule("c", orgID+3)}, 60, models.ProvenanceAPI)
+		require.ErrorIs(t, err, ErrQuotaReached)
+		require.Contains(t, err.Error(), "organization 4 cannot have 3 more alert rules")
+	})
+}
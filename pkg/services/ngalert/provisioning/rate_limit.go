@@ -0,0 +1,85 @@
+package provisioning
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/time/rate"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+// RateLimitError is returned for changes that an organization makes faster than its RateLimiter
+// allows. It wraps ErrRateLimited.
+type RateLimitError struct {
+	OrgID int64
+	// RetryAfter is the time after which the change would be allowed.
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%s: organization %d can retry after %s", ErrRateLimited, e.OrgID, e.RetryAfter)
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return ErrRateLimited
+}
+
+// RateLimiter limits how fast every organization can change provisioned resources with a token
+// bucket per organization. Changes made by file provisioning are never limited. A nil RateLimiter
+// does not limit anything.
+type RateLimiter struct {
+	limit rate.Limit
+	burst int
+	// throttled counts the rejected changes by organization. It is optional.
+	throttled *prometheus.CounterVec
+	now       func() time.Time
+
+	mtx      sync.Mutex
+	limiters map[int64]*rate.Limiter
+}
+
+// NewRateLimiter returns a limiter that allows every organization requestsPerSecond changes per
+// second on average and burst changes at once.
+func NewRateLimiter(requestsPerSecond float64, burst int, throttled *prometheus.CounterVec) *RateLimiter {
+	return &RateLimiter{
+		limit:     rate.Limit(requestsPerSecond),
+		burst:     burst,
+		throttled: throttled,
+		now:       time.Now,
+		limiters:  map[int64]*rate.Limiter{},
+	}
+}
+
+// allow takes a token from the bucket of the organization, or returns a RateLimitError if there is none.
+func (l *RateLimiter) allow(orgID int64, provenance models.Provenance) error {
+	if l == nil || provenance == models.ProvenanceFile {
+		return nil
+	}
+	l.mtx.Lock()
+	limiter, ok := l.limiters[orgID]
+	if !ok {
+		limiter = rate.NewLimiter(l.limit, l.burst)
+		l.limiters[orgID] = limiter
+	}
+	l.mtx.Unlock()
+
+	now := l.now()
+	reservation := limiter.ReserveN(now, 1)
+	delay := time.Duration(math.MaxInt64)
+	if reservation.OK() {
+		delay = reservation.DelayFrom(now)
+		if delay == 0 {
+			return nil
+		}
+		reservation.CancelAt(now)
+	}
+	if l.throttled != nil {
+		l.throttled.WithLabelValues(strconv.FormatInt(orgID, 10)).Inc()
+	}
+	return &RateLimitError{OrgID: orgID, RetryAfter: delay}
+}
@@ -0,0 +1,79 @@
+package provisioning
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+func TestRateLimiter(t *testing.T) {
+	newLimiter := func() (*RateLimiter, *prometheus.CounterVec, *time.Time) {
+		throttled := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "throttled"}, []string{"org"})
+		limiter := NewRateLimiter(1, 2, throttled)
+		now := time.Now()
+		limiter.now = func() time.Time { return now }
+		return limiter, throttled, &now
+	}
+
+	t.Run("should allow bursts and reject changes above the rate", func(t *testing.T) {
+		limiter, throttled, now := newLimiter()
+		require.NoError(t, limiter.allow(1, models.ProvenanceAPI))
+		require.NoError(t, limiter.allow(1, models.ProvenanceAPI))
+
+		err := limiter.allow(1, models.ProvenanceAPI)
+		require.ErrorIs(t, err, ErrRateLimited)
+		var limitErr *RateLimitError
+		require.ErrorAs(t, err, &limitErr)
+		require.Equal(t, time.Second, limitErr.RetryAfter)
+		require.Equal(t, 1.0, testutil.ToFloat64(throttled.WithLabelValues("1")))
+
+		*now = now.Add(time.Second)
+		require.NoError(t, limiter.allow(1, models.ProvenanceAPI))
+	})
+	t.Run("should limit every organization on its own", func(t *testing.T) {
+		limiter, throttled, _ := newLimiter()
+		require.NoError(t, limiter.allow(1, models.ProvenanceAPI))
+		require.NoError(t, limiter.allow(1, models.ProvenanceNone))
+		require.ErrorIs(t, limiter.allow(1, models.ProvenanceAPI), ErrRateLimited)
+
+		require.NoError(t, limiter.allow(2, models.ProvenanceAPI))
+		require.Equal(t, 0.0, testutil.ToFloat64(throttled.WithLabelValues("2")))
+	})
+	t.Run("should never limit file provisioning", func(t *testing.T) {
+		limiter, _, _ := newLimiter()
+		for i := 0; i < 5; i++ {
+			require.NoError(t, limiter.allow(1, models.ProvenanceFile))
+		}
+		require.NoError(t, limiter.allow(1, models.ProvenanceAPI))
+	})
+	t.Run("should not limit anything without a limiter", func(t *testing.T) {
+		var limiter *RateLimiter
+		require.NoError(t, limiter.allow(1, models.ProvenanceAPI))
+	})
+}
+
+func TestAlertRuleServiceRateLimit(t *testing.T) {
+	ruleService := createAlertRuleService(t)
+	ruleService.policy.RateLimiter = NewRateLimiter(0.001, 1, nil)
+	var orgID int64 = 11
+
+	rule, err := ruleService.CreateAlertRule(context.Background(), dummyRule("test#limit-1", orgID), models.ProvenanceAPI)
+	require.NoError(t, err)
+
+	_, err = ruleService.CreateAlertRule(context.Background(), dummyRule("test#limit-2", orgID), models.ProvenanceAPI)
+	require.ErrorIs(t, err, ErrRateLimited)
+	require.Equal(t, ErrCodeRateLimited, ErrorCodeOf(err))
+	err = ruleService.DeleteAlertRule(context.Background(), orgID, rule.UID, models.ProvenanceAPI)
+	require.ErrorIs(t, err, ErrRateLimited)
+
+	_, _, err = ruleService.GetAlertRule(context.Background(), orgID, rule.UID)
+	require.NoError(t, err)
+	_, err = ruleService.CreateAlertRule(context.Background(), dummyRule("test#limit-3", orgID), models.ProvenanceFile)
+	require.NoError(t, err)
+}
@@ -0,0 +1,361 @@
+package provisioning
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/grafana/grafana/pkg/expr"
+	"github.com/grafana/grafana/pkg/infra/log"
+	models2 "github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/notifier/channels"
+	"github.com/grafana/grafana/pkg/services/ngalert/store"
+)
+
+// resourceHealthBatchSize is the number of alert rules that are loaded at once by ValidateProvisionedResources.
+const resourceHealthBatchSize = 100
+
+var (
+	templateDefinition = regexp.MustCompile(`{{-?\s*define\s+"([^"]+)"`)
+	templateReference  = regexp.MustCompile(`{{-?\s*template\s+"([^"]+)"`)
+)
+
+// DataSourceLookup finds the data sources of organizations.
+type DataSourceLookup interface {
+	GetDataSource(ctx context.Context, query *models2.GetDataSourceQuery) error
+}
+
+// ProvisionedResourceReport lists the provisioned resources of an organization that reference
+// resources that no longer exist.
+type ProvisionedResourceReport struct {
+	OrgID int64 `json:"orgId"`
+	// RulesByMissingDataSource maps the UIDs of missing data sources to the UIDs of the provisioned
+	// alert rules that query them.
+	RulesByMissingDataSource map[string][]string `json:"rulesByMissingDataSource"`
+	// ContactPointsByMissingTemplate maps the names of missing templates to the UIDs of the
+	// provisioned contact points that use them.
+	ContactPointsByMissingTemplate map[string][]string `json:"contactPointsByMissingTemplate"`
+	// MissingReceivers are the receivers that the provisioned notification policy tree routes to
+	// but that do not exist.
+	MissingReceivers []string `json:"missingReceivers"`
+}
+
+// BrokenResources returns the number of provisioned resources of the report that reference missing
+// resources. A resource that references several missing resources is counted once.
+func (r *ProvisionedResourceReport) BrokenResources() int {
+	broken := 0
+	for _, byMissing := range []map[string][]string{r.RulesByMissingDataSource, r.ContactPointsByMissingTemplate} {
+		uids := make(map[string]struct{})
+		for _, resources := range byMissing {
+			for _, uid := range resources {
+				uids[uid] = struct{}{}
+			}
+		}
+		broken += len(uids)
+	}
+	if len(r.MissingReceivers) > 0 {
+		broken++
+	}
+	return broken
+}
+
+// ResourceHealthService finds the provisioned resources that silently broke because a resource they
+// reference was deleted or renamed.
+type ResourceHealthService struct {
+	ruleStore       store.RuleStore
+	provenanceStore ProvisioningStore
+	amStore         AMConfigStore
+	datasources     DataSourceLookup
+	orgs            store.OrgStore
+	// brokenResources is optional and set to the number of broken provisioned resources of every organization.
+	brokenResources *prometheus.GaugeVec
+	batchSize       int
+	log             log.Logger
+}
+
+func NewResourceHealthService(ruleStore store.RuleStore, provenanceStore ProvisioningStore, amStore AMConfigStore, datasources DataSourceLookup, orgs store.OrgStore, brokenResources *prometheus.GaugeVec, log log.Logger) *ResourceHealthService {
+	return &ResourceHealthService{
+		ruleStore:       ruleStore,
+		provenanceStore: provenanceStore,
+		amStore:         amStore,
+		datasources:     datasources,
+		orgs:            orgs,
+		brokenResources: brokenResources,
+		batchSize:       resourceHealthBatchSize,
+		log:             log,
+	}
+}
+
+// ValidateProvisionedResources checks that the provisioned resources of the organization only reference
+// resources that exist: the data sources that alert rules query, which must belong to the organization,
+// the templates that contact points use, and the receivers that the notification policy tree routes to.
+// Alert rules are loaded in batches, and every data source is looked up once.
+func (service *ResourceHealthService) ValidateProvisionedResources(ctx context.Context, orgID int64) (*ProvisionedResourceReport, error) {
+	report := &ProvisionedResourceReport{
+		OrgID:                          orgID,
+		RulesByMissingDataSource:       map[string][]string{},
+		ContactPointsByMissingTemplate: map[string][]string{},
+		MissingReceivers:               []string{},
+	}
+	if err := service.validateAlertRules(ctx, orgID, report); err != nil {
+		return nil, err
+	}
+	if err := service.validateNotificationConfig(ctx, orgID, report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func (service *ResourceHealthService) validateAlertRules(ctx context.Context, orgID int64, report *ProvisionedResourceReport) error {
+	provenances, err := service.provenanceStore.GetProvenances(ctx, orgID, (&models.AlertRule{}).ResourceType())
+	if err != nil {
+		return err
+	}
+	if !hasProvisioned(provenances) {
+		return nil
+	}
+	exists := make(map[string]bool)
+	for offset := 0; ; offset += service.batchSize {
+		query := &models.ListAlertRulesQuery{OrgID: orgID, Limit: service.batchSize, Offset: offset}
+		if err := service.ruleStore.ListAlertRules(ctx, query); err != nil {
+			return err
+		}
+		for _, rule := range query.Result {
+			if provenance, ok := provenances[rule.UID]; !ok || provenance == models.ProvenanceNone {
+				continue
+			}
+			for _, uid := range queriedDataSources(rule) {
+				found, ok := exists[uid]
+				if !ok {
+					found, err = service.dataSourceExists(ctx, orgID, uid)
+					if err != nil {
+						return err
+					}
+					exists[uid] = found
+				}
+				if !found {
+					report.RulesByMissingDataSource[uid] = append(report.RulesByMissingDataSource[uid], rule.UID)
+				}
+			}
+		}
+		if len(query.Result) < service.batchSize {
+			return nil
+		}
+	}
+}
+
+// queriedDataSources returns the UIDs of the data sources that the rule queries, without expressions.
+func queriedDataSources(rule *models.AlertRule) []string {
+	var uids []string
+	seen := make(map[string]struct{})
+	for _, query := range rule.Data {
+		if query.DatasourceUID == "" || expr.IsDataSource(query.DatasourceUID) {
+			continue
+		}
+		if _, ok := seen[query.DatasourceUID]; ok {
+			continue
+		}
+		seen[query.DatasourceUID] = struct{}{}
+		uids = append(uids, query.DatasourceUID)
+	}
+	return uids
+}
+
+func (service *ResourceHealthService) dataSourceExists(ctx context.Context, orgID int64, uid string) (bool, error) {
+	err := service.datasources.GetDataSource(ctx, &models2.GetDataSourceQuery{OrgId: orgID, Uid: uid})
+	if errors.Is(err, models2.ErrDataSourceNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// notificationConfig is the part of an Alertmanager configuration that references templates and
+// receivers. Unlike PostableUserConfig it is not validated when it is read, so that it can be read
+// when a route references a missing receiver.
+type notificationConfig struct {
+	TemplateFiles      map[string]string `json:"template_files"`
+	AlertmanagerConfig struct {
+		Route     *definitions.Route `json:"route"`
+		Receivers []struct {
+			Name                    string                                 `json:"name"`
+			GrafanaManagedReceivers []*definitions.PostableGrafanaReceiver `json:"grafana_managed_receiver_configs"`
+		} `json:"receivers"`
+	} `json:"alertmanager_config"`
+}
+
+func (service *ResourceHealthService) validateNotificationConfig(ctx context.Context, orgID int64, report *ProvisionedResourceReport) error {
+	query := models.GetLatestAlertmanagerConfigurationQuery{OrgID: orgID}
+	if err := service.amStore.GetLatestAlertmanagerConfiguration(ctx, &query); err != nil {
+		return err
+	}
+	if query.Result == nil {
+		return nil
+	}
+	var cfg notificationConfig
+	if err := json.Unmarshal([]byte(query.Result.AlertmanagerConfiguration), &cfg); err != nil {
+		return fmt.Errorf("failed to deserialize alertmanager configuration: %w", err)
+	}
+
+	contactPoints, err := service.provenanceStore.GetProvenances(ctx, orgID, (&definitions.EmbeddedContactPoint{}).ResourceType())
+	if err != nil {
+		return err
+	}
+	if hasProvisioned(contactPoints) {
+		defined := definedTemplates(cfg.TemplateFiles)
+		for _, receiver := range cfg.AlertmanagerConfig.Receivers {
+			for _, contactPoint := range receiver.GrafanaManagedReceivers {
+				if provenance, ok := contactPoints[contactPoint.UID]; !ok || provenance == models.ProvenanceNone || contactPoint.Settings == nil {
+					continue
+				}
+				for _, name := range referencedTemplates(contactPoint.Settings.Interface()) {
+					if _, ok := defined[name]; !ok {
+						report.ContactPointsByMissingTemplate[name] = append(report.ContactPointsByMissingTemplate[name], contactPoint.UID)
+					}
+				}
+			}
+		}
+	}
+
+	tree := cfg.AlertmanagerConfig.Route
+	if tree == nil {
+		return nil
+	}
+	provenance, err := service.provenanceStore.GetProvenance(ctx, tree, orgID)
+	if err != nil {
+		return err
+	}
+	if provenance == models.ProvenanceNone {
+		return nil
+	}
+	existing := make(map[string]struct{}, len(cfg.AlertmanagerConfig.Receivers))
+	for _, receiver := range cfg.AlertmanagerConfig.Receivers {
+		existing[receiver.Name] = struct{}{}
+	}
+	missing := make(map[string]struct{})
+	for _, name := range routeReceivers(tree) {
+		if _, ok := existing[name]; !ok {
+			missing[name] = struct{}{}
+		}
+	}
+	for name := range missing {
+		report.MissingReceivers = append(report.MissingReceivers, name)
+	}
+	sort.Strings(report.MissingReceivers)
+	return nil
+}
+
+// definedTemplates returns the names of the templates that the template files and the default templates define.
+func definedTemplates(files map[string]string) map[string]struct{} {
+	defined := make(map[string]struct{})
+	for _, content := range append(mapValues(files), channels.DefaultTemplateString) {
+		for _, match := range templateDefinition.FindAllStringSubmatch(content, -1) {
+			defined[match[1]] = struct{}{}
+		}
+	}
+	return defined
+}
+
+// referencedTemplates returns the names of the templates that the strings of the settings use, without duplicates.
+func referencedTemplates(settings interface{}) []string {
+	var names []string
+	seen := make(map[string]struct{})
+	var walk func(value interface{})
+	walk = func(value interface{}) {
+		switch v := value.(type) {
+		case string:
+			for _, match := range templateReference.FindAllStringSubmatch(v, -1) {
+				if _, ok := seen[match[1]]; !ok {
+					seen[match[1]] = struct{}{}
+					names = append(names, match[1])
+				}
+			}
+		case map[string]interface{}:
+			for _, child := range v {
+				walk(child)
+			}
+		case []interface{}:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	walk(settings)
+	sort.Strings(names)
+	return names
+}
+
+// routeReceivers returns the receivers of the route and all its child routes. Routes without a
+// receiver inherit the receiver of their parent and are skipped.
+func routeReceivers(route *definitions.Route) []string {
+	var receivers []string
+	if route.Receiver != "" {
+		receivers = append(receivers, route.Receiver)
+	}
+	for _, child := range route.Routes {
+		receivers = append(receivers, routeReceivers(child)...)
+	}
+	return receivers
+}
+
+func hasProvisioned(provenances map[string]models.Provenance) bool {
+	for _, provenance := range provenances {
+		if provenance != models.ProvenanceNone {
+			return true
+		}
+	}
+	return false
+}
+
+func mapValues(m map[string]string) []string {
+	values := make([]string, 0, len(m))
+	for _, v := range m {
+		values = append(values, v)
+	}
+	return values
+}
+
+// RunResourceHealthCheck validates the provisioned resources of all organizations every interval until
+// the context is done, and sets the gauge of broken provisioned resources of every organization.
+func (service *ResourceHealthService) RunResourceHealthCheck(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			service.checkAllOrgs(ctx)
+		}
+	}
+}
+
+func (service *ResourceHealthService) checkAllOrgs(ctx context.Context) {
+	orgIDs, err := service.orgs.GetOrgs(ctx)
+	if err != nil {
+		service.log.Error("failed to list the organizations to validate their provisioned resources", "err", err)
+		return
+	}
+	for _, orgID := range orgIDs {
+		report, err := service.ValidateProvisionedResources(ctx, orgID)
+		if err != nil {
+			service.log.Error("failed to validate provisioned resources", "org", orgID, "err", err)
+			continue
+		}
+		broken := report.BrokenResources()
+		if broken > 0 {
+			service.log.Warn("provisioned resources reference missing resources", "org", orgID, "count", broken)
+		}
+		if service.brokenResources != nil {
+			service.brokenResources.WithLabelValues(strconv.FormatInt(orgID, 10)).Set(float64(broken))
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package provisioning
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	models2 "github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+const brokenAlertmanagerConfigJSON = `
+{
+	"template_files": {
+		"custom": "{{ define \"custom.title\" }}custom{{ end }}"
+	},
+	"alertmanager_config": {
+		"route": {
+			"receiver": "team-a",
+			"routes": [{
+				"receiver": "gone",
+				"object_matchers": [["a", "=", "b"]]
+			}, {
+				"object_matchers": [["a", "=", "c"]]
+			}]
+		},
+		"receivers": [{
+			"name": "team-a",
+			"grafana_managed_receiver_configs": [{
+				"uid": "cp-broken",
+				"name": "team-a",
+				"type": "slack",
+				"settings": {
+					"title": "{{ template \"missing.title\" . }}",
+					"text": "{{ template \"default.message\" . }}"
+				}
+			}, {
+				"uid": "cp-healthy",
+				"name": "team-a",
+				"type": "slack",
+				"settings": {
+					"title": "{{ template \"custom.title\" . }}"
+				}
+			}, {
+				"uid": "cp-unprovisioned",
+				"name": "team-a",
+				"type": "slack",
+				"settings": {
+					"title": "{{ template \"missing.title\" . }}"
+				}
+			}]
+		}]
+	}
+}
+`
+
+type fakeDataSourceLookup map[int64][]string
+
+func (f fakeDataSourceLookup) GetDataSource(ctx context.Context, query *models2.GetDataSourceQuery) error {
+	for _, uid := range f[query.OrgId] {
+		if uid == query.Uid {
+			query.Result = &models2.DataSource{OrgId: query.OrgId, Uid: uid}
+			return nil
+		}
+	}
+	return models2.ErrDataSourceNotFound
+}
+
+type fakeOrgStore []int64
+
+func (f fakeOrgStore) GetOrgs(ctx context.Context) ([]int64, error) {
+	return f, nil
+}
+
+func TestResourceHealthServiceValidateProvisionedResources(t *testing.T) {
+	var orgID int64 = 1
+	ruleService := createAlertRuleService(t)
+	createRule := func(title string, provenance models.Provenance, datasourceUIDs ...string) models.AlertRule {
+		rule := dummyRule(title, orgID)
+		query := rule.Data[0]
+		rule.Data = nil
+		for _, uid := range datasourceUIDs {
+			query.DatasourceUID = uid
+			rule.Data = append(rule.Data, query)
+		}
+		rule, err := ruleService.CreateAlertRule(context.Background(), rule, provenance)
+		require.NoError(t, err)
+		return rule
+	}
+	broken := createRule("broken", models.ProvenanceAPI, "deleted", "-100", "elsewhere")
+	alsoBroken := createRule("also-broken", models.ProvenanceFile, "deleted")
+	createRule("healthy", models.ProvenanceAPI, "existing")
+	createRule("unprovisioned", models.ProvenanceNone, "deleted")
+
+	amStore := newFakeAMConfigStore()
+	amStore.config.AlertmanagerConfiguration = brokenAlertmanagerConfigJSON
+	provenanceStore := NewFakeProvisioningStore()
+	for _, uid := range []string{"cp-broken", "cp-healthy"} {
+		require.NoError(t, provenanceStore.SetProvenance(context.Background(), &definitions.EmbeddedContactPoint{UID: uid}, orgID, models.ProvenanceAPI))
+	}
+	require.NoError(t, provenanceStore.SetProvenance(context.Background(), &definitions.Route{}, orgID, models.ProvenanceFile))
+
+	datasources := fakeDataSourceLookup{orgID: {"existing"}, 2: {"elsewhere"}}
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "broken"}, []string{"org"})
+	service := NewResourceHealthService(ruleService.ruleStore, &ruleAndNotificationProvenances{rules: ruleService.provenanceStore, notifications: provenanceStore}, amStore, datasources, fakeOrgStore{orgID}, gauge, log.NewNopLogger())
+	// a batch size of one checks that all batches are validated.
+	service.batchSize = 1
+
+	report, err := service.ValidateProvisionedResources(context.Background(), orgID)
+	require.NoError(t, err)
+
+	t.Run("should group provisioned rules by missing data source", func(t *testing.T) {
+		require.Len(t, report.RulesByMissingDataSource, 2)
+		require.ElementsMatch(t, []string{broken.UID, alsoBroken.UID}, report.RulesByMissingDataSource["deleted"])
+		require.Equal(t, []string{broken.UID}, report.RulesByMissingDataSource["elsewhere"])
+	})
+	t.Run("should group provisioned contact points by missing template", func(t *testing.T) {
+		require.Equal(t, map[string][]string{"missing.title": {"cp-broken"}}, report.ContactPointsByMissingTemplate)
+	})
+	t.Run("should list the missing receivers of the policy tree", func(t *testing.T) {
+		require.Equal(t, []string{"gone"}, report.MissingReceivers)
+	})
+	t.Run("should count every broken resource once", func(t *testing.T) {
+		require.Equal(t, 4, report.BrokenResources())
+	})
+	t.Run("should update the gauge of every organization", func(t *testing.T) {
+		service.checkAllOrgs(context.Background())
+		require.Equal(t, float64(4), testutil.ToFloat64(gauge.WithLabelValues("1")))
+	})
+}
+
+// ruleAndNotificationProvenances reads the provenances of alert rules from one store and the
+// provenances of the notification resources from another.
+type ruleAndNotificationProvenances struct {
+	ProvisioningStore
+	rules         ProvisioningStore
+	notifications ProvisioningStore
+}
+
+func (s *ruleAndNotificationProvenances) store(resourceType string) ProvisioningStore {
+	if resourceType == (&models.AlertRule{}).ResourceType() {
+		return s.rules
+	}
+	return s.notifications
+}
+
+func (s *ruleAndNotificationProvenances) GetProvenance(ctx context.Context, o models.Provisionable, org int64) (models.Provenance, error) {
+	return s.store(o.ResourceType()).GetProvenance(ctx, o, org)
+}
+
+func (s *ruleAndNotificationProvenances) GetProvenances(ctx context.Context, org int64, resourceType string) (map[string]models.Provenance, error) {
+	return s.store(resourceType).GetProvenances(ctx, org, resourceType)
+}
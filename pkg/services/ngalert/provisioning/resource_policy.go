@@ -0,0 +1,77 @@
+package provisioning
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+// ResourceType is a type of resource that can be changed through provisioning.
+type ResourceType string
+
+const (
+	ResourceTypeAlertRules           ResourceType = "rules"
+	ResourceTypeContactPoints        ResourceType = "contact-points"
+	ResourceTypeNotificationPolicies ResourceType = "policies"
+	ResourceTypeMuteTimings          ResourceType = "mute-timings"
+	ResourceTypeTemplates            ResourceType = "templates"
+)
+
+var resourceTypes = []ResourceType{
+	ResourceTypeAlertRules,
+	ResourceTypeContactPoints,
+	ResourceTypeNotificationPolicies,
+	ResourceTypeMuteTimings,
+	ResourceTypeTemplates,
+}
+
+// ParseResourceType returns the resource type with the name, or an error if there is none.
+func ParseResourceType(name string) (ResourceType, error) {
+	for _, t := range resourceTypes {
+		if string(t) == strings.TrimSpace(name) {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("unknown provisioning resource type '%s'", name)
+}
+
+// ResourcePolicy decides which types of resources can be changed through provisioning. It is
+// evaluated by the provisioning services, so that it governs every caller of the services and not
+// only the HTTP API. The zero value allows changing all types of resources.
+type ResourcePolicy struct {
+	// ReadOnly are the types of resources that can only be changed by file provisioning.
+	ReadOnly map[ResourceType]struct{}
+	// RateLimiter limits how fast organizations can change resources. It is optional.
+	RateLimiter *RateLimiter
+}
+
+// NewResourcePolicy returns a policy that makes the types of resources read-only.
+func NewResourcePolicy(readOnly ...ResourceType) ResourcePolicy {
+	p := ResourcePolicy{ReadOnly: make(map[ResourceType]struct{}, len(readOnly))}
+	for _, t := range readOnly {
+		p.ReadOnly[t] = struct{}{}
+	}
+	return p
+}
+
+// checkWritable returns ErrProvisioningDisabled if resources of the type cannot be changed with the
+// provenance. Changes made by file provisioning are always allowed.
+func (p ResourcePolicy) checkWritable(resource ResourceType, provenance models.Provenance) error {
+	if provenance == models.ProvenanceFile {
+		return nil
+	}
+	if _, ok := p.ReadOnly[resource]; ok {
+		return fmt.Errorf("%w: %s are read-only", ErrProvisioningDisabled, resource)
+	}
+	return nil
+}
+
+// checkMutation checks that resources of the type can be changed with the provenance, and that the
+// organization does not exceed its rate limit with the change. It is called once per operation.
+func (p ResourcePolicy) checkMutation(orgID int64, resource ResourceType, provenance models.Provenance) error {
+	if err := p.checkWritable(resource, provenance); err != nil {
+		return err
+	}
+	return p.RateLimiter.allow(orgID, provenance)
+}
@@ -0,0 +1,78 @@
+package provisioning
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+func TestParseResourceType(t *testing.T) {
+	for _, name := range []string{"rules", "contact-points", "policies", "mute-timings", " templates "} {
+		_, err := ParseResourceType(name)
+		require.NoError(t, err, name)
+	}
+
+	_, err := ParseResourceType("dashboards")
+	require.Error(t, err)
+}
+
+func TestResourcePolicy(t *testing.T) {
+	t.Run("zero value allows changing all resources", func(t *testing.T) {
+		var policy ResourcePolicy
+		for _, resource := range resourceTypes {
+			require.NoError(t, policy.checkWritable(resource, models.ProvenanceAPI))
+		}
+	})
+
+	t.Run("read-only resources can only be changed by files", func(t *testing.T) {
+		policy := NewResourcePolicy(ResourceTypeNotificationPolicies)
+
+		err := policy.checkWritable(ResourceTypeNotificationPolicies, models.ProvenanceAPI)
+		require.ErrorIs(t, err, ErrProvisioningDisabled)
+		require.Contains(t, err.Error(), string(ResourceTypeNotificationPolicies))
+		require.ErrorIs(t, policy.checkWritable(ResourceTypeNotificationPolicies, models.ProvenanceNone), ErrProvisioningDisabled)
+		require.NoError(t, policy.checkWritable(ResourceTypeNotificationPolicies, models.ProvenanceFile))
+		require.NoError(t, policy.checkWritable(ResourceTypeAlertRules, models.ProvenanceAPI))
+	})
+
+	t.Run("services reject changes of read-only resources", func(t *testing.T) {
+		policy := NewResourcePolicy(ResourceTypeNotificationPolicies, ResourceTypeMuteTimings, ResourceTypeTemplates)
+
+		policies := createNotificationPolicyServiceSut()
+		policies.policy = policy
+		err := policies.UpdatePolicyTree(context.Background(), 1, createTestRoutingTree(), models.ProvenanceAPI)
+		require.ErrorIs(t, err, ErrProvisioningDisabled)
+		err = policies.DeleteRoute(context.Background(), 1, RoutePath{"0"}, false, models.ProvenanceAPI)
+		require.ErrorIs(t, err, ErrProvisioningDisabled)
+
+		muteTimings := createMuteTimingSvcSut()
+		muteTimings.policy = policy
+		_, err = muteTimings.CreateMuteTiming(context.Background(), createMuteTiming(), 1)
+		require.ErrorIs(t, err, ErrProvisioningDisabled)
+		err = muteTimings.DeleteMuteTiming(context.Background(), "interval", 1)
+		require.ErrorIs(t, err, ErrProvisioningDisabled)
+
+		templates := createTemplateServiceSut()
+		templates.policy = policy
+		_, err = templates.SetTemplate(context.Background(), 1, createMessageTemplate())
+		require.ErrorIs(t, err, ErrProvisioningDisabled)
+	})
+
+	t.Run("alert rules can still be provisioned by files", func(t *testing.T) {
+		service := createAlertRuleService(t)
+		service.policy = NewResourcePolicy(ResourceTypeAlertRules)
+
+		_, err := service.CreateAlertRule(context.Background(), dummyRule("api rule", 1), models.ProvenanceAPI)
+		require.ErrorIs(t, err, ErrProvisioningDisabled)
+		require.Equal(t, ErrCodeProvisioningDisabled, ErrorCodeOf(err))
+
+		rule, err := service.CreateAlertRule(context.Background(), dummyRule("file rule", 1), models.ProvenanceFile)
+		require.NoError(t, err)
+
+		err = service.DeleteAlertRule(context.Background(), 1, rule.UID, models.ProvenanceAPI)
+		require.ErrorIs(t, err, ErrProvisioningDisabled)
+	})
+}
@@ -0,0 +1,142 @@
+package provisioning
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/alertmanager/dispatch"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/timeinterval"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+// RoutePreviewOptions configures PreviewRoute.
+type RoutePreviewOptions struct {
+	// Tree is a candidate policy tree to route the alert with instead of the stored one.
+	// Its mute timings must exist in the stored configuration.
+	Tree *definitions.Route
+	// Now is the time at which the mute timings are checked. The current time is used if it is zero.
+	Now time.Time
+}
+
+// RoutePreview is a route of the policy tree that an alert is routed to. The settings are the
+// effective ones, that is, including the settings the route inherits from its parent routes.
+type RoutePreview struct {
+	Path           RoutePath
+	Receiver       string
+	GroupBy        []string
+	GroupWait      time.Duration
+	GroupInterval  time.Duration
+	RepeatInterval time.Duration
+	// MuteTimeIntervals are the mute timings of the route.
+	MuteTimeIntervals []string
+	// ActiveMuteTimeIntervals are the mute timings of the route that mute its notifications at the
+	// time of the preview.
+	ActiveMuteTimeIntervals []string
+}
+
+// PreviewRoute returns the routes of the policy tree that an alert with the labels is routed to, in
+// the order the Alertmanager routes it. The routes are matched and the mute timings are checked with
+// the same code the Alertmanager uses, so the preview reflects how the alert would be routed.
+func (nps *NotificationPolicyService) PreviewRoute(ctx context.Context, orgID int64, lbls map[string]string, opts RoutePreviewOptions) ([]RoutePreview, error) {
+	revision, err := getLastConfiguration(ctx, orgID, nps.amStore)
+	if err != nil {
+		return nil, err
+	}
+	tree := revision.cfg.AlertmanagerConfig.Config.Route
+	if opts.Tree != nil {
+		if err := opts.Tree.Validate(); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrValidation, err.Error())
+		}
+		tree = opts.Tree
+	}
+	if tree == nil {
+		return nil, fmt.Errorf("no route present in current alertmanager config")
+	}
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	muteTimes := make(map[string][]timeinterval.TimeInterval, len(revision.cfg.AlertmanagerConfig.MuteTimeIntervals))
+	for _, ti := range revision.cfg.AlertmanagerConfig.MuteTimeIntervals {
+		muteTimes[ti.Name] = ti.TimeIntervals
+	}
+	muteStage := notify.NewTimeMuteStage(muteTimes)
+
+	root := dispatch.NewRoute(tree.AsAMRoute(), nil)
+	paths := make(map[*dispatch.Route]RoutePath)
+	collectRoutePaths(root, RoutePath{}, paths)
+
+	lset := make(model.LabelSet, len(lbls))
+	for name, value := range lbls {
+		lset[model.LabelName(name)] = model.LabelValue(value)
+	}
+	matched := root.Match(lset)
+	result := make([]RoutePreview, 0, len(matched))
+	for _, route := range matched {
+		preview := RoutePreview{
+			Path:              paths[route],
+			Receiver:          route.RouteOpts.Receiver,
+			GroupBy:           routeGroupBy(route.RouteOpts),
+			GroupWait:         route.RouteOpts.GroupWait,
+			GroupInterval:     route.RouteOpts.GroupInterval,
+			RepeatInterval:    route.RouteOpts.RepeatInterval,
+			MuteTimeIntervals: route.RouteOpts.MuteTimeIntervals,
+		}
+		for _, name := range route.RouteOpts.MuteTimeIntervals {
+			muted, err := nps.isMuted(ctx, muteStage, name, now, lset)
+			if err != nil {
+				return nil, fmt.Errorf("%w: route %s: %s", ErrValidation, preview.Path, err)
+			}
+			if muted {
+				preview.ActiveMuteTimeIntervals = append(preview.ActiveMuteTimeIntervals, name)
+			}
+		}
+		result = append(result, preview)
+	}
+	return result, nil
+}
+
+// isMuted runs the mute stage of the Alertmanager notification pipeline for the mute timing and
+// returns whether it mutes an alert with the labels at the given time.
+func (nps *NotificationPolicyService) isMuted(ctx context.Context, stage *notify.TimeMuteStage, name string, now time.Time, lset model.LabelSet) (bool, error) {
+	ctx = notify.WithMuteTimeIntervals(ctx, []string{name})
+	ctx = notify.WithNow(ctx, now)
+	alert := &types.Alert{Alert: model.Alert{Labels: lset, StartsAt: now}}
+	_, alerts, err := stage.Exec(ctx, nps.log, alert)
+	if err != nil {
+		return false, err
+	}
+	return len(alerts) == 0, nil
+}
+
+// collectRoutePaths stores the path of the route and all its child routes.
+func collectRoutePaths(route *dispatch.Route, path RoutePath, paths map[*dispatch.Route]RoutePath) {
+	paths[route] = path
+	for i, child := range route.Routes {
+		childPath := make(RoutePath, 0, len(path)+1)
+		childPath = append(childPath, path...)
+		collectRoutePaths(child, append(childPath, strconv.Itoa(i)), paths)
+	}
+}
+
+// routeGroupBy returns the sorted labels the alerts of the route are grouped by.
+func routeGroupBy(opts dispatch.RouteOpts) []string {
+	if opts.GroupByAll {
+		return []string{models.GroupByAll}
+	}
+	result := make([]string, 0, len(opts.GroupBy))
+	for label := range opts.GroupBy {
+		result = append(result, string(label))
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,131 @@
+package provisioning
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+)
+
+const routePreviewConfigJSON = `
+{
+	"alertmanager_config": {
+		"route": {
+			"receiver": "grafana-default-email",
+			"group_by": ["alertname"],
+			"routes": [{
+				"receiver": "a new receiver",
+				"object_matchers": [["team", "=", "a"]],
+				"continue": true,
+				"group_wait": "1m",
+				"mute_time_intervals": ["weekends"]
+			}, {
+				"object_matchers": [["severity", "=", "critical"]],
+				"routes": [{
+					"object_matchers": [["team", "=~", "a|b"]],
+					"group_by": ["..."]
+				}]
+			}]
+		},
+		"mute_time_intervals": [{
+			"name": "weekends",
+			"time_intervals": [{"weekdays": ["saturday", "sunday"]}]
+		}],
+		"receivers": [{
+			"name": "grafana-default-email",
+			"grafana_managed_receiver_configs": [{
+				"uid": "",
+				"name": "email receiver",
+				"type": "email",
+				"settings": {"addresses": "<example@email.com>"},
+				"secureFields": {}
+			}]
+		}, {
+			"name": "a new receiver",
+			"grafana_managed_receiver_configs": [{
+				"uid": "",
+				"name": "email receiver",
+				"type": "email",
+				"settings": {"addresses": "<another@email.com>"},
+				"secureFields": {}
+			}]
+		}]
+	}
+}
+`
+
+func TestNotificationPolicyServicePreviewRoute(t *testing.T) {
+	sut := createNotificationPolicyServiceSut()
+	sut.amStore.(*fakeAMConfigStore).config.AlertmanagerConfiguration = routePreviewConfigJSON
+	saturday := time.Date(2022, time.June, 4, 12, 0, 0, 0, time.UTC)
+	monday := time.Date(2022, time.June, 6, 12, 0, 0, 0, time.UTC)
+
+	t.Run("should return all matched routes with their effective settings", func(t *testing.T) {
+		routes, err := sut.PreviewRoute(context.Background(), 1, map[string]string{"team": "a", "severity": "critical"}, RoutePreviewOptions{Now: monday})
+		require.NoError(t, err)
+		require.Equal(t, []RoutePreview{
+			{
+				Path:              RoutePath{"0"},
+				Receiver:          "a new receiver",
+				GroupBy:           []string{"alertname"},
+				GroupWait:         time.Minute,
+				GroupInterval:     5 * time.Minute,
+				RepeatInterval:    4 * time.Hour,
+				MuteTimeIntervals: []string{"weekends"},
+			},
+			{
+				Path:           RoutePath{"1", "0"},
+				Receiver:       "grafana-default-email",
+				GroupBy:        []string{"..."},
+				GroupWait:      30 * time.Second,
+				GroupInterval:  5 * time.Minute,
+				RepeatInterval: 4 * time.Hour,
+			},
+		}, routes)
+	})
+
+	t.Run("should return the mute timings in effect", func(t *testing.T) {
+		routes, err := sut.PreviewRoute(context.Background(), 1, map[string]string{"team": "a"}, RoutePreviewOptions{Now: saturday})
+		require.NoError(t, err)
+		require.Len(t, routes, 1)
+		require.Equal(t, []string{"weekends"}, routes[0].ActiveMuteTimeIntervals)
+	})
+
+	t.Run("should return the root route if no child route matches", func(t *testing.T) {
+		routes, err := sut.PreviewRoute(context.Background(), 1, map[string]string{"team": "c"}, RoutePreviewOptions{})
+		require.NoError(t, err)
+		require.Len(t, routes, 1)
+		require.Equal(t, RoutePath{}, routes[0].Path)
+		require.Equal(t, "grafana-default-email", routes[0].Receiver)
+	})
+
+	t.Run("should route with a candidate tree", func(t *testing.T) {
+		tree := definitions.Route{
+			Receiver: "a new receiver",
+			Routes:   []*definitions.Route{{Receiver: "grafana-default-email", MuteTimeIntervals: []string{"weekends"}}},
+		}
+		routes, err := sut.PreviewRoute(context.Background(), 1, map[string]string{"team": "a"}, RoutePreviewOptions{Tree: &tree, Now: saturday})
+		require.NoError(t, err)
+		require.Len(t, routes, 1)
+		require.Equal(t, RoutePath{"0"}, routes[0].Path)
+		require.Equal(t, "grafana-default-email", routes[0].Receiver)
+		require.Equal(t, []string{"weekends"}, routes[0].ActiveMuteTimeIntervals)
+	})
+
+	t.Run("should reject invalid candidate trees", func(t *testing.T) {
+		_, err := sut.PreviewRoute(context.Background(), 1, map[string]string{"team": "a"}, RoutePreviewOptions{Tree: &definitions.Route{}})
+		require.ErrorIs(t, err, ErrValidation)
+	})
+
+	t.Run("should reject candidate trees with unknown mute timings", func(t *testing.T) {
+		tree := definitions.Route{
+			Receiver: "a new receiver",
+			Routes:   []*definitions.Route{{Receiver: "grafana-default-email", MuteTimeIntervals: []string{"holidays"}}},
+		}
+		_, err := sut.PreviewRoute(context.Background(), 1, map[string]string{"team": "a"}, RoutePreviewOptions{Tree: &tree})
+		require.ErrorIs(t, err, ErrValidation)
+	})
+}
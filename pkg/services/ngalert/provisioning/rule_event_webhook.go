@@ -0,0 +1,123 @@
+package provisioning
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+const (
+	// RuleEventSignatureHeader is the header of the HMAC-SHA256 signature of the body of rule events,
+	// in the form "sha256=<hex digest>". It is only set if the webhook has a secret.
+	RuleEventSignatureHeader = "X-Grafana-Signature"
+
+	ruleEventDefaultTimeout = 10 * time.Second
+)
+
+// ProvisioningWebhookConfig configures the webhook that is informed about the alert rules that the
+// AlertRuleService creates, updates and deletes.
+type ProvisioningWebhookConfig struct {
+	// URL receives the rule events. The webhook is disabled if it is empty.
+	URL string
+	// Secret is the key of the signature of the rule events. Events are not signed if it is empty.
+	Secret string
+	// Timeout limits how long the delivery of an event may take. 0 uses a timeout of 10 seconds.
+	Timeout time.Duration
+}
+
+// RuleEventType is the kind of change of a rule event.
+type RuleEventType string
+
+const (
+	RuleEventCreated RuleEventType = "rule_created"
+	RuleEventUpdated RuleEventType = "rule_updated"
+	RuleEventDeleted RuleEventType = "rule_deleted"
+)
+
+// RuleEvent describes a committed change of a single alert rule.
+type RuleEvent struct {
+	Type         RuleEventType     `json:"type"`
+	OrgID        int64             `json:"orgId"`
+	UID          string            `json:"uid"`
+	NamespaceUID string            `json:"namespaceUid,omitempty"`
+	RuleGroup    string            `json:"ruleGroup,omitempty"`
+	Title        string            `json:"title,omitempty"`
+	Provenance   models.Provenance `json:"provenance"`
+	Timestamp    time.Time         `json:"timestamp"`
+}
+
+func newRuleEvent(eventType RuleEventType, rule models.AlertRule, provenance models.Provenance) RuleEvent {
+	return RuleEvent{
+		Type:         eventType,
+		OrgID:        rule.OrgID,
+		UID:          rule.UID,
+		NamespaceUID: rule.NamespaceUID,
+		RuleGroup:    rule.RuleGroup,
+		Title:        rule.Title,
+		Provenance:   provenance,
+		Timestamp:    time.Now().UTC(),
+	}
+}
+
+// signRuleEvent returns the value of the RuleEventSignatureHeader of the body.
+func signRuleEvent(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// publishRuleEvent posts the event to the provisioning webhook in the background, so that the
+// operation that committed the change does not wait for the webhook. Failed deliveries are logged.
+func (service *AlertRuleService) publishRuleEvent(event RuleEvent) {
+	webhook := service.cfg.ProvisioningWebhook
+	if webhook.URL == "" {
+		return
+	}
+	go func() {
+		if err := sendRuleEvent(webhook, event); err != nil {
+			service.log.Warn("failed to send rule event to the provisioning webhook", "type", event.Type, "uid", event.UID, "error", err)
+		}
+	}()
+}
+
+func sendRuleEvent(webhook ProvisioningWebhookConfig, event RuleEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	timeout := webhook.Timeout
+	if timeout <= 0 {
+		timeout = ruleEventDefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if webhook.Secret != "" {
+		req.Header.Set(RuleEventSignatureHeader, signRuleEvent(webhook.Secret, body))
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package provisioning
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+func TestAlertRuleServiceRuleEvents(t *testing.T) {
+	type delivery struct {
+		body      []byte
+		signature string
+	}
+	deliveries := make(chan delivery, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		deliveries <- delivery{body: body, signature: r.Header.Get(RuleEventSignatureHeader)}
+	}))
+	defer server.Close()
+
+	const secret = "webhook-secret"
+	ruleService := createAlertRuleService(t)
+	ruleService.cfg.ProvisioningWebhook = ProvisioningWebhookConfig{URL: server.URL, Secret: secret, Timeout: time.Second}
+	receive := func(t *testing.T) RuleEvent {
+		t.Helper()
+		select {
+		case d := <-deliveries:
+			mac := hmac.New(sha256.New, []byte(secret))
+			_, _ = mac.Write(d.body)
+			require.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), d.signature)
+			var event RuleEvent
+			require.NoError(t, json.Unmarshal(d.body, &event))
+			return event
+		case <-time.After(time.Second):
+			require.FailNow(t, "the webhook did not receive the rule event")
+			return RuleEvent{}
+		}
+	}
+
+	rule := dummyRule("test#events", 1)
+	rule.Data[0].RelativeTimeRange.From = models.Duration(time.Minute)
+	rule, err := ruleService.CreateAlertRule(context.Background(), rule, models.ProvenanceAPI)
+	require.NoError(t, err)
+	event := receive(t)
+	require.Equal(t, RuleEventCreated, event.Type)
+	require.Equal(t, rule.OrgID, event.OrgID)
+	require.Equal(t, rule.UID, event.UID)
+	require.Equal(t, rule.NamespaceUID, event.NamespaceUID)
+	require.Equal(t, rule.RuleGroup, event.RuleGroup)
+	require.Equal(t, rule.Title, event.Title)
+	require.Equal(t, models.ProvenanceAPI, event.Provenance)
+	require.False(t, event.Timestamp.IsZero())
+
+	t.Run("should send updates and deletes", func(t *testing.T) {
+		rule.Title = "test#events updated"
+		_, err := ruleService.UpdateAlertRule(context.Background(), rule, models.ProvenanceAPI)
+		require.NoError(t, err)
+		event := receive(t)
+		require.Equal(t, RuleEventUpdated, event.Type)
+		require.Equal(t, "test#events updated", event.Title)
+
+		require.NoError(t, ruleService.DeleteAlertRule(context.Background(), rule.OrgID, rule.UID, models.ProvenanceAPI))
+		event = receive(t)
+		require.Equal(t, RuleEventDeleted, event.Type)
+		require.Equal(t, rule.UID, event.UID)
+	})
+	t.Run("should not send events of failed operations", func(t *testing.T) {
+		_, err := ruleService.UpdateAlertRule(context.Background(), rule, models.ProvenanceAPI)
+		require.Error(t, err)
+		select {
+		case <-deliveries:
+			require.FailNow(t, "the webhook should not receive events of failed operations")
+		case <-time.After(100 * time.Millisecond):
+		}
+	})
+}
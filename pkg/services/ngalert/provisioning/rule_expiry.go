@@ -0,0 +1,107 @@
+package provisioning
+
+import (
+	"context"
+	"time"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/store"
+)
+
+// SetAlertRuleTTL sets the rule to expire after the ttl, after which it is deleted by
+// DeleteExpiredAlertRules. The expiry is rounded up to whole seconds, and a ttl of zero or less
+// removes it. Like the evaluation order, the expiry is not part of the provisioned definition of the
+// rule, so it can be set for rules of any provenance.
+func (service *AlertRuleService) SetAlertRuleTTL(ctx context.Context, orgID int64, uid string, ttl time.Duration) (err error) {
+	defer wrapServiceError(&err)
+	if err := service.policy.checkMutation(orgID, ResourceTypeAlertRules, models.ProvenanceNone); err != nil {
+		return err
+	}
+	ctx, cancel := withTimeout(ctx, service.cfg.OperationTimeouts.Update)
+	defer cancel()
+	var rule models.AlertRule
+	err = service.xact.InTransaction(ctx, func(ctx context.Context) error {
+		query := &models.GetAlertRuleByUIDQuery{OrgID: orgID, UID: uid}
+		if err := service.ruleStore.GetAlertRuleByUID(ctx, query); err != nil {
+			return err
+		}
+		if query.Result == nil {
+			return models.ErrAlertRuleNotFound
+		}
+		if err := service.checkNamespaces(ctx, query.Result.NamespaceUID); err != nil {
+			return err
+		}
+		rule = *query.Result
+		rule.ExpiresAt = nil
+		if ttl > 0 {
+			// the store keeps whole seconds, so the expiry is rounded up for rules not to expire early.
+			expiresAt := service.clock.Now().Add(ttl)
+			if truncated := expiresAt.Truncate(time.Second); truncated.Before(expiresAt) {
+				expiresAt = truncated.Add(time.Second)
+			}
+			rule.ExpiresAt = &expiresAt
+		}
+		return service.ruleStore.UpdateAlertRules(ctx, []store.UpdateRule{{Existing: query.Result, New: rule}})
+	})
+	if err != nil {
+		return err
+	}
+	service.notifyGroupChange(ctx, RuleGroupChange{
+		OrgID:        orgID,
+		NamespaceUID: rule.NamespaceUID,
+		RuleGroup:    rule.RuleGroup,
+		Created:      []string{},
+		Updated:      []string{uid},
+		Deleted:      []string{},
+	})
+	return nil
+}
+
+// DeleteExpiredAlertRules deletes the rules of all organizations whose expiry has passed, and returns
+// how many were deleted. Rules are deleted with their stored provenance, and rules that cannot be
+// deleted are logged and retried on the next call.
+func (service *AlertRuleService) DeleteExpiredAlertRules(ctx context.Context) (int, error) {
+	now := service.clock.Now()
+	query := &models.ListAlertRulesQuery{
+		// A negative organization lists the rules of all organizations.
+		OrgID:     -1,
+		ExpiredAt: &now,
+	}
+	if err := service.ruleStore.ListAlertRules(ctx, query); err != nil {
+		return 0, err
+	}
+	deleted := 0
+	for _, rule := range query.Result {
+		provenance, err := service.provenanceStore.GetProvenance(ctx, rule, rule.OrgID)
+		if err != nil {
+			return deleted, err
+		}
+		if err := service.DeleteAlertRule(ctx, rule.OrgID, rule.UID, provenance); err != nil {
+			service.log.Error("failed to delete expired alert rule", "org", rule.OrgID, "uid", rule.UID, "err", err)
+			continue
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// RunAlertRuleExpiry deletes expired alert rules every interval until the context is done.
+func (service *AlertRuleService) RunAlertRuleExpiry(ctx context.Context, interval time.Duration) error {
+	ticker := service.clock.Ticker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			deleted, err := service.DeleteExpiredAlertRules(ctx)
+			if err != nil {
+				service.log.Error("failed to delete expired alert rules", "err", err)
+				continue
+			}
+			if deleted > 0 {
+				service.log.Info("deleted expired alert rules", "count", deleted)
+			}
+		}
+	}
+}
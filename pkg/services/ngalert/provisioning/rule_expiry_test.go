@@ -0,0 +1,73 @@
+package provisioning
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+func TestAlertRuleServiceExpiry(t *testing.T) {
+	ruleService := createAlertRuleService(t)
+	mockedClock := clock.NewMock()
+	mockedClock.Set(time.Date(2022, 6, 1, 10, 0, 0, 0, time.UTC))
+	ruleService.clock = mockedClock
+	const orgID = int64(91)
+	create := func(title string) models.AlertRule {
+		rule := dummyRule(title, orgID)
+		rule.Data[0].RelativeTimeRange.From = models.Duration(time.Minute)
+		created, err := ruleService.CreateAlertRule(context.Background(), rule, models.ProvenanceAPI)
+		require.NoError(t, err)
+		return created
+	}
+
+	t.Run("should delete rules after their expiry", func(t *testing.T) {
+		rule := create("test#expiring")
+		require.NoError(t, ruleService.SetAlertRuleTTL(context.Background(), orgID, rule.UID, time.Millisecond))
+		stored, _, err := ruleService.GetAlertRule(context.Background(), orgID, rule.UID)
+		require.NoError(t, err)
+		require.NotNil(t, stored.ExpiresAt)
+		require.True(t, stored.ExpiresAt.Equal(mockedClock.Now().Add(time.Second)), "the expiry should be rounded up to whole seconds")
+
+		deleted, err := ruleService.DeleteExpiredAlertRules(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, 0, deleted)
+
+		mockedClock.Add(time.Second)
+		deleted, err = ruleService.DeleteExpiredAlertRules(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, 1, deleted)
+		_, _, err = ruleService.GetAlertRule(context.Background(), orgID, rule.UID)
+		require.ErrorIs(t, err, models.ErrAlertRuleNotFound)
+	})
+	t.Run("rules without expiry should never expire", func(t *testing.T) {
+		rule := create("test#permanent")
+		require.Nil(t, rule.ExpiresAt)
+		mockedClock.Add(365 * 24 * time.Hour)
+		deleted, err := ruleService.DeleteExpiredAlertRules(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, 0, deleted)
+		_, _, err = ruleService.GetAlertRule(context.Background(), orgID, rule.UID)
+		require.NoError(t, err)
+	})
+	t.Run("should delete expired rules in the background", func(t *testing.T) {
+		rule := create("test#background")
+		require.NoError(t, ruleService.SetAlertRuleTTL(context.Background(), orgID, rule.UID, time.Millisecond))
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() {
+			done <- ruleService.RunAlertRuleExpiry(ctx, time.Minute)
+		}()
+		require.Eventually(t, func() bool {
+			mockedClock.Add(time.Minute)
+			_, _, err := ruleService.GetAlertRule(context.Background(), orgID, rule.UID)
+			return err != nil
+		}, time.Second, 10*time.Millisecond)
+		cancel()
+		require.NoError(t, <-done)
+	})
+}
@@ -0,0 +1,270 @@
+package provisioning
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+// RuleGroupDraftDiff describes how promoting a draft would change the live rule group.
+type RuleGroupDraftDiff struct {
+	// LiveInterval and DraftInterval are the evaluation intervals of the live group and the draft in seconds.
+	LiveInterval  int64
+	DraftInterval int64
+	// Added are the UIDs of the rules that only the draft has.
+	Added []string
+	// Removed are the UIDs of the rules that only the live group has.
+	Removed []string
+	// Changed are the paths of the changed fields by the UID of the rule.
+	Changed map[string][]string
+}
+
+// IsEmpty returns true if promoting the draft would not change the live group.
+func (d RuleGroupDraftDiff) IsEmpty() bool {
+	return d.LiveInterval == d.DraftInterval && len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// draftIgnoredFields are the fields of alert rules that are not compared by DiffDraft, because the
+// store or promoting the draft sets them.
+var draftIgnoredFields = []string{"ID", "Version", "Updated", "IntervalSeconds"}
+
+// CreateRuleGroupDraft copies the live rule group into a new draft. The owner of the draft is the
+// caller of the context. A group can only have a single draft at a time.
+func (service *AlertRuleService) CreateRuleGroupDraft(ctx context.Context, orgID int64, namespaceUID, group string) (_ models.RuleGroupDraft, err error) {
+	defer wrapServiceError(&err)
+	if service.drafts == nil {
+		return models.RuleGroupDraft{}, errors.New("rule group drafts are not supported")
+	}
+	if err := service.checkNamespaces(ctx, namespaceUID); err != nil {
+		return models.RuleGroupDraft{}, err
+	}
+	existing, err := service.drafts.GetRuleGroupDraft(ctx, orgID, namespaceUID, group)
+	if err != nil {
+		return models.RuleGroupDraft{}, err
+	}
+	if existing != nil {
+		return models.RuleGroupDraft{}, fmt.Errorf("%w: rule group '%s' already has a draft", ErrDraftExists, group)
+	}
+	rules, err := service.GetAlertRuleGroup(ctx, orgID, namespaceUID, group)
+	if err != nil {
+		return models.RuleGroupDraft{}, err
+	}
+	draft := models.RuleGroupDraft{
+		OrgID:           orgID,
+		NamespaceUID:    namespaceUID,
+		RuleGroup:       group,
+		IntervalSeconds: rules[0].IntervalSeconds,
+	}
+	if user := callerFromContext(ctx); user != nil {
+		draft.Owner = user.Login
+	}
+	if err := draft.SetRules(rules); err != nil {
+		return models.RuleGroupDraft{}, err
+	}
+	if err := service.drafts.SaveRuleGroupDraft(ctx, &draft); err != nil {
+		return models.RuleGroupDraft{}, err
+	}
+	return draft, nil
+}
+
+// UpdateRuleGroupDraft replaces the interval and the rules of the draft of the rule group. Rules
+// keep their UID, and rules without one get a new UID, so that the draft can be compared with the
+// live group. The live group is not changed.
+func (service *AlertRuleService) UpdateRuleGroupDraft(ctx context.Context, orgID int64, namespaceUID, group string, interval int64, rules []models.AlertRule) (_ models.RuleGroupDraft, err error) {
+	defer wrapServiceError(&err)
+	draft, err := service.getRuleGroupDraft(ctx, orgID, namespaceUID, group)
+	if err != nil {
+		return models.RuleGroupDraft{}, err
+	}
+	if err := service.validateGroupInterval(ctx, orgID, interval); err != nil {
+		return models.RuleGroupDraft{}, err
+	}
+	uids := make(map[string]struct{}, len(rules))
+	for i := range rules {
+		rule := &rules[i]
+		rule.OrgID = orgID
+		rule.NamespaceUID = namespaceUID
+		rule.RuleGroup = group
+		rule.RuleGroupIndex = i + 1
+		rule.IntervalSeconds = interval
+		if rule.UID == "" {
+			rule.UID = util.GenerateShortUID()
+		}
+		if _, ok := uids[rule.UID]; ok {
+			return models.RuleGroupDraft{}, fmt.Errorf("%w: rule UID '%s' is used more than once", ErrValidation, rule.UID)
+		}
+		uids[rule.UID] = struct{}{}
+		setRuleDefaults(rule)
+		if err := service.validateAlertRule(ctx, *rule); err != nil {
+			return models.RuleGroupDraft{}, err
+		}
+	}
+	draft.IntervalSeconds = interval
+	if err := draft.SetRules(rules); err != nil {
+		return models.RuleGroupDraft{}, err
+	}
+	if err := service.drafts.SaveRuleGroupDraft(ctx, draft); err != nil {
+		return models.RuleGroupDraft{}, err
+	}
+	return *draft, nil
+}
+
+// GetRuleGroupDraft returns the draft of the rule group.
+func (service *AlertRuleService) GetRuleGroupDraft(ctx context.Context, orgID int64, namespaceUID, group string) (_ models.RuleGroupDraft, err error) {
+	defer wrapServiceError(&err)
+	draft, err := service.getRuleGroupDraft(ctx, orgID, namespaceUID, group)
+	if err != nil {
+		return models.RuleGroupDraft{}, err
+	}
+	return *draft, nil
+}
+
+// ListRuleGroupDrafts returns the drafts of the organization.
+func (service *AlertRuleService) ListRuleGroupDrafts(ctx context.Context, orgID int64) (_ []models.RuleGroupDraft, err error) {
+	defer wrapServiceError(&err)
+	if service.drafts == nil {
+		return nil, errors.New("rule group drafts are not supported")
+	}
+	scope, err := service.callerNamespaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+	drafts, err := service.drafts.ListRuleGroupDrafts(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]models.RuleGroupDraft, 0, len(drafts))
+	for _, draft := range drafts {
+		if scope.allows(draft.NamespaceUID) {
+			result = append(result, *draft)
+		}
+	}
+	return result, nil
+}
+
+// DiffDraft compares the draft of the rule group with the live group.
+func (service *AlertRuleService) DiffDraft(ctx context.Context, orgID int64, namespaceUID, group string) (_ RuleGroupDraftDiff, err error) {
+	defer wrapServiceError(&err)
+	draft, err := service.getRuleGroupDraft(ctx, orgID, namespaceUID, group)
+	if err != nil {
+		return RuleGroupDraftDiff{}, err
+	}
+	draftRules, err := draft.GetRules()
+	if err != nil {
+		return RuleGroupDraftDiff{}, err
+	}
+	liveRules, err := service.GetAlertRuleGroup(ctx, orgID, namespaceUID, group)
+	if err != nil {
+		return RuleGroupDraftDiff{}, err
+	}
+	diff := RuleGroupDraftDiff{
+		DraftInterval: draft.IntervalSeconds,
+		Changed:       map[string][]string{},
+	}
+	live := make(map[string]*models.AlertRule, len(liveRules))
+	for i := range liveRules {
+		live[liveRules[i].UID] = &liveRules[i]
+		diff.LiveInterval = liveRules[i].IntervalSeconds
+	}
+	inDraft := make(map[string]struct{}, len(draftRules))
+	for i := range draftRules {
+		rule := &draftRules[i]
+		inDraft[rule.UID] = struct{}{}
+		liveRule, ok := live[rule.UID]
+		if !ok {
+			diff.Added = append(diff.Added, rule.UID)
+			continue
+		}
+		for _, change := range liveRule.Diff(rule, draftIgnoredFields...) {
+			diff.Changed[rule.UID] = append(diff.Changed[rule.UID], change.Path)
+		}
+	}
+	for _, rule := range liveRules {
+		if _, ok := inDraft[rule.UID]; !ok {
+			diff.Removed = append(diff.Removed, rule.UID)
+		}
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	return diff, nil
+}
+
+// PromoteDraft replaces the live rule group with the draft like ReplaceRuleGroup does, and deletes
+// the draft. Rules keep their UIDs.
+func (service *AlertRuleService) PromoteDraft(ctx context.Context, orgID int64, namespaceUID, group string, provenance models.Provenance) (err error) {
+	defer wrapServiceError(&err)
+	draft, err := service.getRuleGroupDraft(ctx, orgID, namespaceUID, group)
+	if err != nil {
+		return err
+	}
+	rules, err := draft.GetRules()
+	if err != nil {
+		return err
+	}
+	if err := service.ReplaceRuleGroup(ctx, orgID, namespaceUID, group, draft.IntervalSeconds, rules, provenance); err != nil {
+		return err
+	}
+	return service.drafts.DeleteRuleGroupDraft(ctx, draft.ID)
+}
+
+// DeleteRuleGroupDraft discards the draft of the rule group.
+func (service *AlertRuleService) DeleteRuleGroupDraft(ctx context.Context, orgID int64, namespaceUID, group string) (err error) {
+	defer wrapServiceError(&err)
+	draft, err := service.getRuleGroupDraft(ctx, orgID, namespaceUID, group)
+	if err != nil {
+		return err
+	}
+	return service.drafts.DeleteRuleGroupDraft(ctx, draft.ID)
+}
+
+// DeleteExpiredRuleGroupDrafts deletes the drafts of all organizations that were not updated within
+// the configured DraftTTL, and returns how many were deleted.
+func (service *AlertRuleService) DeleteExpiredRuleGroupDrafts(ctx context.Context) (int64, error) {
+	if service.drafts == nil || service.cfg.DraftTTL <= 0 {
+		return 0, nil
+	}
+	return service.drafts.DeleteRuleGroupDraftsUpdatedBefore(ctx, time.Now().Add(-service.cfg.DraftTTL))
+}
+
+// RunRuleGroupDraftCleanup deletes expired drafts every interval until the context is done.
+func (service *AlertRuleService) RunRuleGroupDraftCleanup(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			deleted, err := service.DeleteExpiredRuleGroupDrafts(ctx)
+			if err != nil {
+				service.log.Error("failed to delete expired rule group drafts", "err", err)
+				continue
+			}
+			if deleted > 0 {
+				service.log.Info("deleted expired rule group drafts", "count", deleted)
+			}
+		}
+	}
+}
+
+func (service *AlertRuleService) getRuleGroupDraft(ctx context.Context, orgID int64, namespaceUID, group string) (*models.RuleGroupDraft, error) {
+	if service.drafts == nil {
+		return nil, errors.New("rule group drafts are not supported")
+	}
+	if err := service.checkNamespaces(ctx, namespaceUID); err != nil {
+		return nil, err
+	}
+	draft, err := service.drafts.GetRuleGroupDraft(ctx, orgID, namespaceUID, group)
+	if err != nil {
+		return nil, err
+	}
+	if draft == nil {
+		return nil, fmt.Errorf("%w: rule group '%s'", ErrDraftNotFound, group)
+	}
+	return draft, nil
+}
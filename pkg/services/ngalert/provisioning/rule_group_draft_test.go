@@ -0,0 +1,131 @@
+package provisioning
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	models2 "github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/store"
+)
+
+func TestAlertRuleServiceRuleGroupDrafts(t *testing.T) {
+	ruleService := createAlertRuleService(t)
+	ruleService.drafts = ruleService.ruleStore.(store.DBstore)
+	var orgID int64 = 31
+	ctx := WithCaller(context.Background(), &models2.SignedInUser{OrgId: orgID, Login: "editor"})
+	// drafts store the relative time ranges of queries in seconds like the provisioning API does.
+	draftRule := func(title string) models.AlertRule {
+		rule := dummyRule(title, orgID)
+		rule.Data[0].RelativeTimeRange.From = models.Duration(time.Minute)
+		return rule
+	}
+	first, err := ruleService.CreateAlertRule(ctx, draftRule("test#draft-1"), models.ProvenanceAPI)
+	require.NoError(t, err)
+	second, err := ruleService.CreateAlertRule(ctx, draftRule("test#draft-2"), models.ProvenanceAPI)
+	require.NoError(t, err)
+	namespace, group := first.NamespaceUID, first.RuleGroup
+
+	t.Run("should snapshot the live group", func(t *testing.T) {
+		draft, err := ruleService.CreateRuleGroupDraft(ctx, orgID, namespace, group)
+		require.NoError(t, err)
+		require.Equal(t, "editor", draft.Owner)
+		require.Equal(t, int64(60), draft.IntervalSeconds)
+		require.False(t, draft.Created.IsZero())
+		rules, err := draft.GetRules()
+		require.NoError(t, err)
+		require.Len(t, rules, 2)
+
+		diff, err := ruleService.DiffDraft(ctx, orgID, namespace, group)
+		require.NoError(t, err)
+		require.True(t, diff.IsEmpty())
+
+		_, err = ruleService.CreateRuleGroupDraft(ctx, orgID, namespace, group)
+		require.ErrorIs(t, err, ErrDraftExists)
+		require.Equal(t, ErrCodeConflict, ErrorCodeOf(err))
+	})
+
+	t.Run("should list the drafts of the organization", func(t *testing.T) {
+		drafts, err := ruleService.ListRuleGroupDrafts(ctx, orgID)
+		require.NoError(t, err)
+		require.Len(t, drafts, 1)
+		require.Equal(t, group, drafts[0].RuleGroup)
+
+		drafts, err = ruleService.ListRuleGroupDrafts(ctx, orgID+1)
+		require.NoError(t, err)
+		require.Empty(t, drafts)
+	})
+
+	t.Run("should diff an updated draft without changing the live group", func(t *testing.T) {
+		draft, err := ruleService.GetRuleGroupDraft(ctx, orgID, namespace, group)
+		require.NoError(t, err)
+		rules, err := draft.GetRules()
+		require.NoError(t, err)
+		rules[0].Title = "test#draft-1-changed"
+		rules = append(rules[:1], draftRule("test#draft-3"))
+
+		draft, err = ruleService.UpdateRuleGroupDraft(ctx, orgID, namespace, group, 120, rules)
+		require.NoError(t, err)
+		rules, err = draft.GetRules()
+		require.NoError(t, err)
+		added := rules[1].UID
+		require.NotEmpty(t, added)
+
+		diff, err := ruleService.DiffDraft(ctx, orgID, namespace, group)
+		require.NoError(t, err)
+		require.Equal(t, int64(60), diff.LiveInterval)
+		require.Equal(t, int64(120), diff.DraftInterval)
+		require.Equal(t, []string{added}, diff.Added)
+		require.Equal(t, []string{second.UID}, diff.Removed)
+		require.Equal(t, map[string][]string{first.UID: {"Title"}}, diff.Changed)
+
+		live, err := ruleService.GetAlertRuleGroup(ctx, orgID, namespace, group)
+		require.NoError(t, err)
+		require.Len(t, live, 2)
+		require.Equal(t, "test#draft-1", live[0].Title)
+	})
+
+	t.Run("should promote the draft and keep the rule UIDs", func(t *testing.T) {
+		err := ruleService.PromoteDraft(ctx, orgID, namespace, group, models.ProvenanceAPI)
+		require.NoError(t, err)
+
+		live, err := ruleService.GetAlertRuleGroup(ctx, orgID, namespace, group)
+		require.NoError(t, err)
+		require.Len(t, live, 2)
+		require.Equal(t, first.UID, live[0].UID)
+		require.Equal(t, "test#draft-1-changed", live[0].Title)
+		require.Equal(t, int64(120), live[0].IntervalSeconds)
+
+		_, err = ruleService.GetRuleGroupDraft(ctx, orgID, namespace, group)
+		require.ErrorIs(t, err, ErrDraftNotFound)
+		require.Equal(t, ErrCodeRuleNotFound, ErrorCodeOf(err))
+	})
+
+	t.Run("should not create drafts of missing groups", func(t *testing.T) {
+		_, err := ruleService.CreateRuleGroupDraft(ctx, orgID, namespace, "missing-group")
+		require.ErrorIs(t, err, store.ErrAlertRuleGroupNotFound)
+	})
+
+	t.Run("should delete expired drafts", func(t *testing.T) {
+		_, err := ruleService.CreateRuleGroupDraft(ctx, orgID, namespace, group)
+		require.NoError(t, err)
+
+		deleted, err := ruleService.DeleteExpiredRuleGroupDrafts(ctx)
+		require.NoError(t, err)
+		require.Zero(t, deleted, "drafts are kept if no TTL is configured")
+
+		ruleService.cfg.DraftTTL = time.Hour
+		deleted, err = ruleService.DeleteExpiredRuleGroupDrafts(ctx)
+		require.NoError(t, err)
+		require.Zero(t, deleted)
+
+		ruleService.cfg.DraftTTL = time.Nanosecond
+		time.Sleep(time.Millisecond)
+		deleted, err = ruleService.DeleteExpiredRuleGroupDrafts(ctx)
+		require.NoError(t, err)
+		require.Equal(t, int64(1), deleted)
+	})
+}
@@ -0,0 +1,254 @@
+package provisioning
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana/pkg/expr"
+	models2 "github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+// ImportRuleGroupsOptions configures how exported rule groups are imported into an organization.
+type ImportRuleGroupsOptions struct {
+	// DataSourceUIDs maps the UIDs of the data sources of the exported rules to the UIDs of the data
+	// sources of the organization.
+	DataSourceUIDs map[string]string
+	// DataSourceNames maps the UIDs of the data sources of the exported rules to the names of the data
+	// sources of the organization. DataSourceUIDs takes precedence.
+	DataSourceNames map[string]string
+	// Strict fails the import if a data source of the exported rules is neither mapped nor exists in the
+	// organization. Otherwise such data sources are kept and listed in the report of the import.
+	Strict bool
+	// Provenance is the provenance of the imported rules.
+	Provenance models.Provenance
+}
+
+// ImportReport describes an import of rule groups.
+type ImportReport struct {
+	Groups int
+	Rules  int
+	// UnmappedDataSources are the UIDs of the data sources that are neither mapped nor exist in the
+	// organization, with the UIDs of the rules that query them.
+	UnmappedDataSources map[string][]string
+}
+
+// ImportRuleGroups imports exported rule groups into the organization. The data source UIDs of the
+// queries of every rule are rewritten according to the options before the rules are validated, and
+// each group then replaces the stored group of the same folder and name like ReplaceRuleGroup. Data
+// source references that are nested in query models, such as those of expression pipelines, are
+// rewritten as well. Groups are validated and remapped before any of them is stored.
+func (service *AlertRuleService) ImportRuleGroups(ctx context.Context, orgID int64, groups []definitions.AlertRuleGroupExport, opts ImportRuleGroupsOptions) (_ ImportReport, err error) {
+	defer wrapServiceError(&err)
+	remapper, err := service.newDataSourceRemapper(ctx, orgID, opts)
+	if err != nil {
+		return ImportReport{}, err
+	}
+	type importedGroup struct {
+		folderUID string
+		name      string
+		interval  int64
+		rules     []models.AlertRule
+	}
+	imported := make([]importedGroup, 0, len(groups))
+	report := ImportReport{}
+	for _, group := range groups {
+		g := importedGroup{
+			folderUID: group.Folder,
+			name:      group.Name,
+			interval:  int64(time.Duration(group.Interval).Seconds()),
+		}
+		for _, export := range group.Rules {
+			rule, err := parseAlertRuleExport(group, export)
+			if err != nil {
+				return ImportReport{}, fmt.Errorf("%w: rule %s: %s", ErrValidation, export.UID, err)
+			}
+			rule.OrgID = orgID
+			if err := remapper.remapRule(ctx, &rule); err != nil {
+				return ImportReport{}, err
+			}
+			g.rules = append(g.rules, rule)
+		}
+		imported = append(imported, g)
+		report.Rules += len(g.rules)
+	}
+	if len(remapper.unmapped) > 0 {
+		if opts.Strict {
+			return ImportReport{}, fmt.Errorf("%w: data sources are not mapped: %s", ErrValidation, strings.Join(remapper.unmappedUIDs(), ", "))
+		}
+		report.UnmappedDataSources = remapper.unmapped
+	}
+	for _, g := range imported {
+		if err := service.ReplaceRuleGroup(ctx, orgID, g.folderUID, g.name, g.interval, g.rules, opts.Provenance); err != nil {
+			return report, fmt.Errorf("failed to import rule group %s of folder %s: %w", g.name, g.folderUID, err)
+		}
+		report.Groups++
+	}
+	return report, nil
+}
+
+// dataSourceRemapper rewrites the data source UIDs of imported rules.
+type dataSourceRemapper struct {
+	orgID int64
+	uids  map[string]string
+	// existing caches whether UIDs without mapping exist in the organization.
+	existing    map[string]bool
+	datasources DataSourceLookup
+	unmapped    map[string][]string
+}
+
+// newDataSourceRemapper resolves the data source names of the options to UIDs. Names that cannot be
+// resolved are an error in strict mode and leave the data source unmapped otherwise.
+func (service *AlertRuleService) newDataSourceRemapper(ctx context.Context, orgID int64, opts ImportRuleGroupsOptions) (*dataSourceRemapper, error) {
+	remapper := &dataSourceRemapper{
+		orgID:       orgID,
+		uids:        make(map[string]string, len(opts.DataSourceUIDs)+len(opts.DataSourceNames)),
+		existing:    make(map[string]bool),
+		datasources: service.datasources,
+		unmapped:    make(map[string][]string),
+	}
+	for from, name := range opts.DataSourceNames {
+		if _, ok := opts.DataSourceUIDs[from]; ok {
+			continue
+		}
+		if service.datasources == nil {
+			return nil, errors.New("data sources cannot be mapped by name without a data source lookup")
+		}
+		query := &models2.GetDataSourceQuery{OrgId: orgID, Name: name}
+		err := service.datasources.GetDataSource(ctx, query)
+		if errors.Is(err, models2.ErrDataSourceNotFound) {
+			if opts.Strict {
+				return nil, fmt.Errorf("%w: data source %q that %s is mapped to is not found", ErrValidation, name, from)
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		remapper.uids[from] = query.Result.Uid
+	}
+	for from, to := range opts.DataSourceUIDs {
+		remapper.uids[from] = to
+	}
+	return remapper, nil
+}
+
+// remapRule rewrites the data source UIDs of the queries of the rule and of their models.
+func (r *dataSourceRemapper) remapRule(ctx context.Context, rule *models.AlertRule) error {
+	for i := range rule.Data {
+		query := &rule.Data[i]
+		uid, err := r.remap(ctx, rule.UID, query.DatasourceUID)
+		if err != nil {
+			return err
+		}
+		query.DatasourceUID = uid
+		if len(query.Model) == 0 {
+			continue
+		}
+		var model interface{}
+		if err := json.Unmarshal(query.Model, &model); err != nil {
+			return fmt.Errorf("%w: rule %s: failed to parse the model of query %s: %s", ErrValidation, rule.UID, query.RefID, err)
+		}
+		changed, err := r.remapModel(ctx, rule.UID, model)
+		if err != nil {
+			return err
+		}
+		if !changed {
+			continue
+		}
+		if query.Model, err = json.Marshal(model); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// remapModel rewrites the values of all datasourceUid keys and the uid of all datasource objects
+// of a query model, at any depth. It returns true if the model changed.
+func (r *dataSourceRemapper) remapModel(ctx context.Context, ruleUID string, value interface{}) (bool, error) {
+	changed := false
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if uid, ok := item.(string); ok && key == "datasourceUid" {
+				remapped, err := r.remap(ctx, ruleUID, uid)
+				if err != nil {
+					return false, err
+				}
+				changed = changed || remapped != uid
+				v[key] = remapped
+				continue
+			}
+			if datasource, ok := item.(map[string]interface{}); ok && key == "datasource" {
+				if uid, ok := datasource["uid"].(string); ok {
+					remapped, err := r.remap(ctx, ruleUID, uid)
+					if err != nil {
+						return false, err
+					}
+					changed = changed || remapped != uid
+					datasource["uid"] = remapped
+				}
+			}
+			c, err := r.remapModel(ctx, ruleUID, item)
+			if err != nil {
+				return false, err
+			}
+			changed = changed || c
+		}
+	case []interface{}:
+		for _, item := range v {
+			c, err := r.remapModel(ctx, ruleUID, item)
+			if err != nil {
+				return false, err
+			}
+			changed = changed || c
+		}
+	}
+	return changed, nil
+}
+
+// remap returns the UID that the data source is mapped to. Expressions are never mapped. A data
+// source without mapping keeps its UID and is recorded as unmapped if it does not exist in the
+// organization.
+func (r *dataSourceRemapper) remap(ctx context.Context, ruleUID, uid string) (string, error) {
+	if uid == "" || expr.IsDataSource(uid) {
+		return uid, nil
+	}
+	if to, ok := r.uids[uid]; ok {
+		return to, nil
+	}
+	exists, ok := r.existing[uid]
+	if !ok {
+		if r.datasources != nil {
+			err := r.datasources.GetDataSource(ctx, &models2.GetDataSourceQuery{OrgId: r.orgID, Uid: uid})
+			if err != nil && !errors.Is(err, models2.ErrDataSourceNotFound) {
+				return "", err
+			}
+			exists = err == nil
+		}
+		r.existing[uid] = exists
+	}
+	if !exists {
+		rules := r.unmapped[uid]
+		if len(rules) == 0 || rules[len(rules)-1] != ruleUID {
+			r.unmapped[uid] = append(rules, ruleUID)
+		}
+	}
+	return uid, nil
+}
+
+// unmappedUIDs returns the sorted UIDs of the unmapped data sources.
+func (r *dataSourceRemapper) unmappedUIDs() []string {
+	uids := make([]string, 0, len(r.unmapped))
+	for uid := range r.unmapped {
+		uids = append(uids, uid)
+	}
+	sort.Strings(uids)
+	return uids
+}
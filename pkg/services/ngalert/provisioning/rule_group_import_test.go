@@ -0,0 +1,155 @@
+package provisioning
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	models2 "github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+// fakeNamedDataSources finds data sources by UID and by name. It maps the UIDs of the data sources
+// of every organization to their names.
+type fakeNamedDataSources map[int64]map[string]string
+
+func (f fakeNamedDataSources) GetDataSource(ctx context.Context, query *models2.GetDataSourceQuery) error {
+	for uid, name := range f[query.OrgId] {
+		if (query.Uid != "" && uid == query.Uid) || (query.Name != "" && name == query.Name) {
+			query.Result = &models2.DataSource{OrgId: query.OrgId, Uid: uid, Name: name}
+			return nil
+		}
+	}
+	return models2.ErrDataSourceNotFound
+}
+
+func TestAlertRuleServiceImportRuleGroups(t *testing.T) {
+	ruleService := createAlertRuleService(t)
+	ruleService.datasources = fakeNamedDataSources{
+		2: {"prod-prom": "Prometheus", "prod-loki": "Loki"},
+		3: {"prod-prom": "Prometheus", "prod-loki": "Loki"},
+	}
+
+	rule := dummyRule("test#import", 1)
+	rule.Data = []models.AlertQuery{
+		{
+			RefID:             "A",
+			DatasourceUID:     "staging-prom",
+			Model:             json.RawMessage(`{"datasource":{"type":"prometheus","uid":"staging-prom"},"expr":"up"}`),
+			RelativeTimeRange: models.RelativeTimeRange{From: models.Duration(10 * time.Minute)},
+		},
+		{
+			RefID:             "B",
+			DatasourceUID:     "staging-loki",
+			Model:             json.RawMessage(`{"datasourceUid":"staging-loki","expr":"count_over_time({job=\"a\"}[5m])"}`),
+			RelativeTimeRange: models.RelativeTimeRange{From: models.Duration(10 * time.Minute)},
+		},
+		{
+			RefID:         "C",
+			DatasourceUID: "__expr__",
+			Model: json.RawMessage(`{"type":"classic_conditions","datasource":{"type":"__expr__","uid":"__expr__"},` +
+				`"conditions":[{"query":{"params":["A"],"datasourceUid":"staging-prom"},"reducer":{"type":"last"}}]}`),
+		},
+	}
+	rule.Condition = "C"
+	rule, err := ruleService.CreateAlertRule(context.Background(), rule, models.ProvenanceNone)
+	require.NoError(t, err)
+
+	// the groups are exported and parsed again like a file that is moved between instances.
+	query := &models.ListAlertRulesQuery{OrgID: 1}
+	require.NoError(t, ruleService.ruleStore.ListAlertRules(context.Background(), query))
+	file, err := NewAlertingFileExport(1, query.Result)
+	require.NoError(t, err)
+	data, err := yaml.Marshal(file)
+	require.NoError(t, err)
+	var exported definitions.AlertingFileExport
+	require.NoError(t, yaml.Unmarshal(data, &exported))
+
+	parseModel := func(t *testing.T, query models.AlertQuery) map[string]interface{} {
+		t.Helper()
+		var model map[string]interface{}
+		require.NoError(t, json.Unmarshal(query.Model, &model))
+		return model
+	}
+
+	t.Run("should rewrite the data sources of all queries and models", func(t *testing.T) {
+		report, err := ruleService.ImportRuleGroups(context.Background(), 2, exported.Groups, ImportRuleGroupsOptions{
+			DataSourceUIDs:  map[string]string{"staging-prom": "prod-prom"},
+			DataSourceNames: map[string]string{"staging-loki": "Loki"},
+			Strict:          true,
+			Provenance:      models.ProvenanceAPI,
+		})
+		require.NoError(t, err)
+		require.Equal(t, ImportReport{Groups: 1, Rules: 1}, report)
+
+		imported, provenance, err := ruleService.GetAlertRule(context.Background(), 2, rule.UID)
+		require.NoError(t, err)
+		require.Equal(t, models.ProvenanceAPI, provenance)
+		require.Equal(t, "my-cool-folder", imported.NamespaceUID)
+		require.Equal(t, "my-cool-group", imported.RuleGroup)
+		require.Len(t, imported.Data, 3)
+
+		require.Equal(t, "prod-prom", imported.Data[0].DatasourceUID)
+		require.Equal(t, map[string]interface{}{"type": "prometheus", "uid": "prod-prom"}, parseModel(t, imported.Data[0])["datasource"])
+		require.Equal(t, "prod-loki", imported.Data[1].DatasourceUID)
+		require.Equal(t, "prod-loki", parseModel(t, imported.Data[1])["datasourceUid"])
+
+		expression := parseModel(t, imported.Data[2])
+		require.Equal(t, "__expr__", imported.Data[2].DatasourceUID)
+		require.Equal(t, map[string]interface{}{"type": "__expr__", "uid": "__expr__"}, expression["datasource"])
+		condition := expression["conditions"].([]interface{})[0].(map[string]interface{})
+		require.Equal(t, "prod-prom", condition["query"].(map[string]interface{})["datasourceUid"])
+	})
+	t.Run("should not change the exported rules", func(t *testing.T) {
+		stored, _, err := ruleService.GetAlertRule(context.Background(), 1, rule.UID)
+		require.NoError(t, err)
+		require.Equal(t, "staging-prom", stored.Data[0].DatasourceUID)
+	})
+	t.Run("should fail on unmapped data sources in strict mode", func(t *testing.T) {
+		_, err := ruleService.ImportRuleGroups(context.Background(), 3, exported.Groups, ImportRuleGroupsOptions{
+			DataSourceUIDs: map[string]string{"staging-prom": "prod-prom"},
+			Strict:         true,
+		})
+		require.ErrorIs(t, err, ErrValidation)
+		require.Contains(t, err.Error(), "staging-loki")
+		_, _, err = ruleService.GetAlertRule(context.Background(), 3, rule.UID)
+		require.ErrorIs(t, err, models.ErrAlertRuleNotFound)
+	})
+	t.Run("should fail on data source names that are not found in strict mode", func(t *testing.T) {
+		_, err := ruleService.ImportRuleGroups(context.Background(), 3, exported.Groups, ImportRuleGroupsOptions{
+			DataSourceUIDs:  map[string]string{"staging-prom": "prod-prom"},
+			DataSourceNames: map[string]string{"staging-loki": "Missing"},
+			Strict:          true,
+		})
+		require.ErrorIs(t, err, ErrValidation)
+	})
+	t.Run("should report unmapped data sources otherwise", func(t *testing.T) {
+		report, err := ruleService.ImportRuleGroups(context.Background(), 3, exported.Groups, ImportRuleGroupsOptions{
+			DataSourceUIDs: map[string]string{"staging-prom": "prod-prom"},
+		})
+		require.NoError(t, err)
+		require.Equal(t, map[string][]string{"staging-loki": {rule.UID}}, report.UnmappedDataSources)
+
+		imported, _, err := ruleService.GetAlertRule(context.Background(), 3, rule.UID)
+		require.NoError(t, err)
+		require.Equal(t, "prod-prom", imported.Data[0].DatasourceUID)
+		require.Equal(t, "staging-loki", imported.Data[1].DatasourceUID)
+	})
+	t.Run("should update the rules of an earlier import", func(t *testing.T) {
+		report, err := ruleService.ImportRuleGroups(context.Background(), 2, exported.Groups, ImportRuleGroupsOptions{
+			DataSourceUIDs: map[string]string{"staging-prom": "prod-prom", "staging-loki": "prod-loki"},
+			Strict:         true,
+			Provenance:     models.ProvenanceAPI,
+		})
+		require.NoError(t, err)
+		require.Empty(t, report.UnmappedDataSources)
+		rules, err := ruleService.GetAlertRuleGroup(context.Background(), 2, "my-cool-folder", "my-cool-group")
+		require.NoError(t, err)
+		require.Len(t, rules, 1)
+	})
+}
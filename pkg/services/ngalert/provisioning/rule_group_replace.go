@@ -0,0 +1,654 @@
+package provisioning
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/store"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+const (
+	replaceBatchMaxAttempts = 3
+	replaceBatchBackoff     = 100 * time.Millisecond
+)
+
+// ReplaceRuleGroup replaces the rules of a rule group with the given rules. Stored rules are
+// updated if a given rule has their UID, and deleted otherwise. Given rules without a stored
+// counterpart are created. A replace of the same group that was interrupted is completed first.
+//
+// Replaces with more changes than the configured batch size are applied in batches, each in its
+// own transaction. If such a replace is interrupted, the group contains a part of the changes
+// until the next replace of the group completes it.
+func (service *AlertRuleService) ReplaceRuleGroup(ctx context.Context, orgID int64, namespaceUID, group string, interval int64, rules []models.AlertRule, provenance models.Provenance) (err error) {
+	defer wrapServiceError(&err)
+	if err := service.policy.checkMutation(orgID, ResourceTypeAlertRules, provenance); err != nil {
+		return err
+	}
+	if err := service.checkNamespaces(ctx, namespaceUID); err != nil {
+		return err
+	}
+	ctx, cancel := withTimeout(ctx, service.cfg.OperationTimeouts.Update)
+	defer cancel()
+	group, err = service.normalizeName("rule group", group)
+	if err != nil {
+		return err
+	}
+	if err := service.resumeRuleGroupReplace(ctx, orgID, namespaceUID, group); err != nil {
+		return err
+	}
+	if err := service.validateGroupInterval(ctx, orgID, interval); err != nil {
+		return err
+	}
+	rules, err = service.prepareReplaceRules(ctx, orgID, namespaceUID, group, interval, rules)
+	if err != nil {
+		return err
+	}
+	if len(rules) > 0 {
+		if err := service.validateRuleGroupName(ctx, rules[0]); err != nil {
+			return err
+		}
+	}
+	ops, err := service.diffRuleGroup(ctx, orgID, namespaceUID, group, rules, provenance)
+	if err != nil {
+		return err
+	}
+	if len(ops) == 0 {
+		return nil
+	}
+	if err := service.checkRuleCap(ctx, orgID, addedRules(ops)); err != nil {
+		return err
+	}
+	if service.replaceJournals == nil || service.cfg.ReplaceBatchSize <= 0 || len(ops) <= service.cfg.ReplaceBatchSize {
+		err = service.xact.InTransaction(ctx, func(ctx context.Context) error {
+			return service.applyReplaceOperations(ctx, orgID, ops, provenance)
+		})
+		if err != nil {
+			return err
+		}
+	} else {
+		journal := &models.RuleGroupReplaceJournal{
+			OrgID:        orgID,
+			NamespaceUID: namespaceUID,
+			RuleGroup:    group,
+			Provenance:   string(provenance),
+		}
+		if err := journal.SetOperations(ops); err != nil {
+			return err
+		}
+		if err := service.replaceJournals.SaveRuleGroupReplaceJournal(ctx, journal); err != nil {
+			return err
+		}
+		if err := service.applyJournal(ctx, journal, ops); err != nil {
+			return err
+		}
+	}
+	service.notifyGroupChange(ctx, replaceChange(orgID, namespaceUID, group, ops))
+	return nil
+}
+
+// UpdateRuleGroupFull sets the interval of the rule group and reconciles its rules with the given
+// rules like ReplaceRuleGroup does. Unlike ReplaceRuleGroup, all changes are always applied in a
+// single transaction, so that the group either has the given interval and rules or is unchanged.
+func (service *AlertRuleService) UpdateRuleGroupFull(ctx context.Context, orgID int64, namespaceUID, group string, interval int64, rules []models.AlertRule, provenance models.Provenance) (err error) {
+	defer wrapServiceError(&err)
+	if err := service.policy.checkMutation(orgID, ResourceTypeAlertRules, provenance); err != nil {
+		return err
+	}
+	if err := service.checkNamespaces(ctx, namespaceUID); err != nil {
+		return err
+	}
+	ctx, cancel := withTimeout(ctx, service.cfg.OperationTimeouts.Update)
+	defer cancel()
+	group, err = service.normalizeName("rule group", group)
+	if err != nil {
+		return err
+	}
+	if err := service.resumeRuleGroupReplace(ctx, orgID, namespaceUID, group); err != nil {
+		return err
+	}
+	if err := service.validateGroupInterval(ctx, orgID, interval); err != nil {
+		return err
+	}
+	rules, err = service.prepareReplaceRules(ctx, orgID, namespaceUID, group, interval, rules)
+	if err != nil {
+		return err
+	}
+	if len(rules) > 0 {
+		if err := service.validateRuleGroupName(ctx, rules[0]); err != nil {
+			return err
+		}
+	}
+	var ops []models.RuleGroupReplaceOperation
+	err = service.xact.InTransaction(ctx, func(ctx context.Context) error {
+		ops, err = service.diffRuleGroup(ctx, orgID, namespaceUID, group, rules, provenance)
+		if err != nil {
+			return err
+		}
+		return service.applyReplaceOperations(ctx, orgID, ops, provenance)
+	})
+	if err != nil {
+		return err
+	}
+	if len(ops) > 0 {
+		service.notifyGroupChange(ctx, replaceChange(orgID, namespaceUID, group, ops))
+	}
+	return nil
+}
+
+// CreateRuleGroupIfAbsent creates the rule group with the given rules if the group has no rules.
+// It returns false without an error if the group already exists, so that bootstrap provisioning
+// does not overwrite a group that was edited since.
+func (service *AlertRuleService) CreateRuleGroupIfAbsent(ctx context.Context, orgID int64, namespaceUID, group string, rules []models.AlertRule, interval int64, provenance models.Provenance) (_ bool, err error) {
+	defer wrapServiceError(&err)
+	if err := service.policy.checkMutation(orgID, ResourceTypeAlertRules, provenance); err != nil {
+		return false, err
+	}
+	if err := service.checkNamespaces(ctx, namespaceUID); err != nil {
+		return false, err
+	}
+	ctx, cancel := withTimeout(ctx, service.cfg.OperationTimeouts.Create)
+	defer cancel()
+	if len(rules) == 0 {
+		return false, fmt.Errorf("%w: rule group '%s' has no rules", ErrValidation, group)
+	}
+	if _, ok := service.cfg.RequireProvenanceOrgs[orgID]; ok && provenance == models.ProvenanceNone {
+		return false, fmt.Errorf("%w: alert rules of organization %d must be created with a provenance", ErrValidation, orgID)
+	}
+	group, err = service.normalizeName("rule group", group)
+	if err != nil {
+		return false, err
+	}
+	if err := service.validateGroupInterval(ctx, orgID, interval); err != nil {
+		return false, err
+	}
+	rules, err = service.prepareReplaceRules(ctx, orgID, namespaceUID, group, interval, rules)
+	if err != nil {
+		return false, err
+	}
+	if err := service.validateRuleGroupName(ctx, rules[0]); err != nil {
+		return false, err
+	}
+	if err := service.checkRuleCap(ctx, orgID, len(rules)); err != nil {
+		return false, err
+	}
+	ops := make([]models.RuleGroupReplaceOperation, 0, len(rules))
+	for i := range rules {
+		ops = append(ops, models.RuleGroupReplaceOperation{Kind: models.RuleGroupReplaceCreate, UID: rules[i].UID, Rule: &rules[i]})
+	}
+	// Two concurrent calls would both find the group absent before either is committed.
+	unlock := service.createLocks.Lock(fmt.Sprintf("%d/%s/group/%s", orgID, namespaceUID, group))
+	defer unlock()
+	created := false
+	err = service.xact.InTransaction(ctx, func(ctx context.Context) error {
+		_, err := service.ruleStore.GetRuleGroupInterval(ctx, orgID, namespaceUID, group)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, store.ErrAlertRuleGroupNotFound) {
+			return err
+		}
+		if err := service.applyReplaceOperations(ctx, orgID, ops, provenance); err != nil {
+			return err
+		}
+		created = true
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	if created {
+		service.notifyGroupChange(ctx, replaceChange(orgID, namespaceUID, group, ops))
+	}
+	return created, nil
+}
+
+// RuleGroupSpec is the wanted content of a rule group of ReplaceNamespaceRules.
+type RuleGroupSpec struct {
+	Interval int64
+	Rules    []models.AlertRule
+}
+
+// ReplaceNamespaceRules reconciles all rule groups of the namespace with the given groups, keyed by
+// their names, in a single transaction. The rules of every group are reconciled like ReplaceRuleGroup
+// does, except that rules can move between the groups of the namespace. Stored groups that are not
+// given are deleted with their rules.
+func (service *AlertRuleService) ReplaceNamespaceRules(ctx context.Context, orgID int64, namespaceUID string, groups map[string]RuleGroupSpec, provenance models.Provenance) (err error) {
+	defer wrapServiceError(&err)
+	if err := service.policy.checkMutation(orgID, ResourceTypeAlertRules, provenance); err != nil {
+		return err
+	}
+	if err := service.checkNamespaces(ctx, namespaceUID); err != nil {
+		return err
+	}
+	ctx, cancel := withTimeout(ctx, service.cfg.OperationTimeouts.Update)
+	defer cancel()
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	normalized := make(map[string]string, len(groups))
+	var rules []models.AlertRule
+	for _, name := range names {
+		group, err := service.normalizeName("rule group", name)
+		if err != nil {
+			return err
+		}
+		if other, ok := normalized[group]; ok {
+			return fmt.Errorf("%w: rule groups '%s' and '%s' have the same name", ErrValidation, other, name)
+		}
+		normalized[group] = name
+		spec := groups[name]
+		if err := service.validateGroupInterval(ctx, orgID, spec.Interval); err != nil {
+			return err
+		}
+		prepared, err := service.prepareReplaceRules(ctx, orgID, namespaceUID, group, spec.Interval, spec.Rules)
+		if err != nil {
+			return err
+		}
+		if len(prepared) > 0 {
+			if err := service.validateRuleGroupName(ctx, prepared[0]); err != nil {
+				return err
+			}
+		}
+		rules = append(rules, prepared...)
+	}
+	if err := service.resumeNamespaceReplaces(ctx, orgID, namespaceUID); err != nil {
+		return err
+	}
+	var ops []models.RuleGroupReplaceOperation
+	var storedGroups map[string]string
+	err = service.xact.InTransaction(ctx, func(ctx context.Context) error {
+		ops, storedGroups, err = service.diffNamespace(ctx, orgID, namespaceUID, rules, provenance)
+		if err != nil {
+			return err
+		}
+		return service.applyReplaceOperations(ctx, orgID, ops, provenance)
+	})
+	if err != nil {
+		return err
+	}
+	for _, change := range namespaceChanges(orgID, namespaceUID, ops, storedGroups) {
+		service.notifyGroupChange(ctx, change)
+	}
+	return nil
+}
+
+// resumeNamespaceReplaces completes the interrupted replaces of the stored groups of the namespace,
+// so that they cannot restore rules after the namespace was replaced.
+func (service *AlertRuleService) resumeNamespaceReplaces(ctx context.Context, orgID int64, namespaceUID string) error {
+	if service.replaceJournals == nil {
+		return nil
+	}
+	query := &models.ListAlertRulesQuery{OrgID: orgID, NamespaceUIDs: []string{namespaceUID}}
+	if err := service.ruleStore.ListAlertRules(ctx, query); err != nil {
+		return err
+	}
+	resumed := make(map[string]struct{})
+	for _, rule := range query.Result {
+		if _, ok := resumed[rule.RuleGroup]; ok {
+			continue
+		}
+		resumed[rule.RuleGroup] = struct{}{}
+		if err := service.resumeRuleGroupReplace(ctx, orgID, namespaceUID, rule.RuleGroup); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// diffNamespace returns the operations that turn the stored rules of the namespace into the given
+// rules, in the order of diffRuleGroup, and the rule groups of the stored rules by their UIDs.
+func (service *AlertRuleService) diffNamespace(ctx context.Context, orgID int64, namespaceUID string, rules []models.AlertRule, provenance models.Provenance) ([]models.RuleGroupReplaceOperation, map[string]string, error) {
+	query := &models.ListAlertRulesQuery{OrgID: orgID, NamespaceUIDs: []string{namespaceUID}}
+	if err := service.ruleStore.ListAlertRules(ctx, query); err != nil {
+		return nil, nil, err
+	}
+	provenances, err := service.provenanceStore.GetProvenances(ctx, orgID, (&models.AlertRule{}).ResourceType())
+	if err != nil {
+		return nil, nil, err
+	}
+	stored := make(map[string]string, len(query.Result))
+	for _, rule := range query.Result {
+		if storedProvenance, ok := provenances[rule.UID]; ok && storedProvenance != provenance && storedProvenance != models.ProvenanceNone {
+			return nil, nil, fmt.Errorf("%w: cannot replace rule '%s' with provenance '%s', needs '%s'", ErrProvenanceMismatch, rule.UID, provenance, storedProvenance)
+		}
+		stored[rule.UID] = rule.RuleGroup
+	}
+	wanted := make(map[string]struct{}, len(rules))
+	for _, rule := range rules {
+		if _, ok := wanted[rule.UID]; ok {
+			return nil, nil, fmt.Errorf("%w: rule UID '%s' is used more than once", ErrValidation, rule.UID)
+		}
+		wanted[rule.UID] = struct{}{}
+	}
+	var ops []models.RuleGroupReplaceOperation
+	for _, rule := range query.Result {
+		if _, ok := wanted[rule.UID]; !ok {
+			ops = append(ops, models.RuleGroupReplaceOperation{Kind: models.RuleGroupReplaceDelete, UID: rule.UID})
+		}
+	}
+	var creates []models.RuleGroupReplaceOperation
+	for i := range rules {
+		rule := rules[i]
+		if _, ok := stored[rule.UID]; ok {
+			ops = append(ops, models.RuleGroupReplaceOperation{Kind: models.RuleGroupReplaceUpdate, UID: rule.UID, Rule: &rule})
+			continue
+		}
+		err := service.ruleStore.GetAlertRuleByUID(ctx, &models.GetAlertRuleByUIDQuery{OrgID: orgID, UID: rule.UID})
+		if err == nil {
+			return nil, nil, fmt.Errorf("%w: rule '%s' belongs to another namespace", ErrValidation, rule.UID)
+		}
+		if !errors.Is(err, models.ErrAlertRuleNotFound) {
+			return nil, nil, err
+		}
+		creates = append(creates, models.RuleGroupReplaceOperation{Kind: models.RuleGroupReplaceCreate, UID: rule.UID, Rule: &rule})
+	}
+	return append(ops, creates...), stored, nil
+}
+
+// namespaceChanges returns the changes of every rule group of the namespace that the operations
+// changed, ordered by group. Rules that moved to another group are reported as updated in it.
+func namespaceChanges(orgID int64, namespaceUID string, ops []models.RuleGroupReplaceOperation, storedGroups map[string]string) []RuleGroupChange {
+	byGroup := make(map[string][]models.RuleGroupReplaceOperation)
+	for _, op := range ops {
+		group := storedGroups[op.UID]
+		if op.Rule != nil {
+			group = op.Rule.RuleGroup
+		}
+		byGroup[group] = append(byGroup[group], op)
+	}
+	groups := make([]string, 0, len(byGroup))
+	for group := range byGroup {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	changes := make([]RuleGroupChange, 0, len(groups))
+	for _, group := range groups {
+		changes = append(changes, replaceChange(orgID, namespaceUID, group, byGroup[group]))
+	}
+	return changes
+}
+
+// resumeRuleGroupReplace applies the remaining operations of an interrupted replace of the group, if there is one.
+func (service *AlertRuleService) resumeRuleGroupReplace(ctx context.Context, orgID int64, namespaceUID, group string) error {
+	if service.replaceJournals == nil {
+		return nil
+	}
+	journal, err := service.replaceJournals.GetRuleGroupReplaceJournal(ctx, orgID, namespaceUID, group)
+	if err != nil || journal == nil {
+		return err
+	}
+	ops, err := journal.GetOperations()
+	if err != nil {
+		return err
+	}
+	service.log.Info("resuming interrupted rule group replace", "org", orgID, "folder", namespaceUID, "group", group, "applied", journal.Applied, "total", len(ops))
+	if err := service.applyJournal(ctx, journal, ops); err != nil {
+		return err
+	}
+	service.notifyGroupChange(ctx, replaceChange(orgID, namespaceUID, group, ops))
+	return nil
+}
+
+// prepareReplaceRules validates the rules of a replace and moves them into the replaced group
+// in the given order. The labels of the group are replaced too, and removed if the rules have none.
+func (service *AlertRuleService) prepareReplaceRules(ctx context.Context, orgID int64, namespaceUID, group string, interval int64, rules []models.AlertRule) ([]models.AlertRule, error) {
+	groupLabels, err := replaceGroupLabels(rules)
+	if err != nil {
+		return nil, err
+	}
+	prepared := make([]models.AlertRule, 0, len(rules))
+	uids := make(map[string]struct{}, len(rules))
+	now := time.Now()
+	for i, rule := range rules {
+		rule.OrgID = orgID
+		rule.NamespaceUID = namespaceUID
+		rule.RuleGroup = group
+		title, err := service.normalizeName("title", rule.Title)
+		if err != nil {
+			return nil, err
+		}
+		rule.Title = title
+		rule.RuleGroupIndex = i + 1
+		rule.IntervalSeconds = interval
+		rule.GroupLabels = groupLabels
+		rule.Updated = now
+		if rule.UID == "" {
+			rule.UID = util.GenerateShortUID()
+		}
+		if _, ok := uids[rule.UID]; ok {
+			return nil, fmt.Errorf("%w: rule UID '%s' is used more than once", ErrValidation, rule.UID)
+		}
+		uids[rule.UID] = struct{}{}
+		if err := service.materializeLibraryQueries(ctx, &rule); err != nil {
+			return nil, err
+		}
+		setRuleDefaults(&rule)
+		if err := service.validateAlertRule(ctx, rule); err != nil {
+			return nil, err
+		}
+		rule.Annotations = withDashboardAnnotations(rule)
+		prepared = append(prepared, rule)
+	}
+	return prepared, nil
+}
+
+// replaceGroupLabels returns the labels of the group that all rules of a replace must agree on.
+// It never returns nil, so that the store does not keep the labels the group had before.
+func replaceGroupLabels(rules []models.AlertRule) (map[string]string, error) {
+	labels := map[string]string{}
+	for i, rule := range rules {
+		if i == 0 {
+			for k, v := range rule.GroupLabels {
+				labels[k] = v
+			}
+			continue
+		}
+		if !equalLabels(labels, rule.GroupLabels) {
+			return nil, fmt.Errorf("%w: rule '%s' has other group labels than rule '%s'", ErrValidation, rule.Title, rules[0].Title)
+		}
+	}
+	return labels, nil
+}
+
+// equalLabels returns whether both label sets have the same labels. Nil and empty sets are equal.
+func equalLabels(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if other, ok := b[k]; !ok || other != v {
+			return false
+		}
+	}
+	return true
+}
+
+// diffRuleGroup returns the operations that turn the stored rules of the group into the given rules.
+// Deletes come first so that the titles of deleted rules can be reused, then updates, then creates.
+func (service *AlertRuleService) diffRuleGroup(ctx context.Context, orgID int64, namespaceUID, group string, rules []models.AlertRule, provenance models.Provenance) ([]models.RuleGroupReplaceOperation, error) {
+	var ops []models.RuleGroupReplaceOperation
+	err := service.xact.InTransaction(ctx, func(ctx context.Context) error {
+		query := &models.ListAlertRulesQuery{
+			OrgID:         orgID,
+			NamespaceUIDs: []string{namespaceUID},
+			RuleGroup:     group,
+		}
+		if err := service.ruleStore.ListAlertRules(ctx, query); err != nil {
+			return err
+		}
+		provenances, err := service.provenanceStore.GetProvenances(ctx, orgID, (&models.AlertRule{}).ResourceType())
+		if err != nil {
+			return err
+		}
+		stored := make(map[string]struct{}, len(query.Result))
+		for _, rule := range query.Result {
+			if storedProvenance, ok := provenances[rule.UID]; ok && storedProvenance != provenance && storedProvenance != models.ProvenanceNone {
+				return fmt.Errorf("%w: cannot replace rule '%s' with provenance '%s', needs '%s'", ErrProvenanceMismatch, rule.UID, provenance, storedProvenance)
+			}
+			stored[rule.UID] = struct{}{}
+		}
+		wanted := make(map[string]struct{}, len(rules))
+		for _, rule := range rules {
+			wanted[rule.UID] = struct{}{}
+		}
+		for _, rule := range query.Result {
+			if _, ok := wanted[rule.UID]; !ok {
+				ops = append(ops, models.RuleGroupReplaceOperation{Kind: models.RuleGroupReplaceDelete, UID: rule.UID})
+			}
+		}
+		var creates []models.RuleGroupReplaceOperation
+		for i := range rules {
+			rule := rules[i]
+			if _, ok := stored[rule.UID]; ok {
+				ops = append(ops, models.RuleGroupReplaceOperation{Kind: models.RuleGroupReplaceUpdate, UID: rule.UID, Rule: &rule})
+				continue
+			}
+			// a rule of another group cannot be moved by a replace, and failing on insert
+			// would leave a batched replace that cannot be completed.
+			err := service.ruleStore.GetAlertRuleByUID(ctx, &models.GetAlertRuleByUIDQuery{OrgID: orgID, UID: rule.UID})
+			if err == nil {
+				return fmt.Errorf("%w: rule '%s' belongs to another rule group", ErrValidation, rule.UID)
+			}
+			if !errors.Is(err, models.ErrAlertRuleNotFound) {
+				return err
+			}
+			creates = append(creates, models.RuleGroupReplaceOperation{Kind: models.RuleGroupReplaceCreate, UID: rule.UID, Rule: &rule})
+		}
+		ops = append(ops, creates...)
+		return nil
+	})
+	return ops, err
+}
+
+// applyJournal applies the operations of the journal that are not applied yet in batches, and
+// deletes the journal once all of them are applied. Every batch records its progress in the
+// journal in the same transaction, so that an interrupted replace continues after the last
+// applied batch. Failed batches are retried with an exponential backoff.
+func (service *AlertRuleService) applyJournal(ctx context.Context, journal *models.RuleGroupReplaceJournal, ops []models.RuleGroupReplaceOperation) error {
+	batchSize := service.cfg.ReplaceBatchSize
+	if batchSize <= 0 {
+		batchSize = len(ops)
+	}
+	for journal.Applied < len(ops) {
+		end := journal.Applied + batchSize
+		if end > len(ops) {
+			end = len(ops)
+		}
+		err := service.retryReplaceBatch(ctx, func() error {
+			return service.xact.InTransaction(ctx, func(ctx context.Context) error {
+				if err := service.applyReplaceOperations(ctx, journal.OrgID, ops[journal.Applied:end], models.Provenance(journal.Provenance)); err != nil {
+					return err
+				}
+				progress := *journal
+				progress.Applied = end
+				return service.replaceJournals.SaveRuleGroupReplaceJournal(ctx, &progress)
+			})
+		})
+		if err != nil {
+			return fmt.Errorf("replace of rule group '%s' stopped after %d of %d changes and is completed by the next replace of the group: %w", journal.RuleGroup, journal.Applied, len(ops), err)
+		}
+		journal.Applied = end
+	}
+	return service.replaceJournals.DeleteRuleGroupReplaceJournal(ctx, journal.ID)
+}
+
+// retryReplaceBatch calls apply until it succeeds or the attempts are exhausted, waiting
+// twice as long after every attempt.
+func (service *AlertRuleService) retryReplaceBatch(ctx context.Context, apply func() error) error {
+	backoff := replaceBatchBackoff
+	for attempt := 1; ; attempt++ {
+		err := apply()
+		if err == nil || attempt >= replaceBatchMaxAttempts {
+			return err
+		}
+		service.log.Warn("failed to apply a batch of a rule group replace, retrying", "attempt", attempt, "err", err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// applyReplaceOperations applies the operations in the transaction of the context. Updates of rules
+// that do not exist anymore create them, so that resumed replaces converge to the requested rules.
+func (service *AlertRuleService) applyReplaceOperations(ctx context.Context, orgID int64, ops []models.RuleGroupReplaceOperation, provenance models.Provenance) error {
+	for _, op := range ops {
+		switch op.Kind {
+		case models.RuleGroupReplaceDelete:
+			if err := service.ruleStore.DeleteAlertRulesByUID(ctx, orgID, op.UID); err != nil {
+				return err
+			}
+			if err := service.provenanceStore.DeleteProvenance(ctx, &models.AlertRule{OrgID: orgID, UID: op.UID}, orgID); err != nil {
+				return err
+			}
+			continue
+		case models.RuleGroupReplaceUpdate:
+			query := &models.GetAlertRuleByUIDQuery{OrgID: orgID, UID: op.UID}
+			err := service.ruleStore.GetAlertRuleByUID(ctx, query)
+			if err == nil {
+				err = service.ruleStore.UpdateAlertRules(ctx, []store.UpdateRule{{Existing: query.Result, New: *op.Rule}})
+			} else if errors.Is(err, models.ErrAlertRuleNotFound) {
+				_, err = service.ruleStore.InsertAlertRules(ctx, []models.AlertRule{*op.Rule})
+			}
+			if err != nil {
+				return err
+			}
+		case models.RuleGroupReplaceCreate:
+			if _, err := service.ruleStore.InsertAlertRules(ctx, []models.AlertRule{*op.Rule}); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unknown rule group replace operation '%s'", op.Kind)
+		}
+		if err := service.provenanceStore.SetProvenance(ctx, op.Rule, orgID, provenance); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addedRules returns the number of rules that the operations add to the organization, which is
+// negative if they delete more rules than they create.
+func addedRules(ops []models.RuleGroupReplaceOperation) int {
+	added := 0
+	for _, op := range ops {
+		switch op.Kind {
+		case models.RuleGroupReplaceCreate:
+			added++
+		case models.RuleGroupReplaceDelete:
+			added--
+		}
+	}
+	return added
+}
+
+func replaceChange(orgID int64, namespaceUID, group string, ops []models.RuleGroupReplaceOperation) RuleGroupChange {
+	change := RuleGroupChange{
+		OrgID:        orgID,
+		NamespaceUID: namespaceUID,
+		RuleGroup:    group,
+		Created:      []string{},
+		Updated:      []string{},
+		Deleted:      []string{},
+	}
+	for _, op := range ops {
+		switch op.Kind {
+		case models.RuleGroupReplaceCreate:
+			change.Created = append(change.Created, op.UID)
+		case models.RuleGroupReplaceUpdate:
+			change.Updated = append(change.Updated, op.UID)
+		case models.RuleGroupReplaceDelete:
+			change.Deleted = append(change.Deleted, op.UID)
+		}
+	}
+	return change
+}
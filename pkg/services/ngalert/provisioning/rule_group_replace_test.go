@@ -0,0 +1,359 @@
+package provisioning
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/store"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlertRuleServiceReplaceRuleGroup(t *testing.T) {
+	var orgID int64 = 1
+	groupTitles := func(t *testing.T, service AlertRuleService, group string) []string {
+		t.Helper()
+		query := &models.ListAlertRulesQuery{OrgID: orgID, NamespaceUIDs: []string{"folder"}, RuleGroup: group}
+		require.NoError(t, service.ruleStore.ListAlertRules(context.Background(), query))
+		titles := make([]string, 0, len(query.Result))
+		for _, rule := range query.Result {
+			titles = append(titles, rule.Title)
+		}
+		sort.Strings(titles)
+		return titles
+	}
+	rules := func(titles ...string) []models.AlertRule {
+		result := make([]models.AlertRule, 0, len(titles))
+		for _, title := range titles {
+			rule := dummyRule(title, orgID)
+			rule.Data[0].RelativeTimeRange.From = models.Duration(10 * time.Minute)
+			result = append(result, rule)
+		}
+		return result
+	}
+
+	t.Run("small replaces should create, update and delete rules in a single transaction", func(t *testing.T) {
+		service := createAlertRuleService(t)
+		dbStore := service.ruleStore.(store.DBstore)
+		service.replaceJournals = dbStore
+		service.cfg.ReplaceBatchSize = 10
+
+		require.NoError(t, service.ReplaceRuleGroup(context.Background(), orgID, "folder", "group", 60, rules("a", "b", "c"), models.ProvenanceAPI))
+		require.Equal(t, []string{"a", "b", "c"}, groupTitles(t, service, "group"))
+
+		query := &models.ListAlertRulesQuery{OrgID: orgID, NamespaceUIDs: []string{"folder"}, RuleGroup: "group"}
+		require.NoError(t, service.ruleStore.ListAlertRules(context.Background(), query))
+		var kept models.AlertRule
+		for _, rule := range query.Result {
+			if rule.Title == "b" {
+				kept = *rule
+			}
+		}
+		kept.Title = "b-renamed"
+		require.NoError(t, service.ReplaceRuleGroup(context.Background(), orgID, "folder", "group", 120, append(rules("d"), kept), models.ProvenanceAPI))
+		require.Equal(t, []string{"b-renamed", "d"}, groupTitles(t, service, "group"))
+
+		updated, provenance, err := service.GetAlertRule(context.Background(), orgID, kept.UID)
+		require.NoError(t, err)
+		require.Equal(t, int64(120), updated.IntervalSeconds)
+		require.Equal(t, models.ProvenanceAPI, provenance)
+	})
+
+	t.Run("replaces should not change rules with another provenance", func(t *testing.T) {
+		service := createAlertRuleService(t)
+		require.NoError(t, service.ReplaceRuleGroup(context.Background(), orgID, "folder", "group", 60, rules("a"), models.ProvenanceFile))
+
+		err := service.ReplaceRuleGroup(context.Background(), orgID, "folder", "group", 60, rules("b"), models.ProvenanceAPI)
+		require.Error(t, err)
+		require.Equal(t, []string{"a"}, groupTitles(t, service, "group"))
+	})
+
+	t.Run("large replaces should be applied in batches", func(t *testing.T) {
+		service := createAlertRuleService(t)
+		dbStore := service.ruleStore.(store.DBstore)
+		service.replaceJournals = dbStore
+		service.cfg.ReplaceBatchSize = 2
+
+		require.NoError(t, service.ReplaceRuleGroup(context.Background(), orgID, "folder", "group", 60, rules("a", "b", "c", "d", "e"), models.ProvenanceAPI))
+		require.Equal(t, []string{"a", "b", "c", "d", "e"}, groupTitles(t, service, "group"))
+
+		journal, err := dbStore.GetRuleGroupReplaceJournal(context.Background(), orgID, "folder", "group")
+		require.NoError(t, err)
+		require.Nil(t, journal)
+	})
+
+	t.Run("interrupted replaces should be completed by the next replace", func(t *testing.T) {
+		service := createAlertRuleService(t)
+		dbStore := service.ruleStore.(store.DBstore)
+		failing := &failingInsertRuleStore{RuleStore: dbStore, allowed: 2}
+		service.ruleStore = failing
+		service.replaceJournals = dbStore
+		service.cfg.ReplaceBatchSize = 2
+
+		err := service.ReplaceRuleGroup(context.Background(), orgID, "folder", "group", 60, rules("a", "b", "c", "d", "e"), models.ProvenanceAPI)
+		require.ErrorIs(t, err, errInsertFailed)
+		require.Equal(t, []string{"a", "b"}, groupTitles(t, service, "group"))
+
+		journal, err := dbStore.GetRuleGroupReplaceJournal(context.Background(), orgID, "folder", "group")
+		require.NoError(t, err)
+		require.NotNil(t, journal)
+		require.Equal(t, 2, journal.Applied)
+		require.Equal(t, 5, journal.Total)
+
+		failing.allowed = -1
+		require.NoError(t, service.ReplaceRuleGroup(context.Background(), orgID, "folder", "other-group", 60, rules("x"), models.ProvenanceAPI))
+		require.Equal(t, []string{"a", "b"}, groupTitles(t, service, "group"), "replaces of other groups should not resume the replace")
+
+		require.NoError(t, service.resumeRuleGroupReplace(context.Background(), orgID, "folder", "group"))
+		require.Equal(t, []string{"a", "b", "c", "d", "e"}, groupTitles(t, service, "group"))
+
+		journal, err = dbStore.GetRuleGroupReplaceJournal(context.Background(), orgID, "folder", "group")
+		require.NoError(t, err)
+		require.Nil(t, journal)
+	})
+
+	t.Run("the next replace of an interrupted group should apply its own rules after completing it", func(t *testing.T) {
+		service := createAlertRuleService(t)
+		dbStore := service.ruleStore.(store.DBstore)
+		failing := &failingInsertRuleStore{RuleStore: dbStore, allowed: 3}
+		service.ruleStore = failing
+		service.replaceJournals = dbStore
+		service.cfg.ReplaceBatchSize = 2
+
+		err := service.ReplaceRuleGroup(context.Background(), orgID, "folder", "group", 60, rules("a", "b", "c", "d", "e"), models.ProvenanceAPI)
+		require.ErrorIs(t, err, errInsertFailed)
+
+		failing.allowed = -1
+		require.NoError(t, service.ReplaceRuleGroup(context.Background(), orgID, "folder", "group", 60, rules("f"), models.ProvenanceAPI))
+		require.Equal(t, []string{"f"}, groupTitles(t, service, "group"))
+	})
+}
+
+func TestAlertRuleServiceUpdateRuleGroupFull(t *testing.T) {
+	var orgID int64 = 1
+	rules := func(titles ...string) []models.AlertRule {
+		result := make([]models.AlertRule, 0, len(titles))
+		for _, title := range titles {
+			rule := dummyRule(title, orgID)
+			rule.Data[0].RelativeTimeRange.From = models.Duration(10 * time.Minute)
+			result = append(result, rule)
+		}
+		return result
+	}
+	storedGroup := func(t *testing.T, service AlertRuleService) ([]string, []int64) {
+		t.Helper()
+		query := &models.ListAlertRulesQuery{OrgID: orgID, NamespaceUIDs: []string{"folder"}, RuleGroup: "group"}
+		require.NoError(t, service.ruleStore.ListAlertRules(context.Background(), query))
+		titles := make([]string, 0, len(query.Result))
+		intervals := make([]int64, 0, len(query.Result))
+		for _, rule := range query.Result {
+			titles = append(titles, rule.Title)
+			intervals = append(intervals, rule.IntervalSeconds)
+		}
+		return titles, intervals
+	}
+	findRule := func(t *testing.T, service AlertRuleService, title string) models.AlertRule {
+		t.Helper()
+		query := &models.ListAlertRulesQuery{OrgID: orgID, NamespaceUIDs: []string{"folder"}, RuleGroup: "group"}
+		require.NoError(t, service.ruleStore.ListAlertRules(context.Background(), query))
+		for _, rule := range query.Result {
+			if rule.Title == title {
+				return *rule
+			}
+		}
+		require.Failf(t, "rule not found", "rule %s", title)
+		return models.AlertRule{}
+	}
+
+	t.Run("should change the interval and the rules of the group at once", func(t *testing.T) {
+		service := createAlertRuleService(t)
+		require.NoError(t, service.UpdateRuleGroupFull(context.Background(), orgID, "folder", "group", 60, rules("a", "b", "c"), models.ProvenanceAPI))
+
+		kept := findRule(t, service, "b")
+		kept.Title = "b-renamed"
+		err := service.UpdateRuleGroupFull(context.Background(), orgID, "folder", "group", 120, append([]models.AlertRule{kept}, rules("d")...), models.ProvenanceAPI)
+		require.NoError(t, err)
+
+		titles, intervals := storedGroup(t, service)
+		require.Equal(t, []string{"b-renamed", "d"}, titles)
+		require.Equal(t, []int64{120, 120}, intervals)
+		require.Equal(t, kept.UID, findRule(t, service, "b-renamed").UID)
+	})
+
+	t.Run("should not change the group if any change fails", func(t *testing.T) {
+		service := createAlertRuleService(t)
+		require.NoError(t, service.UpdateRuleGroupFull(context.Background(), orgID, "folder", "group", 60, rules("a", "b"), models.ProvenanceAPI))
+		service.ruleStore = &failingInsertRuleStore{RuleStore: service.ruleStore, allowed: 0}
+
+		kept := findRule(t, service, "b")
+		err := service.UpdateRuleGroupFull(context.Background(), orgID, "folder", "group", 120, append([]models.AlertRule{kept}, rules("c")...), models.ProvenanceAPI)
+		require.ErrorIs(t, err, errInsertFailed)
+
+		titles, intervals := storedGroup(t, service)
+		require.Equal(t, []string{"a", "b"}, titles)
+		require.Equal(t, []int64{60, 60}, intervals)
+	})
+
+	t.Run("should replace the labels of the group", func(t *testing.T) {
+		service := createAlertRuleService(t)
+		labelled := rules("a", "b")
+		for i := range labelled {
+			labelled[i].GroupLabels = map[string]string{"team": "a"}
+		}
+		require.NoError(t, service.UpdateRuleGroupFull(context.Background(), orgID, "folder", "group", 60, labelled, models.ProvenanceAPI))
+		require.Equal(t, map[string]string{"team": "a"}, findRule(t, service, "b").GroupLabels)
+
+		labelled[1].GroupLabels = map[string]string{"team": "b"}
+		err := service.UpdateRuleGroupFull(context.Background(), orgID, "folder", "group", 60, labelled, models.ProvenanceAPI)
+		require.ErrorIs(t, err, ErrValidation)
+
+		kept := findRule(t, service, "a")
+		kept.GroupLabels = nil
+		require.NoError(t, service.UpdateRuleGroupFull(context.Background(), orgID, "folder", "group", 60, []models.AlertRule{kept}, models.ProvenanceAPI))
+		require.Empty(t, findRule(t, service, "a").GroupLabels)
+	})
+
+	t.Run("should not change rules with another provenance", func(t *testing.T) {
+		service := createAlertRuleService(t)
+		require.NoError(t, service.UpdateRuleGroupFull(context.Background(), orgID, "folder", "group", 60, rules("a"), models.ProvenanceFile))
+
+		err := service.UpdateRuleGroupFull(context.Background(), orgID, "folder", "group", 120, rules("b"), models.ProvenanceAPI)
+		require.ErrorIs(t, err, ErrProvenanceMismatch)
+		titles, _ := storedGroup(t, service)
+		require.Equal(t, []string{"a"}, titles)
+	})
+}
+
+func TestAlertRuleServiceCreateRuleGroupIfAbsent(t *testing.T) {
+	var orgID int64 = 1
+	service := createAlertRuleService(t)
+	rules := func(titles ...string) []models.AlertRule {
+		result := make([]models.AlertRule, 0, len(titles))
+		for _, title := range titles {
+			result = append(result, dummyRule(title, orgID))
+		}
+		return result
+	}
+	groupTitles := func(t *testing.T, group string) []string {
+		t.Helper()
+		query := &models.ListAlertRulesQuery{OrgID: orgID, NamespaceUIDs: []string{"folder"}, RuleGroup: group}
+		require.NoError(t, service.ruleStore.ListAlertRules(context.Background(), query))
+		titles := make([]string, 0, len(query.Result))
+		for _, rule := range query.Result {
+			titles = append(titles, rule.Title)
+		}
+		sort.Strings(titles)
+		return titles
+	}
+
+	t.Run("should create the group if it has no rules", func(t *testing.T) {
+		created, err := service.CreateRuleGroupIfAbsent(context.Background(), orgID, "folder", "absent", rules("a", "b"), 120, models.ProvenanceFile)
+		require.NoError(t, err)
+		require.True(t, created)
+		require.Equal(t, []string{"a", "b"}, groupTitles(t, "absent"))
+
+		interval, err := service.GetRuleGroupInterval(context.Background(), orgID, "folder", "absent")
+		require.NoError(t, err)
+		require.Equal(t, int64(120), interval)
+	})
+	t.Run("should not change a group that already exists", func(t *testing.T) {
+		existing := dummyRule("edited", orgID)
+		existing.NamespaceUID = "folder"
+		existing.RuleGroup = "present"
+		_, err := service.CreateAlertRule(context.Background(), existing, models.ProvenanceNone)
+		require.NoError(t, err)
+
+		created, err := service.CreateRuleGroupIfAbsent(context.Background(), orgID, "folder", "present", rules("c"), 120, models.ProvenanceFile)
+		require.NoError(t, err)
+		require.False(t, created)
+		require.Equal(t, []string{"edited"}, groupTitles(t, "present"))
+	})
+}
+
+func TestAlertRuleServiceReplaceNamespaceRules(t *testing.T) {
+	var orgID int64 = 1
+	service := createAlertRuleService(t)
+	rules := func(titles ...string) []models.AlertRule {
+		result := make([]models.AlertRule, 0, len(titles))
+		for _, title := range titles {
+			rule := dummyRule(title, orgID)
+			rule.Data[0].RelativeTimeRange.From = models.Duration(10 * time.Minute)
+			result = append(result, rule)
+		}
+		return result
+	}
+	// namespaceRules returns the rules of the namespace by the titles of their groups and rules.
+	namespaceRules := func(t *testing.T) map[string]map[string]string {
+		t.Helper()
+		query := &models.ListAlertRulesQuery{OrgID: orgID, NamespaceUIDs: []string{"namespace"}}
+		require.NoError(t, service.ruleStore.ListAlertRules(context.Background(), query))
+		result := map[string]map[string]string{}
+		for _, rule := range query.Result {
+			if result[rule.RuleGroup] == nil {
+				result[rule.RuleGroup] = map[string]string{}
+			}
+			result[rule.RuleGroup][rule.Title] = rule.UID
+		}
+		return result
+	}
+
+	require.NoError(t, service.ReplaceNamespaceRules(context.Background(), orgID, "namespace", map[string]RuleGroupSpec{
+		"group-1": {Interval: 60, Rules: rules("a", "b")},
+		"group-2": {Interval: 120, Rules: rules("c")},
+	}, models.ProvenanceFile))
+	stored := namespaceRules(t)
+	require.Len(t, stored, 2)
+	require.Len(t, stored["group-1"], 2)
+	require.Len(t, stored["group-2"], 1)
+
+	t.Run("should delete the rules of groups that are not given", func(t *testing.T) {
+		moved := rules("c")[0]
+		moved.UID = stored["group-2"]["c"]
+		kept := rules("a")[0]
+		kept.UID = stored["group-1"]["a"]
+		require.NoError(t, service.ReplaceNamespaceRules(context.Background(), orgID, "namespace", map[string]RuleGroupSpec{
+			"group-1": {Interval: 60, Rules: []models.AlertRule{kept, moved}},
+		}, models.ProvenanceFile))
+
+		require.Equal(t, map[string]map[string]string{
+			"group-1": {"a": kept.UID, "c": moved.UID},
+		}, namespaceRules(t))
+	})
+	t.Run("should not change the namespace if a change fails", func(t *testing.T) {
+		before := namespaceRules(t)
+		ruleStore := service.ruleStore
+		service.ruleStore = &failingInsertRuleStore{RuleStore: ruleStore}
+		t.Cleanup(func() { service.ruleStore = ruleStore })
+
+		err := service.ReplaceNamespaceRules(context.Background(), orgID, "namespace", map[string]RuleGroupSpec{
+			"group-3": {Interval: 60, Rules: rules("d")},
+		}, models.ProvenanceFile)
+		require.ErrorIs(t, err, errInsertFailed)
+		require.Equal(t, before, namespaceRules(t))
+	})
+	t.Run("should reject other provenances", func(t *testing.T) {
+		err := service.ReplaceNamespaceRules(context.Background(), orgID, "namespace", nil, models.ProvenanceAPI)
+		require.ErrorIs(t, err, ErrProvenanceMismatch)
+	})
+}
+
+var errInsertFailed = errors.New("insert failed")
+
+// failingInsertRuleStore fails every insert after the allowed number of inserts. A negative number allows all inserts.
+type failingInsertRuleStore struct {
+	store.RuleStore
+	allowed int
+}
+
+func (s *failingInsertRuleStore) InsertAlertRules(ctx context.Context, rules []models.AlertRule) (map[string]int64, error) {
+	if s.allowed == 0 {
+		return nil, errInsertFailed
+	}
+	if s.allowed > 0 {
+		s.allowed--
+	}
+	return s.RuleStore.InsertAlertRules(ctx, rules)
+}
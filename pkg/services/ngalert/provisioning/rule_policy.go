@@ -0,0 +1,55 @@
+package provisioning
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+// RuleRouteSetter sets the routes of the notification policy tree that belong to single alert rules.
+type RuleRouteSetter interface {
+	SetRuleRoute(ctx context.Context, orgID int64, ruleUID string, route definitions.Route) error
+}
+
+// SetAlertRuleNotificationPolicy routes the alerts of the rule according to the policy. It merges a
+// route that matches the alerts of the rule into the notification policy tree of the organization.
+// The route replaces the route of a previous call for the same rule.
+func (service *AlertRuleService) SetAlertRuleNotificationPolicy(ctx context.Context, orgID int64, ruleUID string, policy models.SimplifiedPolicy) (err error) {
+	defer wrapServiceError(&err)
+	if service.ruleRoutes == nil {
+		return errors.New("notification policies of alert rules are not supported")
+	}
+	ctx, cancel := withTimeout(ctx, service.cfg.OperationTimeouts.Update)
+	defer cancel()
+	if err := policy.Validate(); err != nil {
+		return fmt.Errorf("%w: %s", ErrValidation, err)
+	}
+	exists, err := service.contactPointValidator.Exists(ctx, orgID, policy.ReceiverName)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("%w: '%s'", ErrContactPointNotFound, policy.ReceiverName)
+	}
+	query := &models.GetAlertRuleByUIDQuery{OrgID: orgID, UID: ruleUID}
+	if err := service.ruleStore.GetAlertRuleByUID(ctx, query); err != nil {
+		return err
+	}
+	if query.Result == nil {
+		return models.ErrAlertRuleNotFound
+	}
+	if err := service.checkNamespaces(ctx, query.Result.NamespaceUID); err != nil {
+		return err
+	}
+	route, err := definitions.SimplifiedPolicyRoute(ruleUID, policy)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrValidation, err)
+	}
+
+	unlock := service.createLocks.Lock(fmt.Sprintf("%d/policy-tree", orgID))
+	defer unlock()
+	return service.ruleRoutes.SetRuleRoute(ctx, orgID, ruleUID, *route)
+}
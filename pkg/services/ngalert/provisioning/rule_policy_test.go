@@ -0,0 +1,113 @@
+package provisioning
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/store"
+)
+
+func TestAlertRuleServiceSetAlertRuleNotificationPolicy(t *testing.T) {
+	const orgID = 1
+	setup := func(t *testing.T) (*AlertRuleService, *NotificationPolicyService) {
+		t.Helper()
+		ruleStore := store.NewFakeRuleStore(t)
+		rule := dummyRule("test#policy", orgID)
+		rule.UID = "rule-uid"
+		ruleStore.PutRule(context.Background(), &rule)
+		policies := createNotificationPolicyServiceSut()
+		service := &AlertRuleService{
+			ruleStore:             ruleStore,
+			provenanceStore:       NewFakeProvisioningStore(),
+			contactPointValidator: newFakeContactPointValidator("grafana-default-email", "a new receiver"),
+			xact:                  newNopTransactionManager(),
+			log:                   log.NewNopLogger(),
+			createLocks:           newKeyedMutex(),
+			ruleRoutes:            policies,
+		}
+		return service, policies
+	}
+	ruleRoutes := func(t *testing.T, policies *NotificationPolicyService) []*definitions.Route {
+		t.Helper()
+		tree, err := policies.GetPolicyTree(context.Background(), orgID)
+		require.NoError(t, err)
+		var result []*definitions.Route
+		for _, child := range tree.Routes {
+			for _, matcher := range child.ObjectMatchers {
+				if matcher.Name == models.RuleUIDLabel {
+					result = append(result, child)
+				}
+			}
+		}
+		return result
+	}
+
+	t.Run("should merge the route of the rule into the policy tree", func(t *testing.T) {
+		service, policies := setup(t)
+		groupWait := 30 * time.Second
+		err := service.SetAlertRuleNotificationPolicy(context.Background(), orgID, "rule-uid", models.SimplifiedPolicy{
+			ReceiverName: "a new receiver",
+			GroupBy:      []string{"alertname", "team"},
+			GroupWait:    &groupWait,
+		})
+		require.NoError(t, err)
+
+		tree, err := policies.GetPolicyTree(context.Background(), orgID)
+		require.NoError(t, err)
+		require.Equal(t, "grafana-default-email", tree.Receiver)
+		require.Len(t, tree.Routes, 2)
+		route := tree.Routes[0]
+		require.Equal(t, "a new receiver", route.Receiver)
+		require.Equal(t, []string{"alertname", "team"}, route.GroupByStr)
+		require.Equal(t, `__alert_rule_uid__="rule-uid"`, route.ObjectMatchers[0].String())
+		require.Equal(t, "30s", route.GroupWait.String())
+		require.Nil(t, route.GroupInterval)
+		require.Nil(t, route.RepeatInterval)
+		require.Equal(t, "grafana-default-email", tree.Routes[1].Receiver)
+	})
+
+	t.Run("should replace the route of a previous policy of the rule", func(t *testing.T) {
+		service, policies := setup(t)
+		err := service.SetAlertRuleNotificationPolicy(context.Background(), orgID, "rule-uid", models.SimplifiedPolicy{ReceiverName: "a new receiver"})
+		require.NoError(t, err)
+		repeat := time.Hour
+		err = service.SetAlertRuleNotificationPolicy(context.Background(), orgID, "rule-uid", models.SimplifiedPolicy{
+			ReceiverName:   "grafana-default-email",
+			RepeatInterval: &repeat,
+		})
+		require.NoError(t, err)
+
+		routes := ruleRoutes(t, policies)
+		require.Len(t, routes, 1)
+		require.Equal(t, "grafana-default-email", routes[0].Receiver)
+		require.Equal(t, "1h", routes[0].RepeatInterval.String())
+		tree, err := policies.GetPolicyTree(context.Background(), orgID)
+		require.NoError(t, err)
+		require.Len(t, tree.Routes, 2)
+	})
+
+	t.Run("should fail for unknown rules", func(t *testing.T) {
+		service, policies := setup(t)
+		err := service.SetAlertRuleNotificationPolicy(context.Background(), orgID, "unknown", models.SimplifiedPolicy{ReceiverName: "a new receiver"})
+		require.ErrorIs(t, err, models.ErrAlertRuleNotFound)
+		require.Empty(t, ruleRoutes(t, policies))
+	})
+
+	t.Run("should reject invalid policies", func(t *testing.T) {
+		service, policies := setup(t)
+		zero := time.Duration(0)
+		err := service.SetAlertRuleNotificationPolicy(context.Background(), orgID, "rule-uid", models.SimplifiedPolicy{ReceiverName: "unknown"})
+		require.ErrorIs(t, err, ErrContactPointNotFound)
+		err = service.SetAlertRuleNotificationPolicy(context.Background(), orgID, "rule-uid", models.SimplifiedPolicy{})
+		require.ErrorIs(t, err, ErrValidation)
+		err = service.SetAlertRuleNotificationPolicy(context.Background(), orgID, "rule-uid", models.SimplifiedPolicy{ReceiverName: "a new receiver", GroupInterval: &zero})
+		require.ErrorIs(t, err, ErrValidation)
+		require.Empty(t, ruleRoutes(t, policies))
+	})
+}
@@ -0,0 +1,72 @@
+package provisioning
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-openapi/strfmt"
+	amv2 "github.com/prometheus/alertmanager/api/v2/models"
+	prometheusModel "github.com/prometheus/common/model"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+const (
+	// TestNotificationLabel is set on the alerts of TestAlertRuleNotification, so that they are not
+	// mistaken for, and do not replace, the alerts of the rule.
+	TestNotificationLabel = "grafana_test_notification"
+
+	// testNotificationDuration is how long the test alert fires before the Alertmanager resolves it.
+	testNotificationDuration = 5 * time.Minute
+)
+
+// AlertSender sends alerts to the Alertmanager of an organization.
+type AlertSender interface {
+	PutAlerts(orgID int64, alerts definitions.PostableAlerts) error
+}
+
+// TestAlertRuleNotification sends a firing alert with the labels and annotations of the rule to the
+// Alertmanager of the organization, so that it is routed and delivered like the alerts of the rule
+// without waiting for the rule to fire. Annotations are sent without expanding their templates.
+func (service *AlertRuleService) TestAlertRuleNotification(ctx context.Context, orgID int64, ruleUID string) (err error) {
+	defer wrapServiceError(&err)
+	if service.alerts == nil {
+		return errors.New("test notifications of alert rules are not supported")
+	}
+	rule, _, err := service.GetAlertRule(ctx, orgID, ruleUID)
+	if err != nil {
+		return err
+	}
+	alert := testNotificationAlert(rule, service.clock.Now())
+	return service.alerts.PutAlerts(orgID, definitions.PostableAlerts{PostableAlerts: []amv2.PostableAlert{alert}})
+}
+
+// testNotificationAlert returns the alert of a test notification of the rule that fires at the given time.
+func testNotificationAlert(rule models.AlertRule, now time.Time) amv2.PostableAlert {
+	labels := make(amv2.LabelSet, len(rule.Labels)+8)
+	for k, v := range rule.Labels {
+		labels[k] = v
+	}
+	labels[models.RuleUIDLabel] = rule.UID
+	labels[models.NamespaceUIDLabel] = rule.NamespaceUID
+	labels[prometheusModel.AlertNameLabel] = rule.Title
+	for k, v := range rule.MetaLabels() {
+		labels[k] = v
+	}
+	labels[TestNotificationLabel] = "true"
+
+	annotations := make(amv2.LabelSet, len(rule.Annotations))
+	for k, v := range rule.Annotations {
+		annotations[k] = v
+	}
+	return amv2.PostableAlert{
+		Annotations: annotations,
+		StartsAt:    strfmt.DateTime(now),
+		EndsAt:      strfmt.DateTime(now.Add(testNotificationDuration)),
+		Alert: amv2.Alert{
+			Labels: labels,
+		},
+	}
+}
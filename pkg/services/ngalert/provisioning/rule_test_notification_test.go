@@ -0,0 +1,80 @@
+package provisioning
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/go-openapi/strfmt"
+	amv2 "github.com/prometheus/alertmanager/api/v2/models"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+type fakeAlertSender struct {
+	orgID  int64
+	alerts []amv2.PostableAlert
+}
+
+func (f *fakeAlertSender) PutAlerts(orgID int64, alerts definitions.PostableAlerts) error {
+	f.orgID = orgID
+	f.alerts = append(f.alerts, alerts.PostableAlerts...)
+	return nil
+}
+
+func TestAlertRuleServiceTestAlertRuleNotification(t *testing.T) {
+	ruleService := createAlertRuleService(t)
+	mockClock := clock.NewMock()
+	mockClock.Set(time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC))
+	ruleService.clock = mockClock
+
+	rule := dummyRule("test#notification", 1)
+	rule.Labels = map[string]string{"team": "a", "severity": "page"}
+	rule.Annotations = map[string]string{"summary": "{{ $labels.team }} is down"}
+	rule, err := ruleService.CreateAlertRule(context.Background(), rule, models.ProvenanceNone)
+	require.NoError(t, err)
+
+	t.Run("should fail without alert sender", func(t *testing.T) {
+		require.Error(t, ruleService.TestAlertRuleNotification(context.Background(), 1, rule.UID))
+	})
+
+	sender := &fakeAlertSender{}
+	ruleService.alerts = sender
+
+	t.Run("should send a firing alert with the labels and annotations of the rule", func(t *testing.T) {
+		require.NoError(t, ruleService.TestAlertRuleNotification(context.Background(), 1, rule.UID))
+		require.Equal(t, int64(1), sender.orgID)
+		require.Len(t, sender.alerts, 1)
+		alert := sender.alerts[0]
+		require.Equal(t, amv2.LabelSet{
+			"team":                       "a",
+			"severity":                   "page",
+			"alertname":                  "test#notification",
+			models.RuleUIDLabel:          rule.UID,
+			models.NamespaceUIDLabel:     "my-cool-folder",
+			models.OrgIDMetaLabel:        "1",
+			models.RuleUIDMetaLabel:      rule.UID,
+			models.RuleGroupMetaLabel:    "my-cool-group",
+			models.NamespaceUIDMetaLabel: "my-cool-folder",
+			TestNotificationLabel:        "true",
+		}, alert.Labels)
+		require.Equal(t, amv2.LabelSet{"summary": "{{ $labels.team }} is down"}, alert.Annotations)
+		require.Equal(t, strfmt.DateTime(mockClock.Now()), alert.StartsAt)
+		require.Equal(t, strfmt.DateTime(mockClock.Now().Add(testNotificationDuration)), alert.EndsAt)
+	})
+	t.Run("should not change the labels of the rule", func(t *testing.T) {
+		stored, _, err := ruleService.GetAlertRule(context.Background(), 1, rule.UID)
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{"team": "a", "severity": "page"}, stored.Labels)
+	})
+	t.Run("should fail for missing rules", func(t *testing.T) {
+		err := ruleService.TestAlertRuleNotification(context.Background(), 1, "missing")
+		require.ErrorIs(t, err, models.ErrAlertRuleNotFound)
+		err = ruleService.TestAlertRuleNotification(context.Background(), 2, rule.UID)
+		require.ErrorIs(t, err, models.ErrAlertRuleNotFound)
+		require.Len(t, sender.alerts, 1)
+	})
+}
@@ -0,0 +1,111 @@
+package provisioning
+
+import (
+	"context"
+	"errors"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/store"
+)
+
+// ErrorCode is a machine-readable identifier of the kind of failure of an AlertRuleService operation.
+type ErrorCode string
+
+const (
+	ErrCodeRuleNotFound       ErrorCode = "rule.not_found"
+	ErrCodeProvenanceMismatch ErrorCode = "rule.provenance_mismatch"
+	ErrCodeValidation         ErrorCode = "rule.validation"
+	ErrCodeConflict           ErrorCode = "rule.conflict"
+	// ErrCodeProvisioningDisabled is returned for changes of alert rules if they are read-only.
+	ErrCodeProvisioningDisabled ErrorCode = "rule.provisioning_disabled"
+	// ErrCodeQuotaExceeded is returned for new alert rules if the organization has no quota left.
+	ErrCodeQuotaExceeded ErrorCode = "rule.quota_exceeded"
+	// ErrCodeRateLimited is returned for changes if the organization changes resources too fast.
+	ErrCodeRateLimited ErrorCode = "rule.rate_limited"
+	// ErrCodeAccessDenied is returned for operations across organizations that the caller is not allowed.
+	ErrCodeAccessDenied ErrorCode = "rule.access_denied"
+	// ErrCodeOptimisticLock is returned for updates based on an outdated version of a rule.
+	ErrCodeOptimisticLock ErrorCode = "rule.optimistic_lock"
+	ErrCodeTimeout        ErrorCode = "rule.timeout"
+	ErrCodeInternal       ErrorCode = "rule.internal"
+)
+
+// ServiceError is returned by the operations of the AlertRuleService. It wraps the cause
+// of the failure, so that errors.Is and errors.As keep working on the returned errors.
+type ServiceError struct {
+	Code    ErrorCode
+	Message string
+	Err     error
+}
+
+func (e *ServiceError) Error() string {
+	return e.Message
+}
+
+func (e *ServiceError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorCodeOf returns the code of the ServiceError in the chain of err, or an empty code if there is none.
+func ErrorCodeOf(err error) ErrorCode {
+	var serviceErr *ServiceError
+	if errors.As(err, &serviceErr) {
+		return serviceErr.Code
+	}
+	return ""
+}
+
+// wrapServiceError wraps the error in a ServiceError unless it already contains one.
+// It is deferred by the exported operations of the AlertRuleService.
+func wrapServiceError(err *error) {
+	if *err == nil {
+		return
+	}
+	var serviceErr *ServiceError
+	if errors.As(*err, &serviceErr) {
+		return
+	}
+	*err = &ServiceError{Code: errorCode(*err), Message: (*err).Error(), Err: *err}
+}
+
+func errorCode(err error) ErrorCode {
+	switch {
+	case errors.Is(err, models.ErrAlertRuleNotFound),
+		errors.Is(err, store.ErrAlertRuleGroupNotFound),
+		errors.Is(err, store.ErrVersionNotFound),
+		errors.Is(err, models.ErrLibraryQueryNotFound),
+		errors.Is(err, ErrDraftNotFound),
+		errors.Is(err, ErrJobNotFound):
+		return ErrCodeRuleNotFound
+	case errors.Is(err, ErrProvenanceMismatch):
+		return ErrCodeProvenanceMismatch
+	case errors.Is(err, ErrProvisioningDisabled):
+		return ErrCodeProvisioningDisabled
+	case errors.Is(err, ErrQuotaReached):
+		return ErrCodeQuotaExceeded
+	case errors.Is(err, ErrRateLimited):
+		return ErrCodeRateLimited
+	case errors.Is(err, ErrAccessDenied):
+		return ErrCodeAccessDenied
+	case errors.Is(err, ErrValidation),
+		errors.Is(err, ErrContactPointNotFound),
+		errors.Is(err, models.ErrAlertRuleFailedValidation),
+		errors.Is(err, models.ErrInvalidSortField):
+		return ErrCodeValidation
+	case errors.Is(err, models.ErrAlertRuleDuplicateTitle),
+		errors.Is(err, models.ErrAlertRuleUniqueConstraintViolation),
+		errors.Is(err, ErrDataSourceInUse),
+		errors.Is(err, models.ErrLibraryQueryInUse),
+		errors.Is(err, ErrDraftExists),
+		errors.Is(err, ErrBatchAborted),
+		errors.Is(err, ErrJobFinished),
+		errors.Is(err, ErrJobNotCompleted):
+		return ErrCodeConflict
+	case errors.Is(err, ErrVersionConflict):
+		return ErrCodeOptimisticLock
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		return ErrCodeTimeout
+	default:
+		return ErrCodeInternal
+	}
+}
@@ -0,0 +1,59 @@
+package provisioning
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+func TestAlertRuleServiceErrorCodes(t *testing.T) {
+	var orgID int64 = 1
+	service := createAlertRuleService(t)
+	stored, err := service.CreateAlertRule(context.Background(), dummyRule("test#codes", orgID), models.ProvenanceFile)
+	require.NoError(t, err)
+
+	t.Run("unknown rules should fail with rule.not_found", func(t *testing.T) {
+		_, _, err := service.GetAlertRule(context.Background(), orgID, "unknown")
+		require.Equal(t, ErrCodeRuleNotFound, ErrorCodeOf(err))
+		require.ErrorIs(t, err, models.ErrAlertRuleNotFound)
+	})
+	t.Run("changes with another provenance should fail with rule.provenance_mismatch", func(t *testing.T) {
+		_, err := service.UpdateAlertRule(context.Background(), stored, models.ProvenanceAPI)
+		require.Equal(t, ErrCodeProvenanceMismatch, ErrorCodeOf(err))
+		err = service.DeleteAlertRule(context.Background(), orgID, stored.UID, models.ProvenanceAPI)
+		require.Equal(t, ErrCodeProvenanceMismatch, ErrorCodeOf(err))
+	})
+	t.Run("invalid rules should fail with rule.validation", func(t *testing.T) {
+		rule := dummyRule("test#codes-invalid", orgID)
+		rule.NotificationSettings = []models.NotificationSettings{{GroupBy: []string{"alertname"}}}
+		_, err := service.CreateAlertRule(context.Background(), rule, models.ProvenanceNone)
+		require.Equal(t, ErrCodeValidation, ErrorCodeOf(err))
+		require.ErrorIs(t, err, ErrValidation)
+	})
+	t.Run("duplicate titles should fail with rule.conflict", func(t *testing.T) {
+		_, err := service.CreateAlertRule(context.Background(), dummyRule("test#codes", orgID), models.ProvenanceNone)
+		require.Equal(t, ErrCodeConflict, ErrorCodeOf(err))
+	})
+	t.Run("timed out operations should fail with rule.timeout", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+		defer cancel()
+		<-ctx.Done()
+		_, _, err := service.GetAlertRule(ctx, orgID, stored.UID)
+		require.Equal(t, ErrCodeTimeout, ErrorCodeOf(err))
+	})
+	t.Run("other failures should fail with rule.internal", func(t *testing.T) {
+		err := errors.New("failure")
+		wrapServiceError(&err)
+		require.Equal(t, ErrCodeInternal, ErrorCodeOf(err))
+		require.Equal(t, "failure", err.Error())
+	})
+	t.Run("errors without a ServiceError should have no code", func(t *testing.T) {
+		require.Equal(t, ErrorCode(""), ErrorCodeOf(errors.New("failure")))
+		require.Equal(t, ErrorCode(""), ErrorCodeOf(nil))
+	})
+}
@@ -0,0 +1,163 @@
+package provisioning
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/store"
+)
+
+// snapshotVersion is the version of the format of snapshots created by SnapshotOrgRules.
+const snapshotVersion = 1
+
+// Snapshot is a copy of all alert rules of an organization, including their groups,
+// intervals and provenance. Its content is opaque and only meant to be passed to RestoreOrgRules.
+type Snapshot struct {
+	Version     int                          `json:"version"`
+	OrgID       int64                        `json:"orgId"`
+	Created     time.Time                    `json:"created"`
+	Rules       []models.AlertRule           `json:"rules"`
+	Provenances map[string]models.Provenance `json:"provenances"`
+}
+
+// SnapshotOrgRules returns a snapshot of all alert rules of the organization that the caller can read.
+func (service *AlertRuleService) SnapshotOrgRules(ctx context.Context, orgID int64) (_ Snapshot, err error) {
+	defer wrapServiceError(&err)
+	query := &models.ListAlertRulesQuery{
+		OrgID: orgID,
+	}
+	scope, err := service.callerNamespaces(ctx)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	if scope.restrict(query) {
+		if err := service.ruleStore.ListAlertRules(ctx, query); err != nil {
+			return Snapshot{}, err
+		}
+	}
+	provenances, err := service.provenanceStore.GetProvenances(ctx, orgID, (&models.AlertRule{}).ResourceType())
+	if err != nil {
+		return Snapshot{}, err
+	}
+	snap := Snapshot{
+		Version:     snapshotVersion,
+		OrgID:       orgID,
+		Created:     time.Now(),
+		Rules:       make([]models.AlertRule, 0, len(query.Result)),
+		Provenances: make(map[string]models.Provenance, len(query.Result)),
+	}
+	for _, rule := range query.Result {
+		snap.Rules = append(snap.Rules, *rule)
+		if provenance, ok := provenances[rule.UID]; ok {
+			snap.Provenances[rule.UID] = provenance
+		}
+	}
+	return snap, nil
+}
+
+// RestoreOrgRules replaces all alert rules of the organization with the rules of the snapshot in a
+// single transaction. Rules that are not part of the snapshot are deleted. Like any other change,
+// the restore is rejected if it touches a rule whose provenance cannot be changed by provenance, or
+// a rule in a namespace that the caller is not allowed to write.
+func (service *AlertRuleService) RestoreOrgRules(ctx context.Context, orgID int64, snap Snapshot, provenance models.Provenance) (err error) {
+	defer wrapServiceError(&err)
+	if err := service.policy.checkMutation(orgID, ResourceTypeAlertRules, provenance); err != nil {
+		return err
+	}
+	if snap.Version != snapshotVersion {
+		return fmt.Errorf("%w: unsupported snapshot version %d", ErrValidation, snap.Version)
+	}
+	if snap.OrgID != orgID {
+		return fmt.Errorf("%w: snapshot of organization %d cannot be restored to organization %d", ErrValidation, snap.OrgID, orgID)
+	}
+	scope, err := service.callerNamespaces(ctx)
+	if err != nil {
+		return err
+	}
+	for _, rule := range snap.Rules {
+		if err := scope.check(rule.NamespaceUID); err != nil {
+			return err
+		}
+	}
+	return service.xact.InTransaction(ctx, func(ctx context.Context) error {
+		query := &models.ListAlertRulesQuery{
+			OrgID: orgID,
+		}
+		if err := service.ruleStore.ListAlertRules(ctx, query); err != nil {
+			return err
+		}
+		current := make(map[string]*models.AlertRule, len(query.Result))
+		for _, rule := range query.Result {
+			if err := scope.check(rule.NamespaceUID); err != nil {
+				return err
+			}
+			storedProvenance, err := service.provenanceStore.GetProvenance(ctx, rule, orgID)
+			if err != nil {
+				return err
+			}
+			if storedProvenance != provenance && storedProvenance != models.ProvenanceNone {
+				return fmt.Errorf("cannot restore alert rule '%s' with provided provenance '%s', needs '%s'", rule.UID, provenance, storedProvenance)
+			}
+			current[rule.UID] = rule
+		}
+
+		inSnapshot := make(map[string]struct{}, len(snap.Rules))
+		for _, rule := range snap.Rules {
+			inSnapshot[rule.UID] = struct{}{}
+		}
+		var deletes []string
+		for uid, rule := range current {
+			if _, ok := inSnapshot[uid]; ok {
+				continue
+			}
+			deletes = append(deletes, uid)
+			if err := service.provenanceStore.DeleteProvenance(ctx, rule, orgID); err != nil {
+				return err
+			}
+		}
+		if len(deletes) > 0 {
+			if err := service.ruleStore.DeleteAlertRulesByUID(ctx, orgID, deletes...); err != nil {
+				return err
+			}
+		}
+
+		var updates []store.UpdateRule
+		var inserts []models.AlertRule
+		for _, rule := range snap.Rules {
+			rule.Updated = time.Now()
+			if existing, ok := current[rule.UID]; ok {
+				updates = append(updates, store.UpdateRule{
+					Existing: existing,
+					New:      rule,
+				})
+				continue
+			}
+			rule.ID = 0
+			inserts = append(inserts, rule)
+		}
+		if len(updates) > 0 {
+			if err := service.ruleStore.UpdateAlertRules(ctx, updates); err != nil {
+				return err
+			}
+		}
+		if len(inserts) > 0 {
+			if _, err := service.ruleStore.InsertAlertRules(ctx, inserts); err != nil {
+				return err
+			}
+		}
+
+		for i := range snap.Rules {
+			rule := &snap.Rules[i]
+			ruleProvenance, ok := snap.Provenances[rule.UID]
+			if !ok {
+				ruleProvenance = models.ProvenanceNone
+			}
+			if err := service.provenanceStore.SetProvenance(ctx, rule, orgID, ruleProvenance); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
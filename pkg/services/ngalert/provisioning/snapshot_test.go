@@ -0,0 +1,94 @@
+package provisioning
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+func TestSnapshotOrgRules(t *testing.T) {
+	var orgID int64 = 1
+	service := createAlertRuleService(t)
+
+	unchanged, err := service.CreateAlertRule(context.Background(), snapshotRule("snapshot#1", orgID), models.ProvenanceNone)
+	require.NoError(t, err)
+	updated, err := service.CreateAlertRule(context.Background(), snapshotRule("snapshot#2", orgID), models.ProvenanceAPI)
+	require.NoError(t, err)
+	deleted := snapshotRule("snapshot#3", orgID)
+	deleted.RuleGroup = "other-group"
+	deleted, err = service.CreateAlertRule(context.Background(), deleted, models.ProvenanceNone)
+	require.NoError(t, err)
+	require.NoError(t, service.UpdateAlertGroup(context.Background(), orgID, deleted.NamespaceUID, deleted.RuleGroup, 300, models.ProvenanceNone))
+
+	snap, err := service.SnapshotOrgRules(context.Background(), orgID)
+	require.NoError(t, err)
+	require.Len(t, snap.Rules, 3)
+	expected := snapshotState(t, service, orgID)
+
+	updated.Title = "snapshot#2-updated"
+	_, err = service.UpdateAlertRule(context.Background(), updated, models.ProvenanceAPI)
+	require.NoError(t, err)
+	require.NoError(t, service.DeleteAlertRule(context.Background(), orgID, deleted.UID, models.ProvenanceNone))
+	_, err = service.CreateAlertRule(context.Background(), snapshotRule("snapshot#4", orgID), models.ProvenanceNone)
+	require.NoError(t, err)
+	require.NotEqual(t, expected, snapshotState(t, service, orgID))
+
+	t.Run("restore should be rejected with a conflicting provenance", func(t *testing.T) {
+		err := service.RestoreOrgRules(context.Background(), orgID, snap, models.ProvenanceFile)
+		require.Error(t, err)
+	})
+
+	t.Run("restore should be rejected for another org", func(t *testing.T) {
+		err := service.RestoreOrgRules(context.Background(), 2, snap, models.ProvenanceAPI)
+		require.ErrorIs(t, err, ErrValidation)
+	})
+
+	t.Run("restore should bring back the state of the snapshot", func(t *testing.T) {
+		err := service.RestoreOrgRules(context.Background(), orgID, snap, models.ProvenanceAPI)
+		require.NoError(t, err)
+		require.Equal(t, expected, snapshotState(t, service, orgID))
+
+		_, _, err = service.GetAlertRule(context.Background(), orgID, unchanged.UID)
+		require.NoError(t, err)
+	})
+}
+
+// snapshotRule returns a rule whose queries survive being read back from the database.
+func snapshotRule(title string, orgID int64) models.AlertRule {
+	rule := dummyRule(title, orgID)
+	rule.Data[0].RelativeTimeRange.From = models.Duration(10 * time.Minute)
+	return rule
+}
+
+type snapshotRuleState struct {
+	UID        string
+	Title      string
+	RuleGroup  string
+	Interval   int64
+	Provenance models.Provenance
+}
+
+func snapshotState(t *testing.T, service AlertRuleService, orgID int64) []snapshotRuleState {
+	t.Helper()
+	snap, err := service.SnapshotOrgRules(context.Background(), orgID)
+	require.NoError(t, err)
+	state := make([]snapshotRuleState, 0, len(snap.Rules))
+	for _, rule := range snap.Rules {
+		state = append(state, snapshotRuleState{
+			UID:        rule.UID,
+			Title:      rule.Title,
+			RuleGroup:  rule.RuleGroup,
+			Interval:   rule.IntervalSeconds,
+			Provenance: snap.Provenances[rule.UID],
+		})
+	}
+	sort.Slice(state, func(i, j int) bool {
+		return state[i].UID < state[j].UID
+	})
+	return state
+}
@@ -0,0 +1,22 @@
+package provisioning
+
+import (
+	"context"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+// Subscribe calls the handler for every state change of an alert instance that the scheduler
+// publishes. The handler is called synchronously by the evaluation of the rule, so it must not
+// block. Subscribers should be registered at startup, before the scheduler runs. It has no effect if
+// the service has no event bus.
+func (service *AlertRuleService) Subscribe(handler func(models.AlertRuleStateChangedEvent)) {
+	if service.events == nil {
+		service.log.Warn("cannot subscribe to state changes of alert instances without an event bus")
+		return
+	}
+	service.events.AddEventListener(func(_ context.Context, event *models.AlertRuleStateChangedEvent) error {
+		handler(*event)
+		return nil
+	})
+}
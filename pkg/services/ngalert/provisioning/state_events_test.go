@@ -0,0 +1,35 @@
+package provisioning
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+func TestAlertRuleServiceSubscribe(t *testing.T) {
+	service := createAlertRuleService(t)
+	eventBus := bus.New()
+	service.events = eventBus
+
+	var received []models.AlertRuleStateChangedEvent
+	service.Subscribe(func(event models.AlertRuleStateChangedEvent) {
+		received = append(received, event)
+	})
+
+	event := models.AlertRuleStateChangedEvent{
+		OrgID:         1,
+		RuleUID:       "rule",
+		PreviousState: models.InstanceStateNormal,
+		NewState:      models.InstanceStateFiring,
+		Labels:        map[string]string{"team": "a"},
+		FiredAt:       time.Unix(1000, 0),
+	}
+	require.NoError(t, eventBus.Publish(context.Background(), &event))
+
+	require.Equal(t, []models.AlertRuleStateChangedEvent{event}, received)
+}
@@ -0,0 +1,75 @@
+package provisioning
+
+import (
+	"context"
+	"fmt"
+
+	models2 "github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+type callerKey struct{}
+
+// WithCaller returns a context for the operations of the AlertRuleService that are not scoped to an
+// organization. The operations are authorized for the user.
+func WithCaller(ctx context.Context, user *models2.SignedInUser) context.Context {
+	return context.WithValue(ctx, callerKey{}, user)
+}
+
+func callerFromContext(ctx context.Context) *models2.SignedInUser {
+	user, _ := ctx.Value(callerKey{}).(*models2.SignedInUser)
+	return user
+}
+
+// ListAllOrgAlertRules returns a page of the alert rules of all organizations, sorted by their ID.
+// Pages start at 1. The caller of the context must be allowed to read the alert rules of all
+// organizations, which users that are only members of organizations are not.
+func (service *AlertRuleService) ListAllOrgAlertRules(ctx context.Context, page, pageSize int) (_ []models.AlertRule, err error) {
+	defer wrapServiceError(&err)
+	if page < 1 || pageSize < 1 {
+		return nil, fmt.Errorf("%w: page and page size must be positive", ErrValidation)
+	}
+	if err := service.authorizeSystemAccess(ctx, accesscontrol.ActionAlertingSystemRuleRead); err != nil {
+		return nil, err
+	}
+	ctx, cancel := withTimeout(ctx, service.cfg.OperationTimeouts.List)
+	defer cancel()
+	query := &models.ListAlertRulesQuery{
+		// A negative organization lists the rules of all organizations.
+		OrgID:  -1,
+		Limit:  pageSize,
+		Offset: (page - 1) * pageSize,
+	}
+	if err := service.ruleStore.ListAlertRules(ctx, query); err != nil {
+		return nil, err
+	}
+	result := make([]models.AlertRule, 0, len(query.Result))
+	for _, rule := range query.Result {
+		result = append(result, *rule)
+	}
+	return result, nil
+}
+
+// authorizeSystemAccess returns ErrAccessDenied unless the caller of the context is allowed the
+// action. Grafana admins are allowed every action if access control is disabled.
+func (service *AlertRuleService) authorizeSystemAccess(ctx context.Context, action string) error {
+	user := callerFromContext(ctx)
+	if user == nil {
+		return fmt.Errorf("%w: no caller", ErrAccessDenied)
+	}
+	if service.ac == nil || service.ac.IsDisabled() {
+		if user.IsGrafanaAdmin {
+			return nil
+		}
+		return fmt.Errorf("%w: %s requires a Grafana admin", ErrAccessDenied, action)
+	}
+	ok, err := service.ac.Evaluate(ctx, user, accesscontrol.EvalPermission(action))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: %s is not allowed", ErrAccessDenied, action)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package provisioning
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	models2 "github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	acmock "github.com/grafana/grafana/pkg/services/accesscontrol/mock"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+func TestAlertRuleServiceListAllOrgAlertRules(t *testing.T) {
+	ruleService := createAlertRuleService(t)
+	ruleService.ac = acmock.New().WithPermissions([]*accesscontrol.Permission{
+		{Action: accesscontrol.ActionAlertingSystemRuleRead},
+	})
+	var uids []string
+	for _, orgID := range []int64{21, 22, 23} {
+		for i := 0; i < 2; i++ {
+			rule, err := ruleService.CreateAlertRule(context.Background(), dummyRule(fmt.Sprintf("test#system-%d", i), orgID), models.ProvenanceAPI)
+			require.NoError(t, err)
+			uids = append(uids, rule.UID)
+		}
+	}
+	system := WithCaller(context.Background(), &models2.SignedInUser{OrgId: 1, IsGrafanaAdmin: true})
+	ruleUIDs := func(rules []models.AlertRule) []string {
+		result := make([]string, 0, len(rules))
+		for _, rule := range rules {
+			result = append(result, rule.UID)
+		}
+		return result
+	}
+
+	t.Run("should list the rules of all organizations", func(t *testing.T) {
+		rules, err := ruleService.ListAllOrgAlertRules(system, 1, 10)
+		require.NoError(t, err)
+		require.Equal(t, uids, ruleUIDs(rules))
+		orgs := map[int64]struct{}{}
+		for _, rule := range rules {
+			orgs[rule.OrgID] = struct{}{}
+		}
+		require.Len(t, orgs, 3)
+	})
+	t.Run("should page through the rules", func(t *testing.T) {
+		var listed []string
+		for page := 1; ; page++ {
+			rules, err := ruleService.ListAllOrgAlertRules(system, page, 4)
+			require.NoError(t, err)
+			if len(rules) == 0 {
+				break
+			}
+			require.LessOrEqual(t, len(rules), 4)
+			listed = append(listed, ruleUIDs(rules)...)
+		}
+		require.Equal(t, uids, listed)
+	})
+	t.Run("should reject invalid pages", func(t *testing.T) {
+		_, err := ruleService.ListAllOrgAlertRules(system, 0, 10)
+		require.ErrorIs(t, err, ErrValidation)
+		_, err = ruleService.ListAllOrgAlertRules(system, 1, 0)
+		require.ErrorIs(t, err, ErrValidation)
+	})
+	t.Run("should reject callers without the system permission", func(t *testing.T) {
+		orgScoped := ruleService
+		orgScoped.ac = acmock.New().WithPermissions([]*accesscontrol.Permission{
+			{Action: accesscontrol.ActionAlertingRuleRead, Scope: "folders:*"},
+		})
+		_, err := orgScoped.ListAllOrgAlertRules(WithCaller(context.Background(), &models2.SignedInUser{OrgId: 21}), 1, 10)
+		require.ErrorIs(t, err, ErrAccessDenied)
+		require.Equal(t, ErrCodeAccessDenied, ErrorCodeOf(err))
+	})
+	t.Run("should reject contexts without a caller", func(t *testing.T) {
+		_, err := ruleService.ListAllOrgAlertRules(context.Background(), 1, 10)
+		require.ErrorIs(t, err, ErrAccessDenied)
+	})
+}
@@ -13,14 +13,16 @@ type TemplateService struct {
 	config AMConfigStore
 	prov   ProvisioningStore
 	xact   TransactionManager
+	policy ResourcePolicy
 	log    log.Logger
 }
 
-func NewTemplateService(config AMConfigStore, prov ProvisioningStore, xact TransactionManager, log log.Logger) *TemplateService {
+func NewTemplateService(config AMConfigStore, prov ProvisioningStore, xact TransactionManager, policy ResourcePolicy, log log.Logger) *TemplateService {
 	return &TemplateService{
 		config: config,
 		prov:   prov,
 		xact:   xact,
+		policy: policy,
 		log:    log,
 	}
 }
@@ -39,6 +41,9 @@ func (t *TemplateService) GetTemplates(ctx context.Context, orgID int64) (map[st
 }
 
 func (t *TemplateService) SetTemplate(ctx context.Context, orgID int64, tmpl definitions.MessageTemplate) (definitions.MessageTemplate, error) {
+	if err := t.policy.checkMutation(orgID, ResourceTypeTemplates, tmpl.Provenance); err != nil {
+		return definitions.MessageTemplate{}, err
+	}
 	err := tmpl.Validate()
 	if err != nil {
 		return definitions.MessageTemplate{}, fmt.Errorf("%w: %s", ErrValidation, err.Error())
@@ -84,6 +89,9 @@ func (t *TemplateService) SetTemplate(ctx context.Context, orgID int64, tmpl def
 }
 
 func (t *TemplateService) DeleteTemplate(ctx context.Context, orgID int64, name string) error {
+	if err := t.policy.checkMutation(orgID, ResourceTypeTemplates, models.ProvenanceNone); err != nil {
+		return err
+	}
 	revision, err := getLastConfiguration(ctx, orgID, t.config)
 	if err != nil {
 		return err
@@ -5,8 +5,11 @@ import (
 	"crypto/md5"
 	"fmt"
 	"strings"
+	"time"
 
+	models2 "github.com/grafana/grafana/pkg/models"
 	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/store"
 )
 
 const defaultAlertmanagerConfigJSON = `
@@ -78,6 +81,9 @@ func (f *fakeAMConfigStore) GetLatestAlertmanagerConfiguration(ctx context.Conte
 }
 
 func (f *fakeAMConfigStore) UpdateAlertmanagerConfiguration(ctx context.Context, cmd *models.SaveAlertmanagerConfigurationCmd) error {
+	if cmd.FetchedConfigurationHash != "" && cmd.FetchedConfigurationHash != fmt.Sprintf("%x", md5.Sum([]byte(f.config.AlertmanagerConfiguration))) {
+		return store.ErrVersionLockedObjectNotFound
+	}
 	f.config = models.AlertConfiguration{
 		AlertmanagerConfiguration: cmd.AlertmanagerConfiguration,
 		ConfigurationVersion:      cmd.ConfigurationVersion,
@@ -144,3 +150,79 @@ func newNopTransactionManager() *nopTransactionManager {
 func (n *nopTransactionManager) InTransaction(ctx context.Context, work func(ctx context.Context) error) error {
 	return work(ctx)
 }
+
+type fakeContactPointValidator struct {
+	names map[string]struct{}
+	err   error
+}
+
+func newFakeContactPointValidator(names ...string) *fakeContactPointValidator {
+	validator := &fakeContactPointValidator{names: map[string]struct{}{}}
+	for _, name := range names {
+		validator.names[name] = struct{}{}
+	}
+	return validator
+}
+
+func (f *fakeContactPointValidator) Exists(ctx context.Context, orgID int64, name string) (bool, error) {
+	if f.err != nil {
+		return false, f.err
+	}
+	_, ok := f.names[name]
+	return ok, nil
+}
+
+func (f *fakeContactPointValidator) remove(name string) {
+	delete(f.names, name)
+}
+
+type fakeDashboardProvider struct {
+	uids map[string]struct{}
+}
+
+func newFakeDashboardProvider(uids ...string) *fakeDashboardProvider {
+	provider := &fakeDashboardProvider{uids: map[string]struct{}{}}
+	for _, uid := range uids {
+		provider.uids[uid] = struct{}{}
+	}
+	return provider
+}
+
+func (f *fakeDashboardProvider) GetDashboard(ctx context.Context, query *models2.GetDashboardQuery) error {
+	if _, ok := f.uids[query.Uid]; !ok {
+		return models2.ErrDashboardNotFound
+	}
+	query.Result = &models2.Dashboard{Uid: query.Uid, OrgId: query.OrgId}
+	return nil
+}
+
+type failingTransactionManager struct {
+	err error
+}
+
+func (f *failingTransactionManager) InTransaction(ctx context.Context, work func(ctx context.Context) error) error {
+	return f.err
+}
+
+type fakeEvaluationDurations map[string]time.Duration
+
+func (f fakeEvaluationDurations) GetEvaluationDurations(orgID int64, ruleUIDs ...string) map[string]time.Duration {
+	result := make(map[string]time.Duration)
+	for _, uid := range ruleUIDs {
+		if d, ok := f[uid]; ok {
+			result[uid] = d
+		}
+	}
+	return result
+}
+
+type fakeQuotaChecker struct {
+	exceededOrgs map[int64]struct{}
+}
+
+func (f fakeQuotaChecker) CheckAlertRuleQuota(ctx context.Context, orgID int64) error {
+	if _, ok := f.exceededOrgs[orgID]; ok {
+		return fmt.Errorf("%w: organization %d cannot have more alert rules", ErrQuotaReached, orgID)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package provisioning
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+// timeRangeOutlierRatio is how many times larger or smaller than the median window of its group the
+// query window of a rule may be before it is reported as an outlier.
+const timeRangeOutlierRatio = 4
+
+// CheckGroupTimeRangeConsistency reports whether the rules of a rule group query compatible relative
+// time ranges. The window of a rule is the largest range of its data source queries, and rules whose
+// window is more than timeRangeOutlierRatio times larger or smaller than the median window of the
+// group are returned as outliers by their UIDs. The check is advisory, it never rejects a group.
+func (service *AlertRuleService) CheckGroupTimeRangeConsistency(ctx context.Context, orgID int64, namespaceUID, group string) (_ bool, _ []string, err error) {
+	defer wrapServiceError(&err)
+	rules, err := service.GetAlertRuleGroup(ctx, orgID, namespaceUID, group)
+	if err != nil {
+		return false, nil, err
+	}
+	windows := make(map[string]time.Duration, len(rules))
+	sorted := make([]time.Duration, 0, len(rules))
+	for _, rule := range rules {
+		window, ok := queryWindow(rule)
+		if !ok {
+			continue
+		}
+		windows[rule.UID] = window
+		sorted = append(sorted, window)
+	}
+	if len(sorted) < 2 {
+		return true, nil, nil
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	median := sorted[len(sorted)/2]
+	var outliers []string
+	for _, rule := range rules {
+		window, ok := windows[rule.UID]
+		if ok && (window > median*timeRangeOutlierRatio || window*timeRangeOutlierRatio < median) {
+			outliers = append(outliers, rule.UID)
+		}
+	}
+	return len(outliers) == 0, outliers, nil
+}
+
+// queryWindow returns the largest relative time range of the data source queries of the rule. It
+// returns false if the rule only has expressions.
+func queryWindow(rule models.AlertRule) (time.Duration, bool) {
+	var window time.Duration
+	found := false
+	for _, query := range rule.Data {
+		if isExpression, _ := query.IsExpression(); isExpression {
+			continue
+		}
+		if d := time.Duration(query.RelativeTimeRange.From - query.RelativeTimeRange.To); !found || d > window {
+			window = d
+		}
+		found = true
+	}
+	return window, found
+}
@@ -0,0 +1,37 @@
+package provisioning
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+func TestAlertRuleServiceCheckGroupTimeRangeConsistency(t *testing.T) {
+	ruleService := createAlertRuleService(t)
+	const orgID = int64(81)
+	create := func(title string, window time.Duration) models.AlertRule {
+		rule := dummyRule(title, orgID)
+		rule.RuleGroup = "time-ranges"
+		rule.Data[0].RelativeTimeRange.From = models.Duration(window)
+		created, err := ruleService.CreateAlertRule(context.Background(), rule, models.ProvenanceAPI)
+		require.NoError(t, err)
+		return created
+	}
+	first := create("test#ten-minutes", 10*time.Minute)
+	create("test#fifteen-minutes", 15*time.Minute)
+
+	consistent, outliers, err := ruleService.CheckGroupTimeRangeConsistency(context.Background(), orgID, first.NamespaceUID, first.RuleGroup)
+	require.NoError(t, err)
+	require.True(t, consistent)
+	require.Empty(t, outliers)
+
+	outlier := create("test#one-week", 7*24*time.Hour)
+	consistent, outliers, err = ruleService.CheckGroupTimeRangeConsistency(context.Background(), orgID, first.NamespaceUID, first.RuleGroup)
+	require.NoError(t, err)
+	require.False(t, consistent)
+	require.Equal(t, []string{outlier.UID}, outliers)
+}
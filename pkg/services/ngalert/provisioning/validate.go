@@ -1,5 +1,187 @@
 package provisioning
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/state"
+)
 
 var ErrValidation = fmt.Errorf("invalid object specification")
+var ErrContactPointNotFound = fmt.Errorf("contact point not found")
+var ErrInvalidGroupBy = fmt.Errorf("%w: invalid group_by", ErrValidation)
+var ErrDataSourceInUse = fmt.Errorf("data source is queried by alert rules")
+var ErrProvenanceMismatch = fmt.Errorf("provenance mismatch")
+var ErrRouteNotFound = fmt.Errorf("route not found")
+var ErrPolicyTreeConflict = fmt.Errorf("policy tree was changed concurrently")
+var ErrProvisioningDisabled = fmt.Errorf("provisioning is disabled")
+var ErrQuotaReached = errors.New("quota has been exceeded")
+
+// ErrQuotaExceeded is the former name of ErrQuotaReached.
+//
+// Deprecated: use ErrQuotaReached.
+var ErrQuotaExceeded = ErrQuotaReached
+
+var ErrExportRoundTrip = fmt.Errorf("export does not round-trip")
+var ErrRateLimited = fmt.Errorf("too many changes of provisioned resources")
+var ErrAccessDenied = fmt.Errorf("access denied")
+var ErrPermissionDenied = fmt.Errorf("%w: namespace is not allowed", ErrAccessDenied)
+var ErrDraftNotFound = fmt.Errorf("rule group draft not found")
+var ErrDraftExists = fmt.Errorf("rule group draft already exists")
+var ErrVersionConflict = fmt.Errorf("alert rule was changed since the given version")
+var ErrBatchAborted = fmt.Errorf("batch was aborted")
+var ErrJobNotFound = fmt.Errorf("provisioning job not found")
+var ErrJobFinished = fmt.Errorf("provisioning job is already finished")
+var ErrJobNotCompleted = fmt.Errorf("provisioning job is not completed")
+
+// alertRuleWarnings returns the issues of an alert rule that should be reported
+// to the user but are not severe enough to reject the rule.
+func alertRuleWarnings(rule models.AlertRule) []models.ValidationIssue {
+	var issues []models.ValidationIssue
+	interval := time.Duration(rule.IntervalSeconds) * time.Second
+	for i, query := range rule.Data {
+		if isExpression, _ := query.IsExpression(); isExpression {
+			continue
+		}
+		timeRange := time.Duration(query.RelativeTimeRange.From - query.RelativeTimeRange.To)
+		if timeRange < interval {
+			issues = append(issues, models.ValidationIssue{
+				Code:    models.IssueShortTimeRange,
+				Field:   fmt.Sprintf("data[%d].relativeTimeRange", i),
+				Message: fmt.Sprintf("query %s covers %s which is less than the evaluation interval of %s", query.RefID, timeRange, interval),
+			})
+		}
+	}
+	if rule.For > 0 && rule.For < interval {
+		issues = append(issues, models.ValidationIssue{
+			Code:    models.IssueForShorterThanInterval,
+			Field:   "for",
+			Message: fmt.Sprintf("pending period %s is shorter than the evaluation interval of %s", rule.For, interval),
+		})
+	}
+	return append(issues, annotationTemplateIssues(rule)...)
+}
+
+// annotationTemplateIssues parses the annotations of the rule as templates and checks that the values
+// they read belong to queries or expressions of the rule. Annotations may contain braces on purpose,
+// so these issues only reject rules with strict template validation.
+func annotationTemplateIssues(rule models.AlertRule) []models.ValidationIssue {
+	refIDs := make(map[string]struct{}, len(rule.Data))
+	for _, query := range rule.Data {
+		refIDs[query.RefID] = struct{}{}
+	}
+	keys := make([]string, 0, len(rule.Annotations))
+	for key := range rule.Annotations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var issues []models.ValidationIssue
+	for _, key := range keys {
+		text := rule.Annotations[key]
+		field := fmt.Sprintf("annotations.%s", key)
+		if err := state.ValidateTemplate(key, text); err != nil {
+			issues = append(issues, models.ValidationIssue{
+				Code:    models.IssueInvalidTemplate,
+				Field:   field,
+				Message: fmt.Sprintf("annotation %s is not a valid template: %s", key, err),
+			})
+			continue
+		}
+		for _, refID := range state.TemplateValueRefIDs(text) {
+			if _, ok := refIDs[refID]; !ok {
+				issues = append(issues, models.ValidationIssue{
+					Code:    models.IssueUnknownTemplateValue,
+					Field:   field,
+					Message: fmt.Sprintf("annotation %s reads the value of %s, which is not a query or expression of the rule", key, refID),
+				})
+			}
+		}
+	}
+	return issues
+}
+
+// validateAnnotations rejects annotations that use the namespace of keys reserved by Grafana,
+// such as __dashboardUid__. The reserved keys that link a rule to a panel are accepted if the
+// dedicated fields of the rule are set, because they are overwritten with the values of these
+// fields. This way rules can be written back as they were read.
+func validateAnnotations(rule models.AlertRule) error {
+	linked := rule.DashboardUID != nil && rule.PanelID != nil
+	var offenders []string
+	for key := range rule.Annotations {
+		if !isReservedAnnotation(key) {
+			continue
+		}
+		if linked && (key == models.DashboardUIDAnnotation || key == models.PanelIDAnnotation) {
+			continue
+		}
+		offenders = append(offenders, fmt.Sprintf("'%s'", key))
+	}
+	if len(offenders) == 0 {
+		return nil
+	}
+	sort.Strings(offenders)
+	return fmt.Errorf("%w: annotations %s use keys reserved by Grafana", ErrValidation, strings.Join(offenders, ", "))
+}
+
+// withoutReservedAnnotations returns the annotations that do not use keys reserved by Grafana.
+// The reserved keys are not subject to the annotation allowlist, because Grafana sets them itself.
+func withoutReservedAnnotations(annotations map[string]string) map[string]string {
+	result := make(map[string]string, len(annotations))
+	for key, value := range annotations {
+		if !isReservedAnnotation(key) {
+			result[key] = value
+		}
+	}
+	return result
+}
+
+func isReservedAnnotation(key string) bool {
+	return len(key) > 4 && strings.HasPrefix(key, "__") && strings.HasSuffix(key, "__")
+}
+
+// validateRuleLocation makes sure that a rule belongs to a folder and a rule group. Names that only
+// consist of whitespace count as empty.
+func validateRuleLocation(rule models.AlertRule) error {
+	if strings.TrimSpace(rule.NamespaceUID) == "" {
+		return fmt.Errorf("%w: folderUID must not be empty", ErrValidation)
+	}
+	if strings.TrimSpace(rule.RuleGroup) == "" {
+		return fmt.Errorf("%w: ruleGroup must not be empty", ErrValidation)
+	}
+	return nil
+}
+
+// validateKeys makes sure that a map of labels or annotations only uses allowed keys and contains
+// all required keys. An empty allowlist allows all keys.
+func validateKeys(kind string, values map[string]string, allowed, required []string) error {
+	if len(allowed) > 0 {
+		allowedKeys := make(map[string]struct{}, len(allowed))
+		for _, key := range allowed {
+			allowedKeys[key] = struct{}{}
+		}
+		var offenders []string
+		for key := range values {
+			if _, ok := allowedKeys[key]; !ok {
+				offenders = append(offenders, fmt.Sprintf("'%s'", key))
+			}
+		}
+		if len(offenders) > 0 {
+			sort.Strings(offenders)
+			return fmt.Errorf("%w: %s %s are not allowed", ErrValidation, kind, strings.Join(offenders, ", "))
+		}
+	}
+	var missing []string
+	for _, key := range required {
+		if _, ok := values[key]; !ok {
+			missing = append(missing, fmt.Sprintf("'%s'", key))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: required %s %s are missing", ErrValidation, kind, strings.Join(missing, ", "))
+	}
+	return nil
+}
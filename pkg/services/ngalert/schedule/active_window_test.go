@@ -0,0 +1,58 @@
+package schedule
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/prometheus/alertmanager/timeinterval"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/eval"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/store"
+)
+
+func TestSchedule_ActiveWindow(t *testing.T) {
+	ruleStore := store.NewFakeRuleStore(t)
+	sch, _ := setupScheduler(t, ruleStore, &store.FakeInstanceStore{}, store.NewFakeAdminConfigStore(t), prometheus.NewPedanticRegistry())
+	evaluator := &slowEvaluator{Evaluator: sch.evaluator, started: make(chan struct{}, 2)}
+	sch.evaluator = evaluator
+	evalAppliedChan := make(chan time.Time)
+	sch.evalAppliedFunc = func(key models.AlertRuleKey, t time.Time) {
+		evalAppliedChan <- t
+	}
+
+	rule := CreateTestAlertRule(t, ruleStore, 10, rand.Int63(), eval.Alerting)
+	rule.ActiveWindow = &models.ActiveWindow{
+		Times:    []timeinterval.TimeRange{{StartMinute: 9 * 60, EndMinute: 17 * 60}},
+		Location: "UTC",
+	}
+	evalChan := make(chan *evaluation)
+	go func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		_ = sch.ruleRoutine(ctx, rule.GetKey(), evalChan, make(chan struct{}))
+	}()
+
+	activeAt := time.Date(2022, 6, 1, 10, 0, 0, 0, time.UTC)
+	evalChan <- &evaluation{scheduledAt: activeAt, version: rule.Version}
+	waitForTimeChannel(t, evalAppliedChan)
+	states := sch.stateManager.GetStatesForRuleUID(rule.OrgID, rule.UID)
+	require.Len(t, states, 1)
+	require.Equal(t, eval.Alerting, states[0].State)
+	require.Equal(t, 1, evaluator.evaluations())
+
+	inactiveAt := time.Date(2022, 6, 1, 20, 0, 0, 0, time.UTC)
+	evalChan <- &evaluation{scheduledAt: inactiveAt, version: rule.Version}
+	waitForTimeChannel(t, evalAppliedChan)
+	states = sch.stateManager.GetStatesForRuleUID(rule.OrgID, rule.UID)
+	require.Len(t, states, 1)
+	require.Equal(t, eval.Normal, states[0].State)
+	require.Equal(t, models.StateReasonInactiveWindow, states[0].StateReason)
+	require.True(t, states[0].Resolved)
+	require.Equal(t, inactiveAt, states[0].EndsAt)
+	require.Equal(t, 1, evaluator.evaluations(), "rules should not be evaluated outside of their active window")
+}
@@ -0,0 +1,89 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+// maxDryRunNotifications is the number of dry-run notifications kept per organization. Older
+// notifications are dropped.
+const maxDryRunNotifications = 1000
+
+// ErrDryRunDisabled is returned for the dry-run notification log of a scheduler that sends notifications.
+var ErrDryRunDisabled = errors.New("the scheduler is not in dry-run mode")
+
+// DryRunNotification is an alert that the scheduler would have sent to the notifiers if it was not
+// in dry-run mode.
+type DryRunNotification struct {
+	OrgID       int64
+	RuleUID     string
+	Labels      map[string]string
+	Annotations map[string]string
+	StartsAt    time.Time
+	EndsAt      time.Time
+	// At is the time the notification would have been sent.
+	At time.Time
+}
+
+// dryRunNotificationLog keeps the latest dry-run notifications of every organization.
+type dryRunNotificationLog struct {
+	mtx           sync.Mutex
+	notifications map[int64][]DryRunNotification
+	max           int
+}
+
+func newDryRunNotificationLog(max int) *dryRunNotificationLog {
+	return &dryRunNotificationLog{
+		notifications: map[int64][]DryRunNotification{},
+		max:           max,
+	}
+}
+
+// record adds a notification for every alert of the rule.
+func (l *dryRunNotificationLog) record(key models.AlertRuleKey, alerts definitions.PostableAlerts, at time.Time) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	notifications := l.notifications[key.OrgID]
+	for _, alert := range alerts.PostableAlerts {
+		notifications = append(notifications, DryRunNotification{
+			OrgID:       key.OrgID,
+			RuleUID:     key.UID,
+			Labels:      alert.Labels,
+			Annotations: alert.Annotations,
+			StartsAt:    time.Time(alert.StartsAt),
+			EndsAt:      time.Time(alert.EndsAt),
+			At:          at,
+		})
+	}
+	if len(notifications) > l.max {
+		notifications = append([]DryRunNotification(nil), notifications[len(notifications)-l.max:]...)
+	}
+	l.notifications[key.OrgID] = notifications
+}
+
+// since returns the notifications of the organization recorded at or after the given time.
+func (l *dryRunNotificationLog) since(orgID int64, since time.Time) []DryRunNotification {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	result := make([]DryRunNotification, 0)
+	for _, notification := range l.notifications[orgID] {
+		if !notification.At.Before(since) {
+			result = append(result, notification)
+		}
+	}
+	return result
+}
+
+// GetDryRunNotificationLog returns the notifications of the organization that were not sent since
+// the given time, oldest first. It returns ErrDryRunDisabled if the scheduler sends notifications.
+func (sch *schedule) GetDryRunNotificationLog(_ context.Context, orgID int64, since time.Time) ([]DryRunNotification, error) {
+	if !sch.dryRun {
+		return nil, ErrDryRunDisabled
+	}
+	return sch.dryRunLog.since(orgID, since), nil
+}
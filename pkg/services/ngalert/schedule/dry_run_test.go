@@ -0,0 +1,105 @@
+package schedule
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	amv2 "github.com/prometheus/alertmanager/api/v2/models"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	"github.com/grafana/grafana/pkg/services/ngalert/eval"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/store"
+)
+
+func TestSchedule_DryRun(t *testing.T) {
+	setup := func(t *testing.T, dryRun bool) (*schedule, *clock.Mock, *models.AlertRule, func() int) {
+		ruleStore := store.NewFakeRuleStore(t)
+		sch, mockedClock := setupScheduler(t, ruleStore, &store.FakeInstanceStore{}, store.NewFakeAdminConfigStore(t), prometheus.NewPedanticRegistry())
+		sch.dryRun = dryRun
+		var mtx sync.Mutex
+		var dispatched int
+		sch.dispatch = func(_ models.AlertRuleKey, alerts definitions.PostableAlerts, _ log.Logger) {
+			mtx.Lock()
+			defer mtx.Unlock()
+			dispatched += len(alerts.PostableAlerts)
+		}
+		dispatchCalls := func() int {
+			mtx.Lock()
+			defer mtx.Unlock()
+			return dispatched
+		}
+		return sch, mockedClock, CreateTestAlertRule(t, ruleStore, 10, rand.Int63(), eval.Alerting), dispatchCalls
+	}
+	evaluate := func(t *testing.T, sch *schedule, rule *models.AlertRule, at time.Time) {
+		evalAppliedChan := make(chan time.Time)
+		sch.evalAppliedFunc = func(key models.AlertRuleKey, t time.Time) {
+			evalAppliedChan <- t
+		}
+		evalChan := make(chan *evaluation)
+		go func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			t.Cleanup(cancel)
+			_ = sch.ruleRoutine(ctx, rule.GetKey(), evalChan, make(chan struct{}))
+		}()
+		evalChan <- &evaluation{scheduledAt: at, version: rule.Version}
+		waitForTimeChannel(t, evalAppliedChan)
+	}
+
+	t.Run("should not dispatch notifications in dry-run mode", func(t *testing.T) {
+		sch, mockedClock, rule, dispatched := setup(t, true)
+		start := mockedClock.Now()
+		evaluate(t, sch, rule, start)
+
+		require.Zero(t, dispatched())
+		states := sch.stateManager.GetStatesForRuleUID(rule.OrgID, rule.UID)
+		require.Len(t, states, 1)
+		require.Equal(t, eval.Alerting, states[0].State)
+
+		notifications, err := sch.GetDryRunNotificationLog(context.Background(), rule.OrgID, time.Time{})
+		require.NoError(t, err)
+		require.Len(t, notifications, 1)
+		require.Equal(t, rule.UID, notifications[0].RuleUID)
+		require.Equal(t, rule.OrgID, notifications[0].OrgID)
+		require.Equal(t, start, notifications[0].At)
+
+		require.Equal(t, states[0].Labels, data.Labels(notifications[0].Labels))
+
+		notifications, err = sch.GetDryRunNotificationLog(context.Background(), rule.OrgID, start.Add(time.Second))
+		require.NoError(t, err)
+		require.Empty(t, notifications)
+
+		notifications, err = sch.GetDryRunNotificationLog(context.Background(), rule.OrgID+1, time.Time{})
+		require.NoError(t, err)
+		require.Empty(t, notifications)
+	})
+	t.Run("should dispatch notifications otherwise", func(t *testing.T) {
+		sch, mockedClock, rule, dispatched := setup(t, false)
+		evaluate(t, sch, rule, mockedClock.Now())
+
+		require.Equal(t, 1, dispatched())
+		_, err := sch.GetDryRunNotificationLog(context.Background(), rule.OrgID, time.Time{})
+		require.ErrorIs(t, err, ErrDryRunDisabled)
+	})
+}
+
+func TestDryRunNotificationLog(t *testing.T) {
+	dryRunLog := newDryRunNotificationLog(2)
+	key := models.AlertRuleKey{OrgID: 1, UID: "rule"}
+	at := time.Unix(1000, 0)
+	for i := 0; i < 3; i++ {
+		dryRunLog.record(key, definitions.PostableAlerts{PostableAlerts: make([]amv2.PostableAlert, 1)}, at.Add(time.Duration(i)*time.Second))
+	}
+	notifications := dryRunLog.since(1, time.Time{})
+	require.Len(t, notifications, 2, "only the latest notifications are kept")
+	require.Equal(t, at.Add(time.Second), notifications[0].At)
+	require.Equal(t, at.Add(2*time.Second), notifications[1].At)
+}
@@ -0,0 +1,36 @@
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/ngalert/eval"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+// ErrEvalPanic is the error of the evaluations of alert rules that panicked.
+var ErrEvalPanic = errors.New("evaluation of the alert rule panicked")
+
+// conditionEval evaluates the condition of a rule. If the scheduler recovers from panics of evaluations,
+// a panic results in a single error result that wraps ErrEvalPanic, so that the rule goes into its
+// execution error state and the evaluations of the other rules are not affected. Panicking evaluations
+// are not retried.
+func (sch *schedule) conditionEval(logger log.Logger, condition *models.Condition, now time.Time) (results eval.Results, err error) {
+	if sch.recoverFromEvalPanic {
+		defer func() {
+			if r := recover(); r != nil {
+				logger.Error("evaluation of alert rule panicked", "panic", r, "stack", string(debug.Stack()))
+				results = eval.Results{{
+					State:       eval.Error,
+					Error:       fmt.Errorf("%w: %v", ErrEvalPanic, r),
+					EvaluatedAt: now,
+				}}
+				err = nil
+			}
+		}()
+	}
+	return sch.evaluator.ConditionEval(condition, now, sch.expressionService)
+}
@@ -0,0 +1,107 @@
+package schedule
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/expr"
+	"github.com/grafana/grafana/pkg/services/ngalert/eval"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/store"
+)
+
+func TestSchedule_recoverFromEvalPanic(t *testing.T) {
+	ruleStore := store.NewFakeRuleStore(t)
+	instanceStore := &store.FakeInstanceStore{}
+	sch, _ := setupScheduler(t, ruleStore, instanceStore, store.NewFakeAdminConfigStore(t), nil)
+	sch.recoverFromEvalPanic = true
+	statusStore := &store.FakeRuleStatusStore{}
+	sch.ruleStatusStore = statusStore
+
+	evaluator := &eval.FakeEvaluator{}
+	evaluator.On("ConditionEval", mock.Anything, mock.Anything, mock.Anything).Return(
+		func(c *models.Condition, now time.Time, _ *expr.Service) eval.Results {
+			if c.Condition == "panicking" {
+				panic("nil map")
+			}
+			return eval.Results{{Instance: data.Labels{}, State: eval.Alerting, EvaluatedAt: now}}
+		}, nil)
+	sch.evaluator = evaluator
+
+	rules := make(map[string]*models.AlertRule)
+	for _, name := range []string{"panicking", "firing"} {
+		name := name
+		rules[name] = models.AlertRuleGen(func(rule *models.AlertRule) {
+			rule.OrgID = 1
+			rule.UID = name
+			rule.Condition = name
+			rule.NamespaceUID = "folder"
+			rule.RuleGroup = "group"
+			rule.EvalStrategy = models.EvalStrategyIndependent
+			rule.IntervalSeconds = 10
+			rule.ExecErrState = models.ErrorErrState
+			rule.Annotations = nil
+			rule.Labels = nil
+			rule.For = 0
+		})()
+		ruleStore.PutRule(context.Background(), rules[name])
+	}
+
+	evalChans := make(map[models.AlertRuleKey]chan *evaluation)
+	appliedChans := make(map[models.AlertRuleKey]chan time.Time)
+	for _, rule := range rules {
+		evalChans[rule.GetKey()] = make(chan *evaluation)
+		appliedChans[rule.GetKey()] = make(chan time.Time)
+	}
+	sch.evalAppliedFunc = func(key models.AlertRuleKey, now time.Time) {
+		appliedChans[key] <- now
+	}
+	for _, rule := range rules {
+		key := rule.GetKey()
+		go func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			t.Cleanup(cancel)
+			_ = sch.ruleRoutine(ctx, key, evalChans[key], make(chan struct{}))
+		}()
+	}
+	evaluate := func(name string, at time.Time) {
+		key := rules[name].GetKey()
+		evalChans[key] <- &evaluation{scheduledAt: at, version: rules[name].Version}
+		waitForTimeChannel(t, appliedChans[key])
+	}
+
+	start := time.Now()
+	evaluate("panicking", start)
+	evaluate("firing", start)
+
+	t.Run("the other rules of the group should still be evaluated", func(t *testing.T) {
+		actual := make(map[string]models.InstanceStateType)
+		for _, op := range instanceStore.RecordedOps {
+			if cmd, ok := op.(models.SaveAlertInstanceCommand); ok {
+				actual[cmd.RuleUID] = cmd.State
+			}
+		}
+		require.Equal(t, map[string]models.InstanceStateType{
+			"panicking": models.InstanceStateError,
+			"firing":    models.InstanceStateFiring,
+		}, actual)
+	})
+	t.Run("the status of the rule that panicked should have the panic", func(t *testing.T) {
+		statuses := make(map[string]models.AlertRuleStatusEntry)
+		for _, status := range statusStore.GetStatuses() {
+			statuses[status.RuleUID] = status
+		}
+		require.Equal(t, models.AlertRuleStatusError, statuses["panicking"].Status)
+		require.Contains(t, statuses["panicking"].Error, ErrEvalPanic.Error())
+		require.Contains(t, statuses["panicking"].Error, "nil map")
+		require.Equal(t, models.AlertRuleStatusOK, statuses["firing"].Status)
+	})
+	t.Run("panics should not be retried", func(t *testing.T) {
+		evaluator.AssertNumberOfCalls(t, "ConditionEval", 2)
+	})
+}
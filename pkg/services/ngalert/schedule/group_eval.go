@@ -0,0 +1,118 @@
+package schedule
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+// readyToRunItem is a rule that is due for evaluation in the current tick.
+type readyToRunItem struct {
+	key      models.AlertRuleKey
+	groupKey models.AlertRuleGroupKey
+	ruleName string
+	ruleInfo *alertRuleInfo
+	version  int64
+}
+
+// dependsOnGroup returns true if the evaluation of the rule depends on the evaluations of the other rules
+// of its group, either because the rules of the group are evaluated in order or because the evaluation
+// strategy of the group considers the outcomes of the other rules.
+func dependsOnGroup(rule *models.SchedulableAlertRule) bool {
+	if rule.EvalOrder > 0 {
+		return true
+	}
+	return rule.EvalStrategy != "" && rule.EvalStrategy != models.EvalStrategyIndependent
+}
+
+// evaluationSlots splits the rules that are ready to run into the slots that their evaluations are
+// dispatched in. Every rule gets its own slot, unless concurrent group evaluations are enabled, in which
+// case the rules of a group that do not depend on each other share one slot. The rules are expected to
+// be sorted by group.
+func (sch *schedule) evaluationSlots(items []readyToRunItem, dependentGroups map[models.AlertRuleGroupKey]bool) [][]readyToRunItem {
+	slots := make([][]readyToRunItem, 0, len(items))
+	for i, item := range items {
+		if sch.concurrentGroupEval && i > 0 && items[i-1].groupKey == item.groupKey && !dependentGroups[item.groupKey] {
+			last := len(slots) - 1
+			slots[last] = append(slots[last], item)
+			continue
+		}
+		slots = append(slots, []readyToRunItem{item})
+	}
+	return slots
+}
+
+// evalConcurrently evaluates the rules at once and waits until all of them are evaluated. At most
+// maxConcurrentGroupEval rules are evaluated at the same time, 0 does not limit them. The evaluations
+// are waited for at most one base interval, so that rules whose evaluations hang cannot hold up the
+// rest of the group. The rules that are left at that point are sent without waiting for them.
+func (sch *schedule) evalConcurrently(items []readyToRunItem, tick time.Time) {
+	limit := sch.maxConcurrentGroupEval
+	if limit <= 0 || limit > len(items) {
+		limit = len(items)
+	}
+	sem := make(chan struct{}, limit)
+	expired := make(chan struct{})
+	timeout := time.AfterFunc(sch.baseInterval, func() { close(expired) })
+
+	var wg sync.WaitGroup
+	for _, item := range items {
+		item := item
+		acquired := true
+		select {
+		case sem <- struct{}{}:
+		case <-expired:
+			acquired = false
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if acquired {
+				defer func() { <-sem }()
+			}
+
+			evaluated := make(chan struct{})
+			var once sync.Once
+			if !sch.sendEvaluation(item, tick, func() { once.Do(func() { close(evaluated) }) }) {
+				return
+			}
+			select {
+			case <-evaluated:
+			case <-item.ruleInfo.ctx.Done():
+			case <-expired:
+			}
+		}()
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		timeout.Stop()
+	case <-expired:
+		key := items[0].groupKey
+		sch.log.Warn("Rule group evaluation is too slow - stopped waiting for evaluations", "org", key.OrgID, "namespace", key.NamespaceUID, "group", key.RuleGroup, "time", tick)
+	}
+}
+
+// sendEvaluation sends the evaluation of the tick to the routine of the rule. done is called once the
+// routine evaluated the rule or skipped the evaluation. It returns false if the routine is stopped.
+func (sch *schedule) sendEvaluation(item readyToRunItem, tick time.Time, done func()) bool {
+	success, dropped := item.ruleInfo.send(&evaluation{scheduledAt: tick, version: item.version, done: done})
+	// the dropped evaluation is never going to run, so whoever waits for it must not wait any longer.
+	dropped.finish()
+	if !success {
+		sch.log.Debug("scheduled evaluation was canceled because evaluation routine was stopped", "uid", item.key.UID, "org", item.key.OrgID, "time", tick)
+		return false
+	}
+	if dropped != nil {
+		sch.log.Warn("Alert rule evaluation is too slow - dropped tick", "uid", item.key.UID, "org", item.key.OrgID, "time", tick)
+		orgID := fmt.Sprint(item.key.OrgID)
+		sch.metrics.EvaluationMissed.WithLabelValues(orgID, item.ruleName).Inc()
+	}
+	return true
+}
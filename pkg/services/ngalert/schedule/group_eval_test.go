@@ -0,0 +1,224 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/expr"
+	"github.com/grafana/grafana/pkg/services/ngalert/eval"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/store"
+)
+
+func TestSchedule_evaluationSlots(t *testing.T) {
+	independent := models.AlertRuleGroupKey{OrgID: 1, NamespaceUID: "folder", RuleGroup: "independent"}
+	dependent := models.AlertRuleGroupKey{OrgID: 1, NamespaceUID: "folder", RuleGroup: "dependent"}
+	items := []readyToRunItem{
+		{key: models.AlertRuleKey{OrgID: 1, UID: "dependent-1"}, groupKey: dependent},
+		{key: models.AlertRuleKey{OrgID: 1, UID: "dependent-2"}, groupKey: dependent},
+		{key: models.AlertRuleKey{OrgID: 1, UID: "independent-1"}, groupKey: independent},
+		{key: models.AlertRuleKey{OrgID: 1, UID: "independent-2"}, groupKey: independent},
+		{key: models.AlertRuleKey{OrgID: 1, UID: "independent-3"}, groupKey: independent},
+	}
+	dependentGroups := map[models.AlertRuleGroupKey]bool{dependent: true}
+
+	slotUIDs := func(slots [][]readyToRunItem) [][]string {
+		result := make([][]string, 0, len(slots))
+		for _, slot := range slots {
+			uids := make([]string, 0, len(slot))
+			for _, item := range slot {
+				uids = append(uids, item.key.UID)
+			}
+			result = append(result, uids)
+		}
+		return result
+	}
+
+	t.Run("should give every rule its own slot when concurrent group evaluations are disabled", func(t *testing.T) {
+		sch := setupSchedulerWithFakeStores(t)
+		require.Equal(t, [][]string{{"dependent-1"}, {"dependent-2"}, {"independent-1"}, {"independent-2"}, {"independent-3"}},
+			slotUIDs(sch.evaluationSlots(items, dependentGroups)))
+	})
+	t.Run("should put the rules of independent groups in one slot when concurrent group evaluations are enabled", func(t *testing.T) {
+		sch := setupSchedulerWithFakeStores(t)
+		sch.concurrentGroupEval = true
+		require.Equal(t, [][]string{{"dependent-1"}, {"dependent-2"}, {"independent-1", "independent-2", "independent-3"}},
+			slotUIDs(sch.evaluationSlots(items, dependentGroups)))
+	})
+}
+
+func TestDependsOnGroup(t *testing.T) {
+	require.False(t, dependsOnGroup(&models.SchedulableAlertRule{}))
+	require.False(t, dependsOnGroup(&models.SchedulableAlertRule{EvalStrategy: models.EvalStrategyIndependent}))
+	require.True(t, dependsOnGroup(&models.SchedulableAlertRule{EvalStrategy: models.EvalStrategyIndependent, EvalOrder: 1}))
+	require.True(t, dependsOnGroup(&models.SchedulableAlertRule{EvalStrategy: models.EvalStrategyAllSuccess}))
+	require.True(t, dependsOnGroup(&models.SchedulableAlertRule{EvalStrategy: models.EvalStrategyAnySuccess}))
+}
+
+func TestSchedule_evalConcurrently(t *testing.T) {
+	const rules = 6
+	const limit = 2
+
+	started := make(chan struct{}, rules)
+	release := make(chan struct{})
+	sch, items := setupGroupEvaluation(t, rules, func() {
+		started <- struct{}{}
+		<-release
+	})
+	sch.maxConcurrentGroupEval = limit
+
+	done := make(chan struct{})
+	go func() {
+		sch.evalConcurrently(items, time.Now())
+		close(done)
+	}()
+
+	for i := 0; i < limit; i++ {
+		select {
+		case <-started:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("only %d of %d rules are evaluated at once", i, limit)
+		}
+	}
+	select {
+	case <-started:
+		t.Fatalf("more than %d rules are evaluated at once", limit)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	for i := 0; i < rules; i++ {
+		select {
+		case <-done:
+			t.Fatalf("evaluations of the group finished after %d of %d rules were evaluated", i, rules)
+		default:
+		}
+		select {
+		case release <- struct{}{}:
+		case <-time.After(5 * time.Second):
+			t.Fatal("rule was not evaluated")
+		}
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("evaluations of the group did not finish after all rules were evaluated")
+	}
+}
+
+func TestSchedule_evalConcurrentlyWithHangingEvaluation(t *testing.T) {
+	hang := make(chan struct{})
+	t.Cleanup(func() { close(hang) })
+	evaluated := make(chan struct{}, 10)
+	var calls int32
+	sch, items := setupGroupEvaluation(t, 2, func() {
+		// the evaluation of the first rule never finishes.
+		if atomic.AddInt32(&calls, 1) == 1 {
+			<-hang
+			return
+		}
+		evaluated <- struct{}{}
+	})
+	sch.maxConcurrentGroupEval = 1
+	sch.baseInterval = 100 * time.Millisecond
+
+	evalConcurrently := func(tick time.Time) {
+		done := make(chan struct{})
+		go func() {
+			sch.evalConcurrently(items, tick)
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("evaluations of the group were waited for longer than the base interval")
+		}
+		select {
+		case <-evaluated:
+		case <-time.After(5 * time.Second):
+			t.Fatal("the rule after the hanging rule was not evaluated")
+		}
+	}
+
+	tick := time.Now()
+	evalConcurrently(tick)
+	// the next tick is still dispatched, even though the first rule is still being evaluated.
+	evalConcurrently(tick.Add(sch.baseInterval))
+}
+
+// setupGroupEvaluation starts the evaluation routines of a group of rules that call evaluate whenever they
+// are evaluated and returns the rules ready to run.
+func setupGroupEvaluation(tb testing.TB, rules int, evaluate func()) (*schedule, []readyToRunItem) {
+	tb.Helper()
+	ruleStore := store.NewFakeRuleStore(tb)
+	sch, _ := setupScheduler(tb, ruleStore, &store.FakeInstanceStore{}, store.NewFakeAdminConfigStore(tb), nil)
+
+	evaluator := &eval.FakeEvaluator{}
+	evaluator.On("ConditionEval", mock.Anything, mock.Anything, mock.Anything).Return(
+		func(c *models.Condition, now time.Time, _ *expr.Service) eval.Results {
+			evaluate()
+			return eval.Results{{Instance: data.Labels{}, State: eval.Normal, EvaluatedAt: now}}
+		}, nil)
+	sch.evaluator = evaluator
+
+	ctx, cancel := context.WithCancel(context.Background())
+	tb.Cleanup(cancel)
+	items := make([]readyToRunItem, 0, rules)
+	for i := 0; i < rules; i++ {
+		rule := models.AlertRuleGen(func(rule *models.AlertRule) {
+			rule.OrgID = 1
+			rule.UID = fmt.Sprintf("rule-%d", i)
+			rule.NamespaceUID = "folder"
+			rule.RuleGroup = "group"
+			rule.EvalStrategy = models.EvalStrategyIndependent
+			rule.EvalOrder = 0
+			rule.IntervalSeconds = 10
+			rule.Annotations = nil
+			rule.Labels = nil
+			rule.For = 0
+		})()
+		ruleStore.PutRule(ctx, rule)
+
+		key := rule.GetKey()
+		info, _ := sch.registry.getOrCreateInfo(ctx, key)
+		go func() {
+			_ = sch.ruleRoutine(info.ctx, key, info.evalCh, info.updateCh)
+		}()
+		items = append(items, readyToRunItem{key: key, groupKey: rule.GetGroupKey(), ruleName: rule.Title, ruleInfo: info, version: rule.Version})
+	}
+	return sch, items
+}
+
+// BenchmarkGroupEvaluation evaluates a group of 50 rules whose queries take 100ms one rule at a time
+// and all rules at once.
+func BenchmarkGroupEvaluation(b *testing.B) {
+	if testing.Short() {
+		b.Skip("skipping benchmark in short mode")
+	}
+	const rules = 50
+	const latency = 100 * time.Millisecond
+
+	for _, bc := range []struct {
+		name  string
+		limit int
+	}{
+		{name: "sequential", limit: 1},
+		{name: "concurrent", limit: 0},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			sch, items := setupGroupEvaluation(b, rules, func() { time.Sleep(latency) })
+			sch.maxConcurrentGroupEval = bc.limit
+			sch.baseInterval = time.Minute
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				sch.evalConcurrently(items, time.Now())
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package schedule
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/eval"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/state"
+)
+
+// ruleGroupEvaluations tracks the evaluation strategy of every rule group and whether the latest
+// evaluation of each rule of the group failed. Rules are evaluated by their own routines, so the
+// outcome of the other rules of a group is the one of their latest evaluation.
+type ruleGroupEvaluations struct {
+	mtx        sync.Mutex
+	strategies map[models.AlertRuleGroupKey]string
+	failed     map[models.AlertRuleGroupKey]map[string]bool
+}
+
+func newRuleGroupEvaluations() *ruleGroupEvaluations {
+	return &ruleGroupEvaluations{
+		strategies: make(map[models.AlertRuleGroupKey]string),
+		failed:     make(map[models.AlertRuleGroupKey]map[string]bool),
+	}
+}
+
+// setRules replaces the strategies of the rule groups with the ones of the given rules
+// and forgets the outcomes of rules that are no longer part of their group.
+func (g *ruleGroupEvaluations) setRules(rules []*models.SchedulableAlertRule) {
+	strategies := make(map[models.AlertRuleGroupKey]string)
+	members := make(map[models.AlertRuleGroupKey]map[string]struct{})
+	for _, rule := range rules {
+		key := rule.GetGroupKey()
+		strategies[key] = rule.EvalStrategy
+		if members[key] == nil {
+			members[key] = make(map[string]struct{})
+		}
+		members[key][rule.UID] = struct{}{}
+	}
+
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+	g.strategies = strategies
+	for key, outcomes := range g.failed {
+		for uid := range outcomes {
+			if _, ok := members[key][uid]; !ok {
+				delete(outcomes, uid)
+			}
+		}
+		if len(outcomes) == 0 {
+			delete(g.failed, key)
+		}
+	}
+}
+
+// forget removes the outcome of the rule from all groups.
+func (g *ruleGroupEvaluations) forget(key models.AlertRuleKey) {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+	for group, outcomes := range g.failed {
+		if group.OrgID != key.OrgID {
+			continue
+		}
+		delete(outcomes, key.UID)
+		if len(outcomes) == 0 {
+			delete(g.failed, group)
+		}
+	}
+}
+
+// record stores whether the evaluation of the rule failed and returns the options to process its
+// results with according to the evaluation strategy of its group. The strategy of the rule is used
+// if the group is not known yet.
+func (g *ruleGroupEvaluations) record(rule *models.AlertRule, failed bool) state.ProcessingOptions {
+	key := rule.GetGroupKey()
+
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+	if g.failed[key] == nil {
+		g.failed[key] = make(map[string]bool)
+	}
+	g.failed[key][rule.UID] = failed
+
+	strategy, ok := g.strategies[key]
+	if !ok {
+		strategy = rule.EvalStrategy
+	}
+	switch strategy {
+	case models.EvalStrategyAllSuccess:
+		for _, f := range g.failed[key] {
+			if f {
+				return state.ProcessingOptions{HoldPending: true}
+			}
+		}
+	case models.EvalStrategyAnySuccess:
+		if !failed {
+			break
+		}
+		for uid, f := range g.failed[key] {
+			if uid != rule.UID && !f {
+				return state.ProcessingOptions{KeepStateOnError: true}
+			}
+		}
+	}
+	return state.ProcessingOptions{}
+}
+
+// sortByEvalOrder sorts the rules so that the rules of a group are next to each other and in their
+// evaluation order. Rules without an order are placed after the ordered rules of their group.
+func sortByEvalOrder(rules []*models.SchedulableAlertRule) {
+	sort.Slice(rules, func(i, j int) bool {
+		a, b := rules[i], rules[j]
+		if ak, bk := a.GetGroupKey(), b.GetGroupKey(); ak != bk {
+			if ak.OrgID != bk.OrgID {
+				return ak.OrgID < bk.OrgID
+			}
+			if ak.NamespaceUID != bk.NamespaceUID {
+				return ak.NamespaceUID < bk.NamespaceUID
+			}
+			return ak.RuleGroup < bk.RuleGroup
+		}
+		if a.EvalOrder != b.EvalOrder {
+			if a.EvalOrder <= 0 || b.EvalOrder <= 0 {
+				return b.EvalOrder <= 0
+			}
+			return a.EvalOrder < b.EvalOrder
+		}
+		return a.UID < b.UID
+	})
+}
+
+// hasFailedResults returns true if the evaluation of any series of the rule failed.
+func hasFailedResults(results eval.Results) bool {
+	for _, result := range results {
+		if result.State == eval.Error {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,168 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/expr"
+	"github.com/grafana/grafana/pkg/services/ngalert/eval"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/store"
+)
+
+func TestSchedule_ruleGroupEvalStrategy(t *testing.T) {
+	// Rule "firing" fires after its pending period and rule "failing" always fails to evaluate.
+	// The failing rule fires on errors, so it fires right away when it is evaluated on its own.
+	testCases := []struct {
+		strategy string
+		expected map[string]models.InstanceStateType
+	}{
+		{
+			strategy: models.EvalStrategyIndependent,
+			expected: map[string]models.InstanceStateType{"firing": models.InstanceStateFiring, "failing": models.InstanceStateFiring},
+		},
+		{
+			strategy: models.EvalStrategyAllSuccess,
+			expected: map[string]models.InstanceStateType{"firing": models.InstanceStatePending, "failing": models.InstanceStateFiring},
+		},
+		{
+			strategy: models.EvalStrategyAnySuccess,
+			expected: map[string]models.InstanceStateType{"firing": models.InstanceStateFiring, "failing": models.InstanceStateNormal},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.strategy, func(t *testing.T) {
+			ruleStore := store.NewFakeRuleStore(t)
+			instanceStore := &store.FakeInstanceStore{}
+			sch, _ := setupScheduler(t, ruleStore, instanceStore, store.NewFakeAdminConfigStore(t), nil)
+
+			evaluator := &eval.FakeEvaluator{}
+			evaluator.On("ConditionEval", mock.Anything, mock.Anything, mock.Anything).Return(
+				func(c *models.Condition, now time.Time, _ *expr.Service) eval.Results {
+					result := eval.Result{Instance: data.Labels{}, State: eval.Alerting, EvaluatedAt: now}
+					if c.Condition == "failing" {
+						result.State = eval.Error
+						result.Error = errors.New("exec error")
+					}
+					return eval.Results{result}
+				}, nil)
+			sch.evaluator = evaluator
+
+			rules := make(map[string]*models.AlertRule)
+			for _, name := range []string{"firing", "failing"} {
+				name := name
+				rules[name] = models.AlertRuleGen(func(rule *models.AlertRule) {
+					rule.OrgID = 1
+					rule.UID = name
+					rule.Condition = name
+					rule.NamespaceUID = "folder"
+					rule.RuleGroup = "group"
+					rule.EvalStrategy = tc.strategy
+					rule.IntervalSeconds = 10
+					rule.ExecErrState = models.AlertingErrState
+					rule.Annotations = nil
+					rule.Labels = nil
+					rule.For = 0
+					if name == "firing" {
+						rule.For = 10 * time.Second
+					}
+				})()
+				ruleStore.PutRule(context.Background(), rules[name])
+			}
+
+			evalChans := make(map[models.AlertRuleKey]chan *evaluation)
+			appliedChans := make(map[models.AlertRuleKey]chan time.Time)
+			for _, rule := range rules {
+				evalChans[rule.GetKey()] = make(chan *evaluation)
+				appliedChans[rule.GetKey()] = make(chan time.Time)
+			}
+			sch.evalAppliedFunc = func(key models.AlertRuleKey, now time.Time) {
+				appliedChans[key] <- now
+			}
+			for _, rule := range rules {
+				key := rule.GetKey()
+				go func() {
+					ctx, cancel := context.WithCancel(context.Background())
+					t.Cleanup(cancel)
+					_ = sch.ruleRoutine(ctx, key, evalChans[key], make(chan struct{}))
+				}()
+			}
+			evaluate := func(name string, at time.Time) {
+				key := rules[name].GetKey()
+				evalChans[key] <- &evaluation{scheduledAt: at, version: rules[name].Version}
+				waitForTimeChannel(t, appliedChans[key])
+			}
+
+			start := time.Now()
+			evaluate("firing", start)
+			evaluate("failing", start)
+			evaluate("failing", start.Add(20*time.Second))
+			evaluate("firing", start.Add(20*time.Second))
+
+			actual := make(map[string]models.InstanceStateType)
+			for _, op := range instanceStore.RecordedOps {
+				if cmd, ok := op.(models.SaveAlertInstanceCommand); ok {
+					actual[cmd.RuleUID] = cmd.State
+				}
+			}
+			require.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestSchedule_evalOrder(t *testing.T) {
+	ruleStore := store.NewFakeRuleStore(t)
+	sch, mockedClock := setupScheduler(t, ruleStore, &store.FakeInstanceStore{}, store.NewFakeAdminConfigStore(t), nil)
+
+	var mtx sync.Mutex
+	var evaluated []string
+	evaluator := &eval.FakeEvaluator{}
+	evaluator.On("ConditionEval", mock.Anything, mock.Anything, mock.Anything).Return(
+		func(c *models.Condition, now time.Time, _ *expr.Service) eval.Results {
+			mtx.Lock()
+			defer mtx.Unlock()
+			evaluated = append(evaluated, c.Condition)
+			return eval.Results{{Instance: data.Labels{}, State: eval.Normal, EvaluatedAt: now}}
+		}, nil)
+	sch.evaluator = evaluator
+
+	orders := map[string]int{"third": 3, "unordered": 0, "first": 1, "second": 2}
+	for name, order := range orders {
+		name, order := name, order
+		ruleStore.PutRule(context.Background(), models.AlertRuleGen(func(rule *models.AlertRule) {
+			rule.OrgID = 1
+			rule.UID = name
+			rule.Condition = name
+			rule.NamespaceUID = "folder"
+			rule.RuleGroup = "group"
+			rule.EvalOrder = order
+			rule.IntervalSeconds = 1
+			rule.Annotations = nil
+			rule.Labels = nil
+		})())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go func() {
+		_ = sch.Run(ctx)
+	}()
+	mockedClock.Add(time.Second)
+
+	require.Eventually(t, func() bool {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return len(evaluated) >= len(orders)
+	}, 5*time.Second, 50*time.Millisecond)
+	mtx.Lock()
+	defer mtx.Unlock()
+	require.Equal(t, []string{"first", "second", "third", "unordered"}, evaluated[:len(orders)])
+}
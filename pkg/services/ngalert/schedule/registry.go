@@ -77,6 +77,9 @@ type alertRuleInfo struct {
 	updateCh chan struct{}
 	ctx      context.Context
 	stop     context.CancelFunc
+	// existedAtStart is true for rules that already existed when the scheduler started. They are not
+	// new and are not warmed up.
+	existedAtStart bool
 }
 
 func newAlertRuleInfo(parent context.Context) *alertRuleInfo {
@@ -91,6 +94,14 @@ func newAlertRuleInfo(parent context.Context) *alertRuleInfo {
 //   - false when the send operation is stopped
 // the second element contains a dropped message that was sent by a concurrent sender.
 func (a *alertRuleInfo) eval(t time.Time, version int64) (bool, *evaluation) {
+	return a.send(&evaluation{
+		scheduledAt: t,
+		version:     version,
+	})
+}
+
+// send sends the evaluation to the evaluation routine. It behaves like eval.
+func (a *alertRuleInfo) send(e *evaluation) (bool, *evaluation) {
 	// read the channel in unblocking manner to make sure that there is no concurrent send operation.
 	var droppedMsg *evaluation
 	select {
@@ -99,10 +110,7 @@ func (a *alertRuleInfo) eval(t time.Time, version int64) (bool, *evaluation) {
 	}
 
 	select {
-	case a.evalCh <- &evaluation{
-		scheduledAt: t,
-		version:     version,
-	}:
+	case a.evalCh <- e:
 		return true, droppedMsg
 	case <-a.ctx.Done():
 		return false, droppedMsg
@@ -122,6 +130,15 @@ func (a *alertRuleInfo) update() bool {
 type evaluation struct {
 	scheduledAt time.Time
 	version     int64
+	// done is called once the evaluation routine evaluated the rule or skipped the evaluation. It is optional.
+	done func()
+}
+
+// finish calls the done function of the evaluation, if it has one.
+func (e *evaluation) finish() {
+	if e != nil && e.done != nil {
+		e.done()
+	}
 }
 
 type schedulableAlertRulesRegistry struct {
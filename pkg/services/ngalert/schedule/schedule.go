@@ -8,6 +8,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/grafana/grafana/pkg/bus"
 	"github.com/grafana/grafana/pkg/expr"
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/services/alerting"
@@ -43,6 +44,12 @@ type ScheduleService interface {
 	UpdateAlertRule(key models.AlertRuleKey)
 	// DeleteAlertRule notifies scheduler that a rule has been changed
 	DeleteAlertRule(key models.AlertRuleKey)
+	// GetDryRunNotificationLog returns the notifications of the organization that the scheduler did
+	// not send since the given time because it runs in dry-run mode, oldest first.
+	GetDryRunNotificationLog(ctx context.Context, orgID int64, since time.Time) ([]DryRunNotification, error)
+	// Shutdown stops the evaluation of alert rules and waits until the running evaluations completed
+	// or the context is done.
+	Shutdown(ctx context.Context) error
 	// the following are used by tests only used for tests
 	evalApplied(models.AlertRuleKey, time.Time)
 	stopApplied(models.AlertRuleKey)
@@ -78,6 +85,7 @@ type schedule struct {
 
 	ruleStore         store.RuleStore
 	instanceStore     store.InstanceStore
+	ruleStatusStore   store.RuleStatusStore
 	adminConfigStore  store.AdminConfigurationStore
 	orgStore          store.OrgStore
 	expressionService *expr.Service
@@ -97,12 +105,34 @@ type schedule struct {
 	adminConfigPollInterval time.Duration
 	disabledOrgs            map[int64]struct{}
 	minRuleInterval         time.Duration
+	eventBus                bus.Bus
 
 	// schedulableAlertRules contains the alert rules that are considered for
 	// evaluation in the current tick. The evaluation of an alert rule in the
 	// current tick depends on its evaluation interval and when it was
 	// last evaluated.
 	schedulableAlertRules schedulableAlertRulesRegistry
+
+	// ruleGroups tracks the evaluation strategies of the rule groups and the outcomes of their rules.
+	ruleGroups *ruleGroupEvaluations
+
+	// dispatch sends the alerts of a rule to the notifiers. It is dispatchAlerts unless tests replace it.
+	dispatch func(models.AlertRuleKey, definitions.PostableAlerts, log.Logger)
+	// dryRun keeps the scheduler from sending notifications. The notifications it would have sent
+	// are recorded in dryRunLog instead.
+	dryRun    bool
+	dryRunLog *dryRunNotificationLog
+	// recoverFromEvalPanic makes conditionEval recover from panics of evaluations.
+	recoverFromEvalPanic bool
+	// concurrentGroupEval makes the rules of independent rule groups be evaluated at once, at most
+	// maxConcurrentGroupEval of them at the same time.
+	concurrentGroupEval    bool
+	maxConcurrentGroupEval int
+
+	// shutdownMtx guards shuttingDown and the start of evaluations, which are counted by inFlight.
+	shutdownMtx  sync.Mutex
+	shuttingDown bool
+	inFlight     sync.WaitGroup
 }
 
 // SchedulerCfg is the scheduler configuration.
@@ -117,12 +147,25 @@ type SchedulerCfg struct {
 	RuleStore               store.RuleStore
 	OrgStore                store.OrgStore
 	InstanceStore           store.InstanceStore
+	RuleStatusStore         store.RuleStatusStore
 	AdminConfigStore        store.AdminConfigurationStore
 	MultiOrgNotifier        *notifier.MultiOrgAlertmanager
 	Metrics                 *metrics.Scheduler
 	AdminConfigPollInterval time.Duration
 	DisabledOrgs            map[int64]struct{}
 	MinRuleInterval         time.Duration
+	// EventBus is optional. The scheduler publishes the state changes of alert instances on it.
+	EventBus bus.Bus
+	// DryRun evaluates rules and updates the state of their alert instances without sending notifications.
+	DryRun bool
+	// RecoverFromEvalPanic turns panics of evaluations into error results of the rule that panicked,
+	// which then goes into its execution error state, instead of crashing the process.
+	RecoverFromEvalPanic bool
+	// ConcurrentGroupEval evaluates the rules of a rule group at once, instead of spreading their evaluations
+	// over the base interval, when none of the rules depends on the evaluations of the others.
+	ConcurrentGroupEval bool
+	// MaxConcurrentGroupEval is the number of rules of a group that are evaluated at once. 0 does not limit it.
+	MaxConcurrentGroupEval int
 }
 
 // NewScheduler returns a new schedule.
@@ -141,6 +184,7 @@ func NewScheduler(cfg SchedulerCfg, expressionService *expr.Service, appURL *url
 		evaluator:               cfg.Evaluator,
 		ruleStore:               cfg.RuleStore,
 		instanceStore:           cfg.InstanceStore,
+		ruleStatusStore:         cfg.RuleStatusStore,
 		orgStore:                cfg.OrgStore,
 		expressionService:       expressionService,
 		adminConfigStore:        cfg.AdminConfigStore,
@@ -154,8 +198,16 @@ func NewScheduler(cfg SchedulerCfg, expressionService *expr.Service, appURL *url
 		adminConfigPollInterval: cfg.AdminConfigPollInterval,
 		disabledOrgs:            cfg.DisabledOrgs,
 		minRuleInterval:         cfg.MinRuleInterval,
+		eventBus:                cfg.EventBus,
 		schedulableAlertRules:   schedulableAlertRulesRegistry{rules: make(map[models.AlertRuleKey]*models.SchedulableAlertRule)},
+		ruleGroups:              newRuleGroupEvaluations(),
+		dryRun:                  cfg.DryRun,
+		dryRunLog:               newDryRunNotificationLog(maxDryRunNotifications),
+		recoverFromEvalPanic:    cfg.RecoverFromEvalPanic,
+		concurrentGroupEval:     cfg.ConcurrentGroupEval,
+		maxConcurrentGroupEval:  cfg.MaxConcurrentGroupEval,
 	}
+	sch.dispatch = sch.dispatchAlerts
 	return &sch
 }
 
@@ -338,6 +390,7 @@ func (sch *schedule) DeleteAlertRule(key models.AlertRuleKey) {
 	}
 	// stop rule evaluation
 	ruleInfo.stop()
+	sch.ruleGroups.forget(key)
 
 	// Our best bet at this point is that we update the metrics with what we hope to schedule in the next tick.
 	alertRules := sch.schedulableAlertRules.all()
@@ -368,9 +421,14 @@ func (sch *schedule) adminConfigSync(ctx context.Context) error {
 
 func (sch *schedule) schedulePeriodic(ctx context.Context) error {
 	dispatcherGroup, ctx := errgroup.WithContext(ctx)
+	// the rules of the first tick already existed when the scheduler started.
+	firstTick := true
 	for {
 		select {
 		case tick := <-sch.ticker.C:
+			if sch.isShuttingDown() {
+				continue
+			}
 			// We use Round(0) on the start time to remove the monotonic clock.
 			// This is required as ticks from the ticker and time.Now() can have
 			// a monotonic clock that when subtracted do not represent the delta
@@ -388,6 +446,8 @@ func (sch *schedule) schedulePeriodic(ctx context.Context) error {
 				sch.log.Error("scheduler failed to update alert rules", "err", err)
 			}
 			alertRules := sch.schedulableAlertRules.all()
+			sch.ruleGroups.setRules(alertRules)
+			sortByEvalOrder(alertRules)
 
 			sch.log.Debug("alert rules fetched", "count", len(alertRules), "disabled_orgs", disabledOrgs)
 
@@ -402,16 +462,13 @@ func (sch *schedule) schedulePeriodic(ctx context.Context) error {
 			sch.metrics.SchedulableAlertRules.Set(float64(len(alertRules)))
 			sch.metrics.SchedulableAlertRulesHash.Set(float64(hashUIDs(alertRules)))
 
-			type readyToRunItem struct {
-				key      models.AlertRuleKey
-				ruleName string
-				ruleInfo *alertRuleInfo
-				version  int64
-			}
-
 			readyToRun := make([]readyToRunItem, 0)
+			dependentGroups := make(map[models.AlertRuleGroupKey]bool)
 			for _, item := range alertRules {
 				key := item.GetKey()
+				if dependsOnGroup(item) {
+					dependentGroups[item.GetGroupKey()] = true
+				}
 				itemVersion := item.Version
 				ruleInfo, newRoutine := sch.registry.getOrCreateInfo(ctx, key)
 
@@ -424,6 +481,7 @@ func (sch *schedule) schedulePeriodic(ctx context.Context) error {
 				invalidInterval := item.IntervalSeconds%int64(sch.baseInterval.Seconds()) != 0
 
 				if newRoutine && !invalidInterval {
+					ruleInfo.existedAtStart = firstTick
 					dispatcherGroup.Go(func() error {
 						return sch.ruleRoutine(ruleInfo.ctx, key, ruleInfo.evalCh, ruleInfo.updateCh)
 					})
@@ -437,33 +495,39 @@ func (sch *schedule) schedulePeriodic(ctx context.Context) error {
 				}
 
 				itemFrequency := item.IntervalSeconds / int64(sch.baseInterval.Seconds())
-				if item.IntervalSeconds != 0 && tickNum%itemFrequency == 0 {
-					readyToRun = append(readyToRun, readyToRunItem{key: key, ruleName: item.Title, ruleInfo: ruleInfo, version: itemVersion})
+				// rules that are sampled are only evaluated on every Nth tick of their interval.
+				if item.EvalEveryN > 1 {
+					itemFrequency *= int64(item.EvalEveryN)
+				}
+				// the offset shifts the ticks of the rule, it is validated to be a multiple of the base interval.
+				itemOffset := item.EvalOffsetSeconds / int64(sch.baseInterval.Seconds())
+				if item.IntervalSeconds != 0 && tickNum%itemFrequency == itemOffset%itemFrequency {
+					readyToRun = append(readyToRun, readyToRunItem{key: key, groupKey: item.GetGroupKey(), ruleName: item.Title, ruleInfo: ruleInfo, version: itemVersion})
 				}
 
 				// remove the alert rule from the registered alert rules
 				delete(registeredDefinitions, key)
 			}
 
+			// Every rule is evaluated by its own routine. The evaluations are spread over the base interval
+			// to avoid sending all queries to the data sources at the same time, in the evaluation order of
+			// the rules within their group. With concurrent group evaluations, the rules of groups that do not
+			// depend on each other share a slot and are evaluated at once.
+			slots := sch.evaluationSlots(readyToRun, dependentGroups)
 			var step int64 = 0
-			if len(readyToRun) > 0 {
-				step = sch.baseInterval.Nanoseconds() / int64(len(readyToRun))
+			if len(slots) > 0 {
+				step = sch.baseInterval.Nanoseconds() / int64(len(slots))
 			}
 
-			for i := range readyToRun {
-				item := readyToRun[i]
+			for i := range slots {
+				slot := slots[i]
 
 				time.AfterFunc(time.Duration(int64(i)*step), func() {
-					success, dropped := item.ruleInfo.eval(tick, item.version)
-					if !success {
-						sch.log.Debug("scheduled evaluation was canceled because evaluation routine was stopped", "uid", item.key.UID, "org", item.key.OrgID, "time", tick)
+					if len(slot) == 1 {
+						sch.sendEvaluation(slot[0], tick, nil)
 						return
 					}
-					if dropped != nil {
-						sch.log.Warn("Alert rule evaluation is too slow - dropped tick", "uid", item.key.UID, "org", item.key.OrgID, "time", tick)
-						orgID := fmt.Sprint(item.key.OrgID)
-						sch.metrics.EvaluationMissed.WithLabelValues(orgID, item.ruleName).Inc()
-					}
+					sch.evalConcurrently(slot, tick)
 				})
 			}
 
@@ -473,6 +537,7 @@ func (sch *schedule) schedulePeriodic(ctx context.Context) error {
 			}
 
 			sch.metrics.SchedulePeriodicDuration.Observe(time.Since(start).Seconds())
+			firstTick = false
 		case <-ctx.Done():
 			waitErr := dispatcherGroup.Wait()
 
@@ -505,43 +570,12 @@ func (sch *schedule) ruleRoutine(grafanaCtx context.Context, key models.AlertRul
 			logger.Debug("no alerts to put in the notifier or to send to external Alertmanager(s)")
 			return
 		}
-
-		// Send alerts to local notifier if they need to be handled internally
-		// or if no external AMs have been discovered yet.
-		var localNotifierExist, externalNotifierExist bool
-		if sch.sendAlertsTo[key.OrgID] == models.ExternalAlertmanagers && len(sch.AlertmanagersFor(key.OrgID)) > 0 {
-			logger.Debug("no alerts to put in the notifier")
-		} else {
-			logger.Debug("sending alerts to local notifier", "count", len(alerts.PostableAlerts), "alerts", alerts.PostableAlerts)
-			n, err := sch.multiOrgNotifier.AlertmanagerFor(key.OrgID)
-			if err == nil {
-				localNotifierExist = true
-				if err := n.PutAlerts(alerts); err != nil {
-					logger.Error("failed to put alerts in the local notifier", "count", len(alerts.PostableAlerts), "err", err)
-				}
-			} else {
-				if errors.Is(err, notifier.ErrNoAlertmanagerForOrg) {
-					logger.Debug("local notifier was not found")
-				} else {
-					logger.Error("local notifier is not available", "err", err)
-				}
-			}
-		}
-
-		// Send alerts to external Alertmanager(s) if we have a sender for this organization
-		// and alerts are not being handled just internally.
-		sch.adminConfigMtx.RLock()
-		defer sch.adminConfigMtx.RUnlock()
-		s, ok := sch.senders[key.OrgID]
-		if ok && sch.sendAlertsTo[key.OrgID] != models.InternalAlertmanager {
-			logger.Debug("sending alerts to external notifier", "count", len(alerts.PostableAlerts), "alerts", alerts.PostableAlerts)
-			s.SendAlerts(alerts)
-			externalNotifierExist = true
-		}
-
-		if !localNotifierExist && !externalNotifierExist {
-			logger.Error("no external or internal notifier - alerts not delivered!", "count", len(alerts.PostableAlerts))
+		if sch.dryRun {
+			sch.dryRunLog.record(key, alerts, sch.clock.Now())
+			logger.Info("dry-run notification would have been sent", "count", len(alerts.PostableAlerts))
+			return
 		}
+		sch.dispatch(key, alerts, logger)
 	}
 
 	clearState := func() {
@@ -551,6 +585,12 @@ func (sch *schedule) ruleRoutine(grafanaCtx context.Context, key models.AlertRul
 		notify(expiredAlerts, logger)
 	}
 
+	// evaluations counts the evaluations of the rule since its routine started. The notifications of the
+	// first WarmUpEvals evaluations of new rules are suppressed.
+	evaluations := 0
+	info, err := sch.registry.get(key)
+	warmedUp := err == nil && info.existedAtStart
+
 	updateRule := func(ctx context.Context, oldRule *models.AlertRule) (*models.AlertRule, error) {
 		q := models.GetAlertRuleByUIDQuery{OrgID: key.OrgID, UID: key.UID}
 		err := sch.ruleStore.GetAlertRuleByUID(ctx, &q)
@@ -566,6 +606,15 @@ func (sch *schedule) ruleRoutine(grafanaCtx context.Context, key models.AlertRul
 
 	evaluate := func(ctx context.Context, r *models.AlertRule, attempt int64, e *evaluation) error {
 		logger := logger.New("version", r.Version, "attempt", attempt, "now", e.scheduledAt)
+		if !r.ActiveWindow.Contains(e.scheduledAt) {
+			logger.Debug("skipping evaluation outside of the active window of the rule")
+			previousStates := sch.currentStates(r)
+			inactiveStates := sch.stateManager.ResolveInactive(ctx, r, e.scheduledAt)
+			sch.saveAlertStates(ctx, inactiveStates)
+			sch.publishStateChanges(ctx, previousStates, inactiveStates)
+			notify(FromAlertStateToPostableAlerts(inactiveStates, sch.stateManager, sch.appURL), logger)
+			return nil
+		}
 		start := sch.clock.Now()
 
 		condition := models.Condition{
@@ -573,7 +622,7 @@ func (sch *schedule) ruleRoutine(grafanaCtx context.Context, key models.AlertRul
 			OrgID:     r.OrgID,
 			Data:      r.Data,
 		}
-		results, err := sch.evaluator.ConditionEval(&condition, e.scheduledAt, sch.expressionService)
+		results, err := sch.conditionEval(logger, &condition, e.scheduledAt)
 		dur := sch.clock.Now().Sub(start)
 		evalTotal.Inc()
 		evalDuration.Observe(dur.Seconds())
@@ -581,14 +630,27 @@ func (sch *schedule) ruleRoutine(grafanaCtx context.Context, key models.AlertRul
 			evalTotalFailures.Inc()
 			// consider saving alert instance on error
 			logger.Error("failed to evaluate alert rule", "duration", dur, "err", err)
+			sch.saveRuleStatus(ctx, r, e.scheduledAt, nil, err)
+			sch.ruleGroups.record(r, true)
 			return err
 		}
 		logger.Debug("alert rule evaluated", "results", results, "duration", dur)
+		sch.saveRuleStatus(ctx, r, e.scheduledAt, results, nil)
 
-		processedStates := sch.stateManager.ProcessEvalResults(ctx, r, results)
+		opts := sch.ruleGroups.record(r, hasFailedResults(results))
+		previousStates := sch.currentStates(r)
+		processedStates := sch.stateManager.ProcessEvalResultsWithOptions(ctx, r, results, opts)
 		sch.saveAlertStates(ctx, processedStates)
-		alerts := FromAlertStateToPostableAlerts(processedStates, sch.stateManager, sch.appURL)
+		sch.publishStateChanges(ctx, previousStates, processedStates)
 
+		// the alerts are not converted during the warm-up, so that they are not considered sent.
+		evaluations++
+		if !warmedUp && evaluations <= r.WarmUpEvals {
+			logger.Debug("suppressing notifications during the warm-up of the rule", "evaluation", evaluations, "warm_up_evals", r.WarmUpEvals)
+			return nil
+		}
+		warmedUp = true
+		alerts := FromAlertStateToPostableAlerts(processedStates, sch.stateManager, sch.appURL)
 		notify(alerts, logger)
 		return nil
 	}
@@ -632,14 +694,22 @@ func (sch *schedule) ruleRoutine(grafanaCtx context.Context, key models.AlertRul
 				return nil
 			}
 			if evalRunning {
+				ctx.finish()
+				continue
+			}
+			if !sch.startEvaluation() {
+				logger.Debug("skipping evaluation because the scheduler is shutting down", "now", ctx.scheduledAt)
+				ctx.finish()
 				continue
 			}
 
 			func() {
 				evalRunning = true
+				defer sch.inFlight.Done()
 				defer func() {
 					evalRunning = false
 					sch.evalApplied(key, ctx.scheduledAt)
+					ctx.finish()
 				}()
 
 				err := retryIfError(func(attempt int64) error {
@@ -666,18 +736,60 @@ func (sch *schedule) ruleRoutine(grafanaCtx context.Context, key models.AlertRul
 	}
 }
 
+// dispatchAlerts sends the alerts of the rule to the local notifier and to the external
+// Alertmanagers of its organization, depending on where the organization handles its alerts.
+func (sch *schedule) dispatchAlerts(key models.AlertRuleKey, alerts definitions.PostableAlerts, logger log.Logger) {
+	// Send alerts to local notifier if they need to be handled internally
+	// or if no external AMs have been discovered yet.
+	var localNotifierExist, externalNotifierExist bool
+	if sch.sendAlertsTo[key.OrgID] == models.ExternalAlertmanagers && len(sch.AlertmanagersFor(key.OrgID)) > 0 {
+		logger.Debug("no alerts to put in the notifier")
+	} else {
+		logger.Debug("sending alerts to local notifier", "count", len(alerts.PostableAlerts), "alerts", alerts.PostableAlerts)
+		n, err := sch.multiOrgNotifier.AlertmanagerFor(key.OrgID)
+		if err == nil {
+			localNotifierExist = true
+			if err := n.PutAlerts(alerts); err != nil {
+				logger.Error("failed to put alerts in the local notifier", "count", len(alerts.PostableAlerts), "err", err)
+			}
+		} else {
+			if errors.Is(err, notifier.ErrNoAlertmanagerForOrg) {
+				logger.Debug("local notifier was not found")
+			} else {
+				logger.Error("local notifier is not available", "err", err)
+			}
+		}
+	}
+
+	// Send alerts to external Alertmanager(s) if we have a sender for this organization
+	// and alerts are not being handled just internally.
+	sch.adminConfigMtx.RLock()
+	defer sch.adminConfigMtx.RUnlock()
+	s, ok := sch.senders[key.OrgID]
+	if ok && sch.sendAlertsTo[key.OrgID] != models.InternalAlertmanager {
+		logger.Debug("sending alerts to external notifier", "count", len(alerts.PostableAlerts), "alerts", alerts.PostableAlerts)
+		s.SendAlerts(alerts)
+		externalNotifierExist = true
+	}
+
+	if !localNotifierExist && !externalNotifierExist {
+		logger.Error("no external or internal notifier - alerts not delivered!", "count", len(alerts.PostableAlerts))
+	}
+}
+
 func (sch *schedule) saveAlertStates(ctx context.Context, states []*state.State) {
 	sch.log.Debug("saving alert states", "count", len(states))
 	for _, s := range states {
 		cmd := models.SaveAlertInstanceCommand{
-			RuleOrgID:         s.OrgID,
-			RuleUID:           s.AlertRuleUID,
-			Labels:            models.InstanceLabels(s.Labels),
-			State:             models.InstanceStateType(s.State.String()),
-			StateReason:       s.StateReason,
-			LastEvalTime:      s.LastEvaluationTime,
-			CurrentStateSince: s.StartsAt,
-			CurrentStateEnd:   s.EndsAt,
+			RuleOrgID:           s.OrgID,
+			RuleUID:             s.AlertRuleUID,
+			Labels:              models.InstanceLabels(s.Labels),
+			State:               models.InstanceStateType(s.State.String()),
+			StateReason:         s.StateReason,
+			LastEvalTime:        s.LastEvaluationTime,
+			CurrentStateSince:   s.StartsAt,
+			CurrentStateEnd:     s.EndsAt,
+			MissingSeriesLabels: s.MissingSeriesLabels,
 		}
 		err := sch.instanceStore.SaveAlertInstance(ctx, &cmd)
 		if err != nil {
@@ -686,6 +798,53 @@ func (sch *schedule) saveAlertStates(ctx context.Context, states []*state.State)
 	}
 }
 
+// saveRuleStatus stores the status of the rule that follows from the outcome of its evaluation.
+func (sch *schedule) saveRuleStatus(ctx context.Context, r *models.AlertRule, evaluatedAt time.Time, results eval.Results, evalErr error) {
+	if sch.ruleStatusStore == nil {
+		return
+	}
+	status, msg := ruleStatusFromResults(results, evalErr)
+	err := sch.ruleStatusStore.SaveAlertRuleStatus(ctx, models.AlertRuleStatusEntry{
+		OrgID:          r.OrgID,
+		RuleUID:        r.UID,
+		Status:         status,
+		Error:          msg,
+		LastEvaluation: evaluatedAt,
+	})
+	if err != nil {
+		sch.log.Error("failed to save alert rule status", "uid", r.UID, "orgId", r.OrgID, "status", status, "msg", err.Error())
+	}
+}
+
+// ruleStatusFromResults returns the status of a rule and the first error of its evaluation.
+// The rule is in error if it could not be evaluated or all of its series failed, and it is degraded
+// if only some of its series failed or had no data.
+func ruleStatusFromResults(results eval.Results, evalErr error) (models.AlertRuleStatus, string) {
+	if evalErr != nil {
+		return models.AlertRuleStatusError, evalErr.Error()
+	}
+	failed, missing := 0, 0
+	msg := ""
+	for _, result := range results {
+		switch result.State {
+		case eval.Error:
+			failed++
+			if msg == "" && result.Error != nil {
+				msg = result.Error.Error()
+			}
+		case eval.NoData:
+			missing++
+		}
+	}
+	switch {
+	case len(results) > 0 && failed == len(results):
+		return models.AlertRuleStatusError, msg
+	case failed > 0 || (missing > 0 && missing < len(results)):
+		return models.AlertRuleStatusDegraded, msg
+	}
+	return models.AlertRuleStatusOK, ""
+}
+
 // overrideCfg is only used on tests.
 func (sch *schedule) overrideCfg(cfg SchedulerCfg) {
 	sch.clock = cfg.C
@@ -55,6 +55,29 @@ func (_m *FakeScheduleService) DroppedAlertmanagersFor(orgID int64) []*url.URL {
 	return r0
 }
 
+// GetDryRunNotificationLog provides a mock function with given fields: ctx, orgID, since
+func (_m *FakeScheduleService) GetDryRunNotificationLog(ctx context.Context, orgID int64, since time.Time) ([]DryRunNotification, error) {
+	ret := _m.Called(ctx, orgID, since)
+
+	var r0 []DryRunNotification
+	if rf, ok := ret.Get(0).(func(context.Context, int64, time.Time) []DryRunNotification); ok {
+		r0 = rf(ctx, orgID, since)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]DryRunNotification)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64, time.Time) error); ok {
+		r1 = rf(ctx, orgID, since)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Pause provides a mock function with given fields:
 func (_m *FakeScheduleService) Pause() error {
 	ret := _m.Called()
@@ -83,6 +106,20 @@ func (_m *FakeScheduleService) Run(_a0 context.Context) error {
 	return r0
 }
 
+// Shutdown provides a mock function with given fields: ctx
+func (_m *FakeScheduleService) Shutdown(ctx context.Context) error {
+	ret := _m.Called(ctx)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context) error); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Unpause provides a mock function with given fields:
 func (_m *FakeScheduleService) Unpause() error {
 	ret := _m.Called()
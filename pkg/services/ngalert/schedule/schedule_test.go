@@ -25,6 +25,7 @@ import (
 	"github.com/grafana/grafana/pkg/services/ngalert/models"
 	"github.com/grafana/grafana/pkg/services/ngalert/schedule"
 	"github.com/grafana/grafana/pkg/services/ngalert/state"
+	"github.com/grafana/grafana/pkg/services/ngalert/store"
 	"github.com/grafana/grafana/pkg/services/ngalert/tests"
 )
 
@@ -241,6 +242,61 @@ func TestAlertingTicker(t *testing.T) {
 	})
 }
 
+func TestAlertingTickerEvalEveryN(t *testing.T) {
+	ctx := context.Background()
+	ng, dbstore := tests.SetupTestEnv(t, 1)
+
+	const mainOrgID int64 = 1
+	sample := func(rule *models.AlertRule, n int) *models.AlertRule {
+		updated := *rule
+		updated.EvalEveryN = n
+		require.NoError(t, dbstore.UpdateAlertRules(ctx, []store.UpdateRule{{Existing: rule, New: updated}}))
+		return &updated
+	}
+	sampled := sample(tests.CreateTestAlertRule(t, ctx, dbstore, 1, mainOrgID), 3)
+	everyTick := sample(tests.CreateTestAlertRule(t, ctx, dbstore, 1, mainOrgID), 1)
+
+	evalAppliedCh := make(chan evalAppliedInfo, 2)
+	mockedClock := clock.NewMock()
+	schedCfg := schedule.SchedulerCfg{
+		C:            mockedClock,
+		BaseInterval: time.Second,
+		EvalAppliedFunc: func(alertDefKey models.AlertRuleKey, now time.Time) {
+			evalAppliedCh <- evalAppliedInfo{alertDefKey: alertDefKey, now: now}
+		},
+		RuleStore:               dbstore,
+		InstanceStore:           dbstore,
+		Logger:                  log.New("ngalert schedule test"),
+		Metrics:                 testMetrics.GetSchedulerMetrics(),
+		AdminConfigPollInterval: 10 * time.Minute, // do not poll in unit tests.
+	}
+	st := state.NewManager(schedCfg.Logger, testMetrics.GetStateMetrics(), nil, dbstore, dbstore, ng.SQLStore, &dashboards.FakeDashboardService{}, &image.NoopImageService{})
+	sched := schedule.NewScheduler(schedCfg, nil, &url.URL{Scheme: "http", Host: "localhost"}, st)
+
+	go func() {
+		err := sched.Run(ctx)
+		require.NoError(t, err)
+	}()
+	runtime.Gosched()
+
+	// the rule with EvalEveryN=1 is evaluated on every tick like rules without sampling, the rule with
+	// EvalEveryN=3 skips the 1st and the 2nd tick and is evaluated on the 3rd.
+	expected := [][]models.AlertRuleKey{
+		{everyTick.GetKey()},
+		{everyTick.GetKey()},
+		{sampled.GetKey(), everyTick.GetKey()},
+		{everyTick.GetKey()},
+		{everyTick.GetKey()},
+		{sampled.GetKey(), everyTick.GetKey()},
+	}
+	for i, keys := range expected {
+		t.Run(fmt.Sprintf("on tick %d alert rules: %s should be evaluated", i+1, concatenate(keys)), func(t *testing.T) {
+			tick := advanceClock(t, mockedClock)
+			assertEvalRun(t, evalAppliedCh, tick, keys...)
+		})
+	}
+}
+
 func assertEvalRun(t *testing.T, ch <-chan evalAppliedInfo, tick time.Time, keys ...models.AlertRuleKey) {
 	timeout := time.After(time.Second)
 
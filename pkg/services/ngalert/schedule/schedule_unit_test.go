@@ -16,12 +16,14 @@ import (
 	"github.com/grafana/grafana-plugin-sdk-go/data"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
 	"github.com/grafana/grafana/pkg/expr"
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/services/annotations"
 	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
 	"github.com/grafana/grafana/pkg/services/ngalert/eval"
 	"github.com/grafana/grafana/pkg/services/ngalert/image"
 	"github.com/grafana/grafana/pkg/services/ngalert/metrics"
@@ -760,6 +762,46 @@ func TestSchedule_ruleRoutine(t *testing.T) {
 			// TODO figure out how to simulate failure
 			t.Skip()
 		})
+		t.Run("it should set the rule status to error until an evaluation succeeds", func(t *testing.T) {
+			evalChan := make(chan *evaluation)
+			evalAppliedChan := make(chan time.Time)
+
+			sch, ruleStore, _, _, _ := createSchedule(evalAppliedChan)
+			statusStore := &store.FakeRuleStatusStore{}
+			sch.ruleStatusStore = statusStore
+			evaluator := &eval.FakeEvaluator{}
+			evaluator.EXPECT().ConditionEval(mock.Anything, mock.Anything, mock.Anything).Return(nil, errors.New("exec error")).Once()
+			evaluator.EXPECT().ConditionEval(mock.Anything, mock.Anything, mock.Anything).Return(eval.Results{{State: eval.Normal}}, nil).Once()
+			sch.evaluator = evaluator
+
+			rule := CreateTestAlertRule(t, ruleStore, 10, rand.Int63(), eval.Normal)
+
+			go func() {
+				ctx, cancel := context.WithCancel(context.Background())
+				t.Cleanup(cancel)
+				_ = sch.ruleRoutine(ctx, rule.GetKey(), evalChan, make(chan struct{}))
+			}()
+
+			failedAt := time.UnixMicro(rand.Int63())
+			evalChan <- &evaluation{scheduledAt: failedAt, version: rule.Version}
+			waitForTimeChannel(t, evalAppliedChan)
+
+			statuses := statusStore.GetStatuses()
+			require.Len(t, statuses, 1)
+			require.Equal(t, models.AlertRuleStatusError, statuses[0].Status)
+			require.Equal(t, "exec error", statuses[0].Error)
+			require.Equal(t, rule.UID, statuses[0].RuleUID)
+			require.Equal(t, failedAt, statuses[0].LastEvaluation)
+
+			evalChan <- &evaluation{scheduledAt: failedAt.Add(10 * time.Second), version: rule.Version}
+			waitForTimeChannel(t, evalAppliedChan)
+
+			statuses = statusStore.GetStatuses()
+			require.Len(t, statuses, 2)
+			require.Equal(t, models.AlertRuleStatusOK, statuses[1].Status)
+			require.Empty(t, statuses[1].Error)
+			evaluator.AssertExpectations(t)
+		})
 	})
 
 	t.Run("when there are alerts that should be firing", func(t *testing.T) {
@@ -886,6 +928,76 @@ func TestSchedule_DeleteAlertRule(t *testing.T) {
 	})
 }
 
+func TestSchedule_ruleRoutine_warmUp(t *testing.T) {
+	setup := func(t *testing.T) (*schedule, *store.FakeInstanceStore, *models.AlertRule, func() int) {
+		ruleStore := store.NewFakeRuleStore(t)
+		instanceStore := &store.FakeInstanceStore{}
+		sch, _ := setupScheduler(t, ruleStore, instanceStore, store.NewFakeAdminConfigStore(t), prometheus.NewPedanticRegistry())
+		var mtx sync.Mutex
+		var dispatched int
+		sch.dispatch = func(_ models.AlertRuleKey, alerts definitions.PostableAlerts, _ log.Logger) {
+			mtx.Lock()
+			defer mtx.Unlock()
+			dispatched += len(alerts.PostableAlerts)
+		}
+		dispatchCalls := func() int {
+			mtx.Lock()
+			defer mtx.Unlock()
+			return dispatched
+		}
+		rule := CreateTestAlertRule(t, ruleStore, 10, rand.Int63(), eval.Alerting)
+		rule.WarmUpEvals = 2
+		return sch, instanceStore, rule, dispatchCalls
+	}
+	run := func(t *testing.T, sch *schedule, rule *models.AlertRule) func(at time.Time) {
+		evalAppliedChan := make(chan time.Time)
+		sch.evalAppliedFunc = func(key models.AlertRuleKey, t time.Time) {
+			evalAppliedChan <- t
+		}
+		evalChan := make(chan *evaluation)
+		go func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			t.Cleanup(cancel)
+			_ = sch.ruleRoutine(ctx, rule.GetKey(), evalChan, make(chan struct{}))
+		}()
+		return func(at time.Time) {
+			evalChan <- &evaluation{scheduledAt: at, version: rule.Version}
+			waitForTimeChannel(t, evalAppliedChan)
+		}
+	}
+
+	t.Run("should not send notifications during the warm-up of new rules", func(t *testing.T) {
+		sch, instanceStore, rule, dispatched := setup(t)
+		evaluate := run(t, sch, rule)
+		start := time.Now()
+
+		evaluate(start)
+		var states []models.InstanceStateType
+		for _, op := range instanceStore.RecordedOps {
+			if cmd, ok := op.(models.SaveAlertInstanceCommand); ok {
+				states = append(states, cmd.State)
+			}
+		}
+		require.Equal(t, []models.InstanceStateType{models.InstanceStateFiring}, states)
+		require.Zero(t, dispatched())
+
+		evaluate(start.Add(10 * time.Second))
+		require.Zero(t, dispatched())
+
+		evaluate(start.Add(20 * time.Second))
+		require.Equal(t, 1, dispatched())
+	})
+	t.Run("should send notifications of rules that existed when the scheduler started", func(t *testing.T) {
+		sch, _, rule, dispatched := setup(t)
+		info, _ := sch.registry.getOrCreateInfo(context.Background(), rule.GetKey())
+		info.existedAtStart = true
+		evaluate := run(t, sch, rule)
+
+		evaluate(time.Now())
+		require.Equal(t, 1, dispatched())
+	})
+}
+
 func generateRuleKey() models.AlertRuleKey {
 	return models.AlertRuleKey{
 		OrgID: rand.Int63(),
@@ -902,7 +1014,7 @@ func setupSchedulerWithFakeStores(t *testing.T) *schedule {
 	return sch
 }
 
-func setupScheduler(t *testing.T, rs store.RuleStore, is store.InstanceStore, acs store.AdminConfigurationStore, registry *prometheus.Registry) (*schedule, *clock.Mock) {
+func setupScheduler(t testing.TB, rs store.RuleStore, is store.InstanceStore, acs store.AdminConfigurationStore, registry *prometheus.Registry) (*schedule, *clock.Mock) {
 	t.Helper()
 
 	fakeAnnoRepo := store.NewFakeAnnotationsRepo()
@@ -1016,3 +1128,25 @@ func CreateTestAlertRule(t *testing.T, dbstore *store.FakeRuleStore, intervalSec
 	t.Logf("alert definition: %v with interval: %d created", rule.GetKey(), rule.IntervalSeconds)
 	return rule
 }
+
+func TestRuleStatusFromResults(t *testing.T) {
+	testCases := []struct {
+		name     string
+		results  eval.Results
+		err      error
+		expected models.AlertRuleStatus
+	}{
+		{name: "evaluation error", err: errors.New("exec error"), expected: models.AlertRuleStatusError},
+		{name: "all series failed", results: eval.Results{{State: eval.Error}, {State: eval.Error}}, expected: models.AlertRuleStatusError},
+		{name: "some series failed", results: eval.Results{{State: eval.Error}, {State: eval.Normal}}, expected: models.AlertRuleStatusDegraded},
+		{name: "some series had no data", results: eval.Results{{State: eval.NoData}, {State: eval.Alerting}}, expected: models.AlertRuleStatusDegraded},
+		{name: "rule had no data", results: eval.Results{{State: eval.NoData}}, expected: models.AlertRuleStatusOK},
+		{name: "all series evaluated", results: eval.Results{{State: eval.Normal}, {State: eval.Alerting}}, expected: models.AlertRuleStatusOK},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			status, _ := ruleStatusFromResults(tc.results, tc.err)
+			require.Equal(t, tc.expected, status)
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package schedule
+
+import (
+	"context"
+	"io"
+)
+
+// startEvaluation registers an evaluation as running. It returns false if the scheduler is shutting
+// down and the evaluation must not start.
+func (sch *schedule) startEvaluation() bool {
+	sch.shutdownMtx.Lock()
+	defer sch.shutdownMtx.Unlock()
+	if sch.shuttingDown {
+		return false
+	}
+	sch.inFlight.Add(1)
+	return true
+}
+
+func (sch *schedule) isShuttingDown() bool {
+	sch.shutdownMtx.Lock()
+	defer sch.shutdownMtx.Unlock()
+	return sch.shuttingDown
+}
+
+// Shutdown stops the scheduler from starting evaluations, and waits until the running evaluations
+// completed, so that the state of their alert instances is saved completely. It then closes the
+// stores of the scheduler that have connections of their own, which are the stores that implement
+// io.Closer. The shared database is closed by its own service. Shutdown returns the error of the
+// context if it is done before the evaluations completed.
+func (sch *schedule) Shutdown(ctx context.Context) error {
+	sch.shutdownMtx.Lock()
+	sch.shuttingDown = true
+	sch.shutdownMtx.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		sch.inFlight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		sch.log.Warn("stopped waiting for running evaluations", "err", ctx.Err())
+		return ctx.Err()
+	}
+
+	for _, s := range []interface{}{sch.ruleStore, sch.instanceStore, sch.ruleStatusStore, sch.adminConfigStore} {
+		closer, ok := s.(io.Closer)
+		if !ok {
+			continue
+		}
+		if err := closer.Close(); err != nil {
+			sch.log.Error("failed to close store", "err", err)
+		}
+	}
+	sch.log.Info("scheduler shut down")
+	return nil
+}
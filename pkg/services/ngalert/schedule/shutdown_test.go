@@ -0,0 +1,119 @@
+package schedule
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/expr"
+	"github.com/grafana/grafana/pkg/services/ngalert/eval"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/store"
+)
+
+// slowEvaluator delays the evaluations of its evaluator, and signals on started when one starts.
+type slowEvaluator struct {
+	eval.Evaluator
+	delay   time.Duration
+	started chan struct{}
+
+	mtx   sync.Mutex
+	calls int
+}
+
+func (e *slowEvaluator) ConditionEval(condition *models.Condition, now time.Time, expressionService *expr.Service) (eval.Results, error) {
+	e.mtx.Lock()
+	e.calls++
+	e.mtx.Unlock()
+	e.started <- struct{}{}
+	time.Sleep(e.delay)
+	return e.Evaluator.ConditionEval(condition, now, expressionService)
+}
+
+func (e *slowEvaluator) evaluations() int {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	return e.calls
+}
+
+// closingInstanceStore is an instance store with a connection of its own.
+type closingInstanceStore struct {
+	*store.FakeInstanceStore
+	closed bool
+}
+
+func (s *closingInstanceStore) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestSchedule_Shutdown(t *testing.T) {
+	setup := func(t *testing.T) (*schedule, *closingInstanceStore, *slowEvaluator, chan *evaluation, *models.AlertRule) {
+		ruleStore := store.NewFakeRuleStore(t)
+		instanceStore := &closingInstanceStore{FakeInstanceStore: &store.FakeInstanceStore{}}
+		sch, _ := setupScheduler(t, ruleStore, instanceStore, store.NewFakeAdminConfigStore(t), prometheus.NewPedanticRegistry())
+		evaluator := &slowEvaluator{Evaluator: sch.evaluator, delay: 500 * time.Millisecond, started: make(chan struct{}, 1)}
+		sch.evaluator = evaluator
+		rule := CreateTestAlertRule(t, ruleStore, 10, rand.Int63(), eval.Alerting)
+		evalChan := make(chan *evaluation)
+		go func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			t.Cleanup(cancel)
+			_ = sch.ruleRoutine(ctx, rule.GetKey(), evalChan, make(chan struct{}))
+		}()
+		return sch, instanceStore, evaluator, evalChan, rule
+	}
+	savedInstances := func(instanceStore *closingInstanceStore) int {
+		var count int
+		for _, op := range instanceStore.RecordedOps {
+			if _, ok := op.(models.SaveAlertInstanceCommand); ok {
+				count++
+			}
+		}
+		return count
+	}
+
+	t.Run("should wait for the running evaluation", func(t *testing.T) {
+		sch, instanceStore, evaluator, evalChan, rule := setup(t)
+		scheduledAt := time.UnixMicro(rand.Int63())
+		evalChan <- &evaluation{scheduledAt: scheduledAt, version: rule.Version}
+		<-evaluator.started
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		require.NoError(t, sch.Shutdown(ctx))
+
+		states := sch.stateManager.GetStatesForRuleUID(rule.OrgID, rule.UID)
+		require.Len(t, states, 1)
+		require.Equal(t, scheduledAt, states[0].LastEvaluationTime)
+		require.Equal(t, 1, savedInstances(instanceStore))
+		require.True(t, instanceStore.closed)
+
+		t.Run("and not start evaluations afterwards", func(t *testing.T) {
+			// the second evaluation is only received after the first one was skipped
+			for i := 1; i <= 2; i++ {
+				evalChan <- &evaluation{scheduledAt: scheduledAt.Add(time.Duration(i) * 10 * time.Second), version: rule.Version}
+			}
+			require.Equal(t, 1, evaluator.evaluations())
+			require.Equal(t, 1, savedInstances(instanceStore))
+		})
+	})
+	t.Run("should stop waiting when the context is done", func(t *testing.T) {
+		sch, instanceStore, evaluator, evalChan, rule := setup(t)
+		evalChan <- &evaluation{scheduledAt: time.UnixMicro(rand.Int63()), version: rule.Version}
+		<-evaluator.started
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		require.ErrorIs(t, sch.Shutdown(ctx), context.DeadlineExceeded)
+		require.False(t, instanceStore.closed)
+
+		require.NoError(t, sch.Shutdown(context.Background()))
+		require.True(t, instanceStore.closed)
+	})
+}
@@ -0,0 +1,44 @@
+package schedule
+
+import (
+	"context"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/eval"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/state"
+)
+
+// currentStates returns the state of every alert instance of the rule by the id of the instance.
+func (sch *schedule) currentStates(rule *models.AlertRule) map[string]eval.State {
+	states := sch.stateManager.GetStatesForRuleUID(rule.OrgID, rule.UID)
+	result := make(map[string]eval.State, len(states))
+	for _, s := range states {
+		result[s.CacheId] = s.State
+	}
+	return result
+}
+
+// publishStateChanges publishes an AlertRuleStateChangedEvent for every alert instance whose state
+// differs from its previous state. Alert instances without a previous state start as Normal.
+func (sch *schedule) publishStateChanges(ctx context.Context, previous map[string]eval.State, states []*state.State) {
+	if sch.eventBus == nil {
+		return
+	}
+	for _, s := range states {
+		previousState := previous[s.CacheId]
+		if previousState == s.State {
+			continue
+		}
+		event := &models.AlertRuleStateChangedEvent{
+			OrgID:         s.OrgID,
+			RuleUID:       s.AlertRuleUID,
+			PreviousState: models.InstanceStateType(previousState.String()),
+			NewState:      models.InstanceStateType(s.State.String()),
+			Labels:        s.Labels.Copy(),
+			FiredAt:       s.LastEvaluationTime,
+		}
+		if err := sch.eventBus.Publish(ctx, event); err != nil {
+			sch.log.Error("failed to publish state change", "uid", s.AlertRuleUID, "orgId", s.OrgID, "labels", s.Labels.String(), "state", s.State.String(), "err", err)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package schedule
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/services/ngalert/eval"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/store"
+)
+
+func TestSchedule_publishStateChanges(t *testing.T) {
+	ruleStore := store.NewFakeRuleStore(t)
+	sch, _ := setupScheduler(t, ruleStore, &store.FakeInstanceStore{}, store.NewFakeAdminConfigStore(t), prometheus.NewPedanticRegistry())
+	evalAppliedChan := make(chan time.Time)
+	sch.evalAppliedFunc = func(key models.AlertRuleKey, t time.Time) {
+		evalAppliedChan <- t
+	}
+
+	var mtx sync.Mutex
+	var events []models.AlertRuleStateChangedEvent
+	eventBus := bus.New()
+	eventBus.AddEventListener(func(_ context.Context, event *models.AlertRuleStateChangedEvent) error {
+		mtx.Lock()
+		defer mtx.Unlock()
+		events = append(events, *event)
+		return nil
+	})
+	sch.eventBus = eventBus
+
+	rule := CreateTestAlertRule(t, ruleStore, 10, rand.Int63(), eval.Alerting)
+	evalChan := make(chan *evaluation)
+	go func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		_ = sch.ruleRoutine(ctx, rule.GetKey(), evalChan, make(chan struct{}))
+	}()
+
+	firedAt := time.UnixMicro(rand.Int63())
+	evalChan <- &evaluation{scheduledAt: firedAt, version: rule.Version}
+	waitForTimeChannel(t, evalAppliedChan)
+	// the second evaluation keeps the instance firing, which is not a state change
+	evalChan <- &evaluation{scheduledAt: firedAt.Add(10 * time.Second), version: rule.Version}
+	waitForTimeChannel(t, evalAppliedChan)
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	require.Len(t, events, 1)
+	states := sch.stateManager.GetStatesForRuleUID(rule.OrgID, rule.UID)
+	require.Len(t, states, 1)
+	require.Equal(t, models.AlertRuleStateChangedEvent{
+		OrgID:         rule.OrgID,
+		RuleUID:       rule.UID,
+		PreviousState: models.InstanceStateNormal,
+		NewState:      models.InstanceStateFiring,
+		Labels:        states[0].Labels,
+		FiredAt:       firedAt,
+	}, events[0])
+}
@@ -22,11 +22,16 @@ type cache struct {
 	log         log.Logger
 	metrics     *metrics.State
 	externalURL *url.URL
+
+	// lastSeries are the label sets returned by the last evaluation of the rule that returned data.
+	// It is guarded by mtxStates.
+	lastSeries map[int64]map[string][]map[string]string // orgID > alertRuleUID > label sets
 }
 
 func newCache(logger log.Logger, metrics *metrics.State, externalURL *url.URL) *cache {
 	return &cache{
 		states:      make(map[int64]map[string]map[string]*State),
+		lastSeries:  make(map[int64]map[string][]map[string]string),
 		log:         logger,
 		metrics:     metrics,
 		externalURL: externalURL,
@@ -46,6 +51,16 @@ func (c *cache) getOrCreate(ctx context.Context, alertRule *ngModels.AlertRule,
 	lbs := mergeLabels(ruleLabels, result.Instance)
 	attachRuleLabels(lbs, alertRule)
 
+	if alertRule.TitleTemplate != "" {
+		title, err := ngModels.RenderTitleTemplate(alertRule.TitleTemplate, lbs)
+		if err != nil {
+			c.log.Error("error in rendering title template", "template", alertRule.TitleTemplate, "err", err.Error())
+			// Send the original template on error.
+			title = alertRule.TitleTemplate
+		}
+		annotations[ngModels.TitleAnnotation] = title
+	}
+
 	il := ngModels.InstanceLabels(lbs)
 	id, err := il.StringKey()
 	if err != nil {
@@ -72,6 +87,9 @@ func (c *cache) getOrCreate(ctx context.Context, alertRule *ngModels.AlertRule,
 			}
 		}
 		state.Annotations = annotations
+		// the meta-labels are not part of the key, so the labels of the state are refreshed in case they changed
+		// or the state was created before they were added.
+		state.Labels = lbs
 		c.states[alertRule.OrgID][alertRule.UID][id] = state
 		return state
 	}
@@ -97,6 +115,13 @@ func attachRuleLabels(m map[string]string, alertRule *ngModels.AlertRule) {
 	m[ngModels.RuleUIDLabel] = alertRule.UID
 	m[ngModels.NamespaceUIDLabel] = alertRule.NamespaceUID
 	m[prometheusModel.AlertNameLabel] = alertRule.Title
+	// the meta-labels cannot be overridden, labels with their names are kept with a prefix instead.
+	for name, value := range alertRule.MetaLabels() {
+		if existing, ok := m[name]; ok {
+			m[ngModels.ExportedLabelPrefix+name] = existing
+		}
+		m[name] = value
+	}
 }
 
 func (c *cache) expandRuleLabelsAndAnnotations(ctx context.Context, alertRule *ngModels.AlertRule, labels map[string]string, alertInstance eval.Result) (map[string]string, map[string]string) {
@@ -114,7 +139,7 @@ func (c *cache) expandRuleLabelsAndAnnotations(ctx context.Context, alertRule *n
 
 		return expanded
 	}
-	return expand(alertRule.Labels), expand(alertRule.Annotations)
+	return expand(alertRule.EffectiveLabels()), expand(alertRule.Annotations)
 }
 
 func (c *cache) set(entry *State) {
@@ -165,12 +190,31 @@ func (c *cache) removeByRuleUID(orgID int64, uid string) {
 	c.mtxStates.Lock()
 	defer c.mtxStates.Unlock()
 	delete(c.states[orgID], uid)
+	delete(c.lastSeries[orgID], uid)
 }
 
 func (c *cache) reset() {
 	c.mtxStates.Lock()
 	defer c.mtxStates.Unlock()
 	c.states = make(map[int64]map[string]map[string]*State)
+	c.lastSeries = make(map[int64]map[string][]map[string]string)
+}
+
+// setLastSeries stores the label sets returned by an evaluation of the rule that returned data.
+func (c *cache) setLastSeries(orgID int64, uid string, series []map[string]string) {
+	c.mtxStates.Lock()
+	defer c.mtxStates.Unlock()
+	if _, ok := c.lastSeries[orgID]; !ok {
+		c.lastSeries[orgID] = make(map[string][]map[string]string)
+	}
+	c.lastSeries[orgID][uid] = series
+}
+
+// getLastSeries returns the label sets returned by the last evaluation of the rule that returned data.
+func (c *cache) getLastSeries(orgID int64, uid string) []map[string]string {
+	c.mtxStates.RLock()
+	defer c.mtxStates.RUnlock()
+	return c.lastSeries[orgID][uid]
 }
 
 func (c *cache) recordMetrics() {
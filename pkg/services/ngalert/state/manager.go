@@ -126,6 +126,7 @@ func (st *Manager) Warm(ctx context.Context) {
 				EndsAt:               entry.CurrentStateEnd,
 				LastEvaluationTime:   entry.LastEvalTime,
 				Annotations:          ruleForEntry.Annotations,
+				MissingSeriesLabels:  entry.MissingSeriesLabels,
 			}
 			states = append(states, stateForEntry)
 		}
@@ -158,19 +159,52 @@ func (st *Manager) RemoveByRuleUID(orgID int64, ruleUID string) {
 	st.cache.removeByRuleUID(orgID, ruleUID)
 }
 
+// ProcessingOptions change how the state manager moves alert instances to their next state.
+type ProcessingOptions struct {
+	// HoldPending keeps pending alert instances pending even if their pending period has passed.
+	HoldPending bool
+	// KeepStateOnError keeps the current state of alert instances whose evaluation failed.
+	KeepStateOnError bool
+}
+
 func (st *Manager) ProcessEvalResults(ctx context.Context, alertRule *ngModels.AlertRule, results eval.Results) []*State {
+	return st.ProcessEvalResultsWithOptions(ctx, alertRule, results, ProcessingOptions{})
+}
+
+// ProcessEvalResultsWithOptions is like ProcessEvalResults but moves the alert instances to their
+// next state according to the options.
+func (st *Manager) ProcessEvalResultsWithOptions(ctx context.Context, alertRule *ngModels.AlertRule, results eval.Results, opts ProcessingOptions) []*State {
 	st.log.Debug("state manager processing evaluation results", "uid", alertRule.UID, "resultCount", len(results))
 	var states []*State
 	processedResults := make(map[string]*State, len(results))
 	for _, result := range results {
-		s := st.setNextState(ctx, alertRule, result)
+		s := st.setNextState(ctx, alertRule, result, opts)
 		states = append(states, s)
 		processedResults[s.CacheId] = s
 	}
+	if series, ok := returnedSeries(results); ok {
+		st.cache.setLastSeries(alertRule.OrgID, alertRule.UID, series)
+	}
 	st.staleResultsHandler(ctx, alertRule, processedResults)
 	return states
 }
 
+// returnedSeries returns the label sets of the results if the evaluation returned data, that is,
+// if none of the results is NoData or Error.
+func returnedSeries(results eval.Results) ([]map[string]string, bool) {
+	if len(results) == 0 {
+		return nil, false
+	}
+	series := make([]map[string]string, 0, len(results))
+	for _, result := range results {
+		if result.State == eval.NoData || result.State == eval.Error {
+			return nil, false
+		}
+		series = append(series, map[string]string(result.Instance.Copy()))
+	}
+	return series, true
+}
+
 // Maybe take a screenshot. Do it if:
 // 1. The alert state is transitioning into the "Alerting" state from something else.
 // 2. The alert state has just transitioned to the resolved state.
@@ -203,7 +237,7 @@ func (st *Manager) maybeTakeScreenshot(
 }
 
 // Set the current state based on evaluation results
-func (st *Manager) setNextState(ctx context.Context, alertRule *ngModels.AlertRule, result eval.Result) *State {
+func (st *Manager) setNextState(ctx context.Context, alertRule *ngModels.AlertRule, result eval.Result, opts ProcessingOptions) *State {
 	currentState := st.getOrCreate(ctx, alertRule, result)
 
 	currentState.LastEvaluationTime = result.EvaluatedAt
@@ -218,6 +252,7 @@ func (st *Manager) setNextState(ctx context.Context, alertRule *ngModels.AlertRu
 	currentState.TrimResults(alertRule)
 	oldState := currentState.State
 	oldReason := currentState.StateReason
+	oldStartsAt, oldEndsAt := currentState.StartsAt, currentState.EndsAt
 
 	st.log.Debug("setting alert state", "uid", alertRule.UID)
 	switch result.State {
@@ -226,12 +261,30 @@ func (st *Manager) setNextState(ctx context.Context, alertRule *ngModels.AlertRu
 	case eval.Alerting:
 		currentState.resultAlerting(alertRule, result)
 	case eval.Error:
+		if opts.KeepStateOnError {
+			currentState.Error = result.Error
+			if currentState.State == eval.Alerting {
+				currentState.setEndsAt(alertRule, result)
+			}
+			break
+		}
 		currentState.resultError(alertRule, result)
 	case eval.NoData:
 		currentState.resultNoData(alertRule, result)
 	case eval.Pending: // we do not emit results with this state
 	}
 
+	currentState.MissingSeriesLabels = nil
+	if result.State == eval.NoData && alertRule.NoDataState == ngModels.Alerting {
+		currentState.MissingSeriesLabels = st.cache.getLastSeries(alertRule.OrgID, alertRule.UID)
+	}
+
+	if opts.HoldPending && oldState == eval.Pending && currentState.State != eval.Pending && currentState.State != eval.Normal {
+		currentState.State = eval.Pending
+		currentState.StartsAt = oldStartsAt
+		currentState.EndsAt = oldEndsAt
+	}
+
 	// Set reason iff: result is different than state, reason is not Alerting or Normal
 	currentState.StateReason = ""
 
@@ -263,6 +316,31 @@ func (st *Manager) setNextState(ctx context.Context, alertRule *ngModels.AlertRu
 	return currentState
 }
 
+// ResolveInactive moves the alert instances of a rule that is outside of its active window to Normal,
+// with the reason that the rule is inactive, and returns the instances that changed. Alerting
+// instances are resolved.
+func (st *Manager) ResolveInactive(ctx context.Context, alertRule *ngModels.AlertRule, at time.Time) []*State {
+	var states []*State
+	for _, s := range st.GetStatesForRuleUID(alertRule.OrgID, alertRule.UID) {
+		if s.State == eval.Normal && s.StateReason == ngModels.StateReasonInactiveWindow {
+			continue
+		}
+		oldState, oldReason := s.State, s.StateReason
+		s.Error = nil
+		if s.State != eval.Normal {
+			s.StartsAt = at
+			s.EndsAt = at
+		}
+		s.State = eval.Normal
+		s.StateReason = ngModels.StateReasonInactiveWindow
+		s.Resolved = oldState == eval.Alerting
+		st.set(s)
+		go st.annotateState(ctx, alertRule, s.Labels, at, InstanceStateAndReason{State: s.State, Reason: s.StateReason}, InstanceStateAndReason{State: oldState, Reason: oldReason})
+		states = append(states, s)
+	}
+	return states
+}
+
 func (st *Manager) GetAll(orgID int64) []*State {
 	return st.cache.getAll(orgID)
 }
@@ -271,6 +349,20 @@ func (st *Manager) GetStatesForRuleUID(orgID int64, alertRuleUID string) []*Stat
 	return st.cache.getStatesForRuleUID(orgID, alertRuleUID)
 }
 
+// GetEvaluationDurations returns how long the last evaluation of each of the given rules took.
+// Rules that have not been evaluated since the start of Grafana are not included.
+func (st *Manager) GetEvaluationDurations(orgID int64, ruleUIDs ...string) map[string]time.Duration {
+	result := make(map[string]time.Duration, len(ruleUIDs))
+	for _, uid := range ruleUIDs {
+		for _, s := range st.cache.getStatesForRuleUID(orgID, uid) {
+			if s.EvaluationDuration > result[uid] {
+				result[uid] = s.EvaluationDuration
+			}
+		}
+	}
+	return result
+}
+
 func (st *Manager) recordMetrics() {
 	// TODO: parameterize?
 	// Setting to a reasonable default scrape interval for Prometheus.
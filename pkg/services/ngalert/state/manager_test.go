@@ -119,6 +119,10 @@ func TestProcessEvalResults(t *testing.T) {
 					Labels: data.Labels{
 						"__alert_rule_namespace_uid__": "test_namespace_uid",
 						"__alert_rule_uid__":           "test_alert_rule_uid",
+						"__namespace_uid__":            "test_namespace_uid",
+						"__org_id__":                   "1",
+						"__rule_group__":               "",
+						"__rule_uid__":                 "test_alert_rule_uid",
 						"alertname":                    "test_title",
 						"label":                        "test",
 						"instance_label":               "test",
@@ -173,6 +177,10 @@ func TestProcessEvalResults(t *testing.T) {
 					Labels: data.Labels{
 						"__alert_rule_namespace_uid__": "test_namespace_uid",
 						"__alert_rule_uid__":           "test_alert_rule_uid",
+						"__namespace_uid__":            "test_namespace_uid",
+						"__org_id__":                   "1",
+						"__rule_group__":               "",
+						"__rule_uid__":                 "test_alert_rule_uid",
 						"alertname":                    "test_title",
 						"label":                        "test",
 						"instance_label_1":             "test",
@@ -196,6 +204,10 @@ func TestProcessEvalResults(t *testing.T) {
 					Labels: data.Labels{
 						"__alert_rule_namespace_uid__": "test_namespace_uid",
 						"__alert_rule_uid__":           "test_alert_rule_uid",
+						"__namespace_uid__":            "test_namespace_uid",
+						"__org_id__":                   "1",
+						"__rule_group__":               "",
+						"__rule_uid__":                 "test_alert_rule_uid",
 						"alertname":                    "test_title",
 						"label":                        "test",
 						"instance_label_2":             "test",
@@ -253,6 +265,10 @@ func TestProcessEvalResults(t *testing.T) {
 					Labels: data.Labels{
 						"__alert_rule_namespace_uid__": "test_namespace_uid",
 						"__alert_rule_uid__":           "test_alert_rule_uid_1",
+						"__namespace_uid__":            "test_namespace_uid",
+						"__org_id__":                   "1",
+						"__rule_group__":               "",
+						"__rule_uid__":                 "test_alert_rule_uid_1",
 						"alertname":                    "test_title",
 						"label":                        "test",
 						"instance_label":               "test",
@@ -314,6 +330,10 @@ func TestProcessEvalResults(t *testing.T) {
 					Labels: data.Labels{
 						"__alert_rule_namespace_uid__": "test_namespace_uid",
 						"__alert_rule_uid__":           "test_alert_rule_uid_2",
+						"__namespace_uid__":            "test_namespace_uid",
+						"__org_id__":                   "1",
+						"__rule_group__":               "",
+						"__rule_uid__":                 "test_alert_rule_uid_2",
 						"alertname":                    "test_title",
 						"label":                        "test",
 						"instance_label":               "test",
@@ -386,6 +406,10 @@ func TestProcessEvalResults(t *testing.T) {
 					Labels: data.Labels{
 						"__alert_rule_namespace_uid__": "test_namespace_uid",
 						"__alert_rule_uid__":           "test_alert_rule_uid_2",
+						"__namespace_uid__":            "test_namespace_uid",
+						"__org_id__":                   "1",
+						"__rule_group__":               "",
+						"__rule_uid__":                 "test_alert_rule_uid_2",
 						"alertname":                    "test_title",
 						"label":                        "test",
 						"instance_label":               "test",
@@ -480,6 +504,10 @@ func TestProcessEvalResults(t *testing.T) {
 					Labels: data.Labels{
 						"__alert_rule_namespace_uid__": "test_namespace_uid",
 						"__alert_rule_uid__":           "test_alert_rule_uid_2",
+						"__namespace_uid__":            "test_namespace_uid",
+						"__org_id__":                   "1",
+						"__rule_group__":               "",
+						"__rule_uid__":                 "test_alert_rule_uid_2",
 						"alertname":                    "test_title",
 						"label":                        "test",
 						"instance_label":               "test",
@@ -561,6 +589,10 @@ func TestProcessEvalResults(t *testing.T) {
 					Labels: data.Labels{
 						"__alert_rule_namespace_uid__": "test_namespace_uid",
 						"__alert_rule_uid__":           "test_alert_rule_uid_2",
+						"__namespace_uid__":            "test_namespace_uid",
+						"__org_id__":                   "1",
+						"__rule_group__":               "",
+						"__rule_uid__":                 "test_alert_rule_uid_2",
 						"alertname":                    "test_title",
 						"label":                        "test",
 						"instance_label":               "test",
@@ -625,6 +657,10 @@ func TestProcessEvalResults(t *testing.T) {
 					Labels: data.Labels{
 						"__alert_rule_namespace_uid__": "test_namespace_uid",
 						"__alert_rule_uid__":           "test_alert_rule_uid_2",
+						"__namespace_uid__":            "test_namespace_uid",
+						"__org_id__":                   "1",
+						"__rule_group__":               "",
+						"__rule_uid__":                 "test_alert_rule_uid_2",
 						"alertname":                    "test_title",
 						"label":                        "test",
 						"instance_label":               "test",
@@ -689,6 +725,10 @@ func TestProcessEvalResults(t *testing.T) {
 					Labels: data.Labels{
 						"__alert_rule_namespace_uid__": "test_namespace_uid",
 						"__alert_rule_uid__":           "test_alert_rule_uid_2",
+						"__namespace_uid__":            "test_namespace_uid",
+						"__org_id__":                   "1",
+						"__rule_group__":               "",
+						"__rule_uid__":                 "test_alert_rule_uid_2",
 						"alertname":                    "test_title",
 						"label":                        "test",
 						"instance_label":               "test",
@@ -753,6 +793,10 @@ func TestProcessEvalResults(t *testing.T) {
 					Labels: data.Labels{
 						"__alert_rule_namespace_uid__": "test_namespace_uid",
 						"__alert_rule_uid__":           "test_alert_rule_uid_2",
+						"__namespace_uid__":            "test_namespace_uid",
+						"__org_id__":                   "1",
+						"__rule_group__":               "",
+						"__rule_uid__":                 "test_alert_rule_uid_2",
 						"alertname":                    "test_title",
 						"label":                        "test",
 						"instance_label":               "test",
@@ -771,11 +815,12 @@ func TestProcessEvalResults(t *testing.T) {
 							Values:          make(map[string]*float64),
 						},
 					},
-					StartsAt:           evaluationTime.Add(10 * time.Second),
-					EndsAt:             evaluationTime.Add(10 * time.Second).Add(state.ResendDelay * 3),
-					LastEvaluationTime: evaluationTime.Add(10 * time.Second),
-					EvaluationDuration: evaluationDuration,
-					Annotations:        map[string]string{"annotation": "test"},
+					StartsAt:            evaluationTime.Add(10 * time.Second),
+					EndsAt:              evaluationTime.Add(10 * time.Second).Add(state.ResendDelay * 3),
+					LastEvaluationTime:  evaluationTime.Add(10 * time.Second),
+					EvaluationDuration:  evaluationDuration,
+					Annotations:         map[string]string{"annotation": "test"},
+					MissingSeriesLabels: []map[string]string{{"instance_label": "test"}},
 				},
 			},
 		},
@@ -818,6 +863,10 @@ func TestProcessEvalResults(t *testing.T) {
 					Labels: data.Labels{
 						"__alert_rule_namespace_uid__": "test_namespace_uid",
 						"__alert_rule_uid__":           "test_alert_rule_uid_2",
+						"__namespace_uid__":            "test_namespace_uid",
+						"__org_id__":                   "1",
+						"__rule_group__":               "",
+						"__rule_uid__":                 "test_alert_rule_uid_2",
 						"alertname":                    "test_title",
 						"label":                        "test",
 						"instance_label":               "test",
@@ -882,6 +931,10 @@ func TestProcessEvalResults(t *testing.T) {
 					Labels: data.Labels{
 						"__alert_rule_namespace_uid__": "test_namespace_uid",
 						"__alert_rule_uid__":           "test_alert_rule_uid_2",
+						"__namespace_uid__":            "test_namespace_uid",
+						"__org_id__":                   "1",
+						"__rule_group__":               "",
+						"__rule_uid__":                 "test_alert_rule_uid_2",
 						"alertname":                    "test_title",
 						"label":                        "test",
 					},
@@ -946,6 +999,10 @@ func TestProcessEvalResults(t *testing.T) {
 					Labels: data.Labels{
 						"__alert_rule_namespace_uid__": "test_namespace_uid",
 						"__alert_rule_uid__":           "test_alert_rule_uid_2",
+						"__namespace_uid__":            "test_namespace_uid",
+						"__org_id__":                   "1",
+						"__rule_group__":               "",
+						"__rule_uid__":                 "test_alert_rule_uid_2",
 						"alertname":                    "test_title",
 						"label":                        "test",
 					},
@@ -1012,6 +1069,10 @@ func TestProcessEvalResults(t *testing.T) {
 					Labels: data.Labels{
 						"__alert_rule_namespace_uid__": "test_namespace_uid",
 						"__alert_rule_uid__":           "test_alert_rule_uid_2",
+						"__namespace_uid__":            "test_namespace_uid",
+						"__org_id__":                   "1",
+						"__rule_group__":               "",
+						"__rule_uid__":                 "test_alert_rule_uid_2",
 						"alertname":                    "test_title",
 						"label":                        "test",
 						"instance_label":               "test",
@@ -1071,6 +1132,10 @@ func TestProcessEvalResults(t *testing.T) {
 					Labels: data.Labels{
 						"__alert_rule_namespace_uid__": "test_namespace_uid",
 						"__alert_rule_uid__":           "test_alert_rule_uid_2",
+						"__namespace_uid__":            "test_namespace_uid",
+						"__org_id__":                   "1",
+						"__rule_group__":               "",
+						"__rule_uid__":                 "test_alert_rule_uid_2",
 						"alertname":                    "test_title",
 						"label":                        "test",
 						"instance_label":               "test",
@@ -1137,6 +1202,10 @@ func TestProcessEvalResults(t *testing.T) {
 					Labels: data.Labels{
 						"__alert_rule_namespace_uid__": "test_namespace_uid",
 						"__alert_rule_uid__":           "test_alert_rule_uid_2",
+						"__namespace_uid__":            "test_namespace_uid",
+						"__org_id__":                   "1",
+						"__rule_group__":               "",
+						"__rule_uid__":                 "test_alert_rule_uid_2",
 						"alertname":                    "test_title",
 						"label":                        "test",
 						"instance_label":               "test",
@@ -1156,11 +1225,12 @@ func TestProcessEvalResults(t *testing.T) {
 							Values:          make(map[string]*float64),
 						},
 					},
-					StartsAt:           evaluationTime.Add(10 * time.Second),
-					EndsAt:             evaluationTime.Add(10 * time.Second).Add(state.ResendDelay * 3),
-					LastEvaluationTime: evaluationTime.Add(10 * time.Second),
-					EvaluationDuration: evaluationDuration,
-					Annotations:        map[string]string{"annotation": "test"},
+					StartsAt:            evaluationTime.Add(10 * time.Second),
+					EndsAt:              evaluationTime.Add(10 * time.Second).Add(state.ResendDelay * 3),
+					LastEvaluationTime:  evaluationTime.Add(10 * time.Second),
+					EvaluationDuration:  evaluationDuration,
+					Annotations:         map[string]string{"annotation": "test"},
+					MissingSeriesLabels: []map[string]string{{"instance_label": "test"}},
 				},
 			},
 		},
@@ -1204,6 +1274,10 @@ func TestProcessEvalResults(t *testing.T) {
 					Labels: data.Labels{
 						"__alert_rule_namespace_uid__": "test_namespace_uid",
 						"__alert_rule_uid__":           "test_alert_rule_uid_2",
+						"__namespace_uid__":            "test_namespace_uid",
+						"__org_id__":                   "1",
+						"__rule_group__":               "",
+						"__rule_uid__":                 "test_alert_rule_uid_2",
 						"alertname":                    "test_title",
 						"label":                        "test",
 						"instance_label":               "test",
@@ -1294,6 +1368,10 @@ func TestProcessEvalResults(t *testing.T) {
 					Labels: data.Labels{
 						"__alert_rule_namespace_uid__": "test_namespace_uid",
 						"__alert_rule_uid__":           "test_alert_rule_uid_2",
+						"__namespace_uid__":            "test_namespace_uid",
+						"__org_id__":                   "1",
+						"__rule_group__":               "",
+						"__rule_uid__":                 "test_alert_rule_uid_2",
 						"alertname":                    "test_title",
 						"label":                        "test",
 						"instance_label":               "test",
@@ -1373,6 +1451,10 @@ func TestProcessEvalResults(t *testing.T) {
 					Labels: data.Labels{
 						"__alert_rule_namespace_uid__": "test_namespace_uid",
 						"__alert_rule_uid__":           "test_alert_rule_uid_2",
+						"__namespace_uid__":            "test_namespace_uid",
+						"__org_id__":                   "1",
+						"__rule_group__":               "",
+						"__rule_uid__":                 "test_alert_rule_uid_2",
 						"alertname":                    "test_title",
 						"label":                        "test",
 						"instance_label":               "test",
@@ -1452,6 +1534,10 @@ func TestProcessEvalResults(t *testing.T) {
 					Labels: data.Labels{
 						"__alert_rule_namespace_uid__": "test_namespace_uid",
 						"__alert_rule_uid__":           "test_alert_rule_uid_2",
+						"__namespace_uid__":            "test_namespace_uid",
+						"__org_id__":                   "1",
+						"__rule_group__":               "",
+						"__rule_uid__":                 "test_alert_rule_uid_2",
 						"alertname":                    "test_title",
 						"label":                        "test",
 						"instance_label":               "test",
@@ -1525,6 +1611,10 @@ func TestProcessEvalResults(t *testing.T) {
 					Labels: data.Labels{
 						"__alert_rule_namespace_uid__": "test_namespace_uid",
 						"__alert_rule_uid__":           "test_alert_rule_uid_2",
+						"__namespace_uid__":            "test_namespace_uid",
+						"__org_id__":                   "1",
+						"__rule_group__":               "",
+						"__rule_uid__":                 "test_alert_rule_uid_2",
 						"alertname":                    "test_title",
 						"label":                        "test",
 						"instance_label":               "test",
@@ -1608,6 +1698,10 @@ func TestProcessEvalResults(t *testing.T) {
 					Labels: data.Labels{
 						"__alert_rule_namespace_uid__": "test_namespace_uid",
 						"__alert_rule_uid__":           "test_alert_rule_uid_2",
+						"__namespace_uid__":            "test_namespace_uid",
+						"__org_id__":                   "1",
+						"__rule_group__":               "",
+						"__rule_uid__":                 "test_alert_rule_uid_2",
 						"alertname":                    "test_title",
 						"label":                        "test",
 						"instance_label":               "test",
@@ -1695,6 +1789,10 @@ func TestProcessEvalResults(t *testing.T) {
 					Labels: data.Labels{
 						"__alert_rule_namespace_uid__": "test_namespace_uid",
 						"__alert_rule_uid__":           "test_alert_rule_uid_2",
+						"__namespace_uid__":            "test_namespace_uid",
+						"__org_id__":                   "1",
+						"__rule_group__":               "",
+						"__rule_uid__":                 "test_alert_rule_uid_2",
 						"alertname":                    "test_title",
 						"label":                        "test",
 						"instance_label":               "test",
@@ -1754,6 +1852,10 @@ func TestProcessEvalResults(t *testing.T) {
 					Labels: data.Labels{
 						"__alert_rule_namespace_uid__": "test_namespace_uid",
 						"__alert_rule_uid__":           "test_alert_rule_uid",
+						"__namespace_uid__":            "test_namespace_uid",
+						"__org_id__":                   "1",
+						"__rule_group__":               "",
+						"__rule_uid__":                 "test_alert_rule_uid",
 						"alertname":                    "test_title",
 						"cluster":                      "us-central-1",
 						"namespace":                    "prod",
@@ -1775,6 +1877,58 @@ func TestProcessEvalResults(t *testing.T) {
 				},
 			},
 		},
+		{
+			desc: "the title template is rendered with the labels of the instance",
+			alertRule: &models.AlertRule{
+				OrgID:           1,
+				Title:           "test_title",
+				TitleTemplate:   "{{ .Labels.instance_label }} on {{ .Labels.missing }}{{ .Labels.label }}",
+				UID:             "test_alert_rule_uid",
+				NamespaceUID:    "test_namespace_uid",
+				Annotations:     map[string]string{"annotation": "test"},
+				Labels:          map[string]string{"label": "test"},
+				IntervalSeconds: 10,
+			},
+			evalResults: []eval.Results{
+				{
+					eval.Result{
+						Instance:           data.Labels{"instance_label": "instance"},
+						State:              eval.Normal,
+						EvaluatedAt:        evaluationTime,
+						EvaluationDuration: evaluationDuration,
+					},
+				},
+			},
+			expectedStates: map[string]*state.State{
+				`[["__alert_rule_namespace_uid__","test_namespace_uid"],["__alert_rule_uid__","test_alert_rule_uid"],["alertname","test_title"],["instance_label","instance"],["label","test"]]`: {
+					AlertRuleUID: "test_alert_rule_uid",
+					OrgID:        1,
+					CacheId:      `[["__alert_rule_namespace_uid__","test_namespace_uid"],["__alert_rule_uid__","test_alert_rule_uid"],["alertname","test_title"],["instance_label","instance"],["label","test"]]`,
+					Labels: data.Labels{
+						"__alert_rule_namespace_uid__": "test_namespace_uid",
+						"__alert_rule_uid__":           "test_alert_rule_uid",
+						"__namespace_uid__":            "test_namespace_uid",
+						"__org_id__":                   "1",
+						"__rule_group__":               "",
+						"__rule_uid__":                 "test_alert_rule_uid",
+						"alertname":                    "test_title",
+						"label":                        "test",
+						"instance_label":               "instance",
+					},
+					State: eval.Normal,
+					Results: []state.Evaluation{
+						{
+							EvaluationTime:  evaluationTime,
+							EvaluationState: eval.Normal,
+							Values:          make(map[string]*float64),
+						},
+					},
+					LastEvaluationTime: evaluationTime,
+					EvaluationDuration: evaluationDuration,
+					Annotations:        map[string]string{"annotation": "test", models.TitleAnnotation: "instance on test"},
+				},
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -1875,6 +2029,10 @@ func TestStaleResultsHandler(t *testing.T) {
 					Labels: data.Labels{
 						"__alert_rule_namespace_uid__": "namespace",
 						"__alert_rule_uid__":           rule.UID,
+						"__namespace_uid__":            "namespace",
+						"__org_id__":                   "1",
+						"__rule_group__":               rule.RuleGroup,
+						"__rule_uid__":                 rule.UID,
 						"alertname":                    rule.Title,
 						"test1":                        "testValue1",
 					},
@@ -1920,3 +2078,129 @@ func TestStaleResultsHandler(t *testing.T) {
 		assert.Equal(t, tc.finalStateCount, len(existingStatesForRule))
 	}
 }
+
+func TestProcessEvalResultsMissingSeries(t *testing.T) {
+	evaluationTime, err := time.Parse("2006-01-02", "2021-03-25")
+	require.NoError(t, err)
+
+	rule := &models.AlertRule{
+		OrgID:           1,
+		Title:           "test_title",
+		UID:             "test_alert_rule_uid",
+		NamespaceUID:    "test_namespace_uid",
+		IntervalSeconds: 10,
+		NoDataState:     models.Alerting,
+	}
+	st := state.NewManager(log.New("test_state_manager"), testMetrics.GetStateMetrics(), nil, nil, &store.FakeInstanceStore{}, mockstore.NewSQLStoreMock(), &dashboards.FakeDashboardService{}, &image.NotAvailableImageService{})
+	annotations.SetRepository(store.NewFakeAnnotationsRepo())
+
+	states := st.ProcessEvalResults(context.Background(), rule, eval.Results{
+		{Instance: data.Labels{"instance": "a"}, State: eval.Normal, EvaluatedAt: evaluationTime},
+		{Instance: data.Labels{"instance": "b"}, State: eval.Alerting, EvaluatedAt: evaluationTime},
+	})
+	require.Len(t, states, 2)
+	for _, s := range states {
+		require.Nil(t, s.MissingSeriesLabels)
+	}
+
+	states = st.ProcessEvalResults(context.Background(), rule, eval.Results{
+		{Instance: data.Labels{}, State: eval.NoData, EvaluatedAt: evaluationTime.Add(10 * time.Second)},
+	})
+	require.Len(t, states, 1)
+	require.Equal(t, eval.Alerting, states[0].State)
+	require.Equal(t, []map[string]string{{"instance": "a"}, {"instance": "b"}}, states[0].MissingSeriesLabels)
+
+	t.Run("missing series are cleared when the rule returns data again", func(t *testing.T) {
+		states := st.ProcessEvalResults(context.Background(), rule, eval.Results{
+			{Instance: data.Labels{"instance": "a"}, State: eval.Normal, EvaluatedAt: evaluationTime.Add(20 * time.Second)},
+		})
+		require.Len(t, states, 1)
+		require.Nil(t, states[0].MissingSeriesLabels)
+	})
+}
+
+func TestProcessEvalResultsMetaLabels(t *testing.T) {
+	evaluationTime, err := time.Parse("2006-01-02", "2021-03-25")
+	require.NoError(t, err)
+
+	rule := &models.AlertRule{
+		OrgID:           3,
+		Title:           "test_title",
+		UID:             "test_alert_rule_uid",
+		NamespaceUID:    "test_namespace_uid",
+		RuleGroup:       "test_group",
+		IntervalSeconds: 10,
+		Labels:          map[string]string{"label": "test", models.OrgIDMetaLabel: "rule"},
+	}
+	st := state.NewManager(log.New("test_state_manager"), testMetrics.GetStateMetrics(), nil, nil, &store.FakeInstanceStore{}, mockstore.NewSQLStoreMock(), &dashboards.FakeDashboardService{}, &image.NotAvailableImageService{})
+	annotations.SetRepository(store.NewFakeAnnotationsRepo())
+
+	states := st.ProcessEvalResults(context.Background(), rule, eval.Results{
+		{Instance: data.Labels{"instance": "a", models.RuleUIDMetaLabel: "instance"}, State: eval.Normal, EvaluatedAt: evaluationTime},
+	})
+	require.Len(t, states, 1)
+	labels := states[0].Labels
+	require.Equal(t, "3", labels[models.OrgIDMetaLabel])
+	require.Equal(t, "test_alert_rule_uid", labels[models.RuleUIDMetaLabel])
+	require.Equal(t, "test_group", labels[models.RuleGroupMetaLabel])
+	require.Equal(t, "test_namespace_uid", labels[models.NamespaceUIDMetaLabel])
+	require.Equal(t, "rule", labels["exported___org_id__"], "rule labels with the name of a meta-label should be kept with a prefix")
+	require.Equal(t, "instance", labels["exported___rule_uid__"], "instance labels with the name of a meta-label should be kept with a prefix")
+	require.Equal(t, "a", labels["instance"])
+	require.Equal(t, map[string]string{"label": "test", models.OrgIDMetaLabel: "rule"}, rule.Labels, "the labels of the rule should not be changed")
+}
+
+func TestProcessEvalResultsKeepsKeysOfWarmedInstances(t *testing.T) {
+	evaluationTime, err := time.Parse("2006-01-02", "2021-03-25")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	_, dbstore := tests.SetupTestEnv(t, 1)
+	rule := tests.CreateTestAlertRule(t, ctx, dbstore, 600, 1)
+
+	// the instance was saved before the meta-labels were added to the labels of alert instances.
+	saved := models.InstanceLabels{
+		models.RuleUIDLabel:       rule.UID,
+		models.NamespaceUIDLabel:  rule.NamespaceUID,
+		"alertname":               rule.Title,
+		"instance":                "a",
+		models.RuleGroupMetaLabel: "instance",
+	}
+	cacheID, err := saved.StringKey()
+	require.NoError(t, err)
+	require.NoError(t, dbstore.SaveAlertInstance(ctx, &models.SaveAlertInstanceCommand{
+		RuleOrgID:         rule.OrgID,
+		RuleUID:           rule.UID,
+		Labels:            saved,
+		State:             models.InstanceStateFiring,
+		LastEvalTime:      evaluationTime,
+		CurrentStateSince: evaluationTime,
+	}))
+
+	st := state.NewManager(log.New("test_state_manager"), testMetrics.GetStateMetrics(), nil, dbstore, dbstore, mockstore.NewSQLStoreMock(), &dashboards.FakeDashboardService{}, &image.NoopImageService{})
+	annotations.SetRepository(store.NewFakeAnnotationsRepo())
+	st.Warm(ctx)
+
+	states := st.ProcessEvalResults(ctx, rule, eval.Results{
+		{Instance: data.Labels{"instance": "a", models.RuleGroupMetaLabel: "instance"}, State: eval.Alerting, EvaluatedAt: evaluationTime.Add(time.Minute)},
+	})
+	require.Len(t, states, 1)
+	require.Equal(t, cacheID, states[0].CacheId)
+	require.Equal(t, eval.Alerting, states[0].State)
+	require.Equal(t, evaluationTime, states[0].StartsAt, "the warmed state should be continued")
+	require.Equal(t, rule.RuleGroup, states[0].Labels[models.RuleGroupMetaLabel])
+	require.Equal(t, "instance", states[0].Labels[models.ExportedLabelPrefix+models.RuleGroupMetaLabel])
+	require.Len(t, st.GetStatesForRuleUID(rule.OrgID, rule.UID), 1)
+
+	require.NoError(t, dbstore.SaveAlertInstance(ctx, &models.SaveAlertInstanceCommand{
+		RuleOrgID:    rule.OrgID,
+		RuleUID:      rule.UID,
+		Labels:       models.InstanceLabels(states[0].Labels),
+		State:        models.InstanceStateFiring,
+		LastEvalTime: states[0].LastEvaluationTime,
+	}))
+	q := &models.ListAlertInstancesQuery{RuleOrgID: rule.OrgID, RuleUID: rule.UID}
+	require.NoError(t, dbstore.ListAlertInstances(ctx, q))
+	require.Len(t, q.Result, 1, "the instance should be saved under its previous key")
+	require.Equal(t, rule.RuleGroup, q.Result[0].Labels[models.RuleGroupMetaLabel])
+}
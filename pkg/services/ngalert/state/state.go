@@ -34,6 +34,11 @@ type State struct {
 	Labels               data.Labels
 	Image                *models.Image
 	Error                error
+
+	// MissingSeriesLabels are the label sets returned by the last evaluation of the rule that
+	// returned data. It is only set if the last evaluation returned no data and the rule fires
+	// on no data.
+	MissingSeriesLabels []map[string]string
 }
 
 type Evaluation struct {
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	text_template "text/template"
+	"text/template/parse"
 
 	"github.com/grafana/grafana/pkg/services/ngalert/eval"
 	"github.com/prometheus/common/model"
@@ -31,9 +32,10 @@ func (v templateCaptureValue) String() string {
 	return strconv.FormatFloat(v.Value, 'f', -1, 64)
 }
 
+// templateVariables defines the variables that templates can use.
+const templateVariables = "{{- $labels := .Labels -}}{{- $values := .Values -}}{{- $value := .Value -}}"
+
 func expandTemplate(ctx context.Context, name, text string, labels map[string]string, alertInstance eval.Result, externalURL *url.URL) (result string, resultErr error) {
-	name = "__alert_" + name
-	text = "{{- $labels := .Labels -}}{{- $values := .Values -}}{{- $value := .Value -}}" + text
 	data := struct {
 		Labels map[string]string
 		Values map[string]templateCaptureValue
@@ -43,13 +45,89 @@ func expandTemplate(ctx context.Context, name, text string, labels map[string]st
 		Values: newTemplateCaptureValues(alertInstance.Values),
 		Value:  alertInstance.EvaluationString,
 	}
+	return newTemplateExpander(ctx, name, text, data, alertInstance.EvaluatedAt, externalURL).Expand()
+}
+
+// ValidateTemplate parses a label or annotation template the same way it is parsed when it is expanded.
+func ValidateTemplate(name, text string) error {
+	return newTemplateExpander(context.Background(), name, text, nil, time.Time{}, nil).ParseTest()
+}
 
+// TemplateValueRefIDs returns the RefIDs that a template reads from $values or .Values, in order
+// of their first use. Templates that cannot be parsed have no RefIDs.
+func TemplateValueRefIDs(text string) []string {
+	tree := parse.New("refids")
+	tree.Mode = parse.SkipFuncCheck
+	if _, err := tree.Parse(templateVariables+text, "", "", make(map[string]*parse.Tree)); err != nil {
+		return nil
+	}
+	var refIDs []string
+	seen := make(map[string]struct{})
+	add := func(ident []string, root string) {
+		if len(ident) < 2 || ident[0] != root {
+			return
+		}
+		if _, ok := seen[ident[1]]; !ok {
+			seen[ident[1]] = struct{}{}
+			refIDs = append(refIDs, ident[1])
+		}
+	}
+	var walk func(node parse.Node)
+	walk = func(node parse.Node) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, child := range n.Nodes {
+				walk(child)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe)
+		case *parse.IfNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.WithNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.TemplateNode:
+			walk(n.Pipe)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, cmd := range n.Cmds {
+				walk(cmd)
+			}
+		case *parse.CommandNode:
+			for _, arg := range n.Args {
+				walk(arg)
+			}
+		case *parse.ChainNode:
+			walk(n.Node)
+		case *parse.VariableNode:
+			add(n.Ident, "$values")
+		case *parse.FieldNode:
+			add(n.Ident, "Values")
+		}
+	}
+	walk(tree.Root)
+	return refIDs
+}
+
+func newTemplateExpander(ctx context.Context, name, text string, data interface{}, evaluatedAt time.Time, externalURL *url.URL) *template.Expander {
 	expander := template.NewTemplateExpander(
 		ctx, // This context is only used with the `query()` function - which we don't support yet.
-		text,
-		name,
+		templateVariables+text,
+		"__alert_"+name,
 		data,
-		model.Time(timestamp.FromTime(alertInstance.EvaluatedAt)),
+		model.Time(timestamp.FromTime(evaluatedAt)),
 		func(context.Context, string, time.Time) (promql.Vector, error) {
 			return nil, nil
 		},
@@ -66,8 +144,7 @@ func expandTemplate(ctx context.Context, name, text string, labels map[string]st
 			return ""
 		},
 	})
-
-	return expander.Expand()
+	return expander
 }
 
 func newTemplateCaptureValues(values map[string]eval.NumberValueCapture) map[string]templateCaptureValue {
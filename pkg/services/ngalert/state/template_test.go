@@ -415,3 +415,17 @@ func TestExpandTemplate(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateTemplate(t *testing.T) {
+	require.NoError(t, ValidateTemplate("test", `{{ $labels.instance }} is at {{ humanize $values.A.Value }}`))
+	require.Error(t, ValidateTemplate("test", `{{ $labels.instance `))
+	require.Error(t, ValidateTemplate("test", `{{ $unknown }}`))
+	require.Error(t, ValidateTemplate("test", `{{ unknownFunc }}`))
+}
+
+func TestTemplateValueRefIDs(t *testing.T) {
+	refIDs := TemplateValueRefIDs(`{{ $values.A }} {{ if gt $values.B.Value 1.0 }}{{ .Values.C }}{{ end }} {{ $values.A.Labels.instance }}`)
+	require.Equal(t, []string{"A", "B", "C"}, refIDs)
+	require.Empty(t, TemplateValueRefIDs(`{{ $labels.instance }} {{ $value }}`))
+	require.Empty(t, TemplateValueRefIDs(`{{ $values.A `))
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -12,16 +13,29 @@ import (
 	apimodels "github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
 	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
 	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/services/sqlstore/migrator"
 	"github.com/grafana/grafana/pkg/services/sqlstore/searchstore"
 	"github.com/grafana/grafana/pkg/util"
 )
 
+// sortColumns maps the fields alert rules can be sorted by to their columns.
+var sortColumns = map[ngmodels.SortField]string{
+	ngmodels.SortByTitle:     "title",
+	ngmodels.SortByInterval:  "interval_seconds",
+	ngmodels.SortByUpdatedAt: "updated",
+	ngmodels.SortByGroup:     "rule_group",
+	ngmodels.SortByNamespace: "namespace_uid",
+}
+
 // AlertRuleMaxTitleLength is the maximum length of the alert rule title
 const AlertRuleMaxTitleLength = 190
 
 // AlertRuleMaxRuleGroupNameLength is the maximum length of the alert rule group name
 const AlertRuleMaxRuleGroupNameLength = 190
 
+// AlertRuleMaxManagedByLength is the maximum length of the owner of an alert rule
+const AlertRuleMaxManagedByLength = 190
+
 type UpdateRuleGroupCmd struct {
 	OrgID           int64
 	NamespaceUID    string
@@ -31,10 +45,13 @@ type UpdateRuleGroupCmd struct {
 type UpdateRule struct {
 	Existing *ngmodels.AlertRule
 	New      ngmodels.AlertRule
+	// RestoredFrom is the version of the rule that New was restored from, if any.
+	RestoredFrom int64
 }
 
 var (
 	ErrAlertRuleGroupNotFound = errors.New("rulegroup not found")
+	ErrVersionNotFound        = errors.New("alert rule version not found")
 )
 
 // RuleStore is the interface for persisting alert rules and instances
@@ -42,28 +59,57 @@ type RuleStore interface {
 	DeleteAlertRulesByUID(ctx context.Context, orgID int64, ruleUID ...string) error
 	DeleteAlertInstancesByRuleUID(ctx context.Context, orgID int64, ruleUID string) error
 	GetAlertRuleByUID(ctx context.Context, query *ngmodels.GetAlertRuleByUIDQuery) error
+	// GetAlertRuleByTitle returns the alert rule of the folder with exactly the title. It returns
+	// ErrAlertRuleNotFound if the folder has no such rule.
+	GetAlertRuleByTitle(ctx context.Context, orgID int64, namespaceUID string, title string) (*ngmodels.AlertRule, error)
+	// GetAlertRuleVersion returns the given version of an alert rule from its history.
+	GetAlertRuleVersion(ctx context.Context, orgID int64, ruleUID string, version int64) (*ngmodels.AlertRuleVersion, error)
 	GetAlertRulesGroupByRuleUID(ctx context.Context, query *ngmodels.GetAlertRulesGroupByRuleUIDQuery) error
 	GetAlertRulesForScheduling(ctx context.Context, query *ngmodels.GetAlertRulesForSchedulingQuery) error
 	ListAlertRules(ctx context.Context, query *ngmodels.ListAlertRulesQuery) error
 	// GetRuleGroups returns the unique rule groups across all organizations.
 	GetRuleGroups(ctx context.Context, query *ngmodels.ListRuleGroupsQuery) error
+	// ListOrgRuleGroups returns the unique rule groups of an organization as pairs of rule group and namespace UID.
+	ListOrgRuleGroups(ctx context.Context, query *ngmodels.ListOrgRuleGroupsQuery) error
 	GetRuleGroupInterval(ctx context.Context, orgID int64, namespaceUID string, ruleGroup string) (int64, error)
 	// UpdateRuleGroup will update the interval for all rules in the group.
 	UpdateRuleGroup(ctx context.Context, orgID int64, namespaceUID string, ruleGroup string, interval int64) error
+	// SetRuleGroupInterval validates the interval and sets it on all rules in the group with a single update,
+	// incrementing their version. It returns ErrAlertRuleGroupNotFound if the group has no rules.
+	SetRuleGroupInterval(ctx context.Context, orgID int64, namespaceUID string, ruleGroup string, interval int64) error
+	// UpdateRuleGroupEvalStrategy will update the evaluation strategy for all rules in the group.
+	// It returns ErrAlertRuleGroupNotFound if the group has no rules.
+	UpdateRuleGroupEvalStrategy(ctx context.Context, orgID int64, namespaceUID string, ruleGroup string, strategy string) error
+	// UpdateRuleGroupLabels will replace the labels of the rule group for all rules in the group.
+	UpdateRuleGroupLabels(ctx context.Context, orgID int64, namespaceUID string, ruleGroup string, labels map[string]string) error
 	GetUserVisibleNamespaces(context.Context, int64, *models.SignedInUser) (map[string]*models.Folder, error)
 	GetNamespaceByTitle(context.Context, string, int64, *models.SignedInUser, bool) (*models.Folder, error)
 	// InsertAlertRules will insert all alert rules passed into the function
 	// and return the map of uuid to id.
 	InsertAlertRules(ctx context.Context, rule []ngmodels.AlertRule) (map[string]int64, error)
 	UpdateAlertRules(ctx context.Context, rule []UpdateRule) error
+	// Ping checks that the store is reachable.
+	Ping(ctx context.Context) error
+	// ListAmbiguousGroups returns the rule groups whose names only differ by case or surrounding whitespace.
+	ListAmbiguousGroups(ctx context.Context, orgID int64) ([]ngmodels.AmbiguousRuleGroup, error)
+	// GetAlertRulesByDataSource returns the alert rules of the organization that query the data source.
+	GetAlertRulesByDataSource(ctx context.Context, orgID int64, datasourceUID string) ([]*ngmodels.AlertRule, error)
+	// GetAlertRulesByOwner returns the alert rules of the organization that are managed by the owner.
+	GetAlertRulesByOwner(ctx context.Context, orgID int64, owner string) ([]*ngmodels.AlertRule, error)
+	// ListDuplicateAlertRuleUIDs returns the UIDs of alert rules that are used by more than one organization,
+	// with the IDs of these organizations.
+	ListDuplicateAlertRuleUIDs(ctx context.Context) (map[string][]int64, error)
 }
 
+// getAlertRuleByUID returns the alert rule of the organization. It returns ErrAlertRuleNotFound if the
+// organization has no rule with the UID, even if another organization has one. The conditions are
+// explicit, because xorm ignores the fields of a bean that have their zero value.
 func getAlertRuleByUID(sess *sqlstore.DBSession, alertRuleUID string, orgID int64) (*ngmodels.AlertRule, error) {
 	// we consider optionally enabling some caching
-	alertRule := ngmodels.AlertRule{OrgID: orgID, UID: alertRuleUID}
-	has, err := sess.Get(&alertRule)
+	var alertRule ngmodels.AlertRule
+	has, err := sess.Table("alert_rule").Where("org_id = ? AND uid = ?", orgID, alertRuleUID).Get(&alertRule)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to get alert rule %s: %w", alertRuleUID, err)
 	}
 	if !has {
 		return nil, ngmodels.ErrAlertRuleNotFound
@@ -92,7 +138,10 @@ func (st DBstore) DeleteAlertRulesByUID(ctx context.Context, orgID int64, ruleUI
 			return err
 		}
 		logger.Debug("deleted alert instances", "count", rows)
-		return nil
+		if err := deleteAlertRuleStatuses(sess, orgID, ruleUID...); err != nil {
+			return err
+		}
+		return deleteRuleDatasources(sess, orgID, ruleUID...)
 	})
 }
 
@@ -115,18 +164,43 @@ func (st DBstore) GetAlertRuleByUID(ctx context.Context, query *ngmodels.GetAler
 		if err != nil {
 			return err
 		}
+		alertRule.Status, err = getAlertRuleStatus(sess, query.OrgID, query.UID)
+		if err != nil {
+			return err
+		}
 		query.Result = alertRule
 		return nil
 	})
 }
 
+// GetAlertRuleByTitle returns the alert rule of the folder with exactly the title. The query uses the unique
+// index on org_id, namespace_uid and title. Its collation can ignore case, so the titles are compared again.
+func (st DBstore) GetAlertRuleByTitle(ctx context.Context, orgID int64, namespaceUID string, title string) (*ngmodels.AlertRule, error) {
+	var result *ngmodels.AlertRule
+	err := st.SQLStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		var rules []*ngmodels.AlertRule
+		err := sess.Table("alert_rule").Where("org_id = ? AND namespace_uid = ? AND title = ?", orgID, namespaceUID, title).Find(&rules)
+		if err != nil {
+			return fmt.Errorf("failed to get alert rule by title: %w", err)
+		}
+		for _, rule := range rules {
+			if rule.Title == title {
+				result = rule
+				return nil
+			}
+		}
+		return ngmodels.ErrAlertRuleNotFound
+	})
+	return result, err
+}
+
 // GetAlertRulesGroupByRuleUID is a handler for retrieving a group of alert rules from that database by UID and organisation ID of one of rules that belong to that group.
 func (st DBstore) GetAlertRulesGroupByRuleUID(ctx context.Context, query *ngmodels.GetAlertRulesGroupByRuleUIDQuery) error {
 	return st.SQLStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
 		var result []*ngmodels.AlertRule
 		err := sess.Table("alert_rule").Alias("A").Join(
 			"INNER",
-			"alert_rule AS B", "A.org_id = B.org_id AND A.namespace_uid = B.namespace_uid AND A.rule_group = B.rule_group AND B.uid = ?", query.UID,
+			"alert_rule AS B", "A.org_id = B.org_id AND A.namespace_uid = B.namespace_uid AND "+st.binaryEqual("A.rule_group", "B.rule_group")+" AND B.uid = ?", query.UID,
 		).Where("A.org_id = ?", query.OrgID).Select("A.*").Find(&result)
 		if err != nil {
 			return err
@@ -152,31 +226,32 @@ func (st DBstore) InsertAlertRules(ctx context.Context, rules []ngmodels.AlertRu
 				r.UID = uid
 			}
 			r.Version = 1
+			if r.EvalStrategy == "" {
+				strategy, err := st.getRuleGroupEvalStrategy(sess, r.OrgID, r.NamespaceUID, r.RuleGroup)
+				if err != nil {
+					return err
+				}
+				r.EvalStrategy = strategy
+			}
+			if r.GroupLabels == nil {
+				labels, err := st.getRuleGroupLabels(sess, r.OrgID, r.NamespaceUID, r.RuleGroup)
+				if err != nil {
+					return err
+				}
+				r.GroupLabels = labels
+			}
+			if r.EvalEveryN == 0 {
+				r.EvalEveryN = ngmodels.DefaultEvalEveryN
+			}
 			if err := st.validateAlertRule(r); err != nil {
 				return err
 			}
 			if err := (&r).PreSave(TimeNow); err != nil {
 				return err
 			}
+			r.TitleLower = strings.ToLower(r.Title)
 			newRules = append(newRules, r)
-			ruleVersions = append(ruleVersions, ngmodels.AlertRuleVersion{
-				RuleUID:          r.UID,
-				RuleOrgID:        r.OrgID,
-				RuleNamespaceUID: r.NamespaceUID,
-				RuleGroup:        r.RuleGroup,
-				ParentVersion:    0,
-				Version:          r.Version,
-				Created:          r.Updated,
-				Condition:        r.Condition,
-				Title:            r.Title,
-				Data:             r.Data,
-				IntervalSeconds:  r.IntervalSeconds,
-				NoDataState:      r.NoDataState,
-				ExecErrState:     r.ExecErrState,
-				For:              r.For,
-				Annotations:      r.Annotations,
-				Labels:           r.Labels,
-			})
+			ruleVersions = append(ruleVersions, newAlertRuleVersion(r, 0, 0))
 		}
 		if len(newRules) > 0 {
 			// we have to insert the rules one by one as otherwise we are
@@ -188,6 +263,9 @@ func (st DBstore) InsertAlertRules(ctx context.Context, rules []ngmodels.AlertRu
 					}
 					return fmt.Errorf("failed to create new rules: %w", err)
 				}
+				if err := saveRuleDatasources(sess, newRules[i].UID, newRules[i]); err != nil {
+					return err
+				}
 				ids[newRules[i].UID] = newRules[i].ID
 			}
 		}
@@ -209,12 +287,30 @@ func (st DBstore) UpdateAlertRules(ctx context.Context, rules []UpdateRule) erro
 			var parentVersion int64
 			r.New.ID = r.Existing.ID
 			r.New.Version = r.Existing.Version + 1
+			if r.New.EvalStrategy == "" {
+				r.New.EvalStrategy = r.Existing.EvalStrategy
+			}
+			if r.New.EvalStrategy == "" {
+				r.New.EvalStrategy = ngmodels.EvalStrategyIndependent
+			}
+			if r.New.GroupLabels == nil {
+				if r.New.NamespaceUID == r.Existing.NamespaceUID && r.New.RuleGroup == r.Existing.RuleGroup {
+					r.New.GroupLabels = r.Existing.GroupLabels
+				} else {
+					labels, err := st.getRuleGroupLabels(sess, r.New.OrgID, r.New.NamespaceUID, r.New.RuleGroup)
+					if err != nil {
+						return err
+					}
+					r.New.GroupLabels = labels
+				}
+			}
 			if err := st.validateAlertRule(r.New); err != nil {
 				return err
 			}
 			if err := (&r.New).PreSave(TimeNow); err != nil {
 				return err
 			}
+			r.New.TitleLower = strings.ToLower(r.New.Title)
 			// no way to update multiple rules at once
 			if _, err := sess.ID(r.Existing.ID).AllCols().Update(r.New); err != nil {
 				if st.SQLStore.Dialect.IsUniqueConstraintViolation(err) {
@@ -222,25 +318,11 @@ func (st DBstore) UpdateAlertRules(ctx context.Context, rules []UpdateRule) erro
 				}
 				return fmt.Errorf("failed to update rule [%s] %s: %w", r.New.UID, r.New.Title, err)
 			}
+			if err := saveRuleDatasources(sess, r.Existing.UID, r.New); err != nil {
+				return err
+			}
 			parentVersion = r.Existing.Version
-			ruleVersions = append(ruleVersions, ngmodels.AlertRuleVersion{
-				RuleOrgID:        r.New.OrgID,
-				RuleUID:          r.New.UID,
-				RuleNamespaceUID: r.New.NamespaceUID,
-				RuleGroup:        r.New.RuleGroup,
-				ParentVersion:    parentVersion,
-				Version:          r.New.Version,
-				Created:          r.New.Updated,
-				Condition:        r.New.Condition,
-				Title:            r.New.Title,
-				Data:             r.New.Data,
-				IntervalSeconds:  r.New.IntervalSeconds,
-				NoDataState:      r.New.NoDataState,
-				ExecErrState:     r.New.ExecErrState,
-				For:              r.New.For,
-				Annotations:      r.New.Annotations,
-				Labels:           r.New.Labels,
-			})
+			ruleVersions = append(ruleVersions, newAlertRuleVersion(r.New, parentVersion, r.RestoredFrom))
 		}
 		if len(ruleVersions) > 0 {
 			if _, err := sess.Insert(&ruleVersions); err != nil {
@@ -251,6 +333,58 @@ func (st DBstore) UpdateAlertRules(ctx context.Context, rules []UpdateRule) erro
 	})
 }
 
+// newAlertRuleVersion returns the version entry that records the given content of the rule.
+func newAlertRuleVersion(r ngmodels.AlertRule, parentVersion, restoredFrom int64) ngmodels.AlertRuleVersion {
+	return ngmodels.AlertRuleVersion{
+		RuleOrgID:            r.OrgID,
+		RuleUID:              r.UID,
+		RuleNamespaceUID:     r.NamespaceUID,
+		RuleGroup:            r.RuleGroup,
+		RuleGroupIndex:       r.RuleGroupIndex,
+		EvalOrder:            r.EvalOrder,
+		EvalOffset:           r.EvalOffsetSeconds,
+		EvalStrategy:         r.EvalStrategy,
+		ParentVersion:        parentVersion,
+		RestoredFrom:         restoredFrom,
+		Version:              r.Version,
+		Created:              r.Updated,
+		Condition:            r.Condition,
+		Title:                r.Title,
+		TitleTemplate:        r.TitleTemplate,
+		Data:                 r.Data,
+		IntervalSeconds:      r.IntervalSeconds,
+		NoDataState:          r.NoDataState,
+		ExecErrState:         r.ExecErrState,
+		For:                  r.For,
+		Annotations:          r.Annotations,
+		Labels:               r.Labels,
+		NotificationSettings: r.NotificationSettings,
+		ActiveWindow:         r.ActiveWindow,
+		ExpiresAt:            r.ExpiresAt,
+		EvalEveryN:           r.EvalEveryN,
+		WarmUpEvals:          r.WarmUpEvals,
+		GroupLabels:          r.GroupLabels,
+		ManagedBy:            r.ManagedBy,
+	}
+}
+
+func (st DBstore) GetAlertRuleVersion(ctx context.Context, orgID int64, ruleUID string, version int64) (*ngmodels.AlertRuleVersion, error) {
+	var result *ngmodels.AlertRuleVersion
+	err := st.SQLStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		ruleVersion := ngmodels.AlertRuleVersion{RuleOrgID: orgID, RuleUID: ruleUID, Version: version}
+		has, err := sess.Get(&ruleVersion)
+		if err != nil {
+			return err
+		}
+		if !has {
+			return ErrVersionNotFound
+		}
+		result = &ruleVersion
+		return nil
+	})
+	return result, err
+}
+
 // GetOrgAlertRules is a handler for retrieving alert rules of specific organisation.
 func (st DBstore) ListAlertRules(ctx context.Context, query *ngmodels.ListAlertRulesQuery) error {
 	return st.SQLStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
@@ -277,11 +411,51 @@ func (st DBstore) ListAlertRules(ctx context.Context, query *ngmodels.ListAlertR
 			q = q.Where(fmt.Sprintf("namespace_uid IN (%s)", strings.Join(in, ",")), args...)
 		}
 
+		if len(query.RuleUIDs) > 0 {
+			q = q.In("uid", query.RuleUIDs)
+		}
+
 		if query.RuleGroup != "" {
-			q = q.Where("rule_group = ?", query.RuleGroup)
+			q = q.Where(st.binaryEqual("rule_group", "?"), query.RuleGroup)
+			// the rules of a single group are listed in their order within the group
+			if len(query.SortBy) == 0 {
+				q = q.Asc("rule_group_idx")
+			}
+		}
+
+		if query.ExpiredAt != nil {
+			q = q.Where("expires_at IS NOT NULL AND expires_at <= ?", *query.ExpiredAt)
 		}
 
-		q = q.OrderBy("id ASC")
+		if query.TitleSearch != "" {
+			search := strings.ToLower(query.TitleSearch)
+			if query.TitlePrefix {
+				condition, pattern := st.titlePrefixCondition(search)
+				q = q.Where(condition, pattern)
+			} else {
+				q = q.Where(st.likeCondition("title_lower"), "%"+likeEscaper.Replace(search)+"%")
+			}
+			if len(query.SortBy) == 0 {
+				q = q.Asc("title_lower")
+			}
+		}
+
+		for _, field := range query.SortBy {
+			column, ok := sortColumns[field]
+			if !ok {
+				return fmt.Errorf("%w: '%s'", ngmodels.ErrInvalidSortField, field)
+			}
+			if query.SortDesc {
+				q = q.Desc(column)
+			} else {
+				q = q.Asc(column)
+			}
+		}
+		q = q.Asc("id")
+
+		if query.Limit > 0 {
+			q = q.Limit(query.Limit, query.Offset)
+		}
 
 		alertRules := make([]*ngmodels.AlertRule, 0)
 		if err := q.Find(&alertRules); err != nil {
@@ -295,41 +469,388 @@ func (st DBstore) ListAlertRules(ctx context.Context, query *ngmodels.ListAlertR
 
 func (st DBstore) GetRuleGroups(ctx context.Context, query *ngmodels.ListRuleGroupsQuery) error {
 	return st.SQLStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
-		ruleGroups := make([]string, 0)
-		if err := sess.Table("alert_rule").Distinct("rule_group").Find(&ruleGroups); err != nil {
+		// The names are deduplicated here, because DISTINCT would merge names that only
+		// differ by case in databases with case-insensitive collations.
+		var rows []string
+		if err := sess.Table("alert_rule").Cols("rule_group").Find(&rows); err != nil {
 			return err
 		}
+		seen := make(map[string]struct{}, len(rows))
+		ruleGroups := make([]string, 0)
+		for _, ruleGroup := range rows {
+			if _, ok := seen[ruleGroup]; ok {
+				continue
+			}
+			seen[ruleGroup] = struct{}{}
+			ruleGroups = append(ruleGroups, ruleGroup)
+		}
 		query.Result = ruleGroups
 		return nil
 	})
 }
 
+func (st DBstore) ListOrgRuleGroups(ctx context.Context, query *ngmodels.ListOrgRuleGroupsQuery) error {
+	return st.SQLStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		q := sess.Table("alert_rule").
+			Where("org_id = ?", query.OrgID)
+
+		if query.DashboardUID != "" {
+			q = q.Where("dashboard_uid = ?", query.DashboardUID)
+			if query.PanelID != 0 {
+				q = q.Where("panel_id = ?", query.PanelID)
+			}
+		}
+
+		if len(query.NamespaceUIDs) > 0 {
+			args := make([]interface{}, 0, len(query.NamespaceUIDs))
+			in := make([]string, 0, len(query.NamespaceUIDs))
+			for _, namespaceUID := range query.NamespaceUIDs {
+				args = append(args, namespaceUID)
+				in = append(in, "?")
+			}
+			q = q.Where(fmt.Sprintf("namespace_uid IN (%s)", strings.Join(in, ",")), args...)
+		}
+
+		var rows []struct {
+			RuleGroup    string `xorm:"rule_group"`
+			NamespaceUID string `xorm:"namespace_uid"`
+		}
+		// The groups are deduplicated and sorted here, because DISTINCT and ORDER BY would
+		// treat names that only differ by case as equal in databases with case-insensitive collations.
+		if err := q.Cols("rule_group", "namespace_uid").Find(&rows); err != nil {
+			return err
+		}
+
+		seen := make(map[[2]string]struct{}, len(rows))
+		result := make([][]string, 0, len(rows))
+		for _, row := range rows {
+			key := [2]string{row.RuleGroup, row.NamespaceUID}
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			result = append(result, []string{row.RuleGroup, row.NamespaceUID})
+		}
+		sort.Slice(result, func(i, j int) bool {
+			if result[i][1] != result[j][1] {
+				return result[i][1] < result[j][1]
+			}
+			return result[i][0] < result[j][0]
+		})
+		query.Result = result
+		return nil
+	})
+}
+
+// GetRuleGroupInterval returns the interval of a rule group by reading it from a single rule.
+// All rules of a group have the same interval. It returns ErrAlertRuleGroupNotFound if the group has no rules.
 func (st DBstore) GetRuleGroupInterval(ctx context.Context, orgID int64, namespaceUID string, ruleGroup string) (int64, error) {
 	var interval int64 = 0
 	return interval, st.SQLStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
-		ruleGroups := make([]ngmodels.AlertRule, 0)
-		err := sess.Find(
-			&ruleGroups,
-			ngmodels.AlertRule{OrgID: orgID, RuleGroup: ruleGroup, NamespaceUID: namespaceUID},
-		)
-		if len(ruleGroups) == 0 {
+		found, err := sess.Table("alert_rule").
+			Cols("interval_seconds").
+			Where("org_id = ? AND namespace_uid = ?", orgID, namespaceUID).
+			Where(st.binaryEqual("rule_group", "?"), ruleGroup).
+			Limit(1).
+			Get(&interval)
+		if err != nil {
+			return err
+		}
+		if !found {
 			return ErrAlertRuleGroupNotFound
 		}
-		interval = ruleGroups[0].IntervalSeconds
+		return nil
+	})
+}
+
+// ListNotificationSettings returns the notification settings of the alert rules of the organization that
+// override the notification policy tree, by rule UID.
+func (st DBstore) ListNotificationSettings(ctx context.Context, orgID int64) (map[string]ngmodels.NotificationSettings, error) {
+	var rules []ngmodels.AlertRule
+	err := st.SQLStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		return sess.Table("alert_rule").Cols("uid", "notification_settings").Where("org_id = ? AND notification_settings IS NOT NULL", orgID).Find(&rules)
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]ngmodels.NotificationSettings)
+	for _, rule := range rules {
+		if settings := rule.GetNotificationSettings(); settings != nil && settings.ReceiverName != "" {
+			result[rule.UID] = *settings
+		}
+	}
+	return result, nil
+}
+
+func (st DBstore) Ping(ctx context.Context) error {
+	return st.SQLStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		_, err := sess.Exec("SELECT 1")
 		return err
 	})
 }
 
+// ListDuplicateAlertRuleUIDs returns the UIDs of alert rules that are used by more than one organization,
+// with the IDs of these organizations in ascending order. UIDs are only unique within an organization.
+func (st DBstore) ListDuplicateAlertRuleUIDs(ctx context.Context) (map[string][]int64, error) {
+	result := make(map[string][]int64)
+	err := st.SQLStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		var rows []struct {
+			UID   string `xorm:"uid"`
+			OrgID int64  `xorm:"org_id"`
+		}
+		err := sess.SQL("SELECT uid, org_id FROM alert_rule WHERE uid IN " +
+			"(SELECT uid FROM alert_rule GROUP BY uid HAVING COUNT(*) > 1) ORDER BY uid, org_id").Find(&rows)
+		if err != nil {
+			return fmt.Errorf("failed to list duplicate alert rule UIDs: %w", err)
+		}
+		for _, row := range rows {
+			result[row.UID] = append(result[row.UID], row.OrgID)
+		}
+		return nil
+	})
+	return result, err
+}
+
 func (st DBstore) UpdateRuleGroup(ctx context.Context, orgID int64, namespaceUID string, ruleGroup string, interval int64) error {
 	return st.SQLStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
-		_, err := sess.Update(
-			ngmodels.AlertRule{IntervalSeconds: interval},
-			ngmodels.AlertRule{OrgID: orgID, RuleGroup: ruleGroup, NamespaceUID: namespaceUID},
-		)
+		_, err := sess.Table("alert_rule").
+			Where("org_id = ? AND namespace_uid = ?", orgID, namespaceUID).
+			Where(st.binaryEqual("rule_group", "?"), ruleGroup).
+			Update(ngmodels.AlertRule{IntervalSeconds: interval})
 		return err
 	})
 }
 
+// SetRuleGroupInterval changes the interval of all rules of the rule group with a single update, without
+// loading the rules. The version of the rules is incremented, so that the scheduler evaluates them with
+// the new interval, and recorded in their history. The provenance of the rules does not change.
+func (st DBstore) SetRuleGroupInterval(ctx context.Context, orgID int64, namespaceUID string, ruleGroup string, interval int64) error {
+	if interval%int64(st.BaseInterval.Seconds()) != 0 || interval <= 0 {
+		return fmt.Errorf("%w: interval (%v) should be non-zero and divided exactly by scheduler interval: %v", ngmodels.ErrAlertRuleFailedValidation, time.Duration(interval)*time.Second, st.BaseInterval)
+	}
+	return st.SQLStore.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		// the evaluation offset of every rule must stay shorter than its interval.
+		offsets, err := sess.Table("alert_rule").
+			Where("org_id = ? AND namespace_uid = ?", orgID, namespaceUID).
+			Where(st.binaryEqual("rule_group", "?"), ruleGroup).
+			Where("eval_offset >= ?", interval).
+			Count()
+		if err != nil {
+			return err
+		}
+		if offsets > 0 {
+			return fmt.Errorf("%w: the evaluation offset of %d rules of group %s is not shorter than the interval of %ds", ngmodels.ErrAlertRuleFailedValidation, offsets, ruleGroup, interval)
+		}
+		updated, err := sess.Table("alert_rule").
+			Where("org_id = ? AND namespace_uid = ?", orgID, namespaceUID).
+			Where(st.binaryEqual("rule_group", "?"), ruleGroup).
+			Cols("interval_seconds", "updated").
+			Incr("version").
+			Update(ngmodels.AlertRule{IntervalSeconds: interval, Updated: TimeNow()})
+		if err != nil {
+			return err
+		}
+		if updated == 0 {
+			return ErrAlertRuleGroupNotFound
+		}
+		return st.insertRuleGroupVersions(sess, orgID, namespaceUID, ruleGroup)
+	})
+}
+
+// ruleVersionColumns maps the columns of alert_rule_version to the columns of alert_rule they copy.
+var ruleVersionColumns = [][2]string{
+	{"rule_org_id", "org_id"},
+	{"rule_uid", "uid"},
+	{"rule_namespace_uid", "namespace_uid"},
+	{"rule_group", "rule_group"},
+	{"rule_group_idx", "rule_group_idx"},
+	{"eval_strategy", "eval_strategy"},
+	{"eval_order", "eval_order"},
+	{"eval_offset", "eval_offset"},
+	{"version", "version"},
+	{"created", "updated"},
+	{"title", "title"},
+	{"title_template", "title_template"},
+	{"condition", "condition"},
+	{"data", "data"},
+	{"interval_seconds", "interval_seconds"},
+	{"no_data_state", "no_data_state"},
+	{"exec_err_state", "exec_err_state"},
+	{"for", "for"},
+	{"annotations", "annotations"},
+	{"labels", "labels"},
+	{"notification_settings", "notification_settings"},
+	{"active_window", "active_window"},
+	{"expires_at", "expires_at"},
+	{"eval_every_n", "eval_every_n"},
+	{"warm_up_evals", "warm_up_evals"},
+	{"group_labels", "group_labels"},
+	{"managed_by", "managed_by"},
+}
+
+// insertRuleGroupVersions records the current content of every rule of the rule group as a new version
+// of the rule. Updates that change all rules of a group at once and increment their version call it in
+// the same session, so that the history of the rules does not miss the change. The versions are copied
+// by the database, without loading the rules.
+func (st DBstore) insertRuleGroupVersions(sess *sqlstore.DBSession, orgID int64, namespaceUID string, ruleGroup string) error {
+	quote := st.SQLStore.Dialect.Quote
+	columns := make([]string, 0, len(ruleVersionColumns)+2)
+	values := make([]string, 0, len(ruleVersionColumns)+2)
+	for _, column := range ruleVersionColumns {
+		columns = append(columns, quote(column[0]))
+		values = append(values, quote(column[1]))
+	}
+	columns = append(columns, quote("parent_version"), quote("restored_from"))
+	values = append(values, quote("version")+" - 1", "0")
+	sql := fmt.Sprintf("INSERT INTO alert_rule_version (%s) SELECT %s FROM alert_rule WHERE org_id = ? AND namespace_uid = ? AND %s",
+		strings.Join(columns, ", "), strings.Join(values, ", "), st.binaryEqual("rule_group", "?"))
+	if _, err := sess.Exec(sql, orgID, namespaceUID, ruleGroup); err != nil {
+		return fmt.Errorf("failed to create new rule versions: %w", err)
+	}
+	return nil
+}
+
+// UpdateRuleGroupEvalStrategy sets the evaluation strategy of the rule group on all its rules. The version
+// of the rules is incremented, so that the scheduler evaluates them with the new strategy, and recorded in
+// their history.
+func (st DBstore) UpdateRuleGroupEvalStrategy(ctx context.Context, orgID int64, namespaceUID string, ruleGroup string, strategy string) error {
+	return st.SQLStore.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		updated, err := sess.Table("alert_rule").
+			Where("org_id = ? AND namespace_uid = ?", orgID, namespaceUID).
+			Where(st.binaryEqual("rule_group", "?"), ruleGroup).
+			Cols("eval_strategy", "updated").
+			Incr("version").
+			Update(ngmodels.AlertRule{EvalStrategy: strategy, Updated: TimeNow()})
+		if err != nil {
+			return err
+		}
+		if updated == 0 {
+			return ErrAlertRuleGroupNotFound
+		}
+		return st.insertRuleGroupVersions(sess, orgID, namespaceUID, ruleGroup)
+	})
+}
+
+// UpdateRuleGroupLabels replaces the labels of the rule group on all its rules. The version of the rules
+// is incremented, so that the scheduler evaluates them with the new labels, and recorded in their history.
+func (st DBstore) UpdateRuleGroupLabels(ctx context.Context, orgID int64, namespaceUID string, ruleGroup string, labels map[string]string) error {
+	return st.SQLStore.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		updated, err := sess.Table("alert_rule").
+			Where("org_id = ? AND namespace_uid = ?", orgID, namespaceUID).
+			Where(st.binaryEqual("rule_group", "?"), ruleGroup).
+			Cols("group_labels", "updated").
+			Incr("version").
+			Update(ngmodels.AlertRule{GroupLabels: labels, Updated: TimeNow()})
+		if err != nil || updated == 0 {
+			return err
+		}
+		return st.insertRuleGroupVersions(sess, orgID, namespaceUID, ruleGroup)
+	})
+}
+
+// getRuleGroupLabels returns the labels of the rule group, or nil if the group has no rules or no labels.
+func (st DBstore) getRuleGroupLabels(sess *sqlstore.DBSession, orgID int64, namespaceUID string, ruleGroup string) (map[string]string, error) {
+	var rules []ngmodels.AlertRule
+	err := sess.Table("alert_rule").Cols("group_labels").
+		Where("org_id = ? AND namespace_uid = ?", orgID, namespaceUID).
+		Where(st.binaryEqual("rule_group", "?"), ruleGroup).
+		Limit(1).Find(&rules)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the labels of rule group %s: %w", ruleGroup, err)
+	}
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	return rules[0].GroupLabels, nil
+}
+
+// getRuleGroupEvalStrategy returns the evaluation strategy of the rules of the group,
+// or the independent strategy if the group has no rules yet.
+func (st DBstore) getRuleGroupEvalStrategy(sess *sqlstore.DBSession, orgID int64, namespaceUID string, ruleGroup string) (string, error) {
+	var strategies []string
+	err := sess.Table("alert_rule").Cols("eval_strategy").
+		Where("org_id = ? AND namespace_uid = ?", orgID, namespaceUID).
+		Where(st.binaryEqual("rule_group", "?"), ruleGroup).
+		Limit(1).Find(&strategies)
+	if err != nil {
+		return "", fmt.Errorf("failed to get the evaluation strategy of rule group %s: %w", ruleGroup, err)
+	}
+	if len(strategies) == 0 || strategies[0] == "" {
+		return ngmodels.EvalStrategyIndependent, nil
+	}
+	return strategies[0], nil
+}
+
+// ListAmbiguousGroups returns the rule groups of the organization whose names only differ by case
+// or surrounding whitespace from another group of the same namespace.
+func (st DBstore) ListAmbiguousGroups(ctx context.Context, orgID int64) ([]ngmodels.AmbiguousRuleGroup, error) {
+	query := &ngmodels.ListOrgRuleGroupsQuery{OrgID: orgID}
+	if err := st.ListOrgRuleGroups(ctx, query); err != nil {
+		return nil, err
+	}
+	return ambiguousRuleGroups(query.Result), nil
+}
+
+// ambiguousRuleGroups finds the ambiguous groups among pairs of rule group and namespace UID
+// sorted by namespace UID.
+func ambiguousRuleGroups(groups [][]string) []ngmodels.AmbiguousRuleGroup {
+	result := make([]ngmodels.AmbiguousRuleGroup, 0)
+	byName := make(map[[2]string]int)
+	for _, group := range groups {
+		ruleGroup, namespaceUID := group[0], group[1]
+		key := [2]string{namespaceUID, ngmodels.NormalizeRuleGroupName(ruleGroup)}
+		idx, ok := byName[key]
+		if !ok {
+			byName[key] = len(result)
+			result = append(result, ngmodels.AmbiguousRuleGroup{NamespaceUID: namespaceUID, RuleGroups: []string{ruleGroup}})
+			continue
+		}
+		result[idx].RuleGroups = append(result[idx].RuleGroups, ruleGroup)
+	}
+	ambiguous := result[:0]
+	for _, group := range result {
+		if len(group.RuleGroups) > 1 {
+			ambiguous = append(ambiguous, group)
+		}
+	}
+	return ambiguous
+}
+
+// binaryEqual returns a condition that compares two expressions byte by byte. MySQL compares
+// strings case-insensitively in its default collation, which would merge rule groups whose names
+// only differ by case.
+func (st DBstore) binaryEqual(left, right string) string {
+	if st.SQLStore.Dialect.DriverName() == migrator.MySQL {
+		return fmt.Sprintf("BINARY %s = %s", left, right)
+	}
+	return fmt.Sprintf("%s = %s", left, right)
+}
+
+// titlePrefixCondition returns a condition that matches the rules whose lowercased title starts with
+// the prefix and that can use the index on title_lower. SQLite only uses indexes for LIKE if they
+// ignore case, so GLOB is used there instead.
+func (st DBstore) titlePrefixCondition(prefix string) (string, string) {
+	if st.SQLStore.Dialect.DriverName() == migrator.SQLite {
+		return "title_lower GLOB ?", globEscaper.Replace(prefix) + "*"
+	}
+	return st.likeCondition("title_lower"), likeEscaper.Replace(prefix) + "%"
+}
+
+// likeCondition returns a LIKE condition on the column whose pattern escapes wildcards with a backslash.
+func (st DBstore) likeCondition(column string) string {
+	// MySQL also treats the backslash as escape character in string literals.
+	if st.SQLStore.Dialect.DriverName() == migrator.MySQL {
+		return column + ` LIKE ? ESCAPE '\\'`
+	}
+	return column + ` LIKE ? ESCAPE '\'`
+}
+
+// globEscaper escapes the wildcards of GLOB patterns.
+var globEscaper = strings.NewReplacer("*", "[*]", "?", "[?]", "[", "[[]")
+
+// likeEscaper escapes the wildcards of LIKE patterns, and the backslash that escapes them.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
 // GetNamespaces returns the folders that are visible to the user and have at least one alert in it
 func (st DBstore) GetUserVisibleNamespaces(ctx context.Context, orgID int64, user *models.SignedInUser) (map[string]*models.Folder, error) {
 	namespaceMap := make(map[string]*models.Folder)
@@ -449,6 +970,10 @@ func (st DBstore) validateAlertRule(alertRule ngmodels.AlertRule) error {
 		return fmt.Errorf("%w: interval (%v) should be non-zero and divided exactly by scheduler interval: %v", ngmodels.ErrAlertRuleFailedValidation, time.Duration(alertRule.IntervalSeconds)*time.Second, st.BaseInterval)
 	}
 
+	if alertRule.EvalOffsetSeconds < 0 || alertRule.EvalOffsetSeconds%int64(st.BaseInterval.Seconds()) != 0 {
+		return fmt.Errorf("%w: evaluation offset (%v) should not be negative and divided exactly by scheduler interval: %v", ngmodels.ErrAlertRuleFailedValidation, time.Duration(alertRule.EvalOffsetSeconds)*time.Second, st.BaseInterval)
+	}
+
 	// enfore max name length in SQLite
 	if len(alertRule.Title) > AlertRuleMaxTitleLength {
 		return fmt.Errorf("%w: name length should not be greater than %d", ngmodels.ErrAlertRuleFailedValidation, AlertRuleMaxTitleLength)
@@ -459,6 +984,18 @@ func (st DBstore) validateAlertRule(alertRule ngmodels.AlertRule) error {
 		return fmt.Errorf("%w: rule group name length should not be greater than %d", ngmodels.ErrAlertRuleFailedValidation, AlertRuleMaxRuleGroupNameLength)
 	}
 
+	if alertRule.EvalEveryN < 1 {
+		return fmt.Errorf("%w: evaluation sampling should be at least 1", ngmodels.ErrAlertRuleFailedValidation)
+	}
+
+	if alertRule.WarmUpEvals < 0 {
+		return fmt.Errorf("%w: warm-up evaluations should not be negative", ngmodels.ErrAlertRuleFailedValidation)
+	}
+
+	if len(alertRule.ManagedBy) > AlertRuleMaxManagedByLength {
+		return fmt.Errorf("%w: owner length should not be greater than %d", ngmodels.ErrAlertRuleFailedValidation, AlertRuleMaxManagedByLength)
+	}
+
 	if alertRule.OrgID == 0 {
 		return fmt.Errorf("%w: no organisation is found", ngmodels.ErrAlertRuleFailedValidation)
 	}
@@ -475,5 +1012,19 @@ func (st DBstore) validateAlertRule(alertRule ngmodels.AlertRule) error {
 		return err
 	}
 
+	if err := ngmodels.ValidateEvalStrategy(alertRule.EvalStrategy); err != nil {
+		return fmt.Errorf("%w: %s", ngmodels.ErrAlertRuleFailedValidation, err)
+	}
+
+	if alertRule.TitleTemplate != "" {
+		if err := ngmodels.ValidateTitleTemplate(alertRule.TitleTemplate); err != nil {
+			return err
+		}
+	}
+
+	if err := alertRule.ActiveWindow.Validate(); err != nil {
+		return err
+	}
+
 	return nil
 }
@@ -0,0 +1,51 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+)
+
+// GetAlertRulesByDataSource returns the alert rules of the organization that query the data source.
+func (st DBstore) GetAlertRulesByDataSource(ctx context.Context, orgID int64, datasourceUID string) ([]*ngmodels.AlertRule, error) {
+	var result []*ngmodels.AlertRule
+	err := st.SQLStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		return sess.Table("alert_rule").Alias("R").Join(
+			"INNER",
+			"alert_rule_datasources AS D", "R.org_id = D.org_id AND R.uid = D.rule_uid",
+		).Where("D.org_id = ? AND D.datasource_uid = ?", orgID, datasourceUID).Asc("R.id").Select("R.*").Find(&result)
+	})
+	return result, err
+}
+
+// GetAlertRulesByOwner returns the alert rules of the organization that are managed by the owner.
+func (st DBstore) GetAlertRulesByOwner(ctx context.Context, orgID int64, owner string) ([]*ngmodels.AlertRule, error) {
+	var result []*ngmodels.AlertRule
+	err := st.SQLStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		return sess.Table("alert_rule").Where("org_id = ? AND managed_by = ?", orgID, owner).Asc("id").Find(&result)
+	})
+	return result, err
+}
+
+// saveRuleDatasources replaces the links of the rule with previousUID to the data sources it queried
+// with links of the rule to the data sources it queries now.
+func saveRuleDatasources(sess *sqlstore.DBSession, previousUID string, rule ngmodels.AlertRule) error {
+	if err := deleteRuleDatasources(sess, rule.OrgID, previousUID); err != nil {
+		return err
+	}
+	for _, uid := range ngmodels.QueriedDatasourceUIDs(rule.Data) {
+		if _, err := sess.Insert(&ngmodels.AlertRuleDatasource{OrgID: rule.OrgID, RuleUID: rule.UID, DatasourceUID: uid}); err != nil {
+			return fmt.Errorf("failed to link rule %s to data source %s: %w", rule.UID, uid, err)
+		}
+	}
+	return nil
+}
+
+func deleteRuleDatasources(sess *sqlstore.DBSession, orgID int64, ruleUID ...string) error {
+	if _, err := sess.Where("org_id = ?", orgID).In("rule_uid", ruleUID).Delete(&ngmodels.AlertRuleDatasource{}); err != nil {
+		return fmt.Errorf("failed to delete data source links of rules: %w", err)
+	}
+	return nil
+}
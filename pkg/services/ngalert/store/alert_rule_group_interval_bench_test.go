@@ -0,0 +1,53 @@
+package store_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/store"
+	"github.com/grafana/grafana/pkg/services/ngalert/tests"
+)
+
+// BenchmarkSetRuleGroupInterval compares changing the interval of a large rule group with a single
+// update to loading, changing and saving every rule of the group.
+func BenchmarkSetRuleGroupInterval(b *testing.B) {
+	if testing.Short() {
+		b.Skip("skipping benchmark with 500 rules in short mode")
+	}
+	const ruleCount = 500
+	_, dbstore := tests.SetupTestEnv(b, baseIntervalSeconds)
+	titles := make([]string, 0, ruleCount)
+	for i := 0; i < ruleCount; i++ {
+		titles = append(titles, fmt.Sprintf("rule-%03d", i))
+	}
+	insertSearchRules(b, *dbstore, 1, titles...)
+
+	b.Run("load and save", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			interval := int64((i%2 + 1) * baseIntervalSeconds)
+			query := &models.ListAlertRulesQuery{OrgID: 1, NamespaceUIDs: []string{"folder"}, RuleGroup: "group"}
+			if err := dbstore.ListAlertRules(context.Background(), query); err != nil {
+				b.Fatal(err)
+			}
+			updates := make([]store.UpdateRule, 0, len(query.Result))
+			for _, rule := range query.Result {
+				updated := *rule
+				updated.IntervalSeconds = interval
+				updates = append(updates, store.UpdateRule{Existing: rule, New: updated})
+			}
+			if err := dbstore.UpdateAlertRules(context.Background(), updates); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("bulk update", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			interval := int64((i%2 + 1) * baseIntervalSeconds)
+			if err := dbstore.SetRuleGroupInterval(context.Background(), 1, "folder", "group", interval); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package store_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/store"
+	"github.com/grafana/grafana/pkg/services/ngalert/tests"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntegrationSetRuleGroupInterval(t *testing.T) {
+	_, dbstore := tests.SetupTestEnv(t, baseIntervalSeconds)
+	insertSearchRules(t, *dbstore, 1, "rule-1", "rule-2")
+	insertSearchRules(t, *dbstore, 2, "other org")
+
+	listRules := func(t *testing.T, orgID int64) []*models.AlertRule {
+		t.Helper()
+		query := &models.ListAlertRulesQuery{OrgID: orgID}
+		require.NoError(t, dbstore.ListAlertRules(context.Background(), query))
+		return query.Result
+	}
+
+	t.Run("should set the interval and increment the version of all rules of the group", func(t *testing.T) {
+		require.NoError(t, dbstore.SetRuleGroupInterval(context.Background(), 1, "folder", "group", 6*baseIntervalSeconds))
+		rules := listRules(t, 1)
+		require.Len(t, rules, 2)
+		for _, rule := range rules {
+			require.Equal(t, int64(6*baseIntervalSeconds), rule.IntervalSeconds)
+			require.Equal(t, int64(2), rule.Version)
+		}
+		other := listRules(t, 2)
+		require.Equal(t, int64(baseIntervalSeconds), other[0].IntervalSeconds)
+		require.Equal(t, int64(1), other[0].Version)
+	})
+	t.Run("should record the new version of every rule in its history", func(t *testing.T) {
+		for _, rule := range listRules(t, 1) {
+			version, err := dbstore.GetAlertRuleVersion(context.Background(), 1, rule.UID, rule.Version)
+			require.NoError(t, err)
+			require.Equal(t, int64(6*baseIntervalSeconds), version.IntervalSeconds)
+			require.Equal(t, rule.Version-1, version.ParentVersion)
+			require.Equal(t, rule.Title, version.Title)
+			require.Equal(t, rule.Data, version.Data)
+			require.Equal(t, rule.EvalEveryN, version.EvalEveryN)
+
+			previous, err := dbstore.GetAlertRuleVersion(context.Background(), 1, rule.UID, rule.Version-1)
+			require.NoError(t, err)
+			require.Equal(t, int64(baseIntervalSeconds), previous.IntervalSeconds)
+		}
+		_, err := dbstore.GetAlertRuleVersion(context.Background(), 2, listRules(t, 2)[0].UID, 2)
+		require.ErrorIs(t, err, store.ErrVersionNotFound)
+	})
+	t.Run("should reject intervals that are not a multiple of the base interval", func(t *testing.T) {
+		err := dbstore.SetRuleGroupInterval(context.Background(), 1, "folder", "group", baseIntervalSeconds+1)
+		require.ErrorIs(t, err, models.ErrAlertRuleFailedValidation)
+		err = dbstore.SetRuleGroupInterval(context.Background(), 1, "folder", "group", 0)
+		require.ErrorIs(t, err, models.ErrAlertRuleFailedValidation)
+	})
+	t.Run("should reject intervals that are not longer than the evaluation offset of a rule", func(t *testing.T) {
+		rules := listRules(t, 1)
+		updated := *rules[0]
+		updated.EvalOffsetSeconds = 2 * baseIntervalSeconds
+		require.NoError(t, dbstore.UpdateAlertRules(context.Background(), []store.UpdateRule{{Existing: rules[0], New: updated}}))
+
+		err := dbstore.SetRuleGroupInterval(context.Background(), 1, "folder", "group", 2*baseIntervalSeconds)
+		require.ErrorIs(t, err, models.ErrAlertRuleFailedValidation)
+		for _, rule := range listRules(t, 1) {
+			require.Equal(t, int64(6*baseIntervalSeconds), rule.IntervalSeconds)
+		}
+	})
+	t.Run("should return ErrAlertRuleGroupNotFound for groups without rules", func(t *testing.T) {
+		err := dbstore.SetRuleGroupInterval(context.Background(), 1, "folder", "missing", baseIntervalSeconds)
+		require.ErrorIs(t, err, store.ErrAlertRuleGroupNotFound)
+	})
+}
@@ -0,0 +1,49 @@
+package store_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/tests"
+)
+
+// BenchmarkAlertRuleTitleSearch compares searches for the start of titles, which use the index on
+// title_lower, with searches anywhere in titles, which scan all rules of the organization. It runs
+// against SQLite by default and against PostgreSQL with GRAFANA_TEST_DB=postgres.
+func BenchmarkAlertRuleTitleSearch(b *testing.B) {
+	if testing.Short() {
+		b.Skip("skipping benchmark with 50000 rules in short mode")
+	}
+	const ruleCount = 50000
+	_, dbstore := tests.SetupTestEnv(b, baseIntervalSeconds)
+	titles := make([]string, 0, ruleCount)
+	for i := 0; i < ruleCount; i++ {
+		titles = append(titles, fmt.Sprintf("service-%05d latency", i))
+	}
+	for start := 0; start < ruleCount; start += 1000 {
+		insertSearchRules(b, *dbstore, 1, titles[start:start+1000]...)
+	}
+
+	for _, bench := range []struct {
+		name  string
+		query models.ListAlertRulesQuery
+	}{
+		{name: "prefix", query: models.ListAlertRulesQuery{TitleSearch: "service-0420", TitlePrefix: true}},
+		{name: "contains", query: models.ListAlertRulesQuery{TitleSearch: "service-0420"}},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				query := bench.query
+				query.OrgID = 1
+				if err := dbstore.ListAlertRules(context.Background(), &query); err != nil {
+					b.Fatal(err)
+				}
+				if len(query.Result) != 10 {
+					b.Fatalf("expected 10 rules, got %d", len(query.Result))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package store_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/store"
+	"github.com/grafana/grafana/pkg/services/ngalert/tests"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntegrationAlertRuleTitleSearch(t *testing.T) {
+	_, dbstore := tests.SetupTestEnv(t, baseIntervalSeconds)
+	insertSearchRules(t, *dbstore, 1, "Service-A latency", "service-a errors", "Disk usage of service-a", "service-b errors")
+	insertSearchRules(t, *dbstore, 2, "service-a other org")
+
+	search := func(t *testing.T, query *models.ListAlertRulesQuery) []string {
+		t.Helper()
+		query.OrgID = 1
+		require.NoError(t, dbstore.ListAlertRules(context.Background(), query))
+		titles := make([]string, 0, len(query.Result))
+		for _, rule := range query.Result {
+			titles = append(titles, rule.Title)
+		}
+		return titles
+	}
+
+	t.Run("should match titles that contain the search ignoring case", func(t *testing.T) {
+		titles := search(t, &models.ListAlertRulesQuery{TitleSearch: "SERVICE-A"})
+		require.Equal(t, []string{"Disk usage of service-a", "service-a errors", "Service-A latency"}, titles)
+	})
+	t.Run("should only match the start of titles with prefix searches", func(t *testing.T) {
+		titles := search(t, &models.ListAlertRulesQuery{TitleSearch: "service-a", TitlePrefix: true})
+		require.Equal(t, []string{"service-a errors", "Service-A latency"}, titles)
+	})
+	t.Run("should page through the matches", func(t *testing.T) {
+		titles := search(t, &models.ListAlertRulesQuery{TitleSearch: "service", Limit: 2, Offset: 1})
+		require.Equal(t, []string{"service-a errors", "Service-A latency"}, titles)
+	})
+	t.Run("should match renamed rules by their new title", func(t *testing.T) {
+		query := &models.ListAlertRulesQuery{TitleSearch: "service-b"}
+		search(t, query)
+		require.Len(t, query.Result, 1)
+		renamed := *query.Result[0]
+		renamed.Title = "Service-C errors"
+		require.NoError(t, dbstore.UpdateAlertRules(context.Background(), []store.UpdateRule{{Existing: query.Result[0], New: renamed}}))
+		require.Empty(t, search(t, &models.ListAlertRulesQuery{TitleSearch: "service-b"}))
+		require.Equal(t, []string{"Service-C errors"}, search(t, &models.ListAlertRulesQuery{TitleSearch: "service-c", TitlePrefix: true}))
+	})
+	t.Run("should match wildcards of the search literally", func(t *testing.T) {
+		insertSearchRules(t, *dbstore, 3, "100% cpu", "100 percent cpu", "disk_usage", "diskxusage", `back\slash`, "backxslash")
+		search := func(t *testing.T, query *models.ListAlertRulesQuery) []string {
+			t.Helper()
+			query.OrgID = 3
+			require.NoError(t, dbstore.ListAlertRules(context.Background(), query))
+			titles := make([]string, 0, len(query.Result))
+			for _, rule := range query.Result {
+				titles = append(titles, rule.Title)
+			}
+			return titles
+		}
+		for _, prefix := range []bool{false, true} {
+			require.Equal(t, []string{"100% cpu"}, search(t, &models.ListAlertRulesQuery{TitleSearch: "100%", TitlePrefix: prefix}))
+			require.Equal(t, []string{"disk_usage"}, search(t, &models.ListAlertRulesQuery{TitleSearch: "disk_", TitlePrefix: prefix}))
+			require.Equal(t, []string{`back\slash`}, search(t, &models.ListAlertRulesQuery{TitleSearch: `back\s`, TitlePrefix: prefix}))
+		}
+	})
+}
+
+func insertSearchRules(t testing.TB, dbstore store.DBstore, orgID int64, titles ...string) {
+	t.Helper()
+	rules := make([]models.AlertRule, 0, len(titles))
+	for _, title := range titles {
+		rules = append(rules, models.AlertRule{
+			OrgID:           orgID,
+			Title:           title,
+			Condition:       "A",
+			IntervalSeconds: baseIntervalSeconds,
+			NamespaceUID:    "folder",
+			RuleGroup:       "group",
+			NoDataState:     models.NoData,
+			ExecErrState:    models.AlertingErrState,
+			Data: []models.AlertQuery{{
+				RefID:             "A",
+				DatasourceUID:     "-100",
+				Model:             json.RawMessage(`{"type": "math", "expression": "2 + 3 > 1"}`),
+				RelativeTimeRange: models.RelativeTimeRange{From: models.Duration(5 * time.Hour)},
+			}},
+		})
+	}
+	if _, err := dbstore.InsertAlertRules(context.Background(), rules); err != nil {
+		t.Fatalf("failed to insert rules: %s", err)
+	}
+}
@@ -0,0 +1,73 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+)
+
+// RuleStatusStore stores the status of alert rules after their evaluation.
+type RuleStatusStore interface {
+	SaveAlertRuleStatus(ctx context.Context, status ngmodels.AlertRuleStatusEntry) error
+}
+
+// SaveAlertRuleStatus replaces the stored status of the rule with the given one.
+func (st DBstore) SaveAlertRuleStatus(ctx context.Context, status ngmodels.AlertRuleStatusEntry) error {
+	return st.SQLStore.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		rows, err := sess.Where("org_id = ? AND rule_uid = ?", status.OrgID, status.RuleUID).
+			Cols("status", "error", "last_evaluation").Update(&status)
+		if err != nil {
+			return fmt.Errorf("failed to update status of rule %s: %w", status.RuleUID, err)
+		}
+		if rows > 0 {
+			return nil
+		}
+		status.ID = 0
+		if _, err := sess.Insert(&status); err != nil {
+			return fmt.Errorf("failed to insert status of rule %s: %w", status.RuleUID, err)
+		}
+		return nil
+	})
+}
+
+// GetAlertRuleStatuses returns the stored statuses of the rules of the organization by rule UID.
+// Rules that were not evaluated yet are not part of the result.
+func (st DBstore) GetAlertRuleStatuses(ctx context.Context, orgID int64, ruleUIDs ...string) (map[string]ngmodels.AlertRuleStatusEntry, error) {
+	var rows []ngmodels.AlertRuleStatusEntry
+	err := st.SQLStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		q := sess.Where("org_id = ?", orgID)
+		if len(ruleUIDs) > 0 {
+			q = q.In("rule_uid", ruleUIDs)
+		}
+		return q.Find(&rows)
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]ngmodels.AlertRuleStatusEntry, len(rows))
+	for _, row := range rows {
+		result[row.RuleUID] = row
+	}
+	return result, nil
+}
+
+func getAlertRuleStatus(sess *sqlstore.DBSession, orgID int64, ruleUID string) (ngmodels.AlertRuleStatus, error) {
+	status := ngmodels.AlertRuleStatusEntry{}
+	has, err := sess.Where("org_id = ? AND rule_uid = ?", orgID, ruleUID).Get(&status)
+	if err != nil {
+		return "", fmt.Errorf("failed to get the status of alert rule %s: %w", ruleUID, err)
+	}
+	if !has {
+		return "", nil
+	}
+	return status.Status, nil
+}
+
+func deleteAlertRuleStatuses(sess *sqlstore.DBSession, orgID int64, ruleUID ...string) error {
+	if _, err := sess.Where("org_id = ?", orgID).In("rule_uid", ruleUID).Delete(&ngmodels.AlertRuleStatusEntry{}); err != nil {
+		return fmt.Errorf("failed to delete statuses of rules: %w", err)
+	}
+	return nil
+}
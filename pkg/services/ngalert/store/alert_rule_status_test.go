@@ -0,0 +1,53 @@
+package store_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/tests"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntegrationAlertRuleStatus(t *testing.T) {
+	_, dbstore := tests.SetupTestEnv(t, baseIntervalSeconds)
+	insertSearchRules(t, *dbstore, 1, "rule")
+	query := &models.ListAlertRulesQuery{OrgID: 1}
+	require.NoError(t, dbstore.ListAlertRules(context.Background(), query))
+	require.Len(t, query.Result, 1)
+	uid := query.Result[0].UID
+
+	getStatus := func(t *testing.T) models.AlertRuleStatus {
+		t.Helper()
+		q := &models.GetAlertRuleByUIDQuery{OrgID: 1, UID: uid}
+		require.NoError(t, dbstore.GetAlertRuleByUID(context.Background(), q))
+		return q.Result.Status
+	}
+
+	t.Run("rules that were not evaluated should have no status", func(t *testing.T) {
+		require.Empty(t, getStatus(t))
+	})
+
+	t.Run("saving a status should replace the previous one", func(t *testing.T) {
+		now := time.Now().UTC().Truncate(time.Second)
+		require.NoError(t, dbstore.SaveAlertRuleStatus(context.Background(), models.AlertRuleStatusEntry{OrgID: 1, RuleUID: uid, Status: models.AlertRuleStatusError, Error: "exec error", LastEvaluation: now}))
+		require.Equal(t, models.AlertRuleStatusError, getStatus(t))
+
+		require.NoError(t, dbstore.SaveAlertRuleStatus(context.Background(), models.AlertRuleStatusEntry{OrgID: 1, RuleUID: uid, Status: models.AlertRuleStatusOK, LastEvaluation: now.Add(time.Minute)}))
+		require.Equal(t, models.AlertRuleStatusOK, getStatus(t))
+
+		statuses, err := dbstore.GetAlertRuleStatuses(context.Background(), 1)
+		require.NoError(t, err)
+		require.Len(t, statuses, 1)
+		require.Empty(t, statuses[uid].Error)
+		require.Equal(t, now.Add(time.Minute), statuses[uid].LastEvaluation.UTC())
+	})
+
+	t.Run("deleting a rule should delete its status", func(t *testing.T) {
+		require.NoError(t, dbstore.DeleteAlertRulesByUID(context.Background(), 1, uid))
+		statuses, err := dbstore.GetAlertRuleStatuses(context.Background(), 1, uid)
+		require.NoError(t, err)
+		require.Empty(t, statuses)
+	})
+}
@@ -0,0 +1,66 @@
+package store_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/store"
+	"github.com/grafana/grafana/pkg/services/ngalert/tests"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntegrationAlertRuleEvalEveryN(t *testing.T) {
+	_, dbstore := tests.SetupTestEnv(t, baseIntervalSeconds)
+	insertSearchRules(t, *dbstore, 1, "rule")
+	query := &models.ListAlertRulesQuery{OrgID: 1}
+	require.NoError(t, dbstore.ListAlertRules(context.Background(), query))
+	require.Len(t, query.Result, 1)
+	existing := query.Result[0]
+
+	t.Run("new rules without evaluation sampling should be evaluated on every tick", func(t *testing.T) {
+		require.Equal(t, models.DefaultEvalEveryN, existing.EvalEveryN)
+	})
+	t.Run("updates should reject evaluation sampling below 1", func(t *testing.T) {
+		for _, n := range []int{0, -1} {
+			updated := *existing
+			updated.EvalEveryN = n
+			err := dbstore.UpdateAlertRules(context.Background(), []store.UpdateRule{{Existing: existing, New: updated}})
+			require.ErrorIs(t, err, models.ErrAlertRuleFailedValidation)
+		}
+	})
+}
+
+func TestIntegrationGetAlertRuleByTitle(t *testing.T) {
+	_, dbstore := tests.SetupTestEnv(t, baseIntervalSeconds)
+	insertSearchRules(t, *dbstore, 1, "rule", "Other rule")
+	insertSearchRules(t, *dbstore, 2, "Rule")
+
+	rule, err := dbstore.GetAlertRuleByTitle(context.Background(), 1, "folder", "rule")
+	require.NoError(t, err)
+	require.Equal(t, "rule", rule.Title)
+	require.Equal(t, int64(1), rule.OrgID)
+
+	for _, title := range []string{"Rule", "other rule", "rule "} {
+		_, err = dbstore.GetAlertRuleByTitle(context.Background(), 1, "folder", title)
+		require.ErrorIs(t, err, models.ErrAlertRuleNotFound, "titles should be compared exactly")
+	}
+	_, err = dbstore.GetAlertRuleByTitle(context.Background(), 1, "other-folder", "rule")
+	require.ErrorIs(t, err, models.ErrAlertRuleNotFound)
+}
+
+func TestIntegrationListAlertRulesByUID(t *testing.T) {
+	_, dbstore := tests.SetupTestEnv(t, baseIntervalSeconds)
+	insertSearchRules(t, *dbstore, 1, "a", "b", "c")
+	all := &models.ListAlertRulesQuery{OrgID: 1}
+	require.NoError(t, dbstore.ListAlertRules(context.Background(), all))
+	require.Len(t, all.Result, 3)
+
+	query := &models.ListAlertRulesQuery{OrgID: 1, RuleUIDs: []string{all.Result[2].UID, all.Result[0].UID, "unknown"}}
+	require.NoError(t, dbstore.ListAlertRules(context.Background(), query))
+	titles := make([]string, 0, len(query.Result))
+	for _, rule := range query.Result {
+		titles = append(titles, rule.Title)
+	}
+	require.Equal(t, []string{"a", "c"}, titles)
+}
@@ -0,0 +1,48 @@
+package store_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/store"
+	"github.com/grafana/grafana/pkg/services/ngalert/tests"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntegrationAlertRuleVersions(t *testing.T) {
+	_, dbstore := tests.SetupTestEnv(t, baseIntervalSeconds)
+	insertSearchRules(t, *dbstore, 1, "rule")
+	query := &models.ListAlertRulesQuery{OrgID: 1}
+	require.NoError(t, dbstore.ListAlertRules(context.Background(), query))
+	require.Len(t, query.Result, 1)
+	existing := query.Result[0]
+
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	updated := *existing
+	updated.ExpiresAt = &expiresAt
+	updated.EvalEveryN = 3
+	updated.WarmUpEvals = 2
+	updated.GroupLabels = map[string]string{"team": "a"}
+	updated.ManagedBy = "operator"
+	require.NoError(t, dbstore.UpdateAlertRules(context.Background(), []store.UpdateRule{{Existing: existing, New: updated}}))
+
+	t.Run("versions should record the content of the rule", func(t *testing.T) {
+		version, err := dbstore.GetAlertRuleVersion(context.Background(), 1, existing.UID, existing.Version+1)
+		require.NoError(t, err)
+		require.NotNil(t, version.ExpiresAt)
+		require.Equal(t, expiresAt, version.ExpiresAt.UTC())
+		require.Equal(t, 3, version.EvalEveryN)
+		require.Equal(t, 2, version.WarmUpEvals)
+		require.Equal(t, map[string]string{"team": "a"}, version.GroupLabels)
+		require.Equal(t, "operator", version.ManagedBy)
+	})
+	t.Run("the first version should keep the content the rule was created with", func(t *testing.T) {
+		version, err := dbstore.GetAlertRuleVersion(context.Background(), 1, existing.UID, existing.Version)
+		require.NoError(t, err)
+		require.Nil(t, version.ExpiresAt)
+		require.Empty(t, version.GroupLabels)
+		require.Empty(t, version.ManagedBy)
+	})
+}
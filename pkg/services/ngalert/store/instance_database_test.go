@@ -180,4 +180,78 @@ func TestIntegrationAlertInstanceOperations(t *testing.T) {
 		require.Equal(t, saveCmdTwo.Labels, listQuery.Result[0].Labels)
 		require.Equal(t, saveCmdTwo.State, listQuery.Result[0].CurrentState)
 	})
+
+	t.Run("can save and clear missing series of alert instance", func(t *testing.T) {
+		saveCmd := &models.SaveAlertInstanceCommand{
+			RuleOrgID:           alertRule1.OrgID,
+			RuleUID:             alertRule1.UID,
+			State:               models.InstanceStateFiring,
+			StateReason:         string(models.InstanceStateNoData),
+			Labels:              models.InstanceLabels{"test": "noData"},
+			MissingSeriesLabels: []map[string]string{{"instance": "a"}, {"instance": "b"}},
+		}
+		err := dbstore.SaveAlertInstance(ctx, saveCmd)
+		require.NoError(t, err)
+
+		getCmd := &models.GetAlertInstanceQuery{
+			RuleOrgID: saveCmd.RuleOrgID,
+			RuleUID:   saveCmd.RuleUID,
+			Labels:    saveCmd.Labels,
+		}
+		err = dbstore.GetAlertInstance(ctx, getCmd)
+		require.NoError(t, err)
+		require.Equal(t, saveCmd.MissingSeriesLabels, getCmd.Result.MissingSeriesLabels)
+
+		saveCmd.State = models.InstanceStateNormal
+		saveCmd.StateReason = ""
+		saveCmd.MissingSeriesLabels = nil
+		err = dbstore.SaveAlertInstance(ctx, saveCmd)
+		require.NoError(t, err)
+
+		err = dbstore.GetAlertInstance(ctx, getCmd)
+		require.NoError(t, err)
+		require.Empty(t, getCmd.Result.MissingSeriesLabels)
+	})
+}
+
+func TestIntegrationFiringAlertSummary(t *testing.T) {
+	ctx := context.Background()
+	_, dbstore := tests.SetupTestEnv(t, baseIntervalSeconds)
+
+	rule1 := tests.CreateTestAlertRule(t, ctx, dbstore, 60, 1)
+	rule2 := tests.CreateTestAlertRule(t, ctx, dbstore, 60, 1)
+	otherOrgRule := tests.CreateTestAlertRule(t, ctx, dbstore, 60, 2)
+
+	instances := []struct {
+		rule   *models.AlertRule
+		state  models.InstanceStateType
+		labels models.InstanceLabels
+	}{
+		{rule1, models.InstanceStateFiring, models.InstanceLabels{"severity": "critical", "instance": "a"}},
+		{rule1, models.InstanceStateFiring, models.InstanceLabels{"severity": "critical", "instance": "b"}},
+		{rule2, models.InstanceStateFiring, models.InstanceLabels{"severity": "critical", "instance": "a"}},
+		{rule2, models.InstanceStateFiring, models.InstanceLabels{"severity": "warning"}},
+		{rule1, models.InstanceStateFiring, models.InstanceLabels{"instance": "c"}},
+		{rule2, models.InstanceStateFiring, models.InstanceLabels{}},
+		{rule1, models.InstanceStatePending, models.InstanceLabels{"severity": "critical", "instance": "d"}},
+		{rule2, models.InstanceStateNormal, models.InstanceLabels{"severity": "warning", "instance": "e"}},
+		{otherOrgRule, models.InstanceStateFiring, models.InstanceLabels{"severity": "critical"}},
+	}
+	for _, instance := range instances {
+		err := dbstore.SaveAlertInstance(ctx, &models.SaveAlertInstanceCommand{
+			RuleOrgID: instance.rule.OrgID,
+			RuleUID:   instance.rule.UID,
+			State:     instance.state,
+			Labels:    instance.labels,
+		})
+		require.NoError(t, err)
+	}
+
+	summary, err := dbstore.GetFiringAlertSummary(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, map[string]int{"critical": 3, "warning": 1, models.SeverityUnset: 2}, summary)
+
+	summary, err = dbstore.GetFiringAlertSummary(ctx, 3)
+	require.NoError(t, err)
+	require.Empty(t, summary)
 }
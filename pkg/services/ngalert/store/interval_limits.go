@@ -0,0 +1,40 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+)
+
+// GetRuleGroupIntervalLimits returns the interval limits of the organization. It falls back to the
+// default limits if the organization has none, and returns nil if there are no default limits either.
+func (st DBstore) GetRuleGroupIntervalLimits(ctx context.Context, orgID int64) (*models.RuleGroupIntervalLimits, error) {
+	var result *models.RuleGroupIntervalLimits
+	err := st.SQLStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		var limits []models.RuleGroupIntervalLimits
+		if err := sess.In("org_id", []int64{orgID, 0}).Desc("org_id").Find(&limits); err != nil {
+			return fmt.Errorf("failed to get interval limits: %w", err)
+		}
+		if len(limits) > 0 {
+			result = &limits[0]
+		}
+		return nil
+	})
+	return result, err
+}
+
+// SetRuleGroupIntervalLimits creates or replaces the interval limits of the organization of the limits.
+func (st DBstore) SetRuleGroupIntervalLimits(ctx context.Context, limits models.RuleGroupIntervalLimits) error {
+	return st.SQLStore.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		if _, err := sess.Where("org_id = ?", limits.OrgID).Delete(&models.RuleGroupIntervalLimits{}); err != nil {
+			return fmt.Errorf("failed to delete interval limits: %w", err)
+		}
+		limits.ID = 0
+		if _, err := sess.Insert(&limits); err != nil {
+			return fmt.Errorf("failed to save interval limits: %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,59 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+)
+
+// GetLibraryQuery returns the library query with the UID or ErrLibraryQueryNotFound.
+func (st DBstore) GetLibraryQuery(ctx context.Context, orgID int64, uid string) (*models.LibraryQuery, error) {
+	query := models.LibraryQuery{OrgID: orgID, UID: uid}
+	err := st.SQLStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		has, err := sess.Get(&query)
+		if err != nil {
+			return fmt.Errorf("failed to get library query: %w", err)
+		}
+		if !has {
+			return models.ErrLibraryQueryNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &query, nil
+}
+
+// SaveLibraryQuery creates the library query or updates the existing one with the same UID.
+func (st DBstore) SaveLibraryQuery(ctx context.Context, query *models.LibraryQuery) error {
+	return st.SQLStore.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		query.Updated = TimeNow()
+		existing := models.LibraryQuery{OrgID: query.OrgID, UID: query.UID}
+		has, err := sess.Get(&existing)
+		if err != nil {
+			return fmt.Errorf("failed to get library query: %w", err)
+		}
+		if has {
+			query.ID = existing.ID
+			if _, err := sess.ID(existing.ID).AllCols().Update(query); err != nil {
+				return fmt.Errorf("failed to update library query: %w", err)
+			}
+			return nil
+		}
+		if _, err := sess.Insert(query); err != nil {
+			return fmt.Errorf("failed to create library query: %w", err)
+		}
+		return nil
+	})
+}
+
+// DeleteLibraryQuery deletes the library query with the UID.
+func (st DBstore) DeleteLibraryQuery(ctx context.Context, orgID int64, uid string) error {
+	return st.SQLStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		_, err := sess.Where("org_id = ? AND uid = ?", orgID, uid).Delete(&models.LibraryQuery{})
+		return err
+	})
+}
@@ -0,0 +1,97 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+)
+
+// finishedProvisioningJobStatuses are the statuses of the jobs that do not run anymore.
+var finishedProvisioningJobStatuses = []interface{}{models.ProvisioningJobCompleted, models.ProvisioningJobFailed, models.ProvisioningJobCanceled}
+
+// GetProvisioningJob returns the job of the organization with the given UID, or nil if there is none.
+func (st DBstore) GetProvisioningJob(ctx context.Context, orgID int64, uid string) (*models.ProvisioningJob, error) {
+	var result *models.ProvisioningJob
+	err := st.SQLStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		var job models.ProvisioningJob
+		has, err := sess.Where("org_id = ? AND uid = ?", orgID, uid).Get(&job)
+		if err != nil {
+			return fmt.Errorf("failed to get provisioning job: %w", err)
+		}
+		if has {
+			result = &job
+		}
+		return nil
+	})
+	return result, err
+}
+
+// ListProvisioningJobs returns the jobs of all organizations with one of the statuses, oldest first.
+func (st DBstore) ListProvisioningJobs(ctx context.Context, statuses ...models.ProvisioningJobStatus) ([]*models.ProvisioningJob, error) {
+	result := make([]*models.ProvisioningJob, 0)
+	err := st.SQLStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		args := make([]interface{}, 0, len(statuses))
+		for _, status := range statuses {
+			args = append(args, status)
+		}
+		if err := sess.In("status", args...).Asc("id").Find(&result); err != nil {
+			return fmt.Errorf("failed to list provisioning jobs: %w", err)
+		}
+		return nil
+	})
+	return result, err
+}
+
+// InsertProvisioningJob creates the job.
+func (st DBstore) InsertProvisioningJob(ctx context.Context, job *models.ProvisioningJob) error {
+	return st.SQLStore.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		job.Created = time.Now()
+		job.Updated = job.Created
+		job.Version = 1
+		if _, err := sess.Insert(job); err != nil {
+			return fmt.Errorf("failed to save provisioning job: %w", err)
+		}
+		return nil
+	})
+}
+
+// UpdateProvisioningJob updates the status, the progress and the result of the job if it was not
+// updated since it was read. It returns false and leaves the job as is otherwise.
+func (st DBstore) UpdateProvisioningJob(ctx context.Context, job *models.ProvisioningJob) (bool, error) {
+	updated := false
+	err := st.SQLStore.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		update := *job
+		update.Version++
+		update.Updated = time.Now()
+		affected, err := sess.ID(job.ID).Where("version = ?", job.Version).
+			Cols("status", "result", "errors", "message", "processed", "total", "version", "updated").
+			Update(&update)
+		if err != nil {
+			return fmt.Errorf("failed to update provisioning job: %w", err)
+		}
+		if affected > 0 {
+			*job = update
+			updated = true
+		}
+		return nil
+	})
+	return updated, err
+}
+
+// DeleteProvisioningJobsUpdatedBefore deletes the finished jobs of all organizations that were last
+// updated before the given time, and returns how many were deleted.
+func (st DBstore) DeleteProvisioningJobsUpdatedBefore(ctx context.Context, before time.Time) (int64, error) {
+	var deleted int64
+	err := st.SQLStore.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		var err error
+		deleted, err = sess.Where("updated < ?", before).In("status", finishedProvisioningJobStatuses...).Delete(&models.ProvisioningJob{})
+		if err != nil {
+			return fmt.Errorf("failed to delete provisioning jobs: %w", err)
+		}
+		return nil
+	})
+	return deleted, err
+}
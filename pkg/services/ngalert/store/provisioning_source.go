@@ -0,0 +1,98 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+)
+
+type provisioningSourceRecord struct {
+	ID          int64     `xorm:"pk autoincr 'id'"`
+	OrgID       int64     `xorm:"'org_id'"`
+	RecordKey   string    `xorm:"'record_key'"`
+	RecordType  string    `xorm:"'record_type'"`
+	Path        string    `xorm:"'path'"`
+	ContentHash string    `xorm:"'content_hash'"`
+	AppliedAt   time.Time `xorm:"'applied_at'"`
+}
+
+func (r provisioningSourceRecord) TableName() string {
+	return "provisioning_source"
+}
+
+func (r provisioningSourceRecord) source() models.ProvisioningSource {
+	return models.ProvisioningSource{
+		Path:      r.Path,
+		Hash:      r.ContentHash,
+		AppliedAt: r.AppliedAt,
+	}
+}
+
+// GetProvisioningSource returns the file that the provisionable object was last applied from, or
+// nil if it was not applied from a file.
+func (st DBstore) GetProvisioningSource(ctx context.Context, o models.Provisionable, org int64) (*models.ProvisioningSource, error) {
+	var result *models.ProvisioningSource
+	err := st.SQLStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		var record provisioningSourceRecord
+		has, err := sess.Where("record_key = ? AND record_type = ? AND org_id = ?", o.ResourceID(), o.ResourceType(), org).Get(&record)
+		if err != nil {
+			return fmt.Errorf("failed to query for the provisioning source: %w", err)
+		}
+		if has {
+			source := record.source()
+			result = &source
+		}
+		return nil
+	})
+	return result, err
+}
+
+// GetProvisioningSources returns the files that the objects of the resource type were last applied
+// from by the IDs of the objects.
+func (st DBstore) GetProvisioningSources(ctx context.Context, org int64, resourceType string) (map[string]models.ProvisioningSource, error) {
+	result := make(map[string]models.ProvisioningSource)
+	err := st.SQLStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		var records []provisioningSourceRecord
+		if err := sess.Where("record_type = ? AND org_id = ?", resourceType, org).Find(&records); err != nil {
+			return fmt.Errorf("failed to query for the provisioning sources: %w", err)
+		}
+		for _, record := range records {
+			result[record.RecordKey] = record.source()
+		}
+		return nil
+	})
+	return result, err
+}
+
+// SetProvisioningSource records the file that the provisionable object was applied from.
+func (st DBstore) SetProvisioningSource(ctx context.Context, o models.Provisionable, org int64, source models.ProvisioningSource) error {
+	return st.SQLStore.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		filter := "record_key = ? AND record_type = ? AND org_id = ?"
+		if _, err := sess.Where(filter, o.ResourceID(), o.ResourceType(), org).Delete(provisioningSourceRecord{}); err != nil {
+			return fmt.Errorf("failed to delete the previous provisioning source: %w", err)
+		}
+		record := provisioningSourceRecord{
+			OrgID:       org,
+			RecordKey:   o.ResourceID(),
+			RecordType:  o.ResourceType(),
+			Path:        source.Path,
+			ContentHash: source.Hash,
+			AppliedAt:   source.AppliedAt,
+		}
+		if _, err := sess.Insert(&record); err != nil {
+			return fmt.Errorf("failed to store the provisioning source: %w", err)
+		}
+		return nil
+	})
+}
+
+// DeleteProvisioningSource deletes the provisioning source of the provisionable object.
+func (st DBstore) DeleteProvisioningSource(ctx context.Context, o models.Provisionable, org int64) error {
+	return st.SQLStore.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		_, err := sess.Where("record_key = ? AND record_type = ? AND org_id = ?", o.ResourceID(), o.ResourceType(), org).Delete(provisioningSourceRecord{})
+		return err
+	})
+}
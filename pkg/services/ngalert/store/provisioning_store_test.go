@@ -3,6 +3,7 @@ package store_test
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/grafana/grafana/pkg/services/ngalert"
 	"github.com/grafana/grafana/pkg/services/ngalert/models"
@@ -125,6 +126,35 @@ func TestProvisioningStore(t *testing.T) {
 	})
 }
 
+func TestProvisioningSourceStore(t *testing.T) {
+	_, dbstore := tests.SetupTestEnv(t, testAlertingIntervalSeconds)
+	var store provisioning.ProvisioningSourceStore = dbstore
+	rule := models.AlertRule{UID: "source"}
+	appliedAt := time.Date(2022, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	source, err := store.GetProvisioningSource(context.Background(), &rule, 1)
+	require.NoError(t, err)
+	require.Nil(t, source, "resources that were not applied from a file have no source")
+
+	require.NoError(t, store.SetProvisioningSource(context.Background(), &rule, 1, models.ProvisioningSource{Path: "/etc/old.yaml", Hash: "old", AppliedAt: appliedAt}))
+	require.NoError(t, store.SetProvisioningSource(context.Background(), &rule, 1, models.ProvisioningSource{Path: "/etc/rules.yaml", Hash: "new", AppliedAt: appliedAt}))
+	source, err = store.GetProvisioningSource(context.Background(), &rule, 1)
+	require.NoError(t, err)
+	require.NotNil(t, source)
+	require.Equal(t, "/etc/rules.yaml", source.Path)
+	require.Equal(t, "new", source.Hash)
+	require.True(t, appliedAt.Equal(source.AppliedAt))
+
+	source, err = store.GetProvisioningSource(context.Background(), &rule, 2)
+	require.NoError(t, err)
+	require.Nil(t, source, "sources should be scoped to their organization")
+
+	require.NoError(t, store.DeleteProvisioningSource(context.Background(), &rule, 1))
+	source, err = store.GetProvisioningSource(context.Background(), &rule, 1)
+	require.NoError(t, err)
+	require.Nil(t, source)
+}
+
 func createProvisioningStoreSut(_ *ngalert.AlertNG, db *store.DBstore) provisioning.ProvisioningStore {
 	return db
 }
@@ -0,0 +1,84 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+)
+
+// GetRuleGroupDraft returns the draft of the rule group, or nil if there is none.
+func (st DBstore) GetRuleGroupDraft(ctx context.Context, orgID int64, namespaceUID, group string) (*models.RuleGroupDraft, error) {
+	var result *models.RuleGroupDraft
+	err := st.SQLStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		var draft models.RuleGroupDraft
+		has, err := sess.Where("org_id = ? AND namespace_uid = ?", orgID, namespaceUID).
+			And(st.binaryEqual("rule_group", "?"), group).
+			Get(&draft)
+		if err != nil {
+			return fmt.Errorf("failed to get rule group draft: %w", err)
+		}
+		if has {
+			result = &draft
+		}
+		return nil
+	})
+	return result, err
+}
+
+// ListRuleGroupDrafts returns the drafts of the organization sorted by namespace and rule group.
+func (st DBstore) ListRuleGroupDrafts(ctx context.Context, orgID int64) ([]*models.RuleGroupDraft, error) {
+	result := make([]*models.RuleGroupDraft, 0)
+	err := st.SQLStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		if err := sess.Where("org_id = ?", orgID).Asc("namespace_uid", "rule_group").Find(&result); err != nil {
+			return fmt.Errorf("failed to list rule group drafts: %w", err)
+		}
+		return nil
+	})
+	return result, err
+}
+
+// SaveRuleGroupDraft creates the draft, or updates its interval and rules if it already exists.
+func (st DBstore) SaveRuleGroupDraft(ctx context.Context, draft *models.RuleGroupDraft) error {
+	return st.SQLStore.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		draft.Updated = time.Now()
+		if draft.ID == 0 {
+			draft.Created = draft.Updated
+			if _, err := sess.Insert(draft); err != nil {
+				return fmt.Errorf("failed to save rule group draft: %w", err)
+			}
+			return nil
+		}
+		if _, err := sess.ID(draft.ID).Cols("interval_seconds", "rules", "updated").Update(draft); err != nil {
+			return fmt.Errorf("failed to update rule group draft: %w", err)
+		}
+		return nil
+	})
+}
+
+// DeleteRuleGroupDraft deletes the draft with the given ID.
+func (st DBstore) DeleteRuleGroupDraft(ctx context.Context, id int64) error {
+	return st.SQLStore.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		if _, err := sess.ID(id).Delete(&models.RuleGroupDraft{}); err != nil {
+			return fmt.Errorf("failed to delete rule group draft: %w", err)
+		}
+		return nil
+	})
+}
+
+// DeleteRuleGroupDraftsUpdatedBefore deletes the drafts of all organizations that were last updated
+// before the given time, and returns how many were deleted.
+func (st DBstore) DeleteRuleGroupDraftsUpdatedBefore(ctx context.Context, before time.Time) (int64, error) {
+	var deleted int64
+	err := st.SQLStore.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		var err error
+		deleted, err = sess.Where("updated < ?", before).Delete(&models.RuleGroupDraft{})
+		if err != nil {
+			return fmt.Errorf("failed to delete expired rule group drafts: %w", err)
+		}
+		return nil
+	})
+	return deleted, err
+}
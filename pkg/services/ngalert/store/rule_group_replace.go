@@ -0,0 +1,57 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+)
+
+// GetRuleGroupReplaceJournal returns the journal of an unfinished replace of the rule group, or nil if there is none.
+func (st DBstore) GetRuleGroupReplaceJournal(ctx context.Context, orgID int64, namespaceUID, group string) (*models.RuleGroupReplaceJournal, error) {
+	var result *models.RuleGroupReplaceJournal
+	err := st.SQLStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		var journal models.RuleGroupReplaceJournal
+		has, err := sess.Where("org_id = ? AND namespace_uid = ?", orgID, namespaceUID).
+			And(st.binaryEqual("rule_group", "?"), group).
+			Get(&journal)
+		if err != nil {
+			return fmt.Errorf("failed to get rule group replace journal: %w", err)
+		}
+		if has {
+			result = &journal
+		}
+		return nil
+	})
+	return result, err
+}
+
+// SaveRuleGroupReplaceJournal creates the journal, or updates its progress if it already exists.
+func (st DBstore) SaveRuleGroupReplaceJournal(ctx context.Context, journal *models.RuleGroupReplaceJournal) error {
+	return st.SQLStore.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		journal.Updated = time.Now()
+		if journal.ID == 0 {
+			journal.Created = journal.Updated
+			if _, err := sess.Insert(journal); err != nil {
+				return fmt.Errorf("failed to save rule group replace journal: %w", err)
+			}
+			return nil
+		}
+		if _, err := sess.ID(journal.ID).Cols("applied", "updated").Update(journal); err != nil {
+			return fmt.Errorf("failed to update rule group replace journal: %w", err)
+		}
+		return nil
+	})
+}
+
+// DeleteRuleGroupReplaceJournal deletes the journal with the given ID.
+func (st DBstore) DeleteRuleGroupReplaceJournal(ctx context.Context, id int64) error {
+	return st.SQLStore.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		if _, err := sess.ID(id).Delete(&models.RuleGroupReplaceJournal{}); err != nil {
+			return fmt.Errorf("failed to delete rule group replace journal: %w", err)
+		}
+		return nil
+	})
+}
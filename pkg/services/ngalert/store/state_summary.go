@@ -0,0 +1,88 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+)
+
+// GetAlertRuleStateSummaries summarizes the alert instances of the rules of the organization with a
+// single aggregate query. If no rule UIDs are given, all rules of the organization are summarized.
+// Rules without alert instances are not part of the result.
+func (st DBstore) GetAlertRuleStateSummaries(ctx context.Context, orgID int64, ruleUIDs ...string) (map[string]*models.AlertRuleStateSummary, error) {
+	var rows []struct {
+		RuleUID      string `xorm:"rule_uid"`
+		CurrentState string `xorm:"current_state"`
+		Instances    int64  `xorm:"instances"`
+		LastEval     int64  `xorm:"last_eval"`
+		LastError    int64  `xorm:"last_error"`
+	}
+	err := st.SQLStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		q := sess.Table("alert_instance").Select(fmt.Sprintf(
+			"rule_uid, current_state, COUNT(*) AS instances, MAX(last_eval_time) AS last_eval, "+
+				"MAX(CASE WHEN current_state = '%[1]s' OR current_reason = '%[1]s' THEN last_eval_time ELSE 0 END) AS last_error",
+			models.InstanceStateError,
+		)).Where("rule_org_id = ?", orgID)
+		if len(ruleUIDs) > 0 {
+			q = q.In("rule_uid", ruleUIDs)
+		}
+		if err := q.GroupBy("rule_uid, current_state").Find(&rows); err != nil {
+			return fmt.Errorf("failed to summarize alert instances: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]*models.AlertRuleStateSummary)
+	for _, row := range rows {
+		summary, ok := result[row.RuleUID]
+		if !ok {
+			summary = &models.AlertRuleStateSummary{Instances: make(map[models.InstanceStateType]int64)}
+			result[row.RuleUID] = summary
+		}
+		summary.Instances[models.InstanceStateType(row.CurrentState)] += row.Instances
+		if lastEval := time.Unix(row.LastEval, 0); lastEval.After(summary.LastEvaluation) {
+			summary.LastEvaluation = lastEval
+		}
+		if row.LastError > 0 {
+			if lastError := time.Unix(row.LastError, 0); lastError.After(summary.LastError) {
+				summary.LastError = lastError
+			}
+		}
+	}
+	return result, nil
+}
+
+// GetFiringAlertSummary counts the firing alert instances of the organization by the value of their
+// severity label. Instances without a severity label are counted as models.SeverityUnset. The instances
+// are counted by their labels with a single aggregate query and then summed up by severity.
+func (st DBstore) GetFiringAlertSummary(ctx context.Context, orgID int64) (map[string]int, error) {
+	var rows []struct {
+		Labels    models.InstanceLabels `xorm:"labels"`
+		Instances int                   `xorm:"instances"`
+	}
+	err := st.SQLStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		q := sess.Table("alert_instance").Select("labels, COUNT(*) AS instances").
+			Where("rule_org_id = ? AND current_state = ?", orgID, models.InstanceStateFiring)
+		if err := q.GroupBy("labels").Find(&rows); err != nil {
+			return fmt.Errorf("failed to summarize firing alert instances: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]int)
+	for _, row := range rows {
+		severity, ok := row.Labels[models.SeverityLabel]
+		if !ok {
+			severity = models.SeverityUnset
+		}
+		result[severity] += row.Instances
+	}
+	return result, nil
+}
@@ -8,6 +8,7 @@ import (
 	"math/rand"
 	"net/http"
 	"net/http/httptest"
+	"sort"
 	"sync"
 	"testing"
 
@@ -23,7 +24,7 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-func NewFakeRuleStore(t *testing.T) *FakeRuleStore {
+func NewFakeRuleStore(t testing.TB) *FakeRuleStore {
 	return &FakeRuleStore{
 		t:     t,
 		Rules: map[int64][]*models.AlertRule{},
@@ -36,7 +37,7 @@ func NewFakeRuleStore(t *testing.T) *FakeRuleStore {
 
 // FakeRuleStore mocks the RuleStore of the scheduler.
 type FakeRuleStore struct {
-	t   *testing.T
+	t   testing.TB
 	mtx sync.Mutex
 	// OrgID -> RuleGroup -> Namespace -> Rules
 	Rules       map[int64][]*models.AlertRule
@@ -138,18 +139,34 @@ func (f *FakeRuleStore) GetAlertRuleByUID(_ context.Context, q *models.GetAlertR
 	if err := f.Hook(*q); err != nil {
 		return err
 	}
-	rules, ok := f.Rules[q.OrgID]
-	if !ok {
-		return nil
-	}
-
-	for _, rule := range rules {
+	for _, rule := range f.Rules[q.OrgID] {
 		if rule.UID == q.UID {
 			q.Result = rule
-			break
+			return nil
 		}
 	}
-	return nil
+	return models.ErrAlertRuleNotFound
+}
+
+func (f *FakeRuleStore) GetAlertRuleByTitle(_ context.Context, orgID int64, namespaceUID string, title string) (*models.AlertRule, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	for _, rule := range f.Rules[orgID] {
+		if rule.NamespaceUID == namespaceUID && rule.Title == title {
+			return rule, nil
+		}
+	}
+	return nil, models.ErrAlertRuleNotFound
+}
+
+func (f *FakeRuleStore) GetAlertRuleVersion(_ context.Context, orgID int64, ruleUID string, version int64) (*models.AlertRuleVersion, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	f.RecordedOps = append(f.RecordedOps, GenericRecordedQuery{
+		Name:   "GetAlertRuleVersion",
+		Params: []interface{}{orgID, ruleUID, version},
+	})
+	return nil, ErrVersionNotFound
 }
 
 func (f *FakeRuleStore) GetAlertRulesGroupByRuleUID(_ context.Context, q *models.GetAlertRulesGroupByRuleUIDQuery) error {
@@ -194,10 +211,16 @@ func (f *FakeRuleStore) GetAlertRulesForScheduling(_ context.Context, q *models.
 	for _, rules := range f.Rules {
 		for _, rule := range rules {
 			q.Result = append(q.Result, &models.SchedulableAlertRule{
-				UID:             rule.UID,
-				OrgID:           rule.OrgID,
-				IntervalSeconds: rule.IntervalSeconds,
-				Version:         rule.Version,
+				UID:               rule.UID,
+				OrgID:             rule.OrgID,
+				NamespaceUID:      rule.NamespaceUID,
+				RuleGroup:         rule.RuleGroup,
+				IntervalSeconds:   rule.IntervalSeconds,
+				EvalStrategy:      rule.EvalStrategy,
+				EvalOrder:         rule.EvalOrder,
+				EvalOffsetSeconds: rule.EvalOffsetSeconds,
+				EvalEveryN:        rule.EvalEveryN,
+				Version:           rule.Version,
 			})
 		}
 	}
@@ -243,6 +266,15 @@ func (f *FakeRuleStore) ListAlertRules(_ context.Context, q *models.ListAlertRul
 		return true
 	}
 
+	hasUID := func(r *models.AlertRule, uids []string) bool {
+		for _, uid := range uids {
+			if uid == r.UID {
+				return true
+			}
+		}
+		return false
+	}
+
 	for _, r := range f.Rules[q.OrgID] {
 		if !hasDashboard(r, q.DashboardUID, q.PanelID) {
 			continue
@@ -253,6 +285,12 @@ func (f *FakeRuleStore) ListAlertRules(_ context.Context, q *models.ListAlertRul
 		if q.RuleGroup != "" && r.RuleGroup != q.RuleGroup {
 			continue
 		}
+		if len(q.RuleUIDs) > 0 && !hasUID(r, q.RuleUIDs) {
+			continue
+		}
+		if q.ExpiredAt != nil && (r.ExpiresAt == nil || r.ExpiresAt.After(*q.ExpiredAt)) {
+			continue
+		}
 		q.Result = append(q.Result, r)
 	}
 
@@ -278,6 +316,42 @@ func (f *FakeRuleStore) GetRuleGroups(_ context.Context, q *models.ListRuleGroup
 	return nil
 }
 
+func (f *FakeRuleStore) ListOrgRuleGroups(_ context.Context, q *models.ListOrgRuleGroupsQuery) error {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	f.RecordedOps = append(f.RecordedOps, *q)
+
+	namespaces := make(map[string]struct{}, len(q.NamespaceUIDs))
+	for _, uid := range q.NamespaceUIDs {
+		namespaces[uid] = struct{}{}
+	}
+
+	m := make(map[string]map[string]struct{})
+	for _, rule := range f.Rules[q.OrgID] {
+		if _, ok := namespaces[rule.NamespaceUID]; len(namespaces) > 0 && !ok {
+			continue
+		}
+		if _, ok := m[rule.NamespaceUID]; !ok {
+			m[rule.NamespaceUID] = make(map[string]struct{})
+		}
+		m[rule.NamespaceUID][rule.RuleGroup] = struct{}{}
+	}
+
+	q.Result = make([][]string, 0)
+	for namespaceUID, groups := range m {
+		for group := range groups {
+			q.Result = append(q.Result, []string{group, namespaceUID})
+		}
+	}
+	sort.Slice(q.Result, func(i, j int) bool {
+		if q.Result[i][1] != q.Result[j][1] {
+			return q.Result[i][1] < q.Result[j][1]
+		}
+		return q.Result[i][0] < q.Result[j][0]
+	})
+	return nil
+}
+
 func (f *FakeRuleStore) GetUserVisibleNamespaces(_ context.Context, orgID int64, _ *models2.SignedInUser) (map[string]*models2.Folder, error) {
 	f.mtx.Lock()
 	defer f.mtx.Unlock()
@@ -341,6 +415,35 @@ func (f *FakeRuleStore) GetRuleGroupInterval(ctx context.Context, orgID int64, n
 	return 0, ErrAlertRuleGroupNotFound
 }
 
+func (f *FakeRuleStore) UpdateRuleGroupEvalStrategy(ctx context.Context, orgID int64, namespaceUID string, ruleGroup string, strategy string) error {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	found := false
+	for _, rule := range f.Rules[orgID] {
+		if rule.RuleGroup == ruleGroup && rule.NamespaceUID == namespaceUID {
+			rule.EvalStrategy = strategy
+			rule.Version++
+			found = true
+		}
+	}
+	if !found {
+		return ErrAlertRuleGroupNotFound
+	}
+	return nil
+}
+
+func (f *FakeRuleStore) UpdateRuleGroupLabels(ctx context.Context, orgID int64, namespaceUID string, ruleGroup string, labels map[string]string) error {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	for _, rule := range f.Rules[orgID] {
+		if rule.RuleGroup == ruleGroup && rule.NamespaceUID == namespaceUID {
+			rule.GroupLabels = labels
+			rule.Version++
+		}
+	}
+	return nil
+}
+
 func (f *FakeRuleStore) UpdateRuleGroup(ctx context.Context, orgID int64, namespaceUID string, ruleGroup string, interval int64) error {
 	f.mtx.Lock()
 	defer f.mtx.Unlock()
@@ -352,6 +455,85 @@ func (f *FakeRuleStore) UpdateRuleGroup(ctx context.Context, orgID int64, namesp
 	return nil
 }
 
+func (f *FakeRuleStore) SetRuleGroupInterval(ctx context.Context, orgID int64, namespaceUID string, ruleGroup string, interval int64) error {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	found := false
+	for _, rule := range f.Rules[orgID] {
+		if rule.RuleGroup == ruleGroup && rule.NamespaceUID == namespaceUID {
+			rule.IntervalSeconds = interval
+			rule.Version++
+			found = true
+		}
+	}
+	if !found {
+		return ErrAlertRuleGroupNotFound
+	}
+	return nil
+}
+
+func (f *FakeRuleStore) ListAmbiguousGroups(ctx context.Context, orgID int64) ([]models.AmbiguousRuleGroup, error) {
+	query := &models.ListOrgRuleGroupsQuery{OrgID: orgID}
+	if err := f.ListOrgRuleGroups(ctx, query); err != nil {
+		return nil, err
+	}
+	return ambiguousRuleGroups(query.Result), nil
+}
+
+func (f *FakeRuleStore) ListDuplicateAlertRuleUIDs(_ context.Context) (map[string][]int64, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	orgs := make(map[string][]int64)
+	for orgID, rules := range f.Rules {
+		for _, rule := range rules {
+			orgs[rule.UID] = append(orgs[rule.UID], orgID)
+		}
+	}
+	result := make(map[string][]int64)
+	for uid, orgIDs := range orgs {
+		if len(orgIDs) > 1 {
+			sort.Slice(orgIDs, func(i, j int) bool { return orgIDs[i] < orgIDs[j] })
+			result[uid] = orgIDs
+		}
+	}
+	return result, nil
+}
+
+func (f *FakeRuleStore) GetAlertRulesByOwner(_ context.Context, orgID int64, owner string) ([]*models.AlertRule, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	var result []*models.AlertRule
+	for _, rule := range f.Rules[orgID] {
+		if rule.ManagedBy == owner {
+			result = append(result, rule)
+		}
+	}
+	return result, nil
+}
+
+func (f *FakeRuleStore) GetAlertRulesByDataSource(_ context.Context, orgID int64, datasourceUID string) ([]*models.AlertRule, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	var result []*models.AlertRule
+	for _, rule := range f.Rules[orgID] {
+		for _, uid := range models.QueriedDatasourceUIDs(rule.Data) {
+			if uid == datasourceUID {
+				result = append(result, rule)
+				break
+			}
+		}
+	}
+	return result, nil
+}
+
+func (f *FakeRuleStore) Ping(_ context.Context) error {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	q := GenericRecordedQuery{Name: "Ping"}
+	f.RecordedOps = append(f.RecordedOps, q)
+	return f.Hook(q)
+}
+
 type FakeInstanceStore struct {
 	mtx         sync.Mutex
 	RecordedOps []interface{}
@@ -380,8 +562,31 @@ func (f *FakeInstanceStore) FetchOrgIds(_ context.Context) ([]int64, error) { re
 func (f *FakeInstanceStore) DeleteAlertInstance(_ context.Context, _ int64, _, _ string) error {
 	return nil
 }
+func (f *FakeInstanceStore) GetFiringAlertSummary(_ context.Context, _ int64) (map[string]int, error) {
+	return map[string]int{}, nil
+}
+
+// FakeRuleStatusStore records the statuses of alert rules in the order in which they are saved.
+type FakeRuleStatusStore struct {
+	mtx      sync.Mutex
+	Statuses []models.AlertRuleStatusEntry
+}
+
+func (f *FakeRuleStatusStore) SaveAlertRuleStatus(_ context.Context, status models.AlertRuleStatusEntry) error {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	f.Statuses = append(f.Statuses, status)
+	return nil
+}
+
+// GetStatuses returns a copy of the saved statuses.
+func (f *FakeRuleStatusStore) GetStatuses() []models.AlertRuleStatusEntry {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	return append([]models.AlertRuleStatusEntry(nil), f.Statuses...)
+}
 
-func NewFakeAdminConfigStore(t *testing.T) *FakeAdminConfigStore {
+func NewFakeAdminConfigStore(t testing.TB) *FakeAdminConfigStore {
 	t.Helper()
 	return &FakeAdminConfigStore{Configs: map[int64]*models.AdminConfiguration{}}
 }
@@ -0,0 +1,70 @@
+package alertrules
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+)
+
+type configReader struct {
+	log log.Logger
+}
+
+// readConfig parses all YAML files of the directory in the order of their names. Files that
+// cannot be parsed are returned as failures, so that all problems are reported at once.
+func (cr *configReader) readConfig(path string) ([]*rulesFile, []Failure) {
+	cr.log.Debug("Looking for alert rule provisioning files", "path", path)
+
+	files, err := ioutil.ReadDir(path)
+	if err != nil {
+		cr.log.Debug("Can't read alert rule provisioning files from directory", "path", path, "error", err)
+		return nil, nil
+	}
+
+	var result []*rulesFile
+	var failures []Failure
+	for _, file := range files {
+		if file.IsDir() || !(strings.HasSuffix(file.Name(), ".yaml") || strings.HasSuffix(file.Name(), ".yml")) {
+			continue
+		}
+		filename := filepath.Join(path, file.Name())
+		cr.log.Debug("Parsing alert rule provisioning file", "path", filename)
+		parsed, err := cr.parseFile(filename)
+		if err != nil {
+			failures = append(failures, Failure{File: filename, Reason: err.Error()})
+			continue
+		}
+		result = append(result, parsed)
+	}
+	return result, failures
+}
+
+func (cr *configReader) parseFile(filename string) (*rulesFile, error) {
+	// nolint:gosec
+	// We can ignore the gosec G304 warning on this one because `filename` comes from ps.Cfg.ProvisioningPath
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var cfg rulesFileV1
+	if err := yaml.Unmarshal(content, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse file: %w", err)
+	}
+	if cfg.APIVersion != 1 {
+		return nil, fmt.Errorf("unsupported apiVersion %d", cfg.APIVersion)
+	}
+	for i := range cfg.Groups {
+		if cfg.Groups[i].OrgID < 1 {
+			cfg.Groups[i].OrgID = 1
+		}
+	}
+	sum := sha256.Sum256(content)
+	return &rulesFile{Path: filename, Hash: hex.EncodeToString(sum[:]), Groups: cfg.Groups}, nil
+}
@@ -0,0 +1,283 @@
+package alertrules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+// AlertRuleManager is the part of the alert rule provisioning service used to apply rule files.
+type AlertRuleManager interface {
+	ValidateAlertRule(ctx context.Context, rule models.AlertRule) error
+	GetAlertRule(ctx context.Context, orgID int64, ruleUID string) (models.AlertRule, models.Provenance, error)
+	CreateAlertRule(ctx context.Context, rule models.AlertRule, provenance models.Provenance) (models.AlertRule, error)
+	UpdateAlertRule(ctx context.Context, rule models.AlertRule, provenance models.Provenance) (models.AlertRule, error)
+	UpdateAlertGroup(ctx context.Context, orgID int64, folderUID, ruleGroup string, interval int64, provenance models.Provenance) error
+	SetProvisioningSource(ctx context.Context, orgID int64, resourceType, uid string, source models.ProvisioningSource) error
+}
+
+// errRollback makes a transaction roll back the changes of a file that could not be applied completely.
+var errRollback = errors.New("rollback")
+
+// TransactionManager runs work in a database transaction.
+type TransactionManager interface {
+	InTransaction(ctx context.Context, work func(ctx context.Context) error) error
+}
+
+// Options controls how the alert rule files are provisioned.
+type Options struct {
+	// Strict fails the whole provisioning if any rule is invalid or cannot be applied.
+	Strict bool
+	// AtomicFileProvisioning applies every file in a transaction. A file is only provisioned
+	// if all of its rules are valid, and its changes are rolled back if any rule cannot be applied.
+	AtomicFileProvisioning bool
+}
+
+// Failure is a problem with a file, rule group or rule that prevented it from being provisioned.
+type Failure struct {
+	File   string `json:"file"`
+	Group  string `json:"group,omitempty"`
+	Title  string `json:"title,omitempty"`
+	Reason string `json:"reason"`
+}
+
+func (f Failure) String() string {
+	switch {
+	case f.Title != "":
+		return fmt.Sprintf("%s: group '%s', rule '%s': %s", f.File, f.Group, f.Title, f.Reason)
+	case f.Group != "":
+		return fmt.Sprintf("%s: group '%s': %s", f.File, f.Group, f.Reason)
+	default:
+		return fmt.Sprintf("%s: %s", f.File, f.Reason)
+	}
+}
+
+// Report is the result of provisioning the alert rule files.
+type Report struct {
+	Time     time.Time `json:"time"`
+	Strict   bool      `json:"strict"`
+	Rules    int       `json:"rules"`
+	Failures []Failure `json:"failures"`
+}
+
+func (r Report) String() string {
+	lines := make([]string, 0, len(r.Failures))
+	for _, failure := range r.Failures {
+		lines = append(lines, failure.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Provision provisions the alert rules of all files in configDirectory. All files are validated
+// before anything is applied. In strict mode, any invalid rule fails the whole provisioning and
+// nothing is applied. Otherwise invalid rules are skipped and reported. The report is returned
+// in both cases.
+func Provision(ctx context.Context, configDirectory string, manager AlertRuleManager, xact TransactionManager, opts Options) (Report, error) {
+	logger := log.New("provisioning.alertrules")
+	p := &rulesProvisioner{
+		log:         logger,
+		cfgProvider: &configReader{log: logger},
+		manager:     manager,
+		xact:        xact,
+	}
+	return p.applyChanges(ctx, configDirectory, opts)
+}
+
+type rulesProvisioner struct {
+	log         log.Logger
+	cfgProvider *configReader
+	manager     AlertRuleManager
+	xact        TransactionManager
+	// appliedAt is the time of the provisioning that is recorded as the source of the applied rules.
+	appliedAt time.Time
+}
+
+// ruleGroup is a validated group of a provisioning file.
+type ruleGroup struct {
+	file      string
+	fileHash  string
+	orgID     int64
+	folderUID string
+	name      string
+	interval  int64
+	rules     []models.AlertRule
+}
+
+func (p *rulesProvisioner) applyChanges(ctx context.Context, configPath string, opts Options) (Report, error) {
+	report := Report{Time: time.Now(), Strict: opts.Strict}
+	p.appliedAt = report.Time
+	files, failures := p.cfgProvider.readConfig(configPath)
+	report.Failures = append(report.Failures, failures...)
+
+	groups, failures := p.validate(ctx, files)
+	report.Failures = append(report.Failures, failures...)
+	if opts.Strict && len(report.Failures) > 0 {
+		return report, fmt.Errorf("invalid alert rule provisioning files:\n%s", report)
+	}
+
+	if opts.AtomicFileProvisioning {
+		applied, failures := p.applyFiles(ctx, files, groups, failures)
+		report.Rules = applied
+		report.Failures = append(report.Failures, failures...)
+	} else {
+		for _, group := range groups {
+			report.Rules += len(group.rules)
+			report.Failures = append(report.Failures, p.applyGroup(ctx, group)...)
+		}
+	}
+	if len(report.Failures) == 0 {
+		return report, nil
+	}
+	if opts.Strict {
+		return report, fmt.Errorf("failed to provision alert rules:\n%s", report)
+	}
+	for _, failure := range report.Failures {
+		p.log.Error("Failed to provision alert rule", "file", failure.File, "group", failure.Group, "title", failure.Title, "reason", failure.Reason)
+	}
+	return report, nil
+}
+
+// validate maps the groups of all files to alert rules and validates them with the
+// validation of the alert rule service. Invalid rules are left out of the result.
+func (p *rulesProvisioner) validate(ctx context.Context, files []*rulesFile) ([]ruleGroup, []Failure) {
+	var groups []ruleGroup
+	var failures []Failure
+	uids := map[string]string{}
+	for _, file := range files {
+		for _, group := range file.Groups {
+			groupFailure := func(reason string) {
+				failures = append(failures, Failure{File: file.Path, Group: group.Name, Reason: reason})
+			}
+			switch {
+			case group.Name == "":
+				groupFailure("name is required")
+				continue
+			case group.Folder == "":
+				groupFailure("folder is required")
+				continue
+			case group.Interval <= 0:
+				groupFailure("interval must be positive")
+				continue
+			}
+			result := ruleGroup{
+				file:      file.Path,
+				fileHash:  file.Hash,
+				orgID:     group.OrgID,
+				folderUID: group.Folder,
+				name:      group.Name,
+				interval:  int64(time.Duration(group.Interval).Seconds()),
+			}
+			for _, export := range group.Rules {
+				ruleFailure := func(reason string) {
+					failures = append(failures, Failure{File: file.Path, Group: group.Name, Title: export.Title, Reason: reason})
+				}
+				if export.UID == "" {
+					ruleFailure("uid is required")
+					continue
+				}
+				key := fmt.Sprintf("%d/%s", group.OrgID, export.UID)
+				if other, ok := uids[key]; ok {
+					ruleFailure(fmt.Sprintf("uid '%s' is already used in %s", export.UID, other))
+					continue
+				}
+				uids[key] = file.Path
+				rule, err := export.UpstreamModel(group)
+				if err != nil {
+					ruleFailure(err.Error())
+					continue
+				}
+				if err := p.manager.ValidateAlertRule(ctx, rule); err != nil {
+					ruleFailure(err.Error())
+					continue
+				}
+				result.rules = append(result.rules, rule)
+			}
+			groups = append(groups, result)
+		}
+	}
+	return groups, failures
+}
+
+// applyFiles applies the groups of every file in a transaction. Files with invalid rules are
+// skipped, and the changes of a file are rolled back as soon as one of its rules cannot be applied.
+// It returns the number of provisioned rules and the failures.
+func (p *rulesProvisioner) applyFiles(ctx context.Context, files []*rulesFile, groups []ruleGroup, invalid []Failure) (int, []Failure) {
+	var failures []Failure
+	var applied int
+	invalidFiles := make(map[string]struct{}, len(invalid))
+	for _, failure := range invalid {
+		invalidFiles[failure.File] = struct{}{}
+	}
+	for _, file := range files {
+		if _, ok := invalidFiles[file.Path]; ok {
+			failures = append(failures, Failure{File: file.Path, Reason: "the file was not provisioned because it contains invalid rules"})
+			continue
+		}
+		var fileRules int
+		var fileFailures []Failure
+		err := p.xact.InTransaction(ctx, func(ctx context.Context) error {
+			for _, group := range groups {
+				if group.file != file.Path {
+					continue
+				}
+				if fileFailures = p.applyGroup(ctx, group); len(fileFailures) > 0 {
+					return errRollback
+				}
+				fileRules += len(group.rules)
+			}
+			return nil
+		})
+		switch {
+		case err == nil:
+			applied += fileRules
+		case errors.Is(err, errRollback):
+			failures = append(failures, fileFailures...)
+			failures = append(failures, Failure{File: file.Path, Reason: "all changes of the file were rolled back"})
+		default:
+			failures = append(failures, Failure{File: file.Path, Reason: err.Error()})
+		}
+	}
+	return applied, failures
+}
+
+// applyGroup creates or updates the rules of the group and sets its interval. The file of the group
+// is recorded as the provisioning source of every applied rule.
+func (p *rulesProvisioner) applyGroup(ctx context.Context, group ruleGroup) []Failure {
+	var failures []Failure
+	var applied int
+	for _, rule := range group.rules {
+		_, _, err := p.manager.GetAlertRule(ctx, rule.OrgID, rule.UID)
+		switch {
+		case errors.Is(err, models.ErrAlertRuleNotFound):
+			p.log.Debug("inserting alert rule from configuration", "uid", rule.UID, "title", rule.Title)
+			_, err = p.manager.CreateAlertRule(ctx, rule, models.ProvenanceFile)
+		case err == nil:
+			p.log.Debug("updating alert rule from configuration", "uid", rule.UID, "title", rule.Title)
+			_, err = p.manager.UpdateAlertRule(ctx, rule, models.ProvenanceFile)
+		}
+		if err == nil {
+			err = p.manager.SetProvisioningSource(ctx, rule.OrgID, rule.ResourceType(), rule.UID, models.ProvisioningSource{
+				Path:      group.file,
+				Hash:      group.fileHash,
+				AppliedAt: p.appliedAt,
+			})
+		}
+		if err != nil {
+			failures = append(failures, Failure{File: group.file, Group: group.name, Title: rule.Title, Reason: err.Error()})
+			continue
+		}
+		applied++
+	}
+	if applied == 0 {
+		return failures
+	}
+	if err := p.manager.UpdateAlertGroup(ctx, group.orgID, group.folderUID, group.name, group.interval, models.ProvenanceFile); err != nil {
+		failures = append(failures, Failure{File: group.file, Group: group.name, Reason: err.Error()})
+	}
+	return failures
+}
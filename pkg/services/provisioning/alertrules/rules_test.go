@@ -0,0 +1,308 @@
+package alertrules
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+const validRulesFile = `apiVersion: 1
+groups:
+  - orgId: 1
+    name: my-group
+    folder: my-folder
+    interval: 1m
+    rules:
+      - uid: rule-1
+        title: Rule 1
+        condition: A
+        data:
+          - refId: A
+            relativeTimeRange:
+              from: 600
+              to: 0
+            datasourceUid: my-datasource
+            model:
+              expr: up
+        noDataState: NoData
+        execErrState: Alerting
+        for: 5m
+        notificationSettings:
+          receiver: my-receiver
+          group_by:
+            - alertname
+            - team
+          group_wait: 1m
+          repeat_interval: 4h
+`
+
+const invalidRulesFile = `apiVersion: 1
+groups:
+  - orgId: 1
+    name: other-group
+    folder: my-folder
+    interval: 1m
+    rules:
+      - uid: rule-2
+        title: Rule 2
+        condition: A
+      - title: Rule 3
+        condition: A
+`
+
+func TestProvision(t *testing.T) {
+	t.Run("should create and update rules and set the group interval", func(t *testing.T) {
+		dir := writeRuleFiles(t, map[string]string{"rules.yaml": validRulesFile})
+		manager := newFakeAlertRuleManager()
+
+		report, err := Provision(context.Background(), dir, manager, manager, Options{Strict: true})
+		require.NoError(t, err)
+		require.Empty(t, report.Failures)
+		require.Equal(t, 1, report.Rules)
+		require.Equal(t, []string{"rule-1"}, manager.created)
+		require.Equal(t, int64(60), manager.intervals["my-folder/my-group"])
+		rule := manager.rules["rule-1"]
+		require.Equal(t, "my-folder", rule.NamespaceUID)
+		require.JSONEq(t, `{"expr":"up"}`, string(rule.Data[0].Model))
+		groupWait, repeatInterval := model.Duration(time.Minute), model.Duration(4*time.Hour)
+		require.Equal(t, &models.NotificationSettings{ReceiverName: "my-receiver", GroupBy: []string{"alertname", "team"}, GroupWait: &groupWait, RepeatInterval: &repeatInterval}, rule.GetNotificationSettings())
+
+		_, err = Provision(context.Background(), dir, manager, manager, Options{Strict: true})
+		require.NoError(t, err)
+		require.Equal(t, []string{"rule-1"}, manager.updated)
+	})
+	t.Run("should record the file that every rule was applied from", func(t *testing.T) {
+		dir := writeRuleFiles(t, map[string]string{"rules.yaml": validRulesFile})
+		manager := newFakeAlertRuleManager()
+
+		report, err := Provision(context.Background(), dir, manager, manager, Options{Strict: true})
+		require.NoError(t, err)
+		sum := sha256.Sum256([]byte(validRulesFile))
+		require.Equal(t, models.ProvisioningSource{
+			Path:      filepath.Join(dir, "rules.yaml"),
+			Hash:      hex.EncodeToString(sum[:]),
+			AppliedAt: report.Time,
+		}, manager.sources["alertRule/rule-1"])
+
+		changed := strings.Replace(validRulesFile, "for: 5m", "for: 10m", 1)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "rules.yaml"), []byte(changed), 0600))
+		report, err = Provision(context.Background(), dir, manager, manager, Options{Strict: true})
+		require.NoError(t, err)
+		sum = sha256.Sum256([]byte(changed))
+		require.Equal(t, hex.EncodeToString(sum[:]), manager.sources["alertRule/rule-1"].Hash)
+		require.Equal(t, report.Time, manager.sources["alertRule/rule-1"].AppliedAt)
+	})
+	t.Run("should fail in strict mode and apply nothing if a rule is invalid", func(t *testing.T) {
+		dir := writeRuleFiles(t, map[string]string{
+			"a.yaml": validRulesFile,
+			"b.yaml": invalidRulesFile,
+		})
+		manager := newFakeAlertRuleManager()
+
+		report, err := Provision(context.Background(), dir, manager, manager, Options{Strict: true})
+		require.Error(t, err)
+		require.Empty(t, manager.created)
+		require.Equal(t, []Failure{
+			{File: filepath.Join(dir, "b.yaml"), Group: "other-group", Title: "Rule 2", Reason: "no queries"},
+			{File: filepath.Join(dir, "b.yaml"), Group: "other-group", Title: "Rule 3", Reason: "uid is required"},
+		}, report.Failures)
+		require.Contains(t, err.Error(), "b.yaml: group 'other-group', rule 'Rule 2': no queries")
+	})
+	t.Run("should skip invalid rules and report them if not strict", func(t *testing.T) {
+		dir := writeRuleFiles(t, map[string]string{
+			"a.yaml": validRulesFile,
+			"b.yaml": invalidRulesFile,
+			"c.yml":  "apiVersion: [",
+		})
+		manager := newFakeAlertRuleManager()
+
+		report, err := Provision(context.Background(), dir, manager, manager, Options{})
+		require.NoError(t, err)
+		require.Equal(t, []string{"rule-1"}, manager.created)
+		require.Len(t, report.Failures, 3)
+		require.Equal(t, filepath.Join(dir, "c.yml"), report.Failures[0].File)
+	})
+	t.Run("should report rules that cannot be applied", func(t *testing.T) {
+		dir := writeRuleFiles(t, map[string]string{"rules.yaml": validRulesFile})
+		manager := newFakeAlertRuleManager()
+		manager.createErr = errors.New("cannot change provenance")
+
+		report, err := Provision(context.Background(), dir, manager, manager, Options{})
+		require.NoError(t, err)
+		require.Equal(t, []Failure{
+			{File: filepath.Join(dir, "rules.yaml"), Group: "my-group", Title: "Rule 1", Reason: "cannot change provenance"},
+		}, report.Failures)
+		require.Empty(t, manager.intervals)
+
+		_, err = Provision(context.Background(), dir, manager, manager, Options{Strict: true})
+		require.Error(t, err)
+	})
+}
+
+const twoRulesFile = `apiVersion: 1
+groups:
+  - orgId: 1
+    name: my-group
+    folder: my-folder
+    interval: 1m
+    rules:
+      - uid: rule-1
+        title: Rule 1
+        condition: A
+        data:
+          - refId: A
+            datasourceUid: my-datasource
+            model:
+              expr: up
+      - uid: rule-2
+        title: Rule 2
+        condition: A
+        data:
+          - refId: A
+            datasourceUid: my-datasource
+            model:
+              expr: up
+`
+
+const mixedRulesFile = twoRulesFile + `      - uid: rule-3
+        title: Rule 3
+        condition: A
+`
+
+func TestProvisionAtomic(t *testing.T) {
+	t.Run("should not apply a file with an invalid rule", func(t *testing.T) {
+		dir := writeRuleFiles(t, map[string]string{"rules.yaml": mixedRulesFile})
+		manager := newFakeAlertRuleManager()
+
+		report, err := Provision(context.Background(), dir, manager, manager, Options{AtomicFileProvisioning: true})
+		require.NoError(t, err)
+		require.Empty(t, manager.rules)
+		require.Zero(t, report.Rules)
+		require.Equal(t, Failure{File: filepath.Join(dir, "rules.yaml"), Group: "my-group", Title: "Rule 3", Reason: "no queries"}, report.Failures[0])
+		require.Len(t, report.Failures, 2)
+	})
+	t.Run("should apply the valid rules of the file if not atomic", func(t *testing.T) {
+		dir := writeRuleFiles(t, map[string]string{"rules.yaml": mixedRulesFile})
+		manager := newFakeAlertRuleManager()
+
+		_, err := Provision(context.Background(), dir, manager, manager, Options{})
+		require.NoError(t, err)
+		require.Equal(t, []string{"rule-1", "rule-2"}, manager.created)
+		require.Contains(t, manager.rules, "rule-1")
+		require.Contains(t, manager.rules, "rule-2")
+	})
+	t.Run("should roll back a file if a rule cannot be saved", func(t *testing.T) {
+		dir := writeRuleFiles(t, map[string]string{
+			"a.yaml": validRulesFile,
+			"b.yaml": strings.Replace(strings.Replace(twoRulesFile, "rule-", "other-rule-", -1), "my-group", "other-group", 1),
+		})
+		manager := newFakeAlertRuleManager()
+		manager.createErr = errors.New("database is locked")
+		manager.failUID = "other-rule-2"
+
+		report, err := Provision(context.Background(), dir, manager, manager, Options{AtomicFileProvisioning: true})
+		require.NoError(t, err)
+		require.Equal(t, []string{"rule-1"}, manager.created)
+		require.NotContains(t, manager.intervals, "my-folder/other-group")
+		require.Equal(t, 1, report.Rules)
+		require.Equal(t, "all changes of the file were rolled back", report.Failures[len(report.Failures)-1].Reason)
+	})
+}
+
+func writeRuleFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0600))
+	}
+	return dir
+}
+
+type fakeAlertRuleManager struct {
+	rules     map[string]models.AlertRule
+	intervals map[string]int64
+	sources   map[string]models.ProvisioningSource
+	created   []string
+	updated   []string
+	// createErr is returned when creating the rule failUID, or any rule if failUID is empty.
+	createErr error
+	failUID   string
+}
+
+func newFakeAlertRuleManager() *fakeAlertRuleManager {
+	return &fakeAlertRuleManager{
+		rules:     map[string]models.AlertRule{},
+		intervals: map[string]int64{},
+		sources:   map[string]models.ProvisioningSource{},
+	}
+}
+
+func (f *fakeAlertRuleManager) ValidateAlertRule(ctx context.Context, rule models.AlertRule) error {
+	if len(rule.Data) == 0 {
+		return errors.New("no queries")
+	}
+	return nil
+}
+
+func (f *fakeAlertRuleManager) GetAlertRule(ctx context.Context, orgID int64, ruleUID string) (models.AlertRule, models.Provenance, error) {
+	rule, ok := f.rules[ruleUID]
+	if !ok {
+		return models.AlertRule{}, models.ProvenanceNone, models.ErrAlertRuleNotFound
+	}
+	return rule, models.ProvenanceFile, nil
+}
+
+func (f *fakeAlertRuleManager) CreateAlertRule(ctx context.Context, rule models.AlertRule, provenance models.Provenance) (models.AlertRule, error) {
+	if f.createErr != nil && (f.failUID == "" || f.failUID == rule.UID) {
+		return models.AlertRule{}, f.createErr
+	}
+	f.rules[rule.UID] = rule
+	f.created = append(f.created, rule.UID)
+	return rule, nil
+}
+
+func (f *fakeAlertRuleManager) UpdateAlertRule(ctx context.Context, rule models.AlertRule, provenance models.Provenance) (models.AlertRule, error) {
+	f.rules[rule.UID] = rule
+	f.updated = append(f.updated, rule.UID)
+	return rule, nil
+}
+
+func (f *fakeAlertRuleManager) UpdateAlertGroup(ctx context.Context, orgID int64, folderUID, ruleGroup string, interval int64, provenance models.Provenance) error {
+	f.intervals[folderUID+"/"+ruleGroup] = interval
+	return nil
+}
+
+func (f *fakeAlertRuleManager) SetProvisioningSource(ctx context.Context, orgID int64, resourceType, uid string, source models.ProvisioningSource) error {
+	f.sources[resourceType+"/"+uid] = source
+	return nil
+}
+
+// InTransaction restores the rules and intervals of the fake if work fails.
+func (f *fakeAlertRuleManager) InTransaction(ctx context.Context, work func(ctx context.Context) error) error {
+	rules := make(map[string]models.AlertRule, len(f.rules))
+	for uid, rule := range f.rules {
+		rules[uid] = rule
+	}
+	intervals := make(map[string]int64, len(f.intervals))
+	for key, interval := range f.intervals {
+		intervals[key] = interval
+	}
+	created, updated := len(f.created), len(f.updated)
+	if err := work(ctx); err != nil {
+		f.rules, f.intervals = rules, intervals
+		f.created, f.updated = f.created[:created], f.updated[:updated]
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package alertrules
+
+import (
+	"github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+)
+
+// rulesFileV1 is the content of an alert rule provisioning file. Its groups have the same
+// format as the files of an export of rule groups.
+type rulesFileV1 struct {
+	APIVersion int64                              `yaml:"apiVersion"`
+	Groups     []definitions.AlertRuleGroupExport `yaml:"groups"`
+}
+
+// rulesFile is a parsed alert rule provisioning file.
+type rulesFile struct {
+	Path string
+	// Hash is the hex-encoded SHA-256 of the content of the file.
+	Hash   string
+	Groups []definitions.AlertRuleGroupExport
+}
@@ -13,8 +13,10 @@ import (
 	dashboardservice "github.com/grafana/grafana/pkg/services/dashboards"
 	datasourceservice "github.com/grafana/grafana/pkg/services/datasources"
 	"github.com/grafana/grafana/pkg/services/encryption"
+	"github.com/grafana/grafana/pkg/services/ngalert"
 	"github.com/grafana/grafana/pkg/services/notifications"
 	"github.com/grafana/grafana/pkg/services/pluginsettings"
+	"github.com/grafana/grafana/pkg/services/provisioning/alertrules"
 	"github.com/grafana/grafana/pkg/services/provisioning/dashboards"
 	"github.com/grafana/grafana/pkg/services/provisioning/datasources"
 	"github.com/grafana/grafana/pkg/services/provisioning/notifiers"
@@ -30,6 +32,7 @@ func ProvideService(cfg *setting.Cfg, sqlStore *sqlstore.SQLStore, pluginStore p
 	datasourceService datasourceservice.DataSourceService,
 	dashboardService dashboardservice.DashboardService,
 	alertingService *alerting.AlertNotificationService, pluginSettings pluginsettings.Service,
+	ngAlert *ngalert.AlertNG,
 ) (*ProvisioningServiceImpl, error) {
 	s := &ProvisioningServiceImpl{
 		Cfg:                          cfg,
@@ -42,12 +45,18 @@ func ProvideService(cfg *setting.Cfg, sqlStore *sqlstore.SQLStore, pluginStore p
 		provisionNotifiers:           notifiers.Provision,
 		provisionDatasources:         datasources.Provision,
 		provisionPlugins:             plugins.Provision,
+		provisionAlertRules:          alertrules.Provision,
 		dashboardProvisioningService: dashboardProvisioningService,
 		dashboardService:             dashboardService,
 		datasourceService:            datasourceService,
 		alertingService:              alertingService,
 		pluginsSettings:              pluginSettings,
 	}
+	if ngAlert != nil && !ngAlert.IsDisabled() {
+		if ruleService := ngAlert.AlertRuleService(); ruleService != nil {
+			s.alertRuleManager = ruleService
+		}
+	}
 	return s, nil
 }
 
@@ -58,6 +67,8 @@ type ProvisioningService interface {
 	ProvisionPlugins(ctx context.Context) error
 	ProvisionNotifications(ctx context.Context) error
 	ProvisionDashboards(ctx context.Context) error
+	ProvisionAlertRules(ctx context.Context) error
+	GetLastProvisioningReport(ctx context.Context) alertrules.Report
 	GetDashboardProvisionerResolvedPath(name string) string
 	GetAllowUIUpdatesFromConfig(name string) bool
 }
@@ -70,6 +81,7 @@ func NewProvisioningServiceImpl() *ProvisioningServiceImpl {
 		provisionNotifiers:      notifiers.Provision,
 		provisionDatasources:    datasources.Provision,
 		provisionPlugins:        plugins.Provision,
+		provisionAlertRules:     alertrules.Provision,
 	}
 }
 
@@ -86,6 +98,7 @@ func newProvisioningServiceImpl(
 		provisionNotifiers:      provisionNotifiers,
 		provisionDatasources:    provisionDatasources,
 		provisionPlugins:        provisionPlugins,
+		provisionAlertRules:     alertrules.Provision,
 	}
 }
 
@@ -102,12 +115,16 @@ type ProvisioningServiceImpl struct {
 	provisionNotifiers           func(context.Context, string, notifiers.Manager, notifiers.SQLStore, encryption.Internal, *notifications.NotificationService) error
 	provisionDatasources         func(context.Context, string, datasources.Store, utils.OrgStore) error
 	provisionPlugins             func(context.Context, string, plugins.Store, plugifaces.Store, pluginsettings.Service) error
+	provisionAlertRules          func(context.Context, string, alertrules.AlertRuleManager, alertrules.TransactionManager, alertrules.Options) (alertrules.Report, error)
 	mutex                        sync.Mutex
 	dashboardProvisioningService dashboardservice.DashboardProvisioningService
 	dashboardService             dashboardservice.DashboardService
 	datasourceService            datasourceservice.DataSourceService
 	alertingService              *alerting.AlertNotificationService
 	pluginsSettings              pluginsettings.Service
+	alertRuleManager             alertrules.AlertRuleManager
+	reportMutex                  sync.Mutex
+	alertRulesReport             alertrules.Report
 }
 
 func (ps *ProvisioningServiceImpl) RunInitProvisioners(ctx context.Context) error {
@@ -126,6 +143,11 @@ func (ps *ProvisioningServiceImpl) RunInitProvisioners(ctx context.Context) erro
 		return err
 	}
 
+	err = ps.ProvisionAlertRules(ctx)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -188,6 +210,37 @@ func (ps *ProvisioningServiceImpl) ProvisionNotifications(ctx context.Context) e
 	return nil
 }
 
+// ProvisionAlertRules provisions the alert rules of unified alerting. If strict provisioning is enabled,
+// an invalid rule in any file fails the provisioning. Otherwise invalid rules are only reported.
+func (ps *ProvisioningServiceImpl) ProvisionAlertRules(ctx context.Context) error {
+	if ps.alertRuleManager == nil {
+		return nil
+	}
+	alertRulesPath := filepath.Join(ps.Cfg.ProvisioningPath, "alerting")
+	report, err := ps.provisionAlertRules(ctx, alertRulesPath, ps.alertRuleManager, ps.SQLStore, alertrules.Options{
+		Strict:                 ps.Cfg.UnifiedAlerting.ProvisioningStrict,
+		AtomicFileProvisioning: ps.Cfg.UnifiedAlerting.AtomicFileProvisioning,
+	})
+
+	ps.reportMutex.Lock()
+	ps.alertRulesReport = report
+	ps.reportMutex.Unlock()
+
+	if err != nil {
+		err = fmt.Errorf("%v: %w", "Alert rule provisioning error", err)
+		ps.log.Error("Failed to provision alert rules", "error", err)
+		return err
+	}
+	return nil
+}
+
+// GetLastProvisioningReport returns the report of the last provisioning of alert rules.
+func (ps *ProvisioningServiceImpl) GetLastProvisioningReport(ctx context.Context) alertrules.Report {
+	ps.reportMutex.Lock()
+	defer ps.reportMutex.Unlock()
+	return ps.alertRulesReport
+}
+
 func (ps *ProvisioningServiceImpl) ProvisionDashboards(ctx context.Context) error {
 	dashboardPath := filepath.Join(ps.Cfg.ProvisioningPath, "dashboards")
 	dashProvisioner, err := ps.newDashboardProvisioner(ctx, dashboardPath, ps.dashboardProvisioningService, ps.SQLStore, ps.dashboardService)
@@ -1,6 +1,10 @@
 package provisioning
 
-import "context"
+import (
+	"context"
+
+	"github.com/grafana/grafana/pkg/services/provisioning/alertrules"
+)
 
 type Calls struct {
 	RunInitProvisioners                 []interface{}
@@ -8,6 +12,8 @@ type Calls struct {
 	ProvisionPlugins                    []interface{}
 	ProvisionNotifications              []interface{}
 	ProvisionDashboards                 []interface{}
+	ProvisionAlertRules                 []interface{}
+	GetLastProvisioningReport           []interface{}
 	GetDashboardProvisionerResolvedPath []interface{}
 	GetAllowUIUpdatesFromConfig         []interface{}
 	Run                                 []interface{}
@@ -20,6 +26,8 @@ type ProvisioningServiceMock struct {
 	ProvisionPluginsFunc                    func() error
 	ProvisionNotificationsFunc              func() error
 	ProvisionDashboardsFunc                 func() error
+	ProvisionAlertRulesFunc                 func() error
+	GetLastProvisioningReportFunc           func() alertrules.Report
 	GetDashboardProvisionerResolvedPathFunc func(name string) string
 	GetAllowUIUpdatesFromConfigFunc         func(name string) bool
 	RunFunc                                 func(ctx context.Context) error
@@ -71,6 +79,22 @@ func (mock *ProvisioningServiceMock) ProvisionDashboards(ctx context.Context) er
 	return nil
 }
 
+func (mock *ProvisioningServiceMock) ProvisionAlertRules(ctx context.Context) error {
+	mock.Calls.ProvisionAlertRules = append(mock.Calls.ProvisionAlertRules, nil)
+	if mock.ProvisionAlertRulesFunc != nil {
+		return mock.ProvisionAlertRulesFunc()
+	}
+	return nil
+}
+
+func (mock *ProvisioningServiceMock) GetLastProvisioningReport(ctx context.Context) alertrules.Report {
+	mock.Calls.GetLastProvisioningReport = append(mock.Calls.GetLastProvisioningReport, nil)
+	if mock.GetLastProvisioningReportFunc != nil {
+		return mock.GetLastProvisioningReportFunc()
+	}
+	return alertrules.Report{}
+}
+
 func (mock *ProvisioningServiceMock) GetDashboardProvisionerResolvedPath(name string) string {
 	mock.Calls.GetDashboardProvisionerResolvedPath = append(mock.Calls.GetDashboardProvisionerResolvedPath, name)
 	if mock.GetDashboardProvisionerResolvedPathFunc != nil {
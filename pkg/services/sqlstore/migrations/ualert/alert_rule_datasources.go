@@ -0,0 +1,64 @@
+package ualert
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"xorm.io/xorm"
+
+	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+)
+
+func AddAlertRuleDatasourcesMigrations(mg *migrator.Migrator) {
+	datasourcesTable := migrator.Table{
+		Name: "alert_rule_datasources",
+		Columns: []*migrator.Column{
+			{Name: "id", Type: migrator.DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: migrator.DB_BigInt, Nullable: false},
+			{Name: "rule_uid", Type: migrator.DB_NVarchar, Length: 40, Nullable: false},
+			{Name: "datasource_uid", Type: migrator.DB_NVarchar, Length: 40, Nullable: false},
+		},
+		Indices: []*migrator.Index{
+			{Cols: []string{"org_id", "rule_uid", "datasource_uid"}, Type: migrator.UniqueIndex},
+			{Cols: []string{"org_id", "datasource_uid"}, Type: migrator.IndexType},
+		},
+	}
+	mg.AddMigration("create alert_rule_datasources table", migrator.NewAddTableMigration(datasourcesTable))
+	mg.AddMigration("add unique index on org_id, rule_uid and datasource_uid to alert_rule_datasources table", migrator.NewAddIndexMigration(datasourcesTable, datasourcesTable.Indices[0]))
+	mg.AddMigration("add index on org_id and datasource_uid to alert_rule_datasources table", migrator.NewAddIndexMigration(datasourcesTable, datasourcesTable.Indices[1]))
+	mg.AddMigration("fill alert_rule_datasources table", &fillAlertRuleDatasourcesMigration{})
+}
+
+// fillAlertRuleDatasourcesMigration links the existing alert rules to the data sources they query.
+type fillAlertRuleDatasourcesMigration struct {
+	migrator.MigrationBase
+}
+
+func (m *fillAlertRuleDatasourcesMigration) SQL(migrator.Dialect) string {
+	return "code migration"
+}
+
+func (m *fillAlertRuleDatasourcesMigration) Exec(sess *xorm.Session, mg *migrator.Migrator) error {
+	var rules []struct {
+		OrgID int64  `xorm:"org_id"`
+		UID   string `xorm:"uid"`
+		Data  string `xorm:"data"`
+	}
+	if err := sess.Table("alert_rule").Cols("org_id", "uid", "data").Find(&rules); err != nil {
+		return fmt.Errorf("failed to read the alert rules: %w", err)
+	}
+	for _, rule := range rules {
+		var data []ngmodels.AlertQuery
+		if err := json.Unmarshal([]byte(rule.Data), &data); err != nil {
+			mg.Logger.Warn("skipping alert rule with invalid queries", "org_id", rule.OrgID, "uid", rule.UID, "err", err)
+			continue
+		}
+		for _, uid := range ngmodels.QueriedDatasourceUIDs(data) {
+			if _, err := sess.Insert(&ngmodels.AlertRuleDatasource{OrgID: rule.OrgID, RuleUID: rule.UID, DatasourceUID: uid}); err != nil {
+				return fmt.Errorf("failed to link alert rule %s to data source %s: %w", rule.UID, uid, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+package ualert
+
+import "github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+
+func AddAlertRuleStatusMigrations(mg *migrator.Migrator) {
+	statusTable := migrator.Table{
+		Name: "alert_rule_status",
+		Columns: []*migrator.Column{
+			{Name: "id", Type: migrator.DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: migrator.DB_BigInt, Nullable: false},
+			{Name: "rule_uid", Type: migrator.DB_NVarchar, Length: 40, Nullable: false},
+			{Name: "status", Type: migrator.DB_NVarchar, Length: 40, Nullable: false},
+			{Name: "error", Type: migrator.DB_Text, Nullable: false},
+			{Name: "last_evaluation", Type: migrator.DB_DateTime, Nullable: false},
+		},
+		Indices: []*migrator.Index{
+			{Cols: []string{"org_id", "rule_uid"}, Type: migrator.UniqueIndex},
+		},
+	}
+	mg.AddMigration("create alert_rule_status table", migrator.NewAddTableMigration(statusTable))
+	mg.AddMigration("add unique index on org_id and rule_uid to alert_rule_status table", migrator.NewAddIndexMigration(statusTable, statusTable.Indices[0]))
+}
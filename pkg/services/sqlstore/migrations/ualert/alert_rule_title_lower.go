@@ -0,0 +1,51 @@
+package ualert
+
+import (
+	"fmt"
+	"strings"
+
+	"xorm.io/xorm"
+
+	"github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+)
+
+func AddAlertRuleTitleLowerMigrations(mg *migrator.Migrator) {
+	alertRule := migrator.Table{Name: "alert_rule"}
+	mg.AddMigration("add title_lower column to alert_rule table", migrator.NewAddColumnMigration(alertRule, &migrator.Column{
+		Name: "title_lower", Type: migrator.DB_NVarchar, Length: 190, Nullable: false, Default: "''",
+	}))
+	// PostgreSQL only uses an index for prefix matches with LIKE if the index compares bytes.
+	mg.AddMigration("alter alert_rule table title_lower column to C collation in postgres", migrator.NewRawSQLMigration("").
+		Postgres(`ALTER TABLE alert_rule ALTER COLUMN title_lower TYPE VARCHAR(190) COLLATE "C";`))
+	mg.AddMigration("fill title_lower column of alert_rule table", &fillAlertRuleTitleLowerMigration{})
+	mg.AddMigration("add index on org_id and title_lower to alert_rule table", migrator.NewAddIndexMigration(alertRule, &migrator.Index{
+		Name: "IDX_alert_rule_org_id_title_lower",
+		Cols: []string{"org_id", "title_lower"},
+	}))
+}
+
+// fillAlertRuleTitleLowerMigration lowercases the titles of the existing alert rules. It lowercases
+// in Go rather than SQL, because the LOWER function of SQLite only lowercases ASCII characters.
+type fillAlertRuleTitleLowerMigration struct {
+	migrator.MigrationBase
+}
+
+func (m *fillAlertRuleTitleLowerMigration) SQL(migrator.Dialect) string {
+	return "code migration"
+}
+
+func (m *fillAlertRuleTitleLowerMigration) Exec(sess *xorm.Session, mg *migrator.Migrator) error {
+	var rules []struct {
+		ID    int64  `xorm:"id"`
+		Title string `xorm:"title"`
+	}
+	if err := sess.Table("alert_rule").Cols("id", "title").Find(&rules); err != nil {
+		return fmt.Errorf("failed to read the alert rules: %w", err)
+	}
+	for _, rule := range rules {
+		if _, err := sess.Exec("UPDATE alert_rule SET title_lower = ? WHERE id = ?", strings.ToLower(rule.Title), rule.ID); err != nil {
+			return fmt.Errorf("failed to set the lowercased title of alert rule %d: %w", rule.ID, err)
+		}
+	}
+	return nil
+}
@@ -27,6 +27,22 @@ func AddTablesMigrations(mg *migrator.Migrator) {
 	AddProvisioningMigrations(mg)
 
 	AddAlertImageMigrations(mg)
+
+	AddRuleGroupIntervalLimitsMigrations(mg)
+
+	AddLibraryQueryMigrations(mg)
+
+	AddRuleGroupReplaceJournalMigrations(mg)
+
+	AddAlertRuleDatasourcesMigrations(mg)
+
+	AddAlertRuleTitleLowerMigrations(mg)
+
+	AddAlertRuleStatusMigrations(mg)
+
+	AddRuleGroupDraftMigrations(mg)
+
+	AddProvisioningJobMigrations(mg)
 }
 
 // AddAlertDefinitionMigrations should not be modified.
@@ -160,6 +176,11 @@ func AlertInstanceMigration(mg *migrator.Migrator) {
 		migrator.NewAddColumnMigration(alertInstance, &migrator.Column{
 			Name: "current_reason", Type: migrator.DB_NVarchar, Length: 190, Nullable: true,
 		}))
+
+	mg.AddMigration("add missing_series_labels column to alert_instance",
+		migrator.NewAddColumnMigration(alertInstance, &migrator.Column{
+			Name: "missing_series_labels", Type: migrator.DB_Text, Nullable: true,
+		}))
 }
 
 func AddAlertRuleMigrations(mg *migrator.Migrator, defaultIntervalSeconds int64) {
@@ -241,6 +262,62 @@ func AddAlertRuleMigrations(mg *migrator.Migrator, defaultIntervalSeconds int64)
 			Cols: []string{"org_id", "dashboard_uid", "panel_id"},
 		},
 	))
+
+	mg.AddMigration("add notification_settings column to alert_rule table", migrator.NewAddColumnMigration(alertRule, &migrator.Column{
+		Name: "notification_settings", Type: migrator.DB_Text, Nullable: true,
+	}))
+
+	mg.AddMigration("add rule_group_idx column to alert_rule table", migrator.NewAddColumnMigration(alertRule, &migrator.Column{
+		Name: "rule_group_idx", Type: migrator.DB_Int, Nullable: false, Default: "1",
+	}))
+
+	mg.AddMigration("add eval_strategy column to alert_rule table", migrator.NewAddColumnMigration(alertRule, &migrator.Column{
+		Name: "eval_strategy", Type: migrator.DB_NVarchar, Length: 40, Nullable: false, Default: "'independent'",
+	}))
+
+	mg.AddMigration("add title_template column to alert_rule table", migrator.NewAddColumnMigration(alertRule, &migrator.Column{
+		Name: "title_template", Type: migrator.DB_Text, Nullable: true,
+	}))
+
+	mg.AddMigration("add active_window column to alert_rule table", migrator.NewAddColumnMigration(alertRule, &migrator.Column{
+		Name: "active_window", Type: migrator.DB_Text, Nullable: true,
+	}))
+
+	mg.AddMigration("add eval_order column to alert_rule table", migrator.NewAddColumnMigration(alertRule, &migrator.Column{
+		Name: "eval_order", Type: migrator.DB_Int, Nullable: false, Default: "0",
+	}))
+
+	mg.AddMigration("add expires_at column to alert_rule table", migrator.NewAddColumnMigration(alertRule, &migrator.Column{
+		Name: "expires_at", Type: migrator.DB_DateTime, Nullable: true,
+	}))
+
+	mg.AddMigration("add eval_offset column to alert_rule table", migrator.NewAddColumnMigration(alertRule, &migrator.Column{
+		Name: "eval_offset", Type: migrator.DB_BigInt, Nullable: false, Default: "0",
+	}))
+
+	mg.AddMigration("add group_labels column to alert_rule table", migrator.NewAddColumnMigration(alertRule, &migrator.Column{
+		Name: "group_labels", Type: migrator.DB_Text, Nullable: true,
+	}))
+
+	mg.AddMigration("add managed_by column to alert_rule table", migrator.NewAddColumnMigration(alertRule, &migrator.Column{
+		Name: "managed_by", Type: migrator.DB_NVarchar, Length: 190, Nullable: false, Default: "''",
+	}))
+
+	mg.AddMigration("add index in alert_rule on org_id and managed_by columns", migrator.NewAddIndexMigration(alertRule, &migrator.Index{
+		Cols: []string{"org_id", "managed_by"}, Type: migrator.IndexType,
+	}))
+
+	mg.AddMigration("add eval_every_n column to alert_rule table", migrator.NewAddColumnMigration(alertRule, &migrator.Column{
+		Name: "eval_every_n", Type: migrator.DB_Int, Nullable: false, Default: "1",
+	}))
+
+	// rules that were created without evaluation sampling were stored with 0, which is now rejected.
+	mg.AddMigration("set missing eval_every_n of alert_rule to 1", migrator.NewRawSQLMigration(
+		"UPDATE alert_rule SET eval_every_n = 1 WHERE eval_every_n < 1;"))
+
+	mg.AddMigration("add warm_up_evals column to alert_rule table", migrator.NewAddColumnMigration(alertRule, &migrator.Column{
+		Name: "warm_up_evals", Type: migrator.DB_Int, Nullable: false, Default: "0",
+	}))
 }
 
 func AddAlertRuleVersionMigrations(mg *migrator.Migrator) {
@@ -284,6 +361,54 @@ func AddAlertRuleVersionMigrations(mg *migrator.Migrator) {
 
 	// add labels column
 	mg.AddMigration("add column labels to alert_rule_version", migrator.NewAddColumnMigration(alertRuleVersion, &migrator.Column{Name: "labels", Type: migrator.DB_Text, Nullable: true}))
+
+	mg.AddMigration("add notification_settings column to alert_rule_version table", migrator.NewAddColumnMigration(alertRuleVersion, &migrator.Column{
+		Name: "notification_settings", Type: migrator.DB_Text, Nullable: true,
+	}))
+
+	mg.AddMigration("add rule_group_idx column to alert_rule_version table", migrator.NewAddColumnMigration(alertRuleVersion, &migrator.Column{
+		Name: "rule_group_idx", Type: migrator.DB_Int, Nullable: false, Default: "1",
+	}))
+
+	mg.AddMigration("add eval_strategy column to alert_rule_version table", migrator.NewAddColumnMigration(alertRuleVersion, &migrator.Column{
+		Name: "eval_strategy", Type: migrator.DB_NVarchar, Length: 40, Nullable: false, Default: "'independent'",
+	}))
+
+	mg.AddMigration("add title_template column to alert_rule_version table", migrator.NewAddColumnMigration(alertRuleVersion, &migrator.Column{
+		Name: "title_template", Type: migrator.DB_Text, Nullable: true,
+	}))
+
+	mg.AddMigration("add active_window column to alert_rule_version table", migrator.NewAddColumnMigration(alertRuleVersion, &migrator.Column{
+		Name: "active_window", Type: migrator.DB_Text, Nullable: true,
+	}))
+
+	mg.AddMigration("add eval_order column to alert_rule_version table", migrator.NewAddColumnMigration(alertRuleVersion, &migrator.Column{
+		Name: "eval_order", Type: migrator.DB_Int, Nullable: false, Default: "0",
+	}))
+
+	mg.AddMigration("add eval_offset column to alert_rule_version table", migrator.NewAddColumnMigration(alertRuleVersion, &migrator.Column{
+		Name: "eval_offset", Type: migrator.DB_BigInt, Nullable: false, Default: "0",
+	}))
+
+	mg.AddMigration("add expires_at column to alert_rule_version table", migrator.NewAddColumnMigration(alertRuleVersion, &migrator.Column{
+		Name: "expires_at", Type: migrator.DB_DateTime, Nullable: true,
+	}))
+
+	mg.AddMigration("add eval_every_n column to alert_rule_version table", migrator.NewAddColumnMigration(alertRuleVersion, &migrator.Column{
+		Name: "eval_every_n", Type: migrator.DB_Int, Nullable: false, Default: "1",
+	}))
+
+	mg.AddMigration("add warm_up_evals column to alert_rule_version table", migrator.NewAddColumnMigration(alertRuleVersion, &migrator.Column{
+		Name: "warm_up_evals", Type: migrator.DB_Int, Nullable: false, Default: "0",
+	}))
+
+	mg.AddMigration("add group_labels column to alert_rule_version table", migrator.NewAddColumnMigration(alertRuleVersion, &migrator.Column{
+		Name: "group_labels", Type: migrator.DB_Text, Nullable: true,
+	}))
+
+	mg.AddMigration("add managed_by column to alert_rule_version table", migrator.NewAddColumnMigration(alertRuleVersion, &migrator.Column{
+		Name: "managed_by", Type: migrator.DB_NVarchar, Length: 190, Nullable: false, Default: "''",
+	}))
 }
 
 func AddAlertmanagerConfigMigrations(mg *migrator.Migrator) {
@@ -359,6 +484,25 @@ func AddProvisioningMigrations(mg *migrator.Migrator) {
 
 	mg.AddMigration("create provenance_type table", migrator.NewAddTableMigration(provisioningTable))
 	mg.AddMigration("add index to uniquify (record_key, record_type, org_id) columns", migrator.NewAddIndexMigration(provisioningTable, provisioningTable.Indices[0]))
+
+	sourceTable := migrator.Table{
+		Name: "provisioning_source",
+		Columns: []*migrator.Column{
+			{Name: "id", Type: migrator.DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: migrator.DB_BigInt, Nullable: false},
+			{Name: "record_key", Type: migrator.DB_NVarchar, Length: 190, Nullable: false},
+			{Name: "record_type", Type: migrator.DB_NVarchar, Length: 190, Nullable: false},
+			{Name: "path", Type: migrator.DB_Text, Nullable: false},
+			{Name: "content_hash", Type: migrator.DB_NVarchar, Length: 64, Nullable: false},
+			{Name: "applied_at", Type: migrator.DB_DateTime, Nullable: false},
+		},
+		Indices: []*migrator.Index{
+			{Cols: []string{"record_type", "record_key", "org_id"}, Type: migrator.UniqueIndex},
+		},
+	}
+
+	mg.AddMigration("create provisioning_source table", migrator.NewAddTableMigration(sourceTable))
+	mg.AddMigration("add unique index on provisioning_source (record_type, record_key, org_id)", migrator.NewAddIndexMigration(sourceTable, sourceTable.Indices[0]))
 }
 
 func AddAlertImageMigrations(mg *migrator.Migrator) {
@@ -379,3 +523,117 @@ func AddAlertImageMigrations(mg *migrator.Migrator) {
 	mg.AddMigration("create alert_image table", migrator.NewAddTableMigration(imageTable))
 	mg.AddMigration("add unique index on token to alert_image table", migrator.NewAddIndexMigration(imageTable, imageTable.Indices[0]))
 }
+
+func AddRuleGroupIntervalLimitsMigrations(mg *migrator.Migrator) {
+	limitsTable := migrator.Table{
+		Name: "alert_rule_group_interval_limits",
+		Columns: []*migrator.Column{
+			{Name: "id", Type: migrator.DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: migrator.DB_BigInt, Nullable: false},
+			{Name: "min_interval_seconds", Type: migrator.DB_BigInt, Nullable: false},
+			{Name: "max_interval_seconds", Type: migrator.DB_BigInt, Nullable: false},
+		},
+		Indices: []*migrator.Index{
+			{Cols: []string{"org_id"}, Type: migrator.UniqueIndex},
+		},
+	}
+	mg.AddMigration("create alert_rule_group_interval_limits table", migrator.NewAddTableMigration(limitsTable))
+	mg.AddMigration("add unique index on org_id to alert_rule_group_interval_limits table", migrator.NewAddIndexMigration(limitsTable, limitsTable.Indices[0]))
+}
+
+func AddLibraryQueryMigrations(mg *migrator.Migrator) {
+	libraryQueryTable := migrator.Table{
+		Name: "alert_library_query",
+		Columns: []*migrator.Column{
+			{Name: "id", Type: migrator.DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: migrator.DB_BigInt, Nullable: false},
+			{Name: "uid", Type: migrator.DB_NVarchar, Length: 40, Nullable: false},
+			{Name: "title", Type: migrator.DB_NVarchar, Length: 190, Nullable: false},
+			{Name: "datasource_uid", Type: migrator.DB_NVarchar, Length: 40, Nullable: false},
+			{Name: "query_type", Type: migrator.DB_NVarchar, Length: 50, Nullable: false},
+			{Name: "model", Type: migrator.DB_Text, Nullable: false},
+			{Name: "updated", Type: migrator.DB_DateTime, Nullable: false},
+		},
+		Indices: []*migrator.Index{
+			{Cols: []string{"org_id", "uid"}, Type: migrator.UniqueIndex},
+		},
+	}
+	mg.AddMigration("create alert_library_query table", migrator.NewAddTableMigration(libraryQueryTable))
+	mg.AddMigration("add unique index on org_id and uid to alert_library_query table", migrator.NewAddIndexMigration(libraryQueryTable, libraryQueryTable.Indices[0]))
+}
+
+func AddRuleGroupReplaceJournalMigrations(mg *migrator.Migrator) {
+	journalTable := migrator.Table{
+		Name: "alert_rule_group_replace_journal",
+		Columns: []*migrator.Column{
+			{Name: "id", Type: migrator.DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: migrator.DB_BigInt, Nullable: false},
+			{Name: "namespace_uid", Type: migrator.DB_NVarchar, Length: 40, Nullable: false},
+			{Name: "rule_group", Type: migrator.DB_NVarchar, Length: 190, Nullable: false},
+			{Name: "provenance", Type: migrator.DB_NVarchar, Length: 190, Nullable: false},
+			{Name: "operations", Type: migrator.DB_MediumText, Nullable: false},
+			{Name: "total", Type: migrator.DB_Int, Nullable: false},
+			{Name: "applied", Type: migrator.DB_Int, Nullable: false},
+			{Name: "created", Type: migrator.DB_DateTime, Nullable: false},
+			{Name: "updated", Type: migrator.DB_DateTime, Nullable: false},
+		},
+		Indices: []*migrator.Index{
+			{Cols: []string{"org_id", "namespace_uid", "rule_group"}, Type: migrator.UniqueIndex},
+		},
+	}
+	mg.AddMigration("create alert_rule_group_replace_journal table", migrator.NewAddTableMigration(journalTable))
+	mg.AddMigration("add unique index on org_id, namespace_uid and rule_group to alert_rule_group_replace_journal table", migrator.NewAddIndexMigration(journalTable, journalTable.Indices[0]))
+}
+
+func AddProvisioningJobMigrations(mg *migrator.Migrator) {
+	jobTable := migrator.Table{
+		Name: "alert_provisioning_job",
+		Columns: []*migrator.Column{
+			{Name: "id", Type: migrator.DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "uid", Type: migrator.DB_NVarchar, Length: 40, Nullable: false},
+			{Name: "org_id", Type: migrator.DB_BigInt, Nullable: false},
+			{Name: "type", Type: migrator.DB_NVarchar, Length: 40, Nullable: false},
+			{Name: "status", Type: migrator.DB_NVarchar, Length: 40, Nullable: false},
+			{Name: "input", Type: migrator.DB_LongText, Nullable: false},
+			{Name: "result", Type: migrator.DB_LongBlob, Nullable: true},
+			{Name: "errors", Type: migrator.DB_MediumText, Nullable: false},
+			{Name: "message", Type: migrator.DB_Text, Nullable: false},
+			{Name: "processed", Type: migrator.DB_Int, Nullable: false},
+			{Name: "total", Type: migrator.DB_Int, Nullable: false},
+			{Name: "version", Type: migrator.DB_BigInt, Nullable: false},
+			{Name: "created", Type: migrator.DB_DateTime, Nullable: false},
+			{Name: "updated", Type: migrator.DB_DateTime, Nullable: false},
+		},
+		Indices: []*migrator.Index{
+			{Cols: []string{"org_id", "uid"}, Type: migrator.UniqueIndex},
+			{Cols: []string{"status"}, Type: migrator.IndexType},
+		},
+	}
+	mg.AddMigration("create alert_provisioning_job table", migrator.NewAddTableMigration(jobTable))
+	mg.AddMigration("add unique index on org_id and uid to alert_provisioning_job table", migrator.NewAddIndexMigration(jobTable, jobTable.Indices[0]))
+	mg.AddMigration("add index on status to alert_provisioning_job table", migrator.NewAddIndexMigration(jobTable, jobTable.Indices[1]))
+}
+
+func AddRuleGroupDraftMigrations(mg *migrator.Migrator) {
+	draftTable := migrator.Table{
+		Name: "alert_rule_group_draft",
+		Columns: []*migrator.Column{
+			{Name: "id", Type: migrator.DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: migrator.DB_BigInt, Nullable: false},
+			{Name: "namespace_uid", Type: migrator.DB_NVarchar, Length: 40, Nullable: false},
+			{Name: "rule_group", Type: migrator.DB_NVarchar, Length: 190, Nullable: false},
+			{Name: "interval_seconds", Type: migrator.DB_BigInt, Nullable: false},
+			{Name: "rules", Type: migrator.DB_MediumText, Nullable: false},
+			{Name: "owner", Type: migrator.DB_NVarchar, Length: 190, Nullable: false},
+			{Name: "created", Type: migrator.DB_DateTime, Nullable: false},
+			{Name: "updated", Type: migrator.DB_DateTime, Nullable: false},
+		},
+		Indices: []*migrator.Index{
+			{Cols: []string{"org_id", "namespace_uid", "rule_group"}, Type: migrator.UniqueIndex},
+			{Cols: []string{"updated"}, Type: migrator.IndexType},
+		},
+	}
+	mg.AddMigration("create alert_rule_group_draft table", migrator.NewAddTableMigration(draftTable))
+	mg.AddMigration("add unique index on org_id, namespace_uid and rule_group to alert_rule_group_draft table", migrator.NewAddIndexMigration(draftTable, draftTable.Indices[0]))
+	mg.AddMigration("add index on updated to alert_rule_group_draft table", migrator.NewAddIndexMigration(draftTable, draftTable.Indices[1]))
+}
@@ -48,6 +48,7 @@ const (
 	schedulereDefaultExecuteAlerts          = true
 	schedulerDefaultMaxAttempts             = 3
 	schedulerDefaultLegacyMinInterval       = 1
+	schedulerDefaultMaxConcurrentGroupEval  = 10
 	screenshotsDefaultCapture               = false
 	screenshotsDefaultMaxConcurrent         = 5
 	screenshotsDefaultUploadImageStorage    = false
@@ -81,6 +82,49 @@ type UnifiedAlertingSettings struct {
 	// DefaultRuleEvaluationInterval default interval between evaluations of a rule.
 	DefaultRuleEvaluationInterval time.Duration
 	Screenshots                   UnifiedAlertingScreenshotSettings
+	// ProvisioningWebhookURL is notified about changes of rule groups made through provisioning.
+	ProvisioningWebhookURL string
+	// ProvisioningRuleEventsWebhookURL is notified about every alert rule that is created, updated or
+	// deleted through provisioning.
+	ProvisioningRuleEventsWebhookURL string
+	// ProvisioningRuleEventsWebhookSecret signs the events sent to ProvisioningRuleEventsWebhookURL.
+	ProvisioningRuleEventsWebhookSecret string
+	// ProvisioningRuleEventsWebhookTimeout limits how long the delivery of a rule event may take.
+	ProvisioningRuleEventsWebhookTimeout time.Duration
+	// ProvisioningStrict fails the startup if a file provisioning an alert rule is invalid.
+	ProvisioningStrict bool
+	// AtomicFileProvisioning provisions every alert rule file in a transaction, so that a file is applied completely or not at all.
+	AtomicFileProvisioning bool
+	// ProvisioningRequireProvenanceOrgs are the organizations in which alert rules cannot be created without provenance.
+	ProvisioningRequireProvenanceOrgs map[int64]struct{}
+	// ProvisioningReplaceBatchSize is the number of changes a rule group replace applies per transaction.
+	ProvisioningReplaceBatchSize int
+	// ProvisioningReadOnlyResources are the types of resources that can only be changed by file provisioning.
+	ProvisioningReadOnlyResources []string
+	// ProvisioningDefaultFor is the pending period of alert rules created through provisioning without one.
+	ProvisioningDefaultFor time.Duration
+	// ProvisioningCollapseNameWhitespace collapses inner whitespace of alert rule titles and rule group names.
+	ProvisioningCollapseNameWhitespace bool
+	// ProvisioningRateLimit is the number of changes per second an organization can make through provisioning. 0 disables the limit.
+	ProvisioningRateLimit float64
+	// ProvisioningRateLimitBurst is the number of changes an organization can make at once through provisioning.
+	ProvisioningRateLimitBurst int
+	// ProvisioningDraftTTL is how long drafts of rule groups are kept after their last update. 0 keeps them forever.
+	ProvisioningDraftTTL time.Duration
+	// ProvisioningJobConcurrency is the number of import and export jobs an instance runs at once.
+	ProvisioningJobConcurrency int
+	// ProvisioningJobRetention is how long finished import and export jobs and their results are kept.
+	ProvisioningJobRetention time.Duration
+	// BlockDSDeleteIfUsed rejects the deletion of data sources that alert rules query.
+	BlockDSDeleteIfUsed bool
+	// DryRun evaluates alert rules without sending notifications. The notifications are logged instead.
+	DryRun bool
+	// RecoverFromEvalPanic turns panics of the evaluations of alert rules into errors of the rules that panicked.
+	RecoverFromEvalPanic bool
+	// ConcurrentGroupEval evaluates the rules of a rule group at once when none of them depends on the others.
+	ConcurrentGroupEval bool
+	// MaxConcurrentGroupEval is the number of rules of a group that are evaluated at once. 0 does not limit it.
+	MaxConcurrentGroupEval int
 }
 
 type UnifiedAlertingScreenshotSettings struct {
@@ -207,6 +251,13 @@ func (cfg *Cfg) ReadUnifiedAlertingSettings(iniFile *ini.File) error {
 		uaExecuteAlerts = legacyExecuteAlerts
 	}
 	uaCfg.ExecuteAlerts = uaExecuteAlerts
+	uaCfg.DryRun = ua.Key("dry_run").MustBool(false)
+	uaCfg.RecoverFromEvalPanic = ua.Key("recover_from_eval_panic").MustBool(false)
+	uaCfg.ConcurrentGroupEval = ua.Key("concurrent_group_eval").MustBool(false)
+	uaCfg.MaxConcurrentGroupEval = ua.Key("max_concurrent_group_eval").MustInt(schedulerDefaultMaxConcurrentGroupEval)
+	if uaCfg.MaxConcurrentGroupEval < 0 {
+		return fmt.Errorf("value of setting 'max_concurrent_group_eval' should not be negative")
+	}
 
 	// if the unified alerting options equal the defaults, apply the respective legacy one
 	uaEvaluationTimeout, err := gtime.ParseDuration(valueAsString(ua, "evaluation_timeout", evaluatorDefaultEvaluationTimeout.String()))
@@ -257,6 +308,64 @@ func (cfg *Cfg) ReadUnifiedAlertingSettings(iniFile *ini.File) error {
 		uaCfg.DefaultRuleEvaluationInterval = uaMinInterval
 	}
 
+	uaCfg.ProvisioningWebhookURL = ua.Key("provisioning_webhook_url").MustString("")
+	uaCfg.ProvisioningRuleEventsWebhookURL = ua.Key("provisioning_rule_events_webhook_url").MustString("")
+	uaCfg.ProvisioningRuleEventsWebhookSecret = ua.Key("provisioning_rule_events_webhook_secret").MustString("")
+	uaCfg.ProvisioningRuleEventsWebhookTimeout, err = gtime.ParseDuration(valueAsString(ua, "provisioning_rule_events_webhook_timeout", "10s"))
+	if err != nil {
+		return err
+	}
+	uaCfg.ProvisioningStrict = ua.Key("provisioning_strict").MustBool(false)
+	uaCfg.AtomicFileProvisioning = ua.Key("atomic_file_provisioning").MustBool(false)
+	uaCfg.ProvisioningReplaceBatchSize = ua.Key("provisioning_replace_batch_size").MustInt(100)
+	if uaCfg.ProvisioningReplaceBatchSize < 0 {
+		return fmt.Errorf("value of setting 'provisioning_replace_batch_size' should not be negative")
+	}
+	uaCfg.ProvisioningReadOnlyResources = util.SplitString(valueAsString(ua, "provisioning_read_only_resources", ""))
+	uaCfg.ProvisioningDefaultFor, err = gtime.ParseDuration(valueAsString(ua, "provisioning_default_for", "0s"))
+	if err != nil {
+		return err
+	}
+	if uaCfg.ProvisioningDefaultFor < 0 {
+		return fmt.Errorf("value of setting 'provisioning_default_for' should not be negative")
+	}
+	uaCfg.ProvisioningCollapseNameWhitespace = ua.Key("provisioning_collapse_name_whitespace").MustBool(false)
+	uaCfg.ProvisioningRateLimit = ua.Key("provisioning_rate_limit").MustFloat64(0)
+	if uaCfg.ProvisioningRateLimit < 0 {
+		return fmt.Errorf("value of setting 'provisioning_rate_limit' should not be negative")
+	}
+	uaCfg.ProvisioningRateLimitBurst = ua.Key("provisioning_rate_limit_burst").MustInt(10)
+	if uaCfg.ProvisioningRateLimitBurst < 1 {
+		return fmt.Errorf("value of setting 'provisioning_rate_limit_burst' should be at least 1")
+	}
+	uaCfg.ProvisioningDraftTTL, err = gtime.ParseDuration(valueAsString(ua, "provisioning_draft_ttl", "168h"))
+	if err != nil {
+		return err
+	}
+	if uaCfg.ProvisioningDraftTTL < 0 {
+		return fmt.Errorf("value of setting 'provisioning_draft_ttl' should not be negative")
+	}
+	uaCfg.ProvisioningJobConcurrency = ua.Key("provisioning_job_concurrency").MustInt(2)
+	if uaCfg.ProvisioningJobConcurrency < 1 {
+		return fmt.Errorf("value of setting 'provisioning_job_concurrency' should be at least 1")
+	}
+	uaCfg.ProvisioningJobRetention, err = gtime.ParseDuration(valueAsString(ua, "provisioning_job_retention", "24h"))
+	if err != nil {
+		return err
+	}
+	if uaCfg.ProvisioningJobRetention <= 0 {
+		return fmt.Errorf("value of setting 'provisioning_job_retention' should be greater than 0")
+	}
+	uaCfg.BlockDSDeleteIfUsed = ua.Key("block_datasource_delete_if_used").MustBool(false)
+	uaCfg.ProvisioningRequireProvenanceOrgs = make(map[int64]struct{})
+	for _, org := range util.SplitString(valueAsString(ua, "provisioning_require_provenance_orgs", "")) {
+		orgID, err := strconv.ParseInt(org, 10, 64)
+		if err != nil {
+			return err
+		}
+		uaCfg.ProvisioningRequireProvenanceOrgs[orgID] = struct{}{}
+	}
+
 	screenshots := iniFile.Section("unified_alerting.screenshots")
 	uaCfgScreenshots := uaCfg.Screenshots
 